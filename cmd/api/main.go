@@ -2,20 +2,30 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"log"
+	"log/slog"
 	"net/http"
+	"net/netip"
+	"os"
 	"os/signal"
+	"slices"
 	"strings"
 	"syscall"
 	"time"
 
 	"opensbx/internal/api"
+	"opensbx/internal/audit"
 	"opensbx/internal/config"
 	"opensbx/internal/database"
 	"opensbx/internal/docker"
+	"opensbx/internal/firewall"
+	"opensbx/internal/keys"
 	"opensbx/internal/logging"
 	"opensbx/internal/proxy"
+	"opensbx/internal/secrets"
+	"opensbx/models"
 
 	"github.com/gin-gonic/gin"
 	swaggerfiles "github.com/swaggo/files"
@@ -24,6 +34,9 @@ import (
 	_ "opensbx/docs"
 )
 
+// version is the build version, set by the release build with -ldflags "-X main.version=...".
+var version = "dev"
+
 // @title           Opensbx API
 // @version         1.0
 // @description     Lightweight sandbox API for running untrusted code in isolated environments.
@@ -37,7 +50,7 @@ import (
 
 func main() {
 	cfg := config.Load()
-	logFileCloser, err := logging.Setup(cfg.LogFile)
+	logFileCloser, err := logging.Setup(cfg.LogFile, cfg.LogFormat)
 	if err != nil {
 		log.Fatalf("logging setup failed: %v", err)
 	}
@@ -48,43 +61,231 @@ func main() {
 		mcpLocalhostProtection = "disabled"
 	}
 
-	db := database.New("sandbox.db")
+	db := database.NewWithOptions(cfg.DatabaseURL, database.Options{
+		MaxOpenConns:    cfg.DatabaseMaxOpenConns,
+		MaxIdleConns:    cfg.DatabaseMaxIdleConns,
+		ConnMaxLifetime: cfg.DatabaseConnMaxLifetime,
+	})
 	repo := database.NewRepository(db)
-	dc := docker.New(repo)
+	dockerOpts := docker.Options{Host: cfg.DockerHost, CertPath: cfg.DockerCertPath}
+	if dockerOpts.Host == "" && cfg.DockerContext != "" {
+		if dockerOpts, err = docker.ContextOptions(cfg.DockerContext); err != nil {
+			logging.Fatal("failed to load docker context", "err", err)
+		}
+	}
+	dockerOpts.Engine = cfg.ContainerEngine
+	dc, err := docker.NewWithOptions(repo, dockerOpts)
+	if err != nil {
+		logging.Fatal("failed to create docker client", "err", err)
+	}
+	defer dc.Close()
+	slog.Info("container engine", "engine", cfg.ContainerEngine, "host", dc.DaemonHost())
+	if cfg.ShutdownPolicy != config.ShutdownStop && cfg.ShutdownPolicy != config.ShutdownDetach {
+		logging.Fatal("invalid SHUTDOWN_POLICY (use stop or detach)", "value", cfg.ShutdownPolicy)
+	}
+	dc.SetIsolatedNetwork(cfg.SandboxNetwork)
+	dc.SetMicroVM(docker.MicroVM{Runtimes: cfg.VMRuntimes, MemoryOverhead: cfg.VMMemoryOverhead})
+	if cfg.MicroVMOnly {
+		if !slices.Contains(cfg.VMRuntimes, cfg.SandboxRuntime) {
+			logging.Fatal("MICROVM_ONLY needs a VM runtime as SANDBOX_RUNTIME", "runtime", cfg.SandboxRuntime, "vm_runtimes", cfg.VMRuntimes)
+		}
+		if len(cfg.AllowedRuntimes) == 0 {
+			cfg.AllowedRuntimes = cfg.VMRuntimes
+		}
+		for _, name := range cfg.AllowedRuntimes {
+			if !slices.Contains(cfg.VMRuntimes, name) {
+				logging.Fatal("MICROVM_ONLY allows VM runtimes only", "runtime", name, "vm_runtimes", cfg.VMRuntimes)
+			}
+		}
+	}
+	dc.SetPolicy(docker.Policy{AllowedDevices: cfg.AllowedDevices, AllowedRuntimes: cfg.AllowedRuntimes, AllowGPUs: cfg.AllowGPUs})
+
+	if len(cfg.AllowedRuntimes) > 0 && !slices.Contains(cfg.AllowedRuntimes, cfg.SandboxRuntime) {
+		logging.Fatal("SANDBOX_RUNTIME must be one of ALLOWED_RUNTIMES", "runtime", cfg.SandboxRuntime, "allowed", cfg.AllowedRuntimes)
+	}
+	dc.SetRuntime(cfg.SandboxRuntime)
+	if cfg.SandboxRuntime != "" {
+		// Creates fail until the runtime is configured; warn early instead of refusing to start.
+		if err := dc.CheckRuntime(context.Background(), cfg.SandboxRuntime); err != nil {
+			slog.Warn("sandbox runtime unavailable", "runtime", cfg.SandboxRuntime, "err", err)
+		}
+	}
+
+	hostIP, err := netip.ParseAddr(cfg.SandboxHostIP)
+	if err != nil {
+		logging.Fatal("invalid SANDBOX_HOST_IP", "value", cfg.SandboxHostIP, "err", err)
+	}
+	// The proxy dials sandbox ports at hostIP from this machine.
+	if !dc.LocalDaemon() && (hostIP.IsLoopback() || hostIP.IsUnspecified()) {
+		logging.Fatal("with a remote container engine, SANDBOX_HOST_IP must be an address of its host", "host", dc.DaemonHost(), "host_ip", hostIP)
+	}
+	if err := dc.SetPortBinding(hostIP, cfg.SandboxPortRange); err != nil {
+		logging.Fatal("invalid SANDBOX_PORT_RANGE", "err", err)
+	}
+	if !hostIP.IsLoopback() {
+		slog.Warn("sandbox ports are reachable without the proxy", "host_ip", hostIP)
+	}
+
+	hardening := docker.Hardening{
+		ReadOnlyRootfs:  cfg.SandboxReadOnly,
+		NoNewPrivileges: cfg.SandboxNoNewPrivileges,
+		CapDrop:         cfg.SandboxCapDrop,
+		PidsLimit:       cfg.SandboxPidsLimit,
+		User:            cfg.SandboxUser,
+	}
+	if cfg.SandboxSeccompProfile != "" {
+		profile, err := os.ReadFile(cfg.SandboxSeccompProfile)
+		if err != nil {
+			logging.Fatal("failed to read seccomp profile", "err", err)
+		}
+		hardening.SeccompProfile = string(profile)
+	}
+	dc.SetHardening(hardening)
+	dc.SetCodeRunners(docker.CodeRunners{PoolSize: cfg.CodePoolSize, Images: cfg.CodeImages})
+	dc.SetMaxLifetime(cfg.MaxSandboxLifetime)
+
+	// --- Egress firewall (opt-in, requires iptables + root) ---
+	if cfg.EgressFirewall {
+		// iptables and nsenter act on this machine, not on a remote daemon's host.
+		if !dc.LocalDaemon() {
+			logging.Fatal("EGRESS_FIREWALL needs a local container engine (unix socket)", "host", dc.DaemonHost())
+		}
+		rules, err := firewall.ParseRules(cfg.EgressDenyList())
+		if err != nil {
+			logging.Fatal("egress firewall setup failed", "err", err)
+		}
+		subnets, err := dc.SandboxSubnets(context.Background())
+		if err != nil {
+			logging.Fatal("egress firewall setup failed (requires -sandbox-network)", "err", err)
+		}
+		fw := firewall.New(rules, nil)
+		if err := fw.Install(context.Background()); err != nil {
+			logging.Fatal("egress firewall setup failed", "err", err)
+		}
+		for _, subnet := range subnets {
+			if err := fw.Attach(context.Background(), subnet); err != nil {
+				logging.Fatal("egress firewall setup failed", "subnet", subnet, "err", err)
+			}
+		}
+		// Stack and network group networks are attached as they are created.
+		if err := dc.SetEgressFirewall(context.Background(), fw); err != nil {
+			logging.Fatal("egress firewall setup failed", "err", err)
+		}
+		dc.SetSandboxFirewall(firewall.NewSandbox(nil))
+		slog.Info("egress firewall installed", "rules", len(rules), "subnets", subnets)
+	}
 
 	// --- Reverse proxy (multi-listen) ---
 	proxyServer := proxy.New(cfg.BaseDomain, repo)
 	dc.SetCacheInvalidator(proxyServer.InvalidateCache)
+	if n, err := dc.AdoptContainers(context.Background()); err != nil {
+		slog.Warn("adopting sandbox containers failed", "err", err)
+	} else if n > 0 {
+		slog.Info("adopted sandbox containers missing from the database", "count", n)
+	}
+	if n, err := dc.ResumeTimers(context.Background()); err != nil {
+		slog.Warn("resuming sandbox timers failed", "err", err)
+	} else if n > 0 {
+		slog.Info("resumed timers of sandboxes left running", "count", n)
+	}
+	go dc.ResumeReadyChecks(context.Background())
+	if !hostIP.IsUnspecified() {
+		proxyServer.SetUpstreamHost(hostIP.String())
+	}
+	proxyServer.SetActivityHook(dc.TouchByName)
+	proxyServer.SetWakeHook(dc.WakeByName)
+	proxyServer.SetWebSocketLimits(cfg.ProxyWSIdleTimeout, cfg.ProxyWSMaxDuration)
+	proxyServer.SetCacheTTL(cfg.ProxyCacheTTL)
+	if cfg.ProxyPagesDir != "" {
+		if err := proxyServer.SetPagesDir(cfg.ProxyPagesDir); err != nil {
+			logging.Fatal("failed to load proxy pages", "dir", cfg.ProxyPagesDir, "err", err)
+		}
+	}
 	proxyHandler := proxyServer.Handler()
 
+	// HTTPS uses either a static (wildcard) certificate or per-sandbox ACME
+	// certificates. With ACME, plain listeners also answer HTTP-01 challenges.
+	var proxyTLS *tls.Config
+	plainProxyHandler := proxyHandler
+	if cfg.ProxyTLSEnabled() {
+		if cfg.ProxyACME {
+			m := proxyServer.ACMEManager(cfg.ACMEEmail, cfg.ACMECacheDir, cfg.ACMEDirectoryURL)
+			proxyTLS = m.TLSConfig()
+			plainProxyHandler = m.HTTPHandler(proxyHandler)
+			slog.Info("proxy acme enabled", "cache", cfg.ACMECacheDir, "directory", cfg.ACMEDirectoryURL)
+		} else {
+			proxyTLS, err = cfg.ProxyTLSConfig()
+			if err != nil {
+				logging.Fatal("proxy tls setup failed", "err", err)
+			}
+		}
+	}
+
 	var proxySrvs []*http.Server
 	for _, addr := range cfg.ProxyAddrs {
-		srv := &http.Server{Addr: addr, Handler: proxyHandler}
+		srv := &http.Server{Addr: addr, Handler: plainProxyHandler}
 		proxySrvs = append(proxySrvs, srv)
 		go func(a string) {
-			log.Printf("proxy listening on %s (domain: *.%s)", a, cfg.BaseDomain)
+			slog.Info("proxy listening", "addr", a, "domain", "*."+cfg.BaseDomain)
 			if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-				log.Fatalf("proxy listen %s: %v", a, err)
+				logging.Fatal("proxy listen failed", "addr", a, "err", err)
+			}
+		}(addr)
+	}
+	for _, addr := range cfg.ProxyTLSAddrs {
+		srv := &http.Server{Addr: addr, Handler: proxyHandler, TLSConfig: proxyTLS}
+		proxySrvs = append(proxySrvs, srv)
+		go func(a string) {
+			slog.Info("proxy listening", "addr", a, "domain", "*."+cfg.BaseDomain, "tls", true)
+			if err := srv.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
+				logging.Fatal("proxy listen failed", "addr", a, "err", err)
 			}
 		}(addr)
 	}
-	log.Printf("proxy URLs via %s", strings.Join(cfg.ProxyAddrs, ", "))
-	log.Printf("mcp localhost protection: %s (base-domain: %s)", mcpLocalhostProtection, cfg.BaseDomain)
-	log.Printf("logs file: %s", cfg.LogFile)
+	slog.Info("proxy URLs", "addrs", strings.Join(cfg.ProxyAddrs, ", "))
+	slog.Info("mcp localhost protection", "mode", mcpLocalhostProtection, "base_domain", cfg.BaseDomain)
+	slog.Info("logs file", "path", cfg.LogFile)
 
 	// --- API server ---
 	r := gin.New()
-	r.Use(gin.Logger(), gin.Recovery())
+	r.Use(api.RequestID(), api.RequestLogger(), gin.Recovery())
 
+	// Auth is always installed: with no API_KEY, SIGNING_SECRET or stored keys it
+	// lets loopback requests through so the first key can be created, and
+	// enforces keys as soon as it is.
+	keyStore := keys.New(repo)
 	v1 := r.Group("/v1")
-	if cfg.APIKey != "" {
-		v1.Use(api.APIKeyAuth(cfg.APIKey))
+	creds := api.NewCredentials(cfg.APIKey, cfg.SigningSecret)
+	v1.Use(api.CredentialAuth(creds, keyStore))
+	// Audit runs before the authorization hook so denied operations are recorded too.
+	auditLog := audit.New(repo)
+	v1.Use(api.Audit(auditLog))
+	if cfg.AuthzWebhookURL != "" {
+		v1.Use(api.Authorize(api.NewHTTPAuthorizer(cfg.AuthzWebhookURL, 5*time.Second)))
+		slog.Info("authorization hook enabled", "url", cfg.AuthzWebhookURL)
 	}
+	v1.Use(api.RequestTimeout(api.RouteTimeouts{Short: cfg.RequestTimeout, Long: cfg.LongRequestTimeout}))
 
 	h := api.New(dc, cfg.BaseDomain, cfg.PrimaryProxyAddr())
+	h.SetKeyStore(keyStore)
+	h.SetAuditLog(auditLog)
+	h.SetAllowedOrigins(cfg.AllowedOrigins)
+	if cfg.SecretsKey != "" {
+		secretStore, err := secrets.New(repo, cfg.SecretsKey)
+		if err != nil {
+			logging.Fatal("secrets setup failed", "err", err)
+		}
+		h.SetSecretStore(secretStore)
+		dc.SetSecretResolver(secretStore)
+	}
+	dc.SetQuota(models.Quota{MaxSandboxes: cfg.MaxSandboxes, MaxMemory: cfg.MaxTotalMemory, MaxCPUs: cfg.MaxTotalCPUs})
+	h.SetVersion(version)
+	h.SetStreamLimits(api.StreamLimits{MaxLineSize: cfg.StreamMaxLineSize, WriteTimeout: cfg.StreamWriteTimeout, KeepAlive: cfg.StreamKeepAlive})
+	h.AddHealthCheck("database", repo.Ping)
+	h.AddHealthCheck("proxy", api.DialCheck(append(slices.Clone(cfg.ProxyAddrs), cfg.ProxyTLSAddrs...)...))
 	h.RegisterHealthCheck(r)
 	h.RegisterRoutes(v1)
-	mcpHandler := api.NewMCPHandler(dc, cfg.BaseDomain, cfg.PrimaryProxyAddr(), cfg.MCPDisableLocalhostProtection)
+	mcpHandler := api.NewMCPHandler(dc, h.BaseDomain, cfg.PrimaryProxyAddr(), cfg.MCPDisableLocalhostProtection)
 	mcp := v1.Group("")
 	mcp.Use(api.MCPMetadataLogger())
 	mcp.Any("/mcp", gin.WrapH(mcpHandler))
@@ -99,21 +300,72 @@ func main() {
 		})
 	})
 
-	// Graceful shutdown: listen for SIGINT/SIGTERM, then stop tracked containers.
+	// SIGHUP reloads credentials, the base domain and quotas from the env file.
+	// Listeners stay up, so open log, exec and terminal streams are kept.
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		current := cfg
+		for range hup {
+			next, err := current.Reload()
+			if err != nil {
+				slog.Error("config reload failed", "err", err)
+				continue
+			}
+			creds.Set(next.APIKey, next.SigningSecret)
+			h.SetBaseDomain(next.BaseDomain)
+			proxyServer.SetBaseDomain(next.BaseDomain)
+			dc.SetQuota(models.Quota{MaxSandboxes: next.MaxSandboxes, MaxMemory: next.MaxTotalMemory, MaxCPUs: next.MaxTotalCPUs})
+			if next.BaseDomain != current.BaseDomain {
+				slog.Warn("base domain changed; MCP localhost protection and the static proxy certificate are not reloaded", "base_domain", next.BaseDomain)
+			}
+			current = next
+			slog.Info("config reloaded", "env_file", next.EnvFile, "base_domain", next.BaseDomain,
+				"max_sandboxes", next.MaxSandboxes, "max_total_memory", next.MaxTotalMemory, "max_total_cpus", next.MaxTotalCPUs)
+		}
+	}()
+
+	// Graceful shutdown: listen for SIGINT/SIGTERM, then stop tracked containers
+	// or, with SHUTDOWN_POLICY=detach, leave them running.
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
+	dc.StartReaper(ctx, cfg.ReapGracePeriod)
+	dc.StartLogPruner(ctx, cfg.CommandLogRetention)
+	dc.StartImageGC(ctx, cfg.ImageGCRetention)
+	dc.StartEventWatcher(ctx)
+	dc.StartScheduler(ctx)
+	dc.StartCodePool(ctx)
+
 	srv := &http.Server{Addr: cfg.Addr, Handler: r}
 
+	if (cfg.TLSCertFile == "") != (cfg.TLSKeyFile == "") {
+		logging.Fatal("tls: both -tls-cert and -tls-key must be set")
+	}
+	if cfg.TLSEnabled() {
+		tlsCfg, err := cfg.ServerTLSConfig()
+		if err != nil {
+			logging.Fatal("tls setup failed", "err", err)
+		}
+		srv.TLSConfig = tlsCfg
+	}
+
 	go func() {
-		log.Printf("api listening on %s", cfg.Addr)
+		if cfg.TLSEnabled() {
+			slog.Info("api listening", "addr", cfg.Addr, "tls", true, "client_certs_required", cfg.TLSClientCAFile != "")
+			if err := srv.ListenAndServeTLS(cfg.TLSCertFile, cfg.TLSKeyFile); err != nil && err != http.ErrServerClosed {
+				logging.Fatal("api listen failed", "err", err)
+			}
+			return
+		}
+		slog.Info("api listening", "addr", cfg.Addr)
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("api listen: %v", err)
+			logging.Fatal("api listen failed", "err", err)
 		}
 	}()
 
 	<-ctx.Done()
-	log.Println("shutting down: stopping incoming traffic...")
+	slog.Info("shutting down: stopping incoming traffic")
 
 	httpShutdownCtx, cancelHTTP := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancelHTTP()
@@ -121,24 +373,29 @@ func main() {
 	for _, ps := range proxySrvs {
 		if err := ps.Shutdown(httpShutdownCtx); err != nil {
 			if errors.Is(err, context.DeadlineExceeded) {
-				log.Printf("proxy shutdown %s: timeout reached", ps.Addr)
+				slog.Warn("proxy shutdown: timeout reached", "addr", ps.Addr)
 			} else {
-				log.Printf("proxy shutdown %s: %v", ps.Addr, err)
+				slog.Error("proxy shutdown failed", "addr", ps.Addr, "err", err)
 			}
 		}
 	}
 	if err := srv.Shutdown(httpShutdownCtx); err != nil {
 		if errors.Is(err, context.DeadlineExceeded) {
-			log.Printf("api shutdown: timeout reached")
+			slog.Warn("api shutdown: timeout reached")
 		} else {
-			log.Printf("api shutdown: %v", err)
+			slog.Error("api shutdown failed", "err", err)
 		}
 	}
 
-	log.Println("shutting down: stopping tracked sandboxes...")
-	sandboxShutdownCtx, cancelSandboxes := context.WithTimeout(context.Background(), 45*time.Second)
-	defer cancelSandboxes()
-	dc.Shutdown(sandboxShutdownCtx)
+	if cfg.ShutdownPolicy == config.ShutdownDetach {
+		slog.Info("shutting down: leaving sandboxes running")
+		dc.Detach()
+	} else {
+		slog.Info("shutting down: stopping tracked sandboxes")
+		sandboxShutdownCtx, cancelSandboxes := context.WithTimeout(context.Background(), 45*time.Second)
+		defer cancelSandboxes()
+		dc.Shutdown(sandboxShutdownCtx)
+	}
 
-	log.Println("server stopped")
+	slog.Info("server stopped")
 }
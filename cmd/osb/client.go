@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gorilla/websocket"
+)
+
+// Client calls the opensbx /v1 API.
+type Client struct {
+	base   string
+	apiKey string
+	http   *http.Client
+}
+
+// NewClient creates a Client for the API at base, e.g. "http://localhost:8080".
+func NewClient(base, apiKey string) *Client {
+	return &Client{base: strings.TrimSuffix(base, "/"), apiKey: apiKey, http: http.DefaultClient}
+}
+
+// APIError is an error response from the API.
+type APIError struct {
+	Status  int
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("HTTP %d: %s", e.Status, e.Message)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// sandboxPath returns the API path of a sandbox, optionally followed by more segments.
+func sandboxPath(ref string, more ...string) string {
+	p := "/v1/sandboxes/" + url.PathEscape(ref)
+	for _, m := range more {
+		p += "/" + m
+	}
+	return p
+}
+
+// Do sends a request and returns the response, or an *APIError for non-2xx
+// answers. body is JSON-encoded unless it is an io.Reader, which is sent as
+// raw bytes. The caller closes the response body.
+func (c *Client) Do(method, path string, query url.Values, body any) (*http.Response, error) {
+	var r io.Reader
+	contentType := ""
+	switch b := body.(type) {
+	case nil:
+	case io.Reader:
+		r, contentType = b, "application/octet-stream"
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			return nil, err
+		}
+		r, contentType = bytes.NewReader(data), "application/json"
+	}
+
+	u := c.base + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequest(method, u, r)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		apiErr := &APIError{Status: resp.StatusCode}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		if json.Unmarshal(data, apiErr) != nil || apiErr.Message == "" {
+			apiErr.Message = strings.TrimSpace(string(data))
+		}
+		return nil, apiErr
+	}
+	return resp, nil
+}
+
+// JSON sends a request and decodes the JSON response into out (if not nil).
+func (c *Client) JSON(method, path string, query url.Values, body, out any) error {
+	resp, err := c.Do(method, path, query, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// Dial opens a WebSocket to path, e.g. a tunnel's connect URL, or returns an
+// *APIError when the server refuses the upgrade.
+func (c *Client) Dial(path string) (*websocket.Conn, error) {
+	u := "ws" + strings.TrimPrefix(c.base, "http") + path
+	header := http.Header{}
+	if c.apiKey != "" {
+		header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	ws, resp, err := websocket.DefaultDialer.Dial(u, header)
+	if err != nil && resp != nil {
+		defer resp.Body.Close()
+		apiErr := &APIError{Status: resp.StatusCode}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		if json.Unmarshal(data, apiErr) != nil || apiErr.Message == "" {
+			apiErr.Message = strings.TrimSpace(string(data))
+		}
+		return nil, apiErr
+	}
+	return ws, err
+}
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"opensbx/models"
+)
+
+func runList(a *app, args []string) error {
+	fs := a.newFlagSet("list")
+	state := fs.String("state", "", "only sandboxes in this state, e.g. running")
+	var labels listFlag
+	fs.Var(&labels, "label", "only sandboxes with this label (KEY=VALUE or KEY); repeatable")
+	pos, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 0 {
+		return errUsage
+	}
+
+	q := url.Values{}
+	if *state != "" {
+		q.Set("state", *state)
+	}
+	for _, l := range labels {
+		q.Add("label", l)
+	}
+	var resp struct {
+		Sandboxes []models.SandboxSummary `json:"sandboxes"`
+	}
+	if err := a.client.JSON(http.MethodGet, "/v1/sandboxes", q, nil, &resp); err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(a.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tSTATE\tIMAGE\tURL\tID")
+	for _, s := range resp.Sandboxes {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", s.Name, s.State, s.Image, s.URL, shortID(s.ID))
+	}
+	return tw.Flush()
+}
+
+func runCreate(a *app, args []string) error {
+	fs := a.newFlagSet("create")
+	image := fs.String("image", "", "image to run, e.g. node:24 (required)")
+	name := fs.String("name", "", "sandbox name, also its subdomain (default: generated)")
+	timeout := fs.Int("timeout", 0, "seconds until the sandbox stops (default: the server's)")
+	var ports, env, labels listFlag
+	fs.Var(&ports, "port", "container port to expose, e.g. 3000; repeatable, the first is the main port")
+	fs.Var(&env, "env", "environment variable KEY=VALUE; repeatable")
+	fs.Var(&labels, "label", "label KEY=VALUE; repeatable")
+	pos, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if *image == "" || len(pos) != 0 {
+		return errUsage
+	}
+	labelMap, err := keyValues(labels)
+	if err != nil {
+		return err
+	}
+
+	req := models.CreateSandboxRequest{
+		Image:   *image,
+		Name:    *name,
+		Ports:   ports,
+		Env:     env,
+		Labels:  labelMap,
+		Timeout: *timeout,
+	}
+	var resp models.CreateSandboxResponse
+	if err := a.client.JSON(http.MethodPost, "/v1/sandboxes", nil, req, &resp); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.stdout, "%s\t%s\n", resp.Name, resp.ID)
+	if resp.URL != "" {
+		fmt.Fprintln(a.stdout, resp.URL)
+	}
+	return nil
+}
+
+func runGet(a *app, args []string) error {
+	pos, err := parseArgs(a.newFlagSet("get"), args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 1 {
+		return errUsage
+	}
+	var detail json.RawMessage
+	if err := a.client.JSON(http.MethodGet, sandboxPath(pos[0]), nil, nil, &detail); err != nil {
+		return err
+	}
+	return printJSON(a.stdout, detail)
+}
+
+// runAction returns a command that POSTs to /v1/sandboxes/:id/<action>.
+func runAction(action string) func(a *app, args []string) error {
+	return func(a *app, args []string) error {
+		pos, err := parseArgs(a.newFlagSet(action), args)
+		if err != nil {
+			return err
+		}
+		if len(pos) != 1 {
+			return errUsage
+		}
+		return a.client.JSON(http.MethodPost, sandboxPath(pos[0], action), nil, nil, nil)
+	}
+}
+
+func runRemove(a *app, args []string) error {
+	pos, err := parseArgs(a.newFlagSet("rm"), args)
+	if err != nil {
+		return err
+	}
+	if len(pos) == 0 {
+		return errUsage
+	}
+	for _, ref := range pos {
+		if err := a.client.JSON(http.MethodDelete, sandboxPath(ref), nil, nil, nil); err != nil {
+			return fmt.Errorf("%s: %w", ref, err)
+		}
+	}
+	return nil
+}
+
+func runExec(a *app, args []string) error {
+	fs := a.newFlagSet("exec")
+	cwd := fs.String("cwd", "", "working directory")
+	user := fs.String("user", "", "run as this user (name, uid, user:group)")
+	timeout := fs.Int("timeout", 0, "seconds before the command is killed (default: no limit)")
+	stdin := fs.Bool("i", false, "send osb's stdin to the command")
+	var env listFlag
+	fs.Var(&env, "env", "environment variable KEY=VALUE; repeatable")
+	pos, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) < 2 {
+		return errUsage
+	}
+	envMap, err := keyValues(env)
+	if err != nil {
+		return err
+	}
+
+	req := models.ExecCommandRequest{
+		Command: pos[1],
+		Args:    pos[2:],
+		Cwd:     *cwd,
+		Env:     envMap,
+		Timeout: *timeout,
+		User:    *user,
+	}
+	if *stdin {
+		data, err := io.ReadAll(a.stdin)
+		if err != nil {
+			return err
+		}
+		req.Stdin = string(data)
+	}
+
+	resp, err := a.client.Do(http.MethodPost, sandboxPath(pos[0], "run"), nil, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	exit, err := a.copyEvents(resp.Body)
+	if err != nil {
+		return err
+	}
+	if exit == nil {
+		return errors.New("connection closed before the command exited")
+	}
+	if *exit != 0 {
+		return exitError{code: *exit}
+	}
+	return nil
+}
+
+// followLogs copies a command's streamed output to stdout and stderr until it exits.
+func (a *app) followLogs(ref, cmdID string) error {
+	resp, err := a.client.Do(http.MethodGet, sandboxPath(ref, "cmd", cmdID, "logs"), url.Values{"stream": {"true"}}, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, err = a.copyEvents(resp.Body)
+	return err
+}
+
+// copyEvents writes the output lines of an ND-JSON command stream to stdout
+// and stderr. It returns the exit code if the stream carried an exit line.
+func (a *app) copyEvents(r io.Reader) (*int, error) {
+	var exit *int
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), 16<<20)
+	for scanner.Scan() {
+		var ev models.CommandEvent
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			continue
+		}
+		switch ev.Type {
+		case "stdout":
+			io.WriteString(a.stdout, ev.Data)
+		case "stderr":
+			io.WriteString(a.stderr, ev.Data)
+		case "exit":
+			exit = ev.ExitCode
+		}
+	}
+	return exit, scanner.Err()
+}
+
+func runLogs(a *app, args []string) error {
+	fs := a.newFlagSet("logs")
+	follow := fs.Bool("f", false, "stream output until the command exits")
+	pos, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 2 {
+		return errUsage
+	}
+	if *follow {
+		return a.followLogs(pos[0], pos[1])
+	}
+	var logs models.CommandLogsResponse
+	if err := a.client.JSON(http.MethodGet, sandboxPath(pos[0], "cmd", pos[1], "logs"), nil, nil, &logs); err != nil {
+		return err
+	}
+	io.WriteString(a.stdout, logs.Stdout)
+	io.WriteString(a.stderr, logs.Stderr)
+	return nil
+}
+
+func runFiles(a *app, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	pos, err := parseArgs(a.newFlagSet("files "+args[0]), args[1:])
+	if err != nil {
+		return err
+	}
+	switch {
+	case args[0] == "cp" && len(pos) == 2:
+		return a.copyFile(pos[0], pos[1])
+	case args[0] == "ls" && (len(pos) == 1 || len(pos) == 2):
+		path := "/"
+		if len(pos) == 2 {
+			path = pos[1]
+		}
+		var resp models.FileListResponse
+		if err := a.client.JSON(http.MethodGet, sandboxPath(pos[0], "files", "list"), url.Values{"path": {path}}, nil, &resp); err != nil {
+			return err
+		}
+		io.WriteString(a.stdout, resp.Output)
+		return nil
+	}
+	return errUsage
+}
+
+// copyFile copies a file between the local machine and a sandbox. Exactly one
+// of src and dst is SANDBOX:/path; "-" is stdin or stdout.
+func (a *app) copyFile(src, dst string) error {
+	srcBox, srcPath := splitRemote(src)
+	dstBox, dstPath := splitRemote(dst)
+	switch {
+	case srcBox != "" && dstBox == "":
+		resp, err := a.client.Do(http.MethodGet, sandboxPath(srcBox, "files"), url.Values{"path": {srcPath}, "encoding": {"raw"}}, nil)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if dst == "-" {
+			_, err = io.Copy(a.stdout, resp.Body)
+			return err
+		}
+		f, err := os.Create(dst)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(f, resp.Body); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+
+	case srcBox == "" && dstBox != "":
+		var r io.Reader = a.stdin
+		if src != "-" {
+			f, err := os.Open(src)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			r = f
+		}
+		return a.client.JSON(http.MethodPut, sandboxPath(dstBox, "files"), url.Values{"path": {dstPath}}, r, nil)
+	}
+	return fmt.Errorf("exactly one of %q and %q must be SANDBOX:/path", src, dst)
+}
+
+// splitRemote splits "sandbox:/path" into the sandbox and the path. Local
+// paths return an empty sandbox.
+func splitRemote(arg string) (sandbox, path string) {
+	name, path, ok := strings.Cut(arg, ":")
+	if !ok || name == "" || !strings.HasPrefix(path, "/") || strings.ContainsAny(name, `/\`) {
+		return "", arg
+	}
+	return name, path
+}
+
+// shortID abbreviates a container ID like `docker ps` does.
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+// printJSON writes raw JSON indented.
+func printJSON(w io.Writer, raw json.RawMessage) error {
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
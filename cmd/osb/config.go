@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultURL is the API address used when none is configured.
+const defaultURL = "http://localhost:8080"
+
+// Config is where osb finds the API. It is read from ~/.opensandbox.yaml:
+//
+//	url: https://sandbox.example.com
+//	api_key: osb_...
+//
+// OPENSBX_URL and OPENSBX_API_KEY override the file, and the --url and
+// --api-key flags override both.
+type Config struct {
+	URL    string `yaml:"url"`
+	APIKey string `yaml:"api_key"`
+}
+
+// configPath returns the path of the config file.
+func configPath() string {
+	if p := os.Getenv("OPENSBX_CONFIG"); p != "" {
+		return p
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".opensandbox.yaml"
+	}
+	return filepath.Join(home, ".opensandbox.yaml")
+}
+
+// loadConfig reads the config file, if any, and applies the environment.
+func loadConfig(path string) (Config, error) {
+	var cfg Config
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return Config{}, err
+	default:
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			return Config{}, err
+		}
+	}
+	if v := os.Getenv("OPENSBX_URL"); v != "" {
+		cfg.URL = v
+	}
+	if v := os.Getenv("OPENSBX_API_KEY"); v != "" {
+		cfg.APIKey = v
+	}
+	if cfg.URL == "" {
+		cfg.URL = defaultURL
+	}
+	return cfg, nil
+}
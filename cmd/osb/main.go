@@ -0,0 +1,186 @@
+// Command osb is a command-line client for the opensbx API.
+//
+//	osb create --image node:24 --name web --port 3000
+//	osb exec web -- npm install
+//	osb files cp ./app.js web:/app/app.js
+//	osb list --label team=ml
+//	osb tunnel db 5432
+//
+// See Config for how it finds the server.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// command is an osb subcommand.
+type command struct {
+	usage   string // arguments, shown in help
+	summary string
+	run     func(a *app, args []string) error
+}
+
+// commands maps subcommand names to their implementation.
+var commands = map[string]command{
+	"list":    {"[--state STATE] [--label KEY=VALUE]...", "List sandboxes", runList},
+	"create":  {"--image IMAGE [--name NAME] [--port PORT]... [--env KEY=VALUE]... [--label KEY=VALUE]... [--timeout SECONDS]", "Create and start a sandbox", runCreate},
+	"get":     {"SANDBOX", "Show a sandbox as JSON", runGet},
+	"start":   {"SANDBOX", "Start a stopped sandbox", runAction("start")},
+	"stop":    {"SANDBOX", "Stop a sandbox", runAction("stop")},
+	"restart": {"SANDBOX", "Restart a sandbox", runAction("restart")},
+	"rm":      {"SANDBOX", "Delete a sandbox", runRemove},
+	"exec":    {"[--cwd DIR] [--user USER] [--env KEY=VALUE]... [--timeout SECONDS] [-i] SANDBOX -- COMMAND [ARG]...", "Run a command and stream its output; exits with its exit code", runExec},
+	"logs":    {"[-f] SANDBOX COMMAND_ID", "Print a command's output, or follow it with -f", runLogs},
+	"files":   {"cp SRC DST | ls SANDBOX [PATH]", "Copy files in and out of a sandbox (SANDBOX:/path) or list a directory", runFiles},
+	"tunnel":  {"[--local ADDR] SANDBOX PORT", "Forward a local port to a port inside a sandbox until interrupted", runTunnel},
+}
+
+// app holds what commands need: the API client and the output streams.
+type app struct {
+	client *Client
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// exitError makes osb exit with a command's exit code.
+type exitError struct{ code int }
+
+func (e exitError) Error() string { return fmt.Sprintf("exit code %d", e.code) }
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes osb with args and returns the process exit code.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("osb", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	urlFlag := fs.String("url", "", "API address (default from ~/.opensandbox.yaml, OPENSBX_URL or "+defaultURL+")")
+	keyFlag := fs.String("api-key", "", "API key (default from ~/.opensandbox.yaml or OPENSBX_API_KEY)")
+	fs.Usage = func() { usage(fs) }
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	cmd, ok := commands[fs.Arg(0)]
+	if !ok {
+		fmt.Fprintf(stderr, "osb: unknown command %q\n", fs.Arg(0))
+		fs.Usage()
+		return 2
+	}
+
+	cfg, err := loadConfig(configPath())
+	if err != nil {
+		fmt.Fprintf(stderr, "osb: config: %v\n", err)
+		return 1
+	}
+	if *urlFlag != "" {
+		cfg.URL = *urlFlag
+	}
+	if *keyFlag != "" {
+		cfg.APIKey = *keyFlag
+	}
+
+	a := &app{client: NewClient(cfg.URL, cfg.APIKey), stdin: stdin, stdout: stdout, stderr: stderr}
+	err = cmd.run(a, fs.Args()[1:])
+	var exit exitError
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &exit):
+		return exit.code
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, errUsage):
+		fmt.Fprintf(stderr, "usage: osb %s %s\n", fs.Arg(0), cmd.usage)
+		return 2
+	}
+	fmt.Fprintf(stderr, "osb: %v\n", err)
+	return 1
+}
+
+// usage prints the global help.
+func usage(fs *flag.FlagSet) {
+	w := fs.Output()
+	fmt.Fprintln(w, "usage: osb [--url URL] [--api-key KEY] COMMAND [ARGS]")
+	fmt.Fprintln(w, "\nCommands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-8s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintln(w, "\nFlags:")
+	fs.PrintDefaults()
+}
+
+// errUsage reports wrong arguments; run prints the command's usage.
+var errUsage = errors.New("usage")
+
+// parseArgs parses flags given anywhere among the positional arguments and
+// returns the positional ones. Everything after "--" is positional.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var rest []string
+	for i, arg := range args {
+		if arg == "--" {
+			args, rest = args[:i], args[i+1:]
+			break
+		}
+	}
+	var pos []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return append(pos, rest...), nil
+		}
+		pos = append(pos, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// newFlagSet returns a flag set for a subcommand that reports errors through run.
+func (a *app) newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("osb "+name, flag.ContinueOnError)
+	fs.SetOutput(a.stderr)
+	return fs
+}
+
+// listFlag collects a repeatable string flag.
+type listFlag []string
+
+func (l *listFlag) String() string { return strings.Join(*l, ",") }
+
+func (l *listFlag) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// keyValues parses KEY=VALUE pairs.
+func keyValues(pairs []string) (map[string]string, error) {
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+	out := make(map[string]string, len(pairs))
+	for _, p := range pairs {
+		k, v, ok := strings.Cut(p, "=")
+		if !ok || k == "" {
+			return nil, fmt.Errorf("%q is not KEY=VALUE", p)
+		}
+		out[k] = v
+	}
+	return out, nil
+}
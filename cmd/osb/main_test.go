@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"opensbx/models"
+)
+
+// runOSB runs osb against srv and returns the exit code, stdout and stderr.
+func runOSB(t *testing.T, srv *httptest.Server, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+	t.Setenv("OPENSBX_CONFIG", filepath.Join(t.TempDir(), "missing.yaml"))
+	var stdout, stderr bytes.Buffer
+	code := run(append([]string{"--url", srv.URL, "--api-key", "k"}, args...), strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestParseArgs(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	cwd := fs.String("cwd", "", "")
+	pos, err := parseArgs(fs, []string{"web", "--cwd", "/app", "--", "ls", "-la"})
+	if err != nil {
+		t.Fatalf("parseArgs: %v", err)
+	}
+	if *cwd != "/app" {
+		t.Fatalf("cwd = %q, want /app", *cwd)
+	}
+	if want := []string{"web", "ls", "-la"}; !slices.Equal(pos, want) {
+		t.Fatalf("pos = %q, want %q", pos, want)
+	}
+}
+
+func TestSplitRemote(t *testing.T) {
+	tests := []struct{ arg, sandbox, path string }{
+		{"web:/app/index.js", "web", "/app/index.js"},
+		{"./index.js", "", "./index.js"},
+		{"-", "", "-"},
+		{"dir/web:/x", "", "dir/web:/x"},
+		{`C:\file`, "", `C:\file`},
+	}
+	for _, tt := range tests {
+		sandbox, path := splitRemote(tt.arg)
+		if sandbox != tt.sandbox || path != tt.path {
+			t.Errorf("splitRemote(%q) = %q, %q; want %q, %q", tt.arg, sandbox, path, tt.sandbox, tt.path)
+		}
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "osb.yaml")
+	if err := os.WriteFile(path, []byte("url: http://file\napi_key: from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("OPENSBX_URL", "")
+	t.Setenv("OPENSBX_API_KEY", "from-env")
+	cfg, err := loadConfig(path)
+	if err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+	if cfg.URL != "http://file" || cfg.APIKey != "from-env" {
+		t.Fatalf("cfg = %+v", cfg)
+	}
+}
+
+func TestList(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer k" {
+			t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+		}
+		if r.URL.Path != "/v1/sandboxes" || r.URL.Query().Get("label") != "team=ml" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"sandboxes": []models.SandboxSummary{{ID: "0123456789abcdef", Name: "web", State: "running", Image: "node:24"}},
+			"total":     1,
+		})
+	}))
+	defer srv.Close()
+
+	code, stdout, stderr := runOSB(t, srv, "", "list", "--label", "team=ml")
+	if code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	if !strings.Contains(stdout, "web") || !strings.Contains(stdout, "0123456789ab") || strings.Contains(stdout, "cdef") {
+		t.Fatalf("stdout = %q", stdout)
+	}
+}
+
+func TestExec(t *testing.T) {
+	exit := 3
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/sandboxes/web/run" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		var req models.ExecCommandRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Command != "sh" || !slices.Equal(req.Args, []string{"-c", "exit 3"}) || req.Stdin != "input" {
+			t.Errorf("request = %+v", req)
+		}
+		enc := json.NewEncoder(w)
+		enc.Encode(models.CommandEvent{Type: "start", Command: &models.CommandDetail{ID: "cmd_1"}})
+		enc.Encode(models.CommandEvent{Type: "stdout", Data: "out\n"})
+		enc.Encode(models.CommandEvent{Type: "stderr", Data: "err\n"})
+		enc.Encode(models.CommandEvent{Type: "exit", ExitCode: &exit})
+	}))
+	defer srv.Close()
+
+	code, stdout, stderr := runOSB(t, srv, "input", "exec", "-i", "web", "--", "sh", "-c", "exit 3")
+	if code != 3 || stdout != "out\n" || stderr != "err\n" {
+		t.Fatalf("exit %d, stdout %q, stderr %q", code, stdout, stderr)
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, `{"code":"SANDBOX_NOT_FOUND","message":"sandbox not found"}`)
+	}))
+	defer srv.Close()
+
+	code, _, stderr := runOSB(t, srv, "", "stop", "nope")
+	if code != 1 || !strings.Contains(stderr, "SANDBOX_NOT_FOUND: sandbox not found") {
+		t.Fatalf("exit %d, stderr %q", code, stderr)
+	}
+}
+
+func TestTunnel(t *testing.T) {
+	deleted := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /v1/sandboxes/db/tunnels":
+			var req models.CreateTunnelRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			if req.Port != 5432 {
+				t.Errorf("port = %d", req.Port)
+			}
+			json.NewEncoder(w).Encode(models.Tunnel{ID: "tun_1", Port: req.Port, URL: "/v1/sandboxes/db/tunnels/tun_1/connect"})
+		case "GET /v1/sandboxes/db/tunnels/tun_1/connect":
+			if r.Header.Get("Authorization") != "Bearer k" {
+				t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+			}
+			ws, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+			if err != nil {
+				return
+			}
+			defer ws.Close()
+			for {
+				_, data, err := ws.ReadMessage()
+				if err != nil {
+					return
+				}
+				ws.WriteMessage(websocket.BinaryMessage, bytes.ToUpper(data))
+			}
+		case "DELETE /v1/sandboxes/db/tunnels/tun_1":
+			deleted <- r.URL.Path
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer srv.Close()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	exited := make(chan int, 1)
+	go func() {
+		code, _, stderr := runOSB(t, srv, "", "tunnel", "--local", addr, "db", "5432")
+		if code != 0 {
+			t.Errorf("exit %d: %s", code, stderr)
+		}
+		exited <- code
+	}()
+
+	var conn net.Conn
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		if conn, err = net.Dial("tcp", addr); err == nil || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("dial tunnel: %v", err)
+	}
+	defer conn.Close()
+
+	conn.Write([]byte("ping"))
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(conn, buf); err != nil || string(buf) != "PING" {
+		t.Fatalf("read %q, %v", buf, err)
+	}
+
+	// Interrupting osb closes the tunnel on the server.
+	syscall.Kill(syscall.Getpid(), syscall.SIGINT)
+	select {
+	case <-exited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("osb did not exit on SIGINT")
+	}
+	select {
+	case <-deleted:
+	default:
+		t.Fatal("tunnel was not deleted")
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"opensbx/models"
+)
+
+func runTunnel(a *app, args []string) error {
+	fs := a.newFlagSet("tunnel")
+	local := fs.String("local", "", "local address to listen on (default 127.0.0.1:PORT)")
+	pos, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 2 {
+		return errUsage
+	}
+	port, err := strconv.Atoi(pos[1])
+	if err != nil {
+		return fmt.Errorf("invalid port %q", pos[1])
+	}
+	addr := *local
+	if addr == "" {
+		addr = net.JoinHostPort("127.0.0.1", pos[1])
+	}
+
+	// Stop on Ctrl-C so the tunnel is closed on the server.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var tunnel models.Tunnel
+	if err := a.client.JSON(http.MethodPost, sandboxPath(pos[0], "tunnels"), nil, models.CreateTunnelRequest{Port: port}, &tunnel); err != nil {
+		return err
+	}
+	defer a.client.JSON(http.MethodDelete, sandboxPath(pos[0], "tunnels", tunnel.ID), nil, nil, nil)
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+	fmt.Fprintf(a.stdout, "Forwarding %s -> %s:%d (Ctrl-C to stop)\n", ln.Addr(), pos[0], port)
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		go a.forward(conn, tunnel.URL)
+	}
+}
+
+// forward carries one local connection through a tunnel's WebSocket.
+func (a *app) forward(conn net.Conn, path string) {
+	defer conn.Close()
+	ws, err := a.client.Dial(path)
+	if err != nil {
+		fmt.Fprintf(a.stderr, "osb: tunnel: %v\n", err)
+		return
+	}
+	defer ws.Close()
+
+	go func() {
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := conn.Read(buf)
+			if n > 0 {
+				if werr := ws.WriteMessage(websocket.BinaryMessage, buf[:n]); werr != nil {
+					return
+				}
+			}
+			if err != nil {
+				break
+			}
+		}
+		ws.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+	}()
+
+	for {
+		kind, data, err := ws.ReadMessage()
+		if err != nil {
+			return
+		}
+		if kind != websocket.BinaryMessage {
+			continue
+		}
+		if _, err := conn.Write(data); err != nil {
+			return
+		}
+	}
+}
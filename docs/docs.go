@@ -15,59 +15,29 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/health": {
-            "get": {
-                "description": "Returns the health status of the API and its Docker daemon connection.",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "system"
-                ],
-                "summary": "Health check",
-                "responses": {
-                    "200": {
-                        "description": "status: healthy",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
-                        }
-                    },
-                    "503": {
-                        "description": "status: unhealthy",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
-                        }
-                    }
-                }
-            }
-        },
-        "/images": {
+        "/admin/keys": {
             "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Returns all Docker images available locally.",
+                "description": "Lists all API keys, including revoked ones. Secrets are never returned.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "images"
+                    "admin"
                 ],
-                "summary": "List local images",
+                "summary": "List API keys",
                 "responses": {
                     "200": {
-                        "description": "List of images",
+                        "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.APIKey"
+                            }
                         }
                     },
                     "500": {
@@ -77,16 +47,14 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/images/pull": {
+            },
             "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Downloads a Docker image from a registry to use in sandboxes.",
+                "description": "Creates a scoped API key. Scopes: read-only (GET endpoints), exec (create and manage sandboxes, commands, files), images (pull and remove images), admin (everything, including key management). Non-admin keys only see sandboxes of their owner; keys created with the same owner share sandboxes. The secret is returned only in this response.",
                 "consumes": [
                     "application/json"
                 ],
@@ -94,25 +62,25 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "images"
+                    "admin"
                 ],
-                "summary": "Pull a Docker image",
+                "summary": "Create an API key",
                 "parameters": [
                     {
-                        "description": "Image to pull",
+                        "description": "Key name and scopes",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.ImagePullRequest"
+                            "$ref": "#/definitions/models.CreateAPIKeyRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/models.ImagePullResponse"
+                            "$ref": "#/definitions/models.CreateAPIKeyResponse"
                         }
                     },
                     "400": {
@@ -130,75 +98,25 @@ const docTemplate = `{
                 }
             }
         },
-        "/images/{id}": {
-            "get": {
-                "security": [
-                    {
-                        "ApiKeyAuth": []
-                    }
-                ],
-                "description": "Returns details for a single local Docker image.",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "images"
-                ],
-                "summary": "Inspect an image",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Image ID or name:tag",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/models.ImageDetail"
-                        }
-                    },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "$ref": "#/definitions/internal_api.ErrorResponse"
-                        }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "$ref": "#/definitions/internal_api.ErrorResponse"
-                        }
-                    }
-                }
-            },
+        "/admin/keys/{id}": {
             "delete": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Removes a Docker image from the local store. Use force=true if containers reference it.",
+                "description": "Revokes an API key immediately. Revoked keys stay listed.",
                 "tags": [
-                    "images"
+                    "admin"
                 ],
-                "summary": "Delete a local image",
+                "summary": "Revoke an API key",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Image ID or name:tag",
+                        "description": "Key ID",
                         "name": "id",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "type": "boolean",
-                        "description": "Force removal even if referenced by containers",
-                        "name": "force",
-                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -220,44 +138,39 @@ const docTemplate = `{
                 }
             }
         },
-        "/sandboxes": {
+        "/admin/policy": {
             "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "List all sandboxes (running and stopped).",
+                "description": "Returns the host privileges sandboxes may be granted. Privileged mode, host namespaces, added capabilities and host mounts are always denied.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "sandboxes"
+                    "admin"
                 ],
-                "summary": "List sandboxes",
+                "summary": "Get host policy",
                 "responses": {
                     "200": {
-                        "description": "List of sandboxes",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": true
-                        }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                            "$ref": "#/definitions/models.HostPolicy"
                         }
                     }
                 }
-            },
+            }
+        },
+        "/apply": {
             "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Create and start a new Docker container. Returns its ID and assigned host ports.",
+                "description": "Reconciles sandboxes to match the spec. Sandboxes are matched by name: missing ones are created (and seeded with files), changed ones are recreated, unchanged ones are left running. With prune, sandboxes previously created by apply but missing from the spec are deleted. Sandboxes created via POST /sandboxes are never modified.",
                 "consumes": [
                     "application/json"
                 ],
@@ -267,23 +180,23 @@ const docTemplate = `{
                 "tags": [
                     "sandboxes"
                 ],
-                "summary": "Create a sandbox",
+                "summary": "Apply a declarative sandbox spec",
                 "parameters": [
                     {
-                        "description": "Sandbox configuration",
+                        "description": "Desired sandboxes",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.CreateSandboxRequest"
+                            "$ref": "#/definitions/models.ApplyRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.CreateSandboxResponse"
+                            "$ref": "#/definitions/models.ApplyResponse"
                         }
                     },
                     "400": {
@@ -292,6 +205,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/internal_api.ErrorResponse"
                         }
                     },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -301,39 +220,65 @@ const docTemplate = `{
                 }
             }
         },
-        "/sandboxes/{id}": {
+        "/audit": {
             "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Returns detailed info about the sandbox including ports, resources, and expiration.",
+                "description": "Lists recorded mutating operations (who, what, when, on which sandbox), newest first. Requires the admin scope.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "sandboxes"
+                    "admin"
                 ],
-                "summary": "Inspect a sandbox",
+                "summary": "Query the audit log",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Sandbox ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Only events for this sandbox ID or name",
+                        "name": "sandbox_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only this action, e.g. sandbox.create, command.exec, file.write",
+                        "name": "action",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only events at or after this time (RFC 3339)",
+                        "name": "since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only events before this time (RFC 3339)",
+                        "name": "until",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum events to return (default 100, max 1000)",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.SandboxDetail"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.AuditEvent"
+                            }
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/internal_api.ErrorResponse"
                         }
@@ -345,35 +290,55 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/health": {
+            "get": {
+                "description": "Checks every component the API depends on (the Docker daemon, the database and the proxy listeners) and reports the API, Go and Docker versions. Responds 503 when any component is unhealthy. For Kubernetes probes use /healthz (liveness) and /readyz (readiness) at the server root instead.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "system"
+                ],
+                "summary": "Health check",
+                "responses": {
+                    "200": {
+                        "description": "status: healthy",
+                        "schema": {
+                            "$ref": "#/definitions/models.Health"
+                        }
+                    },
+                    "503": {
+                        "description": "status: unhealthy",
+                        "schema": {
+                            "$ref": "#/definitions/models.Health"
+                        }
+                    }
+                }
+            }
+        },
+        "/images": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Force-remove a sandbox regardless of its state.",
-                "tags": [
-                    "sandboxes"
+                "description": "Returns all Docker images available locally.",
+                "produces": [
+                    "application/json"
                 ],
-                "summary": "Delete a sandbox",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Sandbox ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
+                "tags": [
+                    "images"
                 ],
+                "summary": "List local images",
                 "responses": {
-                    "204": {
-                        "description": "No Content"
-                    },
-                    "404": {
-                        "description": "Not Found",
+                    "200": {
+                        "description": "List of images",
                         "schema": {
-                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                            "type": "object",
+                            "additionalProperties": true
                         }
                     },
                     "500": {
@@ -385,39 +350,38 @@ const docTemplate = `{
                 }
             }
         },
-        "/sandboxes/{id}/cmd": {
-            "get": {
+        "/images/prune": {
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Returns all commands executed in the sandbox.",
+                "description": "Removes images opensbx pulled, imported or cloned that no sandbox has used for at least older_than, except snapshot images, and reports the disk space reclaimed. Other images on the daemon are kept.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "commands"
+                    "images"
                 ],
-                "summary": "List commands",
+                "summary": "Remove unused images",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Sandbox ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Minimum time unused, as a Go duration (default 24h)",
+                        "name": "older_than",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.CommandListResponse"
+                            "$ref": "#/definitions/models.ImagePruneResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/internal_api.ErrorResponse"
                         }
@@ -429,14 +393,16 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
+            }
+        },
+        "/images/pull": {
             "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Execute a command asynchronously inside the sandbox. Returns a command ID immediately. Use ?wait=true to stream ND-JSON until completion.",
+                "description": "Downloads a Docker image from a registry to use in sandboxes.",
                 "consumes": [
                     "application/json"
                 ],
@@ -444,38 +410,25 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "commands"
+                    "images"
                 ],
-                "summary": "Execute a command",
+                "summary": "Pull a Docker image",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Sandbox ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Command to execute",
+                        "description": "Image to pull",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.ExecCommandRequest"
+                            "$ref": "#/definitions/models.ImagePullRequest"
                         }
-                    },
-                    {
-                        "type": "boolean",
-                        "description": "Block until command finishes (ND-JSON stream)",
-                        "name": "wait",
-                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.CommandResponse"
+                            "$ref": "#/definitions/models.ImagePullResponse"
                         }
                     },
                     "400": {
@@ -484,18 +437,6 @@ const docTemplate = `{
                             "$ref": "#/definitions/internal_api.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "$ref": "#/definitions/internal_api.ErrorResponse"
-                        }
-                    },
-                    "409": {
-                        "description": "Conflict",
-                        "schema": {
-                            "$ref": "#/definitions/internal_api.ErrorResponse"
-                        }
-                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -505,49 +446,80 @@ const docTemplate = `{
                 }
             }
         },
-        "/sandboxes/{id}/cmd/{cmdId}": {
+        "/images/{id}": {
             "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Returns the status of a command. Use ?wait=true to block until the command finishes (ND-JSON stream).",
+                "description": "Returns details for a single local Docker image.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "commands"
+                    "images"
                 ],
-                "summary": "Get command status",
+                "summary": "Inspect an image",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Sandbox ID",
+                        "description": "Image ID or name:tag",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ImageDetail"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
                     },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Removes a Docker image from the local store. Use force=true if containers reference it.",
+                "tags": [
+                    "images"
+                ],
+                "summary": "Delete a local image",
+                "parameters": [
                     {
                         "type": "string",
-                        "description": "Command ID",
-                        "name": "cmdId",
+                        "description": "Image ID or name:tag",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
                         "type": "boolean",
-                        "description": "Block until command finishes (ND-JSON stream)",
-                        "name": "wait",
+                        "description": "Force removal even if referenced by containers",
+                        "name": "force",
                         "in": "query"
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/models.CommandResponse"
-                        }
+                    "204": {
+                        "description": "No Content"
                     },
                     "404": {
                         "description": "Not Found",
@@ -564,14 +536,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/sandboxes/{id}/cmd/{cmdId}/kill": {
+        "/jobs": {
             "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Send a POSIX signal to a running command.",
+                "description": "Creates a sandbox, runs a command in it until it exits, and deletes the sandbox again, returning the exit code and output in one call. Equivalent to POST /sandboxes, POST /cmd, GET /cmd/{cmdId}?wait=true, GET /cmd/{cmdId}/logs and DELETE /sandboxes/{id}. The sandbox is also deleted when the request is cancelled. The command's timeout defaults to 1 hour.",
                 "consumes": [
                     "application/json"
                 ],
@@ -579,31 +551,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "commands"
+                    "jobs"
                 ],
-                "summary": "Kill a command",
+                "summary": "Run a one-shot job",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Sandbox ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Command ID",
-                        "name": "cmdId",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Signal to send",
+                        "description": "Sandbox and command",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.KillCommandRequest"
+                            "$ref": "#/definitions/models.JobRequest"
                         }
                     }
                 ],
@@ -611,7 +569,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.CommandResponse"
+                            "$ref": "#/definitions/models.JobResult"
                         }
                     },
                     "400": {
@@ -620,14 +578,14 @@ const docTemplate = `{
                             "$ref": "#/definitions/internal_api.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "409": {
+                        "description": "Conflict",
                         "schema": {
                             "$ref": "#/definitions/internal_api.ErrorResponse"
                         }
                     },
-                    "409": {
-                        "description": "Conflict",
+                    "429": {
+                        "description": "Too Many Requests",
                         "schema": {
                             "$ref": "#/definitions/internal_api.ErrorResponse"
                         }
@@ -641,55 +599,29 @@ const docTemplate = `{
                 }
             }
         },
-        "/sandboxes/{id}/cmd/{cmdId}/logs": {
+        "/networks": {
             "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Returns stdout and stderr of a command. By default returns a JSON snapshot. Use ?stream=true to stream as ND-JSON lines in real time.",
+                "description": "Lists the caller's shared networks and the sandboxes attached to each.",
                 "produces": [
-                    "application/json",
-                    "application/x-ndjson"
+                    "application/json"
                 ],
                 "tags": [
-                    "commands"
-                ],
-                "summary": "Get command logs",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Sandbox ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Command ID",
-                        "name": "cmdId",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "boolean",
-                        "description": "Stream logs as ND-JSON (default: false)",
-                        "name": "stream",
-                        "in": "query"
-                    }
+                    "networks"
                 ],
+                "summary": "List networks",
                 "responses": {
                     "200": {
-                        "description": "JSON snapshot (default)",
-                        "schema": {
-                            "$ref": "#/definitions/models.CommandLogsResponse"
-                        }
-                    },
-                    "404": {
-                        "description": "Not Found",
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.NetworkGroup"
+                            }
                         }
                     },
                     "500": {
@@ -699,44 +631,40 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/sandboxes/{id}/files": {
-            "get": {
+            },
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Returns the content of a file at the given path inside the sandbox.",
+                "description": "Creates a shared network. Sandboxes created with its name in network_group join it and reach each other by sandbox name, e.g. an app connecting to \"db:5432\", without publishing host ports.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "files"
+                    "networks"
                 ],
-                "summary": "Read a file",
+                "summary": "Create a network",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Sandbox ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "File path inside the sandbox",
-                        "name": "path",
-                        "in": "query",
-                        "required": true
+                        "description": "Network name",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateNetworkRequest"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/models.FileReadResponse"
+                            "$ref": "#/definitions/models.NetworkGroup"
                         }
                     },
                     "400": {
@@ -745,8 +673,8 @@ const docTemplate = `{
                             "$ref": "#/definitions/internal_api.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "409": {
+                        "description": "Conflict",
                         "schema": {
                             "$ref": "#/definitions/internal_api.ErrorResponse"
                         }
@@ -758,63 +686,37 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "put": {
+            }
+        },
+        "/networks/{name}": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Write or overwrite a file inside the sandbox. Creates parent directories as needed.",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Returns a shared network and the sandboxes attached to it.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "files"
+                    "networks"
                 ],
-                "summary": "Write a file",
+                "summary": "Get a network",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Sandbox ID",
-                        "name": "id",
+                        "description": "Network name",
+                        "name": "name",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "File path inside the sandbox",
-                        "name": "path",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "description": "File content",
-                        "name": "body",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.FileWriteRequest"
-                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "path and status",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                            "$ref": "#/definitions/models.NetworkGroup"
                         }
                     },
                     "404": {
@@ -837,39 +739,32 @@ const docTemplate = `{
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Remove a file or directory (recursive) inside the sandbox.",
+                "description": "Deletes a shared network. Returns 409 with code NETWORK_IN_USE while sandboxes are attached to it.",
                 "tags": [
-                    "files"
+                    "networks"
                 ],
-                "summary": "Delete a file",
+                "summary": "Delete a network",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Sandbox ID",
-                        "name": "id",
+                        "description": "Network name",
+                        "name": "name",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "File path inside the sandbox",
-                        "name": "path",
-                        "in": "query",
-                        "required": true
                     }
                 ],
                 "responses": {
                     "204": {
                         "description": "No Content"
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/internal_api.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "409": {
+                        "description": "Conflict",
                         "schema": {
                             "$ref": "#/definitions/internal_api.ErrorResponse"
                         }
@@ -883,45 +778,50 @@ const docTemplate = `{
                 }
             }
         },
-        "/sandboxes/{id}/files/list": {
-            "get": {
+        "/run": {
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Returns the output of ls -la for the given directory. Defaults to root (/).",
+                "description": "Runs a program in a fresh sandbox for its language and returns its stdout, stderr and exit code. Sandboxes come from a warm pool kept per language once it has been used, and each is deleted after one run. The language's image is pulled on first use if the API key has the images scope; otherwise a missing image is 400 IMAGE_NOT_FOUND. Supported languages: python, node, go, ruby, bash.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "files"
+                    "run"
                 ],
-                "summary": "List a directory",
+                "summary": "Run code",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Sandbox ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Directory path (default: /)",
-                        "name": "path",
-                        "in": "query"
+                        "description": "Language, code and stdin",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.RunCodeRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.FileListResponse"
+                            "$ref": "#/definitions/models.RunCodeResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
                         "schema": {
                             "$ref": "#/definitions/internal_api.ErrorResponse"
                         }
@@ -935,39 +835,94 @@ const docTemplate = `{
                 }
             }
         },
-        "/sandboxes/{id}/network": {
+        "/sandboxes": {
             "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Returns the selected main proxy port and current container-to-host port mapping.",
+                "description": "List sandboxes (running and stopped), optionally filtered, sorted and paged. total counts the matches before limit and offset. The response carries an ETag; send it back in If-None-Match to get 304 Not Modified while the list is unchanged.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "sandboxes"
                 ],
-                "summary": "Get sandbox network routing",
+                "summary": "List sandboxes",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Sandbox ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Only sandboxes in this state: running, exited, paused, created, restarting, dead or removed",
+                        "name": "state",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only sandboxes of this image",
+                        "name": "image",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only sandboxes whose name starts with this",
+                        "name": "name_prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Only sandboxes with this label: key=value, or key for any value. Repeat to require several",
+                        "name": "label",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort by created (default), name or image",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "asc (default) or desc",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum sandboxes to return (max 1000, default all)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Matches to skip",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of a previous response",
+                        "name": "If-None-Match",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "List of sandboxes",
                         "schema": {
-                            "$ref": "#/definitions/models.SandboxNetwork"
+                            "type": "object",
+                            "additionalProperties": true
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "304": {
+                        "description": "Not Modified"
+                    },
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/internal_api.ErrorResponse"
                         }
@@ -979,44 +934,74 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/sandboxes/{id}/pause": {
+            },
             "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Freeze all processes inside the sandbox.",
+                "description": "Create and start a new Docker container. Returns its ID and assigned host ports. A name that is already in use returns 409 with code SANDBOX_NAME_TAKEN. With ready_check and wait_ready=true, the response is sent once the check passes or times out, and reports which in ready. A retry with the same Idempotency-Key within 24 hours returns the sandbox the first request created, with the Idempotent-Replayed header set.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "sandboxes"
                 ],
-                "summary": "Pause a sandbox",
+                "summary": "Create a sandbox",
                 "parameters": [
+                    {
+                        "description": "Sandbox configuration",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateSandboxRequest"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Wait for ready_check before responding",
+                        "name": "wait_ready",
+                        "in": "query"
+                    },
                     {
                         "type": "string",
-                        "description": "Sandbox ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Unique key of this create, at most 255 characters",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "status: paused",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/models.CreateSandboxResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
                         "schema": {
                             "$ref": "#/definitions/internal_api.ErrorResponse"
                         }
@@ -1030,16 +1015,16 @@ const docTemplate = `{
                 }
             }
         },
-        "/sandboxes/{id}/renew-expiration": {
+        "/sandboxes/import": {
             "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Reset the auto-stop timer for a sandbox.",
+                "description": "Creates a sandbox from the raw tar in the request body: a bundle produced by GET /sandboxes/{id}/export, whose ports, resource limits and labels are kept, or a plain docker save (exactly one image) or docker export tarball, which gets the defaults of POST /sandboxes. A bundle's image.tar must hold exactly one image, tagged as metadata.json names it and nothing else. The image is loaded as opensbx-import/\u003crandom\u003e:\u003cunix time\u003e, so importing never retags an existing image.",
                 "consumes": [
-                    "application/json"
+                    "application/x-tar"
                 ],
                 "produces": [
                     "application/json"
@@ -1047,30 +1032,23 @@ const docTemplate = `{
                 "tags": [
                     "sandboxes"
                 ],
-                "summary": "Renew sandbox expiration",
+                "summary": "Import a sandbox bundle or image archive",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Sandbox ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "New timeout",
-                        "name": "body",
+                        "description": "Sandbox bundle, docker save or docker export tarball",
+                        "name": "bundle",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.RenewExpirationRequest"
+                            "type": "string"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/models.RenewExpirationResponse"
+                            "$ref": "#/definitions/models.CreateSandboxResponse"
                         }
                     },
                     "400": {
@@ -1079,8 +1057,8 @@ const docTemplate = `{
                             "$ref": "#/definitions/internal_api.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "429": {
+                        "description": "Too Many Requests",
                         "schema": {
                             "$ref": "#/definitions/internal_api.ErrorResponse"
                         }
@@ -1094,21 +1072,21 @@ const docTemplate = `{
                 }
             }
         },
-        "/sandboxes/{id}/restart": {
-            "post": {
+        "/sandboxes/{id}": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Restart a sandbox (stop + start). Returns the new port mappings and a fresh expiration timer.",
+                "description": "Returns detailed info about the sandbox including ports, resources, and expiration.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "sandboxes"
                 ],
-                "summary": "Restart a sandbox",
+                "summary": "Inspect a sandbox",
                 "parameters": [
                     {
                         "type": "string",
@@ -1122,7 +1100,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.RestartResponse"
+                            "$ref": "#/definitions/models.SandboxDetail"
                         }
                     },
                     "404": {
@@ -1138,23 +1116,18 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/sandboxes/{id}/resume": {
-            "post": {
+            },
+            "delete": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Resume a paused sandbox.",
-                "produces": [
-                    "application/json"
-                ],
+                "description": "Force-remove a sandbox regardless of its state.",
                 "tags": [
                     "sandboxes"
                 ],
-                "summary": "Resume a sandbox",
+                "summary": "Delete a sandbox",
                 "parameters": [
                     {
                         "type": "string",
@@ -1165,14 +1138,8 @@ const docTemplate = `{
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "status: resumed",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
-                        }
+                    "204": {
+                        "description": "No Content"
                     },
                     "404": {
                         "description": "Not Found",
@@ -1180,6 +1147,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/internal_api.ErrorResponse"
                         }
                     },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -1189,21 +1162,24 @@ const docTemplate = `{
                 }
             }
         },
-        "/sandboxes/{id}/start": {
+        "/sandboxes/{id}/checkpoint": {
             "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Start a stopped sandbox. Returns the port mappings and a fresh expiration timer.",
+                "description": "Saves the memory and process state of a running or paused sandbox with CRIU, then stops it unless leave_running is set. Resume it later with POST /sandboxes/{id}/restore. Requires CRIU and Docker experimental features on the worker.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "sandboxes"
                 ],
-                "summary": "Start a sandbox",
+                "summary": "Checkpoint a sandbox",
                 "parameters": [
                     {
                         "type": "string",
@@ -1211,13 +1187,27 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Checkpoint options",
+                        "name": "body",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.CheckpointRequest"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/models.RestartResponse"
+                            "$ref": "#/definitions/models.Checkpoint"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
                         }
                     },
                     "404": {
@@ -1226,30 +1216,42 @@ const docTemplate = `{
                             "$ref": "#/definitions/internal_api.ErrorResponse"
                         }
                     },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/internal_api.ErrorResponse"
                         }
+                    },
+                    "501": {
+                        "description": "Not Implemented",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
                     }
                 }
             }
         },
-        "/sandboxes/{id}/stats": {
+        "/sandboxes/{id}/checkpoints": {
             "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Returns a snapshot of CPU, memory and process usage for the sandbox.",
+                "description": "Returns the checkpoints saved for a sandbox.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "sandboxes"
                 ],
-                "summary": "Get container stats",
+                "summary": "List sandbox checkpoints",
                 "parameters": [
                     {
                         "type": "string",
@@ -1261,9 +1263,10 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "checkpoints: list of checkpoints",
                         "schema": {
-                            "$ref": "#/definitions/models.SandboxStats"
+                            "type": "object",
+                            "additionalProperties": true
                         }
                     },
                     "404": {
@@ -1277,25 +1280,28 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/internal_api.ErrorResponse"
                         }
+                    },
+                    "501": {
+                        "description": "Not Implemented",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
                     }
                 }
             }
         },
-        "/sandboxes/{id}/stop": {
-            "post": {
+        "/sandboxes/{id}/checkpoints/{name}": {
+            "delete": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Gracefully stop a running sandbox.",
-                "produces": [
-                    "application/json"
-                ],
+                "description": "Deletes a saved checkpoint from the worker's disk.",
                 "tags": [
                     "sandboxes"
                 ],
-                "summary": "Stop a sandbox",
+                "summary": "Delete a sandbox checkpoint",
                 "parameters": [
                     {
                         "type": "string",
@@ -1303,17 +1309,18 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Checkpoint name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "status: stopped",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
-                        }
+                    "204": {
+                        "description": "No Content"
                     },
                     "404": {
                         "description": "Not Found",
@@ -1326,195 +1333,5959 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/internal_api.ErrorResponse"
                         }
+                    },
+                    "501": {
+                        "description": "Not Implemented",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "internal_api.ErrorResponse": {
-            "type": "object",
-            "properties": {
-                "code": {
-                    "type": "string",
-                    "example": "BAD_REQUEST"
+        },
+        "/sandboxes/{id}/clone": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Creates a new sandbox with the configuration of an existing one: ports, resources, command, environment, network policy, labels and ready check. By default the source's filesystem is committed and copied to the clone; with filesystem=false the clone starts from the source's original image. The source keeps running.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sandboxes"
+                ],
+                "summary": "Clone a sandbox",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sandbox ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Clone options",
+                        "name": "body",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.CloneRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateSandboxResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sandboxes/{id}/cmd": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the commands executed in the sandbox, oldest first unless order=desc. total counts every command before limit and offset.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "commands"
+                ],
+                "summary": "List commands",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sandbox ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "asc (default) or desc",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum commands to return (max 1000, default all)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Commands to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.CommandListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Execute a command asynchronously inside the sandbox. Returns a command ID immediately. Use ?wait=true to stream ND-JSON until completion. Send Accept: text/event-stream to get the stream as Server-Sent Events instead.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "application/x-ndjson",
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "commands"
+                ],
+                "summary": "Execute a command",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sandbox ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Command to execute",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ExecCommandRequest"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Block until command finishes (ND-JSON stream)",
+                        "name": "wait",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.CommandResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sandboxes/{id}/cmd/batch": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Runs the given commands one after another, each once the previous one finished, and returns every step's command, stdout and stderr. With stop_on_error the batch ends at the first non-zero exit. Use ?stream=true to receive each step as an ND-JSON line as it finishes, followed by a final {\"success\": bool} line. Send Accept: text/event-stream to get the stream as Server-Sent Events, with step and result events.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "application/x-ndjson",
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "commands"
+                ],
+                "summary": "Execute commands in sequence",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sandbox ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Commands to execute",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.BatchCommandRequest"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Stream steps as ND-JSON (default: false)",
+                        "name": "stream",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.BatchCommandResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sandboxes/{id}/cmd/{cmdId}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the status of a command. Use ?wait=true to block until the command finishes (ND-JSON stream). Send Accept: text/event-stream to get the stream as Server-Sent Events instead.",
+                "produces": [
+                    "application/json",
+                    "application/x-ndjson",
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "commands"
+                ],
+                "summary": "Get command status",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sandbox ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Command ID",
+                        "name": "cmdId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Block until command finishes (ND-JSON stream)",
+                        "name": "wait",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.CommandResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sandboxes/{id}/cmd/{cmdId}/kill": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Send a POSIX signal, by name (\"SIGTERM\") or number (15), to a running command's process group.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "commands"
+                ],
+                "summary": "Kill a command",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sandbox ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Command ID",
+                        "name": "cmdId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Signal to send",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.KillCommandRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.CommandResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sandboxes/{id}/cmd/{cmdId}/logs": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns stdout and stderr of a command. By default returns a JSON snapshot. Use ?stream=true to stream as ND-JSON lines in real time. Output of finished commands is persisted and kept for COMMAND_LOG_RETENTION. Send Accept: text/event-stream to get the stream as Server-Sent Events instead.",
+                "produces": [
+                    "application/json",
+                    "application/x-ndjson",
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "commands"
+                ],
+                "summary": "Get command logs",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sandbox ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Command ID",
+                        "name": "cmdId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Stream logs as ND-JSON (default: false)",
+                        "name": "stream",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "JSON snapshot (default)",
+                        "schema": {
+                            "$ref": "#/definitions/models.CommandLogsResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sandboxes/{id}/domains": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Lists the external hostnames routed to the sandbox.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sandboxes"
+                ],
+                "summary": "List custom domains",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sandbox ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.SandboxDomain"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Routes an external hostname to the sandbox's main port through the proxy. Point the domain's DNS at the proxy; with PROXY_ACME the certificate is issued on first visit. Subdomains of the base domain are reserved for sandbox names.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sandboxes"
+                ],
+                "summary": "Add a custom domain",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sandbox ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Domain",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.AddDomainRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.SandboxDomain"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sandboxes/{id}/domains/{domain}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Stops routing the hostname to the sandbox.",
+                "tags": [
+                    "sandboxes"
+                ],
+                "summary": "Remove a custom domain",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sandbox ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Domain",
+                        "name": "domain",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sandboxes/{id}/env": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the sandbox's environment: the variables it was created with, overridden by those set with PUT /sandboxes/{id}/env.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sandboxes"
+                ],
+                "summary": "Get sandbox environment variables",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sandbox ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SandboxEnv"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Sets variables for commands, processes and terminals started from now on, without recreating the sandbox. A null value removes a variable set earlier through this endpoint. Processes already running, including the container's main command, keep their environment.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sandboxes"
+                ],
+                "summary": "Set sandbox environment variables",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sandbox ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Variables to set or remove",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdateEnvRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SandboxEnv"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sandboxes/{id}/export": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Commits the sandbox filesystem and streams a portable bundle: a tar with metadata.json (ports, resources, source image) and image.tar (docker save format).",
+                "produces": [
+                    "application/x-tar"
+                ],
+                "tags": [
+                    "sandboxes"
+                ],
+                "summary": "Export a sandbox bundle",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sandbox ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sandboxes/{id}/files": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the content of a file at the given path inside the sandbox. Use encoding=base64 for binary files in JSON, or encoding=raw (or Accept: application/octet-stream) to stream the bytes.",
+                "produces": [
+                    "application/json",
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Read a file",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sandbox ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "File path inside the sandbox",
+                        "name": "path",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "utf-8 (default), base64 or raw",
+                        "name": "encoding",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.FileReadResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Write or overwrite a file inside the sandbox. Creates parent directories as needed. Besides JSON (optionally base64-encoded), the body may be raw bytes with Content-Type application/octet-stream, or multipart/form-data with a \"file\" field.",
+                "consumes": [
+                    "application/json",
+                    "application/octet-stream",
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Write a file",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sandbox ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "File path inside the sandbox",
+                        "name": "path",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "File content",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.FileWriteRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "path and status",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Remove a file or directory (recursive) inside the sandbox. Returns 404 if the path does not exist.",
+                "tags": [
+                    "files"
+                ],
+                "summary": "Delete a file",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sandbox ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "File path inside the sandbox",
+                        "name": "path",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sandboxes/{id}/files/download": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Streams a file or directory from the sandbox as a tar (default) or zip archive.",
+                "produces": [
+                    "application/x-tar",
+                    "application/zip"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Download an archive",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sandbox ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "File or directory inside the sandbox",
+                        "name": "path",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Archive format: tar (default) or zip",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sandboxes/{id}/files/list": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the output of ls -la for the given directory. Defaults to root (/). Returns 404 with the ls error if the path does not exist. With tree=true, returns a recursive JSON tree (directories first) down to depth levels instead.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "List a directory",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sandbox ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Directory path (default: /)",
+                        "name": "path",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Return a recursive JSON tree",
+                        "name": "tree",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Tree depth, 1-10 (default: 3)",
+                        "name": "depth",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "ls output (default); a models.FileTreeResponse with tree=true",
+                        "schema": {
+                            "$ref": "#/definitions/models.FileListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sandboxes/{id}/files/search": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the entries below path whose path relative to it matches glob. Segments use shell wildcards (*, ?, [...]) and ** matches any number of directories, e.g. **/*.ts. Searches descend at most 32 levels.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Search files by glob",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sandbox ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Glob relative to path, e.g. **/*.ts",
+                        "name": "glob",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Directory to search (default: /)",
+                        "name": "path",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum matches, 1-10000 (default: 1000)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.FileSearchResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sandboxes/{id}/files/stat": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the size, permissions, modification time and type of a file or directory without reading it. Symlinks are not followed. Returns 404 if the path does not exist.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Stat a file",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sandbox ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Path inside the sandbox",
+                        "name": "path",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.FileStat"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sandboxes/{id}/files/upload": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Extracts a tar or zip archive into a directory inside the sandbox (created if missing). The format is zip when Content-Type is application/zip or format=zip, tar otherwise.",
+                "consumes": [
+                    "application/x-tar",
+                    "application/zip"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Upload an archive",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sandbox ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Destination directory inside the sandbox",
+                        "name": "path",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Archive format: tar (default) or zip",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Archive bytes",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "path and status",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sandboxes/{id}/git/pull": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Fast-forwards a repository inside a running sandbox to its upstream branch, by default the one cloned by the git option on create. Credentials are not kept from create: send the token or ssh_key again for private repositories. Fails with GIT_FAILED and git's message when the pull does, e.g. on diverged history or a detached HEAD.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sandboxes"
+                ],
+                "summary": "Pull a repository",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sandbox ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Repository and credentials",
+                        "name": "body",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.GitPullRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.GitStatus"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sandboxes/{id}/isolation": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Reports whether the sandbox can reach other containers over its attached networks.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sandboxes"
+                ],
+                "summary": "Get sandbox network isolation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sandbox ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SandboxIsolation"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sandboxes/{id}/logs": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Streams the output of the sandbox's main process (its entrypoint and startup command) as ND-JSON lines, in the same format as command logs. Commands started through the API are not included; use their own logs. Without follow the stream ends after the existing output. Send Accept: text/event-stream to get the stream as Server-Sent Events instead.",
+                "produces": [
+                    "application/x-ndjson",
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "sandboxes"
+                ],
+                "summary": "Get sandbox logs",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sandbox ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Keep streaming new output",
+                        "name": "follow",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Lines from the end, or all (default)",
+                        "name": "tail",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only output after this: RFC 3339, unix seconds, or a duration ago such as 10m",
+                        "name": "since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Prefix every line with its timestamp",
+                        "name": "timestamps",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "One ND-JSON line per output line",
+                        "schema": {
+                            "$ref": "#/definitions/models.CommandEvent"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sandboxes/{id}/network": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the selected main proxy port, the current container-to-host port mapping, and a proxy URL per port. Ports other than the main one are reachable at \u003cname\u003e--\u003cport\u003e.\u003cbase-domain\u003e.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sandboxes"
+                ],
+                "summary": "Get sandbox network routing",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sandbox ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SandboxNetwork"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sandboxes/{id}/pause": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Freeze all processes inside the sandbox.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sandboxes"
+                ],
+                "summary": "Pause a sandbox",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sandbox ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "status: paused",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sandboxes/{id}/processes": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the supervised processes of the sandbox with their status, and a snapshot of every process running in it (pid, user, cpu, memory, command).",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "processes"
+                ],
+                "summary": "List processes",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sandbox ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProcessListResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Starts a long-running process (e.g. a dev server) that is restarted according to its restart policy when it exits, and started again whenever the sandbox starts or restarts. Each run is a command whose output is available under /cmd/{command_id}/logs.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "processes"
+                ],
+                "summary": "Start a supervised process",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sandbox ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Process definition",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.StartProcessRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProcessDetail"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sandboxes/{id}/processes/{name}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns a supervised process with its status, restart count and current command.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "processes"
+                ],
+                "summary": "Get a supervised process",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sandbox ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Process name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProcessDetail"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Stops supervising the process, terminates its current run and forgets its definition.",
+                "tags": [
+                    "processes"
+                ],
+                "summary": "Remove a supervised process",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sandbox ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Process name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sandboxes/{id}/renew-expiration": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Reset the auto-stop timer for a sandbox. The timer never runs past the sandbox's max_lifetime; once that is reached, renewing fails with LIFETIME_EXCEEDED.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sandboxes"
+                ],
+                "summary": "Renew sandbox expiration",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sandbox ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New timeout",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.RenewExpirationRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.RenewExpirationResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sandboxes/{id}/restart": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Restart a sandbox (stop + start). Returns the new port mappings and a fresh expiration timer. With wait_port=true, the response is sent once the main port accepts TCP connections or port_timeout passes, and reports which in port_ready.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sandboxes"
+                ],
+                "summary": "Restart a sandbox",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sandbox ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Wait for the main port to accept connections before responding",
+                        "name": "wait_port",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Seconds to wait with wait_port (default 30, max 600)",
+                        "name": "port_timeout",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.RestartResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sandboxes/{id}/restore": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Starts a stopped sandbox with the memory and processes saved in a checkpoint.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sandboxes"
+                ],
+                "summary": "Restore a sandbox from a checkpoint",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sandbox ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Checkpoint to restore",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.RestoreRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.RestartResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "Not Implemented",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sandboxes/{id}/resume": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Resume a paused sandbox.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sandboxes"
+                ],
+                "summary": "Resume a sandbox",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sandbox ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "status: resumed",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sandboxes/{id}/run": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Executes a command and streams it as ND-JSON in one call: a \"start\" line with the command, its stdout and stderr lines as they are written, then an \"exit\" line with exit_code and the finished command. Equivalent to POST /cmd, GET /cmd/{cmdId}/logs?stream=true and GET /cmd/{cmdId}?wait=true. Send Accept: text/event-stream to get the stream as Server-Sent Events instead.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/x-ndjson",
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "commands"
+                ],
+                "summary": "Run a command and stream its output",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sandbox ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Command to execute",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ExecCommandRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "one per line",
+                        "schema": {
+                            "$ref": "#/definitions/models.CommandEvent"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sandboxes/{id}/sessions": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the sessions running in a sandbox, oldest first.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sessions"
+                ],
+                "summary": "List code sessions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sandbox ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "sessions: list of sessions",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Starts a persistent Python or Node interpreter in a running sandbox, like a Jupyter kernel: variables, imports and definitions survive from one execution to the next. The sandbox image must provide python3 or node. Sessions live in memory and end when the sandbox stops or the server restarts.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sessions"
+                ],
+                "summary": "Start a code session",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sandbox ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Interpreter",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateSessionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Session"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sandboxes/{id}/sessions/{sid}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Stops a session's interpreter and discards its state. A cell in progress ends with an error event.",
+                "tags": [
+                    "sessions"
+                ],
+                "summary": "Stop a code session",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sandbox ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "sid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sandboxes/{id}/sessions/{sid}/execute": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Runs code in a session and streams its output as ND-JSON events: \"stdout\" and \"stderr\" text, \"display\" and \"result\" values as MIME bundles (text/plain, text/html, image/png as base64, ...), an \"error\" with the exception, and a final \"end\" line with the execution count. In Python the value of a trailing expression is the result. On timeout, or when the client disconnects, the cell is interrupted and the session keeps its state. One cell runs at a time per session. Send Accept: text/event-stream to get the stream as Server-Sent Events instead.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/x-ndjson",
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "sessions"
+                ],
+                "summary": "Execute a cell",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sandbox ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "sid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Cell",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ExecuteRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "one per line",
+                        "schema": {
+                            "$ref": "#/definitions/models.SessionEvent"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sandboxes/{id}/snapshot": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Commits the sandbox filesystem (installed deps, generated files) to a new image, optionally pushing it to a registry. Create identical sandboxes later by passing the returned image to POST /sandboxes. Without image the snapshot is named opensbx-snapshot/\u003cowner\u003e/\u003cname\u003e:\u003cunix time\u003e; other names need the images scope. An existing tag is only replaced when it is a snapshot by the same owner, otherwise 409 IMAGE_EXISTS.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sandboxes"
+                ],
+                "summary": "Snapshot a sandbox to an image",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sandbox ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Snapshot options",
+                        "name": "body",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.SnapshotRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.SnapshotResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sandboxes/{id}/start": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Start a stopped sandbox. Returns the port mappings and a fresh expiration timer. With wait_port=true, the response is sent once the main port accepts TCP connections or port_timeout passes, and reports which in port_ready.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sandboxes"
+                ],
+                "summary": "Start a sandbox",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sandbox ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Wait for the main port to accept connections before responding",
+                        "name": "wait_port",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Seconds to wait with wait_port (default 30, max 600)",
+                        "name": "port_timeout",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.RestartResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sandboxes/{id}/stats": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns a snapshot of CPU, memory and process usage for the sandbox, with the network traffic per interface and the block device bytes read and written since it started.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sandboxes"
+                ],
+                "summary": "Get container stats",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sandbox ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SandboxStats"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sandboxes/{id}/stop": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Gracefully stop a running sandbox.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sandboxes"
+                ],
+                "summary": "Stop a sandbox",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sandbox ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "status: stopped",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sandboxes/{id}/terminal": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Upgrades to a WebSocket attached to a TTY exec (default /bin/sh). Binary frames carry raw terminal bytes both ways. Text frames are JSON models.TerminalMessage: send {\"type\":\"input\",\"data\":\"ls\\n\"} or {\"type\":\"resize\",\"rows\":40,\"cols\":120}; the server sends {\"type\":\"exit\",\"exit_code\":0} when the shell exits. Browsers authenticate with the subprotocols [\"opensbx\", \"opensbx.bearer.\u003cbase64url key\u003e\"] and must open it from the API's host or an ALLOWED_ORIGINS origin.",
+                "tags": [
+                    "commands"
+                ],
+                "summary": "Open an interactive terminal",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sandbox ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Command to run, space separated (default /bin/sh)",
+                        "name": "cmd",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Initial terminal rows (default 24)",
+                        "name": "rows",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Initial terminal columns (default 80)",
+                        "name": "cols",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "101": {
+                        "description": "Switching Protocols"
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sandboxes/{id}/tunnels": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the tunnels open to a sandbox, oldest first.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tunnels"
+                ],
+                "summary": "List tunnels",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sandbox ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "tunnels: list of tunnels",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Opens a TCP tunnel to a port inside a running sandbox, such as postgres or redis, without publishing the port on the host. Connect to the returned url with a WebSocket, authenticated like any other request: each WebSocket connection is one TCP connection to the port, and binary frames carry its bytes both ways. The port may start listening later; a connection that cannot reach it fails with PORT_UNREACHABLE. Tunnels live in memory and end when the sandbox is deleted or the server restarts. ` + "`" + `osb tunnel` + "`" + ` forwards a local port through one.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tunnels"
+                ],
+                "summary": "Open a tunnel",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sandbox ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Port",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateTunnelRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Tunnel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sandboxes/{id}/tunnels/{tid}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Closes a tunnel and every connection open through it.",
+                "tags": [
+                    "tunnels"
+                ],
+                "summary": "Close a tunnel",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sandbox ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tunnel ID",
+                        "name": "tid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sandboxes/{id}/tunnels/{tid}/connect": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Dials the tunnel's port and upgrades to a WebSocket carrying the TCP connection: binary frames hold raw bytes both ways, text frames are ignored. The WebSocket closes when either side closes the connection. Browsers authenticate and are restricted by origin as for the terminal.",
+                "tags": [
+                    "tunnels"
+                ],
+                "summary": "Connect through a tunnel",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sandbox ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tunnel ID",
+                        "name": "tid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "101": {
+                        "description": "Switching Protocols"
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/schedules": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Lists the caller's schedules with their next and most recent run.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "schedules"
+                ],
+                "summary": "List schedules",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Schedule"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Creates a recurring job. On every match of the cron expression (UTC) a sandbox is created from the sandbox spec, runs the command and is deleted again; the exit code and the tail of the output are recorded as a run. A run is skipped while the previous one is still going, and runs missed while the server was down are not made up for.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "schedules"
+                ],
+                "summary": "Create a schedule",
+                "parameters": [
+                    {
+                        "description": "Schedule",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateScheduleRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Schedule"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/schedules/{name}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns a schedule with its next and most recent run.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "schedules"
+                ],
+                "summary": "Get a schedule",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Schedule name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Schedule"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Deletes a schedule and its recorded runs. A run in progress finishes and deletes its sandbox.",
+                "tags": [
+                    "schedules"
+                ],
+                "summary": "Delete a schedule",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Schedule name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/schedules/{name}/runs": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the last 20 runs of a schedule, newest first, with their status, exit code and the last 64 KiB of stdout and stderr.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "schedules"
+                ],
+                "summary": "List schedule runs",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Schedule name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.ScheduleRun"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/secrets": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Lists the caller's secrets by name. Values are never returned.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "secrets"
+                ],
+                "summary": "List secrets",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Secret"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/secrets/{name}": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Creates or replaces a secret, encrypted at rest. Reference it when creating a sandbox with env_from_secrets to set it as the environment variable of the same name. Secrets belong to the caller's owner. The value is never returned.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "secrets"
+                ],
+                "summary": "Store a secret",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Secret name, a valid environment variable name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Secret value",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.PutSecretRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Secret"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Deletes a secret. Sandboxes already created with it keep the value in their environment.",
+                "tags": [
+                    "secrets"
+                ],
+                "summary": "Delete a secret",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Secret name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/stacks": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Lists the caller's stacks with the state of their services.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "stacks"
+                ],
+                "summary": "List stacks",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Stack"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Creates a group of sandboxes from a compose-like spec, e.g. app + postgres + redis. The services share a private network on which each is reachable by its service name, and are started, stopped and deleted as a unit. The web service's sandbox is named after the stack, so the proxy routes the stack name to it; the others are named \"\u003cstack\u003e-\u003cservice\u003e\". If a service fails, the whole stack is rolled back.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "stacks"
+                ],
+                "summary": "Create a stack",
+                "parameters": [
+                    {
+                        "description": "Stack spec",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateStackRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Stack"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/stacks/{name}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns a stack and the state of its services.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "stacks"
+                ],
+                "summary": "Get a stack",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Stack name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Stack"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Removes every service of a stack, its network and the stack itself.",
+                "tags": [
+                    "stacks"
+                ],
+                "summary": "Delete a stack",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Stack name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/stacks/{name}/start": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Starts every stopped service of a stack, the web service last.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "stacks"
+                ],
+                "summary": "Start a stack",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Stack name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Stack"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/stacks/{name}/stop": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Stops every running service of a stack, the web service first.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "stacks"
+                ],
+                "summary": "Stop a stack",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Stack name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Stack"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/stats": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns sandbox counts by state, the memory (MB) and CPUs allocated to and used by running sandboxes, and the host capacity. Scoped keys only count their own sandboxes.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sandboxes"
+                ],
+                "summary": "Get node stats",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.NodeStats"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "internal_api.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "enum": [
+                        "BAD_REQUEST",
+                        "UNAUTHORIZED",
+                        "FORBIDDEN",
+                        "NOT_FOUND",
+                        "TIMEOUT",
+                        "RATE_LIMITED",
+                        "QUOTA_EXCEEDED",
+                        "INTERNAL_ERROR",
+                        "SANDBOX_NOT_FOUND",
+                        "SANDBOX_NAME_TAKEN",
+                        "IMAGE_NOT_FOUND",
+                        "IMAGE_EXISTS",
+                        "INVALID_REFERENCE",
+                        "ALREADY_RUNNING",
+                        "ALREADY_STOPPED",
+                        "ALREADY_PAUSED",
+                        "NOT_PAUSED",
+                        "OOM_KILLED",
+                        "NOT_RUNNING",
+                        "OPERATION_IN_PROGRESS",
+                        "LIFETIME_EXCEEDED",
+                        "IDEMPOTENCY_KEY_REUSED",
+                        "IDEMPOTENCY_IN_PROGRESS",
+                        "COMMAND_NOT_FOUND",
+                        "COMMAND_FINISHED",
+                        "PROCESS_NOT_FOUND",
+                        "PROCESS_EXISTS",
+                        "SESSION_NOT_FOUND",
+                        "SESSION_BUSY",
+                        "INTERPRETER_UNAVAILABLE",
+                        "TUNNEL_NOT_FOUND",
+                        "PORT_UNREACHABLE",
+                        "GIT_FAILED",
+                        "HOOK_FAILED",
+                        "PATH_NOT_FOUND",
+                        "NOT_A_DIRECTORY",
+                        "NOT_A_FILE",
+                        "INVALID_ARCHIVE",
+                        "INVALID_BUNDLE",
+                        "CHECKPOINT_NOT_FOUND",
+                        "INVALID_CHECKPOINT",
+                        "INVALID_DOMAIN",
+                        "INVALID_ENV",
+                        "DOMAIN_TAKEN",
+                        "DOMAIN_NOT_FOUND",
+                        "RUNTIME_NOT_FOUND",
+                        "UNSUPPORTED_BY_ENGINE",
+                        "NETWORK_POLICY_UNSUPPORTED",
+                        "POLICY_VIOLATION",
+                        "STACK_NOT_FOUND",
+                        "STACK_EXISTS",
+                        "NETWORK_NOT_FOUND",
+                        "NETWORK_EXISTS",
+                        "NETWORK_IN_USE",
+                        "SCHEDULE_NOT_FOUND",
+                        "SCHEDULE_EXISTS",
+                        "SECRETS_DISABLED",
+                        "SECRET_NOT_FOUND",
+                        "INVALID_SECRET_NAME",
+                        "API_KEY_NOT_FOUND",
+                        "INVALID_SCOPE"
+                    ],
+                    "example": "BAD_REQUEST"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "image is required"
+                }
+            }
+        },
+        "models.APIKey": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "key_1a2b3c4d5e6f"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "ci"
+                },
+                "owner": {
+                    "description": "tenant whose sandboxes the key can access (admin keys see all)",
+                    "type": "string",
+                    "example": "team-a"
+                },
+                "prefix": {
+                    "description": "first characters of the key, to recognise it",
+                    "type": "string",
+                    "example": "osbx_1a2b3c4"
+                },
+                "quota": {
+                    "description": "limits for the key's owner, nil = only global limits",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Quota"
+                        }
+                    ]
+                },
+                "revoked_at": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "read-only",
+                        "exec"
+                    ]
+                }
+            }
+        },
+        "models.AddDomainRequest": {
+            "type": "object",
+            "required": [
+                "domain"
+            ],
+            "properties": {
+                "domain": {
+                    "description": "hostname whose DNS points at the proxy",
+                    "type": "string",
+                    "example": "app.example.com"
+                }
+            }
+        },
+        "models.ApplyRequest": {
+            "type": "object",
+            "properties": {
+                "prune": {
+                    "description": "delete apply-managed sandboxes missing from the spec",
+                    "type": "boolean"
+                },
+                "sandboxes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ApplySandboxSpec"
+                    }
+                }
+            }
+        },
+        "models.ApplyResponse": {
+            "type": "object",
+            "properties": {
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ApplyResult"
+                    }
+                }
+            }
+        },
+        "models.ApplyResult": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "description": "\"created\", \"updated\", \"unchanged\", \"deleted\" or \"failed\"",
+                    "type": "string"
+                },
+                "error": {
+                    "description": "set when action is \"failed\"",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ApplySandboxSpec": {
+            "type": "object",
+            "required": [
+                "image",
+                "name"
+            ],
+            "properties": {
+                "bandwidth": {
+                    "$ref": "#/definitions/models.BandwidthLimit"
+                },
+                "cmd": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "egress": {
+                    "$ref": "#/definitions/models.EgressPolicy"
+                },
+                "entrypoint": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "env": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "env_from_secrets": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "expiration_action": {
+                    "type": "string",
+                    "enum": [
+                        "stop",
+                        "delete"
+                    ]
+                },
+                "files": {
+                    "description": "files written after the sandbox starts",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.SeedFile"
+                    }
+                },
+                "hooks": {
+                    "$ref": "#/definitions/models.Hooks"
+                },
+                "image": {
+                    "type": "string",
+                    "example": "node:24"
+                },
+                "labels": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "max_lifetime": {
+                    "type": "integer"
+                },
+                "name": {
+                    "description": "sandbox name and subdomain: lowercase letters, digits and single hyphens, max 63",
+                    "type": "string",
+                    "example": "web"
+                },
+                "network": {
+                    "type": "string",
+                    "enum": [
+                        "bridge",
+                        "internal",
+                        "none"
+                    ]
+                },
+                "network_group": {
+                    "type": "string"
+                },
+                "ports": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "3000"
+                    ]
+                },
+                "processes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.StartProcessRequest"
+                    }
+                },
+                "ready_check": {
+                    "$ref": "#/definitions/models.ReadyCheck"
+                },
+                "resources": {
+                    "$ref": "#/definitions/models.ResourceLimits"
+                },
+                "runtime": {
+                    "type": "string"
+                },
+                "security": {
+                    "$ref": "#/definitions/models.SecurityOptions"
+                },
+                "timeout": {
+                    "description": "seconds until auto-stop, 0 = default (900s)",
+                    "type": "integer",
+                    "example": 900
+                },
+                "timeout_mode": {
+                    "type": "string",
+                    "enum": [
+                        "absolute",
+                        "idle"
+                    ]
+                },
+                "wake_on_request": {
+                    "type": "boolean"
+                },
+                "working_dir": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.AuditEvent": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "description": "e.g. sandbox.create, command.exec, file.write",
+                    "type": "string",
+                    "example": "sandbox.create"
+                },
+                "actor": {
+                    "description": "X-Opensbx-Actor header, if sent",
+                    "type": "string",
+                    "example": "alice"
+                },
+                "auth": {
+                    "description": "\"api_key\", \"signature\" or \"none\"",
+                    "type": "string",
+                    "example": "api_key"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 42
+                },
+                "key_id": {
+                    "description": "stored API key used, empty for API_KEY and signatures",
+                    "type": "string",
+                    "example": "key_1a2b3c4d5e6f"
+                },
+                "method": {
+                    "type": "string",
+                    "example": "POST"
+                },
+                "owner": {
+                    "description": "owner the caller was restricted to",
+                    "type": "string",
+                    "example": "team-a"
+                },
+                "path": {
+                    "type": "string",
+                    "example": "/v1/sandboxes/a1b2c3d4e5f6/stop"
+                },
+                "request_id": {
+                    "description": "X-Request-ID, to correlate with logs",
+                    "type": "string",
+                    "example": "req_1a2b3c4d5e6f7a8b"
+                },
+                "sandbox_id": {
+                    "description": "full container ID of the sandbox",
+                    "type": "string",
+                    "example": "a1b2c3d4e5f6"
+                },
+                "status": {
+                    "description": "HTTP status of the response",
+                    "type": "integer",
+                    "example": 200
+                },
+                "time": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.BandwidthLimit": {
+            "type": "object",
+            "properties": {
+                "egress_kbps": {
+                    "description": "upload",
+                    "type": "integer",
+                    "example": 2000
+                },
+                "ingress_kbps": {
+                    "description": "download",
+                    "type": "integer",
+                    "example": 10000
+                }
+            }
+        },
+        "models.BatchCommandRequest": {
+            "type": "object",
+            "required": [
+                "commands"
+            ],
+            "properties": {
+                "commands": {
+                    "description": "run in order, each after the previous one finished",
+                    "type": "array",
+                    "maxItems": 100,
+                    "minItems": 1,
+                    "items": {
+                        "$ref": "#/definitions/models.ExecCommandRequest"
+                    }
+                },
+                "stop_on_error": {
+                    "description": "skip the remaining commands after a non-zero exit",
+                    "type": "boolean",
+                    "example": true
+                }
+            }
+        },
+        "models.BatchCommandResponse": {
+            "type": "object",
+            "properties": {
+                "steps": {
+                    "description": "results of the commands that ran",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.BatchStep"
+                    }
+                },
+                "success": {
+                    "description": "every command ran and exited 0",
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.BatchStep": {
+            "type": "object",
+            "properties": {
+                "command": {
+                    "description": "finished command",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.CommandDetail"
+                        }
+                    ]
+                },
+                "error": {
+                    "description": "set when the command could not be run; the batch stops",
+                    "type": "string"
+                },
+                "index": {
+                    "description": "position in the request's commands",
+                    "type": "integer"
+                },
+                "stderr": {
+                    "description": "captured stderr text",
+                    "type": "string"
+                },
+                "stdout": {
+                    "description": "captured stdout text",
+                    "type": "string"
+                }
+            }
+        },
+        "models.BlockIOUsage": {
+            "type": "object",
+            "properties": {
+                "read_bytes": {
+                    "type": "integer"
+                },
+                "write_bytes": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Checkpoint": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.CheckpointRequest": {
+            "type": "object",
+            "properties": {
+                "leave_running": {
+                    "description": "keep the sandbox running, default stops it once its state is saved",
+                    "type": "boolean"
+                },
+                "name": {
+                    "description": "checkpoint name, empty = cp-\u003cunix time\u003e",
+                    "type": "string",
+                    "example": "before-migration"
+                }
+            }
+        },
+        "models.CloneRequest": {
+            "type": "object",
+            "properties": {
+                "filesystem": {
+                    "description": "copy the source's filesystem (default); false = start from its original image",
+                    "type": "boolean",
+                    "example": true
+                },
+                "name": {
+                    "description": "name of the clone, empty = generated",
+                    "type": "string",
+                    "example": "my-app-fork"
+                }
+            }
+        },
+        "models.CommandDetail": {
+            "type": "object",
+            "properties": {
+                "args": {
+                    "description": "arguments",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "cwd": {
+                    "description": "working directory",
+                    "type": "string"
+                },
+                "exit_code": {
+                    "description": "nil while running",
+                    "type": "integer"
+                },
+                "finished_at": {
+                    "description": "unix milliseconds, nil while running",
+                    "type": "integer"
+                },
+                "id": {
+                    "description": "cmd_\u003chex\u003e",
+                    "type": "string"
+                },
+                "name": {
+                    "description": "executable name",
+                    "type": "string"
+                },
+                "sandbox_id": {
+                    "description": "parent sandbox container ID",
+                    "type": "string"
+                },
+                "started_at": {
+                    "description": "unix milliseconds",
+                    "type": "integer"
+                },
+                "timed_out": {
+                    "description": "killed after exceeding its timeout",
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.CommandEvent": {
+            "type": "object",
+            "properties": {
+                "command": {
+                    "description": "for start and exit",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.CommandDetail"
+                        }
+                    ]
+                },
+                "data": {
+                    "description": "output line, for stdout and stderr",
+                    "type": "string"
+                },
+                "exit_code": {
+                    "description": "for exit",
+                    "type": "integer"
+                },
+                "type": {
+                    "type": "string",
+                    "enum": [
+                        "start",
+                        "stdout",
+                        "stderr",
+                        "exit",
+                        "ping"
+                    ]
+                }
+            }
+        },
+        "models.CommandListResponse": {
+            "type": "object",
+            "properties": {
+                "commands": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.CommandDetail"
+                    }
+                },
+                "total": {
+                    "description": "commands in the sandbox, before limit and offset",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.CommandLogsResponse": {
+            "type": "object",
+            "properties": {
+                "exit_code": {
+                    "description": "nil while command is still running",
+                    "type": "integer"
+                },
+                "stderr": {
+                    "description": "captured stderr text",
+                    "type": "string"
+                },
+                "stdout": {
+                    "description": "captured stdout text",
+                    "type": "string"
+                }
+            }
+        },
+        "models.CommandResponse": {
+            "type": "object",
+            "properties": {
+                "command": {
+                    "$ref": "#/definitions/models.CommandDetail"
+                }
+            }
+        },
+        "models.ComponentHealth": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "latency_ms": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "status": {
+                    "description": "healthy or unhealthy",
+                    "type": "string",
+                    "example": "healthy"
+                }
+            }
+        },
+        "models.CreateAPIKeyRequest": {
+            "type": "object",
+            "required": [
+                "name",
+                "scopes"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "example": "ci"
+                },
+                "owner": {
+                    "description": "keys with the same owner share sandboxes; empty = a new owner for this key",
+                    "type": "string",
+                    "example": "team-a"
+                },
+                "quota": {
+                    "description": "limits for the key's owner, nil = only global limits",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Quota"
+                        }
+                    ]
+                },
+                "scopes": {
+                    "description": "read-only, exec, images, admin",
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "read-only",
+                        "exec"
+                    ]
+                }
+            }
+        },
+        "models.CreateAPIKeyResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "key_1a2b3c4d5e6f"
+                },
+                "key": {
+                    "description": "the secret, shown only once",
+                    "type": "string",
+                    "example": "osbx_1a2b3c4d5e6f..."
+                },
+                "name": {
+                    "type": "string",
+                    "example": "ci"
+                },
+                "owner": {
+                    "description": "tenant whose sandboxes the key can access (admin keys see all)",
+                    "type": "string",
+                    "example": "team-a"
+                },
+                "prefix": {
+                    "description": "first characters of the key, to recognise it",
+                    "type": "string",
+                    "example": "osbx_1a2b3c4"
+                },
+                "quota": {
+                    "description": "limits for the key's owner, nil = only global limits",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Quota"
+                        }
+                    ]
+                },
+                "revoked_at": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "read-only",
+                        "exec"
+                    ]
+                }
+            }
+        },
+        "models.CreateNetworkRequest": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "name": {
+                    "description": "lowercase letters, digits and single hyphens, max 63",
+                    "type": "string",
+                    "example": "backend"
+                }
+            }
+        },
+        "models.CreateSandboxRequest": {
+            "type": "object",
+            "required": [
+                "image"
+            ],
+            "properties": {
+                "bandwidth": {
+                    "description": "network throughput limits",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.BandwidthLimit"
+                        }
+                    ]
+                },
+                "cmd": {
+                    "description": "startup command, empty = keep alive with \"sleep infinity\" (or the entrypoint alone)",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "npm",
+                        "run",
+                        "dev"
+                    ]
+                },
+                "egress": {
+                    "description": "outbound allow/deny rules, bridge network only",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.EgressPolicy"
+                        }
+                    ]
+                },
+                "entrypoint": {
+                    "description": "override the image entrypoint",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "env": {
+                    "description": "extra environment variables (e.g. [\"KEY=VALUE\"])",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "env_from_secrets": {
+                    "description": "stored secrets set as environment variables of the same name; env wins on conflicts",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "OPENAI_API_KEY"
+                    ]
+                },
+                "expiration_action": {
+                    "description": "on timeout: \"stop\" (default) or \"delete\" (removed once stopped past the reap grace period)",
+                    "type": "string",
+                    "enum": [
+                        "stop",
+                        "delete"
+                    ],
+                    "example": "stop"
+                },
+                "git": {
+                    "description": "repository cloned into the sandbox before processes start; create fails if the clone does",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.GitSource"
+                        }
+                    ]
+                },
+                "hooks": {
+                    "description": "commands run inside the sandbox on create, on every start and before every stop",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Hooks"
+                        }
+                    ]
+                },
+                "image": {
+                    "type": "string",
+                    "example": "node:24"
+                },
+                "labels": {
+                    "description": "caller-defined tags, e.g. {\"team\": \"ml\"}; also set as Docker labels. Keys under \"opensbx.\" are reserved",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "max_lifetime": {
+                    "description": "seconds after creation when the sandbox is stopped (or deleted with expiration_action \"delete\") whatever its renewals and activity, and cannot be started again. 0 = the server cap, if any; longer values are lowered to it",
+                    "type": "integer",
+                    "example": 86400
+                },
+                "name": {
+                    "description": "sandbox name and subdomain: lowercase letters, digits and single hyphens, max 63. Empty = generated",
+                    "type": "string",
+                    "example": "my-app"
+                },
+                "network": {
+                    "description": "\"bridge\" (default): outbound access; \"internal\": no outbound access, reaches other \"internal\" sandboxes only; \"none\": loopback only",
+                    "type": "string",
+                    "enum": [
+                        "bridge",
+                        "internal",
+                        "none"
+                    ],
+                    "example": "bridge"
+                },
+                "network_group": {
+                    "description": "shared network created with POST /v1/networks; sandboxes on it reach each other by sandbox name. Not with network \"none\"",
+                    "type": "string",
+                    "example": "backend"
+                },
+                "ports": {
+                    "description": "container ports to expose, e.g. [\"3000\", \"8080/tcp\"]. First port is the default for proxy routing.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "3000",
+                        "8080"
+                    ]
+                },
+                "processes": {
+                    "description": "supervised processes started with the sandbox and again after every start and restart, as with POST /processes",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.StartProcessRequest"
+                    }
+                },
+                "ready_check": {
+                    "description": "how to tell the app inside is serving; the proxy shows a \"starting\" page until it passes",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.ReadyCheck"
+                        }
+                    ]
+                },
+                "resources": {
+                    "description": "CPU/memory limits, nil = defaults (1GB RAM, 1 vCPU)",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.ResourceLimits"
+                        }
+                    ]
+                },
+                "runtime": {
+                    "description": "OCI runtime, e.g. \"runsc\" (gVisor) or \"kata\"; must be configured on the worker. Empty = server default",
+                    "type": "string",
+                    "example": "runsc"
+                },
+                "security": {
+                    "description": "container hardening on top of the server defaults",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.SecurityOptions"
+                        }
+                    ]
+                },
+                "timeout": {
+                    "description": "seconds until auto-stop, 0 = default (900s)",
+                    "type": "integer",
+                    "example": 900
+                },
+                "timeout_mode": {
+                    "description": "\"absolute\" (default) or \"idle\": timeout restarts on exec, file and proxy activity",
+                    "type": "string",
+                    "enum": [
+                        "absolute",
+                        "idle"
+                    ],
+                    "example": "idle"
+                },
+                "wake_on_request": {
+                    "description": "start the sandbox when the proxy gets a request while it is stopped or expired",
+                    "type": "boolean"
+                },
+                "working_dir": {
+                    "description": "working directory for the startup command",
+                    "type": "string",
+                    "example": "/app"
+                }
+            }
+        },
+        "models.CreateSandboxResponse": {
+            "type": "object",
+            "properties": {
+                "git": {
+                    "description": "the clone of the git option",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.GitStatus"
+                        }
+                    ]
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "description": "auto-generated name (e.g. \"eager-turing\")",
+                    "type": "string"
+                },
+                "ports": {
+                    "description": "exposed container ports, e.g. [\"3000/tcp\", \"8080/tcp\"]",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "ready": {
+                    "description": "ready check result, only with ?wait_ready=true",
+                    "type": "string",
+                    "enum": [
+                        "ready",
+                        "timeout"
+                    ]
+                },
+                "url": {
+                    "description": "proxy URL, e.g. \"http://eager-turing.localhost\"",
+                    "type": "string"
+                }
+            }
+        },
+        "models.CreateScheduleRequest": {
+            "type": "object",
+            "required": [
+                "cron",
+                "name"
+            ],
+            "properties": {
+                "command": {
+                    "description": "command each run executes; its timeout defaults to 1 hour",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.ExecCommandRequest"
+                        }
+                    ]
+                },
+                "cron": {
+                    "description": "five-field cron expression or @hourly, @daily, @weekly, @monthly, @yearly; evaluated in UTC",
+                    "type": "string",
+                    "example": "0 3 * * *"
+                },
+                "name": {
+                    "description": "lowercase letters, digits and single hyphens, max 63",
+                    "type": "string",
+                    "example": "nightly-tests"
+                },
+                "sandbox": {
+                    "description": "sandbox created for every run; its name must be empty",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.CreateSandboxRequest"
+                        }
+                    ]
+                }
+            }
+        },
+        "models.CreateSessionRequest": {
+            "type": "object",
+            "required": [
+                "language"
+            ],
+            "properties": {
+                "cwd": {
+                    "description": "working directory of the interpreter",
+                    "type": "string",
+                    "example": "/app"
+                },
+                "env": {
+                    "description": "extra environment variables",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "language": {
+                    "description": "interpreter to start; the sandbox image must provide python3 or node",
+                    "type": "string",
+                    "enum": [
+                        "python",
+                        "node"
+                    ],
+                    "example": "python"
+                }
+            }
+        },
+        "models.CreateStackRequest": {
+            "type": "object",
+            "required": [
+                "name",
+                "services"
+            ],
+            "properties": {
+                "name": {
+                    "description": "stack name; also the name of the web service's sandbox. The others are named \"\u003cstack\u003e-\u003cservice\u003e\"",
+                    "type": "string",
+                    "example": "shop"
+                },
+                "services": {
+                    "description": "services by name, e.g. \"app\", \"db\"; each reaches the others by service name. Their name and network fields must be empty",
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/models.CreateSandboxRequest"
+                    }
+                },
+                "web": {
+                    "description": "service the proxy routes the stack name to; empty = the only service with ports, if there is one",
+                    "type": "string",
+                    "example": "app"
+                }
+            }
+        },
+        "models.CreateTunnelRequest": {
+            "type": "object",
+            "required": [
+                "port"
+            ],
+            "properties": {
+                "port": {
+                    "description": "TCP port inside the sandbox; it does not need to be published",
+                    "type": "integer",
+                    "maximum": 65535,
+                    "minimum": 1,
+                    "example": 5432
+                }
+            }
+        },
+        "models.EgressPolicy": {
+            "type": "object",
+            "properties": {
+                "allow": {
+                    "description": "only these destinations are reachable",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "140.82.112.0/20",
+                        ":443"
+                    ]
+                },
+                "deny": {
+                    "description": "these destinations are blocked; cannot be combined with allow",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "10.0.0.0/8"
+                    ]
+                }
+            }
+        },
+        "models.ExecCommandRequest": {
+            "type": "object",
+            "required": [
+                "command"
+            ],
+            "properties": {
+                "args": {
+                    "description": "arguments (e.g. [\"install\"])",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "install"
+                    ]
+                },
+                "command": {
+                    "description": "executable name (e.g. \"npm\")",
+                    "type": "string",
+                    "example": "npm"
+                },
+                "cwd": {
+                    "description": "working directory",
+                    "type": "string",
+                    "example": "/app"
+                },
+                "env": {
+                    "description": "extra environment variables",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "inherit_env": {
+                    "description": "see the container's environment (default true); false runs with only env and a default PATH",
+                    "type": "boolean",
+                    "example": true
+                },
+                "stdin": {
+                    "description": "written to the command's stdin, which is then closed",
+                    "type": "string",
+                    "example": "SELECT 1;\n"
+                },
+                "timeout": {
+                    "description": "seconds before the command is killed, 0 = no limit",
+                    "type": "integer",
+                    "example": 300
+                },
+                "user": {
+                    "description": "run as this user: name, uid, or user:group / uid:gid. Default: the container's user",
+                    "type": "string",
+                    "example": "1000:1000"
+                }
+            }
+        },
+        "models.ExecuteRequest": {
+            "type": "object",
+            "required": [
+                "code"
+            ],
+            "properties": {
+                "code": {
+                    "description": "cell source; in Python the value of a trailing expression is returned as a result event",
+                    "type": "string",
+                    "example": "x = 21\nx * 2"
+                },
+                "timeout": {
+                    "description": "seconds before the cell is interrupted, 0 = no limit",
+                    "type": "integer",
+                    "example": 60
+                }
+            }
+        },
+        "models.FileListResponse": {
+            "type": "object",
+            "properties": {
+                "output": {
+                    "type": "string"
+                },
+                "path": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.FileNode": {
+            "type": "object",
+            "properties": {
+                "children": {
+                    "description": "directory contents, up to the requested depth",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.FileNode"
+                    }
+                },
+                "name": {
+                    "type": "string",
+                    "example": "src"
+                },
+                "path": {
+                    "type": "string",
+                    "example": "/app/src"
+                },
+                "type": {
+                    "type": "string",
+                    "enum": [
+                        "dir",
+                        "file",
+                        "symlink"
+                    ],
+                    "example": "dir"
+                }
+            }
+        },
+        "models.FileReadResponse": {
+            "type": "object",
+            "properties": {
+                "content": {
+                    "type": "string"
+                },
+                "encoding": {
+                    "description": "\"base64\" when requested with ?encoding=base64",
+                    "type": "string"
+                },
+                "path": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.FileSearchResponse": {
+            "type": "object",
+            "properties": {
+                "glob": {
+                    "type": "string",
+                    "example": "**/*.ts"
+                },
+                "matches": {
+                    "description": "matching entries, without children",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.FileNode"
+                    }
+                },
+                "path": {
+                    "type": "string"
+                },
+                "truncated": {
+                    "description": "the result or scan limit was reached; some matches may be missing",
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.FileStat": {
+            "type": "object",
+            "properties": {
+                "link_target": {
+                    "description": "symlink target, for symlinks only",
+                    "type": "string"
+                },
+                "mode": {
+                    "description": "permission bits in octal",
+                    "type": "string",
+                    "example": "0644"
+                },
+                "mtime": {
+                    "description": "last modification",
+                    "type": "string",
+                    "example": "2025-01-01T00:00:00Z"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "package.json"
+                },
+                "path": {
+                    "type": "string",
+                    "example": "/app/package.json"
+                },
+                "permissions": {
+                    "description": "ls-style mode string",
+                    "type": "string",
+                    "example": "-rw-r--r--"
+                },
+                "size": {
+                    "description": "bytes",
+                    "type": "integer",
+                    "example": 512
+                },
+                "type": {
+                    "type": "string",
+                    "enum": [
+                        "dir",
+                        "file",
+                        "symlink",
+                        "other"
+                    ],
+                    "example": "file"
+                }
+            }
+        },
+        "models.FileWriteRequest": {
+            "type": "object",
+            "required": [
+                "content"
+            ],
+            "properties": {
+                "content": {
+                    "type": "string",
+                    "example": "console.log('hello')"
+                },
+                "encoding": {
+                    "description": "\"base64\" if content is base64-encoded",
+                    "type": "string",
+                    "example": ""
+                }
+            }
+        },
+        "models.GitPullRequest": {
+            "type": "object",
+            "properties": {
+                "dir": {
+                    "description": "repository directory, default the directory git cloned into on create",
+                    "type": "string",
+                    "example": "/app"
+                },
+                "ssh_key": {
+                    "description": "private deploy key, as on create",
+                    "type": "string"
+                },
+                "token": {
+                    "description": "HTTPS access token, as on create",
+                    "type": "string"
+                }
+            }
+        },
+        "models.GitSource": {
+            "type": "object",
+            "required": [
+                "url"
+            ],
+            "properties": {
+                "depth": {
+                    "description": "commits of history to fetch, 0 = all. Ignored when ref is a commit",
+                    "type": "integer",
+                    "example": 1
+                },
+                "dir": {
+                    "description": "absolute directory to clone into, default working_dir or /workspace; must be empty or missing",
+                    "type": "string",
+                    "example": "/app"
+                },
+                "ref": {
+                    "description": "branch, tag or commit to check out, empty = the default branch",
+                    "type": "string",
+                    "example": "main"
+                },
+                "ssh_key": {
+                    "description": "private deploy key for ssh URLs; written to a temporary file for the clone only",
+                    "type": "string"
+                },
+                "token": {
+                    "description": "HTTPS access token, e.g. a GitHub personal access token; sent as a header, never stored in the sandbox",
+                    "type": "string"
+                },
+                "url": {
+                    "description": "https://, ssh:// or git@host:path; put credentials in token or ssh_key, not in the URL",
+                    "type": "string",
+                    "example": "https://github.com/acme/web.git"
+                }
+            }
+        },
+        "models.GitStatus": {
+            "type": "object",
+            "properties": {
+                "branch": {
+                    "description": "checked-out branch, empty when HEAD is detached (e.g. a tag or commit ref)",
+                    "type": "string",
+                    "example": "main"
+                },
+                "commit": {
+                    "description": "checked-out commit",
+                    "type": "string",
+                    "example": "9fceb02d0ae598e95dc970b74767f19372d61af8"
+                },
+                "dir": {
+                    "type": "string",
+                    "example": "/app"
+                }
+            }
+        },
+        "models.Health": {
+            "type": "object",
+            "properties": {
+                "components": {
+                    "description": "by name: docker, database, proxy",
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/models.ComponentHealth"
+                    }
+                },
+                "status": {
+                    "description": "healthy (or ready) when every component is, otherwise unhealthy (or not_ready)",
+                    "type": "string",
+                    "example": "healthy"
+                },
+                "versions": {
+                    "description": "api, go and docker; only on /v1/health",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.Hook": {
+            "type": "object",
+            "required": [
+                "command"
+            ],
+            "properties": {
+                "command": {
+                    "description": "executable and arguments",
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "sh",
+                        "-c",
+                        "npm ci"
+                    ]
+                },
+                "cwd": {
+                    "description": "working directory, default the sandbox's",
+                    "type": "string",
+                    "example": "/app"
+                },
+                "timeout": {
+                    "description": "seconds before the hook is killed and counted as failed, 0 = default (300s), max 3600",
+                    "type": "integer",
+                    "example": 300
+                }
+            }
+        },
+        "models.Hooks": {
+            "type": "object",
+            "properties": {
+                "before_stop": {
+                    "description": "before every stop and restart through the API or the timeout; the stop goes ahead if it fails",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Hook"
+                        }
+                    ]
+                },
+                "on_create": {
+                    "description": "once, after the git clone and before processes start; create fails if it does",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Hook"
+                        }
+                    ]
+                },
+                "on_start": {
+                    "description": "after every start and restart, the first one included, before processes start",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Hook"
+                        }
+                    ]
+                }
+            }
+        },
+        "models.HostPolicy": {
+            "type": "object",
+            "properties": {
+                "allowed_devices": {
+                    "description": "host devices sandboxes may map",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "allowed_runtimes": {
+                    "description": "OCI runtimes sandboxes may use, empty = any runtime the worker has",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "cap_add": {
+                    "description": "adding linux capabilities",
+                    "type": "boolean"
+                },
+                "gpus": {
+                    "description": "GPU passthrough",
+                    "type": "boolean"
+                },
+                "host_ipc": {
+                    "description": "sharing the host ipc namespace",
+                    "type": "boolean"
+                },
+                "host_mounts": {
+                    "description": "bind mounts from the host filesystem",
+                    "type": "boolean"
+                },
+                "host_network": {
+                    "description": "sharing the host network namespace",
+                    "type": "boolean"
+                },
+                "host_pid": {
+                    "description": "sharing the host pid namespace",
+                    "type": "boolean"
+                },
+                "host_uts": {
+                    "description": "sharing the host uts namespace",
+                    "type": "boolean"
+                },
+                "privileged": {
+                    "description": "privileged containers",
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.ImageDetail": {
+            "type": "object",
+            "properties": {
+                "architecture": {
+                    "description": "e.g. \"amd64\"",
+                    "type": "string"
+                },
+                "created": {
+                    "description": "RFC3339",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "os": {
+                    "description": "e.g. \"linux\"",
+                    "type": "string"
+                },
+                "size": {
+                    "description": "bytes",
+                    "type": "integer"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.ImagePruneResponse": {
+            "type": "object",
+            "properties": {
+                "deleted": {
+                    "description": "removed images",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ImageSummary"
+                    }
+                },
+                "space_reclaimed": {
+                    "description": "bytes freed on disk",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ImagePullRequest": {
+            "type": "object",
+            "required": [
+                "image"
+            ],
+            "properties": {
+                "image": {
+                    "description": "image name with optional tag (e.g. \"nginx:latest\")",
+                    "type": "string",
+                    "example": "node:22"
+                }
+            }
+        },
+        "models.ImagePullResponse": {
+            "type": "object",
+            "properties": {
+                "image": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ImageSummary": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "size": {
+                    "description": "bytes",
+                    "type": "integer"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.JobRequest": {
+            "type": "object",
+            "properties": {
+                "command": {
+                    "description": "command to run; its timeout defaults to 1 hour",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.ExecCommandRequest"
+                        }
+                    ]
+                },
+                "sandbox": {
+                    "description": "sandbox to run the command in; expiration_action is always \"delete\"",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.CreateSandboxRequest"
+                        }
+                    ]
+                }
+            }
+        },
+        "models.JobResult": {
+            "type": "object",
+            "properties": {
+                "exit_code": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "finished_at": {
+                    "description": "when the command exited",
+                    "type": "string"
+                },
+                "sandbox_id": {
+                    "description": "sandbox the job ran in, already deleted",
+                    "type": "string"
+                },
+                "started_at": {
+                    "description": "when the sandbox was created",
+                    "type": "string"
+                },
+                "stderr": {
+                    "description": "captured stderr, the last 1 MB at most",
+                    "type": "string"
+                },
+                "stdout": {
+                    "description": "captured stdout, the last 1 MB at most",
+                    "type": "string"
+                },
+                "timed_out": {
+                    "description": "the command was killed after exceeding its timeout",
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.KillCommandRequest": {
+            "type": "object",
+            "required": [
+                "signal"
+            ],
+            "properties": {
+                "signal": {
+                    "description": "signal name (\"SIGTERM\", \"KILL\") or POSIX number (15, 9)",
+                    "type": "string",
+                    "example": "SIGTERM"
+                }
+            }
+        },
+        "models.MemoryUsage": {
+            "type": "object",
+            "properties": {
+                "limit": {
+                    "description": "bytes limit",
+                    "type": "integer"
                 },
-                "message": {
+                "percent": {
+                    "description": "usage / limit * 100",
+                    "type": "number"
+                },
+                "usage": {
+                    "description": "bytes currently used",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.NetworkGroup": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "name": {
                     "type": "string",
-                    "example": "image is required"
+                    "example": "backend"
+                },
+                "network": {
+                    "description": "backing Docker network",
+                    "type": "string",
+                    "example": "opensbx-net-backend"
+                },
+                "sandboxes": {
+                    "description": "names of the sandboxes attached to it",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "app",
+                        "db"
+                    ]
                 }
             }
         },
-        "models.CommandDetail": {
+        "models.NetworkIsolation": {
+            "type": "object",
+            "properties": {
+                "driver": {
+                    "type": "string"
+                },
+                "inter_container": {
+                    "description": "inter-container communication enabled on this network",
+                    "type": "boolean"
+                },
+                "internal": {
+                    "description": "network has no outbound connectivity",
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "peers": {
+                    "description": "other containers attached to this network",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.NetworkUsage": {
+            "type": "object",
+            "properties": {
+                "rx_bytes": {
+                    "type": "integer"
+                },
+                "rx_packets": {
+                    "type": "integer"
+                },
+                "tx_bytes": {
+                    "type": "integer"
+                },
+                "tx_packets": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.NodeStats": {
+            "type": "object",
+            "properties": {
+                "allocated": {
+                    "description": "summed limits of running sandboxes",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.ResourceTotals"
+                        }
+                    ]
+                },
+                "capacity": {
+                    "description": "host memory and CPUs",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.ResourceTotals"
+                        }
+                    ]
+                },
+                "sandboxes": {
+                    "description": "sandboxes by container state",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.SandboxCounts"
+                        }
+                    ]
+                },
+                "used": {
+                    "description": "live usage of running sandboxes",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.ResourceTotals"
+                        }
+                    ]
+                }
+            }
+        },
+        "models.ProcessDetail": {
             "type": "object",
             "properties": {
                 "args": {
-                    "description": "arguments",
                     "type": "array",
                     "items": {
                         "type": "string"
                     }
                 },
+                "command": {
+                    "type": "string",
+                    "example": "npm"
+                },
+                "command_id": {
+                    "description": "current or last run; read its output via /cmd/:cmdId/logs",
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
                 "cwd": {
-                    "description": "working directory",
                     "type": "string"
                 },
-                "exit_code": {
-                    "description": "nil while running",
-                    "type": "integer"
+                "env": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
                 },
-                "finished_at": {
-                    "description": "unix milliseconds, nil while running",
+                "name": {
+                    "type": "string",
+                    "example": "web"
+                },
+                "restart": {
+                    "type": "string",
+                    "example": "on-failure"
+                },
+                "restarts": {
+                    "description": "restarts since the process was last (re)started",
                     "type": "integer"
                 },
-                "id": {
-                    "description": "cmd_\u003chex\u003e",
+                "sandbox_id": {
                     "type": "string"
                 },
-                "name": {
-                    "description": "executable name",
+                "status": {
+                    "description": "stopped = not supervised until the sandbox is next started",
+                    "type": "string",
+                    "enum": [
+                        "running",
+                        "backoff",
+                        "exited",
+                        "stopped"
+                    ],
+                    "example": "running"
+                },
+                "user": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ProcessInfo": {
+            "type": "object",
+            "properties": {
+                "command": {
+                    "type": "string",
+                    "example": "node server.js"
+                },
+                "cpu": {
+                    "description": "CPU usage in percent",
+                    "type": "number",
+                    "example": 1.5
+                },
+                "host_pid": {
+                    "description": "PID on the Docker host",
+                    "type": "integer",
+                    "example": 48213
+                },
+                "memory": {
+                    "description": "resident memory in percent of host memory",
+                    "type": "number",
+                    "example": 0.7
+                },
+                "pid": {
+                    "description": "PID inside the sandbox, omitted when it cannot be resolved",
+                    "type": "integer",
+                    "example": 17
+                },
+                "user": {
+                    "description": "owner, as resolved on the Docker host",
+                    "type": "string",
+                    "example": "root"
+                }
+            }
+        },
+        "models.ProcessListResponse": {
+            "type": "object",
+            "properties": {
+                "processes": {
+                    "description": "supervised processes",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ProcessDetail"
+                    }
+                },
+                "running": {
+                    "description": "process table snapshot, empty when the sandbox is not running",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ProcessInfo"
+                    }
+                }
+            }
+        },
+        "models.PutSecretRequest": {
+            "type": "object",
+            "required": [
+                "value"
+            ],
+            "properties": {
+                "value": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Quota": {
+            "type": "object",
+            "properties": {
+                "max_cpus": {
+                    "description": "total CPUs of running sandboxes",
+                    "type": "number",
+                    "example": 4
+                },
+                "max_memory": {
+                    "description": "total memory of running sandboxes, in MB",
+                    "type": "integer",
+                    "example": 4096
+                },
+                "max_sandboxes": {
+                    "description": "running sandboxes",
+                    "type": "integer",
+                    "example": 5
+                }
+            }
+        },
+        "models.ReadyCheck": {
+            "type": "object",
+            "properties": {
+                "command": {
+                    "description": "command run inside the sandbox instead of probing a port",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "test",
+                        "-f",
+                        "/tmp/ready"
+                    ]
+                },
+                "path": {
+                    "description": "HTTP path; empty = TCP check",
+                    "type": "string",
+                    "example": "/health"
+                },
+                "port": {
+                    "description": "container port to probe, default the main port",
+                    "type": "string",
+                    "example": "3000"
+                },
+                "timeout": {
+                    "description": "seconds to keep probing, 0 = default (60s), max 600",
+                    "type": "integer",
+                    "example": 60
+                }
+            }
+        },
+        "models.RegistryAuth": {
+            "type": "object",
+            "properties": {
+                "password": {
+                    "type": "string"
+                },
+                "server_address": {
+                    "description": "e.g. \"ghcr.io\", empty = derived from the image",
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.RenewExpirationRequest": {
+            "type": "object",
+            "required": [
+                "timeout"
+            ],
+            "properties": {
+                "timeout": {
+                    "description": "new TTL in seconds",
+                    "type": "integer",
+                    "example": 900
+                }
+            }
+        },
+        "models.RenewExpirationResponse": {
+            "type": "object",
+            "properties": {
+                "status": {
+                    "type": "string"
+                },
+                "timeout": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ResourceLimits": {
+            "type": "object",
+            "properties": {
+                "cpus": {
+                    "description": "fractional CPU limit (e.g. 1.5). Default: 1.0, Max: 4.0",
+                    "type": "number",
+                    "example": 1
+                },
+                "gpus": {
+                    "description": "GPUs to pass through: a count (1), \"all\", or device IDs ([\"0\", \"GPU-3a23c669\"]). Default: none",
+                    "type": "string",
+                    "example": "all"
+                },
+                "memory": {
+                    "description": "memory limit in MB (e.g. 512 = 512MB). Default: 1024 (1GB), Max: 8192 (8GB)",
+                    "type": "integer",
+                    "example": 1024
+                }
+            }
+        },
+        "models.ResourceTotals": {
+            "type": "object",
+            "properties": {
+                "cpus": {
+                    "type": "number"
+                },
+                "memory": {
+                    "description": "MB",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.RestartResponse": {
+            "type": "object",
+            "properties": {
+                "expires_at": {
                     "type": "string"
                 },
-                "sandbox_id": {
-                    "description": "parent sandbox container ID",
+                "port_ready": {
+                    "description": "whether the main port accepted connections in time, only with ?wait_port=true",
+                    "type": "string",
+                    "enum": [
+                        "ready",
+                        "timeout"
+                    ]
+                },
+                "ports": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "status": {
                     "type": "string"
-                },
-                "started_at": {
-                    "description": "unix milliseconds",
-                    "type": "integer"
                 }
             }
         },
-        "models.CommandListResponse": {
+        "models.RestoreRequest": {
             "type": "object",
+            "required": [
+                "checkpoint"
+            ],
             "properties": {
-                "commands": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/models.CommandDetail"
-                    }
+                "checkpoint": {
+                    "description": "name of the checkpoint to restore",
+                    "type": "string",
+                    "example": "before-migration"
                 }
             }
         },
-        "models.CommandLogsResponse": {
+        "models.RunCodeRequest": {
+            "type": "object",
+            "required": [
+                "code",
+                "language"
+            ],
+            "properties": {
+                "code": {
+                    "description": "program source, written to a file and run as the language's main program",
+                    "type": "string",
+                    "example": "print(input().upper())"
+                },
+                "language": {
+                    "type": "string",
+                    "enum": [
+                        "python",
+                        "node",
+                        "go",
+                        "ruby",
+                        "bash"
+                    ],
+                    "example": "python"
+                },
+                "stdin": {
+                    "description": "written to the program's stdin, which is then closed",
+                    "type": "string",
+                    "example": "hello\n"
+                },
+                "timeout": {
+                    "description": "seconds before the program is killed, 0 = default (60s)",
+                    "type": "integer",
+                    "example": 30
+                }
+            }
+        },
+        "models.RunCodeResponse": {
             "type": "object",
             "properties": {
                 "exit_code": {
-                    "description": "nil while command is still running",
-                    "type": "integer"
+                    "type": "integer",
+                    "example": 0
+                },
+                "language": {
+                    "type": "string",
+                    "example": "python"
                 },
                 "stderr": {
-                    "description": "captured stderr text",
                     "type": "string"
                 },
                 "stdout": {
-                    "description": "captured stdout text",
-                    "type": "string"
+                    "type": "string",
+                    "example": "HELLO\n"
+                },
+                "timed_out": {
+                    "description": "the program was killed after exceeding its timeout",
+                    "type": "boolean"
                 }
             }
         },
-        "models.CommandResponse": {
+        "models.SandboxCounts": {
             "type": "object",
             "properties": {
-                "command": {
-                    "$ref": "#/definitions/models.CommandDetail"
+                "by_state": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "total": {
+                    "type": "integer"
                 }
             }
         },
-        "models.CreateSandboxRequest": {
+        "models.SandboxDetail": {
             "type": "object",
-            "required": [
-                "image"
-            ],
             "properties": {
-                "env": {
-                    "description": "extra environment variables (e.g. [\"KEY=VALUE\"])",
+                "events": {
+                    "description": "recent OOM kills, unexpected exits, restarts and hook failures, oldest first",
                     "type": "array",
                     "items": {
-                        "type": "string"
+                        "$ref": "#/definitions/models.SandboxEvent"
                     }
                 },
+                "exit_code": {
+                    "description": "exit code of the last run, only while not running",
+                    "type": "integer"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "finished_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
                 "image": {
+                    "type": "string"
+                },
+                "isolation": {
+                    "description": "\"microvm\" when the runtime boots a VM per sandbox (Kata, Firecracker)",
                     "type": "string",
-                    "example": "node:24"
+                    "enum": [
+                        "container",
+                        "microvm"
+                    ]
                 },
-                "ports": {
-                    "description": "container ports to expose, e.g. [\"3000\", \"8080/tcp\"]. First port is the default for proxy routing.",
-                    "type": "array",
-                    "items": {
+                "labels": {
+                    "type": "object",
+                    "additionalProperties": {
                         "type": "string"
-                    },
-                    "example": [
-                        "3000",
-                        "8080"
-                    ]
+                    }
                 },
-                "resources": {
-                    "description": "CPU/memory limits, nil = defaults (1GB RAM, 1 vCPU)",
-                    "allOf": [
-                        {
-                            "$ref": "#/definitions/models.ResourceLimits"
-                        }
-                    ]
+                "lifetime_ends_at": {
+                    "description": "when max_lifetime is reached; expires_at never goes past it",
+                    "type": "string"
                 },
-                "timeout": {
-                    "description": "seconds until auto-stop, 0 = default (900s)",
+                "max_lifetime": {
+                    "description": "seconds the sandbox may exist in total, whatever its renewals; omitted when unlimited",
                     "type": "integer",
-                    "example": 900
-                }
-            }
-        },
-        "models.CreateSandboxResponse": {
-            "type": "object",
-            "properties": {
-                "id": {
-                    "type": "string"
+                    "example": 86400
                 },
                 "name": {
-                    "description": "auto-generated name (e.g. \"eager-turing\")",
                     "type": "string"
                 },
+                "oom_killed": {
+                    "description": "the last run was ended by the kernel OOM killer",
+                    "type": "boolean"
+                },
                 "ports": {
-                    "description": "exposed container ports, e.g. [\"3000/tcp\", \"8080/tcp\"]",
                     "type": "array",
                     "items": {
                         "type": "string"
                     }
                 },
+                "ready": {
+                    "description": "ready check state since the last start; empty without a ready check",
+                    "type": "string",
+                    "enum": [
+                        "starting",
+                        "ready",
+                        "timeout"
+                    ]
+                },
+                "resources": {
+                    "$ref": "#/definitions/models.ResourceLimits"
+                },
+                "running": {
+                    "type": "boolean"
+                },
+                "runtime": {
+                    "description": "OCI runtime, empty = the worker's default",
+                    "type": "string",
+                    "example": "runsc"
+                },
+                "started_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
                 "url": {
-                    "description": "proxy URL, e.g. \"http://eager-turing.localhost\"",
                     "type": "string"
+                },
+                "wake_on_request": {
+                    "type": "boolean"
                 }
             }
         },
-        "models.ExecCommandRequest": {
+        "models.SandboxDomain": {
             "type": "object",
-            "required": [
-                "command"
-            ],
             "properties": {
-                "args": {
-                    "description": "arguments (e.g. [\"install\"])",
-                    "type": "array",
-                    "items": {
-                        "type": "string"
-                    },
-                    "example": [
-                        "install"
-                    ]
+                "created_at": {
+                    "type": "string"
                 },
-                "command": {
-                    "description": "executable name (e.g. \"npm\")",
+                "domain": {
                     "type": "string",
-                    "example": "npm"
+                    "example": "app.example.com"
                 },
-                "cwd": {
-                    "description": "working directory",
+                "sandbox_id": {
                     "type": "string",
-                    "example": "/app"
+                    "example": "a1b2c3d4e5f6"
                 },
+                "url": {
+                    "type": "string",
+                    "example": "https://app.example.com"
+                }
+            }
+        },
+        "models.SandboxEnv": {
+            "type": "object",
+            "properties": {
                 "env": {
-                    "description": "extra environment variables",
+                    "description": "the container's environment with the variables set after creation applied",
                     "type": "object",
                     "additionalProperties": {
                         "type": "string"
@@ -1522,197 +7293,434 @@ const docTemplate = `{
                 }
             }
         },
-        "models.FileListResponse": {
+        "models.SandboxEvent": {
             "type": "object",
             "properties": {
-                "output": {
+                "exit_code": {
+                    "description": "exit code of \"exit\" and \"hook_failed\" events; none when the hook timed out",
+                    "type": "integer"
+                },
+                "hook": {
+                    "description": "hook of \"hook_failed\" events",
+                    "type": "string",
+                    "enum": [
+                        "on_start",
+                        "before_stop"
+                    ]
+                },
+                "time": {
                     "type": "string"
                 },
-                "path": {
+                "type": {
+                    "description": "\"oom\": a process hit the memory limit, \"exit\": the sandbox stopped without being asked to, \"restart\": it was restarted, \"hook_failed\": a lifecycle hook failed",
+                    "type": "string",
+                    "enum": [
+                        "oom",
+                        "exit",
+                        "restart",
+                        "hook_failed"
+                    ]
+                }
+            }
+        },
+        "models.SandboxIsolation": {
+            "type": "object",
+            "properties": {
+                "isolated": {
+                    "description": "true when no other container is reachable over the sandbox's networks",
+                    "type": "boolean"
+                },
+                "network_mode": {
+                    "description": "docker network mode (e.g. \"opensbx-isolated\", \"bridge\")",
                     "type": "string"
+                },
+                "networks": {
+                    "description": "attached networks and their isolation settings",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.NetworkIsolation"
+                    }
+                },
+                "reasons": {
+                    "description": "why the sandbox is not isolated",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 }
             }
         },
-        "models.FileReadResponse": {
+        "models.SandboxNetwork": {
             "type": "object",
             "properties": {
-                "content": {
+                "main_port": {
+                    "description": "selected container port for proxy routing (e.g. \"3000/tcp\")",
                     "type": "string"
                 },
-                "path": {
+                "name": {
+                    "description": "sandbox name, used in proxy URLs",
                     "type": "string"
+                },
+                "ports_map": {
+                    "description": "map of container port -\u003e docker host port",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "urls": {
+                    "description": "proxy URL per container port, e.g. \"8080/tcp\" -\u003e \"http://my-app--8080.localhost:3000\"",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
                 }
             }
         },
-        "models.FileWriteRequest": {
+        "models.SandboxStats": {
             "type": "object",
-            "required": [
-                "content"
-            ],
             "properties": {
-                "content": {
+                "block_io": {
+                    "description": "bytes read from and written to block devices since start",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.BlockIOUsage"
+                        }
+                    ]
+                },
+                "cpu_percent": {
+                    "description": "CPU usage percentage",
+                    "type": "number"
+                },
+                "memory": {
+                    "description": "memory usage and limit",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.MemoryUsage"
+                        }
+                    ]
+                },
+                "network": {
+                    "description": "traffic per interface (e.g. \"eth0\") since start; empty without networking",
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/models.NetworkUsage"
+                    }
+                },
+                "pids": {
+                    "description": "number of running processes",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Schedule": {
+            "type": "object",
+            "properties": {
+                "command": {
+                    "$ref": "#/definitions/models.ExecCommandRequest"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "cron": {
+                    "type": "string",
+                    "example": "0 3 * * *"
+                },
+                "last_run": {
+                    "description": "most recent run, nil before the first",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.ScheduleRun"
+                        }
+                    ]
+                },
+                "name": {
                     "type": "string",
-                    "example": "console.log('hello')"
+                    "example": "nightly-tests"
+                },
+                "next_run": {
+                    "type": "string"
+                },
+                "sandbox": {
+                    "$ref": "#/definitions/models.CreateSandboxRequest"
                 }
             }
         },
-        "models.ImageDetail": {
+        "models.ScheduleRun": {
             "type": "object",
             "properties": {
-                "architecture": {
-                    "description": "e.g. \"amd64\"",
+                "error": {
+                    "description": "why the run could not complete, for status \"error\"",
                     "type": "string"
                 },
-                "created": {
-                    "description": "RFC3339",
+                "exit_code": {
+                    "type": "integer"
+                },
+                "finished_at": {
+                    "description": "nil while running",
                     "type": "string"
                 },
                 "id": {
+                    "type": "integer"
+                },
+                "sandbox_id": {
+                    "description": "sandbox the run created, deleted once it finished",
                     "type": "string"
                 },
-                "os": {
-                    "description": "e.g. \"linux\"",
+                "started_at": {
                     "type": "string"
                 },
-                "size": {
-                    "description": "bytes",
-                    "type": "integer"
+                "status": {
+                    "description": "\"failed\": non-zero exit code, \"error\": the sandbox or command could not run",
+                    "type": "string",
+                    "enum": [
+                        "running",
+                        "succeeded",
+                        "failed",
+                        "error"
+                    ],
+                    "example": "succeeded"
                 },
-                "tags": {
-                    "type": "array",
-                    "items": {
-                        "type": "string"
-                    }
+                "stderr": {
+                    "description": "last 64 KiB of the command's stderr",
+                    "type": "string"
+                },
+                "stdout": {
+                    "description": "last 64 KiB of the command's stdout",
+                    "type": "string"
                 }
             }
         },
-        "models.ImagePullRequest": {
+        "models.Secret": {
             "type": "object",
-            "required": [
-                "image"
-            ],
             "properties": {
-                "image": {
-                    "description": "image name with optional tag (e.g. \"nginx:latest\")",
+                "created_at": {
+                    "type": "string"
+                },
+                "name": {
                     "type": "string",
-                    "example": "node:22"
+                    "example": "OPENAI_API_KEY"
+                },
+                "updated_at": {
+                    "type": "string"
                 }
             }
         },
-        "models.ImagePullResponse": {
+        "models.SecurityOptions": {
             "type": "object",
             "properties": {
-                "image": {
-                    "type": "string"
+                "cap_drop": {
+                    "description": "capabilities dropped on top of the server defaults; \"ALL\" drops every capability",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "NET_RAW",
+                        "MKNOD"
+                    ]
                 },
-                "status": {
+                "no_new_privileges": {
+                    "description": "stop setuid binaries from gaining privileges",
+                    "type": "boolean",
+                    "example": true
+                },
+                "pids_limit": {
+                    "description": "maximum processes, 0 = server default",
+                    "type": "integer",
+                    "example": 256
+                },
+                "read_only_rootfs": {
+                    "description": "read-only root filesystem; /tmp and /run stay writable. The files API cannot write to such a sandbox",
+                    "type": "boolean",
+                    "example": true
+                },
+                "seccomp_profile": {
+                    "description": "seccomp profile JSON, empty = server default",
                     "type": "string"
+                },
+                "user": {
+                    "description": "run as this user: name, uid, or user:group / uid:gid",
+                    "type": "string",
+                    "example": "1000:1000"
                 }
             }
         },
-        "models.KillCommandRequest": {
+        "models.SeedFile": {
             "type": "object",
             "required": [
-                "signal"
+                "path"
             ],
             "properties": {
-                "signal": {
-                    "description": "POSIX signal number (15=SIGTERM, 9=SIGKILL)",
-                    "type": "integer",
-                    "example": 15
+                "content": {
+                    "type": "string"
+                },
+                "path": {
+                    "type": "string",
+                    "example": "/app/index.js"
                 }
             }
         },
-        "models.MemoryUsage": {
+        "models.Session": {
             "type": "object",
             "properties": {
-                "limit": {
-                    "description": "bytes limit",
-                    "type": "integer"
+                "busy": {
+                    "description": "a cell is executing",
+                    "type": "boolean"
                 },
-                "percent": {
-                    "description": "usage / limit * 100",
-                    "type": "number"
+                "created_at": {
+                    "type": "string"
                 },
-                "usage": {
-                    "description": "bytes currently used",
+                "execution_count": {
+                    "description": "cells executed so far",
                     "type": "integer"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "ses_4f9c2a1b7d3e8f60a5b1c2d3"
+                },
+                "language": {
+                    "type": "string",
+                    "example": "python"
+                },
+                "sandbox_id": {
+                    "type": "string"
                 }
             }
         },
-        "models.RenewExpirationRequest": {
+        "models.SessionEvent": {
             "type": "object",
-            "required": [
-                "timeout"
-            ],
             "properties": {
-                "timeout": {
-                    "description": "new TTL in seconds",
-                    "type": "integer",
-                    "example": 900
+                "data": {
+                    "description": "display and result: representations keyed by MIME type, e.g. \"text/plain\", \"text/html\", \"image/png\" (base64)",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "execution_count": {
+                    "description": "end: number of this execution in the session",
+                    "type": "integer"
+                },
+                "name": {
+                    "description": "error: exception type",
+                    "type": "string",
+                    "example": "ZeroDivisionError"
+                },
+                "text": {
+                    "description": "stdout and stderr: the text written",
+                    "type": "string"
+                },
+                "traceback": {
+                    "description": "error: formatted traceback",
+                    "type": "string"
+                },
+                "type": {
+                    "description": "\"end\" is always the last line; \"ping\" is sent while the cell runs without output",
+                    "type": "string",
+                    "enum": [
+                        "stdout",
+                        "stderr",
+                        "display",
+                        "result",
+                        "error",
+                        "end",
+                        "ping"
+                    ],
+                    "example": "result"
+                },
+                "value": {
+                    "description": "error: exception message",
+                    "type": "string",
+                    "example": "division by zero"
                 }
             }
         },
-        "models.RenewExpirationResponse": {
+        "models.SnapshotRequest": {
             "type": "object",
             "properties": {
-                "status": {
+                "comment": {
+                    "description": "commit message stored in the image",
                     "type": "string"
                 },
-                "timeout": {
-                    "type": "integer"
+                "image": {
+                    "description": "image reference to create, empty = opensbx-snapshot/\u003cowner\u003e/\u003cname\u003e:\u003cunix time\u003e; other names need the images scope",
+                    "type": "string",
+                    "example": "myorg/node-deps:v1"
+                },
+                "push": {
+                    "description": "push the image to its registry after committing",
+                    "type": "boolean"
+                },
+                "registry_auth": {
+                    "description": "credentials for push, nil = anonymous",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.RegistryAuth"
+                        }
+                    ]
                 }
             }
         },
-        "models.ResourceLimits": {
+        "models.SnapshotResponse": {
             "type": "object",
             "properties": {
-                "cpus": {
-                    "description": "fractional CPU limit (e.g. 1.5). Default: 1.0, Max: 4.0",
-                    "type": "number",
-                    "example": 1
+                "id": {
+                    "description": "image ID (sha256:...)",
+                    "type": "string"
                 },
-                "memory": {
-                    "description": "memory limit in MB (e.g. 512 = 512MB). Default: 1024 (1GB), Max: 8192 (8GB)",
-                    "type": "integer",
-                    "example": 1024
+                "image": {
+                    "description": "reference of the created image",
+                    "type": "string"
+                },
+                "pushed": {
+                    "description": "true if the image was pushed to a registry",
+                    "type": "boolean"
                 }
             }
         },
-        "models.RestartResponse": {
+        "models.Stack": {
             "type": "object",
             "properties": {
-                "expires_at": {
+                "created_at": {
                     "type": "string"
                 },
-                "ports": {
+                "name": {
+                    "type": "string",
+                    "example": "shop"
+                },
+                "network": {
+                    "description": "Docker network the services share",
+                    "type": "string",
+                    "example": "opensbx-stack-shop"
+                },
+                "services": {
                     "type": "array",
                     "items": {
-                        "type": "string"
+                        "$ref": "#/definitions/models.StackService"
                     }
                 },
-                "status": {
+                "url": {
+                    "description": "proxy URL of the web service",
                     "type": "string"
+                },
+                "web": {
+                    "type": "string",
+                    "example": "app"
                 }
             }
         },
-        "models.SandboxDetail": {
+        "models.StackService": {
             "type": "object",
             "properties": {
-                "expires_at": {
-                    "type": "string"
-                },
-                "finished_at": {
-                    "type": "string"
-                },
                 "id": {
-                    "type": "string"
-                },
-                "image": {
+                    "description": "sandbox ID",
                     "type": "string"
                 },
                 "name": {
-                    "type": "string"
+                    "description": "sandbox name",
+                    "type": "string",
+                    "example": "shop-db"
                 },
                 "ports": {
                     "type": "array",
@@ -1720,57 +7728,113 @@ const docTemplate = `{
                         "type": "string"
                     }
                 },
-                "resources": {
-                    "$ref": "#/definitions/models.ResourceLimits"
-                },
-                "running": {
-                    "type": "boolean"
-                },
-                "started_at": {
-                    "type": "string"
+                "service": {
+                    "type": "string",
+                    "example": "db"
                 },
                 "status": {
-                    "type": "string"
-                },
-                "url": {
-                    "type": "string"
+                    "type": "string",
+                    "example": "running"
                 }
             }
         },
-        "models.SandboxNetwork": {
+        "models.StartProcessRequest": {
             "type": "object",
+            "required": [
+                "command",
+                "name"
+            ],
             "properties": {
-                "main_port": {
-                    "description": "selected container port for proxy routing (e.g. \"3000/tcp\")",
-                    "type": "string"
+                "args": {
+                    "description": "arguments",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "run",
+                        "dev"
+                    ]
                 },
-                "ports_map": {
-                    "description": "map of container port -\u003e docker host port",
+                "command": {
+                    "description": "executable name",
+                    "type": "string",
+                    "example": "npm"
+                },
+                "cwd": {
+                    "description": "working directory",
+                    "type": "string",
+                    "example": "/app"
+                },
+                "env": {
+                    "description": "extra environment variables",
                     "type": "object",
                     "additionalProperties": {
                         "type": "string"
                     }
+                },
+                "name": {
+                    "description": "unique per sandbox: lowercase letters, digits and dashes",
+                    "type": "string",
+                    "example": "web"
+                },
+                "restart": {
+                    "description": "restart policy when the process exits. Default: always",
+                    "type": "string",
+                    "enum": [
+                        "always",
+                        "on-failure",
+                        "never"
+                    ],
+                    "example": "on-failure"
+                },
+                "user": {
+                    "description": "run as this user (see ExecCommandRequest)",
+                    "type": "string",
+                    "example": "node"
                 }
             }
         },
-        "models.SandboxStats": {
+        "models.Tunnel": {
             "type": "object",
             "properties": {
-                "cpu_percent": {
-                    "description": "CPU usage percentage",
-                    "type": "number"
+                "connections": {
+                    "description": "connections currently open",
+                    "type": "integer"
                 },
-                "memory": {
-                    "description": "memory usage and limit",
-                    "allOf": [
-                        {
-                            "$ref": "#/definitions/models.MemoryUsage"
-                        }
-                    ]
+                "created_at": {
+                    "type": "string"
                 },
-                "pids": {
-                    "description": "number of running processes",
-                    "type": "integer"
+                "id": {
+                    "type": "string",
+                    "example": "tun_8a3f0c5d2b7e4f1a6c9d0e2b"
+                },
+                "port": {
+                    "type": "integer",
+                    "example": 5432
+                },
+                "sandbox_id": {
+                    "type": "string"
+                },
+                "url": {
+                    "description": "WebSocket path to connect to, authenticated like the rest of the API",
+                    "type": "string",
+                    "example": "/v1/sandboxes/3f2a1b/tunnels/tun_8a3f0c5d2b7e4f1a6c9d0e2b/connect"
+                }
+            }
+        },
+        "models.UpdateEnvRequest": {
+            "type": "object",
+            "required": [
+                "env"
+            ],
+            "properties": {
+                "env": {
+                    "description": "variables to set; null removes a variable set earlier through this endpoint",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
                 }
             }
         }
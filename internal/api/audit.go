@@ -0,0 +1,273 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"opensbx/internal/logging"
+	"opensbx/models"
+)
+
+// AuditLog stores audit events. Implemented by *audit.Log.
+type AuditLog interface {
+	Record(e models.AuditEvent) error
+	Query(q models.AuditQuery) ([]models.AuditEvent, error)
+}
+
+// auditSandboxKey lets handlers name the sandbox they created, for routes without an :id.
+const auditSandboxKey = "opensbx.audit_sandbox"
+
+// auditActions names mutating routes in the audit log. Unlisted mutating
+// routes are recorded as "METHOD route".
+var auditActions = map[string]string{
+	"POST /v1/apply":                               "apply",
+	"POST /v1/sandboxes":                           "sandbox.create",
+	"POST /v1/sandboxes/import":                    "sandbox.import",
+	"DELETE /v1/sandboxes/:id":                     "sandbox.delete",
+	"POST /v1/sandboxes/:id/start":                 "sandbox.start",
+	"POST /v1/sandboxes/:id/stop":                  "sandbox.stop",
+	"POST /v1/sandboxes/:id/restart":               "sandbox.restart",
+	"POST /v1/sandboxes/:id/pause":                 "sandbox.pause",
+	"POST /v1/sandboxes/:id/resume":                "sandbox.resume",
+	"POST /v1/sandboxes/:id/renew-expiration":      "sandbox.renew",
+	"POST /v1/sandboxes/:id/snapshot":              "sandbox.snapshot",
+	"POST /v1/sandboxes/:id/clone":                 "sandbox.clone",
+	"GET /v1/sandboxes/:id/terminal":               "terminal.open",
+	"POST /v1/sandboxes/:id/domains":               "domain.add",
+	"DELETE /v1/sandboxes/:id/domains/:domain":     "domain.remove",
+	"POST /v1/sandboxes/:id/cmd":                   "command.exec",
+	"POST /v1/sandboxes/:id/cmd/batch":             "command.batch",
+	"POST /v1/sandboxes/:id/run":                   "command.exec",
+	"POST /v1/sandboxes/:id/cmd/:cmdId/kill":       "command.kill",
+	"POST /v1/sandboxes/:id/processes":             "process.start",
+	"DELETE /v1/sandboxes/:id/processes/:name":     "process.remove",
+	"POST /v1/sandboxes/:id/sessions":              "session.create",
+	"DELETE /v1/sandboxes/:id/sessions/:sid":       "session.delete",
+	"POST /v1/sandboxes/:id/sessions/:sid/execute": "session.execute",
+	"POST /v1/sandboxes/:id/git/pull":              "git.pull",
+	"POST /v1/sandboxes/:id/tunnels":               "tunnel.create",
+	"DELETE /v1/sandboxes/:id/tunnels/:tid":        "tunnel.delete",
+	"GET /v1/sandboxes/:id/tunnels/:tid/connect":   "tunnel.connect",
+	"PUT /v1/sandboxes/:id/files":                  "file.write",
+	"DELETE /v1/sandboxes/:id/files":               "file.delete",
+	"POST /v1/sandboxes/:id/files/upload":          "file.upload",
+	"POST /v1/stacks":                              "stack.create",
+	"DELETE /v1/stacks/:name":                      "stack.delete",
+	"POST /v1/stacks/:name/start":                  "stack.start",
+	"POST /v1/stacks/:name/stop":                   "stack.stop",
+	"POST /v1/networks":                            "network.create",
+	"DELETE /v1/networks/:name":                    "network.delete",
+	"POST /v1/jobs":                                "job.run",
+	"POST /v1/run":                                 "code.run",
+	"POST /v1/schedules":                           "schedule.create",
+	"DELETE /v1/schedules/:name":                   "schedule.delete",
+	"POST /v1/images/pull":                         "image.pull",
+	"DELETE /v1/images/:id":                        "image.delete",
+	"PUT /v1/secrets/:name":                        "secret.put",
+	"DELETE /v1/secrets/:name":                     "secret.delete",
+	"POST /v1/admin/keys":                          "key.create",
+	"DELETE /v1/admin/keys/:id":                    "key.revoke",
+}
+
+// auditAction returns the audit action for a request, or "" if it is not audited.
+// Reads are not audited, except opening a terminal or a tunnel connection. MCP
+// requests are not audited as such; their tool calls are, see mcpToolCalls.
+func auditAction(method, route string) string {
+	if action, ok := auditActions[method+" "+route]; ok {
+		return action
+	}
+	switch {
+	case method == http.MethodGet, method == http.MethodHead, method == http.MethodOptions:
+		return ""
+	case strings.HasPrefix(route, "/v1/mcp"):
+		return ""
+	}
+	return method + " " + route
+}
+
+// Audit returns a middleware that records every mutating request after it
+// completes, with the caller identity set by RequestAuth and the response status.
+// Failures to record are logged and never fail the request.
+func Audit(l AuditLog) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if route == "" {
+			route = c.Request.URL.Path
+		}
+		action := auditAction(c.Request.Method, route)
+		var calls []mcpToolCall
+		if c.Request.Method == http.MethodPost && strings.HasPrefix(route, "/v1/mcp") {
+			calls = readMCPToolCalls(c)
+		}
+		if action == "" && len(calls) == 0 {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		c.Next()
+
+		auth := c.GetString(authMethodKey)
+		if auth == "" {
+			auth = "none"
+		}
+		record := func(action, sandboxID string) {
+			err := l.Record(models.AuditEvent{
+				Time:      start,
+				Action:    action,
+				SandboxID: sandboxID,
+				Auth:      auth,
+				KeyID:     c.GetString(authKeyIDKey),
+				Owner:     c.GetString(authOwnerKey),
+				Actor:     c.GetHeader(HeaderActor),
+				Method:    c.Request.Method,
+				Path:      c.Request.URL.Path,
+				Status:    c.Writer.Status(),
+				RequestID: c.GetString(requestIDKey),
+			})
+			if err != nil {
+				logging.FromContext(c.Request.Context()).Error("audit: record failed", "action", action, "sandbox_id", sandboxID, "err", err)
+			}
+		}
+
+		for _, call := range calls {
+			if call.tool == "" {
+				record("mcp", "")
+				continue
+			}
+			record("mcp."+call.tool, call.sandboxID)
+		}
+		if action != "" {
+			sandboxID := c.GetString(auditSandboxKey)
+			if sandboxID == "" && strings.HasPrefix(route, "/v1/sandboxes/:id") {
+				sandboxID = c.Param("id")
+			}
+			record(action, sandboxID)
+		}
+	}
+}
+
+// mcpToolCall is a tools/call request found in an MCP message.
+type mcpToolCall struct {
+	tool      string // tool name, e.g. sandbox_create; empty for a body too large to parse
+	sandboxID string // the id or sandbox_id argument of a sandbox tool, if any
+}
+
+// mcpAuditBodyLimit is the largest MCP request body parsed for tool calls.
+const mcpAuditBodyLimit = 4 << 20
+
+// readMCPToolCalls returns the tool calls in the request's JSON-RPC message
+// or batch and restores the body for the MCP handler. Each call is audited as
+// "mcp.<tool>". The response status is that of the HTTP request: a tool that
+// fails reports its error inside a 200 response. A body over
+// mcpAuditBodyLimit is streamed to the handler unparsed and audited as a
+// single "mcp" event.
+func readMCPToolCalls(c *gin.Context) []mcpToolCall {
+	if c.Request.Body == nil {
+		return nil
+	}
+	body := c.Request.Body
+	head, err := io.ReadAll(io.LimitReader(body, mcpAuditBodyLimit+1))
+	c.Request.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(head), body), body}
+	if err != nil {
+		return nil
+	}
+	if len(head) > mcpAuditBodyLimit {
+		logging.FromContext(c.Request.Context()).Warn("audit: MCP request too large to parse tool calls", "limit_bytes", mcpAuditBodyLimit)
+		return []mcpToolCall{{}}
+	}
+	return mcpToolCalls(head)
+}
+
+// mcpToolCalls parses the tools/call requests of a JSON-RPC message or batch.
+func mcpToolCalls(body []byte) []mcpToolCall {
+	type message struct {
+		Method string `json:"method"`
+		Params struct {
+			Name      string `json:"name"`
+			Arguments struct {
+				ID        string `json:"id"`
+				SandboxID string `json:"sandbox_id"`
+			} `json:"arguments"`
+		} `json:"params"`
+	}
+
+	body = bytes.TrimSpace(body)
+	var msgs []message
+	if len(body) > 0 && body[0] == '[' {
+		if json.Unmarshal(body, &msgs) != nil {
+			return nil
+		}
+	} else {
+		var msg message
+		if json.Unmarshal(body, &msg) != nil {
+			return nil
+		}
+		msgs = append(msgs, msg)
+	}
+
+	var calls []mcpToolCall
+	for _, m := range msgs {
+		if m.Method != "tools/call" || m.Params.Name == "" {
+			continue
+		}
+		var id string
+		if sandboxTool(m.Params.Name) {
+			id = m.Params.Arguments.SandboxID
+			if id == "" {
+				id = m.Params.Arguments.ID
+			}
+		}
+		calls = append(calls, mcpToolCall{tool: m.Params.Name, sandboxID: id})
+	}
+	return calls
+}
+
+// SetAuditLog enables GET /v1/audit. Must be called before RegisterRoutes.
+func (h *Handler) SetAuditLog(l AuditLog) {
+	h.audit = l
+}
+
+// listAudit handles GET /v1/audit.
+// @Summary      Query the audit log
+// @Description  Lists recorded mutating operations (who, what, when, on which sandbox), newest first. Requires the admin scope.
+// @Tags         admin
+// @Produce      json
+// @Param        sandbox_id  query     string  false  "Only events for this sandbox ID or name"
+// @Param        action      query     string  false  "Only this action, e.g. sandbox.create, command.exec, file.write"
+// @Param        since       query     string  false  "Only events at or after this time (RFC 3339)"
+// @Param        until       query     string  false  "Only events before this time (RFC 3339)"
+// @Param        limit       query     int     false  "Maximum events to return (default 100, max 1000)"
+// @Success      200         {array}   models.AuditEvent
+// @Failure      400         {object}  ErrorResponse
+// @Failure      500         {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /audit [get]
+func (h *Handler) listAudit(c *gin.Context) {
+	var q models.AuditQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		badRequest(c, err.Error())
+		return
+	}
+	// Events store full IDs; names and short IDs of existing sandboxes are resolved.
+	if q.SandboxID != "" {
+		if id, err := h.docker.Resolve(c.Request.Context(), q.SandboxID); err == nil {
+			q.SandboxID = id
+		}
+	}
+
+	events, err := h.audit.Query(q)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, events)
+}
@@ -0,0 +1,213 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"opensbx/internal/keys"
+	"opensbx/internal/logging"
+)
+
+// HeaderActor optionally names the caller (user, team, service) for authorization hooks.
+// It is informational only; deployments that rely on it must set it at a trusted gateway.
+const HeaderActor = "X-Opensbx-Actor"
+
+// authMethodKey is the gin context key where RequestAuth records how the caller authenticated.
+const authMethodKey = "opensbx.auth_method"
+
+// authzBodyLimit is how much of a JSON body is decoded into AuthzRequest.Attributes.
+// Larger bodies are passed to the handler untouched and sent without attributes.
+const authzBodyLimit = 64 << 10
+
+// AuthzRequest describes a mutating operation submitted to an Authorizer.
+type AuthzRequest struct {
+	Actor      AuthzActor     `json:"actor"`
+	Action     string         `json:"action"`               // "METHOD route", e.g. "POST /v1/sandboxes/:id/stop"
+	Resource   AuthzResource  `json:"resource"`             // target of the operation
+	Attributes map[string]any `json:"attributes,omitempty"` // decoded JSON request body, if any
+}
+
+// AuthzActor identifies the caller.
+type AuthzActor struct {
+	Name  string `json:"name,omitempty"`   // value of the X-Opensbx-Actor header
+	Auth  string `json:"auth"`             // "api_key", "signature" or "none"
+	KeyID string `json:"key_id,omitempty"` // stored API key used, empty for API_KEY and signatures
+	Owner string `json:"owner,omitempty"`  // owner the key is restricted to, empty = all sandboxes
+}
+
+// AuthzResource identifies the object an operation acts on.
+type AuthzResource struct {
+	Type      string `json:"type"`                 // "sandboxes", "images", "stacks", "admin", "mcp", ...
+	ID        string `json:"id,omitempty"`         // sandbox ID, or stack, network, schedule or secret name from the path
+	CommandID string `json:"command_id,omitempty"` // command ID from the path
+}
+
+// Authorizer decides whether a mutating operation may proceed.
+// Returning allowed=false rejects the request with 403 and the given reason.
+type Authorizer interface {
+	Authorize(ctx context.Context, req AuthzRequest) (allowed bool, reason string, err error)
+}
+
+// AuthorizerFunc adapts a function to the Authorizer interface.
+type AuthorizerFunc func(ctx context.Context, req AuthzRequest) (bool, string, error)
+
+// Authorize calls f.
+func (f AuthorizerFunc) Authorize(ctx context.Context, req AuthzRequest) (bool, string, error) {
+	return f(ctx, req)
+}
+
+// Authorize returns a middleware that consults a before every request that needs
+// more than the read scope (see requiredScope): mutations, but also opening a
+// terminal or tunnel connection, MCP and admin reads. Errors from the authorizer
+// fail closed.
+func Authorize(a Authorizer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if route == "" {
+			route = c.Request.URL.Path
+		}
+		if c.Request.Method == http.MethodOptions || requiredScope(c.Request.Method, route) == keys.ScopeReadOnly {
+			c.Next()
+			return
+		}
+
+		req := buildAuthzRequest(c)
+		allowed, reason, err := a.Authorize(c.Request.Context(), req)
+		if err != nil {
+			internalError(c, fmt.Errorf("authorization: %w", err))
+			c.Abort()
+			return
+		}
+		if !allowed {
+			if reason == "" {
+				reason = "operation not permitted"
+			}
+			forbidden(c, reason)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// buildAuthzRequest derives actor, action, resource and attributes from the request.
+func buildAuthzRequest(c *gin.Context) AuthzRequest {
+	route := c.FullPath()
+	if route == "" {
+		route = c.Request.URL.Path
+	}
+
+	auth := c.GetString(authMethodKey)
+	if auth == "" {
+		auth = "none"
+	}
+
+	req := AuthzRequest{
+		Actor:  AuthzActor{Name: c.GetHeader(HeaderActor), Auth: auth, KeyID: c.GetString(authKeyIDKey), Owner: c.GetString(authOwnerKey)},
+		Action: c.Request.Method + " " + route,
+		Resource: AuthzResource{
+			Type:      resourceType(route),
+			ID:        resourceID(c),
+			CommandID: c.Param("cmdId"),
+		},
+	}
+
+	if c.Request.Body != nil && strings.HasPrefix(c.ContentType(), "application/json") {
+		body := c.Request.Body
+		head, err := io.ReadAll(io.LimitReader(body, authzBodyLimit+1))
+		c.Request.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(head), body), body}
+		if err == nil && len(head) <= authzBodyLimit {
+			var attrs map[string]any
+			if json.Unmarshal(head, &attrs) == nil {
+				req.Attributes = attrs
+			}
+		}
+	}
+	return req
+}
+
+// resourceID returns the object named by the path: ":id" on sandbox routes,
+// ":name" on stack, network, schedule and secret routes.
+func resourceID(c *gin.Context) string {
+	if id := c.Param("id"); id != "" {
+		return id
+	}
+	return c.Param("name")
+}
+
+// resourceType returns the first path segment after /v1, e.g. "sandboxes".
+func resourceType(route string) string {
+	rest := strings.TrimPrefix(route, "/v1/")
+	typ, _, _ := strings.Cut(rest, "/")
+	return typ
+}
+
+// HTTPAuthorizer delegates decisions to an external webhook. The request body is
+// {"input": AuthzRequest}, which matches the Open Policy Agent data API, and the
+// response must be {"result": true|false} or {"result": {"allow": bool, "reason": "..."}}.
+type HTTPAuthorizer struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTPAuthorizer creates an authorizer that POSTs decisions to url.
+func NewHTTPAuthorizer(url string, timeout time.Duration) *HTTPAuthorizer {
+	return &HTTPAuthorizer{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+// Authorize implements Authorizer.
+func (h *HTTPAuthorizer) Authorize(ctx context.Context, req AuthzRequest) (bool, string, error) {
+	payload, err := json.Marshal(map[string]any{"input": req})
+	if err != nil {
+		return false, "", err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(payload))
+	if err != nil {
+		return false, "", err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if id := logging.RequestID(ctx); id != "" {
+		httpReq.Header.Set(HeaderRequestID, id)
+	}
+
+	resp, err := h.client.Do(httpReq)
+	if err != nil {
+		return false, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, "", fmt.Errorf("webhook returned %s", resp.Status)
+	}
+
+	var out struct {
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return false, "", fmt.Errorf("decode webhook response: %w", err)
+	}
+
+	var allowed bool
+	if json.Unmarshal(out.Result, &allowed) == nil {
+		return allowed, "", nil
+	}
+	var decision struct {
+		Allow  bool   `json:"allow"`
+		Reason string `json:"reason"`
+	}
+	if err := json.Unmarshal(out.Result, &decision); err != nil {
+		return false, "", fmt.Errorf("webhook result must be a bool or {allow, reason}")
+	}
+	return decision.Allow, decision.Reason, nil
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"opensbx/models"
+)
+
+// execBatch handles POST /v1/sandboxes/:id/cmd/batch.
+// @Summary      Execute commands in sequence
+// @Description  Runs the given commands one after another, each once the previous one finished, and returns every step's command, stdout and stderr. With stop_on_error the batch ends at the first non-zero exit. Use ?stream=true to receive each step as an ND-JSON line as it finishes, followed by a final {"success": bool} line. Send Accept: text/event-stream to get the stream as Server-Sent Events, with step and result events.
+// @Tags         commands
+// @Accept       json
+// @Produce      json
+// @Produce      application/x-ndjson
+// @Produce      text/event-stream
+// @Param        id      path      string                      true   "Sandbox ID"
+// @Param        body    body      models.BatchCommandRequest  true   "Commands to execute"
+// @Param        stream  query     bool                        false  "Stream steps as ND-JSON (default: false)"
+// @Success      200  {object}  models.BatchCommandResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /sandboxes/{id}/cmd/batch [post]
+func (h *Handler) execBatch(c *gin.Context) {
+	var req models.BatchCommandRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		badRequest(c, err.Error())
+		return
+	}
+	for _, cmd := range req.Commands {
+		if msg := validateExecRequest(cmd); msg != "" {
+			badRequest(c, msg)
+			return
+		}
+	}
+
+	sandboxID := c.Param("id")
+	var stream *eventStream
+	if c.Query("stream") == "true" {
+		stream = h.newEventStream(c)
+		defer stream.close()
+	}
+
+	resp := models.BatchCommandResponse{Success: true}
+	for i, cmdReq := range req.Commands {
+		step, err := h.runBatchStep(c.Request.Context(), sandboxID, i, cmdReq)
+		if err != nil {
+			// Nothing ran yet: report the error (e.g. sandbox not found) as
+			// usual, unless a keepalive ping already started the stream.
+			if i == 0 && (stream == nil || !stream.close()) {
+				internalError(c, err)
+				return
+			}
+			step = models.BatchStep{Index: i, Error: err.Error()}
+		}
+		resp.Steps = append(resp.Steps, step)
+
+		if stream != nil {
+			stream.send("step", step)
+		}
+
+		if step.Error != "" || step.Command.ExitCode == nil || *step.Command.ExitCode != 0 {
+			resp.Success = false
+			if step.Error != "" || req.StopOnError {
+				break
+			}
+		}
+	}
+
+	if stream != nil {
+		stream.send("result", models.BatchCommandResponse{Success: resp.Success})
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// runBatchStep runs one command of a batch to completion and collects its output.
+func (h *Handler) runBatchStep(ctx context.Context, sandboxID string, index int, req models.ExecCommandRequest) (models.BatchStep, error) {
+	cmd, err := h.docker.ExecCommand(ctx, sandboxID, req)
+	if err != nil {
+		return models.BatchStep{}, err
+	}
+	cmd, err = h.docker.WaitCommand(ctx, sandboxID, cmd.ID)
+	if err != nil {
+		return models.BatchStep{}, err
+	}
+	step := models.BatchStep{Index: index, Command: cmd}
+	if logs, err := h.docker.GetCommandLogs(ctx, sandboxID, cmd.ID); err == nil {
+		step.Stdout = logs.Stdout
+		step.Stderr = logs.Stderr
+	}
+	return step, nil
+}
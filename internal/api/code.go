@@ -0,0 +1,67 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"opensbx/internal/docker"
+	"opensbx/internal/keys"
+	"opensbx/models"
+)
+
+// maxCodeTimeout is the longest timeout POST /v1/run accepts, in seconds.
+const maxCodeTimeout = 3600
+
+// runCode handles POST /v1/run.
+// @Summary      Run code
+// @Description  Runs a program in a fresh sandbox for its language and returns its stdout, stderr and exit code. Sandboxes come from a warm pool kept per language once it has been used, and each is deleted after one run. The language's image is pulled on first use if the API key has the images scope; otherwise a missing image is 400 IMAGE_NOT_FOUND. Supported languages: python, node, go, ruby, bash.
+// @Tags         run
+// @Accept       json
+// @Produce      json
+// @Param        body  body      models.RunCodeRequest  true  "Language, code and stdin"
+// @Success      200   {object}  models.RunCodeResponse
+// @Failure      400   {object}  ErrorResponse
+// @Failure      429   {object}  ErrorResponse
+// @Failure      500   {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /run [post]
+func (h *Handler) runCode(c *gin.Context) {
+	var req models.RunCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		badRequest(c, err.Error())
+		return
+	}
+	if msg := validateRunCodeRequest(req); msg != "" {
+		badRequest(c, msg)
+		return
+	}
+	if !h.checkQuota(c, nil) {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if callerAllows(c, keys.ScopeImages) {
+		ctx = docker.WithImagePull(ctx)
+	}
+	result, err := h.docker.RunCode(ctx, req)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// validateRunCodeRequest checks the language and timeout of a POST /v1/run
+// request. Returns an empty string when valid or a client-facing message otherwise.
+func validateRunCodeRequest(req models.RunCodeRequest) string {
+	if languages := docker.CodeLanguages(); !slices.Contains(languages, req.Language) {
+		return fmt.Sprintf("language must be one of %s", strings.Join(languages, ", "))
+	}
+	if req.Timeout < 0 || req.Timeout > maxCodeTimeout {
+		return fmt.Sprintf("timeout must be between 0 and %d", maxCodeTimeout)
+	}
+	return ""
+}
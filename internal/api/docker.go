@@ -3,38 +3,107 @@ package api
 import (
 	"context"
 	"io"
+	"net"
+	"time"
 
+	"opensbx/internal/docker"
 	"opensbx/models"
 )
 
 // DockerClient defines the sandbox operations used by the API handlers.
 type DockerClient interface {
 	Ping(ctx context.Context) error
-	List(ctx context.Context) ([]models.SandboxSummary, error)
+	ServerVersion(ctx context.Context) (string, error)
+	List(ctx context.Context, q models.SandboxListQuery) ([]models.SandboxSummary, int, error)
 	Create(ctx context.Context, req models.CreateSandboxRequest) (models.CreateSandboxResponse, error)
+	BeginIdempotent(ctx context.Context, key, requestHash string) (*models.CreateSandboxResponse, error)
+	FinishIdempotent(ctx context.Context, key string, resp *models.CreateSandboxResponse) error
 	Inspect(ctx context.Context, id string) (models.SandboxDetail, error)
+	WaitReady(ctx context.Context, id string) (string, error)
+	WaitPort(ctx context.Context, id string, timeout time.Duration) (string, error)
 	Start(ctx context.Context, id string) (models.RestartResponse, error)
 	Stop(ctx context.Context, id string) error
 	Restart(ctx context.Context, id string) (models.RestartResponse, error)
 	GetNetwork(ctx context.Context, id string) (models.SandboxNetwork, error)
+	Isolation(ctx context.Context, id string) (models.SandboxIsolation, error)
+	GetEnv(ctx context.Context, id string) (models.SandboxEnv, error)
+	UpdateEnv(ctx context.Context, id string, vars map[string]*string) (models.SandboxEnv, error)
+	CheckOwner(ctx context.Context, id string) error
+	Resolve(ctx context.Context, ref string) (string, error)
+	CheckQuota(ctx context.Context, res ...*models.ResourceLimits) error
+	AddDomain(ctx context.Context, id, host string) (models.SandboxDomain, error)
+	ListDomains(ctx context.Context, id string) ([]models.SandboxDomain, error)
+	RemoveDomain(ctx context.Context, id, host string) error
 	Remove(ctx context.Context, id string) error
+	Apply(ctx context.Context, req models.ApplyRequest) (models.ApplyResponse, error)
+	Snapshot(ctx context.Context, id string, req models.SnapshotRequest) (models.SnapshotResponse, error)
+	Clone(ctx context.Context, id string, req models.CloneRequest) (models.CreateSandboxResponse, error)
+	CreateStack(ctx context.Context, req models.CreateStackRequest) (models.Stack, error)
+	ListStacks(ctx context.Context) ([]models.Stack, error)
+	GetStack(ctx context.Context, name string) (models.Stack, error)
+	StartStack(ctx context.Context, name string) (models.Stack, error)
+	StopStack(ctx context.Context, name string) (models.Stack, error)
+	RemoveStack(ctx context.Context, name string) error
+	CreateNetworkGroup(ctx context.Context, name string) (models.NetworkGroup, error)
+	ListNetworkGroups(ctx context.Context) ([]models.NetworkGroup, error)
+	GetNetworkGroup(ctx context.Context, name string) (models.NetworkGroup, error)
+	RemoveNetworkGroup(ctx context.Context, name string) error
+	RunCode(ctx context.Context, req models.RunCodeRequest) (models.RunCodeResponse, error)
+	RunJob(ctx context.Context, req models.JobRequest) (models.JobResult, error)
+	CreateSchedule(ctx context.Context, req models.CreateScheduleRequest) (models.Schedule, error)
+	ListSchedules(ctx context.Context) ([]models.Schedule, error)
+	GetSchedule(ctx context.Context, name string) (models.Schedule, error)
+	ListScheduleRuns(ctx context.Context, name string) ([]models.ScheduleRun, error)
+	RemoveSchedule(ctx context.Context, name string) error
+	Checkpoint(ctx context.Context, id string, req models.CheckpointRequest) (models.Checkpoint, error)
+	ListCheckpoints(ctx context.Context, id string) ([]models.Checkpoint, error)
+	Restore(ctx context.Context, id, name string) (models.RestartResponse, error)
+	RemoveCheckpoint(ctx context.Context, id, name string) error
+	Export(ctx context.Context, id string, w io.Writer) error
+	Import(ctx context.Context, r io.Reader) (models.CreateSandboxResponse, error)
 	Pause(ctx context.Context, id string) error
 	Resume(ctx context.Context, id string) error
 	RenewExpiration(ctx context.Context, id string, timeout int) error
+	ExecInteractive(ctx context.Context, id string, cmd []string, rows, cols uint) (docker.TerminalSession, error)
 	ExecCommand(ctx context.Context, sandboxID string, req models.ExecCommandRequest) (models.CommandDetail, error)
 	GetCommand(ctx context.Context, sandboxID, cmdID string) (models.CommandDetail, error)
-	ListCommands(ctx context.Context, sandboxID string) ([]models.CommandDetail, error)
+	ListCommands(ctx context.Context, sandboxID string, q models.CommandListQuery) ([]models.CommandDetail, int, error)
 	KillCommand(ctx context.Context, sandboxID, cmdID string, signal int) (models.CommandDetail, error)
 	StreamCommandLogs(ctx context.Context, sandboxID, cmdID string) (io.ReadCloser, io.ReadCloser, error)
+	Logs(ctx context.Context, id string, q models.SandboxLogsQuery) (io.ReadCloser, io.ReadCloser, error)
 	GetCommandLogs(ctx context.Context, sandboxID, cmdID string) (models.CommandLogsResponse, error)
 	WaitCommand(ctx context.Context, sandboxID, cmdID string) (models.CommandDetail, error)
+	StartProcess(ctx context.Context, id string, req models.StartProcessRequest) (models.ProcessDetail, error)
+	ListProcesses(ctx context.Context, id string) ([]models.ProcessDetail, error)
+	GetProcess(ctx context.Context, id, name string) (models.ProcessDetail, error)
+	RemoveProcess(ctx context.Context, id, name string) error
+	CreateSession(ctx context.Context, id string, req models.CreateSessionRequest) (models.Session, error)
+	ListSessions(ctx context.Context, id string) ([]models.Session, error)
+	RemoveSession(ctx context.Context, id, sessionID string) error
+	ExecuteSession(ctx context.Context, id, sessionID string, req models.ExecuteRequest, emit func(models.SessionEvent)) error
+	PullGit(ctx context.Context, id string, req models.GitPullRequest) (models.GitStatus, error)
+	CreateTunnel(ctx context.Context, id string, req models.CreateTunnelRequest) (models.Tunnel, error)
+	ListTunnels(ctx context.Context, id string) ([]models.Tunnel, error)
+	RemoveTunnel(ctx context.Context, id, tunnelID string) error
+	DialTunnel(ctx context.Context, id, tunnelID string) (net.Conn, error)
+	RunningProcesses(ctx context.Context, id string) ([]models.ProcessInfo, error)
 	Stats(ctx context.Context, id string) (models.SandboxStats, error)
+	NodeStats(ctx context.Context) (models.NodeStats, error)
 	ReadFile(ctx context.Context, id, path string) (string, error)
 	WriteFile(ctx context.Context, id, path, content string) error
+	ReadFileStream(ctx context.Context, id, path string) (io.ReadCloser, int64, error)
+	WriteFileStream(ctx context.Context, id, path string, r io.Reader, size int64) error
 	DeleteFile(ctx context.Context, id, path string) error
 	ListDir(ctx context.Context, id, path string) (string, error)
+	FileTree(ctx context.Context, id, path string, depth int) ([]models.FileNode, bool, error)
+	SearchFiles(ctx context.Context, id, path, glob string, limit int) ([]models.FileNode, bool, error)
+	StatFile(ctx context.Context, id, path string) (models.FileStat, error)
+	UploadArchive(ctx context.Context, id, dir string, r io.Reader, format string) error
+	DownloadArchive(ctx context.Context, id, path, format string, w io.Writer) error
 	PullImage(ctx context.Context, image string) error
 	RemoveImage(ctx context.Context, id string, force bool) error
 	InspectImage(ctx context.Context, id string) (models.ImageDetail, error)
 	ListImages(ctx context.Context) ([]models.ImageSummary, error)
+	PruneImages(ctx context.Context, unusedFor time.Duration) (models.ImagePruneResponse, error)
+	Policy() models.HostPolicy
 }
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"opensbx/internal/docker"
+	"opensbx/models"
+)
+
+// addDomain handles POST /v1/sandboxes/:id/domains.
+// @Summary      Add a custom domain
+// @Description  Routes an external hostname to the sandbox's main port through the proxy. Point the domain's DNS at the proxy; with PROXY_ACME the certificate is issued on first visit. Subdomains of the base domain are reserved for sandbox names.
+// @Tags         sandboxes
+// @Accept       json
+// @Produce      json
+// @Param        id    path      string                   true  "Sandbox ID"
+// @Param        body  body      models.AddDomainRequest  true  "Domain"
+// @Success      201   {object}  models.SandboxDomain
+// @Failure      400   {object}  ErrorResponse
+// @Failure      404   {object}  ErrorResponse
+// @Failure      409   {object}  ErrorResponse
+// @Failure      500   {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /sandboxes/{id}/domains [post]
+func (h *Handler) addDomain(c *gin.Context) {
+	var req models.AddDomainRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		badRequest(c, err.Error())
+		return
+	}
+	host, err := docker.NormalizeDomain(req.Domain)
+	if err != nil {
+		badRequest(c, err.Error())
+		return
+	}
+	if base := strings.ToLower(strings.TrimSpace(h.BaseDomain())); host == base || strings.HasSuffix(host, "."+base) {
+		badRequest(c, "subdomains of "+base+" are reserved for sandbox names")
+		return
+	}
+
+	d, err := h.docker.AddDomain(c.Request.Context(), c.Param("id"), host)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	d.URL = buildDomainURL(d.Domain, h.BaseDomain(), h.proxyAddr)
+	c.JSON(http.StatusCreated, d)
+}
+
+// listDomains handles GET /v1/sandboxes/:id/domains.
+// @Summary      List custom domains
+// @Description  Lists the external hostnames routed to the sandbox.
+// @Tags         sandboxes
+// @Produce      json
+// @Param        id   path      string  true  "Sandbox ID"
+// @Success      200  {array}   models.SandboxDomain
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /sandboxes/{id}/domains [get]
+func (h *Handler) listDomains(c *gin.Context) {
+	domains, err := h.docker.ListDomains(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	for i := range domains {
+		domains[i].URL = buildDomainURL(domains[i].Domain, h.BaseDomain(), h.proxyAddr)
+	}
+	c.JSON(http.StatusOK, domains)
+}
+
+// removeDomain handles DELETE /v1/sandboxes/:id/domains/:domain.
+// @Summary      Remove a custom domain
+// @Description  Stops routing the hostname to the sandbox.
+// @Tags         sandboxes
+// @Param        id      path  string  true  "Sandbox ID"
+// @Param        domain  path  string  true  "Domain"
+// @Success      204
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /sandboxes/{id}/domains/{domain} [delete]
+func (h *Handler) removeDomain(c *gin.Context) {
+	if err := h.docker.RemoveDomain(c.Request.Context(), c.Param("id"), c.Param("domain")); err != nil {
+		internalError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -7,11 +7,15 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"opensbx/internal/docker"
+	"opensbx/internal/keys"
+	"opensbx/internal/secrets"
 )
 
 // ErrorResponse is the standard error body returned by all API endpoints.
+// Clients should match on Code, which is stable; Message is for humans and
+// may change.
 type ErrorResponse struct {
-	Code    string `json:"code" example:"BAD_REQUEST"`
+	Code    string `json:"code" example:"BAD_REQUEST" enums:"BAD_REQUEST,UNAUTHORIZED,FORBIDDEN,NOT_FOUND,TIMEOUT,RATE_LIMITED,QUOTA_EXCEEDED,INTERNAL_ERROR,SANDBOX_NOT_FOUND,SANDBOX_NAME_TAKEN,IMAGE_NOT_FOUND,IMAGE_EXISTS,INVALID_REFERENCE,ALREADY_RUNNING,ALREADY_STOPPED,ALREADY_PAUSED,NOT_PAUSED,OOM_KILLED,NOT_RUNNING,OPERATION_IN_PROGRESS,LIFETIME_EXCEEDED,IDEMPOTENCY_KEY_REUSED,IDEMPOTENCY_IN_PROGRESS,COMMAND_NOT_FOUND,COMMAND_FINISHED,PROCESS_NOT_FOUND,PROCESS_EXISTS,SESSION_NOT_FOUND,SESSION_BUSY,INTERPRETER_UNAVAILABLE,TUNNEL_NOT_FOUND,PORT_UNREACHABLE,GIT_FAILED,HOOK_FAILED,PATH_NOT_FOUND,NOT_A_DIRECTORY,NOT_A_FILE,INVALID_ARCHIVE,INVALID_BUNDLE,CHECKPOINT_NOT_FOUND,INVALID_CHECKPOINT,INVALID_DOMAIN,INVALID_ENV,DOMAIN_TAKEN,DOMAIN_NOT_FOUND,RUNTIME_NOT_FOUND,UNSUPPORTED_BY_ENGINE,NETWORK_POLICY_UNSUPPORTED,POLICY_VIOLATION,STACK_NOT_FOUND,STACK_EXISTS,NETWORK_NOT_FOUND,NETWORK_EXISTS,NETWORK_IN_USE,SCHEDULE_NOT_FOUND,SCHEDULE_EXISTS,SECRETS_DISABLED,SECRET_NOT_FOUND,INVALID_SECRET_NAME,API_KEY_NOT_FOUND,INVALID_SCOPE"`
 	Message string `json:"message" example:"image is required"`
 }
 
@@ -20,20 +24,9 @@ func badRequest(c *gin.Context, msg string) {
 	c.JSON(http.StatusBadRequest, ErrorResponse{Code: "BAD_REQUEST", Message: msg})
 }
 
-// notFound writes a 404 response with code NOT_FOUND for the given resource name.
-func notFound(c *gin.Context, resource string) {
-	c.JSON(http.StatusNotFound, ErrorResponse{Code: "NOT_FOUND", Message: resource + " not found"})
-}
-
-// conflict writes a 409 response with code CONFLICT for state-related errors
-// (e.g. starting an already-running sandbox or stopping an already-stopped one).
-func conflict(c *gin.Context, msg string) {
-	c.JSON(http.StatusConflict, ErrorResponse{Code: "CONFLICT", Message: msg})
-}
-
-// requestTimeout writes a 408 response with code TIMEOUT for operations that exceeded their deadline.
-func requestTimeout(c *gin.Context, msg string) {
-	c.JSON(http.StatusRequestTimeout, ErrorResponse{Code: "TIMEOUT", Message: msg})
+// forbidden writes a 403 response with code FORBIDDEN when a request is denied by policy.
+func forbidden(c *gin.Context, msg string) {
+	c.JSON(http.StatusForbidden, ErrorResponse{Code: "FORBIDDEN", Message: msg})
 }
 
 // rateLimited writes a 429 response with code RATE_LIMITED when the caller exceeds request limits.
@@ -41,47 +34,84 @@ func rateLimited(c *gin.Context, msg string) {
 	c.JSON(http.StatusTooManyRequests, ErrorResponse{Code: "RATE_LIMITED", Message: msg})
 }
 
-// internalError writes a 500 response with code INTERNAL_ERROR.
-// It first checks for well-known sentinel errors and downgrades to the appropriate status code.
+// knownErrors maps sentinel errors to a status and a specific error code.
+// msg replaces the error's text when set.
+var knownErrors = []struct {
+	err    error
+	status int
+	code   string
+	msg    string
+}{
+	{docker.ErrNotFound, http.StatusNotFound, "SANDBOX_NOT_FOUND", "sandbox not found"},
+	{docker.ErrNameTaken, http.StatusConflict, "SANDBOX_NAME_TAKEN", ""},
+	{docker.ErrImageNotFound, http.StatusBadRequest, "IMAGE_NOT_FOUND", "image not found locally, use POST /v1/images/pull to download it first"},
+	{docker.ErrImageExists, http.StatusConflict, "IMAGE_EXISTS", ""},
+	{docker.ErrInvalidReference, http.StatusBadRequest, "INVALID_REFERENCE", ""},
+	{docker.ErrQuotaExceeded, http.StatusTooManyRequests, "QUOTA_EXCEEDED", ""},
+	{docker.ErrAlreadyRunning, http.StatusConflict, "ALREADY_RUNNING", ""},
+	{docker.ErrAlreadyStopped, http.StatusConflict, "ALREADY_STOPPED", ""},
+	{docker.ErrAlreadyPaused, http.StatusConflict, "ALREADY_PAUSED", ""},
+	{docker.ErrNotPaused, http.StatusConflict, "NOT_PAUSED", ""},
+	{docker.ErrOOMKilled, http.StatusConflict, "OOM_KILLED", ""}, // before ErrNotRunning, which it wraps
+	{docker.ErrNotRunning, http.StatusConflict, "NOT_RUNNING", ""},
+	{docker.ErrOperationInProgress, http.StatusConflict, "OPERATION_IN_PROGRESS", ""},
+	{docker.ErrLifetimeExceeded, http.StatusConflict, "LIFETIME_EXCEEDED", ""},
+	{docker.ErrIdempotencyKeyReused, http.StatusUnprocessableEntity, "IDEMPOTENCY_KEY_REUSED", ""},
+	{docker.ErrIdempotencyInProgress, http.StatusConflict, "IDEMPOTENCY_IN_PROGRESS", ""},
+	{docker.ErrCommandNotFound, http.StatusNotFound, "COMMAND_NOT_FOUND", "command not found"},
+	{docker.ErrCommandFinished, http.StatusConflict, "COMMAND_FINISHED", ""},
+	{docker.ErrProcessNotFound, http.StatusNotFound, "PROCESS_NOT_FOUND", "process not found"},
+	{docker.ErrProcessExists, http.StatusConflict, "PROCESS_EXISTS", ""},
+	{docker.ErrSessionNotFound, http.StatusNotFound, "SESSION_NOT_FOUND", "session not found"},
+	{docker.ErrSessionBusy, http.StatusConflict, "SESSION_BUSY", ""},
+	{docker.ErrInterpreterUnavailable, http.StatusBadRequest, "INTERPRETER_UNAVAILABLE", ""},
+	{docker.ErrTunnelNotFound, http.StatusNotFound, "TUNNEL_NOT_FOUND", "tunnel not found"},
+	{docker.ErrPortUnreachable, http.StatusBadGateway, "PORT_UNREACHABLE", ""},
+	{docker.ErrGitFailed, http.StatusBadRequest, "GIT_FAILED", ""},
+	{docker.ErrHookFailed, http.StatusBadRequest, "HOOK_FAILED", ""},
+	{docker.ErrPathNotFound, http.StatusNotFound, "PATH_NOT_FOUND", ""}, // carries the in-sandbox error message when there is one
+	{docker.ErrNotADirectory, http.StatusBadRequest, "NOT_A_DIRECTORY", ""},
+	{docker.ErrNotAFile, http.StatusBadRequest, "NOT_A_FILE", ""},
+	{docker.ErrInvalidArchive, http.StatusBadRequest, "INVALID_ARCHIVE", ""},
+	{docker.ErrInvalidBundle, http.StatusBadRequest, "INVALID_BUNDLE", ""},
+	{docker.ErrCheckpointNotFound, http.StatusNotFound, "CHECKPOINT_NOT_FOUND", "checkpoint not found"},
+	{docker.ErrInvalidCheckpoint, http.StatusBadRequest, "INVALID_CHECKPOINT", ""},
+	{docker.ErrInvalidDomain, http.StatusBadRequest, "INVALID_DOMAIN", ""},
+	{docker.ErrInvalidEnv, http.StatusBadRequest, "INVALID_ENV", ""},
+	{docker.ErrDomainTaken, http.StatusConflict, "DOMAIN_TAKEN", ""},
+	{docker.ErrDomainNotFound, http.StatusNotFound, "DOMAIN_NOT_FOUND", "domain not found"},
+	{docker.ErrRuntimeNotFound, http.StatusBadRequest, "RUNTIME_NOT_FOUND", ""},
+	{docker.ErrUnsupportedByEngine, http.StatusNotImplemented, "UNSUPPORTED_BY_ENGINE", ""},
+	{docker.ErrNetworkPolicyUnsupported, http.StatusBadRequest, "NETWORK_POLICY_UNSUPPORTED", ""},
+	{docker.ErrPolicyViolation, http.StatusForbidden, "POLICY_VIOLATION", ""},
+	{docker.ErrStackNotFound, http.StatusNotFound, "STACK_NOT_FOUND", "stack not found"},
+	{docker.ErrStackExists, http.StatusConflict, "STACK_EXISTS", ""},
+	{docker.ErrNetworkGroupNotFound, http.StatusNotFound, "NETWORK_NOT_FOUND", ""},
+	{docker.ErrNetworkGroupExists, http.StatusConflict, "NETWORK_EXISTS", ""},
+	{docker.ErrNetworkGroupInUse, http.StatusConflict, "NETWORK_IN_USE", ""},
+	{docker.ErrScheduleNotFound, http.StatusNotFound, "SCHEDULE_NOT_FOUND", "schedule not found"},
+	{docker.ErrScheduleExists, http.StatusConflict, "SCHEDULE_EXISTS", ""},
+	{docker.ErrSecretsDisabled, http.StatusBadRequest, "SECRETS_DISABLED", ""},
+	{secrets.ErrNotFound, http.StatusNotFound, "SECRET_NOT_FOUND", ""},
+	{secrets.ErrInvalidName, http.StatusBadRequest, "INVALID_SECRET_NAME", ""},
+	{keys.ErrNotFound, http.StatusNotFound, "API_KEY_NOT_FOUND", "api key not found"},
+	{keys.ErrInvalidScope, http.StatusBadRequest, "INVALID_SCOPE", ""},
+	{context.DeadlineExceeded, http.StatusRequestTimeout, "TIMEOUT", "operation timed out"},
+}
+
+// internalError writes the response for an error returned by the backend:
+// the status and code of the first matching knownErrors entry, or a 500 with
+// code INTERNAL_ERROR.
 func internalError(c *gin.Context, err error) {
-	if errors.Is(err, docker.ErrNotFound) {
-		notFound(c, "sandbox")
-		return
-	}
-	if errors.Is(err, docker.ErrImageNotFound) {
-		badRequest(c, "image not found locally, use POST /v1/images/pull to download it first")
-		return
-	}
-	if errors.Is(err, docker.ErrAlreadyRunning) {
-		conflict(c, err.Error())
-		return
-	}
-	if errors.Is(err, docker.ErrAlreadyStopped) {
-		conflict(c, err.Error())
-		return
-	}
-	if errors.Is(err, docker.ErrAlreadyPaused) {
-		conflict(c, err.Error())
-		return
-	}
-	if errors.Is(err, docker.ErrNotPaused) {
-		conflict(c, err.Error())
-		return
-	}
-	if errors.Is(err, docker.ErrNotRunning) {
-		conflict(c, err.Error())
-		return
-	}
-	if errors.Is(err, docker.ErrCommandNotFound) {
-		notFound(c, "command")
-		return
-	}
-	if errors.Is(err, docker.ErrCommandFinished) {
-		conflict(c, err.Error())
-		return
-	}
-	if errors.Is(err, context.DeadlineExceeded) {
-		requestTimeout(c, "operation timed out")
+	for _, k := range knownErrors {
+		if !errors.Is(err, k.err) {
+			continue
+		}
+		msg := k.msg
+		if msg == "" {
+			msg = err.Error()
+		}
+		c.JSON(k.status, ErrorResponse{Code: k.code, Message: msg})
 		return
 	}
 	c.JSON(http.StatusInternalServerError, ErrorResponse{Code: "INTERNAL_ERROR", Message: err.Error()})
@@ -0,0 +1,23 @@
+package api
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestKnownErrorsDocumented(t *testing.T) {
+	field, _ := reflect.TypeOf(ErrorResponse{}).FieldByName("Code")
+	documented := strings.Split(field.Tag.Get("enums"), ",")
+	seen := map[string]bool{}
+	for _, k := range knownErrors {
+		if !slices.Contains(documented, k.code) {
+			t.Errorf("code %s is missing from the ErrorResponse.Code enums", k.code)
+		}
+		if seen[k.code] {
+			t.Errorf("code %s is used for more than one error", k.code)
+		}
+		seen[k.code] = true
+	}
+}
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"opensbx/internal/docker"
+	"opensbx/models"
+)
+
+// pullGit handles POST /v1/sandboxes/:id/git/pull.
+// @Summary      Pull a repository
+// @Description  Fast-forwards a repository inside a running sandbox to its upstream branch, by default the one cloned by the git option on create. Credentials are not kept from create: send the token or ssh_key again for private repositories. Fails with GIT_FAILED and git's message when the pull does, e.g. on diverged history or a detached HEAD.
+// @Tags         sandboxes
+// @Accept       json
+// @Produce      json
+// @Param        id    path      string                 true   "Sandbox ID"
+// @Param        body  body      models.GitPullRequest  false  "Repository and credentials"
+// @Success      200   {object}  models.GitStatus
+// @Failure      400   {object}  ErrorResponse
+// @Failure      404   {object}  ErrorResponse
+// @Failure      409   {object}  ErrorResponse
+// @Failure      500   {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /sandboxes/{id}/git/pull [post]
+func (h *Handler) pullGit(c *gin.Context) {
+	var req models.GitPullRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			badRequest(c, err.Error())
+			return
+		}
+	}
+	if msg := docker.ValidateGitPull(req); msg != "" {
+		badRequest(c, msg)
+		return
+	}
+
+	status, err := h.docker.PullGit(c.Request.Context(), c.Param("id"), req)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
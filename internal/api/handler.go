@@ -1,21 +1,41 @@
 package api
 
 import (
-	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"opensbx/internal/docker"
+	"opensbx/internal/firewall"
+	"opensbx/internal/keys"
 	"opensbx/models"
 )
 
 // Handler holds dependencies for all API handlers.
 type Handler struct {
+	mu         sync.RWMutex // guards baseDomain, which changes on config reload
 	docker     DockerClient
-	baseDomain string // base domain for proxy URLs (e.g. "localhost")
-	proxyAddr  string // proxy listen address (e.g. ":3000")
+	baseDomain string        // base domain for proxy URLs (e.g. "localhost")
+	proxyAddr  string        // proxy listen address (e.g. ":3000")
+	keys       KeyStore      // scoped API keys, nil = /v1/admin/keys disabled
+	audit      AuditLog      // audit log, nil = GET /v1/audit disabled
+	secrets    SecretStore   // encrypted secrets, nil = /v1/secrets disabled
+	checks     []healthCheck // components checked for readiness besides Docker
+	version    string        // build version reported by /v1/health
+	streams    StreamLimits  // bounds on streaming responses
+	origins    []string      // browser origins besides the API's host that may open WebSockets
 }
 
 // New creates a Handler with the given Docker client and proxy config.
@@ -27,39 +47,56 @@ func New(d DockerClient, baseDomain, proxyAddr string) *Handler {
 // Local domains return http URLs and keep the proxy port when needed.
 // Public domains return https URLs without exposing internal proxy ports.
 func (h *Handler) proxyURL(name string) string {
-	return buildSandboxURL(name, h.baseDomain, h.proxyAddr)
-}
-
-// healthCheck handles GET /health.
-// @Summary      Health check
-// @Description  Returns the health status of the API and its Docker daemon connection.
-// @Tags         system
-// @Produce      json
-// @Success      200  {object}  map[string]string  "status: healthy"
-// @Failure      503  {object}  map[string]string  "status: unhealthy"
-// @Router       /health [get]
-func (h *Handler) healthCheck(c *gin.Context) {
-	if err := h.docker.Ping(c.Request.Context()); err != nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{
-			"status": "unhealthy",
-			"error":  err.Error(),
-		})
-		return
-	}
-	c.JSON(http.StatusOK, gin.H{"status": "healthy"})
+	return buildSandboxURL(name, h.BaseDomain(), h.proxyAddr)
+}
+
+// SetBaseDomain changes the base domain of proxy URLs. Safe to call while
+// requests are served.
+func (h *Handler) SetBaseDomain(domain string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.baseDomain = domain
+}
+
+// BaseDomain returns the base domain of proxy URLs.
+func (h *Handler) BaseDomain() string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.baseDomain
 }
 
 // listSandboxes handles GET /v1/sandboxes.
 // @Summary      List sandboxes
-// @Description  List all sandboxes (running and stopped).
+// @Description  List sandboxes (running and stopped), optionally filtered, sorted and paged. total counts the matches before limit and offset. The response carries an ETag; send it back in If-None-Match to get 304 Not Modified while the list is unchanged.
 // @Tags         sandboxes
 // @Produce      json
+// @Param        state        query     string  false  "Only sandboxes in this state: running, exited, paused, created, restarting, dead or removed"
+// @Param        image        query     string  false  "Only sandboxes of this image"
+// @Param        name_prefix  query     string  false  "Only sandboxes whose name starts with this"
+// @Param        label        query     []string  false  "Only sandboxes with this label: key=value, or key for any value. Repeat to require several"  collectionFormat(multi)
+// @Param        sort         query     string  false  "Sort by created (default), name or image"
+// @Param        order        query     string  false  "asc (default) or desc"
+// @Param        limit        query     int     false  "Maximum sandboxes to return (max 1000, default all)"
+// @Param        offset       query     int     false  "Matches to skip"
+// @Param        If-None-Match  header  string  false  "ETag of a previous response"
 // @Success      200  {object}  map[string]interface{}  "List of sandboxes"
+// @Success      304  "Not Modified"
+// @Failure      400  {object}  ErrorResponse
 // @Failure      500  {object}  ErrorResponse
 // @Security     ApiKeyAuth
 // @Router       /sandboxes [get]
 func (h *Handler) listSandboxes(c *gin.Context) {
-	items, err := h.docker.List(c.Request.Context())
+	var q models.SandboxListQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		badRequest(c, err.Error())
+		return
+	}
+	if msg := validateListQuery(q); msg != "" {
+		badRequest(c, msg)
+		return
+	}
+
+	items, total, err := h.docker.List(c.Request.Context(), q)
 	if err != nil {
 		internalError(c, err)
 		return
@@ -70,22 +107,62 @@ func (h *Handler) listSandboxes(c *gin.Context) {
 	}
 
 	if len(items) == 0 {
-		c.JSON(http.StatusOK, gin.H{"sandboxes": items, "message": "no sandboxes found"})
+		jsonWithETag(c, http.StatusOK, gin.H{"sandboxes": items, "total": total, "message": "no sandboxes found"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"sandboxes": items})
+	jsonWithETag(c, http.StatusOK, gin.H{"sandboxes": items, "total": total})
+}
+
+// maxListLimit caps the limit of paged list endpoints.
+const maxListLimit = 1000
+
+// sandboxStates are the values accepted by ?state= on GET /v1/sandboxes.
+var sandboxStates = []string{"created", "running", "paused", "restarting", "removing", "exited", "dead", "removed"}
+
+// validateListQuery returns an error message if q is invalid, or "" if valid.
+func validateListQuery(q models.SandboxListQuery) string {
+	if q.State != "" && !slices.Contains(sandboxStates, q.State) {
+		return "state must be one of " + strings.Join(sandboxStates, ", ")
+	}
+	if _, msg := docker.ParseLabelSelectors(q.Label); msg != "" {
+		return msg
+	}
+	switch q.Sort {
+	case "", "created", "name", "image":
+	default:
+		return "sort must be created, name or image"
+	}
+	return validatePage(q.Order, q.Limit, q.Offset)
+}
+
+// validatePage checks the order, limit and offset of a paged list request.
+func validatePage(order string, limit, offset int) string {
+	switch {
+	case order != "" && order != "asc" && order != "desc":
+		return "order must be asc or desc"
+	case limit < 0 || limit > maxListLimit:
+		return fmt.Sprintf("limit must be between 0 and %d", maxListLimit)
+	case offset < 0:
+		return "offset must be >= 0"
+	}
+	return ""
 }
 
 // createSandbox handles POST /v1/sandboxes.
 // @Summary      Create a sandbox
-// @Description  Create and start a new Docker container. Returns its ID and assigned host ports.
+// @Description  Create and start a new Docker container. Returns its ID and assigned host ports. A name that is already in use returns 409 with code SANDBOX_NAME_TAKEN. With ready_check and wait_ready=true, the response is sent once the check passes or times out, and reports which in ready. A retry with the same Idempotency-Key within 24 hours returns the sandbox the first request created, with the Idempotent-Replayed header set.
 // @Tags         sandboxes
 // @Accept       json
 // @Produce      json
-// @Param        body  body      models.CreateSandboxRequest  true  "Sandbox configuration"
-// @Success      201   {object}  models.CreateSandboxResponse
+// @Param        body             body      models.CreateSandboxRequest  true   "Sandbox configuration"
+// @Param        wait_ready       query     bool                         false  "Wait for ready_check before responding"
+// @Param        Idempotency-Key  header    string                       false  "Unique key of this create, at most 255 characters"
+// @Success      201         {object}  models.CreateSandboxResponse
 // @Failure      400   {object}  ErrorResponse
+// @Failure      409   {object}  ErrorResponse
+// @Failure      422   {object}  ErrorResponse
+// @Failure      429   {object}  ErrorResponse
 // @Failure      500   {object}  ErrorResponse
 // @Security     ApiKeyAuth
 // @Router       /sandboxes [post]
@@ -96,39 +173,494 @@ func (h *Handler) createSandbox(c *gin.Context) {
 		return
 	}
 
-	if req.Timeout < 0 {
-		badRequest(c, "timeout must be >= 0")
+	if msg := validateCreateRequest(req); msg != "" {
+		badRequest(c, msg)
+		return
+	}
+	waitReady := c.Query("wait_ready") == "true"
+	if waitReady && req.ReadyCheck == nil {
+		badRequest(c, "wait_ready requires a ready_check")
+		return
+	}
+	key := c.GetHeader(HeaderIdempotencyKey)
+	if len(key) > maxIdempotencyKeyLen {
+		badRequest(c, fmt.Sprintf("%s must be at most %d characters", HeaderIdempotencyKey, maxIdempotencyKeyLen))
 		return
 	}
+
+	ctx := c.Request.Context()
+	var result *models.CreateSandboxResponse
+	if key != "" {
+		replay, err := h.docker.BeginIdempotent(ctx, key, idempotencyHash(req))
+		if err != nil {
+			internalError(c, err)
+			return
+		}
+		if replay != nil {
+			c.Header(HeaderIdempotentReplayed, "true")
+			result = replay
+		}
+	}
+	if result == nil {
+		created, err := h.docker.Create(ctx, req)
+		if err != nil {
+			h.finishIdempotent(ctx, key, nil)
+			internalError(c, err)
+			return
+		}
+		// Recorded before waiting, so a client that times out while the
+		// sandbox gets ready finds it on retry.
+		h.finishIdempotent(ctx, key, &created)
+		result = &created
+	}
+
+	c.Set(auditSandboxKey, result.ID)
+	if waitReady {
+		ready, err := h.docker.WaitReady(ctx, result.ID)
+		if err != nil {
+			internalError(c, err)
+			return
+		}
+		result.Ready = ready
+	}
+	result.URL = h.proxyURL(result.Name)
+	c.JSON(http.StatusCreated, result)
+}
+
+// validateCreateRequest checks name, timeout, max lifetime, resource limits, labels, ready check, git, hooks and network options.
+// Returns an empty string when valid or a client-facing message otherwise.
+func validateCreateRequest(req models.CreateSandboxRequest) string {
+	if req.Name != "" && !docker.ValidSandboxName(req.Name) {
+		return "name must be 1-63 lowercase letters, digits or hyphens, without leading, trailing or double hyphens"
+	}
+	if req.Timeout < 0 {
+		return "timeout must be >= 0"
+	}
+	if req.MaxLifetime < 0 {
+		return "max_lifetime must be >= 0"
+	}
+	switch req.ExpirationAction {
+	case "", docker.ExpirationStop, docker.ExpirationDelete:
+	default:
+		return "expiration_action must be \"stop\" or \"delete\""
+	}
+	switch req.TimeoutMode {
+	case "", docker.TimeoutAbsolute, docker.TimeoutIdle:
+	default:
+		return "timeout_mode must be \"absolute\" or \"idle\""
+	}
 	if req.Resources != nil {
 		if req.Resources.Memory < 0 {
-			badRequest(c, "resources.memory must be >= 0")
-			return
+			return "resources.memory must be >= 0"
 		}
 		if req.Resources.Memory > 8192 {
-			badRequest(c, "resources.memory must be <= 8192 (8GB)")
-			return
+			return "resources.memory must be <= 8192 (8GB)"
 		}
 		if req.Resources.CPUs < 0 {
-			badRequest(c, "resources.cpus must be >= 0")
-			return
+			return "resources.cpus must be >= 0"
 		}
 		if req.Resources.CPUs > 4.0 {
-			badRequest(c, "resources.cpus must be <= 4.0")
+			return "resources.cpus must be <= 4.0"
+		}
+	}
+	if msg := validateSecurity(req.Security); msg != "" {
+		return msg
+	}
+	if req.Runtime != "" && !runtimeNamePattern.MatchString(req.Runtime) {
+		return "runtime must be a runtime name, e.g. \"runsc\""
+	}
+	if msg := docker.ValidateLabels(req.Labels); msg != "" {
+		return msg
+	}
+	if msg := docker.ValidateReadyCheck(req.ReadyCheck, req.Ports); msg != "" {
+		return msg
+	}
+	if msg := validateProcesses(req.Processes); msg != "" {
+		return msg
+	}
+	if msg := docker.ValidateGitSource(req.Git); msg != "" {
+		return msg
+	}
+	if msg := docker.ValidateHooks(req.Hooks); msg != "" {
+		return msg
+	}
+	return validateNetwork(req)
+}
+
+// capabilityPattern matches a Linux capability name, with or without the CAP_ prefix.
+var capabilityPattern = regexp.MustCompile(`^[A-Za-z_]+$`)
+
+// validateSecurity checks the hardening options of a create request. Whether
+// they loosen the server defaults is checked by the docker client.
+func validateSecurity(opts *models.SecurityOptions) string {
+	if opts == nil {
+		return ""
+	}
+	for _, capName := range opts.CapDrop {
+		if !capabilityPattern.MatchString(capName) {
+			return fmt.Sprintf("security.cap_drop: invalid capability %q", capName)
+		}
+	}
+	if opts.SeccompProfile != "" {
+		var profile map[string]any
+		if err := json.Unmarshal([]byte(opts.SeccompProfile), &profile); err != nil {
+			return "security.seccomp_profile must be a JSON seccomp profile"
+		}
+	}
+	if opts.PidsLimit < 0 {
+		return "security.pids_limit must be >= 0"
+	}
+	if opts.User != "" && !execUserPattern.MatchString(opts.User) {
+		return "security.user must be a name or uid, optionally followed by :group or :gid"
+	}
+	return ""
+}
+
+// maxBandwidthKbps caps bandwidth limits at 10 Gbit/s.
+const maxBandwidthKbps = 10_000_000
+
+// validateNetwork checks the network mode, egress rules and bandwidth limits.
+func validateNetwork(req models.CreateSandboxRequest) string {
+	switch req.Network {
+	case "", docker.NetworkBridge:
+	case docker.NetworkInternal, docker.NetworkNone:
+		// The proxy reaches sandboxes through published ports, which these modes lack.
+		if len(req.Ports) > 0 {
+			return fmt.Sprintf("ports cannot be exposed with network %q", req.Network)
+		}
+		if req.Egress != nil {
+			return fmt.Sprintf("egress rules require network %q", docker.NetworkBridge)
+		}
+	default:
+		return "network must be \"bridge\", \"internal\" or \"none\""
+	}
+	if req.NetworkGroup != "" {
+		if req.Network == docker.NetworkNone {
+			return "network_group cannot be combined with network \"none\""
+		}
+		if !docker.ValidSandboxName(req.NetworkGroup) {
+			return "network_group must be the name of a network created with POST /v1/networks"
+		}
+	}
+	if req.Egress != nil {
+		if len(req.Egress.Allow) > 0 && len(req.Egress.Deny) > 0 {
+			return "egress.allow and egress.deny cannot be combined"
+		}
+		for _, rules := range [][]string{req.Egress.Allow, req.Egress.Deny} {
+			if _, err := firewall.ParseRules(strings.Join(rules, ",")); err != nil {
+				return err.Error()
+			}
+		}
+	}
+	if bw := req.Bandwidth; bw != nil {
+		if bw.IngressKbps < 0 || bw.IngressKbps > maxBandwidthKbps || bw.EgressKbps < 0 || bw.EgressKbps > maxBandwidthKbps {
+			return fmt.Sprintf("bandwidth limits must be between 0 and %d kbit/s", maxBandwidthKbps)
+		}
+	}
+	return ""
+}
+
+// runtimeNamePattern mirrors Docker's rules for OCI runtime names.
+var runtimeNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// applySpec handles POST /v1/apply.
+// @Summary      Apply a declarative sandbox spec
+// @Description  Reconciles sandboxes to match the spec. Sandboxes are matched by name: missing ones are created (and seeded with files), changed ones are recreated, unchanged ones are left running. With prune, sandboxes previously created by apply but missing from the spec are deleted. Sandboxes created via POST /sandboxes are never modified.
+// @Tags         sandboxes
+// @Accept       json
+// @Produce      json
+// @Param        body  body      models.ApplyRequest  true  "Desired sandboxes"
+// @Success      200   {object}  models.ApplyResponse
+// @Failure      400   {object}  ErrorResponse
+// @Failure      429   {object}  ErrorResponse
+// @Failure      500   {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /apply [post]
+func (h *Handler) applySpec(c *gin.Context) {
+	var req models.ApplyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		badRequest(c, err.Error())
+		return
+	}
+
+	seen := make(map[string]bool, len(req.Sandboxes))
+	for _, spec := range req.Sandboxes {
+		if !docker.ValidSandboxName(spec.Name) {
+			badRequest(c, fmt.Sprintf("%q: name must be 1-63 lowercase letters, digits or hyphens, without leading, trailing or double hyphens", spec.Name))
+			return
+		}
+		if seen[spec.Name] {
+			badRequest(c, fmt.Sprintf("duplicate sandbox name %q", spec.Name))
+			return
+		}
+		seen[spec.Name] = true
+		if msg := validateCreateRequest(spec.CreateRequest()); msg != "" {
+			badRequest(c, spec.Name+": "+msg)
 			return
 		}
 	}
 
-	result, err := h.docker.Create(c.Request.Context(), req)
+	result, err := h.docker.Apply(c.Request.Context(), req)
 	if err != nil {
 		internalError(c, err)
 		return
 	}
 
+	for i := range result.Results {
+		if result.Results[i].Action != docker.ApplyDeleted && result.Results[i].Action != docker.ApplyFailed {
+			result.Results[i].URL = h.proxyURL(result.Results[i].Name)
+		}
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// importSandbox handles POST /v1/sandboxes/import.
+// @Summary      Import a sandbox bundle or image archive
+// @Description  Creates a sandbox from the raw tar in the request body: a bundle produced by GET /sandboxes/{id}/export, whose ports, resource limits and labels are kept, or a plain docker save (exactly one image) or docker export tarball, which gets the defaults of POST /sandboxes. A bundle's image.tar must hold exactly one image, tagged as metadata.json names it and nothing else. The image is loaded as opensbx-import/<random>:<unix time>, so importing never retags an existing image.
+// @Tags         sandboxes
+// @Accept       application/x-tar
+// @Produce      json
+// @Param        bundle  body      string  true  "Sandbox bundle, docker save or docker export tarball"
+// @Success      201     {object}  models.CreateSandboxResponse
+// @Failure      400     {object}  ErrorResponse
+// @Failure      429     {object}  ErrorResponse
+// @Failure      500     {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /sandboxes/import [post]
+func (h *Handler) importSandbox(c *gin.Context) {
+	result, err := h.docker.Import(c.Request.Context(), c.Request.Body)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+
+	c.Set(auditSandboxKey, result.ID)
 	result.URL = h.proxyURL(result.Name)
 	c.JSON(http.StatusCreated, result)
 }
 
+// snapshotSandbox handles POST /v1/sandboxes/:id/snapshot.
+// @Summary      Snapshot a sandbox to an image
+// @Description  Commits the sandbox filesystem (installed deps, generated files) to a new image, optionally pushing it to a registry. Create identical sandboxes later by passing the returned image to POST /sandboxes. Without image the snapshot is named opensbx-snapshot/<owner>/<name>:<unix time>; other names need the images scope. An existing tag is only replaced when it is a snapshot by the same owner, otherwise 409 IMAGE_EXISTS.
+// @Tags         sandboxes
+// @Accept       json
+// @Produce      json
+// @Param        id    path      string                  true   "Sandbox ID"
+// @Param        body  body      models.SnapshotRequest  false  "Snapshot options"
+// @Success      201   {object}  models.SnapshotResponse
+// @Failure      400   {object}  ErrorResponse
+// @Failure      403   {object}  ErrorResponse
+// @Failure      404   {object}  ErrorResponse
+// @Failure      409   {object}  ErrorResponse
+// @Failure      500   {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /sandboxes/{id}/snapshot [post]
+func (h *Handler) snapshotSandbox(c *gin.Context) {
+	var req models.SnapshotRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			badRequest(c, err.Error())
+			return
+		}
+	}
+	if req.Image != "" && !callerAllows(c, keys.ScopeImages) && !docker.InSnapshotNamespace(req.Image, c.GetString(authOwnerKey)) {
+		forbidden(c, "snapshot images outside opensbx-snapshot/<owner>/ need the images scope")
+		return
+	}
+
+	result, err := h.docker.Snapshot(c.Request.Context(), c.Param("id"), req)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, result)
+}
+
+// cloneSandbox handles POST /v1/sandboxes/:id/clone.
+// @Summary      Clone a sandbox
+// @Description  Creates a new sandbox with the configuration of an existing one: ports, resources, command, environment, network policy, labels and ready check. By default the source's filesystem is committed and copied to the clone; with filesystem=false the clone starts from the source's original image. The source keeps running.
+// @Tags         sandboxes
+// @Accept       json
+// @Produce      json
+// @Param        id    path      string               true   "Sandbox ID"
+// @Param        body  body      models.CloneRequest  false  "Clone options"
+// @Success      201   {object}  models.CreateSandboxResponse
+// @Failure      400   {object}  ErrorResponse
+// @Failure      404   {object}  ErrorResponse
+// @Failure      409   {object}  ErrorResponse
+// @Failure      429   {object}  ErrorResponse
+// @Failure      500   {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /sandboxes/{id}/clone [post]
+func (h *Handler) cloneSandbox(c *gin.Context) {
+	var req models.CloneRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			badRequest(c, err.Error())
+			return
+		}
+	}
+	if req.Name != "" && !docker.ValidSandboxName(req.Name) {
+		badRequest(c, "name must be 1-63 lowercase letters, digits or hyphens, without leading, trailing or double hyphens")
+		return
+	}
+
+	ctx := c.Request.Context()
+	source, err := h.docker.Inspect(ctx, c.Param("id"))
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	result, err := h.docker.Clone(ctx, source.ID, req)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+
+	c.Set(auditSandboxKey, result.ID)
+	result.URL = h.proxyURL(result.Name)
+	c.JSON(http.StatusCreated, result)
+}
+
+// checkpointSandbox handles POST /v1/sandboxes/:id/checkpoint.
+// @Summary      Checkpoint a sandbox
+// @Description  Saves the memory and process state of a running or paused sandbox with CRIU, then stops it unless leave_running is set. Resume it later with POST /sandboxes/{id}/restore. Requires CRIU and Docker experimental features on the worker.
+// @Tags         sandboxes
+// @Accept       json
+// @Produce      json
+// @Param        id    path      string                    true   "Sandbox ID"
+// @Param        body  body      models.CheckpointRequest  false  "Checkpoint options"
+// @Success      201   {object}  models.Checkpoint
+// @Failure      400   {object}  ErrorResponse
+// @Failure      404   {object}  ErrorResponse
+// @Failure      409   {object}  ErrorResponse
+// @Failure      500   {object}  ErrorResponse
+// @Failure      501   {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /sandboxes/{id}/checkpoint [post]
+func (h *Handler) checkpointSandbox(c *gin.Context) {
+	var req models.CheckpointRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			badRequest(c, err.Error())
+			return
+		}
+	}
+
+	cp, err := h.docker.Checkpoint(c.Request.Context(), c.Param("id"), req)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, cp)
+}
+
+// listCheckpoints handles GET /v1/sandboxes/:id/checkpoints.
+// @Summary      List sandbox checkpoints
+// @Description  Returns the checkpoints saved for a sandbox.
+// @Tags         sandboxes
+// @Produce      json
+// @Param        id   path      string  true  "Sandbox ID"
+// @Success      200  {object}  map[string]interface{}  "checkpoints: list of checkpoints"
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      501  {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /sandboxes/{id}/checkpoints [get]
+func (h *Handler) listCheckpoints(c *gin.Context) {
+	checkpoints, err := h.docker.ListCheckpoints(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"checkpoints": checkpoints})
+}
+
+// removeCheckpoint handles DELETE /v1/sandboxes/:id/checkpoints/:name.
+// @Summary      Delete a sandbox checkpoint
+// @Description  Deletes a saved checkpoint from the worker's disk.
+// @Tags         sandboxes
+// @Param        id    path  string  true  "Sandbox ID"
+// @Param        name  path  string  true  "Checkpoint name"
+// @Success      204  "No Content"
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      501  {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /sandboxes/{id}/checkpoints/{name} [delete]
+func (h *Handler) removeCheckpoint(c *gin.Context) {
+	if err := h.docker.RemoveCheckpoint(c.Request.Context(), c.Param("id"), c.Param("name")); err != nil {
+		internalError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// restoreSandbox handles POST /v1/sandboxes/:id/restore.
+// @Summary      Restore a sandbox from a checkpoint
+// @Description  Starts a stopped sandbox with the memory and processes saved in a checkpoint.
+// @Tags         sandboxes
+// @Accept       json
+// @Produce      json
+// @Param        id    path      string                 true  "Sandbox ID"
+// @Param        body  body      models.RestoreRequest  true  "Checkpoint to restore"
+// @Success      200   {object}  models.RestartResponse
+// @Failure      400   {object}  ErrorResponse
+// @Failure      404   {object}  ErrorResponse
+// @Failure      409   {object}  ErrorResponse
+// @Failure      429   {object}  ErrorResponse
+// @Failure      500   {object}  ErrorResponse
+// @Failure      501   {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /sandboxes/{id}/restore [post]
+func (h *Handler) restoreSandbox(c *gin.Context) {
+	var req models.RestoreRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		badRequest(c, err.Error())
+		return
+	}
+
+	resp, err := h.docker.Restore(c.Request.Context(), c.Param("id"), req.Checkpoint)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// exportSandbox handles GET /v1/sandboxes/:id/export.
+// @Summary      Export a sandbox bundle
+// @Description  Commits the sandbox filesystem and streams a portable bundle: a tar with metadata.json (ports, resources, source image) and image.tar (docker save format).
+// @Tags         sandboxes
+// @Produce      application/x-tar
+// @Param        id   path      string  true  "Sandbox ID"
+// @Success      200  {file}    file
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /sandboxes/{id}/export [get]
+func (h *Handler) exportSandbox(c *gin.Context) {
+	id := c.Param("id")
+	c.Header("Content-Type", "application/x-tar")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", id+".sandbox.tar"))
+
+	// Export writes nothing until the bundle is ready, so early errors still get a JSON body.
+	if err := h.docker.Export(c.Request.Context(), id, c.Writer); err != nil {
+		if c.Writer.Written() {
+			c.Error(err)
+			return
+		}
+		c.Header("Content-Type", "")
+		c.Header("Content-Disposition", "")
+		internalError(c, err)
+	}
+}
+
 // getSandbox handles GET /v1/sandboxes/:id.
 // @Summary      Inspect a sandbox
 // @Description  Returns detailed info about the sandbox including ports, resources, and expiration.
@@ -153,25 +685,71 @@ func (h *Handler) getSandbox(c *gin.Context) {
 
 // startSandbox handles POST /v1/sandboxes/:id/start.
 // @Summary      Start a sandbox
-// @Description  Start a stopped sandbox. Returns the port mappings and a fresh expiration timer.
+// @Description  Start a stopped sandbox. Returns the port mappings and a fresh expiration timer. With wait_port=true, the response is sent once the main port accepts TCP connections or port_timeout passes, and reports which in port_ready.
 // @Tags         sandboxes
 // @Produce      json
-// @Param        id   path      string  true  "Sandbox ID"
+// @Param        id            path      string  true   "Sandbox ID"
+// @Param        wait_port     query     bool    false  "Wait for the main port to accept connections before responding"
+// @Param        port_timeout  query     int     false  "Seconds to wait with wait_port (default 30, max 600)"
 // @Success      200  {object}  models.RestartResponse
+// @Failure      400  {object}  ErrorResponse
 // @Failure      404  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
+// @Failure      429  {object}  ErrorResponse
 // @Failure      500  {object}  ErrorResponse
 // @Security     ApiKeyAuth
 // @Router       /sandboxes/{id}/start [post]
 func (h *Handler) startSandbox(c *gin.Context) {
+	q, ok := bindWaitPort(c)
+	if !ok {
+		return
+	}
+
 	result, err := h.docker.Start(c.Request.Context(), c.Param("id"))
 	if err != nil {
 		internalError(c, err)
 		return
 	}
+	if !h.waitPort(c, q, &result) {
+		return
+	}
 
 	c.JSON(http.StatusOK, result)
 }
 
+// bindWaitPort reads the wait_port query of start and restart, filling in the
+// default timeout. Writes a 400 and returns false when it is invalid.
+func bindWaitPort(c *gin.Context) (models.WaitPortQuery, bool) {
+	var q models.WaitPortQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		badRequest(c, err.Error())
+		return q, false
+	}
+	if q.PortTimeout < 0 || q.PortTimeout > docker.MaxReadyTimeout {
+		badRequest(c, fmt.Sprintf("port_timeout must be between 0 and %d", docker.MaxReadyTimeout))
+		return q, false
+	}
+	if q.PortTimeout == 0 {
+		q.PortTimeout = docker.DefaultPortTimeout
+	}
+	return q, true
+}
+
+// waitPort waits for the main port of a started sandbox when q asks for it and
+// records the outcome in result. Writes the error and returns false on failure.
+func (h *Handler) waitPort(c *gin.Context, q models.WaitPortQuery, result *models.RestartResponse) bool {
+	if !q.WaitPort {
+		return true
+	}
+	state, err := h.docker.WaitPort(c.Request.Context(), c.Param("id"), time.Duration(q.PortTimeout)*time.Second)
+	if err != nil {
+		internalError(c, err)
+		return false
+	}
+	result.PortReady = state
+	return true
+}
+
 // stopSandbox handles POST /v1/sandboxes/:id/stop.
 // @Summary      Stop a sandbox
 // @Description  Gracefully stop a running sandbox.
@@ -180,6 +758,7 @@ func (h *Handler) startSandbox(c *gin.Context) {
 // @Param        id   path      string  true  "Sandbox ID"
 // @Success      200  {object}  map[string]string  "status: stopped"
 // @Failure      404  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
 // @Failure      500  {object}  ErrorResponse
 // @Security     ApiKeyAuth
 // @Router       /sandboxes/{id}/stop [post]
@@ -194,21 +773,34 @@ func (h *Handler) stopSandbox(c *gin.Context) {
 
 // restartSandbox handles POST /v1/sandboxes/:id/restart.
 // @Summary      Restart a sandbox
-// @Description  Restart a sandbox (stop + start). Returns the new port mappings and a fresh expiration timer.
+// @Description  Restart a sandbox (stop + start). Returns the new port mappings and a fresh expiration timer. With wait_port=true, the response is sent once the main port accepts TCP connections or port_timeout passes, and reports which in port_ready.
 // @Tags         sandboxes
 // @Produce      json
-// @Param        id   path      string  true  "Sandbox ID"
+// @Param        id            path      string  true   "Sandbox ID"
+// @Param        wait_port     query     bool    false  "Wait for the main port to accept connections before responding"
+// @Param        port_timeout  query     int     false  "Seconds to wait with wait_port (default 30, max 600)"
 // @Success      200  {object}  models.RestartResponse
+// @Failure      400  {object}  ErrorResponse
 // @Failure      404  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
+// @Failure      429  {object}  ErrorResponse
 // @Failure      500  {object}  ErrorResponse
 // @Security     ApiKeyAuth
 // @Router       /sandboxes/{id}/restart [post]
 func (h *Handler) restartSandbox(c *gin.Context) {
+	q, ok := bindWaitPort(c)
+	if !ok {
+		return
+	}
+
 	result, err := h.docker.Restart(c.Request.Context(), c.Param("id"))
 	if err != nil {
 		internalError(c, err)
 		return
 	}
+	if !h.waitPort(c, q, &result) {
+		return
+	}
 
 	c.JSON(http.StatusOK, result)
 }
@@ -220,6 +812,7 @@ func (h *Handler) restartSandbox(c *gin.Context) {
 // @Param        id   path      string  true  "Sandbox ID"
 // @Success      204  "No Content"
 // @Failure      404  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
 // @Failure      500  {object}  ErrorResponse
 // @Security     ApiKeyAuth
 // @Router       /sandboxes/{id} [delete]
@@ -234,7 +827,7 @@ func (h *Handler) deleteSandbox(c *gin.Context) {
 
 // getStats handles GET /v1/sandboxes/:id/stats.
 // @Summary      Get container stats
-// @Description  Returns a snapshot of CPU, memory and process usage for the sandbox.
+// @Description  Returns a snapshot of CPU, memory and process usage for the sandbox, with the network traffic per interface and the block device bytes read and written since it started.
 // @Tags         sandboxes
 // @Produce      json
 // @Param        id   path      string  true  "Sandbox ID"
@@ -253,12 +846,33 @@ func (h *Handler) getStats(c *gin.Context) {
 	c.JSON(http.StatusOK, stats)
 }
 
+// getNodeStats handles GET /v1/stats.
+// @Summary      Get node stats
+// @Description  Returns sandbox counts by state, the memory (MB) and CPUs allocated to and used by running sandboxes, and the host capacity. Scoped keys only count their own sandboxes.
+// @Tags         sandboxes
+// @Produce      json
+// @Success      200  {object}  models.NodeStats
+// @Failure      500  {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /stats [get]
+func (h *Handler) getNodeStats(c *gin.Context) {
+	stats, err := h.docker.NodeStats(c.Request.Context())
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
 // execCommand handles POST /v1/sandboxes/:id/cmd.
 // @Summary      Execute a command
-// @Description  Execute a command asynchronously inside the sandbox. Returns a command ID immediately. Use ?wait=true to stream ND-JSON until completion.
+// @Description  Execute a command asynchronously inside the sandbox. Returns a command ID immediately. Use ?wait=true to stream ND-JSON until completion. Send Accept: text/event-stream to get the stream as Server-Sent Events instead.
 // @Tags         commands
 // @Accept       json
 // @Produce      json
+// @Produce      application/x-ndjson
+// @Produce      text/event-stream
 // @Param        id    path      string                       true  "Sandbox ID"
 // @Param        body  body      models.ExecCommandRequest    true  "Command to execute"
 // @Param        wait  query     bool                         false "Block until command finishes (ND-JSON stream)"
@@ -275,6 +889,10 @@ func (h *Handler) execCommand(c *gin.Context) {
 		badRequest(c, err.Error())
 		return
 	}
+	if msg := validateExecRequest(req); msg != "" {
+		badRequest(c, msg)
+		return
+	}
 
 	cmd, err := h.docker.ExecCommand(c.Request.Context(), c.Param("id"), req)
 	if err != nil {
@@ -291,31 +909,62 @@ func (h *Handler) execCommand(c *gin.Context) {
 	c.JSON(http.StatusOK, models.CommandResponse{Command: cmd})
 }
 
+// execUserPattern matches a user name or uid, optionally followed by ":group" or ":gid".
+var execUserPattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,31}(:[A-Za-z0-9_][A-Za-z0-9_.-]{0,31})?$`)
+
+// validateExecRequest checks the timeout and user of a command.
+// Returns an empty string when valid or a client-facing message otherwise.
+func validateExecRequest(req models.ExecCommandRequest) string {
+	if req.Timeout < 0 {
+		return "timeout must be >= 0"
+	}
+	if req.User != "" && !execUserPattern.MatchString(req.User) {
+		return "user must be a name or uid, optionally followed by :group or :gid"
+	}
+	return ""
+}
+
 // listCommands handles GET /v1/sandboxes/:id/cmd.
 // @Summary      List commands
-// @Description  Returns all commands executed in the sandbox.
+// @Description  Returns the commands executed in the sandbox, oldest first unless order=desc. total counts every command before limit and offset.
 // @Tags         commands
 // @Produce      json
-// @Param        id   path      string  true  "Sandbox ID"
+// @Param        id      path      string  true   "Sandbox ID"
+// @Param        order   query     string  false  "asc (default) or desc"
+// @Param        limit   query     int     false  "Maximum commands to return (max 1000, default all)"
+// @Param        offset  query     int     false  "Commands to skip"
 // @Success      200  {object}  models.CommandListResponse
+// @Failure      400  {object}  ErrorResponse
 // @Failure      404  {object}  ErrorResponse
 // @Failure      500  {object}  ErrorResponse
 // @Security     ApiKeyAuth
 // @Router       /sandboxes/{id}/cmd [get]
 func (h *Handler) listCommands(c *gin.Context) {
-	cmds, err := h.docker.ListCommands(c.Request.Context(), c.Param("id"))
+	var q models.CommandListQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		badRequest(c, err.Error())
+		return
+	}
+	if msg := validatePage(q.Order, q.Limit, q.Offset); msg != "" {
+		badRequest(c, msg)
+		return
+	}
+
+	cmds, total, err := h.docker.ListCommands(c.Request.Context(), c.Param("id"), q)
 	if err != nil {
 		internalError(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, models.CommandListResponse{Commands: cmds})
+	c.JSON(http.StatusOK, models.CommandListResponse{Commands: cmds, Total: total})
 }
 
 // getCommand handles GET /v1/sandboxes/:id/cmd/:cmdId.
 // @Summary      Get command status
-// @Description  Returns the status of a command. Use ?wait=true to block until the command finishes (ND-JSON stream).
+// @Description  Returns the status of a command. Use ?wait=true to block until the command finishes (ND-JSON stream). Send Accept: text/event-stream to get the stream as Server-Sent Events instead.
 // @Tags         commands
 // @Produce      json
+// @Produce      application/x-ndjson
+// @Produce      text/event-stream
 // @Param        id      path      string  true  "Sandbox ID"
 // @Param        cmdId   path      string  true  "Command ID"
 // @Param        wait    query     bool    false "Block until command finishes (ND-JSON stream)"
@@ -342,7 +991,7 @@ func (h *Handler) getCommand(c *gin.Context) {
 
 // killCommand handles POST /v1/sandboxes/:id/cmd/:cmdId/kill.
 // @Summary      Kill a command
-// @Description  Send a POSIX signal to a running command.
+// @Description  Send a POSIX signal, by name ("SIGTERM") or number (15), to a running command's process group.
 // @Tags         commands
 // @Accept       json
 // @Produce      json
@@ -363,7 +1012,7 @@ func (h *Handler) killCommand(c *gin.Context) {
 		return
 	}
 
-	cmd, err := h.docker.KillCommand(c.Request.Context(), c.Param("id"), c.Param("cmdId"), req.Signal)
+	cmd, err := h.docker.KillCommand(c.Request.Context(), c.Param("id"), c.Param("cmdId"), int(req.Signal))
 	if err != nil {
 		internalError(c, err)
 		return
@@ -374,10 +1023,11 @@ func (h *Handler) killCommand(c *gin.Context) {
 
 // getCommandLogs handles GET /v1/sandboxes/:id/cmd/:cmdId/logs.
 // @Summary      Get command logs
-// @Description  Returns stdout and stderr of a command. By default returns a JSON snapshot. Use ?stream=true to stream as ND-JSON lines in real time.
+// @Description  Returns stdout and stderr of a command. By default returns a JSON snapshot. Use ?stream=true to stream as ND-JSON lines in real time. Output of finished commands is persisted and kept for COMMAND_LOG_RETENTION. Send Accept: text/event-stream to get the stream as Server-Sent Events instead.
 // @Tags         commands
 // @Produce      json
 // @Produce      application/x-ndjson
+// @Produce      text/event-stream
 // @Param        id      path      string  true  "Sandbox ID"
 // @Param        cmdId   path      string  true  "Command ID"
 // @Param        stream  query     bool    false "Stream logs as ND-JSON (default: false)"
@@ -418,22 +1068,36 @@ func (h *Handler) streamLogs(c *gin.Context, sandboxID, cmdID string) {
 	defer stdoutR.Close()
 	defer stderrR.Close()
 
-	c.Header("Content-Type", "application/x-ndjson")
-	c.Status(http.StatusOK)
-	flusher, _ := c.Writer.(http.Flusher)
-	enc := json.NewEncoder(c.Writer)
-
-	type logLine struct {
-		Type string `json:"type"`
-		Data string `json:"data"`
-	}
+	stream := h.newEventStream(c)
+	defer stream.close()
+	stream.open()
+	writeLogLines(c, stream, stdoutR, stderrR)
+}
 
-	// Read from both streams concurrently, write as ND-JSON.
-	lines := make(chan logLine, 64)
-	readStream := func(r io.ReadCloser, streamType string) {
-		scanner := bufio.NewScanner(r)
+// writeLogLines writes stdout and stderr lines as "stdout" and "stderr"
+// events, in the order they arrive, until both readers end, the client goes
+// away or stops reading. The readers are drained by their own goroutines,
+// which give up as soon as the stream ends; callers close the readers to
+// unblock a pending read.
+func writeLogLines(c *gin.Context, stream *eventStream, stdoutR, stderrR io.Reader) {
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+
+	// Read from both streams concurrently. The buffer bounds the lines held
+	// for a client that reads slower than the sandbox writes.
+	lines := make(chan models.CommandEvent, 64)
+	readStream := func(r io.Reader, streamType string) {
+		scanner, partial := scanLogLines(r, stream.maxLine())
 		for scanner.Scan() {
-			lines <- logLine{Type: streamType, Data: scanner.Text() + "\n"}
+			data := scanner.Text()
+			if !*partial {
+				data += "\n"
+			}
+			select {
+			case lines <- models.CommandEvent{Type: streamType, Data: data}:
+			case <-ctx.Done():
+				return
+			}
 		}
 	}
 
@@ -443,52 +1107,112 @@ func (h *Handler) streamLogs(c *gin.Context, sandboxID, cmdID string) {
 	go func() { defer wg.Done(); readStream(stderrR, "stderr") }()
 	go func() { wg.Wait(); close(lines) }()
 
-	for line := range lines {
-		if c.IsAborted() {
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				return
+			}
+			if stream.send(line.Type, line) != nil {
+				return
+			}
+		case <-ctx.Done():
 			return
 		}
-		enc.Encode(line)
-		if flusher != nil {
-			flusher.Flush()
+	}
+}
+
+// getSandboxLogs handles GET /v1/sandboxes/:id/logs.
+// @Summary      Get sandbox logs
+// @Description  Streams the output of the sandbox's main process (its entrypoint and startup command) as ND-JSON lines, in the same format as command logs. Commands started through the API are not included; use their own logs. Without follow the stream ends after the existing output. Send Accept: text/event-stream to get the stream as Server-Sent Events instead.
+// @Tags         sandboxes
+// @Produce      application/x-ndjson
+// @Produce      text/event-stream
+// @Param        id          path   string  true   "Sandbox ID"
+// @Param        follow      query  bool    false  "Keep streaming new output"
+// @Param        tail        query  string  false  "Lines from the end, or all (default)"
+// @Param        since       query  string  false  "Only output after this: RFC 3339, unix seconds, or a duration ago such as 10m"
+// @Param        timestamps  query  bool    false  "Prefix every line with its timestamp"
+// @Success      200  {object}  models.CommandEvent  "One ND-JSON line per output line"
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /sandboxes/{id}/logs [get]
+func (h *Handler) getSandboxLogs(c *gin.Context) {
+	var q models.SandboxLogsQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		badRequest(c, err.Error())
+		return
+	}
+	if msg := validateLogsQuery(q); msg != "" {
+		badRequest(c, msg)
+		return
+	}
+
+	stdoutR, stderrR, err := h.docker.Logs(c.Request.Context(), c.Param("id"), q)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	defer stdoutR.Close()
+	defer stderrR.Close()
+
+	stream := h.newEventStream(c)
+	defer stream.close()
+	stream.open()
+	writeLogLines(c, stream, stdoutR, stderrR)
+}
+
+// validateLogsQuery checks tail and since, which Docker would otherwise reject with a 500.
+func validateLogsQuery(q models.SandboxLogsQuery) string {
+	if q.Tail != "" && q.Tail != "all" {
+		if n, err := strconv.Atoi(q.Tail); err != nil || n < 0 {
+			return "tail must be a number of lines or \"all\""
+		}
+	}
+	if q.Since != "" {
+		_, durErr := time.ParseDuration(q.Since)
+		_, timeErr := time.Parse(time.RFC3339, q.Since)
+		_, unixErr := strconv.ParseFloat(q.Since, 64)
+		if durErr != nil && timeErr != nil && unixErr != nil {
+			return "since must be an RFC 3339 time, unix seconds, or a duration such as \"10m\""
 		}
 	}
+	return ""
 }
 
-// streamWait streams ND-JSON with command status when started and when finished.
+// streamWait streams "command" events with the command's status when started
+// and when finished.
 func (h *Handler) streamWait(c *gin.Context, sandboxID, cmdID string) {
-	c.Header("Content-Type", "application/x-ndjson")
-	c.Status(http.StatusOK)
-	flusher, _ := c.Writer.(http.Flusher)
-	enc := json.NewEncoder(c.Writer)
+	stream := h.newEventStream(c)
+	defer stream.close()
+	stream.open()
 
 	// Emit initial status.
 	cmd, err := h.docker.GetCommand(c.Request.Context(), sandboxID, cmdID)
 	if err != nil {
 		return
 	}
-	enc.Encode(models.CommandResponse{Command: cmd})
-	if flusher != nil {
-		flusher.Flush()
-	}
+	stream.send("command", models.CommandResponse{Command: cmd})
 
 	// Wait for completion.
 	cmd, err = h.docker.WaitCommand(c.Request.Context(), sandboxID, cmdID)
 	if err != nil {
 		return
 	}
-	enc.Encode(models.CommandResponse{Command: cmd})
-	if flusher != nil {
-		flusher.Flush()
-	}
+	stream.send("command", models.CommandResponse{Command: cmd})
 }
 
 // readFile handles GET /v1/sandboxes/:id/files?path=<path>.
 // @Summary      Read a file
-// @Description  Returns the content of a file at the given path inside the sandbox.
+// @Description  Returns the content of a file at the given path inside the sandbox. Use encoding=base64 for binary files in JSON, or encoding=raw (or Accept: application/octet-stream) to stream the bytes.
 // @Tags         files
 // @Produce      json
-// @Param        id    path      string  true  "Sandbox ID"
-// @Param        path  query     string  true  "File path inside the sandbox"
+// @Produce      application/octet-stream
+// @Param        id        path      string  true   "Sandbox ID"
+// @Param        path      query     string  true   "File path inside the sandbox"
+// @Param        encoding  query     string  false  "utf-8 (default), base64 or raw"
 // @Success      200   {object}  models.FileReadResponse
 // @Failure      400   {object}  ErrorResponse
 // @Failure      404   {object}  ErrorResponse
@@ -502,20 +1226,55 @@ func (h *Handler) readFile(c *gin.Context) {
 		return
 	}
 
-	content, err := h.docker.ReadFile(c.Request.Context(), c.Param("id"), path)
-	if err != nil {
-		internalError(c, err)
-		return
+	encoding := c.Query("encoding")
+	if encoding == "" && c.GetHeader("Accept") == "application/octet-stream" {
+		encoding = "raw"
 	}
 
-	c.JSON(http.StatusOK, models.FileReadResponse{Path: path, Content: content})
+	switch encoding {
+	case "", "utf-8":
+		content, err := h.docker.ReadFile(c.Request.Context(), c.Param("id"), path)
+		if err != nil {
+			internalError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, models.FileReadResponse{Path: path, Content: content})
+
+	case "base64", "raw":
+		rc, size, err := h.docker.ReadFileStream(c.Request.Context(), c.Param("id"), path)
+		if err != nil {
+			internalError(c, err)
+			return
+		}
+		defer rc.Close()
+
+		if encoding == "raw" {
+			c.DataFromReader(http.StatusOK, size, "application/octet-stream", rc, nil)
+			return
+		}
+		data, err := io.ReadAll(rc)
+		if err != nil {
+			internalError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, models.FileReadResponse{
+			Path:     path,
+			Content:  base64.StdEncoding.EncodeToString(data),
+			Encoding: "base64",
+		})
+
+	default:
+		badRequest(c, "encoding must be utf-8, base64 or raw")
+	}
 }
 
 // writeFile handles PUT /v1/sandboxes/:id/files?path=<path>.
 // @Summary      Write a file
-// @Description  Write or overwrite a file inside the sandbox. Creates parent directories as needed.
+// @Description  Write or overwrite a file inside the sandbox. Creates parent directories as needed. Besides JSON (optionally base64-encoded), the body may be raw bytes with Content-Type application/octet-stream, or multipart/form-data with a "file" field.
 // @Tags         files
 // @Accept       json
+// @Accept       application/octet-stream
+// @Accept       multipart/form-data
 // @Produce      json
 // @Param        id    path      string                  true  "Sandbox ID"
 // @Param        path  query     string                  true  "File path inside the sandbox"
@@ -533,13 +1292,47 @@ func (h *Handler) writeFile(c *gin.Context) {
 		return
 	}
 
-	var req models.FileWriteRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		badRequest(c, err.Error())
-		return
-	}
+	var err error
+	switch c.ContentType() {
+	case "application/octet-stream":
+		err = h.docker.WriteFileStream(c.Request.Context(), c.Param("id"), path, c.Request.Body, c.Request.ContentLength)
+
+	case "multipart/form-data":
+		fh, ferr := c.FormFile("file")
+		if ferr != nil {
+			badRequest(c, "multipart body must contain a \"file\" field")
+			return
+		}
+		f, ferr := fh.Open()
+		if ferr != nil {
+			internalError(c, ferr)
+			return
+		}
+		defer f.Close()
+		err = h.docker.WriteFileStream(c.Request.Context(), c.Param("id"), path, f, fh.Size)
 
-	if err := h.docker.WriteFile(c.Request.Context(), c.Param("id"), path, req.Content); err != nil {
+	default:
+		var req models.FileWriteRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			badRequest(c, err.Error())
+			return
+		}
+		switch req.Encoding {
+		case "":
+			err = h.docker.WriteFile(c.Request.Context(), c.Param("id"), path, req.Content)
+		case "base64":
+			data, derr := base64.StdEncoding.DecodeString(req.Content)
+			if derr != nil {
+				badRequest(c, "content is not valid base64")
+				return
+			}
+			err = h.docker.WriteFileStream(c.Request.Context(), c.Param("id"), path, bytes.NewReader(data), int64(len(data)))
+		default:
+			badRequest(c, "encoding must be empty or base64")
+			return
+		}
+	}
+	if err != nil {
 		internalError(c, err)
 		return
 	}
@@ -549,7 +1342,7 @@ func (h *Handler) writeFile(c *gin.Context) {
 
 // deleteFile handles DELETE /v1/sandboxes/:id/files?path=<path>.
 // @Summary      Delete a file
-// @Description  Remove a file or directory (recursive) inside the sandbox.
+// @Description  Remove a file or directory (recursive) inside the sandbox. Returns 404 if the path does not exist.
 // @Tags         files
 // @Param        id    path      string  true  "Sandbox ID"
 // @Param        path  query     string  true  "File path inside the sandbox"
@@ -576,19 +1369,27 @@ func (h *Handler) deleteFile(c *gin.Context) {
 
 // listDir handles GET /v1/sandboxes/:id/files/list?path=<path>.
 // @Summary      List a directory
-// @Description  Returns the output of ls -la for the given directory. Defaults to root (/).
+// @Description  Returns the output of ls -la for the given directory. Defaults to root (/). Returns 404 with the ls error if the path does not exist. With tree=true, returns a recursive JSON tree (directories first) down to depth levels instead.
 // @Tags         files
 // @Produce      json
-// @Param        id    path      string  true   "Sandbox ID"
-// @Param        path  query     string  false  "Directory path (default: /)"
-// @Success      200   {object}  models.FileListResponse
-// @Failure      404   {object}  ErrorResponse
-// @Failure      500   {object}  ErrorResponse
+// @Param        id     path      string  true   "Sandbox ID"
+// @Param        path   query     string  false  "Directory path (default: /)"
+// @Param        tree   query     bool    false  "Return a recursive JSON tree"
+// @Param        depth  query     int     false  "Tree depth, 1-10 (default: 3)"
+// @Success      200    {object}  models.FileListResponse  "ls output (default); a models.FileTreeResponse with tree=true"
+// @Failure      400    {object}  ErrorResponse
+// @Failure      404    {object}  ErrorResponse
+// @Failure      500    {object}  ErrorResponse
 // @Security     ApiKeyAuth
 // @Router       /sandboxes/{id}/files/list [get]
 func (h *Handler) listDir(c *gin.Context) {
 	path := c.DefaultQuery("path", "/")
 
+	if c.Query("tree") == "true" {
+		h.listTree(c, path)
+		return
+	}
+
 	output, err := h.docker.ListDir(c.Request.Context(), c.Param("id"), path)
 	if err != nil {
 		internalError(c, err)
@@ -598,6 +1399,192 @@ func (h *Handler) listDir(c *gin.Context) {
 	c.JSON(http.StatusOK, models.FileListResponse{Path: path, Output: output})
 }
 
+// listTree writes a recursive listing of dir for listDir's tree mode.
+func (h *Handler) listTree(c *gin.Context, dir string) {
+	depth := docker.DefaultTreeDepth
+	if raw := c.Query("depth"); raw != "" {
+		d, err := strconv.Atoi(raw)
+		if err != nil || d < 1 || d > docker.MaxTreeDepth {
+			badRequest(c, fmt.Sprintf("depth must be between 1 and %d", docker.MaxTreeDepth))
+			return
+		}
+		depth = d
+	}
+
+	entries, truncated, err := h.docker.FileTree(c.Request.Context(), c.Param("id"), dir, depth)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, models.FileTreeResponse{Path: dir, Entries: entries, Truncated: truncated})
+}
+
+// maxSearchLimit caps the limit query parameter of searchFiles.
+const maxSearchLimit = 10000
+
+// searchFiles handles GET /v1/sandboxes/:id/files/search?glob=<pattern>.
+// @Summary      Search files by glob
+// @Description  Returns the entries below path whose path relative to it matches glob. Segments use shell wildcards (*, ?, [...]) and ** matches any number of directories, e.g. **/*.ts. Searches descend at most 32 levels.
+// @Tags         files
+// @Produce      json
+// @Param        id     path      string  true   "Sandbox ID"
+// @Param        glob   query     string  true   "Glob relative to path, e.g. **/*.ts"
+// @Param        path   query     string  false  "Directory to search (default: /)"
+// @Param        limit  query     int     false  "Maximum matches, 1-10000 (default: 1000)"
+// @Success      200    {object}  models.FileSearchResponse
+// @Failure      400    {object}  ErrorResponse
+// @Failure      404    {object}  ErrorResponse
+// @Failure      500    {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /sandboxes/{id}/files/search [get]
+func (h *Handler) searchFiles(c *gin.Context) {
+	dir := c.DefaultQuery("path", "/")
+	glob := c.Query("glob")
+	if !docker.ValidGlob(glob) {
+		badRequest(c, "glob is required and must be a valid pattern")
+		return
+	}
+	limit := docker.DefaultSearchLimit
+	if raw := c.Query("limit"); raw != "" {
+		l, err := strconv.Atoi(raw)
+		if err != nil || l < 1 || l > maxSearchLimit {
+			badRequest(c, fmt.Sprintf("limit must be between 1 and %d", maxSearchLimit))
+			return
+		}
+		limit = l
+	}
+
+	matches, truncated, err := h.docker.SearchFiles(c.Request.Context(), c.Param("id"), dir, glob, limit)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, models.FileSearchResponse{Path: dir, Glob: glob, Matches: matches, Truncated: truncated})
+}
+
+// statFile handles GET /v1/sandboxes/:id/files/stat?path=<path>.
+// @Summary      Stat a file
+// @Description  Returns the size, permissions, modification time and type of a file or directory without reading it. Symlinks are not followed. Returns 404 if the path does not exist.
+// @Tags         files
+// @Produce      json
+// @Param        id    path      string  true  "Sandbox ID"
+// @Param        path  query     string  true  "Path inside the sandbox"
+// @Success      200   {object}  models.FileStat
+// @Failure      400   {object}  ErrorResponse
+// @Failure      404   {object}  ErrorResponse
+// @Failure      500   {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /sandboxes/{id}/files/stat [get]
+func (h *Handler) statFile(c *gin.Context) {
+	path := c.Query("path")
+	if path == "" {
+		badRequest(c, "path query param is required")
+		return
+	}
+
+	stat, err := h.docker.StatFile(c.Request.Context(), c.Param("id"), path)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, stat)
+}
+
+// uploadArchive handles POST /v1/sandboxes/:id/files/upload?path=<dir>.
+// @Summary      Upload an archive
+// @Description  Extracts a tar or zip archive into a directory inside the sandbox (created if missing). The format is zip when Content-Type is application/zip or format=zip, tar otherwise.
+// @Tags         files
+// @Accept       application/x-tar
+// @Accept       application/zip
+// @Produce      json
+// @Param        id      path      string  true   "Sandbox ID"
+// @Param        path    query     string  true   "Destination directory inside the sandbox"
+// @Param        format  query     string  false  "Archive format: tar (default) or zip"
+// @Param        body    body      string  true   "Archive bytes"
+// @Success      200     {object}  map[string]string  "path and status"
+// @Failure      400     {object}  ErrorResponse
+// @Failure      404     {object}  ErrorResponse
+// @Failure      500     {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /sandboxes/{id}/files/upload [post]
+func (h *Handler) uploadArchive(c *gin.Context) {
+	path := c.Query("path")
+	if path == "" {
+		badRequest(c, "path query param is required")
+		return
+	}
+
+	format := c.Query("format")
+	if format == "" {
+		format = docker.ArchiveTar
+		if c.ContentType() == "application/zip" {
+			format = docker.ArchiveZip
+		}
+	}
+	if format != docker.ArchiveTar && format != docker.ArchiveZip {
+		badRequest(c, "format must be tar or zip")
+		return
+	}
+
+	if err := h.docker.UploadArchive(c.Request.Context(), c.Param("id"), path, c.Request.Body, format); err != nil {
+		internalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"path": path, "status": "extracted"})
+}
+
+// downloadArchive handles GET /v1/sandboxes/:id/files/download?path=<path>.
+// @Summary      Download an archive
+// @Description  Streams a file or directory from the sandbox as a tar (default) or zip archive.
+// @Tags         files
+// @Produce      application/x-tar
+// @Produce      application/zip
+// @Param        id      path      string  true   "Sandbox ID"
+// @Param        path    query     string  true   "File or directory inside the sandbox"
+// @Param        format  query     string  false  "Archive format: tar (default) or zip"
+// @Success      200     {file}    file
+// @Failure      400     {object}  ErrorResponse
+// @Failure      404     {object}  ErrorResponse
+// @Failure      500     {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /sandboxes/{id}/files/download [get]
+func (h *Handler) downloadArchive(c *gin.Context) {
+	path := c.Query("path")
+	if path == "" {
+		badRequest(c, "path query param is required")
+		return
+	}
+
+	format := c.DefaultQuery("format", docker.ArchiveTar)
+	contentType := "application/x-tar"
+	switch format {
+	case docker.ArchiveTar:
+	case docker.ArchiveZip:
+		contentType = "application/zip"
+	default:
+		badRequest(c, "format must be tar or zip")
+		return
+	}
+
+	name := filepath.Base(path)
+	if name == "/" || name == "." {
+		name = "root"
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+"."+format))
+
+	if err := h.docker.DownloadArchive(c.Request.Context(), c.Param("id"), path, format, c.Writer); err != nil {
+		if c.Writer.Written() {
+			c.Error(err)
+			return
+		}
+		c.Header("Content-Type", "")
+		c.Header("Content-Disposition", "")
+		internalError(c, err)
+	}
+}
+
 // pauseSandbox handles POST /v1/sandboxes/:id/pause.
 // @Summary      Pause a sandbox
 // @Description  Freeze all processes inside the sandbox.
@@ -606,6 +1593,7 @@ func (h *Handler) listDir(c *gin.Context) {
 // @Param        id   path      string  true  "Sandbox ID"
 // @Success      200  {object}  map[string]string  "status: paused"
 // @Failure      404  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
 // @Failure      500  {object}  ErrorResponse
 // @Security     ApiKeyAuth
 // @Router       /sandboxes/{id}/pause [post]
@@ -626,6 +1614,7 @@ func (h *Handler) pauseSandbox(c *gin.Context) {
 // @Param        id   path      string  true  "Sandbox ID"
 // @Success      200  {object}  map[string]string  "status: resumed"
 // @Failure      404  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
 // @Failure      500  {object}  ErrorResponse
 // @Security     ApiKeyAuth
 // @Router       /sandboxes/{id}/resume [post]
@@ -640,7 +1629,7 @@ func (h *Handler) resumeSandbox(c *gin.Context) {
 
 // renewExpiration handles POST /v1/sandboxes/:id/renew-expiration.
 // @Summary      Renew sandbox expiration
-// @Description  Reset the auto-stop timer for a sandbox.
+// @Description  Reset the auto-stop timer for a sandbox. The timer never runs past the sandbox's max_lifetime; once that is reached, renewing fails with LIFETIME_EXCEEDED.
 // @Tags         sandboxes
 // @Accept       json
 // @Produce      json
@@ -649,6 +1638,7 @@ func (h *Handler) resumeSandbox(c *gin.Context) {
 // @Success      200   {object}  models.RenewExpirationResponse
 // @Failure      400   {object}  ErrorResponse
 // @Failure      404   {object}  ErrorResponse
+// @Failure      409   {object}  ErrorResponse
 // @Failure      500   {object}  ErrorResponse
 // @Security     ApiKeyAuth
 // @Router       /sandboxes/{id}/renew-expiration [post]
@@ -674,7 +1664,7 @@ func (h *Handler) renewExpiration(c *gin.Context) {
 
 // getSandboxNetwork handles GET /v1/sandboxes/:id/network.
 // @Summary      Get sandbox network routing
-// @Description  Returns the selected main proxy port and current container-to-host port mapping.
+// @Description  Returns the selected main proxy port, the current container-to-host port mapping, and a proxy URL per port. Ports other than the main one are reachable at <name>--<port>.<base-domain>.
 // @Tags         sandboxes
 // @Produce      json
 // @Param        id   path      string  true  "Sandbox ID"
@@ -690,7 +1680,91 @@ func (h *Handler) getSandboxNetwork(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, network)
+	c.JSON(http.StatusOK, withPortURLs(network, h.BaseDomain(), h.proxyAddr))
+}
+
+// getSandboxIsolation handles GET /v1/sandboxes/:id/isolation.
+// @Summary      Get sandbox network isolation
+// @Description  Reports whether the sandbox can reach other containers over its attached networks.
+// @Tags         sandboxes
+// @Produce      json
+// @Param        id   path      string  true  "Sandbox ID"
+// @Success      200  {object}  models.SandboxIsolation
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /sandboxes/{id}/isolation [get]
+func (h *Handler) getSandboxIsolation(c *gin.Context) {
+	isolation, err := h.docker.Isolation(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, isolation)
+}
+
+// getEnv handles GET /v1/sandboxes/:id/env.
+// @Summary      Get sandbox environment variables
+// @Description  Returns the sandbox's environment: the variables it was created with, overridden by those set with PUT /sandboxes/{id}/env.
+// @Tags         sandboxes
+// @Produce      json
+// @Param        id   path      string  true  "Sandbox ID"
+// @Success      200  {object}  models.SandboxEnv
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /sandboxes/{id}/env [get]
+func (h *Handler) getEnv(c *gin.Context) {
+	env, err := h.docker.GetEnv(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, env)
+}
+
+// updateEnv handles PUT /v1/sandboxes/:id/env.
+// @Summary      Set sandbox environment variables
+// @Description  Sets variables for commands, processes and terminals started from now on, without recreating the sandbox. A null value removes a variable set earlier through this endpoint. Processes already running, including the container's main command, keep their environment.
+// @Tags         sandboxes
+// @Accept       json
+// @Produce      json
+// @Param        id    path      string                   true  "Sandbox ID"
+// @Param        body  body      models.UpdateEnvRequest  true  "Variables to set or remove"
+// @Success      200   {object}  models.SandboxEnv
+// @Failure      400   {object}  ErrorResponse
+// @Failure      404   {object}  ErrorResponse
+// @Failure      500   {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /sandboxes/{id}/env [put]
+func (h *Handler) updateEnv(c *gin.Context) {
+	var req models.UpdateEnvRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		badRequest(c, err.Error())
+		return
+	}
+
+	env, err := h.docker.UpdateEnv(c.Request.Context(), c.Param("id"), req.Env)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, env)
+}
+
+// getPolicy handles GET /v1/admin/policy.
+// @Summary      Get host policy
+// @Description  Returns the host privileges sandboxes may be granted. Privileged mode, host namespaces, added capabilities and host mounts are always denied.
+// @Tags         admin
+// @Produce      json
+// @Success      200  {object}  models.HostPolicy
+// @Security     ApiKeyAuth
+// @Router       /admin/policy [get]
+func (h *Handler) getPolicy(c *gin.Context) {
+	c.JSON(http.StatusOK, h.docker.Policy())
 }
 
 // pullImage handles POST /v1/images/pull.
@@ -720,6 +1794,37 @@ func (h *Handler) pullImage(c *gin.Context) {
 	c.JSON(http.StatusOK, models.ImagePullResponse{Status: "pulled", Image: req.Image})
 }
 
+// pruneImages handles POST /v1/images/prune.
+// @Summary      Remove unused images
+// @Description  Removes images opensbx pulled, imported or cloned that no sandbox has used for at least older_than, except snapshot images, and reports the disk space reclaimed. Other images on the daemon are kept.
+// @Tags         images
+// @Produce      json
+// @Param        older_than  query     string  false  "Minimum time unused, as a Go duration (default 24h)"
+// @Success      200         {object}  models.ImagePruneResponse
+// @Failure      400         {object}  ErrorResponse
+// @Failure      500         {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /images/prune [post]
+func (h *Handler) pruneImages(c *gin.Context) {
+	unusedFor := docker.DefaultImageRetention
+	if raw := c.Query("older_than"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d < 0 {
+			badRequest(c, "older_than must be a non-negative duration, e.g. 24h")
+			return
+		}
+		unusedFor = d
+	}
+
+	resp, err := h.docker.PruneImages(c.Request.Context(), unusedFor)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
 // deleteImage handles DELETE /v1/images/:id.
 // @Summary      Delete a local image
 // @Description  Removes a Docker image from the local store. Use force=true if containers reference it.
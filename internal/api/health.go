@@ -0,0 +1,149 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"opensbx/models"
+)
+
+// healthTimeout bounds each component check, so a hung daemon or database
+// fails the probe instead of blocking it.
+const healthTimeout = 5 * time.Second
+
+// HealthCheck reports whether a component the API depends on works.
+type HealthCheck func(ctx context.Context) error
+
+// healthCheck is a named HealthCheck.
+type healthCheck struct {
+	name  string
+	check HealthCheck
+}
+
+// AddHealthCheck adds a component to /v1/health and /readyz. The Docker daemon
+// is always checked. Must be called before RegisterHealthCheck.
+func (h *Handler) AddHealthCheck(name string, check HealthCheck) {
+	h.checks = append(h.checks, healthCheck{name: name, check: check})
+}
+
+// SetVersion sets the build version /v1/health reports for the API.
+func (h *Handler) SetVersion(version string) {
+	h.version = version
+}
+
+// DialCheck returns a HealthCheck that passes while every address accepts TCP
+// connections, e.g. the proxy listeners. Addresses without a host, or with an
+// unspecified one, are dialed on localhost.
+func DialCheck(addrs ...string) HealthCheck {
+	return func(ctx context.Context) error {
+		var d net.Dialer
+		for _, addr := range addrs {
+			host, port, err := net.SplitHostPort(addr)
+			if err != nil {
+				return err
+			}
+			if ip, err := netip.ParseAddr(host); host == "" || (err == nil && ip.IsUnspecified()) {
+				host = "localhost"
+			}
+			conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+			if err != nil {
+				return err
+			}
+			conn.Close()
+		}
+		return nil
+	}
+}
+
+// checkComponents runs the Docker check and the added checks concurrently and
+// reports whether all of them passed.
+func (h *Handler) checkComponents(ctx context.Context) (map[string]models.ComponentHealth, bool) {
+	checks := append([]healthCheck{{name: "docker", check: h.docker.Ping}}, h.checks...)
+	results := make(map[string]models.ComponentHealth, len(checks))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, hc := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, healthTimeout)
+			defer cancel()
+			started := time.Now()
+			err := hc.check(checkCtx)
+			result := models.ComponentHealth{Status: "healthy", LatencyMS: time.Since(started).Milliseconds()}
+			if err != nil {
+				if errors.Is(err, context.DeadlineExceeded) {
+					err = errors.New("check timed out after " + healthTimeout.String())
+				}
+				result.Status = "unhealthy"
+				result.Error = err.Error()
+			}
+			mu.Lock()
+			results[hc.name] = result
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	healthy := true
+	for _, r := range results {
+		healthy = healthy && r.Status == "healthy"
+	}
+	return results, healthy
+}
+
+// healthCheck handles GET /health.
+// @Summary      Health check
+// @Description  Checks every component the API depends on (the Docker daemon, the database and the proxy listeners) and reports the API, Go and Docker versions. Responds 503 when any component is unhealthy. For Kubernetes probes use /healthz (liveness) and /readyz (readiness) at the server root instead.
+// @Tags         system
+// @Produce      json
+// @Success      200  {object}  models.Health  "status: healthy"
+// @Failure      503  {object}  models.Health  "status: unhealthy"
+// @Router       /health [get]
+func (h *Handler) healthCheck(c *gin.Context) {
+	ctx := c.Request.Context()
+	components, healthy := h.checkComponents(ctx)
+
+	version := h.version
+	if version == "" {
+		version = "dev"
+	}
+	versions := map[string]string{"api": version, "go": runtime.Version()}
+	if components["docker"].Status == "healthy" {
+		if v, err := h.docker.ServerVersion(ctx); err == nil {
+			versions["docker"] = v
+		}
+	}
+
+	resp := models.Health{Status: "healthy", Components: components, Versions: versions}
+	if !healthy {
+		resp.Status = "unhealthy"
+		c.JSON(http.StatusServiceUnavailable, resp)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// liveness handles GET /healthz. It only shows the process serves requests:
+// restarting the API does not fix an unreachable Docker daemon or database.
+func (h *Handler) liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "alive"})
+}
+
+// readiness handles GET /readyz. Responds 503 while any component is
+// unhealthy, so load balancers stop routing to the instance.
+func (h *Handler) readiness(c *gin.Context) {
+	components, healthy := h.checkComponents(c.Request.Context())
+	if !healthy {
+		c.JSON(http.StatusServiceUnavailable, models.Health{Status: "not_ready", Components: components})
+		return
+	}
+	c.JSON(http.StatusOK, models.Health{Status: "ready", Components: components})
+}
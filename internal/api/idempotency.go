@@ -0,0 +1,40 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+
+	"opensbx/models"
+)
+
+// Idempotency headers of POST /v1/sandboxes. A retried create with the same
+// key returns the sandbox the first request created, marked as replayed.
+const (
+	HeaderIdempotencyKey     = "Idempotency-Key"
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+)
+
+// maxIdempotencyKeyLen bounds the Idempotency-Key header.
+const maxIdempotencyKeyLen = 255
+
+// idempotencyHash fingerprints a create request, so a key reused with another
+// request is rejected instead of returning an unrelated sandbox.
+func idempotencyHash(req models.CreateSandboxRequest) string {
+	b, _ := json.Marshal(req) // plain data, cannot fail
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// finishIdempotent records the result of a create sent with key, or releases
+// key when resp is nil. A no-op without a key.
+func (h *Handler) finishIdempotent(ctx context.Context, key string, resp *models.CreateSandboxResponse) {
+	if key == "" {
+		return
+	}
+	if err := h.docker.FinishIdempotent(ctx, key, resp); err != nil {
+		slog.Error("idempotency: failed to record create", "key", key, "err", err)
+	}
+}
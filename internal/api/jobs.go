@@ -0,0 +1,44 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"opensbx/models"
+)
+
+// runJob handles POST /v1/jobs.
+// @Summary      Run a one-shot job
+// @Description  Creates a sandbox, runs a command in it until it exits, and deletes the sandbox again, returning the exit code and output in one call. Equivalent to POST /sandboxes, POST /cmd, GET /cmd/{cmdId}?wait=true, GET /cmd/{cmdId}/logs and DELETE /sandboxes/{id}. The sandbox is also deleted when the request is cancelled. The command's timeout defaults to 1 hour.
+// @Tags         jobs
+// @Accept       json
+// @Produce      json
+// @Param        body  body      models.JobRequest  true  "Sandbox and command"
+// @Success      200   {object}  models.JobResult
+// @Failure      400   {object}  ErrorResponse
+// @Failure      409   {object}  ErrorResponse
+// @Failure      429   {object}  ErrorResponse
+// @Failure      500   {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /jobs [post]
+func (h *Handler) runJob(c *gin.Context) {
+	var req models.JobRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		badRequest(c, err.Error())
+		return
+	}
+	if msg := validateCreateRequest(req.Sandbox); msg != "" {
+		badRequest(c, "sandbox: "+msg)
+		return
+	}
+	if msg := validateExecRequest(req.Command); msg != "" {
+		badRequest(c, "command: "+msg)
+		return
+	}
+	result, err := h.docker.RunJob(c.Request.Context(), req)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,197 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"opensbx/internal/keys"
+	"opensbx/models"
+)
+
+// KeyStore manages scoped API keys. Implemented by *keys.Store.
+type KeyStore interface {
+	Authenticate(secret string) (models.APIKey, bool, error)
+	Create(req models.CreateAPIKeyRequest) (models.CreateAPIKeyResponse, error)
+	List() ([]models.APIKey, error)
+	Revoke(id string) error
+	HasActive() (bool, error)
+}
+
+// Gin context keys set by RequestAuth for downstream middleware and handlers.
+const (
+	authKeyIDKey  = "opensbx.key_id" // ID of the stored key used, empty for API_KEY and signatures
+	authScopesKey = "opensbx.scopes" // []string of scopes granted to the caller
+	authOwnerKey  = "opensbx.owner"  // owner the caller is restricted to, empty = all sandboxes
+)
+
+// fullAccess is granted to the static API_KEY, signed requests and open deployments.
+var fullAccess = []string{keys.ScopeAdmin}
+
+// SetKeyStore enables scoped API keys and the /v1/admin/keys endpoints.
+// Must be called before RegisterRoutes.
+func (h *Handler) SetKeyStore(ks KeyStore) {
+	h.keys = ks
+}
+
+// requiredScope returns the scope needed for a route. Images, admin and audit
+// routes have their own scopes; otherwise reads need read-only and anything that
+// changes or executes inside a sandbox needs exec.
+func requiredScope(method, route string) string {
+	switch {
+	case strings.HasPrefix(route, "/v1/admin"), strings.HasPrefix(route, "/v1/audit"):
+		return keys.ScopeAdmin
+	case strings.HasPrefix(route, "/v1/images"):
+		if method == http.MethodGet || method == http.MethodHead {
+			return keys.ScopeReadOnly
+		}
+		return keys.ScopeImages
+	case strings.HasSuffix(route, "/terminal"), strings.HasSuffix(route, "/tunnels/:tid/connect"), strings.HasPrefix(route, "/v1/mcp"):
+		return keys.ScopeExec
+	case method == http.MethodGet || method == http.MethodHead:
+		return keys.ScopeReadOnly
+	default:
+		return keys.ScopeExec
+	}
+}
+
+// authorizeScope aborts with 403 unless scopes permit the current route.
+func authorizeScope(c *gin.Context, scopes []string) bool {
+	route := c.FullPath()
+	if route == "" {
+		route = c.Request.URL.Path
+	}
+	required := requiredScope(c.Request.Method, route)
+	if keys.Allows(scopes, required) {
+		return true
+	}
+	c.AbortWithStatusJSON(http.StatusForbidden, ErrorResponse{
+		Code:    "FORBIDDEN",
+		Message: "api key lacks the " + required + " scope",
+	})
+	return false
+}
+
+// callerAllows reports whether the authenticated caller's scopes permit
+// required. Requests that went through no auth middleware are unrestricted.
+func callerAllows(c *gin.Context, required string) bool {
+	scopes, ok := c.Get(authScopesKey)
+	if !ok {
+		return true
+	}
+	granted, _ := scopes.([]string)
+	return keys.Allows(granted, required)
+}
+
+// keyOwner returns the owner a stored key is restricted to. Admin keys see all sandboxes.
+func keyOwner(key models.APIKey) string {
+	if keys.Allows(key.Scopes, keys.ScopeAdmin) {
+		return ""
+	}
+	return key.Owner
+}
+
+// resolveSandbox rewrites the :id parameter to the sandbox's full container ID,
+// so every handler accepts a name or short ID as well. Unknown sandboxes get 404.
+func (h *Handler) resolveSandbox(c *gin.Context) {
+	ref := c.Param("id")
+	if ref == "" {
+		c.Next()
+		return
+	}
+	id, err := h.docker.Resolve(c.Request.Context(), ref)
+	if err != nil {
+		internalError(c, err)
+		c.Abort()
+		return
+	}
+	for i := range c.Params {
+		if c.Params[i].Key == "id" {
+			c.Params[i].Value = id
+		}
+	}
+	c.Next()
+}
+
+// requireOwner rejects requests for sandboxes outside the caller's owner with 404.
+func (h *Handler) requireOwner(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		c.Next()
+		return
+	}
+	if err := h.docker.CheckOwner(c.Request.Context(), id); err != nil {
+		internalError(c, err)
+		c.Abort()
+		return
+	}
+	c.Next()
+}
+
+// createKey handles POST /v1/admin/keys.
+// @Summary      Create an API key
+// @Description  Creates a scoped API key. Scopes: read-only (GET endpoints), exec (create and manage sandboxes, commands, files), images (pull and remove images), admin (everything, including key management). Non-admin keys only see sandboxes of their owner; keys created with the same owner share sandboxes. The secret is returned only in this response.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        body  body      models.CreateAPIKeyRequest  true  "Key name and scopes"
+// @Success      201   {object}  models.CreateAPIKeyResponse
+// @Failure      400   {object}  ErrorResponse
+// @Failure      500   {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /admin/keys [post]
+func (h *Handler) createKey(c *gin.Context) {
+	var req models.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		badRequest(c, err.Error())
+		return
+	}
+
+	if q := req.Quota; q != nil && (q.MaxSandboxes < 0 || q.MaxMemory < 0 || q.MaxCPUs < 0) {
+		badRequest(c, "quota limits must be >= 0")
+		return
+	}
+
+	created, err := h.keys.Create(req)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, created)
+}
+
+// listKeys handles GET /v1/admin/keys.
+// @Summary      List API keys
+// @Description  Lists all API keys, including revoked ones. Secrets are never returned.
+// @Tags         admin
+// @Produce      json
+// @Success      200  {array}   models.APIKey
+// @Failure      500  {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /admin/keys [get]
+func (h *Handler) listKeys(c *gin.Context) {
+	list, err := h.keys.List()
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// revokeKey handles DELETE /v1/admin/keys/:id.
+// @Summary      Revoke an API key
+// @Description  Revokes an API key immediately. Revoked keys stay listed.
+// @Tags         admin
+// @Param        id  path  string  true  "Key ID"
+// @Success      204
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /admin/keys/{id} [delete]
+func (h *Handler) revokeKey(c *gin.Context) {
+	if err := h.keys.Revoke(c.Param("id")); err != nil {
+		internalError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -3,15 +3,19 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"opensbx/internal/docker"
 	"opensbx/models"
 )
 
 // NewMCPHandler returns a streamable HTTP MCP handler mounted under /v1/mcp.
-func NewMCPHandler(d DockerClient, baseDomain, proxyAddr string, disableLocalhostProtection bool) http.Handler {
+// baseDomain is read on every call, so sandbox URLs follow config reloads.
+func NewMCPHandler(d DockerClient, baseDomain func() string, proxyAddr string, disableLocalhostProtection bool) http.Handler {
 	server := mcp.NewServer(&mcp.Implementation{
 		Name:    "opensbx",
 		Version: "1.0.0",
@@ -19,24 +23,83 @@ func NewMCPHandler(d DockerClient, baseDomain, proxyAddr string, disableLocalhos
 
 	addMCPTools(server, d, baseDomain, proxyAddr)
 	addMCPContext(server)
+	server.AddReceivingMiddleware(mcpOwnerCheck(d))
 	return mcp.NewStreamableHTTPHandler(func(_ *http.Request) *mcp.Server {
 		return server
 	}, &mcp.StreamableHTTPOptions{DisableLocalhostProtection: disableLocalhostProtection})
 }
 
-func addMCPTools(server *mcp.Server, d DockerClient, baseDomain, proxyAddr string) {
+// mcpOwnerCheck rejects sandbox, command and file tool calls on sandboxes the
+// caller does not own. The owner comes from the request that opened the session.
+func mcpOwnerCheck(d DockerClient) mcp.Middleware {
+	return func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			call, ok := req.(*mcp.CallToolRequest)
+			if !ok || docker.OwnerFrom(ctx) == "" || !sandboxTool(call.Params.Name) {
+				return next(ctx, method, req)
+			}
+			var target struct {
+				ID        string `json:"id"`
+				SandboxID string `json:"sandbox_id"`
+			}
+			json.Unmarshal(call.Params.Arguments, &target)
+			id := target.SandboxID
+			if id == "" {
+				id = target.ID
+			}
+			if id != "" {
+				if err := d.CheckOwner(ctx, id); err != nil {
+					return nil, err
+				}
+			}
+			return next(ctx, method, req)
+		}
+	}
+}
+
+// sandboxTool reports whether an MCP tool acts on an existing sandbox.
+func sandboxTool(name string) bool {
+	for _, prefix := range []string{"sandbox_", "command_", "file_"} {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func addMCPTools(server *mcp.Server, d DockerClient, baseDomain func() string, proxyAddr string) {
 	type noArgs struct{}
 
 	type sandboxIDArgs struct {
 		ID string `json:"id" jsonschema:"sandbox id"`
 	}
 
+	type sandboxListArgs struct {
+		State      string   `json:"state,omitempty" jsonschema:"only sandboxes in this state, e.g. running, exited or removed"`
+		Image      string   `json:"image,omitempty" jsonschema:"only sandboxes of this image"`
+		NamePrefix string   `json:"name_prefix,omitempty" jsonschema:"only sandboxes whose name starts with this"`
+		Label      []string `json:"label,omitempty" jsonschema:"only sandboxes with these labels, as key=value (or key for any value)"`
+		Limit      int      `json:"limit,omitempty" jsonschema:"maximum sandboxes to return (max 1000, 0 returns all)"`
+		Offset     int      `json:"offset,omitempty" jsonschema:"matches to skip"`
+	}
+
 	type sandboxCreateArgs struct {
-		Image     string                 `json:"image" jsonschema:"docker image (required), e.g. node:24"`
-		Ports     []string               `json:"ports,omitempty" jsonschema:"container ports, e.g. [3000,8080/tcp]"`
-		Timeout   int                    `json:"timeout,omitempty" jsonschema:"auto stop timeout in seconds (0 uses default)"`
-		Resources *models.ResourceLimits `json:"resources,omitempty" jsonschema:"resource limits"`
-		Env       []string               `json:"env,omitempty" jsonschema:"environment vars as KEY=VALUE"`
+		Name             string                 `json:"name,omitempty" jsonschema:"sandbox name and subdomain, e.g. my-app (default generated)"`
+		Image            string                 `json:"image" jsonschema:"docker image (required), e.g. node:24"`
+		Ports            []string               `json:"ports,omitempty" jsonschema:"container ports, e.g. [3000,8080/tcp]"`
+		Timeout          int                    `json:"timeout,omitempty" jsonschema:"auto stop timeout in seconds (0 uses default)"`
+		Resources        *models.ResourceLimits `json:"resources,omitempty" jsonschema:"resource limits"`
+		Env              []string               `json:"env,omitempty" jsonschema:"environment vars as KEY=VALUE"`
+		Cmd              []string               `json:"cmd,omitempty" jsonschema:"startup command, e.g. [npm run dev] (default keeps the sandbox idle)"`
+		Entrypoint       []string               `json:"entrypoint,omitempty" jsonschema:"override the image entrypoint"`
+		WorkingDir       string                 `json:"working_dir,omitempty" jsonschema:"working directory for the startup command"`
+		ExpirationAction string                 `json:"expiration_action,omitempty" jsonschema:"on timeout: stop (default) or delete"`
+		TimeoutMode      string                 `json:"timeout_mode,omitempty" jsonschema:"absolute (default) or idle: timeout restarts on exec, file and proxy activity"`
+		Network          string                 `json:"network,omitempty" jsonschema:"bridge (default), internal (no outbound access) or none (loopback only)"`
+		Egress           *models.EgressPolicy   `json:"egress,omitempty" jsonschema:"outbound allow or deny rules: IP, CIDR, IP:port or :port"`
+		Labels           map[string]string      `json:"labels,omitempty" jsonschema:"tags to find the sandbox by later, e.g. {team: ml}"`
+		Git              *models.GitSource      `json:"git,omitempty" jsonschema:"repository to clone into the sandbox: url, optional ref, dir, depth and token or ssh_key"`
+		Hooks            *models.Hooks          `json:"hooks,omitempty" jsonschema:"commands run inside the sandbox: on_create (e.g. install dependencies), on_start and before_stop"`
 	}
 
 	type sandboxRenewArgs struct {
@@ -45,12 +108,23 @@ func addMCPTools(server *mcp.Server, d DockerClient, baseDomain, proxyAddr strin
 	}
 
 	type commandExecArgs struct {
-		SandboxID string            `json:"sandbox_id" jsonschema:"sandbox id"`
-		Command   string            `json:"command" jsonschema:"command name, e.g. npm"`
-		Args      []string          `json:"args,omitempty" jsonschema:"command arguments"`
-		Cwd       string            `json:"cwd,omitempty" jsonschema:"working directory"`
-		Env       map[string]string `json:"env,omitempty" jsonschema:"env vars as object, e.g. {\"NODE_ENV\":\"development\"}"`
-		Wait      bool              `json:"wait,omitempty" jsonschema:"wait until command finishes"`
+		SandboxID  string            `json:"sandbox_id" jsonschema:"sandbox id"`
+		Command    string            `json:"command" jsonschema:"command name, e.g. npm"`
+		Args       []string          `json:"args,omitempty" jsonschema:"command arguments"`
+		Cwd        string            `json:"cwd,omitempty" jsonschema:"working directory"`
+		Env        map[string]string `json:"env,omitempty" jsonschema:"env vars as object, e.g. {\"NODE_ENV\":\"development\"}"`
+		Stdin      string            `json:"stdin,omitempty" jsonschema:"input written to the command's stdin, which is then closed"`
+		Timeout    int               `json:"timeout,omitempty" jsonschema:"seconds before the command is killed, 0 = no limit"`
+		User       string            `json:"user,omitempty" jsonschema:"run as this user: name, uid, or user:group / uid:gid"`
+		InheritEnv *bool             `json:"inherit_env,omitempty" jsonschema:"see the container environment (default true)"`
+		Wait       bool              `json:"wait,omitempty" jsonschema:"wait until command finishes"`
+	}
+
+	type codeRunArgs struct {
+		Language string `json:"language" jsonschema:"one of python, node, go, ruby, bash"`
+		Code     string `json:"code" jsonschema:"program source"`
+		Stdin    string `json:"stdin,omitempty" jsonschema:"input written to the program's stdin, which is then closed"`
+		Timeout  int    `json:"timeout,omitempty" jsonschema:"seconds before the program is killed, 0 = default (60s)"`
 	}
 
 	type commandGetArgs struct {
@@ -107,16 +181,20 @@ func addMCPTools(server *mcp.Server, d DockerClient, baseDomain, proxyAddr strin
 			return mcpJSON(map[string]any{"status": "healthy"})
 		})
 
-	mcp.AddTool(server, &mcp.Tool{Name: "sandbox_list", Description: "List all sandboxes"},
-		func(ctx context.Context, _ *mcp.CallToolRequest, _ noArgs) (*mcp.CallToolResult, any, error) {
-			items, err := d.List(ctx)
+	mcp.AddTool(server, &mcp.Tool{Name: "sandbox_list", Description: "List sandboxes, optionally filtered and paged"},
+		func(ctx context.Context, _ *mcp.CallToolRequest, args sandboxListArgs) (*mcp.CallToolResult, any, error) {
+			q := models.SandboxListQuery{State: args.State, Image: args.Image, NamePrefix: args.NamePrefix, Label: args.Label, Limit: args.Limit, Offset: args.Offset}
+			if msg := validateListQuery(q); msg != "" {
+				return nil, nil, errors.New(msg)
+			}
+			items, total, err := d.List(ctx, q)
 			if err != nil {
 				return nil, nil, err
 			}
 			for i := range items {
-				items[i].URL = buildSandboxURL(items[i].Name, baseDomain, proxyAddr)
+				items[i].URL = buildSandboxURL(items[i].Name, baseDomain(), proxyAddr)
 			}
-			return mcpJSON(map[string]any{"sandboxes": items})
+			return mcpJSON(map[string]any{"sandboxes": items, "total": total})
 		})
 
 	mcp.AddTool(server, &mcp.Tool{Name: "sandbox_create", Description: "Create a sandbox"},
@@ -135,18 +213,52 @@ func addMCPTools(server *mcp.Server, d DockerClient, baseDomain, proxyAddr strin
 					return nil, nil, fmt.Errorf("resources.cpus must be between 0 and 4.0")
 				}
 			}
+			if args.ExpirationAction != "" && args.ExpirationAction != docker.ExpirationStop && args.ExpirationAction != docker.ExpirationDelete {
+				return nil, nil, fmt.Errorf("expiration_action must be stop or delete")
+			}
+			if args.TimeoutMode != "" && args.TimeoutMode != docker.TimeoutAbsolute && args.TimeoutMode != docker.TimeoutIdle {
+				return nil, nil, fmt.Errorf("timeout_mode must be absolute or idle")
+			}
 
-			resp, err := d.Create(ctx, models.CreateSandboxRequest{
-				Image:     args.Image,
-				Ports:     args.Ports,
-				Timeout:   args.Timeout,
-				Resources: args.Resources,
-				Env:       args.Env,
-			})
+			req := models.CreateSandboxRequest{
+				Name:             args.Name,
+				Image:            args.Image,
+				Ports:            args.Ports,
+				Timeout:          args.Timeout,
+				Resources:        args.Resources,
+				Env:              args.Env,
+				Cmd:              args.Cmd,
+				Entrypoint:       args.Entrypoint,
+				WorkingDir:       args.WorkingDir,
+				ExpirationAction: args.ExpirationAction,
+				TimeoutMode:      args.TimeoutMode,
+				Network:          args.Network,
+				Egress:           args.Egress,
+				Labels:           args.Labels,
+				Git:              args.Git,
+				Hooks:            args.Hooks,
+			}
+			if req.Name != "" && !docker.ValidSandboxName(req.Name) {
+				return nil, nil, fmt.Errorf("invalid sandbox name %q", req.Name)
+			}
+			if msg := docker.ValidateLabels(req.Labels); msg != "" {
+				return nil, nil, errors.New(msg)
+			}
+			if msg := docker.ValidateGitSource(req.Git); msg != "" {
+				return nil, nil, errors.New(msg)
+			}
+			if msg := docker.ValidateHooks(req.Hooks); msg != "" {
+				return nil, nil, errors.New(msg)
+			}
+			if msg := validateNetwork(req); msg != "" {
+				return nil, nil, errors.New(msg)
+			}
+
+			resp, err := d.Create(ctx, req)
 			if err != nil {
 				return nil, nil, err
 			}
-			resp.URL = buildSandboxURL(resp.Name, baseDomain, proxyAddr)
+			resp.URL = buildSandboxURL(resp.Name, baseDomain(), proxyAddr)
 			return mcpJSON(resp)
 		})
 
@@ -159,7 +271,7 @@ func addMCPTools(server *mcp.Server, d DockerClient, baseDomain, proxyAddr strin
 			if err != nil {
 				return nil, nil, err
 			}
-			resp.URL = buildSandboxURL(resp.Name, baseDomain, proxyAddr)
+			resp.URL = buildSandboxURL(resp.Name, baseDomain(), proxyAddr)
 			return mcpJSON(resp)
 		})
 
@@ -266,7 +378,7 @@ func addMCPTools(server *mcp.Server, d DockerClient, baseDomain, proxyAddr strin
 			if err != nil {
 				return nil, nil, err
 			}
-			return mcpJSON(network)
+			return mcpJSON(withPortURLs(network, baseDomain(), proxyAddr))
 		})
 
 	mcp.AddTool(server, &mcp.Tool{Name: "command_exec", Description: "Execute a command in a sandbox"},
@@ -277,12 +389,20 @@ func addMCPTools(server *mcp.Server, d DockerClient, baseDomain, proxyAddr strin
 			if args.Command == "" {
 				return nil, nil, fmt.Errorf("command is required")
 			}
-			cmd, err := d.ExecCommand(ctx, args.SandboxID, models.ExecCommandRequest{
-				Command: args.Command,
-				Args:    args.Args,
-				Cwd:     args.Cwd,
-				Env:     args.Env,
-			})
+			req := models.ExecCommandRequest{
+				Command:    args.Command,
+				Args:       args.Args,
+				Cwd:        args.Cwd,
+				Env:        args.Env,
+				Stdin:      args.Stdin,
+				Timeout:    args.Timeout,
+				User:       args.User,
+				InheritEnv: args.InheritEnv,
+			}
+			if msg := validateExecRequest(req); msg != "" {
+				return nil, nil, fmt.Errorf("%s", msg)
+			}
+			cmd, err := d.ExecCommand(ctx, args.SandboxID, req)
 			if err != nil {
 				return nil, nil, err
 			}
@@ -300,11 +420,11 @@ func addMCPTools(server *mcp.Server, d DockerClient, baseDomain, proxyAddr strin
 			if args.ID == "" {
 				return nil, nil, fmt.Errorf("id is required")
 			}
-			items, err := d.ListCommands(ctx, args.ID)
+			items, total, err := d.ListCommands(ctx, args.ID, models.CommandListQuery{})
 			if err != nil {
 				return nil, nil, err
 			}
-			return mcpJSON(models.CommandListResponse{Commands: items})
+			return mcpJSON(models.CommandListResponse{Commands: items, Total: total})
 		})
 
 	mcp.AddTool(server, &mcp.Tool{Name: "command_get", Description: "Get command status"},
@@ -354,6 +474,22 @@ func addMCPTools(server *mcp.Server, d DockerClient, baseDomain, proxyAddr strin
 			return mcpJSON(logs)
 		})
 
+	mcp.AddTool(server, &mcp.Tool{Name: "code_run", Description: "Run a program in a fresh sandbox and return its stdout, stderr and exit code"},
+		func(ctx context.Context, _ *mcp.CallToolRequest, args codeRunArgs) (*mcp.CallToolResult, any, error) {
+			if args.Code == "" {
+				return nil, nil, fmt.Errorf("code is required")
+			}
+			req := models.RunCodeRequest{Language: args.Language, Code: args.Code, Stdin: args.Stdin, Timeout: args.Timeout}
+			if msg := validateRunCodeRequest(req); msg != "" {
+				return nil, nil, fmt.Errorf("%s", msg)
+			}
+			result, err := d.RunCode(ctx, req)
+			if err != nil {
+				return nil, nil, err
+			}
+			return mcpJSON(result)
+		})
+
 	mcp.AddTool(server, &mcp.Tool{Name: "file_read", Description: "Read a file in a sandbox"},
 		func(ctx context.Context, _ *mcp.CallToolRequest, args fileReadArgs) (*mcp.CallToolResult, any, error) {
 			if args.SandboxID == "" || args.Path == "" {
@@ -388,6 +524,18 @@ func addMCPTools(server *mcp.Server, d DockerClient, baseDomain, proxyAddr strin
 			return mcpJSON(map[string]string{"status": "deleted"})
 		})
 
+	mcp.AddTool(server, &mcp.Tool{Name: "file_stat", Description: "Get size, mode, mtime and type of a file in a sandbox"},
+		func(ctx context.Context, _ *mcp.CallToolRequest, args fileReadArgs) (*mcp.CallToolResult, any, error) {
+			if args.SandboxID == "" || args.Path == "" {
+				return nil, nil, fmt.Errorf("sandbox_id and path are required")
+			}
+			stat, err := d.StatFile(ctx, args.SandboxID, args.Path)
+			if err != nil {
+				return nil, nil, err
+			}
+			return mcpJSON(stat)
+		})
+
 	mcp.AddTool(server, &mcp.Tool{Name: "file_list", Description: "List directory content in a sandbox"},
 		func(ctx context.Context, _ *mcp.CallToolRequest, args fileListArgs) (*mcp.CallToolResult, any, error) {
 			if args.SandboxID == "" {
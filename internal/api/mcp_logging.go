@@ -4,11 +4,11 @@ import (
 	"bytes"
 	"encoding/json"
 	"io"
-	"log"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"opensbx/internal/logging"
 )
 
 // MCPMetadataLogger logs request metadata for /v1/mcp endpoints.
@@ -31,13 +31,12 @@ func MCPMetadataLogger() gin.HandlerFunc {
 
 		c.Next()
 
-		log.Printf(
-			"mcp request method=%s path=%s status=%d duration=%s ip=%s",
-			method,
-			c.Request.URL.Path,
-			c.Writer.Status(),
-			time.Since(started).Round(time.Millisecond),
-			c.ClientIP(),
+		logging.FromContext(c.Request.Context()).Info("mcp request",
+			"mcp_method", method,
+			"path", c.Request.URL.Path,
+			"status", c.Writer.Status(),
+			"duration", time.Since(started).Round(time.Millisecond),
+			"ip", c.ClientIP(),
 		)
 	}
 }
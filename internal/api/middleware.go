@@ -1,25 +1,106 @@
 package api
 
 import (
-	"crypto/subtle"
-	"net/http"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"opensbx/internal/logging"
 )
 
-// APIKeyAuth returns a middleware that validates the Authorization: Bearer <key> header.
-func APIKeyAuth(key string) gin.HandlerFunc {
+// HeaderRequestID carries the request ID in requests and responses.
+const HeaderRequestID = "X-Request-ID"
+
+// requestIDKey is the gin context key holding the request ID.
+const requestIDKey = "opensbx.request_id"
+
+// maxRequestIDLen bounds client-supplied request IDs so they cannot bloat logs.
+const maxRequestIDLen = 128
+
+// APIKeyAuth returns a middleware that validates the Authorization: Bearer <key>
+// header against the static key or, when store is non-nil, the scoped key store.
+func APIKeyAuth(key string, store KeyStore) gin.HandlerFunc {
+	return RequestAuth(key, "", store)
+}
+
+// RequestID returns a middleware that reuses a valid incoming X-Request-ID or
+// generates one, echoes it in the response and stores it in the request context
+// so logs and outgoing calls (e.g. the authorization hook) carry it.
+func RequestID() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		header := c.GetHeader("Authorization")
-		token, found := strings.CutPrefix(header, "Bearer ")
-		if !found || subtle.ConstantTimeCompare([]byte(token), []byte(key)) != 1 {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-				"code":    "UNAUTHORIZED",
-				"message": "invalid or missing api key",
-			})
-			return
+		id := c.GetHeader(HeaderRequestID)
+		if !validRequestID(id) {
+			id = newRequestID()
 		}
+		c.Set(requestIDKey, id)
+		c.Header(HeaderRequestID, id)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+// RequestLogger returns a middleware that writes one structured log entry per
+// request, including the sandbox and command IDs from the path when present.
+func RequestLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		started := time.Now()
 		c.Next()
+
+		status := c.Writer.Status()
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Duration("duration", time.Since(started)),
+			slog.String("ip", c.ClientIP()),
+		}
+		if id := c.GetString(requestIDKey); id != "" {
+			attrs = append(attrs, slog.String("request_id", id))
+		}
+		if route := c.FullPath(); route != "" {
+			attrs = append(attrs, slog.String("route", route))
+			if strings.HasPrefix(route, "/v1/sandboxes/:id") {
+				attrs = append(attrs, slog.String("sandbox_id", c.Param("id")))
+			}
+		}
+		if cmdID := c.Param("cmdId"); cmdID != "" {
+			attrs = append(attrs, slog.String("cmd_id", cmdID))
+		}
+		if keyID := c.GetString(authKeyIDKey); keyID != "" {
+			attrs = append(attrs, slog.String("key_id", keyID))
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("error", c.Errors.String()))
+		}
+
+		level := slog.LevelInfo
+		if status >= 500 {
+			level = slog.LevelError
+		}
+		slog.LogAttrs(c.Request.Context(), level, "request", attrs...)
+	}
+}
+
+// validRequestID accepts short IDs made of printable ASCII without spaces.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
 	}
+	return "req_" + hex.EncodeToString(b)
 }
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"opensbx/internal/docker"
+	"opensbx/models"
+)
+
+// createNetwork handles POST /v1/networks.
+// @Summary      Create a network
+// @Description  Creates a shared network. Sandboxes created with its name in network_group join it and reach each other by sandbox name, e.g. an app connecting to "db:5432", without publishing host ports.
+// @Tags         networks
+// @Accept       json
+// @Produce      json
+// @Param        body  body      models.CreateNetworkRequest  true  "Network name"
+// @Success      201   {object}  models.NetworkGroup
+// @Failure      400   {object}  ErrorResponse
+// @Failure      409   {object}  ErrorResponse
+// @Failure      500   {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /networks [post]
+func (h *Handler) createNetwork(c *gin.Context) {
+	var req models.CreateNetworkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		badRequest(c, err.Error())
+		return
+	}
+	if !docker.ValidSandboxName(req.Name) {
+		badRequest(c, "name must be 1-63 lowercase letters, digits or hyphens, without leading, trailing or double hyphens")
+		return
+	}
+
+	group, err := h.docker.CreateNetworkGroup(c.Request.Context(), req.Name)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, group)
+}
+
+// listNetworks handles GET /v1/networks.
+// @Summary      List networks
+// @Description  Lists the caller's shared networks and the sandboxes attached to each.
+// @Tags         networks
+// @Produce      json
+// @Success      200  {array}   models.NetworkGroup
+// @Failure      500  {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /networks [get]
+func (h *Handler) listNetworks(c *gin.Context) {
+	groups, err := h.docker.ListNetworkGroups(c.Request.Context())
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, groups)
+}
+
+// getNetwork handles GET /v1/networks/:name.
+// @Summary      Get a network
+// @Description  Returns a shared network and the sandboxes attached to it.
+// @Tags         networks
+// @Produce      json
+// @Param        name  path      string  true  "Network name"
+// @Success      200   {object}  models.NetworkGroup
+// @Failure      404   {object}  ErrorResponse
+// @Failure      500   {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /networks/{name} [get]
+func (h *Handler) getNetwork(c *gin.Context) {
+	group, err := h.docker.GetNetworkGroup(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, group)
+}
+
+// deleteNetwork handles DELETE /v1/networks/:name.
+// @Summary      Delete a network
+// @Description  Deletes a shared network. Returns 409 with code NETWORK_IN_USE while sandboxes are attached to it.
+// @Tags         networks
+// @Param        name  path  string  true  "Network name"
+// @Success      204  "No Content"
+// @Failure      404  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /networks/{name} [delete]
+func (h *Handler) deleteNetwork(c *gin.Context) {
+	if err := h.docker.RemoveNetworkGroup(c.Request.Context(), c.Param("name")); err != nil {
+		internalError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
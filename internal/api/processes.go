@@ -0,0 +1,146 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"opensbx/internal/docker"
+	"opensbx/models"
+)
+
+// processNamePattern matches a process name: lowercase letters, digits and inner dashes.
+var processNamePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// validateProcessRequest checks the name, restart policy and user of a process.
+// Returns an empty string when valid or a client-facing message otherwise.
+func validateProcessRequest(req models.StartProcessRequest) string {
+	if !processNamePattern.MatchString(req.Name) {
+		return "name must be lowercase letters, digits and dashes (max 63)"
+	}
+	switch req.Restart {
+	case "", docker.RestartAlways, docker.RestartOnFailure, docker.RestartNever:
+	default:
+		return "restart must be \"always\", \"on-failure\" or \"never\""
+	}
+	return validateExecRequest(models.ExecCommandRequest{Command: req.Command, User: req.User})
+}
+
+// validateProcesses checks the startup processes of a create request: each
+// must be a valid process and their names unique.
+func validateProcesses(procs []models.StartProcessRequest) string {
+	seen := make(map[string]bool, len(procs))
+	for _, p := range procs {
+		if msg := validateProcessRequest(p); msg != "" {
+			return "processes: " + msg
+		}
+		if seen[p.Name] {
+			return "processes: duplicate name " + p.Name
+		}
+		seen[p.Name] = true
+	}
+	return ""
+}
+
+// startProcess handles POST /v1/sandboxes/:id/processes.
+// @Summary      Start a supervised process
+// @Description  Starts a long-running process (e.g. a dev server) that is restarted according to its restart policy when it exits, and started again whenever the sandbox starts or restarts. Each run is a command whose output is available under /cmd/{command_id}/logs.
+// @Tags         processes
+// @Accept       json
+// @Produce      json
+// @Param        id    path      string                      true  "Sandbox ID"
+// @Param        body  body      models.StartProcessRequest  true  "Process definition"
+// @Success      201   {object}  models.ProcessDetail
+// @Failure      400   {object}  ErrorResponse
+// @Failure      404   {object}  ErrorResponse
+// @Failure      409   {object}  ErrorResponse
+// @Failure      500   {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /sandboxes/{id}/processes [post]
+func (h *Handler) startProcess(c *gin.Context) {
+	var req models.StartProcessRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		badRequest(c, err.Error())
+		return
+	}
+	if msg := validateProcessRequest(req); msg != "" {
+		badRequest(c, msg)
+		return
+	}
+
+	p, err := h.docker.StartProcess(c.Request.Context(), c.Param("id"), req)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, p)
+}
+
+// listProcesses handles GET /v1/sandboxes/:id/processes.
+// @Summary      List processes
+// @Description  Returns the supervised processes of the sandbox with their status, and a snapshot of every process running in it (pid, user, cpu, memory, command).
+// @Tags         processes
+// @Produce      json
+// @Param        id   path      string  true  "Sandbox ID"
+// @Success      200  {object}  models.ProcessListResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /sandboxes/{id}/processes [get]
+func (h *Handler) listProcesses(c *gin.Context) {
+	procs, err := h.docker.ListProcesses(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	running, err := h.docker.RunningProcesses(c.Request.Context(), c.Param("id"))
+	if err != nil && !errors.Is(err, docker.ErrNotRunning) {
+		internalError(c, err)
+		return
+	}
+	if running == nil {
+		running = []models.ProcessInfo{}
+	}
+	c.JSON(http.StatusOK, models.ProcessListResponse{Processes: procs, Running: running})
+}
+
+// getProcess handles GET /v1/sandboxes/:id/processes/:name.
+// @Summary      Get a supervised process
+// @Description  Returns a supervised process with its status, restart count and current command.
+// @Tags         processes
+// @Produce      json
+// @Param        id    path      string  true  "Sandbox ID"
+// @Param        name  path      string  true  "Process name"
+// @Success      200   {object}  models.ProcessDetail
+// @Failure      404   {object}  ErrorResponse
+// @Failure      500   {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /sandboxes/{id}/processes/{name} [get]
+func (h *Handler) getProcess(c *gin.Context) {
+	p, err := h.docker.GetProcess(c.Request.Context(), c.Param("id"), c.Param("name"))
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
+
+// removeProcess handles DELETE /v1/sandboxes/:id/processes/:name.
+// @Summary      Remove a supervised process
+// @Description  Stops supervising the process, terminates its current run and forgets its definition.
+// @Tags         processes
+// @Param        id    path  string  true  "Sandbox ID"
+// @Param        name  path  string  true  "Process name"
+// @Success      204
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /sandboxes/{id}/processes/{name} [delete]
+func (h *Handler) removeProcess(c *gin.Context) {
+	if err := h.docker.RemoveProcess(c.Request.Context(), c.Param("id"), c.Param("name")); err != nil {
+		internalError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
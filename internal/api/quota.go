@@ -0,0 +1,19 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"opensbx/models"
+)
+
+// checkQuota writes a 429 QUOTA_EXCEEDED response and returns false if starting
+// sandboxes with the requested resources, one per entry, would exceed the
+// global quota or the quota of the caller's API key. The docker client checks
+// every sandbox it creates or starts; handlers call this to reject a batch,
+// or a run that may reuse a warm sandbox, before any of it starts.
+func (h *Handler) checkQuota(c *gin.Context, res ...*models.ResourceLimits) bool {
+	if err := h.docker.CheckQuota(c.Request.Context(), res...); err != nil {
+		internalError(c, err)
+		return false
+	}
+	return true
+}
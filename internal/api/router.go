@@ -2,16 +2,26 @@ package api
 
 import "github.com/gin-gonic/gin"
 
-// RegisterHealthCheck attaches the /v1/health endpoint directly to the engine (no auth).
+// RegisterHealthCheck attaches the /v1/health endpoint and the /healthz and
+// /readyz probes directly to the engine (no auth).
 func (h *Handler) RegisterHealthCheck(r *gin.Engine) {
 	r.GET("/v1/health", h.healthCheck)
+	r.GET("/healthz", h.liveness)
+	r.GET("/readyz", h.readiness)
 }
 
 // RegisterRoutes attaches all sandbox routes to the given router group.
 func (h *Handler) RegisterRoutes(v1 *gin.RouterGroup) {
+	v1.POST("/apply", h.applySpec)
+	v1.GET("/stats", h.getNodeStats)
+	v1.POST("/jobs", h.runJob)
+	v1.POST("/run", h.runCode)
+
 	sb := v1.Group("/sandboxes")
+	sb.Use(h.resolveSandbox, h.requireOwner)
 	sb.GET("", h.listSandboxes)
 	sb.POST("", h.createSandbox)
+	sb.POST("/import", h.importSandbox)
 	sb.GET("/:id", h.getSandbox)
 	sb.DELETE("/:id", h.deleteSandbox)
 	sb.POST("/:id/start", h.startSandbox)
@@ -21,20 +31,95 @@ func (h *Handler) RegisterRoutes(v1 *gin.RouterGroup) {
 	sb.POST("/:id/resume", h.resumeSandbox)
 	sb.POST("/:id/renew-expiration", h.renewExpiration)
 	sb.GET("/:id/network", h.getSandboxNetwork)
+	sb.POST("/:id/domains", h.addDomain)
+	sb.GET("/:id/domains", h.listDomains)
+	sb.DELETE("/:id/domains/:domain", h.removeDomain)
+	sb.GET("/:id/isolation", h.getSandboxIsolation)
+	sb.GET("/:id/env", h.getEnv)
+	sb.PUT("/:id/env", h.updateEnv)
+	sb.GET("/:id/export", h.exportSandbox)
+	sb.POST("/:id/snapshot", h.snapshotSandbox)
+	sb.POST("/:id/clone", h.cloneSandbox)
+	sb.POST("/:id/checkpoint", h.checkpointSandbox)
+	sb.GET("/:id/checkpoints", h.listCheckpoints)
+	sb.DELETE("/:id/checkpoints/:name", h.removeCheckpoint)
+	sb.POST("/:id/restore", h.restoreSandbox)
+	sb.GET("/:id/terminal", h.terminal)
 	sb.POST("/:id/cmd", h.execCommand)
+	sb.POST("/:id/cmd/batch", h.execBatch)
+	sb.POST("/:id/run", h.runCommand)
 	sb.GET("/:id/cmd", h.listCommands)
 	sb.GET("/:id/cmd/:cmdId", h.getCommand)
 	sb.POST("/:id/cmd/:cmdId/kill", h.killCommand)
 	sb.GET("/:id/cmd/:cmdId/logs", h.getCommandLogs)
+	sb.POST("/:id/processes", h.startProcess)
+	sb.GET("/:id/processes", h.listProcesses)
+	sb.GET("/:id/processes/:name", h.getProcess)
+	sb.DELETE("/:id/processes/:name", h.removeProcess)
+	sb.POST("/:id/sessions", h.createSession)
+	sb.GET("/:id/sessions", h.listSessions)
+	sb.DELETE("/:id/sessions/:sid", h.deleteSession)
+	sb.POST("/:id/sessions/:sid/execute", h.executeSession)
+	sb.POST("/:id/git/pull", h.pullGit)
+	sb.POST("/:id/tunnels", h.createTunnel)
+	sb.GET("/:id/tunnels", h.listTunnels)
+	sb.DELETE("/:id/tunnels/:tid", h.deleteTunnel)
+	sb.GET("/:id/tunnels/:tid/connect", h.connectTunnel)
+	sb.GET("/:id/logs", h.getSandboxLogs)
 	sb.GET("/:id/stats", h.getStats)
 	sb.GET("/:id/files", h.readFile)
 	sb.PUT("/:id/files", h.writeFile)
 	sb.DELETE("/:id/files", h.deleteFile)
 	sb.GET("/:id/files/list", h.listDir)
+	sb.GET("/:id/files/search", h.searchFiles)
+	sb.GET("/:id/files/stat", h.statFile)
+	sb.POST("/:id/files/upload", h.uploadArchive)
+	sb.GET("/:id/files/download", h.downloadArchive)
+
+	st := v1.Group("/stacks")
+	st.GET("", h.listStacks)
+	st.POST("", h.createStack)
+	st.GET("/:name", h.getStack)
+	st.DELETE("/:name", h.deleteStack)
+	st.POST("/:name/start", h.startStack)
+	st.POST("/:name/stop", h.stopStack)
+
+	nw := v1.Group("/networks")
+	nw.GET("", h.listNetworks)
+	nw.POST("", h.createNetwork)
+	nw.GET("/:name", h.getNetwork)
+	nw.DELETE("/:name", h.deleteNetwork)
+
+	sch := v1.Group("/schedules")
+	sch.GET("", h.listSchedules)
+	sch.POST("", h.createSchedule)
+	sch.GET("/:name", h.getSchedule)
+	sch.GET("/:name/runs", h.listScheduleRuns)
+	sch.DELETE("/:name", h.deleteSchedule)
 
 	img := v1.Group("/images")
 	img.GET("", h.listImages)
 	img.GET("/:id", h.getImage)
 	img.POST("/pull", h.pullImage)
+	img.POST("/prune", h.pruneImages)
 	img.DELETE("/:id", h.deleteImage)
+
+	if h.secrets != nil {
+		sec := v1.Group("/secrets")
+		sec.GET("", h.listSecrets)
+		sec.PUT("/:name", h.putSecret)
+		sec.DELETE("/:name", h.deleteSecret)
+	}
+
+	if h.audit != nil {
+		v1.GET("/audit", h.listAudit)
+	}
+
+	admin := v1.Group("/admin")
+	admin.GET("/policy", h.getPolicy)
+	if h.keys != nil {
+		admin.POST("/keys", h.createKey)
+		admin.GET("/keys", h.listKeys)
+		admin.DELETE("/keys/:id", h.revokeKey)
+	}
 }
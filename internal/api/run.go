@@ -0,0 +1,63 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"opensbx/models"
+)
+
+// runCommand handles POST /v1/sandboxes/:id/run.
+// @Summary      Run a command and stream its output
+// @Description  Executes a command and streams it as ND-JSON in one call: a "start" line with the command, its stdout and stderr lines as they are written, then an "exit" line with exit_code and the finished command. Equivalent to POST /cmd, GET /cmd/{cmdId}/logs?stream=true and GET /cmd/{cmdId}?wait=true. Send Accept: text/event-stream to get the stream as Server-Sent Events instead.
+// @Tags         commands
+// @Accept       json
+// @Produce      application/x-ndjson
+// @Produce      text/event-stream
+// @Param        id    path      string                     true  "Sandbox ID"
+// @Param        body  body      models.ExecCommandRequest  true  "Command to execute"
+// @Success      200   {object}  models.CommandEvent  "one per line"
+// @Failure      400   {object}  ErrorResponse
+// @Failure      404   {object}  ErrorResponse
+// @Failure      409   {object}  ErrorResponse
+// @Failure      500   {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /sandboxes/{id}/run [post]
+func (h *Handler) runCommand(c *gin.Context) {
+	var req models.ExecCommandRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		badRequest(c, err.Error())
+		return
+	}
+	if msg := validateExecRequest(req); msg != "" {
+		badRequest(c, msg)
+		return
+	}
+
+	ctx := c.Request.Context()
+	sandboxID := c.Param("id")
+	cmd, err := h.docker.ExecCommand(ctx, sandboxID, req)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	// The log readers replay output from the start, so nothing is lost
+	// between exec and attach.
+	stdoutR, stderrR, err := h.docker.StreamCommandLogs(ctx, sandboxID, cmd.ID)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	defer stdoutR.Close()
+	defer stderrR.Close()
+
+	stream := h.newEventStream(c)
+	defer stream.close()
+	stream.send("start", models.CommandEvent{Type: "start", Command: &cmd})
+
+	writeLogLines(c, stream, stdoutR, stderrR)
+
+	cmd, err = h.docker.WaitCommand(ctx, sandboxID, cmd.ID)
+	if err != nil {
+		return
+	}
+	stream.send("exit", models.CommandEvent{Type: "exit", Command: &cmd, ExitCode: cmd.ExitCode})
+}
@@ -0,0 +1,145 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"opensbx/internal/cron"
+	"opensbx/internal/docker"
+	"opensbx/models"
+)
+
+// createSchedule handles POST /v1/schedules.
+// @Summary      Create a schedule
+// @Description  Creates a recurring job. On every match of the cron expression (UTC) a sandbox is created from the sandbox spec, runs the command and is deleted again; the exit code and the tail of the output are recorded as a run. A run is skipped while the previous one is still going, and runs missed while the server was down are not made up for.
+// @Tags         schedules
+// @Accept       json
+// @Produce      json
+// @Param        body  body      models.CreateScheduleRequest  true  "Schedule"
+// @Success      201   {object}  models.Schedule
+// @Failure      400   {object}  ErrorResponse
+// @Failure      409   {object}  ErrorResponse
+// @Failure      500   {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /schedules [post]
+func (h *Handler) createSchedule(c *gin.Context) {
+	var req models.CreateScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		badRequest(c, err.Error())
+		return
+	}
+	if msg := validateScheduleRequest(req); msg != "" {
+		badRequest(c, msg)
+		return
+	}
+
+	schedule, err := h.docker.CreateSchedule(c.Request.Context(), req)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, schedule)
+}
+
+// validateScheduleRequest checks the schedule name, cron expression, sandbox
+// spec and command. Returns an empty string when valid or a client-facing
+// message otherwise.
+func validateScheduleRequest(req models.CreateScheduleRequest) string {
+	if !docker.ValidSandboxName(req.Name) {
+		return "name must be 1-63 lowercase letters, digits or hyphens, without leading, trailing or double hyphens"
+	}
+	sched, err := cron.Parse(req.Cron)
+	if err != nil {
+		return err.Error()
+	}
+	if sched.Next(time.Now()).IsZero() {
+		return "cron: expression never matches"
+	}
+	if req.Sandbox.Name != "" {
+		return "sandbox: name must be empty, every run gets a generated name"
+	}
+	if msg := validateCreateRequest(req.Sandbox); msg != "" {
+		return "sandbox: " + msg
+	}
+	if msg := validateExecRequest(req.Command); msg != "" {
+		return "command: " + msg
+	}
+	return ""
+}
+
+// listSchedules handles GET /v1/schedules.
+// @Summary      List schedules
+// @Description  Lists the caller's schedules with their next and most recent run.
+// @Tags         schedules
+// @Produce      json
+// @Success      200  {array}   models.Schedule
+// @Failure      500  {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /schedules [get]
+func (h *Handler) listSchedules(c *gin.Context) {
+	schedules, err := h.docker.ListSchedules(c.Request.Context())
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, schedules)
+}
+
+// getSchedule handles GET /v1/schedules/:name.
+// @Summary      Get a schedule
+// @Description  Returns a schedule with its next and most recent run.
+// @Tags         schedules
+// @Produce      json
+// @Param        name  path      string  true  "Schedule name"
+// @Success      200   {object}  models.Schedule
+// @Failure      404   {object}  ErrorResponse
+// @Failure      500   {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /schedules/{name} [get]
+func (h *Handler) getSchedule(c *gin.Context) {
+	schedule, err := h.docker.GetSchedule(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, schedule)
+}
+
+// listScheduleRuns handles GET /v1/schedules/:name/runs.
+// @Summary      List schedule runs
+// @Description  Returns the last 20 runs of a schedule, newest first, with their status, exit code and the last 64 KiB of stdout and stderr.
+// @Tags         schedules
+// @Produce      json
+// @Param        name  path      string  true  "Schedule name"
+// @Success      200   {array}   models.ScheduleRun
+// @Failure      404   {object}  ErrorResponse
+// @Failure      500   {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /schedules/{name}/runs [get]
+func (h *Handler) listScheduleRuns(c *gin.Context) {
+	runs, err := h.docker.ListScheduleRuns(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, runs)
+}
+
+// deleteSchedule handles DELETE /v1/schedules/:name.
+// @Summary      Delete a schedule
+// @Description  Deletes a schedule and its recorded runs. A run in progress finishes and deletes its sandbox.
+// @Tags         schedules
+// @Param        name  path  string  true  "Schedule name"
+// @Success      204  "No Content"
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /schedules/{name} [delete]
+func (h *Handler) deleteSchedule(c *gin.Context) {
+	if err := h.docker.RemoveSchedule(c.Request.Context(), c.Param("name")); err != nil {
+		internalError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"opensbx/models"
+)
+
+// SecretStore manages encrypted secrets per owner. Implemented by *secrets.Store.
+type SecretStore interface {
+	Put(owner, name, value string) (models.Secret, error)
+	List(owner string) ([]models.Secret, error)
+	Delete(owner, name string) error
+}
+
+// SetSecretStore enables the /v1/secrets endpoints. Must be called before RegisterRoutes.
+func (h *Handler) SetSecretStore(s SecretStore) {
+	h.secrets = s
+}
+
+// putSecret handles PUT /v1/secrets/:name.
+// @Summary      Store a secret
+// @Description  Creates or replaces a secret, encrypted at rest. Reference it when creating a sandbox with env_from_secrets to set it as the environment variable of the same name. Secrets belong to the caller's owner. The value is never returned.
+// @Tags         secrets
+// @Accept       json
+// @Produce      json
+// @Param        name  path      string                   true  "Secret name, a valid environment variable name"
+// @Param        body  body      models.PutSecretRequest  true  "Secret value"
+// @Success      200   {object}  models.Secret
+// @Failure      400   {object}  ErrorResponse
+// @Failure      500   {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /secrets/{name} [put]
+func (h *Handler) putSecret(c *gin.Context) {
+	var req models.PutSecretRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		badRequest(c, err.Error())
+		return
+	}
+
+	secret, err := h.secrets.Put(c.GetString(authOwnerKey), c.Param("name"), req.Value)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, secret)
+}
+
+// listSecrets handles GET /v1/secrets.
+// @Summary      List secrets
+// @Description  Lists the caller's secrets by name. Values are never returned.
+// @Tags         secrets
+// @Produce      json
+// @Success      200  {array}   models.Secret
+// @Failure      500  {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /secrets [get]
+func (h *Handler) listSecrets(c *gin.Context) {
+	list, err := h.secrets.List(c.GetString(authOwnerKey))
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// deleteSecret handles DELETE /v1/secrets/:name.
+// @Summary      Delete a secret
+// @Description  Deletes a secret. Sandboxes already created with it keep the value in their environment.
+// @Tags         secrets
+// @Param        name  path  string  true  "Secret name"
+// @Success      204
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /secrets/{name} [delete]
+func (h *Handler) deleteSecret(c *gin.Context) {
+	if err := h.secrets.Delete(c.GetString(authOwnerKey), c.Param("name")); err != nil {
+		internalError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
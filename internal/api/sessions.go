@@ -0,0 +1,123 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"opensbx/internal/docker"
+	"opensbx/models"
+)
+
+// createSession handles POST /v1/sandboxes/:id/sessions.
+// @Summary      Start a code session
+// @Description  Starts a persistent Python or Node interpreter in a running sandbox, like a Jupyter kernel: variables, imports and definitions survive from one execution to the next. The sandbox image must provide python3 or node. Sessions live in memory and end when the sandbox stops or the server restarts.
+// @Tags         sessions
+// @Accept       json
+// @Produce      json
+// @Param        id    path      string                       true  "Sandbox ID"
+// @Param        body  body      models.CreateSessionRequest  true  "Interpreter"
+// @Success      201   {object}  models.Session
+// @Failure      400   {object}  ErrorResponse
+// @Failure      404   {object}  ErrorResponse
+// @Failure      409   {object}  ErrorResponse
+// @Failure      500   {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /sandboxes/{id}/sessions [post]
+func (h *Handler) createSession(c *gin.Context) {
+	var req models.CreateSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		badRequest(c, err.Error())
+		return
+	}
+	if languages := docker.SessionLanguages(); !slices.Contains(languages, req.Language) {
+		badRequest(c, fmt.Sprintf("language must be one of %s", strings.Join(languages, ", ")))
+		return
+	}
+
+	session, err := h.docker.CreateSession(c.Request.Context(), c.Param("id"), req)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, session)
+}
+
+// listSessions handles GET /v1/sandboxes/:id/sessions.
+// @Summary      List code sessions
+// @Description  Returns the sessions running in a sandbox, oldest first.
+// @Tags         sessions
+// @Produce      json
+// @Param        id   path      string  true  "Sandbox ID"
+// @Success      200  {object}  map[string]interface{}  "sessions: list of sessions"
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /sandboxes/{id}/sessions [get]
+func (h *Handler) listSessions(c *gin.Context) {
+	sessions, err := h.docker.ListSessions(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"sessions": sessions})
+}
+
+// deleteSession handles DELETE /v1/sandboxes/:id/sessions/:sid.
+// @Summary      Stop a code session
+// @Description  Stops a session's interpreter and discards its state. A cell in progress ends with an error event.
+// @Tags         sessions
+// @Param        id   path  string  true  "Sandbox ID"
+// @Param        sid  path  string  true  "Session ID"
+// @Success      204  "No Content"
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /sandboxes/{id}/sessions/{sid} [delete]
+func (h *Handler) deleteSession(c *gin.Context) {
+	if err := h.docker.RemoveSession(c.Request.Context(), c.Param("id"), c.Param("sid")); err != nil {
+		internalError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// executeSession handles POST /v1/sandboxes/:id/sessions/:sid/execute.
+// @Summary      Execute a cell
+// @Description  Runs code in a session and streams its output as ND-JSON events: "stdout" and "stderr" text, "display" and "result" values as MIME bundles (text/plain, text/html, image/png as base64, ...), an "error" with the exception, and a final "end" line with the execution count. In Python the value of a trailing expression is the result. On timeout, or when the client disconnects, the cell is interrupted and the session keeps its state. One cell runs at a time per session. Send Accept: text/event-stream to get the stream as Server-Sent Events instead.
+// @Tags         sessions
+// @Accept       json
+// @Produce      application/x-ndjson
+// @Produce      text/event-stream
+// @Param        id    path      string                 true  "Sandbox ID"
+// @Param        sid   path      string                 true  "Session ID"
+// @Param        body  body      models.ExecuteRequest  true  "Cell"
+// @Success      200   {object}  models.SessionEvent  "one per line"
+// @Failure      400   {object}  ErrorResponse
+// @Failure      404   {object}  ErrorResponse
+// @Failure      409   {object}  ErrorResponse
+// @Failure      500   {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /sandboxes/{id}/sessions/{sid}/execute [post]
+func (h *Handler) executeSession(c *gin.Context) {
+	var req models.ExecuteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		badRequest(c, err.Error())
+		return
+	}
+	if req.Timeout < 0 {
+		badRequest(c, "timeout must be >= 0")
+		return
+	}
+
+	stream := h.newEventStream(c)
+	defer stream.close()
+	err := h.docker.ExecuteSession(c.Request.Context(), c.Param("id"), c.Param("sid"), req, func(ev models.SessionEvent) {
+		stream.send(ev.Type, ev)
+	})
+	if err != nil && !stream.close() {
+		internalError(c, err)
+	}
+}
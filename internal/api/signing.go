@@ -0,0 +1,307 @@
+package api
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"opensbx/internal/docker"
+)
+
+// Headers carrying an HMAC request signature.
+const (
+	HeaderTimestamp = "X-Opensbx-Timestamp" // unix seconds when the request was signed
+	HeaderSignature = "X-Opensbx-Signature" // hex HMAC-SHA256 of the canonical request
+)
+
+// signatureMaxSkew is how far a signed timestamp may drift from the server clock.
+const signatureMaxSkew = 5 * time.Minute
+
+// The body of a signed request is hashed before the signature can be checked.
+// Up to signedBodyMemory bytes are held in memory, larger bodies are spooled
+// to a temporary file, and bodies over signedBodyLimit are rejected.
+const (
+	signedBodyMemory = 1 << 20 // 1 MiB
+	signedBodyLimit  = 4 << 30 // 4 GiB
+)
+
+// SignRequest returns the hex HMAC-SHA256 signature for a request.
+// The canonical form is: timestamp \n METHOD \n request URI \n hex(sha256(body)).
+func SignRequest(secret string, timestamp int64, method, requestURI string, body []byte) string {
+	return signHashed(secret, timestamp, method, requestURI, sha256.Sum256(body))
+}
+
+// signHashed is SignRequest with the body already hashed.
+func signHashed(secret string, timestamp int64, method, requestURI string, bodyHash [sha256.Size]byte) string {
+	canonical := strconv.FormatInt(timestamp, 10) + "\n" +
+		strings.ToUpper(method) + "\n" +
+		requestURI + "\n" +
+		hex.EncodeToString(bodyHash[:])
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(canonical))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// RequestAuth returns a middleware that accepts a Bearer API key (the static
+// apiKey or a key from store) or an HMAC-signed request. An empty apiKey or
+// signingSecret, or a nil store, disables that method. Signed requests must be
+// fresh (±5 min) and each signature is accepted only once.
+//
+// The static key and signatures grant every scope; stored keys only their own.
+// When no method is configured and store holds no active keys, requests from
+// loopback pass through unauthenticated so an operator on the host can create
+// the first key; everyone else gets 401.
+func RequestAuth(apiKey, signingSecret string, store KeyStore) gin.HandlerFunc {
+	return CredentialAuth(NewCredentials(apiKey, signingSecret), store)
+}
+
+// Credentials holds the static API key and signing secret. They can be
+// replaced while the server runs, e.g. when the configuration is reloaded.
+type Credentials struct {
+	mu            sync.RWMutex
+	apiKey        string
+	signingSecret string
+}
+
+// NewCredentials returns Credentials holding apiKey and signingSecret.
+func NewCredentials(apiKey, signingSecret string) *Credentials {
+	return &Credentials{apiKey: apiKey, signingSecret: signingSecret}
+}
+
+// Set replaces the static API key and signing secret. Requests already
+// authenticated are not affected.
+func (cr *Credentials) Set(apiKey, signingSecret string) {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	cr.apiKey, cr.signingSecret = apiKey, signingSecret
+}
+
+// get returns the current static API key and signing secret.
+func (cr *Credentials) get() (string, string) {
+	cr.mu.RLock()
+	defer cr.mu.RUnlock()
+	return cr.apiKey, cr.signingSecret
+}
+
+// CredentialAuth is RequestAuth with credentials that can change at runtime.
+func CredentialAuth(creds *Credentials, store KeyStore) gin.HandlerFunc {
+	seen := newReplayCache()
+	return func(c *gin.Context) {
+		apiKey, signingSecret := creds.get()
+		token, hasBearer := bearerToken(c)
+		if hasBearer && apiKey != "" && subtle.ConstantTimeCompare([]byte(token), []byte(apiKey)) == 1 {
+			grant(c, "api_key", "", "", fullAccess)
+			return
+		}
+		if hasBearer && store != nil {
+			key, ok, err := store.Authenticate(token)
+			if err != nil {
+				internalError(c, err)
+				c.Abort()
+				return
+			}
+			if ok {
+				c.Request = c.Request.WithContext(docker.WithQuota(c.Request.Context(), key.Quota))
+				grant(c, "api_key", key.ID, keyOwner(key), key.Scopes)
+				return
+			}
+		}
+
+		if signingSecret != "" && c.GetHeader(HeaderSignature) != "" {
+			msg, cleanup := verifySignedRequest(c, signingSecret, seen)
+			defer cleanup()
+			if msg != "" {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+					"code":    "UNAUTHORIZED",
+					"message": msg,
+				})
+				return
+			}
+			grant(c, "signature", "", "", fullAccess)
+			return
+		}
+
+		if apiKey == "" && signingSecret == "" && !hasActiveKeys(store) {
+			if fromLoopback(c) {
+				grant(c, "", "", "", fullAccess)
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"code":    "UNAUTHORIZED",
+				"message": "no credentials configured: set API_KEY, or create the first key from the server's host",
+			})
+			return
+		}
+
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"code":    "UNAUTHORIZED",
+			"message": "invalid or missing api key",
+		})
+	}
+}
+
+// bearerToken returns the caller's bearer token: the Authorization header or,
+// on a WebSocket upgrade, a wsBearerPrefix subprotocol.
+func bearerToken(c *gin.Context) (string, bool) {
+	if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok {
+		return token, true
+	}
+	if !websocket.IsWebSocketUpgrade(c.Request) {
+		return "", false
+	}
+	for _, protocol := range websocket.Subprotocols(c.Request) {
+		if encoded, ok := strings.CutPrefix(protocol, wsBearerPrefix); ok {
+			token, err := base64.RawURLEncoding.DecodeString(encoded)
+			return string(token), err == nil
+		}
+	}
+	return "", false
+}
+
+// grant records the caller's identity, owner and scopes, then continues if the
+// scopes permit the current route. A non-empty owner restricts the docker
+// client to that owner's sandboxes for the rest of the request.
+func grant(c *gin.Context, method, keyID, owner string, scopes []string) {
+	if method != "" {
+		c.Set(authMethodKey, method)
+	}
+	c.Set(authKeyIDKey, keyID)
+	c.Set(authOwnerKey, owner)
+	c.Set(authScopesKey, scopes)
+	if owner != "" {
+		c.Request = c.Request.WithContext(docker.WithOwner(c.Request.Context(), owner))
+	}
+	if authorizeScope(c, scopes) {
+		c.Next()
+	}
+}
+
+// fromLoopback reports whether the request's connection comes from the host
+// itself. Forwarding headers are ignored, since any caller can set them.
+func fromLoopback(c *gin.Context) bool {
+	ip := net.ParseIP(c.RemoteIP())
+	return ip != nil && ip.IsLoopback()
+}
+
+// hasActiveKeys reports whether store holds usable keys. Errors count as true
+// so a database failure never opens the API.
+func hasActiveKeys(store KeyStore) bool {
+	if store == nil {
+		return false
+	}
+	active, err := store.HasActive()
+	return err != nil || active
+}
+
+// verifySignedRequest checks the timestamp window and signature of a request.
+// Returns an empty string on success or a client-facing reason on failure,
+// and a func to call once the request is done, which frees the body's copy.
+func verifySignedRequest(c *gin.Context, secret string, seen *replayCache) (string, func()) {
+	ts, err := strconv.ParseInt(c.GetHeader(HeaderTimestamp), 10, 64)
+	if err != nil {
+		return "missing or invalid " + HeaderTimestamp + " header", func() {}
+	}
+	signedAt := time.Unix(ts, 0)
+	if d := time.Since(signedAt); d > signatureMaxSkew || d < -signatureMaxSkew {
+		return "request signature expired", func() {}
+	}
+
+	bodyHash, cleanup, err := hashBody(c)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return "request body exceeds the signed request limit of " + strconv.FormatInt(tooLarge.Limit, 10) + " bytes", cleanup
+		}
+		return "failed to read request body", cleanup
+	}
+
+	want := signHashed(secret, ts, c.Request.Method, c.Request.URL.RequestURI(), bodyHash)
+	got := strings.ToLower(strings.TrimSpace(c.GetHeader(HeaderSignature)))
+	if !hmac.Equal([]byte(got), []byte(want)) {
+		return "invalid request signature", cleanup
+	}
+	if !seen.add(got, signedAt.Add(signatureMaxSkew)) {
+		return "request signature already used", cleanup
+	}
+	return "", cleanup
+}
+
+// hashBody hashes the request body and replaces it with a copy the handler
+// can read: in memory up to signedBodyMemory bytes, in a temporary file
+// beyond. cleanup removes the file once the request is done.
+func hashBody(c *gin.Context) ([sha256.Size]byte, func(), error) {
+	hash := sha256.New()
+	cleanup := func() {}
+	if c.Request.Body == nil {
+		return [sha256.Size]byte(hash.Sum(nil)), cleanup, nil
+	}
+	body := io.TeeReader(http.MaxBytesReader(c.Writer, c.Request.Body, signedBodyLimit), hash)
+
+	var head bytes.Buffer
+	if _, err := io.CopyN(&head, body, signedBodyMemory+1); errors.Is(err, io.EOF) {
+		c.Request.Body = io.NopCloser(&head)
+		return [sha256.Size]byte(hash.Sum(nil)), cleanup, nil
+	} else if err != nil {
+		return [sha256.Size]byte{}, cleanup, err
+	}
+
+	spool, err := os.CreateTemp("", "opensbx-signed-*")
+	if err != nil {
+		return [sha256.Size]byte{}, cleanup, err
+	}
+	cleanup = func() {
+		spool.Close()
+		os.Remove(spool.Name())
+	}
+	if _, err := io.Copy(spool, io.MultiReader(&head, body)); err != nil {
+		return [sha256.Size]byte{}, cleanup, err
+	}
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		return [sha256.Size]byte{}, cleanup, err
+	}
+	c.Request.Body = spool
+	return [sha256.Size]byte(hash.Sum(nil)), cleanup, nil
+}
+
+// replayCache remembers accepted signatures until their timestamp window closes.
+type replayCache struct {
+	mu sync.Mutex
+	m  map[string]time.Time // signature -> expiry
+}
+
+func newReplayCache() *replayCache {
+	return &replayCache{m: make(map[string]time.Time)}
+}
+
+// add records a signature. Returns false if it was already seen and still valid.
+func (r *replayCache) add(sig string, expiresAt time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for k, exp := range r.m {
+		if now.After(exp) {
+			delete(r.m, k)
+		}
+	}
+	if _, ok := r.m[sig]; ok {
+		return false
+	}
+	r.m[sig] = expiresAt
+	return true
+}
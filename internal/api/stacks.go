@@ -0,0 +1,196 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"opensbx/internal/docker"
+	"opensbx/models"
+)
+
+// createStack handles POST /v1/stacks.
+// @Summary      Create a stack
+// @Description  Creates a group of sandboxes from a compose-like spec, e.g. app + postgres + redis. The services share a private network on which each is reachable by its service name, and are started, stopped and deleted as a unit. The web service's sandbox is named after the stack, so the proxy routes the stack name to it; the others are named "<stack>-<service>". If a service fails, the whole stack is rolled back.
+// @Tags         stacks
+// @Accept       json
+// @Produce      json
+// @Param        body  body      models.CreateStackRequest  true  "Stack spec"
+// @Success      201   {object}  models.Stack
+// @Failure      400   {object}  ErrorResponse
+// @Failure      409   {object}  ErrorResponse
+// @Failure      429   {object}  ErrorResponse
+// @Failure      500   {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /stacks [post]
+func (h *Handler) createStack(c *gin.Context) {
+	var req models.CreateStackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		badRequest(c, err.Error())
+		return
+	}
+	if msg := validateStackRequest(req); msg != "" {
+		badRequest(c, msg)
+		return
+	}
+	res := make([]*models.ResourceLimits, 0, len(req.Services))
+	for _, svc := range req.Services {
+		res = append(res, svc.Resources)
+	}
+	if !h.checkQuota(c, res...) {
+		return
+	}
+
+	stack, err := h.docker.CreateStack(c.Request.Context(), req)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, h.withStackURL(stack))
+}
+
+// validateStackRequest checks the stack name, service names and every service spec.
+// Returns an empty string when valid or a client-facing message otherwise.
+func validateStackRequest(req models.CreateStackRequest) string {
+	if !docker.ValidSandboxName(req.Name) {
+		return "name must be 1-63 lowercase letters, digits or hyphens, without leading, trailing or double hyphens"
+	}
+	if _, ok := req.Services[req.Web]; req.Web != "" && !ok {
+		return fmt.Sprintf("web: no service named %q", req.Web)
+	}
+
+	web := docker.StackWeb(req)
+	names := make([]string, 0, len(req.Services))
+	for name := range req.Services {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		svc := req.Services[name]
+		if !docker.ValidSandboxName(name) {
+			return fmt.Sprintf("service %q: names must be lowercase letters, digits or hyphens", name)
+		}
+		if !docker.ValidSandboxName(docker.StackSandboxName(req.Name, name, web)) {
+			return fmt.Sprintf("service %q: sandbox name %q is too long", name, docker.StackSandboxName(req.Name, name, web))
+		}
+		if svc.Image == "" {
+			return fmt.Sprintf("service %q: image is required", name)
+		}
+		if svc.Name != "" || svc.Network != "" {
+			return fmt.Sprintf("service %q: name and network are set by the stack", name)
+		}
+		if msg := validateCreateRequest(svc); msg != "" {
+			return fmt.Sprintf("service %q: %s", name, msg)
+		}
+	}
+	return ""
+}
+
+// listStacks handles GET /v1/stacks.
+// @Summary      List stacks
+// @Description  Lists the caller's stacks with the state of their services.
+// @Tags         stacks
+// @Produce      json
+// @Success      200  {array}   models.Stack
+// @Failure      500  {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /stacks [get]
+func (h *Handler) listStacks(c *gin.Context) {
+	stacks, err := h.docker.ListStacks(c.Request.Context())
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	for i := range stacks {
+		stacks[i] = h.withStackURL(stacks[i])
+	}
+	c.JSON(http.StatusOK, stacks)
+}
+
+// getStack handles GET /v1/stacks/:name.
+// @Summary      Get a stack
+// @Description  Returns a stack and the state of its services.
+// @Tags         stacks
+// @Produce      json
+// @Param        name  path      string  true  "Stack name"
+// @Success      200   {object}  models.Stack
+// @Failure      404   {object}  ErrorResponse
+// @Failure      500   {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /stacks/{name} [get]
+func (h *Handler) getStack(c *gin.Context) {
+	stack, err := h.docker.GetStack(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, h.withStackURL(stack))
+}
+
+// startStack handles POST /v1/stacks/:name/start.
+// @Summary      Start a stack
+// @Description  Starts every stopped service of a stack, the web service last.
+// @Tags         stacks
+// @Produce      json
+// @Param        name  path      string  true  "Stack name"
+// @Success      200   {object}  models.Stack
+// @Failure      404   {object}  ErrorResponse
+// @Failure      429   {object}  ErrorResponse
+// @Failure      500   {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /stacks/{name}/start [post]
+func (h *Handler) startStack(c *gin.Context) {
+	stack, err := h.docker.StartStack(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, h.withStackURL(stack))
+}
+
+// stopStack handles POST /v1/stacks/:name/stop.
+// @Summary      Stop a stack
+// @Description  Stops every running service of a stack, the web service first.
+// @Tags         stacks
+// @Produce      json
+// @Param        name  path      string  true  "Stack name"
+// @Success      200   {object}  models.Stack
+// @Failure      404   {object}  ErrorResponse
+// @Failure      500   {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /stacks/{name}/stop [post]
+func (h *Handler) stopStack(c *gin.Context) {
+	stack, err := h.docker.StopStack(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, h.withStackURL(stack))
+}
+
+// deleteStack handles DELETE /v1/stacks/:name.
+// @Summary      Delete a stack
+// @Description  Removes every service of a stack, its network and the stack itself.
+// @Tags         stacks
+// @Param        name  path  string  true  "Stack name"
+// @Success      204  "No Content"
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /stacks/{name} [delete]
+func (h *Handler) deleteStack(c *gin.Context) {
+	if err := h.docker.RemoveStack(c.Request.Context(), c.Param("name")); err != nil {
+		internalError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// withStackURL sets the proxy URL of a stack with a web service.
+func (h *Handler) withStackURL(stack models.Stack) models.Stack {
+	if stack.Web != "" {
+		stack.URL = h.proxyURL(stack.Name)
+	}
+	return stack
+}
@@ -0,0 +1,215 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Content types of streaming responses. ND-JSON is the default; clients that
+// accept text/event-stream, such as a browser EventSource, get Server-Sent
+// Events instead.
+const (
+	contentTypeNDJSON = "application/x-ndjson"
+	contentTypeSSE    = "text/event-stream"
+)
+
+// DefaultMaxLogLine is the longest log line sent as one event when
+// StreamLimits sets none.
+const DefaultMaxLogLine = 1 << 20
+
+// StreamLimits bound what one streaming connection may hold on to, so a slow
+// or vanished client cannot pin output and goroutines on the server, and set
+// how often an idle stream shows it is still alive.
+type StreamLimits struct {
+	MaxLineSize  int           // bytes of a log line sent as one event; longer lines are split over several. 0 = DefaultMaxLogLine
+	WriteTimeout time.Duration // end the stream when the client takes longer than this to accept an event. 0 = wait forever
+	KeepAlive    time.Duration // send a ping event after this long without events, so proxies keep the connection open. 0 = never
+}
+
+// SetStreamLimits sets the limits of streaming responses.
+func (h *Handler) SetStreamLimits(l StreamLimits) {
+	h.streams = l
+}
+
+// pingEvent is sent on idle streams; clients skip it.
+var pingEvent = []byte(`{"type":"ping"}`)
+
+// eventStream writes the events of a streaming response: one JSON line per
+// event, or one SSE frame whose event field names the event's type and whose
+// data is the same JSON. Headers go out with the first event or an explicit
+// open, so a handler can still answer with an error before that. With a
+// KeepAlive, pings are written from another goroutine until close.
+type eventStream struct {
+	c      *gin.Context
+	sse    bool
+	limits StreamLimits
+	rc     *http.ResponseController
+
+	mu       sync.Mutex // serializes writes of the handler and the keepalive
+	opened   bool
+	closed   bool
+	lastSent time.Time
+	done     chan struct{}
+}
+
+// newEventStream returns a stream in the format the request's Accept header
+// asks for. The caller must close it.
+func (h *Handler) newEventStream(c *gin.Context) *eventStream {
+	s := &eventStream{
+		c:        c,
+		sse:      acceptsSSE(c.GetHeader("Accept")),
+		limits:   h.streams,
+		rc:       http.NewResponseController(c.Writer),
+		lastSent: time.Now(),
+		done:     make(chan struct{}),
+	}
+	if s.limits.KeepAlive > 0 {
+		go s.keepAlive()
+	}
+	return s
+}
+
+// acceptsSSE reports whether an Accept header lists text/event-stream.
+func acceptsSSE(accept string) bool {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, _ := strings.Cut(part, ";")
+		if strings.EqualFold(strings.TrimSpace(mediaType), contentTypeSSE) {
+			return true
+		}
+	}
+	return false
+}
+
+// open sets the status and content type of the stream once.
+func (s *eventStream) open() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.openLocked()
+}
+
+func (s *eventStream) openLocked() {
+	if s.opened {
+		return
+	}
+	s.opened = true
+	if s.sse {
+		s.c.Header("Content-Type", contentTypeSSE)
+		s.c.Header("Cache-Control", "no-cache")
+		s.c.Header("X-Accel-Buffering", "no") // keep reverse proxies from buffering events
+	} else {
+		s.c.Header("Content-Type", contentTypeNDJSON)
+	}
+	s.c.Status(http.StatusOK)
+}
+
+// close stops the keepalive and reports whether the response was started, by
+// an event or a ping. When it was not, the handler may still answer with an
+// error. Events can be sent after close.
+func (s *eventStream) close() (opened bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.done)
+	}
+	return s.opened
+}
+
+// keepAlive sends a ping whenever KeepAlive passes without an event. A ping
+// also starts the response, so a handler that has not sent anything yet
+// after KeepAlive can no longer answer with an error status.
+func (s *eventStream) keepAlive() {
+	timer := time.NewTimer(s.limits.KeepAlive)
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+		case <-s.done:
+			return
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			return
+		}
+		next := s.limits.KeepAlive - time.Since(s.lastSent)
+		if next <= 0 {
+			if err := s.writeLocked("ping", pingEvent); err != nil {
+				s.mu.Unlock()
+				return
+			}
+			next = s.limits.KeepAlive
+		}
+		s.mu.Unlock()
+		timer.Reset(next)
+	}
+}
+
+// send writes v as one event of type event and flushes it to the client. It
+// fails once the client is gone or, with a WriteTimeout, stops reading.
+func (s *eventStream) send(event string, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.writeLocked(event, b)
+}
+
+func (s *eventStream) writeLocked(event string, b []byte) error {
+	s.openLocked()
+	s.lastSent = time.Now()
+	if s.limits.WriteTimeout > 0 {
+		// Only bound this write: the stream may then sit idle for a long time.
+		s.rc.SetWriteDeadline(time.Now().Add(s.limits.WriteTimeout))
+		defer s.rc.SetWriteDeadline(time.Time{})
+	}
+	var err error
+	if s.sse {
+		_, err = fmt.Fprintf(s.c.Writer, "event: %s\ndata: %s\n\n", event, b)
+	} else {
+		_, err = fmt.Fprintf(s.c.Writer, "%s\n", b)
+	}
+	if err != nil {
+		return err
+	}
+	if err := s.rc.Flush(); err != nil && err != http.ErrNotSupported {
+		return err
+	}
+	return nil
+}
+
+// maxLine returns the longest log line sent as one event.
+func (s *eventStream) maxLine() int {
+	if s.limits.MaxLineSize > 0 {
+		return s.limits.MaxLineSize
+	}
+	return DefaultMaxLogLine
+}
+
+// scanLogLines returns a scanner over the lines of r that splits lines longer
+// than max into pieces of max bytes instead of stopping at them. partial
+// reports whether the last token was such a piece, not the end of a line.
+func scanLogLines(r io.Reader, max int) (scanner *bufio.Scanner, partial *bool) {
+	partial = new(bool)
+	scanner = bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, min(max, bufio.MaxScanTokenSize)), max)
+	scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		advance, token, err := bufio.ScanLines(data, atEOF)
+		*partial = advance == 0 && token == nil && err == nil && len(data) >= max
+		if *partial {
+			return max, data[:max], nil
+		}
+		return advance, token, err
+	})
+	return scanner, partial
+}
@@ -0,0 +1,203 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"opensbx/models"
+)
+
+// Default terminal size when the client does not send rows/cols.
+const (
+	defaultTerminalRows = 24
+	defaultTerminalCols = 80
+)
+
+// WebSocket subprotocols of the terminal and tunnel endpoints. Browsers cannot
+// set an Authorization header on a WebSocket, so they offer wsProtocol plus
+// wsBearerPrefix followed by the base64url-encoded API key; the server selects
+// wsProtocol and authenticates the key as if it were a bearer token.
+const (
+	wsProtocol     = "opensbx"
+	wsBearerPrefix = "opensbx.bearer."
+)
+
+// SetAllowedOrigins sets the browser origins, such as
+// "https://app.example.com", that may open terminal and tunnel WebSockets
+// besides the API's own host. Must be called before RegisterRoutes.
+func (h *Handler) SetAllowedOrigins(origins []string) {
+	h.origins = origins
+}
+
+// upgrader returns the WebSocket upgrader of the terminal and tunnel endpoints.
+func (h *Handler) upgrader() *websocket.Upgrader {
+	return &websocket.Upgrader{
+		ReadBufferSize:  4096,
+		WriteBufferSize: 4096,
+		Subprotocols:    []string{wsProtocol},
+		CheckOrigin:     h.originAllowed,
+	}
+}
+
+// originAllowed reports whether a WebSocket may be opened from the request's
+// Origin: the API's own host or one set with SetAllowedOrigins. Requests
+// without an Origin do not come from a browser and are allowed.
+func (h *Handler) originAllowed(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, allowed := range h.origins {
+		if strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkOrigin aborts with 403 unless originAllowed. Handlers call it before
+// starting the exec or dialing the tunnel the WebSocket would attach to.
+func (h *Handler) checkOrigin(c *gin.Context) bool {
+	if h.originAllowed(c.Request) {
+		return true
+	}
+	c.AbortWithStatusJSON(http.StatusForbidden, ErrorResponse{
+		Code:    "FORBIDDEN",
+		Message: "origin " + c.GetHeader("Origin") + " may not open WebSockets, see ALLOWED_ORIGINS",
+	})
+	return false
+}
+
+// terminal handles GET /v1/sandboxes/:id/terminal.
+// @Summary      Open an interactive terminal
+// @Description  Upgrades to a WebSocket attached to a TTY exec (default /bin/sh). Binary frames carry raw terminal bytes both ways. Text frames are JSON models.TerminalMessage: send {"type":"input","data":"ls\n"} or {"type":"resize","rows":40,"cols":120}; the server sends {"type":"exit","exit_code":0} when the shell exits. Browsers authenticate with the subprotocols ["opensbx", "opensbx.bearer.<base64url key>"] and must open it from the API's host or an ALLOWED_ORIGINS origin.
+// @Tags         commands
+// @Param        id     path   string  true   "Sandbox ID"
+// @Param        cmd    query  string  false  "Command to run, space separated (default /bin/sh)"
+// @Param        rows   query  int     false  "Initial terminal rows (default 24)"
+// @Param        cols   query  int     false  "Initial terminal columns (default 80)"
+// @Success      101
+// @Failure      403  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /sandboxes/{id}/terminal [get]
+func (h *Handler) terminal(c *gin.Context) {
+	rows := queryUint(c, "rows", defaultTerminalRows)
+	cols := queryUint(c, "cols", defaultTerminalCols)
+	cmd := strings.Fields(c.Query("cmd"))
+	if !h.checkOrigin(c) {
+		return
+	}
+
+	// Start the exec before upgrading so errors still get a JSON response.
+	session, err := h.docker.ExecInteractive(c.Request.Context(), c.Param("id"), cmd, rows, cols)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	defer session.Close()
+
+	ws, err := h.upgrader().Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return // upgrader already wrote the HTTP error
+	}
+	defer ws.Close()
+
+	// gorilla/websocket allows one concurrent writer; both pumps write.
+	var writeMu sync.Mutex
+	writeJSON := func(msg models.TerminalMessage) {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		ws.WriteJSON(msg)
+	}
+
+	// Output pump: terminal -> websocket. Ends when the process exits.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := session.Read(buf)
+			if n > 0 {
+				writeMu.Lock()
+				werr := ws.WriteMessage(websocket.BinaryMessage, buf[:n])
+				writeMu.Unlock()
+				if werr != nil {
+					return
+				}
+			}
+			if err != nil {
+				break
+			}
+		}
+
+		msg := models.TerminalMessage{Type: "exit"}
+		if code, err := session.ExitCode(context.Background()); err == nil {
+			msg.ExitCode = &code
+		}
+		writeJSON(msg)
+		ws.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+	}()
+
+	// Input pump: websocket -> terminal. Ends when the client disconnects.
+input:
+	for {
+		kind, data, err := ws.ReadMessage()
+		if err != nil {
+			break
+		}
+		if kind == websocket.BinaryMessage {
+			if _, err := session.Write(data); err != nil {
+				break input
+			}
+			continue
+		}
+
+		var msg models.TerminalMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			writeJSON(models.TerminalMessage{Type: "error", Data: "invalid control message"})
+			continue
+		}
+		switch msg.Type {
+		case "input":
+			if _, err := session.Write([]byte(msg.Data)); err != nil {
+				break input
+			}
+		case "resize":
+			if msg.Rows > 0 && msg.Cols > 0 {
+				session.Resize(c.Request.Context(), msg.Rows, msg.Cols)
+			}
+		}
+	}
+
+	// Closing the session unblocks the output pump if the client left first.
+	session.Close()
+	<-done
+}
+
+// queryUint parses a positive integer query parameter, falling back to def.
+func queryUint(c *gin.Context, key string, def uint) uint {
+	v, err := strconv.ParseUint(c.Query(key), 10, 32)
+	if err != nil || v == 0 {
+		return def
+	}
+	return uint(v)
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RouteTimeouts are the deadlines of API requests by route class. The request
+// context carries the deadline down to the Docker client, so a hung daemon
+// fails the request with 408 TIMEOUT instead of holding it open. 0 = no
+// deadline.
+type RouteTimeouts struct {
+	Short time.Duration // lookups and CRUD
+	Long  time.Duration // lifecycle operations that pull images, copy filesystems or run hooks
+}
+
+// longRoutes are the routes held to RouteTimeouts.Long, by method and route.
+var longRoutes = map[string]bool{
+	"POST /v1/apply":                           true,
+	"POST /v1/sandboxes":                       true,
+	"DELETE /v1/sandboxes/:id":                 true,
+	"POST /v1/sandboxes/:id/start":             true,
+	"POST /v1/sandboxes/:id/stop":              true,
+	"POST /v1/sandboxes/:id/restart":           true,
+	"POST /v1/sandboxes/:id/snapshot":          true,
+	"POST /v1/sandboxes/:id/clone":             true,
+	"POST /v1/sandboxes/:id/checkpoint":        true,
+	"POST /v1/sandboxes/:id/restore":           true,
+	"POST /v1/sandboxes/:id/git/pull":          true,
+	"GET /v1/sandboxes/:id/files":              true,
+	"PUT /v1/sandboxes/:id/files":              true,
+	"POST /v1/stacks":                          true,
+	"DELETE /v1/stacks/:name":                  true,
+	"POST /v1/stacks/:name/start":              true,
+	"POST /v1/stacks/:name/stop":               true,
+	"POST /v1/images/pull":                     true,
+	"POST /v1/images/prune":                    true,
+	"DELETE /v1/images/:id":                    true,
+	"POST /v1/sandboxes/:id/sessions":          true, // waits for the interpreter to start
+	"POST /v1/sandboxes/:id/processes":         true,
+	"DELETE /v1/sandboxes/:id/processes/:name": true,
+}
+
+// streamRoutes have no deadline: they stream output, tunnel connections or
+// transfer archives for as long as the client keeps them open. MCP routes,
+// whose sessions hold a stream open and whose tool calls create sandboxes and
+// run commands, have none either.
+var streamRoutes = map[string]bool{
+	"POST /v1/jobs":                                true,
+	"POST /v1/run":                                 true,
+	"POST /v1/sandboxes/import":                    true,
+	"GET /v1/sandboxes/:id/export":                 true,
+	"GET /v1/sandboxes/:id/terminal":               true,
+	"GET /v1/sandboxes/:id/tunnels/:tid/connect":   true,
+	"GET /v1/sandboxes/:id/logs":                   true,
+	"POST /v1/sandboxes/:id/run":                   true,
+	"POST /v1/sandboxes/:id/cmd/batch":             true,
+	"POST /v1/sandboxes/:id/sessions/:sid/execute": true,
+	"GET /v1/sandboxes/:id/files/download":         true,
+	"POST /v1/sandboxes/:id/files/upload":          true,
+}
+
+// routeTimeout returns the deadline of the request's route class.
+func (t RouteTimeouts) routeTimeout(c *gin.Context) time.Duration {
+	route := c.Request.Method + " " + c.FullPath()
+	switch {
+	case streamRoutes[route], strings.HasPrefix(c.FullPath(), "/v1/mcp"):
+		return 0
+	case route == "POST /v1/sandboxes/:id/cmd" || route == "GET /v1/sandboxes/:id/cmd/:cmdId":
+		if c.Query("wait") == "true" {
+			return 0
+		}
+	case route == "GET /v1/sandboxes/:id/cmd/:cmdId/logs":
+		if c.Query("stream") == "true" {
+			return 0
+		}
+	case longRoutes[route]:
+		return t.Long
+	}
+	return t.Short
+}
+
+// RequestTimeout returns a middleware that bounds each request by the
+// deadline of its route class. A handler that gives up because of it answers
+// 408 TIMEOUT through internalError; one that returns without a response gets
+// the same response here.
+func RequestTimeout(t RouteTimeouts) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		d := t.routeTimeout(c)
+		if d <= 0 {
+			c.Next()
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), d)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		// A status set without a body, such as 204, is a response too.
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && !c.Writer.Written() && c.Writer.Status() == http.StatusOK {
+			c.JSON(http.StatusRequestTimeout, ErrorResponse{Code: "TIMEOUT", Message: "request timed out after " + d.String()})
+		}
+	}
+}
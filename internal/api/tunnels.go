@@ -0,0 +1,150 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"opensbx/models"
+)
+
+// createTunnel handles POST /v1/sandboxes/:id/tunnels.
+// @Summary      Open a tunnel
+// @Description  Opens a TCP tunnel to a port inside a running sandbox, such as postgres or redis, without publishing the port on the host. Connect to the returned url with a WebSocket, authenticated like any other request: each WebSocket connection is one TCP connection to the port, and binary frames carry its bytes both ways. The port may start listening later; a connection that cannot reach it fails with PORT_UNREACHABLE. Tunnels live in memory and end when the sandbox is deleted or the server restarts. `osb tunnel` forwards a local port through one.
+// @Tags         tunnels
+// @Accept       json
+// @Produce      json
+// @Param        id    path      string                      true  "Sandbox ID"
+// @Param        body  body      models.CreateTunnelRequest  true  "Port"
+// @Success      201   {object}  models.Tunnel
+// @Failure      400   {object}  ErrorResponse
+// @Failure      404   {object}  ErrorResponse
+// @Failure      409   {object}  ErrorResponse
+// @Failure      500   {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /sandboxes/{id}/tunnels [post]
+func (h *Handler) createTunnel(c *gin.Context) {
+	var req models.CreateTunnelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		badRequest(c, err.Error())
+		return
+	}
+	tunnel, err := h.docker.CreateTunnel(c.Request.Context(), c.Param("id"), req)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, tunnel)
+}
+
+// listTunnels handles GET /v1/sandboxes/:id/tunnels.
+// @Summary      List tunnels
+// @Description  Returns the tunnels open to a sandbox, oldest first.
+// @Tags         tunnels
+// @Produce      json
+// @Param        id   path      string  true  "Sandbox ID"
+// @Success      200  {object}  map[string]interface{}  "tunnels: list of tunnels"
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /sandboxes/{id}/tunnels [get]
+func (h *Handler) listTunnels(c *gin.Context) {
+	tunnels, err := h.docker.ListTunnels(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"tunnels": tunnels})
+}
+
+// deleteTunnel handles DELETE /v1/sandboxes/:id/tunnels/:tid.
+// @Summary      Close a tunnel
+// @Description  Closes a tunnel and every connection open through it.
+// @Tags         tunnels
+// @Param        id   path  string  true  "Sandbox ID"
+// @Param        tid  path  string  true  "Tunnel ID"
+// @Success      204  "No Content"
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /sandboxes/{id}/tunnels/{tid} [delete]
+func (h *Handler) deleteTunnel(c *gin.Context) {
+	if err := h.docker.RemoveTunnel(c.Request.Context(), c.Param("id"), c.Param("tid")); err != nil {
+		internalError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// connectTunnel handles GET /v1/sandboxes/:id/tunnels/:tid/connect.
+// @Summary      Connect through a tunnel
+// @Description  Dials the tunnel's port and upgrades to a WebSocket carrying the TCP connection: binary frames hold raw bytes both ways, text frames are ignored. The WebSocket closes when either side closes the connection. Browsers authenticate and are restricted by origin as for the terminal.
+// @Tags         tunnels
+// @Param        id   path  string  true  "Sandbox ID"
+// @Param        tid  path  string  true  "Tunnel ID"
+// @Success      101
+// @Failure      403  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
+// @Failure      502  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /sandboxes/{id}/tunnels/{tid}/connect [get]
+func (h *Handler) connectTunnel(c *gin.Context) {
+	if !h.checkOrigin(c) {
+		return
+	}
+
+	// Dial before upgrading so errors still get a JSON response.
+	conn, err := h.docker.DialTunnel(c.Request.Context(), c.Param("id"), c.Param("tid"))
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	defer conn.Close()
+
+	ws, err := h.upgrader().Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return // upgrader already wrote the HTTP error
+	}
+	defer ws.Close()
+
+	// Port -> websocket. Ends when the sandbox side closes.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := conn.Read(buf)
+			if n > 0 {
+				if werr := ws.WriteMessage(websocket.BinaryMessage, buf[:n]); werr != nil {
+					return
+				}
+			}
+			if err != nil {
+				break
+			}
+		}
+		ws.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+	}()
+
+	// Websocket -> port. Ends when the client disconnects.
+	for {
+		kind, data, err := ws.ReadMessage()
+		if err != nil {
+			break
+		}
+		if kind != websocket.BinaryMessage {
+			continue
+		}
+		if _, err := conn.Write(data); err != nil {
+			break
+		}
+	}
+
+	// Closing the connection unblocks the output pump if the client left first.
+	conn.Close()
+	<-done
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"net"
 	"strings"
+
+	"opensbx/models"
 )
 
 func buildSandboxURL(name, baseDomain, proxyAddr string) string {
@@ -26,6 +28,40 @@ func buildSandboxURL(name, baseDomain, proxyAddr string) string {
 	return fmt.Sprintf("https://%s.%s", name, baseDomain)
 }
 
+// buildPortURL returns the proxy URL for a specific container port ("8080/tcp"),
+// addressed as <name>--<port>.<baseDomain>.
+func buildPortURL(name, port, baseDomain, proxyAddr string) string {
+	number, _, _ := strings.Cut(port, "/")
+	if name == "" || number == "" {
+		return ""
+	}
+	return buildSandboxURL(name+"--"+number, baseDomain, proxyAddr)
+}
+
+// buildDomainURL returns the proxy URL for a custom domain. Like sandbox URLs,
+// local deployments use plain HTTP on the proxy port and public ones HTTPS.
+func buildDomainURL(host, baseDomain, proxyAddr string) string {
+	if !isLocalBaseDomain(strings.TrimSpace(baseDomain)) {
+		return "https://" + host
+	}
+	if proxyAddr == "" || proxyAddr == ":80" || proxyAddr == ":443" {
+		return "http://" + host
+	}
+	return "http://" + host + proxyAddr
+}
+
+// withPortURLs fills network.URLs with the proxy URL of every mapped port.
+func withPortURLs(network models.SandboxNetwork, baseDomain, proxyAddr string) models.SandboxNetwork {
+	if network.Name == "" || len(network.PortsMap) == 0 {
+		return network
+	}
+	network.URLs = make(map[string]string, len(network.PortsMap))
+	for port := range network.PortsMap {
+		network.URLs[port] = buildPortURL(network.Name, port, baseDomain, proxyAddr)
+	}
+	return network
+}
+
 func isLocalBaseDomain(baseDomain string) bool {
 	host := strings.Trim(strings.TrimSpace(baseDomain), "[]")
 	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
@@ -25,3 +25,17 @@ func TestBuildSandboxURL_EmptyBaseDomainFallsBackToLocalhost(t *testing.T) {
 		t.Fatalf("buildSandboxURL() = %q, want %q", got, want)
 	}
 }
+
+func TestBuildPortURL(t *testing.T) {
+	got := buildPortURL("demo", "8080/tcp", "localhost", ":3000")
+	want := "http://demo--8080.localhost:3000"
+	if got != want {
+		t.Fatalf("buildPortURL() = %q, want %q", got, want)
+	}
+
+	got = buildPortURL("demo", "5173/tcp", "opensbx.run", ":3000")
+	want = "https://demo--5173.opensbx.run"
+	if got != want {
+		t.Fatalf("buildPortURL() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,93 @@
+package audit
+
+import (
+	"time"
+
+	"opensbx/internal/database"
+	"opensbx/models"
+)
+
+// Default and maximum number of events returned by Query.
+const (
+	DefaultLimit = 100
+	MaxLimit     = 1000
+)
+
+// Log persists audit events in the database.
+type Log struct {
+	repo *database.Repository
+}
+
+// New creates a Log backed by the given repository.
+func New(repo *database.Repository) *Log {
+	return &Log{repo: repo}
+}
+
+// Record appends an event. A zero Time is set to now.
+func (l *Log) Record(e models.AuditEvent) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	return l.repo.SaveAuditEvent(database.AuditEvent{
+		Time:      e.Time.UnixMilli(),
+		Action:    e.Action,
+		SandboxID: e.SandboxID,
+		Auth:      e.Auth,
+		KeyID:     e.KeyID,
+		Owner:     e.Owner,
+		Actor:     e.Actor,
+		Method:    e.Method,
+		Path:      e.Path,
+		Status:    e.Status,
+		RequestID: e.RequestID,
+	})
+}
+
+// Query returns events matching q, newest first. The limit defaults to
+// DefaultLimit and is capped at MaxLimit.
+func (l *Log) Query(q models.AuditQuery) ([]models.AuditEvent, error) {
+	f := database.AuditFilter{
+		SandboxID: q.SandboxID,
+		Action:    q.Action,
+		Limit:     clampLimit(q.Limit),
+	}
+	if !q.Since.IsZero() {
+		f.Since = q.Since.UnixMilli()
+	}
+	if !q.Until.IsZero() {
+		f.Until = q.Until.UnixMilli()
+	}
+
+	recs, err := l.repo.FindAuditEvents(f)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]models.AuditEvent, 0, len(recs))
+	for _, rec := range recs {
+		out = append(out, models.AuditEvent{
+			ID:        rec.ID,
+			Time:      time.UnixMilli(rec.Time),
+			Action:    rec.Action,
+			SandboxID: rec.SandboxID,
+			Auth:      rec.Auth,
+			KeyID:     rec.KeyID,
+			Owner:     rec.Owner,
+			Actor:     rec.Actor,
+			Method:    rec.Method,
+			Path:      rec.Path,
+			Status:    rec.Status,
+			RequestID: rec.RequestID,
+		})
+	}
+	return out, nil
+}
+
+func clampLimit(n int) int {
+	switch {
+	case n <= 0:
+		return DefaultLimit
+	case n > MaxLimit:
+		return MaxLimit
+	}
+	return n
+}
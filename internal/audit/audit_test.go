@@ -0,0 +1,54 @@
+package audit
+
+import (
+	"testing"
+	"time"
+
+	"opensbx/internal/database"
+	"opensbx/models"
+)
+
+func TestLogRecordQuery(t *testing.T) {
+	l := New(database.NewRepository(database.New(":memory:")))
+	base := time.UnixMilli(1_700_000_000_000)
+
+	for i, action := range []string{"sandbox.create", "command.exec", "sandbox.delete"} {
+		e := models.AuditEvent{Time: base.Add(time.Duration(i) * time.Minute), Action: action, SandboxID: "sb1", KeyID: "key_1", Status: 200}
+		if err := l.Record(e); err != nil {
+			t.Fatalf("Record() error: %v", err)
+		}
+	}
+	if err := l.Record(models.AuditEvent{Action: "image.pull"}); err != nil {
+		t.Fatalf("Record() error: %v", err)
+	}
+
+	got, err := l.Query(models.AuditQuery{SandboxID: "sb1", Since: base.Add(time.Minute)})
+	if err != nil {
+		t.Fatalf("Query() error: %v", err)
+	}
+	if len(got) != 2 || got[0].Action != "sandbox.delete" || got[1].Action != "command.exec" {
+		t.Fatalf("Query() = %+v, want delete then exec", got)
+	}
+	if !got[1].Time.Equal(base.Add(time.Minute)) || got[1].KeyID != "key_1" {
+		t.Fatalf("Query() event = %+v", got[1])
+	}
+
+	latest, _ := l.Query(models.AuditQuery{Limit: 1})
+	if len(latest) != 1 || latest[0].Action != "image.pull" || latest[0].Time.IsZero() {
+		t.Fatalf("Query(limit 1) = %+v, want the image.pull event stamped now", latest)
+	}
+}
+
+func TestClampLimit(t *testing.T) {
+	tests := []struct{ in, want int }{
+		{0, DefaultLimit},
+		{-5, DefaultLimit},
+		{10, 10},
+		{MaxLimit + 1, MaxLimit},
+	}
+	for _, tt := range tests {
+		if got := clampLimit(tt.in); got != tt.want {
+			t.Fatalf("clampLimit(%d) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
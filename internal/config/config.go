@@ -2,19 +2,86 @@ package config
 
 import (
 	"flag"
+	"log"
 	"net"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // Config holds all application configuration.
 type Config struct {
-	Addr                          string   // HTTP listen address, e.g. ":8080"
-	APIKey                        string   // API key for authentication (env API_KEY). Empty = auth disabled.
-	ProxyAddrs                    []string // Reverse proxy listen addresses, e.g. [":80", ":3000"]
-	BaseDomain                    string   // Base domain for subdomain routing, e.g. "localhost"
-	LogFile                       string   // Path to .log file where API/MCP logs are written.
-	MCPDisableLocalhostProtection bool     // Disable MCP SDK localhost Host-header guard for non-local domains.
+	Addr                          string            // HTTP listen address, e.g. ":8080"
+	APIKey                        string            // API key for authentication (env API_KEY). Empty = auth disabled.
+	SigningSecret                 string            // HMAC secret for signed requests (env SIGNING_SECRET). Empty = signing disabled.
+	SecretsKey                    string            // Key that encrypts stored secrets (env SECRETS_KEY). Empty = /v1/secrets disabled.
+	ContainerEngine               string            // Container engine sandboxes run on: "docker" or "podman".
+	DockerHost                    string            // Docker daemon address, e.g. "tcp://10.0.0.5:2376". Empty = DockerContext or the local daemon.
+	DockerContext                 string            // docker CLI context to use when DockerHost is empty. Empty = none.
+	DockerCertPath                string            // Directory with ca.pem, cert.pem and key.pem for a TLS DockerHost.
+	SandboxNetwork                string            // Isolated bridge network sandboxes join (ICC disabled). Empty = docker default bridge.
+	EgressFirewall                bool              // Install host iptables rules denying sandbox egress to EgressDeny and the API port; enables per-sandbox egress rules and bandwidth limits.
+	EgressDeny                    string            // Comma-separated deny list: IP, CIDR, IP:port or :port (host-local).
+	AllowedDevices                []string          // Host device paths sandboxes may map (everything else is denied by policy).
+	SandboxReadOnly               bool              // Mount sandbox root filesystems read-only (tmpfs /tmp and /run stay writable).
+	SandboxNoNewPrivileges        bool              // Set no-new-privileges on sandboxes.
+	SandboxCapDrop                []string          // Capabilities dropped from every sandbox.
+	SandboxSeccompProfile         string            // Path to a seccomp profile JSON applied to every sandbox. Empty = Docker's default profile.
+	SandboxPidsLimit              int64             // Maximum processes per sandbox. 0 = unlimited.
+	SandboxUser                   string            // User sandboxes run as, e.g. "1000:1000". Empty = the image's user.
+	SandboxHostIP                 string            // Host address sandbox ports are published on. Default 127.0.0.1, reachable only through the proxy.
+	SandboxPortRange              string            // Host port range sandbox ports are published from, e.g. "30000-30999". Empty = ephemeral ports.
+	SandboxRuntime                string            // OCI runtime for sandboxes that do not pick one, e.g. "runsc". Empty = Docker's default runtime.
+	AllowedRuntimes               []string          // OCI runtimes sandboxes may use. Empty = any runtime the Docker daemon has.
+	VMRuntimes                    []string          // OCI runtimes that boot a micro-VM per sandbox (Kata, incl. Firecracker and Cloud Hypervisor).
+	VMMemoryOverhead              int64             // MB per running micro-VM counted against memory quotas on top of its limit.
+	MicroVMOnly                   bool              // Run every sandbox in a micro-VM: SANDBOX_RUNTIME and ALLOWED_RUNTIMES must be VM runtimes.
+	CodePoolSize                  int               // Idle runner sandboxes POST /v1/run keeps per language once it has been used. 0 = create one per run.
+	CodeImages                    map[string]string // Image per POST /v1/run language replacing the built-in one, e.g. {"python": "python:3.13-slim"}.
+	AllowGPUs                     bool              // Let sandboxes request GPUs (requires a GPU-enabled Docker daemon, e.g. the NVIDIA container toolkit).
+	AuthzWebhookURL               string            // External authorization hook consulted before mutating requests. Empty = disabled.
+	AllowedOrigins                []string          // Browser origins besides the API's host that may open terminal and tunnel WebSockets.
+	ReapGracePeriod               time.Duration     // How long a delete-on-expiry sandbox stays stopped before it is removed.
+	MaxSandboxLifetime            time.Duration     // Cap on how long a sandbox may exist, whatever its renewals; also the max_lifetime of sandboxes that set none. 0 = unlimited.
+	CommandLogRetention           time.Duration     // How long finished commands' output is kept. 0 = until the sandbox is removed.
+	ImageGCRetention              time.Duration     // How long an image may go unused by any sandbox before it is removed. 0 = never.
+	MaxSandboxes                  int               // Global cap on running sandboxes. 0 = unlimited.
+	MaxTotalMemory                int64             // Global cap on memory (MB) across running sandboxes. 0 = unlimited.
+	MaxTotalCPUs                  float64           // Global cap on CPUs across running sandboxes. 0 = unlimited.
+	ProxyAddrs                    []string          // Reverse proxy listen addresses, e.g. [":80", ":3000"]
+	ProxyTLSAddrs                 []string          // Reverse proxy HTTPS listen addresses, e.g. [":443"]. Empty = HTTPS disabled.
+	ProxyTLSCertFile              string            // PEM certificate (usually a wildcard for *.BaseDomain) for the proxy's HTTPS listeners.
+	ProxyTLSKeyFile               string            // PEM private key for ProxyTLSCertFile.
+	ProxyACME                     bool              // Obtain proxy certificates per sandbox from an ACME CA instead of a static certificate.
+	ACMEEmail                     string            // Contact email registered with the ACME CA.
+	ACMECacheDir                  string            // Directory where ACME account keys and certificates are cached.
+	ACMEDirectoryURL              string            // ACME directory URL. Empty = Let's Encrypt production.
+	ProxyWSIdleTimeout            time.Duration     // Close proxied WebSockets without traffic for this long. 0 = never.
+	ProxyWSMaxDuration            time.Duration     // Close proxied WebSockets open for this long. 0 = never.
+	ProxyCacheTTL                 time.Duration     // How long the proxy caches resolved routes. 0 = no cache.
+	RequestTimeout                time.Duration     // Deadline of API lookups and CRUD requests. 0 = none.
+	LongRequestTimeout            time.Duration     // Deadline of API requests that pull images, copy filesystems or run hooks. 0 = none.
+	StreamMaxLineSize             int               // Bytes of a log line streamed as one event; longer lines are split. 0 = default (1 MiB).
+	StreamWriteTimeout            time.Duration     // End a streaming response when the client takes longer than this to accept an event. 0 = never.
+	StreamKeepAlive               time.Duration     // Send a ping on streaming responses after this long without events. 0 = never.
+	ProxyPagesDir                 string            // Directory of HTML templates replacing the proxy's loading/stopped/expired/not found pages. Empty = built-in pages.
+	BaseDomain                    string            // Base domain for subdomain routing, e.g. "localhost"
+	DatabaseURL                   string            // SQLite database file, or a "sqlite://", "postgres://" or "mysql://" URL.
+	DatabaseMaxOpenConns          int               // Connections to PostgreSQL or MySQL open at once. 0 = unlimited.
+	DatabaseMaxIdleConns          int               // Idle connections to PostgreSQL or MySQL kept for reuse. 0 = driver default (2).
+	DatabaseConnMaxLifetime       time.Duration     // Replace PostgreSQL or MySQL connections older than this. 0 = never.
+	LogFile                       string            // Path to .log file where API/MCP logs are written.
+	LogFormat                     string            // Structured log format: "json" (default) or "text".
+	MCPDisableLocalhostProtection bool              // Disable MCP SDK localhost Host-header guard for non-local domains.
+	TLSCertFile                   string            // PEM certificate for the API listener. Empty = plain HTTP.
+	TLSKeyFile                    string            // PEM private key for the API listener.
+	TLSMinVersion                 string            // Minimum TLS version accepted by the API listener ("1.2" or "1.3").
+	TLSClientCAFile               string            // CA bundle used to require client certificates on the API listener (mTLS).
+	ShutdownPolicy                string            // What shutdown does with running sandboxes: "stop" (default) or "detach" (leave them running and re-adopt them on startup).
+	EnvFile                       string            // File of KEY=VALUE settings applied over the environment at startup and on SIGHUP. Empty = none.
+
+	flagsSet map[string]bool // flags given on the command line; reloads do not override them
 }
 
 // PrimaryProxyAddr returns the first proxy address, used for generating URLs.
@@ -25,23 +92,171 @@ func (c *Config) PrimaryProxyAddr() string {
 	return c.ProxyAddrs[0]
 }
 
-// Load parses flags and env vars. Flags take precedence over env vars.
+// EgressDenyList returns the configured egress deny list plus the API port on the
+// host, so sandboxes cannot call the control API that manages them.
+func (c *Config) EgressDenyList() string {
+	_, port, err := net.SplitHostPort(c.Addr)
+	if err != nil || port == "" {
+		return c.EgressDeny
+	}
+	if c.EgressDeny == "" {
+		return ":" + port
+	}
+	return c.EgressDeny + ",:" + port
+}
+
+// Load parses flags and env vars. Flags take precedence over env vars, and
+// the settings of the env file (-env-file or ENV_FILE) over the environment.
+// Exits if the env file cannot be read.
 func Load() *Config {
+	envFile := envFilePath(os.Args[1:])
+	if envFile != "" {
+		if err := applyEnvFile(envFile); err != nil {
+			log.Fatalf("config: %v", err)
+		}
+	}
+
 	addr := flag.String("addr", envOrDefault("ADDR", ":8080"), "HTTP listen address")
 	proxyAddr := flag.String("proxy-addr", envOrDefault("PROXY_ADDR", ":80,:3000"), "Comma-separated proxy listen addresses (first is used for URL generation)")
+	proxyTLSAddr := flag.String("proxy-tls-addr", os.Getenv("PROXY_TLS_ADDR"), "Comma-separated HTTPS proxy listen addresses (e.g. :443)")
+	proxyTLSCert := flag.String("proxy-tls-cert", os.Getenv("PROXY_TLS_CERT_FILE"), "TLS certificate file for the proxy (e.g. a wildcard for the base domain)")
+	proxyTLSKey := flag.String("proxy-tls-key", os.Getenv("PROXY_TLS_KEY_FILE"), "TLS private key file for the proxy")
+	proxyACME := flag.Bool("proxy-acme", envOrDefault("PROXY_ACME", "") == "true", "Obtain proxy certificates automatically from an ACME CA (Let's Encrypt)")
+	acmeEmail := flag.String("acme-email", os.Getenv("ACME_EMAIL"), "Contact email for the ACME account")
+	acmeCache := flag.String("acme-cache", envOrDefault("ACME_CACHE_DIR", "acme-cache"), "Directory for cached ACME certificates")
+	acmeDirectory := flag.String("acme-directory", os.Getenv("ACME_DIRECTORY_URL"), "ACME directory URL (default: Let's Encrypt production)")
+	wsIdle := flag.String("proxy-ws-idle-timeout", envOrDefault("PROXY_WS_IDLE_TIMEOUT", "30m"), "Close proxied WebSockets after this long without traffic (0 = never)")
+	wsMax := flag.String("proxy-ws-max-duration", envOrDefault("PROXY_WS_MAX_DURATION", "24h"), "Close proxied WebSockets after this long (0 = never)")
+	cacheTTL := flag.String("proxy-cache-ttl", envOrDefault("PROXY_CACHE_TTL", "30s"), "How long the proxy caches sandbox routes (0 = no cache)")
+	pagesDir := flag.String("proxy-pages-dir", os.Getenv("PROXY_PAGES_DIR"), "Directory of HTML templates (loading.html, stopped.html, expired.html, not_found.html, unavailable.html) replacing the proxy's built-in pages")
+	requestTimeout := flag.String("request-timeout", envOrDefault("REQUEST_TIMEOUT", "30s"), "Deadline of API lookups and CRUD requests (0 = none)")
+	longRequestTimeout := flag.String("long-request-timeout", envOrDefault("LONG_REQUEST_TIMEOUT", "10m"), "Deadline of API requests that create, start, stop or snapshot sandboxes and pull images (0 = none)")
+	streamMaxLine := flag.String("stream-max-line-size", envOrDefault("STREAM_MAX_LINE_SIZE", "1048576"), "Bytes of a log line streamed as one event; longer lines are split")
+	streamWriteTimeout := flag.String("stream-write-timeout", envOrDefault("STREAM_WRITE_TIMEOUT", "30s"), "End a streaming response when the client takes longer than this to accept an event (0 = never)")
+	streamKeepAlive := flag.String("stream-keepalive", envOrDefault("STREAM_KEEPALIVE", "15s"), "Send a {\"type\":\"ping\"} line on streaming responses after this long without output (0 = never)")
 	baseDomain := flag.String("base-domain", envOrDefault("BASE_DOMAIN", "localhost"), "Base domain for subdomain routing")
+	databaseURL := flag.String("database-url", envOrDefault("DATABASE_URL", "sandbox.db"), "SQLite database file, or a sqlite://, postgres:// or mysql:// URL")
+	dbMaxOpen := flag.String("database-max-open-conns", envOrDefault("DATABASE_MAX_OPEN_CONNS", "10"), "Connections to PostgreSQL or MySQL open at once (0 = unlimited)")
+	dbMaxIdle := flag.String("database-max-idle-conns", envOrDefault("DATABASE_MAX_IDLE_CONNS", "5"), "Idle connections to PostgreSQL or MySQL kept for reuse")
+	dbMaxLifetime := flag.String("database-conn-max-lifetime", envOrDefault("DATABASE_CONN_MAX_LIFETIME", "30m"), "Replace PostgreSQL or MySQL connections older than this (0 = never)")
 	logFile := flag.String("log-file", envOrDefault("LOG_FILE", "opensbx.log"), "Path to log file")
+	logFormat := flag.String("log-format", envOrDefault("LOG_FORMAT", "json"), "Log format: json or text")
+	containerEngine := flag.String("container-engine", envOrDefault("CONTAINER_ENGINE", "docker"), "Container engine: docker, or podman through its Docker-compatible API")
+	dockerHost := flag.String("docker-host", os.Getenv("DOCKER_HOST"), "Docker daemon address (e.g. tcp://10.0.0.5:2376); default: the local daemon")
+	dockerContext := flag.String("docker-context", os.Getenv("DOCKER_CONTEXT"), "docker CLI context whose daemon to use when -docker-host is not set")
+	dockerCertPath := flag.String("docker-cert-path", os.Getenv("DOCKER_CERT_PATH"), "Directory with ca.pem, cert.pem and key.pem for a TLS Docker host")
+	sandboxNetwork := flag.String("sandbox-network", envOrDefault("SANDBOX_NETWORK", "opensbx-isolated"), "Isolated bridge network for sandboxes (\"none\" disables isolation)")
+	egressFirewall := flag.Bool("egress-firewall", envOrDefault("EGRESS_FIREWALL", "") == "true", "Install iptables rules blocking sandbox access to metadata and host endpoints (requires root)")
+	egressDeny := flag.String("egress-deny", envOrDefault("EGRESS_DENY", "169.254.169.254"), "Comma-separated destinations sandboxes may not reach (IP, CIDR, IP:port, :port)")
+	sandboxReadOnly := flag.Bool("sandbox-read-only", envOrDefault("SANDBOX_READ_ONLY", "") == "true", "Mount sandbox root filesystems read-only")
+	sandboxNoNewPrivs := flag.Bool("sandbox-no-new-privileges", envOrDefault("SANDBOX_NO_NEW_PRIVILEGES", "true") == "true", "Set no-new-privileges on sandboxes")
+	sandboxCapDrop := flag.String("sandbox-cap-drop", envOrDefault("SANDBOX_CAP_DROP", defaultCapDrop), "Comma-separated capabilities dropped from every sandbox (ALL drops all)")
+	sandboxSeccomp := flag.String("sandbox-seccomp-profile", os.Getenv("SANDBOX_SECCOMP_PROFILE"), "Seccomp profile JSON file applied to sandboxes (default: Docker's profile)")
+	sandboxPidsLimit := flag.String("sandbox-pids-limit", envOrDefault("SANDBOX_PIDS_LIMIT", "512"), "Maximum processes per sandbox (0 = unlimited)")
+	sandboxUser := flag.String("sandbox-user", os.Getenv("SANDBOX_USER"), "User sandboxes run as, e.g. 1000:1000 (default: the image's user)")
+	sandboxHostIP := flag.String("sandbox-host-ip", envOrDefault("SANDBOX_HOST_IP", "127.0.0.1"), "Host address sandbox ports are published on (0.0.0.0 exposes them on every interface)")
+	sandboxPortRange := flag.String("sandbox-port-range", os.Getenv("SANDBOX_PORT_RANGE"), "Host port range sandbox ports are published from, e.g. 30000-30999 (default: ephemeral ports)")
+	sandboxRuntime := flag.String("sandbox-runtime", os.Getenv("SANDBOX_RUNTIME"), "Default OCI runtime for sandboxes, e.g. runsc for gVisor (default: Docker's default runtime)")
+	allowedRuntimes := flag.String("allowed-runtimes", os.Getenv("ALLOWED_RUNTIMES"), "Comma-separated OCI runtimes sandboxes may use (default: any configured runtime)")
+	vmRuntimes := flag.String("vm-runtimes", envOrDefault("VM_RUNTIMES", "kata,kata-runtime,kata-qemu,kata-fc,kata-clh,io.containerd.kata.v2"), "Comma-separated OCI runtimes that boot a micro-VM per sandbox")
+	vmMemoryOverhead := flag.String("vm-memory-overhead", envOrDefault("VM_MEMORY_OVERHEAD", "128"), "Memory in MB each running micro-VM counts against quotas on top of its limit")
+	microVMOnly := flag.Bool("microvm-only", envOrDefault("MICROVM_ONLY", "") == "true", "Run every sandbox in a micro-VM (requires a VM runtime as SANDBOX_RUNTIME)")
+	codePoolSize := flag.String("code-pool-size", envOrDefault("CODE_POOL_SIZE", "2"), "Idle runner sandboxes POST /v1/run keeps per language once it has been used (0 = none)")
+	codeImages := flag.String("code-images", os.Getenv("CODE_IMAGES"), "Comma-separated language=image pairs replacing the images POST /v1/run uses, e.g. python=python:3.13-slim")
+	allowGPUs := flag.Bool("allow-gpus", envOrDefault("ALLOW_GPUS", "") == "true", "Let sandboxes request GPUs through resources.gpus")
+	allowedDevices := flag.String("allowed-devices", os.Getenv("ALLOWED_DEVICES"), "Comma-separated host device paths sandboxes may map (e.g. /dev/fuse)")
+	authzWebhook := flag.String("authz-webhook", os.Getenv("AUTHZ_WEBHOOK_URL"), "URL of an HTTP/OPA authorization hook consulted before mutating requests")
+	allowedOrigins := flag.String("allowed-origins", os.Getenv("ALLOWED_ORIGINS"), "Comma-separated browser origins (e.g. https://app.example.com) that may open terminal and tunnel WebSockets besides the API's own host")
+	reapGrace := flag.String("reap-grace", envOrDefault("REAP_GRACE_PERIOD", "10m"), "How long sandboxes with expiration_action=delete stay stopped before removal")
+	maxLifetime := flag.String("max-sandbox-lifetime", envOrDefault("MAX_SANDBOX_LIFETIME", "0"), "Longest a sandbox may exist before it is stopped for good, whatever its renewals (0 = unlimited)")
+	logRetention := flag.String("command-log-retention", envOrDefault("COMMAND_LOG_RETENTION", "168h"), "How long output of finished commands is kept (0 = until the sandbox is removed)")
+	imageGC := flag.String("image-gc-retention", os.Getenv("IMAGE_GC_RETENTION"), "Remove images opensbx pulled, imported or cloned that no sandbox has used for this long (0 = never)")
+	maxSandboxes := flag.String("max-sandboxes", os.Getenv("MAX_SANDBOXES"), "Maximum running sandboxes across all callers (0 = unlimited)")
+	maxTotalMemory := flag.String("max-total-memory", os.Getenv("MAX_TOTAL_MEMORY"), "Maximum memory in MB across running sandboxes (0 = unlimited)")
+	maxTotalCPUs := flag.String("max-total-cpus", os.Getenv("MAX_TOTAL_CPUS"), "Maximum CPUs across running sandboxes (0 = unlimited)")
+	tlsCert := flag.String("tls-cert", os.Getenv("TLS_CERT_FILE"), "TLS certificate file for the API listener")
+	tlsKey := flag.String("tls-key", os.Getenv("TLS_KEY_FILE"), "TLS private key file for the API listener")
+	tlsMinVersion := flag.String("tls-min-version", envOrDefault("TLS_MIN_VERSION", "1.2"), "Minimum TLS version for the API listener (1.2 or 1.3)")
+	tlsClientCA := flag.String("tls-client-ca", os.Getenv("TLS_CLIENT_CA_FILE"), "CA bundle that client certificates must chain to (enables mTLS)")
+	shutdownPolicy := flag.String("shutdown-policy", envOrDefault("SHUTDOWN_POLICY", ShutdownStop), "What shutdown does with running sandboxes: stop, or detach to leave them running for the next start")
+	flag.String("env-file", envFile, "File of KEY=VALUE settings read at startup and again on SIGHUP")
 	flag.Parse()
 
+	flagsSet := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) { flagsSet[f.Name] = true })
+
 	normalizedBaseDomain := normalizeBaseDomain(*baseDomain)
 
 	return &Config{
 		Addr:                          *addr,
 		APIKey:                        os.Getenv("API_KEY"),
+		SigningSecret:                 os.Getenv("SIGNING_SECRET"),
+		SecretsKey:                    os.Getenv("SECRETS_KEY"),
 		ProxyAddrs:                    parseAddrs(*proxyAddr),
+		ProxyTLSAddrs:                 parseAddrs(*proxyTLSAddr),
+		ProxyTLSCertFile:              strings.TrimSpace(*proxyTLSCert),
+		ProxyTLSKeyFile:               strings.TrimSpace(*proxyTLSKey),
+		ProxyACME:                     *proxyACME,
+		ACMEEmail:                     strings.TrimSpace(*acmeEmail),
+		ACMECacheDir:                  strings.TrimSpace(*acmeCache),
+		ACMEDirectoryURL:              strings.TrimSpace(*acmeDirectory),
+		ProxyWSIdleTimeout:            parseDuration(*wsIdle, defaultWSIdleTimeout),
+		ProxyWSMaxDuration:            parseDuration(*wsMax, defaultWSMaxDuration),
+		ProxyCacheTTL:                 parseDuration(*cacheTTL, defaultProxyCacheTTL),
+		ProxyPagesDir:                 strings.TrimSpace(*pagesDir),
+		RequestTimeout:                parseDuration(*requestTimeout, defaultRequestTimeout),
+		LongRequestTimeout:            parseDuration(*longRequestTimeout, defaultLongRequestTimeout),
+		StreamMaxLineSize:             int(parseLimit(*streamMaxLine)),
+		StreamWriteTimeout:            parseDuration(*streamWriteTimeout, defaultStreamWriteTimeout),
+		StreamKeepAlive:               parseDuration(*streamKeepAlive, defaultStreamKeepAlive),
 		BaseDomain:                    normalizedBaseDomain,
+		DatabaseURL:                   strings.TrimSpace(*databaseURL),
+		DatabaseMaxOpenConns:          int(parseLimit(*dbMaxOpen)),
+		DatabaseMaxIdleConns:          int(parseLimit(*dbMaxIdle)),
+		DatabaseConnMaxLifetime:       parseDuration(*dbMaxLifetime, defaultDatabaseConnMaxLifetime),
 		LogFile:                       normalizeLogFile(*logFile),
+		LogFormat:                     strings.ToLower(strings.TrimSpace(*logFormat)),
 		MCPDisableLocalhostProtection: !isLocalBaseDomain(normalizedBaseDomain),
+		ContainerEngine:               strings.ToLower(strings.TrimSpace(*containerEngine)),
+		DockerHost:                    strings.TrimSpace(*dockerHost),
+		DockerContext:                 strings.TrimSpace(*dockerContext),
+		DockerCertPath:                strings.TrimSpace(*dockerCertPath),
+		SandboxNetwork:                normalizeSandboxNetwork(*sandboxNetwork),
+		EgressFirewall:                *egressFirewall,
+		EgressDeny:                    strings.TrimSpace(*egressDeny),
+		AllowedDevices:                parseAddrs(*allowedDevices),
+		SandboxHostIP:                 strings.TrimSpace(*sandboxHostIP),
+		SandboxPortRange:              strings.TrimSpace(*sandboxPortRange),
+		SandboxRuntime:                strings.TrimSpace(*sandboxRuntime),
+		AllowedRuntimes:               parseAddrs(*allowedRuntimes),
+		VMRuntimes:                    parseAddrs(*vmRuntimes),
+		VMMemoryOverhead:              int64(parseLimit(*vmMemoryOverhead)),
+		MicroVMOnly:                   *microVMOnly,
+		CodePoolSize:                  int(parseLimit(*codePoolSize)),
+		CodeImages:                    parsePairs(*codeImages),
+		AllowGPUs:                     *allowGPUs,
+		SandboxReadOnly:               *sandboxReadOnly,
+		SandboxNoNewPrivileges:        *sandboxNoNewPrivs,
+		SandboxCapDrop:                parseAddrs(*sandboxCapDrop),
+		SandboxSeccompProfile:         strings.TrimSpace(*sandboxSeccomp),
+		SandboxPidsLimit:              int64(parseLimit(*sandboxPidsLimit)),
+		SandboxUser:                   strings.TrimSpace(*sandboxUser),
+		AuthzWebhookURL:               strings.TrimSpace(*authzWebhook),
+		AllowedOrigins:                parseAddrs(*allowedOrigins),
+		ReapGracePeriod:               parseDuration(*reapGrace, defaultReapGracePeriod),
+		MaxSandboxLifetime:            parseDuration(*maxLifetime, 0),
+		CommandLogRetention:           parseDuration(*logRetention, defaultCommandLogRetention),
+		ImageGCRetention:              parseDuration(*imageGC, 0),
+		MaxSandboxes:                  int(parseLimit(*maxSandboxes)),
+		MaxTotalMemory:                int64(parseLimit(*maxTotalMemory)),
+		MaxTotalCPUs:                  parseLimit(*maxTotalCPUs),
+		TLSCertFile:                   strings.TrimSpace(*tlsCert),
+		TLSKeyFile:                    strings.TrimSpace(*tlsKey),
+		TLSMinVersion:                 strings.TrimSpace(*tlsMinVersion),
+		TLSClientCAFile:               strings.TrimSpace(*tlsClientCA),
+		ShutdownPolicy:                strings.ToLower(strings.TrimSpace(*shutdownPolicy)),
+		EnvFile:                       envFile,
+		flagsSet:                      flagsSet,
 	}
 }
 
@@ -57,6 +272,62 @@ func parseAddrs(raw string) []string {
 	return addrs
 }
 
+// parsePairs parses a comma-separated list of key=value pairs. Entries
+// without "=" or with an empty key or value are skipped.
+func parsePairs(raw string) map[string]string {
+	pairs := map[string]string{}
+	for _, entry := range parseAddrs(raw) {
+		k, v, ok := strings.Cut(entry, "=")
+		k, v = strings.TrimSpace(k), strings.TrimSpace(v)
+		if ok && k != "" && v != "" {
+			pairs[k] = v
+		}
+	}
+	return pairs
+}
+
+// Shutdown policies.
+const (
+	ShutdownStop   = "stop"   // stop tracked sandboxes
+	ShutdownDetach = "detach" // leave sandboxes running and resume their timers on startup
+)
+
+// defaultCapDrop lists capabilities sandboxes rarely need: raw sockets,
+// device nodes and audit log writes.
+const defaultCapDrop = "NET_RAW,MKNOD,AUDIT_WRITE"
+
+// Defaults applied when a duration flag is missing or invalid.
+const (
+	defaultReapGracePeriod         = 10 * time.Minute
+	defaultCommandLogRetention     = 7 * 24 * time.Hour
+	defaultDatabaseConnMaxLifetime = 30 * time.Minute
+	defaultWSIdleTimeout           = 30 * time.Minute
+	defaultWSMaxDuration           = 24 * time.Hour
+	defaultProxyCacheTTL           = 30 * time.Second
+	defaultRequestTimeout          = 30 * time.Second
+	defaultLongRequestTimeout      = 10 * time.Minute
+	defaultStreamWriteTimeout      = 30 * time.Second
+	defaultStreamKeepAlive         = 15 * time.Second
+)
+
+// parseDuration parses a Go duration (e.g. "10m"), falling back on invalid or negative input.
+func parseDuration(raw string, fallback time.Duration) time.Duration {
+	d, err := time.ParseDuration(strings.TrimSpace(raw))
+	if err != nil || d < 0 {
+		return fallback
+	}
+	return d
+}
+
+// parseLimit parses a quota limit. Empty, invalid or negative input means unlimited (0).
+func parseLimit(raw string) float64 {
+	v, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+	if err != nil || v < 0 {
+		return 0
+	}
+	return v
+}
+
 func envOrDefault(key, fallback string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
@@ -80,6 +351,15 @@ func normalizeLogFile(raw string) string {
 	return v
 }
 
+// normalizeSandboxNetwork trims the network name; "none" disables isolation.
+func normalizeSandboxNetwork(raw string) string {
+	v := strings.TrimSpace(raw)
+	if v == "none" {
+		return ""
+	}
+	return v
+}
+
 func isLocalBaseDomain(raw string) bool {
 	host := strings.Trim(strings.TrimSpace(raw), "[]")
 	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
@@ -1,6 +1,13 @@
 package config
 
-import "testing"
+import (
+	"crypto/tls"
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
 
 func TestNormalizeBaseDomain(t *testing.T) {
 	tests := []struct {
@@ -66,3 +73,219 @@ func TestNormalizeLogFile(t *testing.T) {
 		})
 	}
 }
+
+func TestParseTLSVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    uint16
+		wantErr bool
+	}{
+		{name: "empty defaults to 1.2", in: "", want: tls.VersionTLS12},
+		{name: "1.2", in: "1.2", want: tls.VersionTLS12},
+		{name: "1.3", in: " 1.3 ", want: tls.VersionTLS13},
+		{name: "unsupported", in: "1.0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTLSVersion(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseTLSVersion(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("parseTLSVersion(%q) = %d, want %d", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServerTLSConfigInvalidClientCA(t *testing.T) {
+	dir := t.TempDir()
+	caPath := filepath.Join(dir, "ca.pem")
+	if err := os.WriteFile(caPath, []byte("not a pem"), 0o600); err != nil {
+		t.Fatalf("write ca: %v", err)
+	}
+
+	cfg := &Config{TLSClientCAFile: caPath}
+	if _, err := cfg.ServerTLSConfig(); err == nil {
+		t.Fatalf("ServerTLSConfig() should fail for invalid client ca")
+	}
+}
+
+func TestProxyTLSConfig(t *testing.T) {
+	cfg := &Config{ProxyTLSAddrs: []string{":443"}}
+	if !cfg.ProxyTLSEnabled() {
+		t.Fatalf("ProxyTLSEnabled() = false with a TLS address")
+	}
+	if _, err := cfg.ProxyTLSConfig(); err == nil {
+		t.Fatalf("ProxyTLSConfig() should fail without a certificate")
+	}
+
+	dir := t.TempDir()
+	cfg.ProxyTLSCertFile = filepath.Join(dir, "cert.pem")
+	cfg.ProxyTLSKeyFile = filepath.Join(dir, "key.pem")
+	if _, err := cfg.ProxyTLSConfig(); err == nil {
+		t.Fatalf("ProxyTLSConfig() should fail for missing files")
+	}
+}
+
+func TestNormalizeSandboxNetwork(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "opensbx-isolated", want: "opensbx-isolated"},
+		{in: "  custom  ", want: "custom"},
+		{in: "none", want: ""},
+		{in: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeSandboxNetwork(tt.in); got != tt.want {
+			t.Fatalf("normalizeSandboxNetwork(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+	}{
+		{in: "30s", want: 30 * time.Second},
+		{in: " 2h ", want: 2 * time.Hour},
+		{in: "0", want: 0},
+		{in: "-1m", want: time.Minute},
+		{in: "soon", want: time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := parseDuration(tt.in, time.Minute); got != tt.want {
+			t.Fatalf("parseDuration(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+	}{
+		{in: "", want: 0},
+		{in: " 10 ", want: 10},
+		{in: "1.5", want: 1.5},
+		{in: "-2", want: 0},
+		{in: "lots", want: 0},
+	}
+
+	for _, tt := range tests {
+		if got := parseLimit(tt.in); got != tt.want {
+			t.Fatalf("parseLimit(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParsePairs(t *testing.T) {
+	got := parsePairs(" python = python:3.13-slim ,node=,=x,go,ruby=ruby:3.4")
+	want := map[string]string{"python": "python:3.13-slim", "ruby": "ruby:3.4"}
+	if !maps.Equal(got, want) {
+		t.Fatalf("parsePairs() = %v, want %v", got, want)
+	}
+}
+
+func TestEgressDenyList(t *testing.T) {
+	tests := []struct {
+		addr string
+		deny string
+		want string
+	}{
+		{addr: ":8080", deny: "169.254.169.254", want: "169.254.169.254,:8080"},
+		{addr: "127.0.0.1:9000", deny: "", want: ":9000"},
+		{addr: "bad", deny: "10.0.0.1", want: "10.0.0.1"},
+	}
+
+	for _, tt := range tests {
+		cfg := &Config{Addr: tt.addr, EgressDeny: tt.deny}
+		if got := cfg.EgressDenyList(); got != tt.want {
+			t.Fatalf("EgressDenyList(%q, %q) = %q, want %q", tt.addr, tt.deny, got, tt.want)
+		}
+	}
+}
+
+func TestEnvFilePath(t *testing.T) {
+	t.Setenv("ENV_FILE", "/etc/opensbx/env")
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{nil, "/etc/opensbx/env"},
+		{[]string{"-env-file", "a.env"}, "a.env"},
+		{[]string{"--env-file=b.env", "-addr", ":8080"}, "b.env"},
+		{[]string{"--", "-env-file", "c.env"}, "/etc/opensbx/env"},
+	}
+	for _, tt := range tests {
+		if got := envFilePath(tt.args); got != tt.want {
+			t.Fatalf("envFilePath(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestApplyEnvFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "opensbx.env")
+	content := "# comment\n\nOPENSBX_TEST_A=1\nexport OPENSBX_TEST_B = \"two words\"\nOPENSBX_TEST_C='x=y'\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("OPENSBX_TEST_A", "old")
+	t.Setenv("OPENSBX_TEST_B", "")
+	t.Setenv("OPENSBX_TEST_C", "")
+
+	if err := applyEnvFile(path); err != nil {
+		t.Fatalf("applyEnvFile() error: %v", err)
+	}
+	for key, want := range map[string]string{"OPENSBX_TEST_A": "1", "OPENSBX_TEST_B": "two words", "OPENSBX_TEST_C": "x=y"} {
+		if got := os.Getenv(key); got != want {
+			t.Fatalf("%s = %q, want %q", key, got, want)
+		}
+	}
+
+	if err := os.WriteFile(path, []byte("NOT A SETTING\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyEnvFile(path); err == nil {
+		t.Fatal("expected error for a line without =")
+	}
+}
+
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "opensbx.env")
+	t.Setenv("API_KEY", "old-key")
+	t.Setenv("BASE_DOMAIN", "")
+	t.Setenv("MAX_SANDBOXES", "")
+	t.Setenv("MAX_TOTAL_CPUS", "")
+	t.Setenv("SIGNING_SECRET", "")
+
+	if _, err := (&Config{}).Reload(); err == nil {
+		t.Fatal("expected error without an env file")
+	}
+
+	content := "API_KEY=new-key\nBASE_DOMAIN=sbx.example.com\nMAX_SANDBOXES=5\nMAX_TOTAL_CPUS=8\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &Config{EnvFile: path, Addr: ":8080", APIKey: "old-key", BaseDomain: "localhost", MaxTotalCPUs: 2, flagsSet: map[string]bool{"max-total-cpus": true}}
+	next, err := cfg.Reload()
+	if err != nil {
+		t.Fatalf("Reload() error: %v", err)
+	}
+	if next.APIKey != "new-key" || next.BaseDomain != "sbx.example.com" || next.MaxSandboxes != 5 {
+		t.Fatalf("Reload() = %+v, want the env file settings", next)
+	}
+	if next.MaxTotalCPUs != 2 {
+		t.Fatalf("MaxTotalCPUs = %v, want the flag value 2", next.MaxTotalCPUs)
+	}
+	if next.Addr != ":8080" || cfg.APIKey != "old-key" {
+		t.Fatal("Reload() must only change reloadable settings of a copy")
+	}
+}
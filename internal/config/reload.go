@@ -0,0 +1,96 @@
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Reload re-reads the env file and returns a copy of c with the settings that
+// can change while the server runs updated: API_KEY, SIGNING_SECRET,
+// BASE_DOMAIN, MAX_SANDBOXES, MAX_TOTAL_MEMORY and MAX_TOTAL_CPUS. Settings
+// given as flags keep their flag value; everything else needs a restart.
+func (c *Config) Reload() (*Config, error) {
+	if c.EnvFile == "" {
+		return nil, errors.New("no env file to reload (set -env-file or ENV_FILE)")
+	}
+	if err := applyEnvFile(c.EnvFile); err != nil {
+		return nil, err
+	}
+
+	next := *c
+	next.APIKey = os.Getenv("API_KEY")
+	next.SigningSecret = os.Getenv("SIGNING_SECRET")
+	if !c.flagsSet["base-domain"] {
+		next.BaseDomain = normalizeBaseDomain(envOrDefault("BASE_DOMAIN", "localhost"))
+	}
+	if !c.flagsSet["max-sandboxes"] {
+		next.MaxSandboxes = int(parseLimit(os.Getenv("MAX_SANDBOXES")))
+	}
+	if !c.flagsSet["max-total-memory"] {
+		next.MaxTotalMemory = int64(parseLimit(os.Getenv("MAX_TOTAL_MEMORY")))
+	}
+	if !c.flagsSet["max-total-cpus"] {
+		next.MaxTotalCPUs = parseLimit(os.Getenv("MAX_TOTAL_CPUS"))
+	}
+	return &next, nil
+}
+
+// envFilePath returns the env file named by the -env-file flag in args, or
+// else by ENV_FILE. It is looked up before flag parsing because the file
+// changes the defaults of the other flags.
+func envFilePath(args []string) string {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || name != "env-file" {
+			continue
+		}
+		if hasValue {
+			return strings.TrimSpace(value)
+		}
+		if i+1 < len(args) {
+			return strings.TrimSpace(args[i+1])
+		}
+	}
+	return strings.TrimSpace(os.Getenv("ENV_FILE"))
+}
+
+// applyEnvFile sets the environment variables listed in an env file: one
+// KEY=VALUE per line, optionally quoted or prefixed with "export". Blank lines
+// and lines starting with # are skipped. An empty value counts as unset.
+func applyEnvFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("env file: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			return fmt.Errorf("env file %s:%d: expected KEY=VALUE", path, n)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return fmt.Errorf("env file %s:%d: %w", path, n, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("env file: %w", err)
+	}
+	return nil
+}
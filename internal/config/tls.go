@@ -0,0 +1,71 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// TLSEnabled reports whether the API listener should serve HTTPS.
+func (c *Config) TLSEnabled() bool {
+	return c.TLSCertFile != "" && c.TLSKeyFile != ""
+}
+
+// ServerTLSConfig builds the tls.Config for the API listener.
+// When TLSClientCAFile is set, clients must present a certificate signed by that CA (mTLS).
+func (c *Config) ServerTLSConfig() (*tls.Config, error) {
+	minVersion, err := parseTLSVersion(c.TLSMinVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &tls.Config{MinVersion: minVersion}
+
+	if c.TLSClientCAFile != "" {
+		pem, err := os.ReadFile(c.TLSClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read client ca: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("client ca %s: no certificates found", c.TLSClientCAFile)
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return cfg, nil
+}
+
+// parseTLSVersion converts "1.2" / "1.3" into the crypto/tls constant. Empty defaults to TLS 1.2.
+func parseTLSVersion(raw string) (uint16, error) {
+	switch strings.TrimSpace(raw) {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported tls min version %q (use 1.2 or 1.3)", raw)
+	}
+}
+
+// ProxyTLSEnabled reports whether the reverse proxy should serve HTTPS.
+func (c *Config) ProxyTLSEnabled() bool {
+	return len(c.ProxyTLSAddrs) > 0
+}
+
+// ProxyTLSConfig builds the tls.Config for the proxy's HTTPS listeners from the
+// static certificate (typically a wildcard for *.BaseDomain). With ACME the
+// certificates come from the autocert manager instead.
+func (c *Config) ProxyTLSConfig() (*tls.Config, error) {
+	if c.ProxyTLSCertFile == "" || c.ProxyTLSKeyFile == "" {
+		return nil, fmt.Errorf("proxy tls: set -proxy-tls-cert and -proxy-tls-key, or enable -proxy-acme")
+	}
+	cert, err := tls.LoadX509KeyPair(c.ProxyTLSCertFile, c.ProxyTLSKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("proxy tls: %w", err)
+	}
+	return &tls.Config{MinVersion: tls.VersionTLS12, Certificates: []tls.Certificate{cert}}, nil
+}
@@ -0,0 +1,81 @@
+package database
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestSQLiteDSN(t *testing.T) {
+	cases := map[string]string{
+		":memory:":                 ":memory:?_pragma=busy_timeout(5000)",
+		"sandbox.db":               "sandbox.db?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)",
+		"sqlite:///var/lib/sbx.db": "/var/lib/sbx.db?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)",
+		"sbx.db?_pragma=foo(1)":    "sbx.db?_pragma=foo(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)",
+	}
+	for in, want := range cases {
+		if got := sqliteDSN(in); got != want {
+			t.Fatalf("sqliteDSN(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestNewFileDatabase(t *testing.T) {
+	db := New(filepath.Join(t.TempDir(), "sandbox.db"))
+
+	var mode string
+	if err := db.Raw("PRAGMA journal_mode").Scan(&mode).Error; err != nil {
+		t.Fatalf("journal_mode error: %v", err)
+	}
+	if mode != "wal" {
+		t.Fatalf("journal_mode = %q, want wal", mode)
+	}
+
+	// Concurrent writers must not fail with "database is locked".
+	repo := NewRepository(db)
+	var wg sync.WaitGroup
+	errs := make(chan error, 50)
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- repo.Save(Sandbox{ID: fmt.Sprintf("sb-%d", i), Name: fmt.Sprintf("sb-%d", i)})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Save() error: %v", err)
+		}
+	}
+}
+
+func TestOpenDialector(t *testing.T) {
+	cases := map[string]string{
+		"sandbox.db":                              "sqlite",
+		"sqlite:///var/lib/sbx.db":                "sqlite",
+		"postgres://sbx:pw@db:5432/opensbx":       "postgres",
+		"postgresql://sbx:pw@db/opensbx":          "postgres",
+		"mysql://sbx:pw@tcp(db:3306)/opensbx":     "mysql",
+		"sqlserver://sbx:pw@db:1433?database=sbx": "",
+	}
+	for dsn, want := range cases {
+		d, _ := openDialector(dsn)
+		got := ""
+		if d != nil {
+			got = d.Name()
+		}
+		if got != want {
+			t.Fatalf("openDialector(%q) = %q, want %q", dsn, got, want)
+		}
+	}
+
+	if got := mysqlDSN("sbx:pw@tcp(db:3306)/opensbx"); got != "sbx:pw@tcp(db:3306)/opensbx?parseTime=true" {
+		t.Fatalf("mysqlDSN() = %q", got)
+	}
+	if got := mysqlDSN("sbx@tcp(db)/opensbx?parseTime=false"); got != "sbx@tcp(db)/opensbx?parseTime=false" {
+		t.Fatalf("mysqlDSN() overrode parseTime: %q", got)
+	}
+}
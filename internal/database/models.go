@@ -6,7 +6,7 @@ import (
 	"fmt"
 )
 
-// JSONMap is a map[string]string that serializes to/from JSON in SQLite.
+// JSONMap is a map[string]string stored as a JSON column.
 type JSONMap map[string]string
 
 func (j JSONMap) Value() (driver.Value, error) {
@@ -34,6 +34,14 @@ func (j *JSONMap) Scan(src any) error {
 	return json.Unmarshal(bytes, j)
 }
 
+// Sandbox states recorded in Sandbox.State, so the proxy can tell why a
+// sandbox does not answer without asking Docker.
+const (
+	SandboxRunning = "running"
+	SandboxStopped = "stopped" // stopped through the API
+	SandboxExpired = "expired" // stopped by its timeout
+)
+
 // Sandbox persists the container ID, metadata, and its assigned host ports.
 type Sandbox struct {
 	ID    string `gorm:"primaryKey"` // Docker container ID
@@ -41,6 +49,150 @@ type Sandbox struct {
 	Image string
 	Ports JSONMap `gorm:"type:json"` // e.g. {"3000/tcp": "32768"}
 	Port  string  // container port exposed, e.g. "3000/tcp"
+
+	SpecHash         string  `gorm:"index"` // hash of the POST /v1/apply spec; empty = not managed by apply
+	ExpirationAction string  `gorm:"index"` // "stop" or "delete"; empty = stop
+	TimeoutMode      string  // "absolute" or "idle"; empty = absolute
+	OwnerID          string  `gorm:"index"` // owner of the API key that created it; empty = unowned
+	Memory           int64   // memory limit in MB, for quota accounting
+	CPUs             float64 // CPU limit, for quota accounting
+	Runtime          string  // OCI runtime; empty = the daemon default
+
+	Network     string // "bridge", "internal" or "none"; empty = bridge
+	EgressAllow string // comma-separated egress allow rules, re-applied on every start
+	EgressDeny  string // comma-separated egress deny rules
+	IngressKbps int    // download limit in kbit/s, 0 = unlimited
+	EgressKbps  int    // upload limit in kbit/s, 0 = unlimited
+
+	Labels    JSONMap `gorm:"type:json"` // caller-defined labels, also set on the container
+	Env       JSONMap `gorm:"type:json"` // variables set after creation, passed to every exec and terminal
+	CreatedAt int64   `gorm:"index"`     // unix milliseconds; 0 for sandboxes created before it was recorded
+
+	MaxLifetime int // seconds after CreatedAt when the sandbox is stopped for good, whatever its renewals; 0 = unlimited
+
+	ReadyCheck string // JSON-encoded models.ReadyCheck; empty = none
+	Hooks      string // JSON-encoded models.Hooks; empty = none
+	Ready      string `gorm:"index"` // ready check state since the last start: "starting", "ready" or "timeout"
+	State      string // last state set by the server, one of the Sandbox* constants; empty = running
+
+	WakeOnRequest bool // started by the proxy when it gets a request while the sandbox is stopped
+
+	// ExpiresAt is when the auto-stop timer of a sandbox left running by a
+	// detaching shutdown fires, in unix milliseconds. 0 = no pending timer.
+	ExpiresAt int64 `gorm:"index"`
+
+	Stack        string `gorm:"index"` // stack the sandbox is a service of; empty = standalone
+	NetworkGroup string `gorm:"index"` // shared network the sandbox joined; empty = none
+}
+
+// LabelSelector matches sandbox labels. Every condition must hold.
+type LabelSelector struct {
+	Equals map[string]string // key has exactly this value
+	Keys   []string          // key is set, with any value
+}
+
+// SandboxFilter selects, orders and pages sandboxes. Zero values match everything.
+type SandboxFilter struct {
+	Owner      string
+	Image      string
+	NamePrefix string
+	Labels     LabelSelector
+	IDs        []string // when non-nil, only these IDs
+	ExcludeIDs []string
+	Sort       string // "name", "image" or "created"; empty = created
+	Desc       bool
+	Limit      int // 0 = no limit
+	Offset     int
+}
+
+// CommandFilter selects and pages the commands of one sandbox.
+type CommandFilter struct {
+	SandboxID string
+	Desc      bool // newest first
+	Limit     int  // 0 = no limit
+	Offset    int
+}
+
+// APIKey persists an API key. Only the SHA-256 of the secret is stored.
+type APIKey struct {
+	ID     string `gorm:"primaryKey"` // key_<hex>
+	Name   string
+	Owner  string `gorm:"index"` // tenant whose sandboxes the key can access
+	Prefix string // first characters of the secret, for display
+	Hash   string `gorm:"uniqueIndex"` // hex SHA-256 of the secret
+	Scopes string // comma-separated scopes
+
+	MaxSandboxes int     // quota for the owner's running sandboxes, 0 = unlimited
+	MaxMemory    int64   // quota for the owner's total memory in MB, 0 = unlimited
+	MaxCPUs      float64 // quota for the owner's total CPUs, 0 = unlimited
+
+	CreatedAt int64  // unix milliseconds
+	RevokedAt *int64 // unix milliseconds, nil while active
+}
+
+// Domain maps an external hostname to a sandbox for the reverse proxy.
+type Domain struct {
+	Host      string `gorm:"primaryKey"` // lowercase hostname without port, e.g. "app.example.com"
+	SandboxID string `gorm:"index"`      // container ID
+	CreatedAt int64  // unix milliseconds
+}
+
+// ImageUse records when a local image was last seen in use by a container,
+// for image garbage collection.
+type ImageUse struct {
+	ImageID  string `gorm:"primaryKey"` // sha256:<hex>
+	LastUsed int64  // unix milliseconds
+	Managed  bool   // pulled, imported or cloned by opensbx; only these are collected
+}
+
+// Stack persists a group of sandboxes created and managed as a unit. Its
+// services are the sandboxes whose Stack field holds its name.
+type Stack struct {
+	Name      string `gorm:"primaryKey"`
+	OwnerID   string `gorm:"index"` // owner of the API key that created it; empty = unowned
+	Web       string // service the proxy routes the stack name to; empty = none
+	CreatedAt int64  // unix milliseconds
+}
+
+// NetworkGroup persists a shared network that sandboxes join by name.
+type NetworkGroup struct {
+	Name      string `gorm:"primaryKey"`
+	OwnerID   string `gorm:"index"` // owner of the API key that created it; empty = unowned
+	CreatedAt int64  // unix milliseconds
+}
+
+// Secret persists a secret value, encrypted by the secrets store.
+type Secret struct {
+	Owner     string `gorm:"primaryKey"` // owner of the API key that stored it; empty = unowned
+	Name      string `gorm:"primaryKey"` // also the environment variable it is injected as
+	Value     []byte // AES-GCM nonce followed by the ciphertext
+	CreatedAt int64  // unix milliseconds
+	UpdatedAt int64  `gorm:"autoUpdateTime:milli"` // unix milliseconds
+}
+
+// AuditEvent persists one mutating API operation.
+type AuditEvent struct {
+	ID        uint   `gorm:"primaryKey"`
+	Time      int64  `gorm:"index"` // unix milliseconds
+	Action    string `gorm:"index"` // e.g. sandbox.create, command.exec
+	SandboxID string `gorm:"index"` // full container ID of the sandbox; empty for non-sandbox operations
+	Auth      string // "api_key", "signature" or "none"
+	KeyID     string // stored API key used
+	Owner     string // owner the caller was restricted to
+	Actor     string // X-Opensbx-Actor header
+	Method    string
+	Path      string
+	Status    int    // HTTP response status
+	RequestID string // X-Request-ID of the request
+}
+
+// AuditFilter selects audit events. Zero values match everything.
+type AuditFilter struct {
+	SandboxID string
+	Action    string
+	Since     int64 // unix milliseconds, inclusive
+	Until     int64 // unix milliseconds, exclusive
+	Limit     int
 }
 
 // Command persists an executed command's metadata and result.
@@ -51,6 +203,79 @@ type Command struct {
 	Args       string `gorm:"type:json"` // JSON-encoded []string
 	Cwd        string // working directory
 	ExitCode   *int   // nil while running
+	TimedOut   bool   // killed after exceeding its timeout
 	StartedAt  int64  // unix milliseconds
 	FinishedAt *int64 // unix milliseconds
 }
+
+// Process persists the definition of a supervised long-running process so it
+// can be started again when its sandbox starts.
+type Process struct {
+	SandboxID string  `gorm:"primaryKey"` // container ID
+	Name      string  `gorm:"primaryKey"` // unique per sandbox
+	Command   string  // executable name
+	Args      string  `gorm:"type:json"` // JSON-encoded []string
+	Cwd       string  // working directory
+	Env       JSONMap `gorm:"type:json"` // extra environment variables
+	User      string  // exec user, empty = container default
+	Restart   string  // always, on-failure or never
+	CreatedAt int64   // unix milliseconds
+}
+
+// CommandLog persists a finished command's captured output so it can be
+// replayed after the in-memory buffers are gone.
+type CommandLog struct {
+	CommandID  string `gorm:"primaryKey"` // cmd_<hex>
+	SandboxID  string `gorm:"index"`      // container ID
+	Stdout     string // last captured stdout (up to the ring buffer size)
+	Stderr     string // last captured stderr (up to the ring buffer size)
+	FinishedAt int64  `gorm:"index"` // unix milliseconds, used for retention
+}
+
+// IdempotencyKey records a create sent with an Idempotency-Key header, so a
+// retry with the same key returns the sandbox it created instead of another.
+type IdempotencyKey struct {
+	Owner       string `gorm:"primaryKey"` // owner of the API key that sent it; empty = unowned
+	Key         string `gorm:"primaryKey"`
+	RequestHash string // hex SHA-256 of the create request, to reject reuse with another request
+	Response    string // JSON-encoded create response; empty while the create runs
+	CreatedAt   int64  `gorm:"index"` // unix milliseconds
+}
+
+// SandboxEvent records something that happened to a sandbox's container
+// outside the API, seen on the Docker event stream: an OOM kill, an exit
+// nobody asked for, or a restart.
+type SandboxEvent struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement"`
+	SandboxID string `gorm:"index"` // container ID
+	Type      string // "oom", "exit", "restart" or "hook_failed"
+	Hook      string // hook of "hook_failed" events: "on_start" or "before_stop"
+	ExitCode  *int   // exit code of "exit" and "hook_failed" events
+	CreatedAt int64  // unix milliseconds
+}
+
+// Schedule persists a recurring job: on every match of Cron a sandbox is
+// created from Spec, runs Command and is removed again.
+type Schedule struct {
+	Name      string `gorm:"primaryKey"`
+	OwnerID   string `gorm:"index"` // owner of the API key that created it; empty = unowned
+	Cron      string // five-field cron expression, evaluated in UTC
+	Spec      string // JSON-encoded models.CreateSandboxRequest
+	Command   string // JSON-encoded models.ExecCommandRequest
+	NextRunAt int64  `gorm:"index"` // unix milliseconds
+	CreatedAt int64  // unix milliseconds
+}
+
+// ScheduleRun records one run of a schedule.
+type ScheduleRun struct {
+	ID         uint   `gorm:"primaryKey;autoIncrement"`
+	Schedule   string `gorm:"index"` // schedule name
+	SandboxID  string // container ID of the sandbox the run created; empty if it failed before
+	Status     string // "running", "succeeded", "failed" or "error"
+	ExitCode   *int   // exit code of the command; nil until it exits
+	Error      string // why the run could not complete, for status "error"
+	Stdout     string // tail of the command's stdout
+	Stderr     string // tail of the command's stderr
+	StartedAt  int64  // unix milliseconds
+	FinishedAt int64  // unix milliseconds; 0 while running
+}
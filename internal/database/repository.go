@@ -1,7 +1,11 @@
 package database
 
 import (
+	"context"
+	"strings"
+
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // Repository provides CRUD operations for persisted sandboxes.
@@ -14,6 +18,12 @@ func NewRepository(db *gorm.DB) *Repository {
 	return &Repository{db: db}
 }
 
+// Ping checks that the database answers queries.
+func (r *Repository) Ping(ctx context.Context) error {
+	var one int
+	return r.db.WithContext(ctx).Raw("SELECT 1").Scan(&one).Error
+}
+
 // Save creates or updates a sandbox record.
 func (r *Repository) Save(s Sandbox) error {
 	return r.db.Save(&s).Error
@@ -40,11 +50,141 @@ func (r *Repository) FindAll() ([]Sandbox, error) {
 	return sandboxes, nil
 }
 
+// sandboxSortColumns maps SandboxFilter.Sort to a column.
+var sandboxSortColumns = map[string]string{
+	"":        "created_at",
+	"created": "created_at",
+	"name":    "name",
+	"image":   "image",
+}
+
+// likeEscaper escapes LIKE wildcards so prefixes match literally. The escape
+// character is "!" because a backslash in a string literal means different
+// things to SQLite, PostgreSQL and MySQL.
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// FindSandboxes returns the page of sandboxes selected by f and the number of
+// sandboxes matching f before paging. Ties are broken by ID.
+func (r *Repository) FindSandboxes(f SandboxFilter) ([]Sandbox, int64, error) {
+	q := r.db.Model(&Sandbox{})
+	if f.Owner != "" {
+		q = q.Where("owner_id = ?", f.Owner)
+	}
+	if f.Image != "" {
+		q = q.Where("image = ?", f.Image)
+	}
+	if f.NamePrefix != "" {
+		q = q.Where("name LIKE ? ESCAPE '!'", likeEscaper.Replace(f.NamePrefix)+"%")
+	}
+	dialect := r.db.Dialector.Name()
+	for k, v := range f.Labels.Equals {
+		equals, _, arg := labelConditions(dialect, k)
+		q = q.Where(equals, arg, v)
+	}
+	for _, k := range f.Labels.Keys {
+		_, exists, arg := labelConditions(dialect, k)
+		q = q.Where(exists, arg)
+	}
+	if f.IDs != nil {
+		q = q.Where("id IN ?", f.IDs)
+	}
+	if len(f.ExcludeIDs) > 0 {
+		q = q.Where("id NOT IN ?", f.ExcludeIDs)
+	}
+
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	col, ok := sandboxSortColumns[f.Sort]
+	if !ok {
+		col = "created_at"
+	}
+	dir := " ASC"
+	if f.Desc {
+		dir = " DESC"
+	}
+	q = q.Order(col + dir).Order("id" + dir)
+	if f.Limit > 0 {
+		q = q.Limit(f.Limit)
+	}
+	if f.Offset > 0 {
+		q = q.Offset(f.Offset)
+	}
+	var sandboxes []Sandbox
+	if err := q.Find(&sandboxes).Error; err != nil {
+		return nil, 0, err
+	}
+	return sandboxes, total, nil
+}
+
+// labelConditions returns, in the SQL dialect of the database, the condition
+// matching a label's value, the condition matching the label's presence, and
+// the argument naming the label in both.
+func labelConditions(dialect, key string) (equals, exists, arg string) {
+	switch dialect {
+	case "postgres":
+		return "labels::jsonb ->> ? = ?", "(labels::jsonb -> ?) IS NOT NULL", key
+	case "mysql":
+		return "JSON_UNQUOTE(JSON_EXTRACT(labels, ?)) = ?", "JSON_CONTAINS_PATH(labels, 'one', ?)", labelPath(key)
+	}
+	return "json_extract(labels, ?) = ?", "json_type(labels, ?) IS NOT NULL", labelPath(key)
+}
+
+// labelPath is the JSON path of a label key. Quoting lets keys contain dots.
+func labelPath(key string) string {
+	return `$."` + key + `"`
+}
+
 // UpdatePorts updates the port mappings for an existing sandbox.
 func (r *Repository) UpdatePorts(id string, ports JSONMap) error {
 	return r.db.Model(&Sandbox{}).Where("id = ?", id).Update("ports", ports).Error
 }
 
+// UpdateEnv replaces the variables set on a sandbox after creation.
+func (r *Repository) UpdateEnv(id string, env JSONMap) error {
+	return r.db.Model(&Sandbox{}).Where("id = ?", id).Update("env", env).Error
+}
+
+// UpdateOwner assigns a sandbox to owner, e.g. a pooled code runner taken by a caller.
+func (r *Repository) UpdateOwner(id, owner string) error {
+	return r.db.Model(&Sandbox{}).Where("id = ?", id).Update("owner_id", owner).Error
+}
+
+// UpdateState records the state of a sandbox, one of the Sandbox* constants.
+func (r *Repository) UpdateState(id, state string) error {
+	return r.db.Model(&Sandbox{}).Where("id = ?", id).Update("state", state).Error
+}
+
+// UpdateReady records the ready check state of a sandbox.
+func (r *Repository) UpdateReady(id, state string) error {
+	return r.db.Model(&Sandbox{}).Where("id = ?", id).Update("ready", state).Error
+}
+
+// FindByReady returns all sandboxes whose ready check is in the given state.
+func (r *Repository) FindByReady(state string) ([]Sandbox, error) {
+	var sandboxes []Sandbox
+	if err := r.db.Where("ready = ?", state).Find(&sandboxes).Error; err != nil {
+		return nil, err
+	}
+	return sandboxes, nil
+}
+
+// UpdateExpiresAt records when a sandbox's pending auto-stop timer fires; 0 clears it.
+func (r *Repository) UpdateExpiresAt(id string, at int64) error {
+	return r.db.Model(&Sandbox{}).Where("id = ?", id).Update("expires_at", at).Error
+}
+
+// FindPendingTimers returns all sandboxes with a recorded auto-stop timer.
+func (r *Repository) FindPendingTimers() ([]Sandbox, error) {
+	var sandboxes []Sandbox
+	if err := r.db.Where("expires_at > 0").Find(&sandboxes).Error; err != nil {
+		return nil, err
+	}
+	return sandboxes, nil
+}
+
 // FindByName returns a sandbox by its name, or nil if not found.
 func (r *Repository) FindByName(name string) (*Sandbox, error) {
 	var s Sandbox
@@ -57,6 +197,33 @@ func (r *Repository) FindByName(name string) (*Sandbox, error) {
 	return &s, nil
 }
 
+// FindByOwner returns all sandboxes belonging to the given owner.
+func (r *Repository) FindByOwner(owner string) ([]Sandbox, error) {
+	var sandboxes []Sandbox
+	if err := r.db.Where("owner_id = ?", owner).Find(&sandboxes).Error; err != nil {
+		return nil, err
+	}
+	return sandboxes, nil
+}
+
+// FindManaged returns all sandboxes created by POST /v1/apply.
+func (r *Repository) FindManaged() ([]Sandbox, error) {
+	var sandboxes []Sandbox
+	if err := r.db.Where("spec_hash <> ''").Find(&sandboxes).Error; err != nil {
+		return nil, err
+	}
+	return sandboxes, nil
+}
+
+// FindByExpirationAction returns all sandboxes with the given expiration action.
+func (r *Repository) FindByExpirationAction(action string) ([]Sandbox, error) {
+	var sandboxes []Sandbox
+	if err := r.db.Where("expiration_action = ?", action).Find(&sandboxes).Error; err != nil {
+		return nil, err
+	}
+	return sandboxes, nil
+}
+
 // Delete removes a sandbox record by its container ID.
 func (r *Repository) Delete(id string) error {
 	return r.db.Delete(&Sandbox{}, "id = ?", id).Error
@@ -88,6 +255,32 @@ func (r *Repository) FindCommandsBySandbox(sandboxID string) ([]Command, error)
 	return cmds, nil
 }
 
+// FindCommands returns the page of commands selected by f, ordered by
+// started_at, and the number of commands in the sandbox.
+func (r *Repository) FindCommands(f CommandFilter) ([]Command, int64, error) {
+	q := r.db.Model(&Command{}).Where("sandbox_id = ?", f.SandboxID)
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if f.Desc {
+		q = q.Order("started_at DESC").Order("id DESC")
+	} else {
+		q = q.Order("started_at ASC").Order("id ASC")
+	}
+	if f.Limit > 0 {
+		q = q.Limit(f.Limit)
+	}
+	if f.Offset > 0 {
+		q = q.Offset(f.Offset)
+	}
+	var cmds []Command
+	if err := q.Find(&cmds).Error; err != nil {
+		return nil, 0, err
+	}
+	return cmds, total, nil
+}
+
 // UpdateCommandFinished marks a command as finished with its exit code.
 func (r *Repository) UpdateCommandFinished(id string, exitCode int, finishedAt int64) error {
 	return r.db.Model(&Command{}).Where("id = ?", id).Updates(map[string]any{
@@ -96,7 +289,501 @@ func (r *Repository) UpdateCommandFinished(id string, exitCode int, finishedAt i
 	}).Error
 }
 
-// DeleteCommandsBySandbox removes all command records for a sandbox.
+// UpdateCommandTimedOut flags a command as killed for exceeding its timeout.
+func (r *Repository) UpdateCommandTimedOut(id string) error {
+	return r.db.Model(&Command{}).Where("id = ?", id).Update("timed_out", true).Error
+}
+
+// DeleteCommandsBySandbox removes all command records and their logs for a sandbox.
 func (r *Repository) DeleteCommandsBySandbox(sandboxID string) error {
+	if err := r.db.Where("sandbox_id = ?", sandboxID).Delete(&CommandLog{}).Error; err != nil {
+		return err
+	}
 	return r.db.Where("sandbox_id = ?", sandboxID).Delete(&Command{}).Error
 }
+
+// SaveProcess creates a process definition.
+func (r *Repository) SaveProcess(p Process) error {
+	return r.db.Create(&p).Error
+}
+
+// FindProcess returns a sandbox's process definition by name, or nil if not found.
+func (r *Repository) FindProcess(sandboxID, name string) (*Process, error) {
+	var p Process
+	if err := r.db.First(&p, "sandbox_id = ? AND name = ?", sandboxID, name).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &p, nil
+}
+
+// FindProcessesBySandbox returns a sandbox's process definitions, oldest first.
+func (r *Repository) FindProcessesBySandbox(sandboxID string) ([]Process, error) {
+	var procs []Process
+	if err := r.db.Where("sandbox_id = ?", sandboxID).Order("created_at ASC, name ASC").Find(&procs).Error; err != nil {
+		return nil, err
+	}
+	return procs, nil
+}
+
+// DeleteProcess removes a process definition. Returns false if it did not exist.
+func (r *Repository) DeleteProcess(sandboxID, name string) (bool, error) {
+	res := r.db.Where("sandbox_id = ? AND name = ?", sandboxID, name).Delete(&Process{})
+	return res.RowsAffected > 0, res.Error
+}
+
+// DeleteProcessesBySandbox removes all process definitions of a sandbox.
+func (r *Repository) DeleteProcessesBySandbox(sandboxID string) error {
+	return r.db.Where("sandbox_id = ?", sandboxID).Delete(&Process{}).Error
+}
+
+// SaveCommandLog creates or replaces the persisted output of a command.
+func (r *Repository) SaveCommandLog(l CommandLog) error {
+	return r.db.Save(&l).Error
+}
+
+// FindCommandLog returns the persisted output of a command, or nil if not found.
+func (r *Repository) FindCommandLog(cmdID string) (*CommandLog, error) {
+	var l CommandLog
+	if err := r.db.First(&l, "command_id = ?", cmdID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &l, nil
+}
+
+// DeleteCommandLogsBefore removes logs of commands that finished before the
+// given unix milliseconds and returns how many were removed.
+func (r *Repository) DeleteCommandLogsBefore(finishedAt int64) (int64, error) {
+	res := r.db.Where("finished_at < ?", finishedAt).Delete(&CommandLog{})
+	return res.RowsAffected, res.Error
+}
+
+// SaveAPIKey creates a new API key record.
+func (r *Repository) SaveAPIKey(k APIKey) error {
+	return r.db.Create(&k).Error
+}
+
+// FindAPIKeyByHash returns the key with the given secret hash, or nil if not found.
+func (r *Repository) FindAPIKeyByHash(hash string) (*APIKey, error) {
+	var k APIKey
+	if err := r.db.First(&k, "hash = ?", hash).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &k, nil
+}
+
+// FindAllAPIKeys returns all API keys, including revoked ones, oldest first.
+func (r *Repository) FindAllAPIKeys() ([]APIKey, error) {
+	var keys []APIKey
+	if err := r.db.Order("created_at ASC").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey marks an active key as revoked. Returns false if no active key has that ID.
+func (r *Repository) RevokeAPIKey(id string, at int64) (bool, error) {
+	res := r.db.Model(&APIKey{}).Where("id = ? AND revoked_at IS NULL", id).Update("revoked_at", at)
+	return res.RowsAffected > 0, res.Error
+}
+
+// CountActiveAPIKeys returns the number of keys that have not been revoked.
+func (r *Repository) CountActiveAPIKeys() (int64, error) {
+	var n int64
+	err := r.db.Model(&APIKey{}).Where("revoked_at IS NULL").Count(&n).Error
+	return n, err
+}
+
+// SaveAuditEvent appends an audit event.
+func (r *Repository) SaveAuditEvent(e AuditEvent) error {
+	return r.db.Create(&e).Error
+}
+
+// FindAuditEvents returns events matching f, newest first.
+func (r *Repository) FindAuditEvents(f AuditFilter) ([]AuditEvent, error) {
+	q := r.db.Order("time DESC, id DESC")
+	if f.SandboxID != "" {
+		q = q.Where("sandbox_id = ?", f.SandboxID)
+	}
+	if f.Action != "" {
+		q = q.Where("action = ?", f.Action)
+	}
+	if f.Since > 0 {
+		q = q.Where("time >= ?", f.Since)
+	}
+	if f.Until > 0 {
+		q = q.Where("time < ?", f.Until)
+	}
+	if f.Limit > 0 {
+		q = q.Limit(f.Limit)
+	}
+	var events []AuditEvent
+	if err := q.Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// SaveDomain creates or replaces a domain mapping.
+func (r *Repository) SaveDomain(d Domain) error {
+	return r.db.Save(&d).Error
+}
+
+// FindDomain returns the mapping for host, or nil if not found.
+func (r *Repository) FindDomain(host string) (*Domain, error) {
+	var d Domain
+	if err := r.db.First(&d, "host = ?", host).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &d, nil
+}
+
+// FindDomainsBySandbox returns the domains mapped to a sandbox, oldest first.
+func (r *Repository) FindDomainsBySandbox(sandboxID string) ([]Domain, error) {
+	var domains []Domain
+	if err := r.db.Where("sandbox_id = ?", sandboxID).Order("created_at ASC").Find(&domains).Error; err != nil {
+		return nil, err
+	}
+	return domains, nil
+}
+
+// DeleteDomain removes host from a sandbox. Returns false if it was not mapped to that sandbox.
+func (r *Repository) DeleteDomain(host, sandboxID string) (bool, error) {
+	res := r.db.Where("host = ? AND sandbox_id = ?", host, sandboxID).Delete(&Domain{})
+	return res.RowsAffected > 0, res.Error
+}
+
+// DeleteDomainsBySandbox removes all domain mappings of a sandbox.
+func (r *Repository) DeleteDomainsBySandbox(sandboxID string) error {
+	return r.db.Where("sandbox_id = ?", sandboxID).Delete(&Domain{}).Error
+}
+
+// SaveImageUse creates or updates when an image was last used.
+func (r *Repository) SaveImageUse(u ImageUse) error {
+	return r.db.Save(&u).Error
+}
+
+// FindImageUses returns the last-use time of every tracked image.
+func (r *Repository) FindImageUses() ([]ImageUse, error) {
+	var uses []ImageUse
+	if err := r.db.Find(&uses).Error; err != nil {
+		return nil, err
+	}
+	return uses, nil
+}
+
+// DeleteImageUse stops tracking an image.
+func (r *Repository) DeleteImageUse(imageID string) error {
+	return r.db.Where("image_id = ?", imageID).Delete(&ImageUse{}).Error
+}
+
+// SaveSecret creates or updates a secret.
+func (r *Repository) SaveSecret(s Secret) error {
+	return r.db.Save(&s).Error
+}
+
+// FindSecret returns an owner's secret by name, or nil if not found.
+func (r *Repository) FindSecret(owner, name string) (*Secret, error) {
+	var s Secret
+	if err := r.db.First(&s, "owner = ? AND name = ?", owner, name).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+// FindSecretsByOwner returns an owner's secrets ordered by name.
+func (r *Repository) FindSecretsByOwner(owner string) ([]Secret, error) {
+	var secrets []Secret
+	if err := r.db.Where("owner = ?", owner).Order("name").Find(&secrets).Error; err != nil {
+		return nil, err
+	}
+	return secrets, nil
+}
+
+// DeleteSecret removes an owner's secret. Returns false if it did not exist.
+func (r *Repository) DeleteSecret(owner, name string) (bool, error) {
+	res := r.db.Where("owner = ? AND name = ?", owner, name).Delete(&Secret{})
+	return res.RowsAffected > 0, res.Error
+}
+
+// SaveStack creates a stack. Fails if a stack with the name exists.
+func (r *Repository) SaveStack(s Stack) error {
+	return r.db.Create(&s).Error
+}
+
+// FindStack returns a stack by name, or nil if not found.
+func (r *Repository) FindStack(name string) (*Stack, error) {
+	var s Stack
+	if err := r.db.First(&s, "name = ?", name).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+// FindStacks returns the stacks of an owner ordered by name. An empty owner returns all stacks.
+func (r *Repository) FindStacks(owner string) ([]Stack, error) {
+	q := r.db.Order("name")
+	if owner != "" {
+		q = q.Where("owner_id = ?", owner)
+	}
+	var stacks []Stack
+	if err := q.Find(&stacks).Error; err != nil {
+		return nil, err
+	}
+	return stacks, nil
+}
+
+// DeleteStack removes a stack record. Its sandboxes are left alone.
+func (r *Repository) DeleteStack(name string) error {
+	return r.db.Where("name = ?", name).Delete(&Stack{}).Error
+}
+
+// FindByStack returns the sandboxes of a stack ordered by name.
+func (r *Repository) FindByStack(stack string) ([]Sandbox, error) {
+	var sandboxes []Sandbox
+	if err := r.db.Where("stack = ?", stack).Order("name").Find(&sandboxes).Error; err != nil {
+		return nil, err
+	}
+	return sandboxes, nil
+}
+
+// SaveNetworkGroup creates a network group. Fails if one with the name exists.
+func (r *Repository) SaveNetworkGroup(g NetworkGroup) error {
+	return r.db.Create(&g).Error
+}
+
+// FindNetworkGroup returns a network group by name, or nil if not found.
+func (r *Repository) FindNetworkGroup(name string) (*NetworkGroup, error) {
+	var g NetworkGroup
+	if err := r.db.First(&g, "name = ?", name).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &g, nil
+}
+
+// FindNetworkGroups returns the network groups of an owner ordered by name. An empty owner returns all of them.
+func (r *Repository) FindNetworkGroups(owner string) ([]NetworkGroup, error) {
+	q := r.db.Order("name")
+	if owner != "" {
+		q = q.Where("owner_id = ?", owner)
+	}
+	var groups []NetworkGroup
+	if err := q.Find(&groups).Error; err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// DeleteNetworkGroup removes a network group record.
+func (r *Repository) DeleteNetworkGroup(name string) error {
+	return r.db.Where("name = ?", name).Delete(&NetworkGroup{}).Error
+}
+
+// FindByNetworkGroup returns the sandboxes attached to a network group ordered by name.
+func (r *Repository) FindByNetworkGroup(group string) ([]Sandbox, error) {
+	var sandboxes []Sandbox
+	if err := r.db.Where("network_group = ?", group).Order("name").Find(&sandboxes).Error; err != nil {
+		return nil, err
+	}
+	return sandboxes, nil
+}
+
+// ReserveIdempotencyKey inserts k unless its owner already used the key.
+// Returns false if the key exists.
+func (r *Repository) ReserveIdempotencyKey(k IdempotencyKey) (bool, error) {
+	res := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&k)
+	return res.RowsAffected > 0, res.Error
+}
+
+// FindIdempotencyKey returns the record of an owner's key, or nil if not found.
+func (r *Repository) FindIdempotencyKey(owner, key string) (*IdempotencyKey, error) {
+	var k IdempotencyKey
+	if err := r.db.First(&k, idempotencyKeyIs(owner, key)).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &k, nil
+}
+
+// SaveIdempotencyKey creates or replaces an idempotency key record.
+func (r *Repository) SaveIdempotencyKey(k IdempotencyKey) error {
+	return r.db.Save(&k).Error
+}
+
+// TakeOverIdempotencyKey moves an abandoned reservation to k.CreatedAt, if
+// the key is still reserved since createdAt and has no response. Returns
+// false if another request finished or took over the key first.
+func (r *Repository) TakeOverIdempotencyKey(k IdempotencyKey, createdAt int64) (bool, error) {
+	cond := idempotencyKeyIs(k.Owner, k.Key)
+	cond["created_at"] = createdAt
+	cond["response"] = ""
+	res := r.db.Model(&IdempotencyKey{}).Where(cond).Update("created_at", k.CreatedAt)
+	return res.RowsAffected > 0, res.Error
+}
+
+// DeleteIdempotencyKey removes an owner's key.
+func (r *Repository) DeleteIdempotencyKey(owner, key string) error {
+	return r.db.Where(idempotencyKeyIs(owner, key)).Delete(&IdempotencyKey{}).Error
+}
+
+// idempotencyKeyIs selects an owner's key. A map condition quotes the column
+// names, and key is a reserved word in MySQL.
+func idempotencyKeyIs(owner, key string) map[string]any {
+	return map[string]any{"owner": owner, "key": key}
+}
+
+// DeleteIdempotencyKeysBefore removes keys created before createdAt (unix
+// milliseconds) and returns how many were removed.
+func (r *Repository) DeleteIdempotencyKeysBefore(createdAt int64) (int64, error) {
+	res := r.db.Where("created_at < ?", createdAt).Delete(&IdempotencyKey{})
+	return res.RowsAffected, res.Error
+}
+
+// SaveSandboxEvent records an event and drops the oldest events of the sandbox
+// beyond the newest keep.
+func (r *Repository) SaveSandboxEvent(e SandboxEvent, keep int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&e).Error; err != nil {
+			return err
+		}
+		return deleteAllButNewest(tx, &SandboxEvent{}, "sandbox_id", e.SandboxID, keep)
+	})
+}
+
+// deleteAllButNewest deletes the rows of model whose column equals value,
+// except the keep with the highest IDs. MySQL rejects LIMIT in an IN
+// subquery, so the oldest row kept is looked up first.
+func deleteAllButNewest(tx *gorm.DB, model any, column string, value any, keep int) error {
+	var oldest []uint
+	if err := tx.Model(model).Where(column+" = ?", value).Order("id DESC").Offset(keep-1).Limit(1).Pluck("id", &oldest).Error; err != nil {
+		return err
+	}
+	if len(oldest) == 0 {
+		return nil
+	}
+	return tx.Where(column+" = ? AND id < ?", value, oldest[0]).Delete(model).Error
+}
+
+// FindSandboxEvents returns the recorded events of a sandbox, oldest first.
+func (r *Repository) FindSandboxEvents(sandboxID string) ([]SandboxEvent, error) {
+	var events []SandboxEvent
+	if err := r.db.Where("sandbox_id = ?", sandboxID).Order("id ASC").Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// DeleteSandboxEventsBySandbox removes all events of a sandbox.
+func (r *Repository) DeleteSandboxEventsBySandbox(sandboxID string) error {
+	return r.db.Where("sandbox_id = ?", sandboxID).Delete(&SandboxEvent{}).Error
+}
+
+// SaveSchedule creates a schedule. Fails if one with the name exists.
+func (r *Repository) SaveSchedule(s Schedule) error {
+	return r.db.Create(&s).Error
+}
+
+// FindSchedule returns a schedule by name, or nil if not found.
+func (r *Repository) FindSchedule(name string) (*Schedule, error) {
+	var s Schedule
+	if err := r.db.First(&s, "name = ?", name).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+// FindSchedules returns the schedules of an owner ordered by name. An empty owner returns all of them.
+func (r *Repository) FindSchedules(owner string) ([]Schedule, error) {
+	q := r.db.Order("name")
+	if owner != "" {
+		q = q.Where("owner_id = ?", owner)
+	}
+	var schedules []Schedule
+	if err := q.Find(&schedules).Error; err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+// FindDueSchedules returns the schedules whose next run is at or before now (unix milliseconds).
+func (r *Repository) FindDueSchedules(now int64) ([]Schedule, error) {
+	var schedules []Schedule
+	if err := r.db.Where("next_run_at <= ?", now).Order("next_run_at").Find(&schedules).Error; err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+// UpdateScheduleNextRun sets when a schedule runs next (unix milliseconds).
+func (r *Repository) UpdateScheduleNextRun(name string, next int64) error {
+	return r.db.Model(&Schedule{}).Where("name = ?", name).Update("next_run_at", next).Error
+}
+
+// DeleteSchedule removes a schedule and its recorded runs.
+func (r *Repository) DeleteSchedule(name string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("schedule = ?", name).Delete(&ScheduleRun{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("name = ?", name).Delete(&Schedule{}).Error
+	})
+}
+
+// SaveScheduleRun records a new run and drops the oldest runs of the schedule
+// beyond the newest keep. run.ID is set to the new record's ID.
+func (r *Repository) SaveScheduleRun(run *ScheduleRun, keep int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(run).Error; err != nil {
+			return err
+		}
+		return deleteAllButNewest(tx, &ScheduleRun{}, "schedule", run.Schedule, keep)
+	})
+}
+
+// UpdateScheduleRun saves the outcome of a run. Runs deleted in the meantime,
+// with their schedule, stay deleted.
+func (r *Repository) UpdateScheduleRun(run ScheduleRun) error {
+	return r.db.Model(&ScheduleRun{}).Where("id = ?", run.ID).Select("*").Updates(&run).Error
+}
+
+// FindScheduleRuns returns the recorded runs of a schedule, newest first.
+func (r *Repository) FindScheduleRuns(schedule string) ([]ScheduleRun, error) {
+	var runs []ScheduleRun
+	if err := r.db.Where("schedule = ?", schedule).Order("id DESC").Find(&runs).Error; err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
+
+// FailRunningScheduleRuns marks runs still recorded as running, interrupted
+// by a server restart, as errors finished at now (unix milliseconds).
+func (r *Repository) FailRunningScheduleRuns(reason string, now int64) (int64, error) {
+	res := r.db.Model(&ScheduleRun{}).Where("status = ?", "running").
+		Updates(map[string]any{"status": "error", "error": reason, "finished_at": now})
+	return res.RowsAffected, res.Error
+}
@@ -1,12 +1,23 @@
 package database
 
-import "testing"
+import (
+	"context"
+	"strings"
+	"testing"
+)
 
 func newTestRepo(t *testing.T) *Repository {
 	t.Helper()
 	return NewRepository(New(":memory:"))
 }
 
+func TestRepositoryPing(t *testing.T) {
+	repo := newTestRepo(t)
+	if err := repo.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping() error: %v", err)
+	}
+}
+
 func TestRepositorySandboxCRUD(t *testing.T) {
 	repo := newTestRepo(t)
 
@@ -50,6 +61,13 @@ func TestRepositorySandboxCRUD(t *testing.T) {
 		t.Fatalf("ports not updated: %+v", updated.Ports)
 	}
 
+	if err := repo.UpdateEnv("sb-1", JSONMap{"TOKEN": "abc"}); err != nil {
+		t.Fatalf("UpdateEnv() error: %v", err)
+	}
+	if updated, _ := repo.FindByID("sb-1"); updated.Env["TOKEN"] != "abc" {
+		t.Fatalf("env not updated: %+v", updated.Env)
+	}
+
 	all, err := repo.FindAll()
 	if err != nil {
 		t.Fatalf("FindAll() error: %v", err)
@@ -118,6 +136,16 @@ func TestRepositoryCommandsCRUD(t *testing.T) {
 	if finished.FinishedAt == nil || *finished.FinishedAt != 99 {
 		t.Fatalf("finished_at not updated: %+v", finished)
 	}
+	if finished.TimedOut {
+		t.Fatalf("timed_out set without timeout: %+v", finished)
+	}
+
+	if err := repo.UpdateCommandTimedOut("cmd-2"); err != nil {
+		t.Fatalf("UpdateCommandTimedOut() error: %v", err)
+	}
+	if timedOut, _ := repo.FindCommandByID("cmd-2"); timedOut == nil || !timedOut.TimedOut {
+		t.Fatalf("timed_out not updated: %+v", timedOut)
+	}
 
 	if err := repo.DeleteCommandsBySandbox("sb-1"); err != nil {
 		t.Fatalf("DeleteCommandsBySandbox() error: %v", err)
@@ -131,3 +159,618 @@ func TestRepositoryCommandsCRUD(t *testing.T) {
 		t.Fatalf("expected 0 commands after delete, got %d", len(empty))
 	}
 }
+
+func TestRepositoryFindManaged(t *testing.T) {
+	repo := newTestRepo(t)
+
+	if err := repo.Save(Sandbox{ID: "sb-1", Name: "adhoc", Image: "node:22"}); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+	if err := repo.Save(Sandbox{ID: "sb-2", Name: "web", Image: "node:22", SpecHash: "abc"}); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	managed, err := repo.FindManaged()
+	if err != nil {
+		t.Fatalf("FindManaged() error: %v", err)
+	}
+	if len(managed) != 1 || managed[0].Name != "web" {
+		t.Fatalf("FindManaged() = %+v, want only web", managed)
+	}
+}
+
+func TestRepositoryFindByOwner(t *testing.T) {
+	repo := newTestRepo(t)
+
+	if err := repo.Save(Sandbox{ID: "sb-1", Name: "a", Image: "node:22", OwnerID: "team-a"}); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+	if err := repo.Save(Sandbox{ID: "sb-2", Name: "b", Image: "node:22", OwnerID: "team-b"}); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	found, err := repo.FindByOwner("team-a")
+	if err != nil {
+		t.Fatalf("FindByOwner() error: %v", err)
+	}
+	if len(found) != 1 || found[0].ID != "sb-1" {
+		t.Fatalf("FindByOwner() = %+v, want only sb-1", found)
+	}
+}
+
+func TestRepositoryFindByExpirationAction(t *testing.T) {
+	repo := newTestRepo(t)
+
+	if err := repo.Save(Sandbox{ID: "sb-1", Name: "keep", Image: "node:22"}); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+	if err := repo.Save(Sandbox{ID: "sb-2", Name: "ephemeral", Image: "node:22", ExpirationAction: "delete"}); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	found, err := repo.FindByExpirationAction("delete")
+	if err != nil {
+		t.Fatalf("FindByExpirationAction() error: %v", err)
+	}
+	if len(found) != 1 || found[0].Name != "ephemeral" {
+		t.Fatalf("FindByExpirationAction() = %+v, want only ephemeral", found)
+	}
+}
+
+func TestRepositoryPendingTimers(t *testing.T) {
+	repo := newTestRepo(t)
+
+	for _, sb := range []Sandbox{{ID: "sb-1", Name: "a"}, {ID: "sb-2", Name: "b"}} {
+		if err := repo.Save(sb); err != nil {
+			t.Fatalf("Save() error: %v", err)
+		}
+	}
+	if err := repo.UpdateExpiresAt("sb-2", 1700000000000); err != nil {
+		t.Fatalf("UpdateExpiresAt() error: %v", err)
+	}
+
+	found, err := repo.FindPendingTimers()
+	if err != nil {
+		t.Fatalf("FindPendingTimers() error: %v", err)
+	}
+	if len(found) != 1 || found[0].ID != "sb-2" || found[0].ExpiresAt != 1700000000000 {
+		t.Fatalf("FindPendingTimers() = %+v, want only sb-2", found)
+	}
+
+	if err := repo.UpdateExpiresAt("sb-2", 0); err != nil {
+		t.Fatalf("UpdateExpiresAt() error: %v", err)
+	}
+	if found, _ := repo.FindPendingTimers(); len(found) != 0 {
+		t.Fatalf("FindPendingTimers() = %+v after clearing, want none", found)
+	}
+}
+
+func TestRepositoryAPIKeys(t *testing.T) {
+	repo := newTestRepo(t)
+
+	if err := repo.SaveAPIKey(APIKey{ID: "key_1", Name: "ci", Hash: "h1", Scopes: "exec", CreatedAt: 1}); err != nil {
+		t.Fatalf("SaveAPIKey() error: %v", err)
+	}
+	if err := repo.SaveAPIKey(APIKey{ID: "key_2", Name: "dash", Hash: "h2", Scopes: "read-only", CreatedAt: 2}); err != nil {
+		t.Fatalf("SaveAPIKey() error: %v", err)
+	}
+
+	k, err := repo.FindAPIKeyByHash("h1")
+	if err != nil || k == nil || k.ID != "key_1" {
+		t.Fatalf("FindAPIKeyByHash() = %+v, %v", k, err)
+	}
+
+	ok, err := repo.RevokeAPIKey("key_1", 10)
+	if err != nil || !ok {
+		t.Fatalf("RevokeAPIKey() = %v, %v", ok, err)
+	}
+	if ok, _ := repo.RevokeAPIKey("key_1", 11); ok {
+		t.Fatalf("RevokeAPIKey() on revoked key should return false")
+	}
+
+	n, err := repo.CountActiveAPIKeys()
+	if err != nil || n != 1 {
+		t.Fatalf("CountActiveAPIKeys() = %d, %v, want 1", n, err)
+	}
+
+	all, err := repo.FindAllAPIKeys()
+	if err != nil || len(all) != 2 || all[0].RevokedAt == nil || *all[0].RevokedAt != 10 {
+		t.Fatalf("FindAllAPIKeys() = %+v, %v", all, err)
+	}
+}
+
+func TestRepositoryAuditEvents(t *testing.T) {
+	repo := newTestRepo(t)
+
+	events := []AuditEvent{
+		{Time: 100, Action: "sandbox.create", SandboxID: "sb1"},
+		{Time: 200, Action: "command.exec", SandboxID: "sb1"},
+		{Time: 300, Action: "sandbox.delete", SandboxID: "sb2"},
+	}
+	for _, e := range events {
+		if err := repo.SaveAuditEvent(e); err != nil {
+			t.Fatalf("SaveAuditEvent() error: %v", err)
+		}
+	}
+
+	all, err := repo.FindAuditEvents(AuditFilter{})
+	if err != nil || len(all) != 3 || all[0].Action != "sandbox.delete" {
+		t.Fatalf("FindAuditEvents() = %+v, %v, want 3 newest first", all, err)
+	}
+
+	bySandbox, _ := repo.FindAuditEvents(AuditFilter{SandboxID: "sb1"})
+	if len(bySandbox) != 2 {
+		t.Fatalf("FindAuditEvents(sandbox) = %+v, want 2", bySandbox)
+	}
+
+	byAction, _ := repo.FindAuditEvents(AuditFilter{Action: "command.exec"})
+	if len(byAction) != 1 || byAction[0].Time != 200 {
+		t.Fatalf("FindAuditEvents(action) = %+v, want the exec event", byAction)
+	}
+
+	byTime, _ := repo.FindAuditEvents(AuditFilter{Since: 200, Until: 300})
+	if len(byTime) != 1 || byTime[0].Action != "command.exec" {
+		t.Fatalf("FindAuditEvents(range) = %+v, want the exec event", byTime)
+	}
+
+	limited, _ := repo.FindAuditEvents(AuditFilter{Limit: 1})
+	if len(limited) != 1 {
+		t.Fatalf("FindAuditEvents(limit) = %+v, want 1", limited)
+	}
+}
+
+func TestRepositoryDomains(t *testing.T) {
+	repo := newTestRepo(t)
+
+	if err := repo.SaveDomain(Domain{Host: "a.example.com", SandboxID: "sb1", CreatedAt: 1}); err != nil {
+		t.Fatalf("SaveDomain() error: %v", err)
+	}
+	if err := repo.SaveDomain(Domain{Host: "b.example.com", SandboxID: "sb1", CreatedAt: 2}); err != nil {
+		t.Fatalf("SaveDomain() error: %v", err)
+	}
+
+	d, err := repo.FindDomain("a.example.com")
+	if err != nil || d == nil || d.SandboxID != "sb1" {
+		t.Fatalf("FindDomain() = %+v, %v", d, err)
+	}
+	if d, _ := repo.FindDomain("c.example.com"); d != nil {
+		t.Fatalf("FindDomain(unknown) = %+v, want nil", d)
+	}
+
+	list, err := repo.FindDomainsBySandbox("sb1")
+	if err != nil || len(list) != 2 || list[0].Host != "a.example.com" {
+		t.Fatalf("FindDomainsBySandbox() = %+v, %v", list, err)
+	}
+
+	if ok, _ := repo.DeleteDomain("a.example.com", "sb2"); ok {
+		t.Fatalf("DeleteDomain() removed a domain of another sandbox")
+	}
+	if ok, err := repo.DeleteDomain("a.example.com", "sb1"); err != nil || !ok {
+		t.Fatalf("DeleteDomain() = %v, %v", ok, err)
+	}
+
+	if err := repo.DeleteDomainsBySandbox("sb1"); err != nil {
+		t.Fatalf("DeleteDomainsBySandbox() error: %v", err)
+	}
+	if list, _ := repo.FindDomainsBySandbox("sb1"); len(list) != 0 {
+		t.Fatalf("FindDomainsBySandbox() after delete = %+v", list)
+	}
+}
+
+func TestRepositoryCommandLogs(t *testing.T) {
+	repo := newTestRepo(t)
+
+	if err := repo.SaveCommandLog(CommandLog{CommandID: "cmd-1", SandboxID: "sb-1", Stdout: "a", FinishedAt: 10}); err != nil {
+		t.Fatalf("SaveCommandLog() error: %v", err)
+	}
+	if err := repo.SaveCommandLog(CommandLog{CommandID: "cmd-2", SandboxID: "sb-1", Stdout: "b", FinishedAt: 30}); err != nil {
+		t.Fatalf("SaveCommandLog() error: %v", err)
+	}
+
+	l, err := repo.FindCommandLog("cmd-1")
+	if err != nil || l == nil || l.Stdout != "a" {
+		t.Fatalf("FindCommandLog() = %+v, %v", l, err)
+	}
+	if l, _ := repo.FindCommandLog("missing"); l != nil {
+		t.Fatalf("FindCommandLog(missing) = %+v, want nil", l)
+	}
+
+	n, err := repo.DeleteCommandLogsBefore(20)
+	if err != nil || n != 1 {
+		t.Fatalf("DeleteCommandLogsBefore() = %d, %v, want 1", n, err)
+	}
+	if l, _ := repo.FindCommandLog("cmd-1"); l != nil {
+		t.Fatalf("cmd-1 log survived retention: %+v", l)
+	}
+
+	if err := repo.DeleteCommandsBySandbox("sb-1"); err != nil {
+		t.Fatalf("DeleteCommandsBySandbox() error: %v", err)
+	}
+	if l, _ := repo.FindCommandLog("cmd-2"); l != nil {
+		t.Fatalf("cmd-2 log survived sandbox removal: %+v", l)
+	}
+}
+
+func TestRepositoryProcesses(t *testing.T) {
+	repo := newTestRepo(t)
+
+	if err := repo.SaveProcess(Process{SandboxID: "sb-1", Name: "web", Command: "npm", Restart: "always", CreatedAt: 2}); err != nil {
+		t.Fatalf("SaveProcess() error: %v", err)
+	}
+	if err := repo.SaveProcess(Process{SandboxID: "sb-1", Name: "worker", Command: "node", Env: JSONMap{"A": "1"}, CreatedAt: 1}); err != nil {
+		t.Fatalf("SaveProcess() error: %v", err)
+	}
+	if err := repo.SaveProcess(Process{SandboxID: "sb-1", Name: "web", Command: "npm"}); err == nil {
+		t.Fatalf("SaveProcess() accepted a duplicate name")
+	}
+
+	p, err := repo.FindProcess("sb-1", "worker")
+	if err != nil || p == nil || p.Env["A"] != "1" {
+		t.Fatalf("FindProcess() = %+v, %v", p, err)
+	}
+	if p, _ := repo.FindProcess("sb-2", "worker"); p != nil {
+		t.Fatalf("FindProcess() in another sandbox = %+v, want nil", p)
+	}
+
+	list, err := repo.FindProcessesBySandbox("sb-1")
+	if err != nil || len(list) != 2 || list[0].Name != "worker" {
+		t.Fatalf("FindProcessesBySandbox() = %+v, %v", list, err)
+	}
+
+	if ok, err := repo.DeleteProcess("sb-1", "web"); err != nil || !ok {
+		t.Fatalf("DeleteProcess() = %v, %v", ok, err)
+	}
+	if ok, _ := repo.DeleteProcess("sb-1", "web"); ok {
+		t.Fatalf("DeleteProcess() removed a missing process")
+	}
+
+	if err := repo.DeleteProcessesBySandbox("sb-1"); err != nil {
+		t.Fatalf("DeleteProcessesBySandbox() error: %v", err)
+	}
+	if list, _ := repo.FindProcessesBySandbox("sb-1"); len(list) != 0 {
+		t.Fatalf("FindProcessesBySandbox() after delete = %+v", list)
+	}
+}
+
+func TestRepositoryFindSandboxes(t *testing.T) {
+	repo := newTestRepo(t)
+	for i, sb := range []Sandbox{
+		{ID: "sb-1", Name: "web-a", Image: "node:22", OwnerID: "team-a"},
+		{ID: "sb-2", Name: "web_b", Image: "python:3", OwnerID: "team-a"},
+		{ID: "sb-3", Name: "webxc", Image: "node:22", OwnerID: "team-b"},
+		{ID: "sb-4", Name: "api", Image: "node:22", OwnerID: "team-a"},
+	} {
+		sb.CreatedAt = int64(100 - i)
+		if err := repo.Save(sb); err != nil {
+			t.Fatalf("Save(%s) error: %v", sb.ID, err)
+		}
+	}
+
+	ids := func(sbs []Sandbox) []string {
+		out := make([]string, len(sbs))
+		for i, sb := range sbs {
+			out[i] = sb.ID
+		}
+		return out
+	}
+	tests := []struct {
+		name  string
+		f     SandboxFilter
+		want  []string
+		total int64
+	}{
+		{"default order is oldest first", SandboxFilter{}, []string{"sb-4", "sb-3", "sb-2", "sb-1"}, 4},
+		{"sort by name desc", SandboxFilter{Sort: "name", Desc: true}, []string{"sb-3", "sb-2", "sb-1", "sb-4"}, 4},
+		{"owner and image", SandboxFilter{Owner: "team-a", Image: "node:22", Sort: "name"}, []string{"sb-4", "sb-1"}, 2},
+		{"prefix wildcards match literally", SandboxFilter{NamePrefix: "web_"}, []string{"sb-2"}, 1},
+		{"ids", SandboxFilter{IDs: []string{"sb-1", "sb-3"}, Sort: "name"}, []string{"sb-1", "sb-3"}, 2},
+		{"no ids", SandboxFilter{IDs: []string{}}, []string{}, 0},
+		{"exclude ids", SandboxFilter{ExcludeIDs: []string{"sb-1", "sb-4"}}, []string{"sb-3", "sb-2"}, 2},
+		{"page", SandboxFilter{Sort: "name", Limit: 2, Offset: 1}, []string{"sb-1", "sb-2"}, 4},
+		{"offset only", SandboxFilter{Sort: "name", Offset: 3}, []string{"sb-3"}, 4},
+	}
+	for _, tc := range tests {
+		got, total, err := repo.FindSandboxes(tc.f)
+		if err != nil {
+			t.Fatalf("%s: FindSandboxes() error: %v", tc.name, err)
+		}
+		if g := ids(got); strings.Join(g, ",") != strings.Join(tc.want, ",") || total != tc.total {
+			t.Fatalf("%s: FindSandboxes() = %v (total %d), want %v (total %d)", tc.name, g, total, tc.want, tc.total)
+		}
+	}
+}
+
+func TestRepositoryFindSandboxesByLabel(t *testing.T) {
+	repo := newTestRepo(t)
+	for _, sb := range []Sandbox{
+		{ID: "sb-1", Labels: JSONMap{"team": "ml", "com.example/job": "42"}},
+		{ID: "sb-2", Labels: JSONMap{"team": "web"}},
+		{ID: "sb-3"},
+	} {
+		if err := repo.Save(sb); err != nil {
+			t.Fatalf("Save(%s) error: %v", sb.ID, err)
+		}
+	}
+
+	tests := []struct {
+		sel  LabelSelector
+		want int64
+	}{
+		{LabelSelector{Equals: map[string]string{"team": "ml"}}, 1},
+		{LabelSelector{Equals: map[string]string{"com.example/job": "42"}}, 1},
+		{LabelSelector{Keys: []string{"team"}}, 2},
+		{LabelSelector{Equals: map[string]string{"team": "web"}, Keys: []string{"com.example/job"}}, 0},
+	}
+	for _, tc := range tests {
+		got, total, err := repo.FindSandboxes(SandboxFilter{Labels: tc.sel})
+		if err != nil {
+			t.Fatalf("FindSandboxes(%+v) error: %v", tc.sel, err)
+		}
+		if total != tc.want || int64(len(got)) != tc.want {
+			t.Fatalf("FindSandboxes(%+v) = %d sandboxes (total %d), want %d", tc.sel, len(got), total, tc.want)
+		}
+	}
+
+	sb, err := repo.FindByID("sb-1")
+	if err != nil || sb.Labels["team"] != "ml" {
+		t.Fatalf("FindByID() labels = %+v, err %v", sb, err)
+	}
+}
+
+func TestRepositoryFindCommands(t *testing.T) {
+	repo := newTestRepo(t)
+	for i, id := range []string{"cmd_a", "cmd_b", "cmd_c"} {
+		if err := repo.SaveCommand(Command{ID: id, SandboxID: "sb-1", Name: "echo", StartedAt: int64(i)}); err != nil {
+			t.Fatalf("SaveCommand(%s) error: %v", id, err)
+		}
+	}
+	if err := repo.SaveCommand(Command{ID: "cmd_other", SandboxID: "sb-2", Name: "echo"}); err != nil {
+		t.Fatalf("SaveCommand error: %v", err)
+	}
+
+	cmds, total, err := repo.FindCommands(CommandFilter{SandboxID: "sb-1", Desc: true, Limit: 2})
+	if err != nil {
+		t.Fatalf("FindCommands() error: %v", err)
+	}
+	if total != 3 || len(cmds) != 2 || cmds[0].ID != "cmd_c" || cmds[1].ID != "cmd_b" {
+		t.Fatalf("FindCommands(desc, limit 2) = %+v (total %d)", cmds, total)
+	}
+
+	cmds, _, err = repo.FindCommands(CommandFilter{SandboxID: "sb-1", Offset: 2})
+	if err != nil {
+		t.Fatalf("FindCommands() error: %v", err)
+	}
+	if len(cmds) != 1 || cmds[0].ID != "cmd_c" {
+		t.Fatalf("FindCommands(offset 2) = %+v", cmds)
+	}
+}
+
+func TestRepositoryStacks(t *testing.T) {
+	repo := newTestRepo(t)
+
+	if err := repo.SaveStack(Stack{Name: "shop", OwnerID: "team-a", Web: "app", CreatedAt: 1}); err != nil {
+		t.Fatalf("SaveStack() error: %v", err)
+	}
+	if err := repo.SaveStack(Stack{Name: "shop"}); err == nil {
+		t.Fatal("SaveStack() accepted a duplicate name")
+	}
+	repo.SaveStack(Stack{Name: "blog", OwnerID: "team-b"})
+
+	st, err := repo.FindStack("shop")
+	if err != nil || st == nil || st.Web != "app" {
+		t.Fatalf("FindStack() = %+v, %v", st, err)
+	}
+	if st, _ := repo.FindStack("missing"); st != nil {
+		t.Fatalf("FindStack(missing) = %+v, want nil", st)
+	}
+	if all, _ := repo.FindStacks(""); len(all) != 2 || all[0].Name != "blog" {
+		t.Fatalf("FindStacks(\"\") = %+v", all)
+	}
+	if owned, _ := repo.FindStacks("team-a"); len(owned) != 1 || owned[0].Name != "shop" {
+		t.Fatalf("FindStacks(team-a) = %+v", owned)
+	}
+
+	repo.Save(Sandbox{ID: "sb2", Name: "shop-db", Stack: "shop"})
+	repo.Save(Sandbox{ID: "sb1", Name: "shop", Stack: "shop"})
+	repo.Save(Sandbox{ID: "sb3", Name: "other"})
+	services, err := repo.FindByStack("shop")
+	if err != nil || len(services) != 2 || services[0].Name != "shop" {
+		t.Fatalf("FindByStack() = %+v, %v", services, err)
+	}
+
+	if err := repo.DeleteStack("shop"); err != nil {
+		t.Fatalf("DeleteStack() error: %v", err)
+	}
+	if st, _ := repo.FindStack("shop"); st != nil {
+		t.Fatalf("FindStack() after delete = %+v", st)
+	}
+}
+
+func TestRepositoryNetworkGroups(t *testing.T) {
+	repo := newTestRepo(t)
+
+	if err := repo.SaveNetworkGroup(NetworkGroup{Name: "backend", OwnerID: "team-a", CreatedAt: 1}); err != nil {
+		t.Fatalf("SaveNetworkGroup() error: %v", err)
+	}
+	if err := repo.SaveNetworkGroup(NetworkGroup{Name: "backend"}); err == nil {
+		t.Fatal("SaveNetworkGroup() accepted a duplicate name")
+	}
+	repo.SaveNetworkGroup(NetworkGroup{Name: "analytics", OwnerID: "team-b"})
+
+	g, err := repo.FindNetworkGroup("backend")
+	if err != nil || g == nil || g.OwnerID != "team-a" {
+		t.Fatalf("FindNetworkGroup() = %+v, %v", g, err)
+	}
+	if g, _ := repo.FindNetworkGroup("missing"); g != nil {
+		t.Fatalf("FindNetworkGroup(missing) = %+v, want nil", g)
+	}
+	if all, _ := repo.FindNetworkGroups(""); len(all) != 2 || all[0].Name != "analytics" {
+		t.Fatalf("FindNetworkGroups(\"\") = %+v", all)
+	}
+	if owned, _ := repo.FindNetworkGroups("team-b"); len(owned) != 1 || owned[0].Name != "analytics" {
+		t.Fatalf("FindNetworkGroups(team-b) = %+v", owned)
+	}
+
+	repo.Save(Sandbox{ID: "sb1", Name: "db", NetworkGroup: "backend"})
+	repo.Save(Sandbox{ID: "sb2", Name: "app", NetworkGroup: "backend"})
+	repo.Save(Sandbox{ID: "sb3", Name: "other"})
+	attached, err := repo.FindByNetworkGroup("backend")
+	if err != nil || len(attached) != 2 || attached[0].Name != "app" {
+		t.Fatalf("FindByNetworkGroup() = %+v, %v", attached, err)
+	}
+
+	if err := repo.DeleteNetworkGroup("backend"); err != nil {
+		t.Fatalf("DeleteNetworkGroup() error: %v", err)
+	}
+	if g, _ := repo.FindNetworkGroup("backend"); g != nil {
+		t.Fatalf("FindNetworkGroup() after delete = %+v", g)
+	}
+}
+
+func TestRepositoryIdempotencyKeys(t *testing.T) {
+	repo := newTestRepo(t)
+
+	ok, err := repo.ReserveIdempotencyKey(IdempotencyKey{Owner: "team-a", Key: "k1", RequestHash: "h1", CreatedAt: 100})
+	if err != nil || !ok {
+		t.Fatalf("ReserveIdempotencyKey() = %v, %v", ok, err)
+	}
+	if ok, err := repo.ReserveIdempotencyKey(IdempotencyKey{Owner: "team-a", Key: "k1", RequestHash: "h2", CreatedAt: 200}); err != nil || ok {
+		t.Fatalf("ReserveIdempotencyKey(taken) = %v, %v, want false", ok, err)
+	}
+	if ok, _ := repo.ReserveIdempotencyKey(IdempotencyKey{Owner: "team-b", Key: "k1", RequestHash: "h3", CreatedAt: 300}); !ok {
+		t.Fatal("ReserveIdempotencyKey(other owner) = false, keys are per owner")
+	}
+
+	// Only the first of two requests taking over the same reservation wins.
+	if ok, err := repo.TakeOverIdempotencyKey(IdempotencyKey{Owner: "team-b", Key: "k1", CreatedAt: 400}, 300); err != nil || !ok {
+		t.Fatalf("TakeOverIdempotencyKey() = %v, %v", ok, err)
+	}
+	if ok, err := repo.TakeOverIdempotencyKey(IdempotencyKey{Owner: "team-b", Key: "k1", CreatedAt: 401}, 300); err != nil || ok {
+		t.Fatalf("TakeOverIdempotencyKey(lost race) = %v, %v, want false", ok, err)
+	}
+	if k, _ := repo.FindIdempotencyKey("team-b", "k1"); k == nil || k.CreatedAt != 400 {
+		t.Fatalf("FindIdempotencyKey() after take over = %+v", k)
+	}
+
+	if err := repo.SaveIdempotencyKey(IdempotencyKey{Owner: "team-a", Key: "k1", RequestHash: "h1", Response: `{"id":"sb1"}`, CreatedAt: 100}); err != nil {
+		t.Fatalf("SaveIdempotencyKey() error: %v", err)
+	}
+	k, err := repo.FindIdempotencyKey("team-a", "k1")
+	if err != nil || k == nil || k.RequestHash != "h1" || k.Response != `{"id":"sb1"}` {
+		t.Fatalf("FindIdempotencyKey() = %+v, %v", k, err)
+	}
+	if k, _ := repo.FindIdempotencyKey("team-a", "missing"); k != nil {
+		t.Fatalf("FindIdempotencyKey(missing) = %+v, want nil", k)
+	}
+
+	// A finished create is never taken over.
+	if ok, _ := repo.TakeOverIdempotencyKey(IdempotencyKey{Owner: "team-a", Key: "k1", CreatedAt: 500}, 100); ok {
+		t.Fatal("TakeOverIdempotencyKey(finished) = true, want false")
+	}
+
+	if n, err := repo.DeleteIdempotencyKeysBefore(200); err != nil || n != 1 {
+		t.Fatalf("DeleteIdempotencyKeysBefore() = %d, %v, want 1", n, err)
+	}
+	if err := repo.DeleteIdempotencyKey("team-b", "k1"); err != nil {
+		t.Fatalf("DeleteIdempotencyKey() error: %v", err)
+	}
+	if k, _ := repo.FindIdempotencyKey("team-b", "k1"); k != nil {
+		t.Fatalf("FindIdempotencyKey() after delete = %+v", k)
+	}
+}
+
+func TestRepositorySandboxEvents(t *testing.T) {
+	repo := newTestRepo(t)
+
+	code := 137
+	for i := range 5 {
+		e := SandboxEvent{SandboxID: "sb1", Type: "restart", CreatedAt: int64(i)}
+		if i == 4 {
+			e.Type, e.ExitCode = "exit", &code
+		}
+		if err := repo.SaveSandboxEvent(e, 3); err != nil {
+			t.Fatalf("SaveSandboxEvent() error: %v", err)
+		}
+	}
+	repo.SaveSandboxEvent(SandboxEvent{SandboxID: "sb2", Type: "oom"}, 3)
+
+	events, err := repo.FindSandboxEvents("sb1")
+	if err != nil || len(events) != 3 {
+		t.Fatalf("FindSandboxEvents() = %+v, %v, want the newest 3", events, err)
+	}
+	if events[0].CreatedAt != 2 || events[2].Type != "exit" || events[2].ExitCode == nil || *events[2].ExitCode != 137 {
+		t.Fatalf("FindSandboxEvents() = %+v", events)
+	}
+
+	if err := repo.DeleteSandboxEventsBySandbox("sb1"); err != nil {
+		t.Fatalf("DeleteSandboxEventsBySandbox() error: %v", err)
+	}
+	if events, _ := repo.FindSandboxEvents("sb1"); len(events) != 0 {
+		t.Fatalf("FindSandboxEvents() after delete = %+v", events)
+	}
+	if events, _ := repo.FindSandboxEvents("sb2"); len(events) != 1 {
+		t.Fatalf("FindSandboxEvents(sb2) = %+v, other sandboxes are kept", events)
+	}
+}
+
+func TestRepositorySchedules(t *testing.T) {
+	repo := newTestRepo(t)
+
+	if err := repo.SaveSchedule(Schedule{Name: "nightly", OwnerID: "team-a", Cron: "@daily", NextRunAt: 100}); err != nil {
+		t.Fatalf("SaveSchedule() error: %v", err)
+	}
+	if err := repo.SaveSchedule(Schedule{Name: "nightly"}); err == nil {
+		t.Fatal("SaveSchedule() accepted a duplicate name")
+	}
+	repo.SaveSchedule(Schedule{Name: "hourly", OwnerID: "team-b", Cron: "@hourly", NextRunAt: 300})
+
+	if s, err := repo.FindSchedule("nightly"); err != nil || s == nil || s.OwnerID != "team-a" {
+		t.Fatalf("FindSchedule() = %+v, %v", s, err)
+	}
+	if s, _ := repo.FindSchedule("missing"); s != nil {
+		t.Fatalf("FindSchedule(missing) = %+v, want nil", s)
+	}
+	if owned, _ := repo.FindSchedules("team-b"); len(owned) != 1 || owned[0].Name != "hourly" {
+		t.Fatalf("FindSchedules(team-b) = %+v", owned)
+	}
+	if due, _ := repo.FindDueSchedules(200); len(due) != 1 || due[0].Name != "nightly" {
+		t.Fatalf("FindDueSchedules(200) = %+v, want nightly", due)
+	}
+	repo.UpdateScheduleNextRun("nightly", 500)
+	if due, _ := repo.FindDueSchedules(400); len(due) != 1 || due[0].Name != "hourly" {
+		t.Fatalf("FindDueSchedules(400) = %+v, want hourly", due)
+	}
+
+	for i := range 4 {
+		run := &ScheduleRun{Schedule: "nightly", Status: "running", StartedAt: int64(i)}
+		if err := repo.SaveScheduleRun(run, 3); err != nil || run.ID == 0 {
+			t.Fatalf("SaveScheduleRun() = %d, %v", run.ID, err)
+		}
+		if i < 3 {
+			code := i
+			run.Status, run.ExitCode = "succeeded", &code
+			repo.UpdateScheduleRun(*run)
+		}
+	}
+	runs, err := repo.FindScheduleRuns("nightly")
+	if err != nil || len(runs) != 3 || runs[0].Status != "running" || *runs[1].ExitCode != 2 {
+		t.Fatalf("FindScheduleRuns() = %+v, %v, want the newest 3", runs, err)
+	}
+	if n, err := repo.FailRunningScheduleRuns("restarted", 9); err != nil || n != 1 {
+		t.Fatalf("FailRunningScheduleRuns() = %d, %v", n, err)
+	}
+	if runs, _ := repo.FindScheduleRuns("nightly"); runs[0].Status != "error" || runs[0].Error != "restarted" || runs[0].FinishedAt != 9 {
+		t.Fatalf("interrupted run = %+v", runs[0])
+	}
+
+	if err := repo.DeleteSchedule("nightly"); err != nil {
+		t.Fatalf("DeleteSchedule() error: %v", err)
+	}
+	if s, _ := repo.FindSchedule("nightly"); s != nil {
+		t.Fatal("schedule still exists after DeleteSchedule()")
+	}
+	repo.UpdateScheduleRun(runs[0])
+	if runs, _ := repo.FindScheduleRuns("nightly"); len(runs) != 0 {
+		t.Fatalf("runs left after DeleteSchedule(): %+v", runs)
+	}
+}
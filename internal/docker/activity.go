@@ -0,0 +1,61 @@
+package docker
+
+import "time"
+
+// Timeout modes chosen per sandbox at create time.
+const (
+	TimeoutAbsolute = "absolute" // expire a fixed time after create, start or renew (default)
+	TimeoutIdle     = "idle"     // expire after a period without exec, file or proxy activity
+)
+
+// touchResolution bounds how often activity re-arms an idle timer, so a busy
+// proxy does not reset it on every request.
+const touchResolution = time.Second
+
+// Touch records activity on a sandbox. For idle-mode sandboxes the auto-stop
+// timer restarts its full window; absolute-mode sandboxes are unaffected.
+func (c *Client) Touch(id string) {
+	entry := c.getTimerEntry(id)
+	if entry == nil || !entry.idle {
+		return
+	}
+	now := time.Now()
+	window := clampToLifetime(entry.window, entry.end, now)
+	next := now.Add(window)
+	if next.Sub(entry.expiresAt) < touchResolution {
+		return
+	}
+	if !entry.timer.Stop() {
+		return // already fired or cancelled
+	}
+	entry.timer.Reset(window)
+
+	// Entries are replaced rather than mutated so readers never race on expiresAt.
+	updated := *entry
+	updated.expiresAt = next
+	c.timers.CompareAndSwap(id, entry, &updated)
+}
+
+// TouchByName records activity on the idle-mode sandbox with the given name.
+// Used by the reverse proxy, which routes by name.
+func (c *Client) TouchByName(name string) {
+	c.timers.Range(func(key, value any) bool {
+		entry := value.(*timerEntry)
+		if entry.idle && entry.name == name {
+			c.Touch(key.(string))
+			return false
+		}
+		return true
+	})
+}
+
+// rescheduleStop re-arms the auto-stop timer after a start, restart or renew,
+// keeping the timeout mode and max lifetime the sandbox was created with.
+func (c *Client) rescheduleStop(id string, seconds int) {
+	name, idle := "", false
+	sb, _ := c.repo.FindByID(id)
+	if sb != nil {
+		name, idle = sb.Name, sb.TimeoutMode == TimeoutIdle
+	}
+	c.scheduleStop(id, name, seconds, idle, lifetimeEnd(sb))
+}
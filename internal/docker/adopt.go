@@ -0,0 +1,91 @@
+package docker
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"opensbx/internal/database"
+
+	moby "github.com/moby/moby/client"
+)
+
+// AdoptContainers records managed containers missing from the database, e.g.
+// after the database file was lost, using their labels. Adopted sandboxes get
+// no auto-stop timer until they are started again, and containers whose name
+// is taken by another sandbox are skipped. Returns how many were adopted.
+func (c *Client) AdoptContainers(ctx context.Context) (int, error) {
+	result, err := c.cli.ContainerList(ctx, moby.ContainerListOptions{All: true, Filters: managedFilter()})
+	if err != nil {
+		return 0, err
+	}
+
+	adopted := 0
+	for _, item := range result.Items {
+		if sb, err := c.repo.FindByID(item.ID); err != nil {
+			return adopted, err
+		} else if sb != nil {
+			continue
+		}
+
+		info, err := c.cli.ContainerInspect(ctx, item.ID, moby.ContainerInspectOptions{})
+		if err != nil {
+			continue // removed in the meantime
+		}
+		ctr := info.Container
+		name := ctr.Config.Labels[LabelName]
+		if other, err := c.repo.FindByName(name); err != nil {
+			return adopted, err
+		} else if other != nil {
+			slog.Warn("not adopting container: name in use", "container_id", ctr.ID, "name", name, "sandbox_id", other.ID)
+			continue
+		}
+
+		// The requested port order is lost, so the lowest exposed port is the main one.
+		ports := extractPorts(ctr.NetworkSettings.Ports)
+		mainPort := ""
+		for p := range ctr.Config.ExposedPorts {
+			if mainPort == "" || p.String() < mainPort {
+				mainPort = p.String()
+			}
+		}
+		state := database.SandboxStopped
+		if ctr.State.Running {
+			state = database.SandboxRunning
+		}
+		created, _ := time.Parse(time.RFC3339Nano, ctr.Created)
+
+		if err := c.repo.Save(database.Sandbox{
+			ID:               ctr.ID,
+			Name:             name,
+			Image:            ctr.Config.Image,
+			Ports:            database.JSONMap(ports),
+			Port:             mainPort,
+			ExpirationAction: ctr.Config.Labels[LabelExpirationAction],
+			OwnerID:          ctr.Config.Labels[LabelOwner],
+			Memory:           ctr.HostConfig.Memory / (1024 * 1024),
+			CPUs:             float64(ctr.HostConfig.NanoCPUs) / 1e9,
+			Runtime:          ctr.HostConfig.Runtime,
+			Network:          adoptedNetwork(string(ctr.HostConfig.NetworkMode)),
+			Labels:           database.JSONMap(callerLabels(ctr.Config.Labels)),
+			CreatedAt:        created.UnixMilli(),
+			State:            state,
+		}); err != nil {
+			return adopted, err
+		}
+		slog.Info("adopted sandbox container", "sandbox_id", ctr.ID, "name", name)
+		adopted++
+	}
+	return adopted, nil
+}
+
+// adoptedNetwork maps a container's network mode back to a sandbox network setting.
+func adoptedNetwork(mode string) string {
+	switch mode {
+	case NetworkNone:
+		return NetworkNone
+	case DefaultInternalNetwork:
+		return NetworkInternal
+	}
+	return ""
+}
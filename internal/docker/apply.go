@@ -0,0 +1,111 @@
+package docker
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"opensbx/models"
+)
+
+// Apply actions reported per sandbox.
+const (
+	ApplyCreated   = "created"
+	ApplyUpdated   = "updated"
+	ApplyUnchanged = "unchanged"
+	ApplyDeleted   = "deleted"
+	ApplyFailed    = "failed"
+)
+
+// Apply reconciles sandboxes to match the declared spec. Sandboxes are matched by
+// name: missing ones are created, changed ones are recreated, and with Prune set,
+// apply-managed sandboxes absent from the spec are removed. Sandboxes created
+// through POST /v1/sandboxes are never touched, and callers scoped to an owner
+// only reconcile their own sandboxes. Per-sandbox failures are reported
+// in the result; the returned error is reserved for database failures.
+func (c *Client) Apply(ctx context.Context, req models.ApplyRequest) (models.ApplyResponse, error) {
+	resp := models.ApplyResponse{Results: []models.ApplyResult{}}
+	declared := make(map[string]bool, len(req.Sandboxes))
+
+	for _, spec := range req.Sandboxes {
+		declared[spec.Name] = true
+		resp.Results = append(resp.Results, c.applyOne(ctx, spec))
+	}
+
+	if !req.Prune {
+		return resp, nil
+	}
+
+	managed, err := c.repo.FindManaged()
+	if err != nil {
+		return resp, err
+	}
+	owner := OwnerFrom(ctx)
+	for _, sb := range managed {
+		if declared[sb.Name] || (owner != "" && sb.OwnerID != owner) {
+			continue
+		}
+		result := models.ApplyResult{Name: sb.Name, ID: sb.ID, Action: ApplyDeleted}
+		if err := c.Remove(ctx, sb.ID); err != nil {
+			result.Action, result.Error = ApplyFailed, err.Error()
+		}
+		resp.Results = append(resp.Results, result)
+	}
+	return resp, nil
+}
+
+// applyOne converges a single declared sandbox.
+func (c *Client) applyOne(ctx context.Context, spec models.ApplySandboxSpec) models.ApplyResult {
+	result := models.ApplyResult{Name: spec.Name, Action: ApplyCreated}
+	fail := func(err error) models.ApplyResult {
+		result.Action, result.Error = ApplyFailed, err.Error()
+		return result
+	}
+
+	hash := specHash(spec)
+	existing, err := c.repo.FindByName(spec.Name)
+	if err != nil {
+		return fail(err)
+	}
+	if existing != nil {
+		if owner := OwnerFrom(ctx); owner != "" && existing.OwnerID != owner {
+			return fail(fmt.Errorf("name %q is already in use", spec.Name))
+		}
+		result.ID = existing.ID
+		switch existing.SpecHash {
+		case "":
+			return fail(fmt.Errorf("name %q is used by a sandbox not managed by apply", spec.Name))
+		case hash:
+			result.Action = ApplyUnchanged
+			return result
+		}
+		if err := c.Remove(ctx, existing.ID); err != nil {
+			return fail(err)
+		}
+		result.Action = ApplyUpdated
+	}
+
+	created, err := c.create(ctx, spec.CreateRequest(), createOptions{name: spec.Name, specHash: hash})
+	if err != nil {
+		return fail(err)
+	}
+	result.ID = created.ID
+
+	for _, f := range spec.Files {
+		if err := c.WriteFile(ctx, created.ID, f.Path, f.Content); err != nil {
+			// Don't leave a half-seeded sandbox marked as matching the spec.
+			c.Remove(ctx, created.ID)
+			return fail(fmt.Errorf("seed %s: %w", f.Path, err))
+		}
+	}
+	return result
+}
+
+// specHash fingerprints a sandbox spec so unchanged sandboxes are left alone.
+func specHash(spec models.ApplySandboxSpec) string {
+	b, _ := json.Marshal(spec)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:8])
+}
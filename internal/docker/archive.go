@@ -0,0 +1,304 @@
+package docker
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/containerd/errdefs"
+	moby "github.com/moby/moby/client"
+)
+
+// Archive formats accepted by UploadArchive and produced by DownloadArchive.
+const (
+	ArchiveTar = "tar"
+	ArchiveZip = "zip"
+)
+
+// UploadArchive extracts a tar or zip archive into dir inside a sandbox,
+// creating dir if needed. Uses Docker's archive API, so contents are binary-safe.
+func (c *Client) UploadArchive(ctx context.Context, id, dir string, r io.Reader, format string) error {
+	c.Touch(id)
+	if _, err := c.cli.ContainerInspect(ctx, id, moby.ContainerInspectOptions{}); err != nil {
+		return wrapNotFound(err)
+	}
+	if err := c.mkdirAll(ctx, id, dir); err != nil {
+		return err
+	}
+
+	content := r
+	if format == ArchiveZip {
+		// zip needs random access to its central directory: spool to disk first.
+		tmp, err := os.CreateTemp("", "opensbx-upload-*.zip")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(tmp.Name())
+		defer tmp.Close()
+
+		size, err := io.Copy(tmp, r)
+		if err != nil {
+			return err
+		}
+		zr, err := zip.NewReader(tmp, size)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+
+		pr, pw := io.Pipe()
+		go func() { pw.CloseWithError(zipToTar(zr, pw)) }()
+		defer pr.Close()
+		content = pr
+	}
+
+	_, err := c.cli.CopyToContainer(ctx, id, moby.CopyToContainerOptions{
+		DestinationPath: dir,
+		Content:         content,
+	})
+	if err != nil {
+		if errdefs.IsInvalidArgument(err) {
+			return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// DownloadArchive writes path (file or directory) from a sandbox to w as a tar
+// or zip archive. Nothing is written to w if the sandbox or path does not exist.
+func (c *Client) DownloadArchive(ctx context.Context, id, path, format string, w io.Writer) error {
+	c.Touch(id)
+	if _, err := c.cli.ContainerInspect(ctx, id, moby.ContainerInspectOptions{}); err != nil {
+		return wrapNotFound(err)
+	}
+
+	result, err := c.cli.CopyFromContainer(ctx, id, moby.CopyFromContainerOptions{SourcePath: path})
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return ErrPathNotFound
+		}
+		return err
+	}
+	defer result.Content.Close()
+
+	if format == ArchiveZip {
+		return tarToZip(tar.NewReader(result.Content), w)
+	}
+	_, err = io.Copy(w, result.Content)
+	return err
+}
+
+// ReadFileStream returns the raw bytes of a regular file inside a sandbox and its size.
+// Uses Docker's archive API, so binary content and any path characters are safe.
+func (c *Client) ReadFileStream(ctx context.Context, id, path string) (io.ReadCloser, int64, error) {
+	c.Touch(id)
+	result, err := c.cli.CopyFromContainer(ctx, id, moby.CopyFromContainerOptions{SourcePath: path})
+	if err != nil {
+		return nil, 0, c.pathError(ctx, id, err)
+	}
+	// The archive API returns symlinks themselves; follow once to the resolved target.
+	if result.Stat.Mode&os.ModeSymlink != 0 && result.Stat.LinkTarget != "" && result.Stat.LinkTarget != path {
+		result.Content.Close()
+		return c.ReadFileStream(ctx, id, result.Stat.LinkTarget)
+	}
+	if !result.Stat.Mode.IsRegular() {
+		result.Content.Close()
+		return nil, 0, fmt.Errorf("%w: %s", ErrNotAFile, path)
+	}
+
+	tr := tar.NewReader(result.Content)
+	hdr, err := tr.Next()
+	if err != nil {
+		result.Content.Close()
+		return nil, 0, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{tr, result.Content}, hdr.Size, nil
+}
+
+// mkdirAll creates dir and its parents inside a sandbox.
+func (c *Client) mkdirAll(ctx context.Context, id, dir string) error {
+	result, err := c.execWithStdin(ctx, id, []string{"mkdir", "-p", dir}, nil)
+	if err != nil {
+		return err
+	}
+	return result.check()
+}
+
+// pathError maps an archive API error for a path inside a sandbox. Docker
+// reports a missing container and a missing path alike, so a not-found error
+// becomes ErrNotFound or ErrPathNotFound depending on whether the sandbox exists.
+func (c *Client) pathError(ctx context.Context, id string, err error) error {
+	if !errdefs.IsNotFound(err) {
+		return err
+	}
+	if _, ierr := c.cli.ContainerInspect(ctx, id, moby.ContainerInspectOptions{}); ierr != nil {
+		return wrapNotFound(ierr)
+	}
+	return ErrPathNotFound
+}
+
+// WriteFileStream writes r to path inside a sandbox, creating parent directories.
+// A negative size spools r to disk first, since tar headers need the length up front.
+func (c *Client) WriteFileStream(ctx context.Context, id, filePath string, r io.Reader, size int64) error {
+	c.Touch(id)
+	if _, err := c.cli.ContainerInspect(ctx, id, moby.ContainerInspectOptions{}); err != nil {
+		return wrapNotFound(err)
+	}
+
+	if size < 0 {
+		tmp, err := os.CreateTemp("", "opensbx-file-*")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(tmp.Name())
+		defer tmp.Close()
+
+		if size, err = io.Copy(tmp, r); err != nil {
+			return err
+		}
+		if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		r = tmp
+	}
+
+	dir, name := path.Split(path.Clean(filePath))
+	if name == "" || name == "/" {
+		return fmt.Errorf("%w: %s", ErrNotAFile, filePath)
+	}
+	if err := c.mkdirAll(ctx, id, dir); err != nil {
+		return err
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		tw := tar.NewWriter(pw)
+		err := tw.WriteHeader(&tar.Header{
+			Name:     name,
+			Mode:     0o644,
+			Size:     size,
+			ModTime:  time.Now(),
+			Typeflag: tar.TypeReg,
+		})
+		if err == nil {
+			_, err = io.CopyN(tw, r, size)
+		}
+		if err == nil {
+			err = tw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+	defer pr.Close()
+
+	_, err := c.cli.CopyToContainer(ctx, id, moby.CopyToContainerOptions{
+		DestinationPath: dir,
+		Content:         pr,
+	})
+	return err
+}
+
+// zipToTar re-encodes a zip archive as a tar stream.
+func zipToTar(zr *zip.Reader, w io.Writer) error {
+	tw := tar.NewWriter(w)
+	for _, f := range zr.File {
+		hdr, err := tar.FileInfoHeader(f.FileInfo(), "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = strings.TrimPrefix(f.Name, "/")
+
+		if f.FileInfo().Mode()&os.ModeSymlink != 0 {
+			target, err := readZipFile(f)
+			if err != nil {
+				return err
+			}
+			hdr.Typeflag = tar.TypeSymlink
+			hdr.Linkname = string(target)
+			hdr.Size = 0
+		}
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(tw, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+// tarToZip re-encodes a tar stream as a zip archive. Symlinks are stored with
+// their target as content, the same convention zip tools use.
+func tarToZip(tr *tar.Reader, w io.Writer) error {
+	zw := zip.NewWriter(w)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir, tar.TypeReg, tar.TypeSymlink:
+		default:
+			continue // devices, fifos, hard links: not representable
+		}
+
+		zh, err := zip.FileInfoHeader(hdr.FileInfo())
+		if err != nil {
+			return err
+		}
+		zh.Name = hdr.Name
+		if hdr.Typeflag == tar.TypeDir && !strings.HasSuffix(zh.Name, "/") {
+			zh.Name += "/"
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			zh.Method = zip.Deflate
+		}
+
+		fw, err := zw.CreateHeader(zh)
+		if err != nil {
+			return err
+		}
+		switch hdr.Typeflag {
+		case tar.TypeReg:
+			if _, err := io.Copy(fw, tr); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if _, err := io.WriteString(fw, hdr.Linkname); err != nil {
+				return err
+			}
+		}
+	}
+	return zw.Close()
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
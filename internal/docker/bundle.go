@@ -0,0 +1,414 @@
+package docker
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"time"
+
+	"opensbx/models"
+
+	"github.com/distribution/reference"
+	moby "github.com/moby/moby/client"
+)
+
+// Entries inside a sandbox bundle. metadata.json always comes first so importers
+// can validate the bundle before streaming the (large) image.
+const (
+	bundleMetadataFile = "metadata.json"
+	bundleImageFile    = "image.tar"
+	bundleVersion      = 1
+)
+
+// Imported images are loaded under importRepo with a tag of their own, so a
+// bundle can never retag an image that already exists on the host.
+const (
+	importRepo      = "opensbx-import"
+	maxManifestSize = 1 << 20 // bound on manifest.json inside image.tar
+)
+
+// Export writes a portable bundle for a sandbox to w: a tar archive containing
+// metadata.json and image.tar (a docker save of the sandbox filesystem committed
+// as an image). Nothing is written to w if preparing the bundle fails.
+func (c *Client) Export(ctx context.Context, id string, w io.Writer) error {
+	detail, err := c.Inspect(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	ports := detail.Ports
+	if sb, _ := c.repo.FindByID(detail.ID); sb != nil && sb.Port != "" {
+		ports = mainPortFirst(ports, sb.Port)
+	}
+
+	ref := fmt.Sprintf("opensbx-export/%s:%d", detail.Name, time.Now().Unix())
+	if _, err := c.cli.ContainerCommit(ctx, detail.ID, moby.ContainerCommitOptions{
+		Reference: ref,
+		Comment:   "opensbx export of " + detail.Name,
+	}); err != nil {
+		return fmt.Errorf("commit sandbox: %w", err)
+	}
+	// The tag only exists to feed docker save; the bundle carries the layers.
+	defer c.cli.ImageRemove(context.WithoutCancel(ctx), ref, moby.ImageRemoveOptions{PruneChildren: true})
+
+	// docker save streams without a known length, but tar headers need one: spool to disk.
+	tmp, err := os.CreateTemp("", "opensbx-export-*.tar")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	saved, err := c.cli.ImageSave(ctx, []string{ref})
+	if err != nil {
+		return fmt.Errorf("save image: %w", err)
+	}
+	size, err := io.Copy(tmp, saved)
+	saved.Close()
+	if err != nil {
+		return fmt.Errorf("save image: %w", err)
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	meta, err := json.MarshalIndent(models.SandboxBundleMetadata{
+		Version:     bundleVersion,
+		Name:        detail.Name,
+		SourceImage: detail.Image,
+		Image:       ref,
+		Ports:       ports,
+		Resources:   detail.Resources,
+		Labels:      detail.Labels,
+		ExportedAt:  time.Now().UTC(),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tw := tar.NewWriter(w)
+	if err := writeTarFile(tw, bundleMetadataFile, int64(len(meta)), bytes.NewReader(meta)); err != nil {
+		return err
+	}
+	if err := writeTarFile(tw, bundleImageFile, size, tmp); err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// Import creates a sandbox from an uploaded archive. A bundle produced by
+// Export keeps the exported ports, resource limits and labels; a plain docker
+// save or docker export tarball gets the defaults of a create request. The
+// image is loaded under a fresh importRepo tag instead of any tag in the
+// archive, so importing never retags an image that already exists.
+func (c *Client) Import(ctx context.Context, r io.Reader) (models.CreateSandboxResponse, error) {
+	br := bufio.NewReader(r)
+	if !isBundle(br) {
+		return c.importArchive(ctx, br)
+	}
+	tr := tar.NewReader(br)
+
+	hdr, err := tr.Next()
+	if err != nil || hdr.Name != bundleMetadataFile {
+		return models.CreateSandboxResponse{}, fmt.Errorf("%w: %s must be the first entry", ErrInvalidBundle, bundleMetadataFile)
+	}
+	var meta models.SandboxBundleMetadata
+	if err := json.NewDecoder(tr).Decode(&meta); err != nil {
+		return models.CreateSandboxResponse{}, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+	}
+	if meta.Version != bundleVersion || meta.Image == "" {
+		return models.CreateSandboxResponse{}, fmt.Errorf("%w: unsupported metadata (version %d)", ErrInvalidBundle, meta.Version)
+	}
+	if msg := ValidateLabels(meta.Labels); msg != "" {
+		return models.CreateSandboxResponse{}, fmt.Errorf("%w: %s", ErrInvalidBundle, msg)
+	}
+	resources := meta.Resources
+	// Checked again by Create, but rejecting here skips loading the image.
+	if err := c.CheckQuota(ctx, c.quotaResources(&resources, c.runtime)); err != nil {
+		return models.CreateSandboxResponse{}, err
+	}
+
+	hdr, err = tr.Next()
+	if err != nil || hdr.Name != bundleImageFile {
+		return models.CreateSandboxResponse{}, fmt.Errorf("%w: missing %s", ErrInvalidBundle, bundleImageFile)
+	}
+
+	// The archive is rewritten before loading, and its manifest comes last: spool to disk.
+	tmp, err := os.CreateTemp("", "opensbx-import-*.tar")
+	if err != nil {
+		return models.CreateSandboxResponse{}, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	ref := importRef()
+	if err := retagImageArchive(tr, tmp, meta.Image, ref); err != nil {
+		return models.CreateSandboxResponse{}, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return models.CreateSandboxResponse{}, err
+	}
+	if err := c.loadImage(ctx, tmp); err != nil {
+		return models.CreateSandboxResponse{}, err
+	}
+	return c.createImported(ctx, models.CreateSandboxRequest{
+		Image:     ref,
+		Ports:     meta.Ports,
+		Resources: &resources,
+		Labels:    meta.Labels,
+	})
+}
+
+// isBundle reports whether the archive in r starts with a bundle's
+// metadata.json, without consuming it.
+func isBundle(r *bufio.Reader) bool {
+	block, err := r.Peek(512) // one tar header
+	if err != nil {
+		return false
+	}
+	hdr, err := tar.NewReader(bytes.NewReader(block)).Next()
+	return err == nil && hdr.Name == bundleMetadataFile
+}
+
+// importArchive creates a sandbox from a plain archive: a docker save
+// archive, loaded like a bundle's image.tar, or a docker export root
+// filesystem, imported as a single-layer image. Neither carries ports or
+// resource limits, so the sandbox gets the defaults.
+func (c *Client) importArchive(ctx context.Context, r io.Reader) (models.CreateSandboxResponse, error) {
+	if err := c.CheckQuota(ctx, c.quotaResources(nil, c.runtime)); err != nil {
+		return models.CreateSandboxResponse{}, err
+	}
+
+	// Only a manifest.json, which may come last, tells the two formats apart: spool to disk.
+	upload, err := os.CreateTemp("", "opensbx-import-*.tar")
+	if err != nil {
+		return models.CreateSandboxResponse{}, err
+	}
+	defer os.Remove(upload.Name())
+	defer upload.Close()
+	if _, err := io.Copy(upload, r); err != nil {
+		return models.CreateSandboxResponse{}, err
+	}
+	if _, err := upload.Seek(0, io.SeekStart); err != nil {
+		return models.CreateSandboxResponse{}, err
+	}
+	saved, err := isImageArchive(upload)
+	if err != nil {
+		return models.CreateSandboxResponse{}, err
+	}
+	if _, err := upload.Seek(0, io.SeekStart); err != nil {
+		return models.CreateSandboxResponse{}, err
+	}
+
+	ref := importRef()
+	if saved {
+		tmp, err := os.CreateTemp("", "opensbx-import-*.tar")
+		if err != nil {
+			return models.CreateSandboxResponse{}, err
+		}
+		defer os.Remove(tmp.Name())
+		defer tmp.Close()
+		if err := retagImageArchive(upload, tmp, "", ref); err != nil {
+			return models.CreateSandboxResponse{}, err
+		}
+		if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+			return models.CreateSandboxResponse{}, err
+		}
+		if err := c.loadImage(ctx, tmp); err != nil {
+			return models.CreateSandboxResponse{}, err
+		}
+	} else {
+		imported, err := c.cli.ImageImport(ctx, moby.ImageImportSource{Source: upload, SourceName: "-"}, ref, moby.ImageImportOptions{Message: "opensbx import"})
+		if err != nil {
+			return models.CreateSandboxResponse{}, fmt.Errorf("import image: %w", err)
+		}
+		_, err = io.Copy(io.Discard, imported)
+		imported.Close()
+		if err != nil {
+			return models.CreateSandboxResponse{}, fmt.Errorf("import image: %w", err)
+		}
+	}
+	return c.createImported(ctx, models.CreateSandboxRequest{Image: ref})
+}
+
+// isImageArchive reports whether the tar archive in r is a docker save
+// archive, which has a manifest.json at its root, rather than a root
+// filesystem. An empty or malformed archive is ErrInvalidBundle.
+func isImageArchive(r io.Reader) (bool, error) {
+	tr := tar.NewReader(r)
+	entries := 0
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return false, fmt.Errorf("%w: not a tar archive: %v", ErrInvalidBundle, err)
+		}
+		if path.Clean(hdr.Name) == "manifest.json" {
+			return true, nil
+		}
+		entries++
+	}
+	if entries == 0 {
+		return false, fmt.Errorf("%w: empty archive", ErrInvalidBundle)
+	}
+	return false, nil
+}
+
+// loadImage loads the docker save archive r.
+func (c *Client) loadImage(ctx context.Context, r io.Reader) error {
+	loaded, err := c.cli.ImageLoad(ctx, r, moby.ImageLoadWithQuiet(true))
+	if err != nil {
+		return fmt.Errorf("load image: %w", err)
+	}
+	_, err = io.Copy(io.Discard, loaded)
+	loaded.Close()
+	if err != nil {
+		return fmt.Errorf("load image: %w", err)
+	}
+	return nil
+}
+
+// createImported creates the sandbox of an import from the image it loaded,
+// removing the image if that fails. The image is left to image garbage
+// collection once the sandbox is gone.
+func (c *Client) createImported(ctx context.Context, req models.CreateSandboxRequest) (models.CreateSandboxResponse, error) {
+	c.trackImage(ctx, req.Image)
+	resp, err := c.Create(ctx, req)
+	if err != nil {
+		c.cli.ImageRemove(context.WithoutCancel(ctx), req.Image, moby.ImageRemoveOptions{PruneChildren: true})
+	}
+	return resp, err
+}
+
+// importRef returns a unique reference to load an imported image under.
+func importRef() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return fmt.Sprintf("%s/%s:%d", importRepo, hex.EncodeToString(b), time.Now().Unix())
+}
+
+// retagImageArchive copies the docker save archive r to w with its image
+// tagged ref instead of want. The archive must hold exactly one image, tagged
+// want and nothing else, or ErrInvalidBundle is returned. An empty want
+// accepts the image whatever its tags. The legacy
+// repositories file and the OCI index, which could apply tags of their own,
+// are dropped, so the loader only reads the rewritten manifest.json.
+func retagImageArchive(r io.Reader, w io.Writer, want, ref string) error {
+	tr := tar.NewReader(r)
+	tw := tar.NewWriter(w)
+	var manifest []byte
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("%w: image archive: %v", ErrInvalidBundle, err)
+		}
+		switch path.Clean(hdr.Name) {
+		case "manifest.json":
+			if manifest != nil {
+				return fmt.Errorf("%w: image archive has more than one manifest.json", ErrInvalidBundle)
+			}
+			if manifest, err = io.ReadAll(io.LimitReader(tr, maxManifestSize+1)); err != nil {
+				return fmt.Errorf("%w: image archive: %v", ErrInvalidBundle, err)
+			}
+			if len(manifest) > maxManifestSize {
+				return fmt.Errorf("%w: manifest.json exceeds %d bytes", ErrInvalidBundle, maxManifestSize)
+			}
+			continue
+		case "repositories", "index.json", "oci-layout":
+			continue
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := io.Copy(tw, tr); err != nil {
+			return fmt.Errorf("%w: image archive: %v", ErrInvalidBundle, err)
+		}
+	}
+	if manifest == nil {
+		return fmt.Errorf("%w: image archive has no manifest.json", ErrInvalidBundle)
+	}
+
+	// Unknown fields are kept as they are; only RepoTags is replaced.
+	var images []map[string]json.RawMessage
+	if err := json.Unmarshal(manifest, &images); err != nil {
+		return fmt.Errorf("%w: manifest.json: %v", ErrInvalidBundle, err)
+	}
+	if len(images) != 1 {
+		return fmt.Errorf("%w: image archive must hold exactly one image, got %d", ErrInvalidBundle, len(images))
+	}
+	if want != "" {
+		var tags []string
+		if err := json.Unmarshal(images[0]["RepoTags"], &tags); err != nil || len(tags) != 1 || !sameImageRef(tags[0], want) {
+			return fmt.Errorf("%w: the image in %s must be tagged %s and nothing else", ErrInvalidBundle, bundleImageFile, want)
+		}
+	}
+	retagged, err := json.Marshal([]string{ref})
+	if err != nil {
+		return err
+	}
+	images[0]["RepoTags"] = retagged
+	out, err := json.Marshal(images)
+	if err != nil {
+		return err
+	}
+	if err := writeTarFile(tw, "manifest.json", int64(len(out)), bytes.NewReader(out)); err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// sameImageRef reports whether a and b name the same image tag once
+// normalized, so "ubuntu" matches "docker.io/library/ubuntu:latest".
+func sameImageRef(a, b string) bool {
+	na, err := reference.ParseNormalizedNamed(a)
+	if err != nil {
+		return false
+	}
+	nb, err := reference.ParseNormalizedNamed(b)
+	if err != nil {
+		return false
+	}
+	return reference.TagNameOnly(na).String() == reference.TagNameOnly(nb).String()
+}
+
+func writeTarFile(tw *tar.Writer, name string, size int64, r io.Reader) error {
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0o644,
+		Size:    size,
+		ModTime: time.Now(),
+	}); err != nil {
+		return err
+	}
+	_, err := io.Copy(tw, r)
+	return err
+}
+
+// mainPortFirst moves the routing port to the front so Create keeps it as the default.
+func mainPortFirst(ports []string, main string) []string {
+	out := make([]string, 0, len(ports))
+	for _, p := range ports {
+		if normalizePort(p) == normalizePort(main) {
+			out = append([]string{p}, out...)
+			continue
+		}
+		out = append(out, p)
+	}
+	return out
+}
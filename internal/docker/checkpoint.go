@@ -0,0 +1,146 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"time"
+
+	"opensbx/internal/database"
+	"opensbx/models"
+
+	"github.com/moby/moby/api/types/checkpoint"
+	moby "github.com/moby/moby/client"
+)
+
+// checkpointNamePattern matches the checkpoint names Docker accepts.
+var checkpointNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]{0,127}$`)
+
+// checkpointName returns the requested checkpoint name, or a timestamped
+// default, and rejects names Docker would not accept.
+func checkpointName(name string, now time.Time) (string, error) {
+	if name == "" {
+		return fmt.Sprintf("cp-%d", now.Unix()), nil
+	}
+	if !checkpointNamePattern.MatchString(name) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidCheckpoint, name)
+	}
+	return name, nil
+}
+
+// Checkpoint saves the memory and process state of a running or paused
+// sandbox with CRIU. Unless req.LeaveRunning is set, the sandbox is stopped
+// once its state is saved, and Restore resumes it from there. Requires CRIU
+// and a Docker daemon with experimental features enabled.
+func (c *Client) Checkpoint(ctx context.Context, id string, req models.CheckpointRequest) (models.Checkpoint, error) {
+	name, err := checkpointName(req.Name, time.Now())
+	if err != nil {
+		return models.Checkpoint{}, err
+	}
+	info, err := c.cli.ContainerInspect(ctx, id, moby.ContainerInspectOptions{})
+	if err != nil {
+		return models.Checkpoint{}, wrapNotFound(err)
+	}
+	if !info.Container.State.Running {
+		return models.Checkpoint{}, ErrNotRunning
+	}
+
+	if !req.LeaveRunning {
+		c.cancelTimer(id)
+		c.cancelReady(info.Container.ID)
+		c.invalidateCache(id)
+	}
+	_, err = c.cli.CheckpointCreate(ctx, info.Container.ID, moby.CheckpointCreateOptions{
+		CheckpointID: name,
+		Exit:         !req.LeaveRunning,
+	})
+	if err != nil {
+		if !req.LeaveRunning {
+			c.rescheduleStop(id, defaultTimeout)
+			c.watchReady(ctx, info.Container.ID)
+		}
+		return models.Checkpoint{}, fmt.Errorf("checkpoint sandbox: %w", wrapNotFound(err))
+	}
+	if !req.LeaveRunning {
+		c.setState(ctx, info.Container.ID, database.SandboxStopped)
+	}
+	return models.Checkpoint{Name: name}, nil
+}
+
+// ListCheckpoints returns the checkpoints saved for a sandbox.
+func (c *Client) ListCheckpoints(ctx context.Context, id string) ([]models.Checkpoint, error) {
+	items, err := c.checkpoints(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]models.Checkpoint, 0, len(items))
+	for _, cp := range items {
+		out = append(out, models.Checkpoint{Name: cp.Name})
+	}
+	return out, nil
+}
+
+// Restore starts a stopped sandbox from a checkpoint, with the memory and
+// processes it had when the checkpoint was taken.
+// Returns ErrAlreadyRunning (409) if the sandbox is running.
+func (c *Client) Restore(ctx context.Context, id, name string) (models.RestartResponse, error) {
+	id, unlock, err := c.lockResolved(ctx, id, "restore")
+	if err != nil {
+		return models.RestartResponse{}, err
+	}
+	defer unlock()
+
+	info, err := c.cli.ContainerInspect(ctx, id, moby.ContainerInspectOptions{})
+	if err != nil {
+		return models.RestartResponse{}, wrapNotFound(err)
+	}
+	if info.Container.State.Running {
+		return models.RestartResponse{}, ErrAlreadyRunning
+	}
+	if err := c.checkLifetime(info.Container.ID); err != nil {
+		return models.RestartResponse{}, err
+	}
+	if err := c.checkpointExists(ctx, info.Container.ID, name); err != nil {
+		return models.RestartResponse{}, err
+	}
+	if err := c.checkStartQuota(ctx, info.Container.ID); err != nil {
+		return models.RestartResponse{}, err
+	}
+
+	if _, err := c.cli.ContainerStart(ctx, info.Container.ID, moby.ContainerStartOptions{CheckpointID: name}); err != nil {
+		return models.RestartResponse{}, fmt.Errorf("restore sandbox: %w", wrapNotFound(err))
+	}
+	return c.started(ctx, id, info.Container.ID, true)
+}
+
+// RemoveCheckpoint deletes a saved checkpoint of a sandbox.
+func (c *Client) RemoveCheckpoint(ctx context.Context, id, name string) error {
+	if err := c.checkpointExists(ctx, id, name); err != nil {
+		return err
+	}
+	_, err := c.cli.CheckpointRemove(ctx, id, moby.CheckpointRemoveOptions{CheckpointID: name})
+	return wrapNotFound(err)
+}
+
+// checkpointExists returns ErrCheckpointNotFound unless the sandbox has a
+// checkpoint with the given name.
+func (c *Client) checkpointExists(ctx context.Context, id, name string) error {
+	items, err := c.checkpoints(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !slices.ContainsFunc(items, func(cp checkpoint.Summary) bool { return cp.Name == name }) {
+		return ErrCheckpointNotFound
+	}
+	return nil
+}
+
+// checkpoints lists the checkpoints Docker stores for a sandbox.
+func (c *Client) checkpoints(ctx context.Context, id string) ([]checkpoint.Summary, error) {
+	res, err := c.cli.CheckpointList(ctx, id, moby.CheckpointListOptions{})
+	if err != nil {
+		return nil, wrapNotFound(err)
+	}
+	return res.Items, nil
+}
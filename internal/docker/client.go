@@ -9,15 +9,21 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
+	"maps"
 	"math"
 	"net/netip"
+	"os"
+	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"opensbx/internal/database"
+	"opensbx/internal/firewall"
+	"opensbx/internal/logging"
 	"opensbx/models"
 
 	"github.com/containerd/errdefs"
@@ -27,13 +33,36 @@ import (
 	moby "github.com/moby/moby/client"
 )
 
-// Client wraps the Docker SDK and exposes sandbox operations.
+// Client drives a container engine and exposes sandbox operations.
 type Client struct {
-	cli            *moby.Client
+	cli            ContainerRuntime
 	repo           *database.Repository
 	timers         sync.Map          // map[containerID]*timerEntry
 	commands       sync.Map          // map[cmdID]*runningCommand
+	processes      sync.Map          // map[sandboxID/name]*supervisedProcess
+	sessions       sync.Map          // map[sessionID]*codeSession
+	tunnels        sync.Map          // map[tunnelID]*tunnel
+	readyWatches   sync.Map          // map[containerID]*readyWatch
+	wakes          sync.Map          // map[containerID]*wakeCall
+	ops            sync.Map          // map[containerID]string: lifecycle operation in progress
+	scheduleRuns   sync.Map          // map[scheduleName]struct{}: schedules with a run in progress
+	code           codePool          // idle runners of POST /v1/run
 	onCacheInvalid func(name string) // called when a sandbox's ports change or it is removed
+
+	isolatedNetwork string            // bridge network with ICC disabled that sandboxes join ("" = docker default)
+	networkMu       sync.Mutex        // serializes creation of managed networks
+	policy          Policy            // host privileges sandboxes may be granted
+	firewall        *firewall.Sandbox // applies per-sandbox egress rules and bandwidth limits, nil = unsupported
+	egress          *firewall.Manager // host egress deny rules stack and network group networks are attached to, nil = none
+	hardening       Hardening         // container hardening defaults
+	runtime         string            // OCI runtime for sandboxes that do not pick one ("" = daemon default)
+	microVM         MicroVM           // runtimes that boot a micro-VM per sandbox
+	hostIP          netip.Addr        // host address sandbox ports are published on (zero = loopback)
+	hostPorts       string            // host port range Docker assigns from, e.g. "30000-30999" ("" = ephemeral)
+	maxLifetime     time.Duration     // cap on a sandbox's max lifetime (0 = none)
+	secrets         SecretResolver    // resolves env_from_secrets, nil = secrets disabled
+	quotaMu         sync.RWMutex      // guards quota, which changes on config reload
+	quota           models.Quota      // deployment-wide limits on running sandboxes, zero = unlimited
 }
 
 // runningCommand tracks a command that is currently executing.
@@ -48,6 +77,7 @@ type runningCommand struct {
 	mu        sync.Mutex
 	exitCode  int
 	finished  bool
+	timedOut  bool
 }
 
 // timerEntry holds a timer and a cancel channel to avoid goroutine leaks.
@@ -55,11 +85,19 @@ type timerEntry struct {
 	timer     *time.Timer
 	cancel    chan struct{}
 	expiresAt time.Time
+	window    time.Duration // full timeout, re-armed by Touch in idle mode
+	idle      bool          // reset on activity instead of expiring at a fixed time
+	name      string        // sandbox name, for activity reported by the proxy
+	end       time.Time     // max lifetime, which the timer never outlasts; zero = none
 }
 
 // defaultTimeout is applied when no timeout is specified (15 minutes).
 const defaultTimeout = 900
 
+// commandKillGrace is how long a timed-out command may take to exit after
+// SIGKILL before its exec is detached.
+const commandKillGrace = 10 * time.Second
+
 // Default resource limits (1 vCPU, 1GB RAM)
 const (
 	defaultMemoryMB = 1024 // 1GB
@@ -72,23 +110,71 @@ const (
 	maxCPUs     = 4.0  // 4 vCPU
 )
 
-var (
-	once       sync.Once
-	mobyClient *moby.Client
-)
+// Options selects the Docker daemon a Client talks to. Empty fields fall back
+// to DOCKER_HOST, DOCKER_API_VERSION, DOCKER_CERT_PATH and DOCKER_TLS_VERIFY,
+// like the docker CLI.
+type Options struct {
+	Engine     string // container engine, EngineDocker or EnginePodman; empty = Docker
+	Host       string // daemon address, e.g. "unix:///var/run/docker.sock" or "tcp://10.0.0.5:2376"
+	APIVersion string // API version to use, e.g. "1.47"; empty = negotiate with the daemon
+	CertPath   string // directory with ca.pem, cert.pem and key.pem for a TLS host; the daemon is verified against ca.pem
+}
 
-// New creates a Docker Client with the given repository.
-// The underlying Docker connection is a singleton (created once),
-// but each Client gets its own repository.
+// New creates a Client for the daemon configured in the environment. Panics
+// if that configuration is invalid; NewWithOptions returns the error instead.
 func New(repo *database.Repository) *Client {
-	once.Do(func() {
-		cli, err := moby.NewClientWithOpts(moby.FromEnv, moby.WithAPIVersionNegotiation())
-		if err != nil {
-			panic(err)
-		}
-		mobyClient = cli
-	})
-	return &Client{cli: mobyClient, repo: repo}
+	c, err := NewWithOptions(repo, Options{})
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// NewWithOptions creates a Client with its own connection to the daemon
+// selected by opts. Clients share no state, so several can manage different
+// daemons side by side, each with its own repository.
+func NewWithOptions(repo *database.Repository, opts Options) (*Client, error) {
+	mobyOpts := []moby.Opt{moby.WithTLSClientConfigFromEnv(), moby.WithHostFromEnv()}
+	if opts.Host == "" && opts.Engine == EnginePodman {
+		opts.Host = podmanSocket()
+	}
+	if opts.Host != "" {
+		mobyOpts = append(mobyOpts, moby.WithHost(opts.Host))
+	}
+	if opts.CertPath != "" {
+		mobyOpts = append(mobyOpts, moby.WithTLSClientConfig(
+			filepath.Join(opts.CertPath, "ca.pem"),
+			filepath.Join(opts.CertPath, "cert.pem"),
+			filepath.Join(opts.CertPath, "key.pem"),
+		))
+	}
+	if opts.APIVersion != "" {
+		mobyOpts = append(mobyOpts, moby.WithAPIVersion(opts.APIVersion))
+	} else {
+		mobyOpts = append(mobyOpts, moby.WithAPIVersionFromEnv())
+	}
+	cli, err := newRuntime(opts.Engine, mobyOpts)
+	if err != nil {
+		return nil, fmt.Errorf("docker client: %w", err)
+	}
+	return &Client{cli: cli, repo: repo}, nil
+}
+
+// DaemonHost returns the address of the engine the Client talks to.
+func (c *Client) DaemonHost() string {
+	return c.cli.DaemonHost()
+}
+
+// LocalDaemon reports whether the engine listens on a local unix socket. Only
+// then are container PIDs and network subnets those of this machine, which
+// the egress firewall and network policies rely on.
+func (c *Client) LocalDaemon() bool {
+	return strings.HasPrefix(c.cli.DaemonHost(), "unix://")
+}
+
+// Close releases the connection to the engine.
+func (c *Client) Close() error {
+	return c.cli.Close()
 }
 
 // SetCacheInvalidator registers a callback invoked when a sandbox's ports
@@ -114,22 +200,32 @@ func (c *Client) Ping(ctx context.Context) error {
 	return err
 }
 
-// List returns all sandboxes tracked in the database, enriched with live
-// state from Docker. Stopped containers are always included.
-func (c *Client) List(ctx context.Context) ([]models.SandboxSummary, error) {
-	// Fetch all persisted sandboxes from the database.
-	dbSandboxes, err := c.repo.FindAll()
+// ServerVersion returns the version of the Docker daemon.
+func (c *Client) ServerVersion(ctx context.Context) (string, error) {
+	v, err := c.cli.ServerVersion(ctx, moby.ServerVersionOptions{})
 	if err != nil {
-		return nil, err
+		return "", err
 	}
-	if len(dbSandboxes) == 0 {
-		return []models.SandboxSummary{}, nil
+	return v.Version, nil
+}
+
+// List returns the page of sandboxes tracked in the database that match q,
+// enriched with live state from Docker, and the number of matches before
+// paging. Stopped containers are included unless q filters by state. Callers
+// scoped to an owner (see WithOwner) only see that owner's sandboxes.
+func (c *Client) List(ctx context.Context, q models.SandboxListQuery) ([]models.SandboxSummary, int, error) {
+	// Fetch all managed containers (including stopped) to build a lookup map.
+	result, err := c.cli.ContainerList(ctx, moby.ContainerListOptions{All: true, Filters: managedFilter()})
+	if err != nil {
+		return nil, 0, err
 	}
 
-	// Fetch all containers (including stopped) to build a lookup map.
-	result, err := c.cli.ContainerList(ctx, moby.ContainerListOptions{All: true})
+	dbSandboxes, total, err := c.repo.FindSandboxes(sandboxFilter(ctx, q, result.Items))
 	if err != nil {
-		return nil, err
+		return nil, 0, err
+	}
+	if len(dbSandboxes) == 0 {
+		return []models.SandboxSummary{}, int(total), nil
 	}
 
 	type containerInfo struct {
@@ -159,10 +255,11 @@ func (c *Client) List(ctx context.Context) ([]models.SandboxSummary, error) {
 	summaries := make([]models.SandboxSummary, 0, len(dbSandboxes))
 	for _, db := range dbSandboxes {
 		s := models.SandboxSummary{
-			ID:    db.ID,
-			Name:  db.Name,
-			Image: db.Image,
-			Ports: portKeys(map[string]string(db.Ports)),
+			ID:     db.ID,
+			Name:   db.Name,
+			Image:  db.Image,
+			Ports:  portKeys(map[string]string(db.Ports)),
+			Labels: db.Labels,
 		}
 
 		// Enrich with live Docker state if the container still exists.
@@ -188,13 +285,68 @@ func (c *Client) List(ctx context.Context) ([]models.SandboxSummary, error) {
 		summaries = append(summaries, s)
 	}
 
-	return summaries, nil
+	return summaries, int(total), nil
+}
+
+// sandboxFilter builds the repository filter for a list query, whose label
+// selectors the API has validated. State lives in Docker, so a state filter
+// becomes the IDs of the containers in that state.
+func sandboxFilter(ctx context.Context, q models.SandboxListQuery, items []container.Summary) database.SandboxFilter {
+	labels, _ := ParseLabelSelectors(q.Label)
+	f := database.SandboxFilter{
+		Owner:      OwnerFrom(ctx),
+		Image:      q.Image,
+		NamePrefix: q.NamePrefix,
+		Labels:     labels,
+		Sort:       q.Sort,
+		Desc:       q.Order == "desc",
+		Limit:      q.Limit,
+		Offset:     q.Offset,
+	}
+	switch q.State {
+	case "":
+	case "removed":
+		for _, item := range items {
+			f.ExcludeIDs = append(f.ExcludeIDs, item.ID)
+		}
+	default:
+		f.IDs = []string{}
+		for _, item := range items {
+			if string(item.State) == q.State {
+				f.IDs = append(f.IDs, item.ID)
+			}
+		}
+	}
+	return f
 }
 
 // Create creates and starts a sandbox. Docker assigns host ports automatically.
 // Applies optional resource limits and schedules auto-stop with a default TTL of 15 minutes.
-// Returns ErrImageNotFound if the image does not exist locally.
+// Returns ErrImageNotFound if the image does not exist locally, or
+// ErrQuotaExceeded if the sandbox would exceed a quota.
 func (c *Client) Create(ctx context.Context, req models.CreateSandboxRequest) (models.CreateSandboxResponse, error) {
+	return c.create(ctx, req, createOptions{name: req.Name})
+}
+
+// createOptions are the settings of create that only the server chooses.
+type createOptions struct {
+	name     string // sandbox name, empty = auto-generated
+	specHash string // marks sandboxes managed by POST /v1/apply
+	stack    string // stack the sandbox is a service of
+	service  string // service name, the sandbox's alias on the stack network
+}
+
+// create backs Create, Apply and CreateStack.
+func (c *Client) create(ctx context.Context, req models.CreateSandboxRequest, opts createOptions) (models.CreateSandboxResponse, error) {
+	name := opts.name
+	runtime := req.Runtime
+	if runtime == "" {
+		runtime = c.runtime
+	}
+	if err := c.CheckQuota(ctx, c.quotaResources(req.Resources, runtime)); err != nil {
+		return models.CreateSandboxResponse{}, err
+	}
+
 	// Verify image exists locally
 	exists, err := c.ImageExists(ctx, req.Image)
 	if err != nil {
@@ -204,6 +356,11 @@ func (c *Client) Create(ctx context.Context, req models.CreateSandboxRequest) (m
 		return models.CreateSandboxResponse{}, ErrImageNotFound
 	}
 
+	env, err := c.createEnv(ctx, req.Env, req.EnvFromSecrets)
+	if err != nil {
+		return models.CreateSandboxResponse{}, err
+	}
+
 	ports := normalizePorts(req.Ports)
 	mainPort := ""
 	if len(ports) > 0 {
@@ -212,60 +369,121 @@ func (c *Client) Create(ctx context.Context, req models.CreateSandboxRequest) (m
 
 	cfg := &container.Config{
 		Image:        req.Image,
-		Env:          req.Env,
-		Cmd:          []string{"sleep", "infinity"},
+		Env:          env,
+		Cmd:          startupCmd(req),
+		Entrypoint:   req.Entrypoint,
+		WorkingDir:   req.WorkingDir,
 		ExposedPorts: buildExposedPorts(ports),
+		Labels:       maps.Clone(req.Labels),
 	}
 
 	hostCfg := &container.HostConfig{
-		PortBindings: buildPortBindings(ports),
+		PortBindings: buildPortBindings(ports, c.publishIP(), c.hostPorts),
 	}
 
-	// Apply resource limits (defaults: 1GB RAM, 1 vCPU)
-	memory := int64(defaultMemoryMB)
-	cpus := defaultCPUs
-	if req.Resources != nil {
-		if req.Resources.Memory > 0 {
-			memory = req.Resources.Memory
-		}
-		if req.Resources.CPUs > 0 {
-			cpus = req.Resources.CPUs
+	if req.NetworkGroup != "" {
+		if _, err := c.findNetworkGroup(ctx, req.NetworkGroup); err != nil {
+			return models.CreateSandboxResponse{}, err
 		}
 	}
+
+	netPolicy, err := networkPolicy(req)
+	if err != nil {
+		return models.CreateSandboxResponse{}, err
+	}
+	if !netPolicy.Empty() && c.firewall == nil {
+		return models.CreateSandboxResponse{}, ErrNetworkPolicyUnsupported
+	}
+	if hostCfg.NetworkMode, err = c.networkMode(ctx, req.Network); err != nil {
+		return models.CreateSandboxResponse{}, err
+	}
+
+	// Apply resource limits (defaults: 1GB RAM, 1 vCPU)
+	memory, cpus := EffectiveResources(req.Resources)
 	hostCfg.Resources = container.Resources{
 		Memory:   memory * 1024 * 1024, // MB to bytes
 		NanoCPUs: int64(cpus * 1e9),
 	}
+	if req.Resources != nil {
+		hostCfg.Resources.DeviceRequests = gpuDeviceRequests(req.Resources.GPUs)
+	}
+
+	security, err := c.hardening.Security(req.Security)
+	if err != nil {
+		return models.CreateSandboxResponse{}, err
+	}
+	security.apply(cfg, hostCfg)
+
+	if hostCfg.Runtime, err = c.sandboxRuntime(ctx, req.Runtime); err != nil {
+		return models.CreateSandboxResponse{}, err
+	}
 
 	// Auto-generate a unique sandbox name.
-	name := generateUniqueName(func(n string) bool {
-		sb, _ := c.repo.FindByName(n)
-		return sb != nil
-	})
+	if name == "" {
+		name = generateUniqueName(func(n string) bool {
+			sb, _ := c.repo.FindByName(n)
+			return sb != nil
+		})
+	} else if sb, err := c.repo.FindByName(name); err != nil {
+		return models.CreateSandboxResponse{}, err
+	} else if sb != nil {
+		return models.CreateSandboxResponse{}, fmt.Errorf("%w: %s", ErrNameTaken, name)
+	}
+
+	timeout := req.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	if cfg.Labels == nil {
+		cfg.Labels = map[string]string{}
+	}
+	maps.Copy(cfg.Labels, managedLabels(name, OwnerFrom(ctx), timeout, req.ExpirationAction))
+	var gitDir string
+	if req.Git != nil {
+		gitDir = gitCloneDir(*req.Git, req.WorkingDir)
+		cfg.Labels[LabelGitDir] = gitDir
+	}
 
-	result, err := c.cli.ContainerCreate(ctx, moby.ContainerCreateOptions{
+	result, err := c.createContainer(ctx, moby.ContainerCreateOptions{
 		Config:     cfg,
 		HostConfig: hostCfg,
 		Name:       name,
 	})
+	if errdefs.IsConflict(err) {
+		// A container outside the database already has the name.
+		return models.CreateSandboxResponse{}, fmt.Errorf("%w: %s", ErrNameTaken, name)
+	}
 	if err != nil {
 		return models.CreateSandboxResponse{}, err
 	}
 
+	// Join shared networks before starting, so peers can be resolved right away.
+	if err := c.joinNetworks(ctx, result.ID, req.NetworkGroup, opts); err != nil {
+		c.removeFailed(ctx, result.ID, "network join")
+		return models.CreateSandboxResponse{}, err
+	}
+
 	if _, err := c.cli.ContainerStart(ctx, result.ID, moby.ContainerStartOptions{}); err != nil {
+		c.removeFailed(ctx, result.ID, "start")
 		return models.CreateSandboxResponse{}, err
 	}
 
-	// Schedule auto-stop. Default 15 min if not specified.
-	timeout := req.Timeout
-	if timeout <= 0 {
-		timeout = defaultTimeout
+	// Never leave a sandbox running without the network policy it asked for.
+	if err := c.enforceNetworkPolicy(ctx, result.ID, netPolicy); err != nil {
+		c.removeFailed(ctx, result.ID, "network policy")
+		return models.CreateSandboxResponse{}, err
 	}
-	c.scheduleStop(result.ID, timeout)
+
+	// Schedule auto-stop. Default 15 min if not specified, and never past the
+	// max lifetime.
+	createdAt := time.Now()
+	maxLifetime := c.effectiveLifetime(req.MaxLifetime)
+	c.scheduleStop(result.ID, name, timeout, req.TimeoutMode == TimeoutIdle, lifetimeEnd(&database.Sandbox{CreatedAt: createdAt.UnixMilli(), MaxLifetime: maxLifetime}))
 
 	// Inspect to get Docker-assigned host ports.
 	info, err := c.cli.ContainerInspect(ctx, result.ID, moby.ContainerInspectOptions{})
 	if err != nil {
+		c.removeFailed(ctx, result.ID, "inspect")
 		return models.CreateSandboxResponse{}, err
 	}
 
@@ -273,22 +491,75 @@ func (c *Client) Create(ctx context.Context, req models.CreateSandboxRequest) (m
 
 	// Persist sandbox (fire-and-forget: log errors, don't block).
 	if err := c.repo.Save(database.Sandbox{
-		ID:    result.ID,
-		Name:  name,
-		Image: req.Image,
-		Ports: database.JSONMap(assignedPorts),
-		Port:  mainPort,
+		ID:               result.ID,
+		Name:             name,
+		Image:            req.Image,
+		Ports:            database.JSONMap(assignedPorts),
+		Port:             mainPort,
+		SpecHash:         opts.specHash,
+		ExpirationAction: req.ExpirationAction,
+		TimeoutMode:      req.TimeoutMode,
+		OwnerID:          OwnerFrom(ctx),
+		Memory:           memory,
+		CPUs:             cpus,
+		Runtime:          hostCfg.Runtime,
+		Network:          req.Network,
+		EgressAllow:      joinRules(netPolicy.Allow),
+		EgressDeny:       joinRules(netPolicy.Deny),
+		IngressKbps:      netPolicy.IngressKbps,
+		EgressKbps:       netPolicy.EgressKbps,
+		Labels:           database.JSONMap(req.Labels),
+		CreatedAt:        createdAt.UnixMilli(),
+		MaxLifetime:      maxLifetime,
+		ReadyCheck:       encodeReadyCheck(req.ReadyCheck),
+		Hooks:            encodeHooks(req.Hooks),
+		Ready:            initialReady(req.ReadyCheck),
+		State:            database.SandboxRunning,
+		WakeOnRequest:    req.WakeOnRequest,
+		Stack:            opts.stack,
+		NetworkGroup:     req.NetworkGroup,
 	}); err != nil {
-		log.Printf("database: failed to persist sandbox %s: %v", result.ID, err)
+		logging.FromContext(ctx).Error("database: failed to persist sandbox", "sandbox_id", result.ID, "err", err)
+	}
+
+	// Clone and run the setup hooks before processes and the ready check,
+	// which usually need the code and its dependencies.
+	var git *models.GitStatus
+	if req.Git != nil {
+		status, err := c.cloneGit(ctx, result.ID, gitDir, *req.Git)
+		if err != nil {
+			c.removeFailed(ctx, result.ID, "clone")
+			return models.CreateSandboxResponse{}, err
+		}
+		git = &status
+	}
+	if req.Hooks != nil && req.Hooks.OnCreate != nil {
+		if _, err := c.runHook(ctx, result.ID, HookOnCreate, *req.Hooks.OnCreate); err != nil {
+			c.removeFailed(ctx, result.ID, HookOnCreate+" hook")
+			return models.CreateSandboxResponse{}, err
+		}
 	}
+	c.runStoredHook(ctx, result.ID, HookOnStart)
+	c.watchReady(ctx, result.ID)
+	c.launchProcesses(ctx, result.ID, req.Processes)
 
 	return models.CreateSandboxResponse{
 		ID:    result.ID,
 		Name:  name,
 		Ports: portKeys(assignedPorts),
+		Git:   git,
 	}, nil
 }
 
+// removeFailed removes a sandbox whose create failed after its container was
+// created; step names what failed, for the log. It runs even if ctx is done,
+// e.g. when the request deadline is what failed the step.
+func (c *Client) removeFailed(ctx context.Context, id, step string) {
+	if err := c.Remove(context.WithoutCancel(ctx), id); err != nil {
+		logging.FromContext(ctx).Error("failed to remove sandbox after a failed "+step, "sandbox_id", id, "err", err)
+	}
+}
+
 // Inspect returns a curated view of a sandbox.
 func (c *Client) Inspect(ctx context.Context, id string) (models.SandboxDetail, error) {
 	result, err := c.cli.ContainerInspect(ctx, id, moby.ContainerInspectOptions{})
@@ -307,7 +578,10 @@ func (c *Client) Inspect(ctx context.Context, id string) (models.SandboxDetail,
 		Resources: models.ResourceLimits{
 			Memory: info.HostConfig.Memory / (1024 * 1024), // bytes to MB
 			CPUs:   float64(info.HostConfig.NanoCPUs) / 1e9,
+			GPUs:   containerGPUs(info.HostConfig.DeviceRequests),
 		},
+		Runtime:    info.HostConfig.Runtime,
+		Isolation:  c.microVM.Isolation(info.HostConfig.Runtime),
 		StartedAt:  info.State.StartedAt,
 		FinishedAt: info.State.FinishedAt,
 	}
@@ -316,6 +590,23 @@ func (c *Client) Inspect(ctx context.Context, id string) (models.SandboxDetail,
 		ea := entry.expiresAt
 		detail.ExpiresAt = &ea
 	}
+	if sb, err := c.repo.FindByID(info.ID); err == nil && sb != nil {
+		detail.Labels = sb.Labels
+		detail.Ready = sb.Ready
+		detail.WakeOnRequest = sb.WakeOnRequest
+		if end := lifetimeEnd(sb); !end.IsZero() {
+			detail.MaxLifetime = sb.MaxLifetime
+			detail.LifetimeEndsAt = &end
+		}
+	}
+	if !info.State.Running && info.State.Status != container.StateCreated {
+		exitCode := info.State.ExitCode
+		detail.OOMKilled = info.State.OOMKilled
+		detail.ExitCode = &exitCode
+	}
+	if detail.Events, err = c.sandboxEvents(info.ID); err != nil {
+		return models.SandboxDetail{}, err
+	}
 
 	return detail, nil
 }
@@ -343,12 +634,19 @@ func (c *Client) GetNetwork(ctx context.Context, id string) (models.SandboxNetwo
 		}
 	}
 
-	return models.SandboxNetwork{MainPort: mainPort, PortsMap: ports}, nil
+	return models.SandboxNetwork{Name: sb.Name, MainPort: mainPort, PortsMap: ports}, nil
 }
 
 // Start starts a stopped sandbox and re-schedules the auto-stop timer.
-// Returns ErrAlreadyRunning (409) if the sandbox is already running.
+// Returns ErrAlreadyRunning (409) if the sandbox is already running, or
+// ErrQuotaExceeded if starting it would exceed a quota.
 func (c *Client) Start(ctx context.Context, id string) (models.RestartResponse, error) {
+	id, unlock, err := c.lockResolved(ctx, id, "start")
+	if err != nil {
+		return models.RestartResponse{}, err
+	}
+	defer unlock()
+
 	// Check current state to return a meaningful conflict error.
 	pre, err := c.cli.ContainerInspect(ctx, id, moby.ContainerInspectOptions{})
 	if err != nil {
@@ -357,12 +655,29 @@ func (c *Client) Start(ctx context.Context, id string) (models.RestartResponse,
 	if pre.Container.State.Running {
 		return models.RestartResponse{}, ErrAlreadyRunning
 	}
+	if err := c.checkLifetime(pre.Container.ID); err != nil {
+		return models.RestartResponse{}, err
+	}
+	if err := c.checkStartQuota(ctx, pre.Container.ID); err != nil {
+		return models.RestartResponse{}, err
+	}
 
 	if _, err := c.cli.ContainerStart(ctx, id, moby.ContainerStartOptions{}); err != nil {
 		return models.RestartResponse{}, wrapNotFound(err)
 	}
+	return c.started(ctx, id, pre.Container.ID, false)
+}
 
-	c.scheduleStop(id, defaultTimeout)
+// started does the bookkeeping after a sandbox's container started: timers,
+// network policy, ports, ready check and supervised processes. A container
+// restored from a checkpoint already runs its processes, so they are not
+// started again.
+func (c *Client) started(ctx context.Context, id, containerID string, restored bool) (models.RestartResponse, error) {
+	if err := c.reapplyNetworkPolicy(ctx, containerID); err != nil {
+		return models.RestartResponse{}, err
+	}
+
+	c.rescheduleStop(id, defaultTimeout)
 
 	info, err := c.cli.ContainerInspect(ctx, id, moby.ContainerInspectOptions{})
 	if err != nil {
@@ -378,12 +693,22 @@ func (c *Client) Start(ctx context.Context, id string) (models.RestartResponse,
 	ports := extractPorts(info.Container.NetworkSettings.Ports)
 
 	if dbErr := c.repo.UpdatePorts(id, database.JSONMap(ports)); dbErr != nil {
-		log.Printf("database: failed to update ports for sandbox %s: %v", id, dbErr)
+		logging.FromContext(ctx).Error("database: failed to update ports", "sandbox_id", id, "err", dbErr)
 	}
+	c.setState(ctx, info.Container.ID, database.SandboxRunning)
+	if !restored {
+		c.runStoredHook(ctx, info.Container.ID, HookOnStart)
+	}
+	c.watchReady(ctx, info.Container.ID)
 	c.invalidateCache(id)
+	status := "restored"
+	if !restored {
+		c.startProcesses(ctx, info.Container.ID)
+		status = "started"
+	}
 
 	return models.RestartResponse{
-		Status:    "started",
+		Status:    status,
 		Ports:     portKeys(ports),
 		ExpiresAt: expiresAt,
 	}, nil
@@ -392,6 +717,12 @@ func (c *Client) Start(ctx context.Context, id string) (models.RestartResponse,
 // Stop stops a running sandbox and cancels its expiration timer.
 // Returns ErrAlreadyStopped (409) if the sandbox is not running.
 func (c *Client) Stop(ctx context.Context, id string) error {
+	id, unlock, err := c.lockResolved(ctx, id, "stop")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	info, err := c.cli.ContainerInspect(ctx, id, moby.ContainerInspectOptions{})
 	if err != nil {
 		return wrapNotFound(err)
@@ -401,28 +732,58 @@ func (c *Client) Stop(ctx context.Context, id string) error {
 	}
 
 	c.cancelTimer(id)
+	c.runStoredHook(ctx, info.Container.ID, HookBeforeStop)
+	c.cancelReady(info.Container.ID)
 	c.invalidateCache(id)
-	_, err = c.cli.ContainerStop(ctx, id, moby.ContainerStopOptions{})
-	return wrapNotFound(err)
+	if _, err := c.cli.ContainerStop(ctx, id, moby.ContainerStopOptions{}); err != nil {
+		return wrapNotFound(err)
+	}
+	c.setState(ctx, info.Container.ID, database.SandboxStopped)
+	return nil
 }
 
 // Restart restarts a sandbox and returns the new port mappings.
 // It cancels any existing timer and schedules a fresh one with the default timeout.
 func (c *Client) Restart(ctx context.Context, id string) (models.RestartResponse, error) {
+	id, unlock, err := c.lockResolved(ctx, id, "restart")
+	if err != nil {
+		return models.RestartResponse{}, err
+	}
+	defer unlock()
+
+	pre, err := c.cli.ContainerInspect(ctx, id, moby.ContainerInspectOptions{})
+	if err != nil {
+		return models.RestartResponse{}, wrapNotFound(err)
+	}
+	if err := c.checkLifetime(pre.Container.ID); err != nil {
+		return models.RestartResponse{}, err
+	}
+	if !pre.Container.State.Running {
+		if err := c.checkStartQuota(ctx, pre.Container.ID); err != nil {
+			return models.RestartResponse{}, err
+		}
+	}
+
 	c.cancelTimer(id)
+	if pre.Container.State.Running {
+		c.runStoredHook(ctx, pre.Container.ID, HookBeforeStop)
+	}
 
 	if _, err := c.cli.ContainerRestart(ctx, id, moby.ContainerRestartOptions{}); err != nil {
 		return models.RestartResponse{}, wrapNotFound(err)
 	}
 
 	// Re-schedule auto-stop with the default timeout.
-	c.scheduleStop(id, defaultTimeout)
+	c.rescheduleStop(id, defaultTimeout)
 
 	// Inspect to get the new ports.
 	info, err := c.cli.ContainerInspect(ctx, id, moby.ContainerInspectOptions{})
 	if err != nil {
 		return models.RestartResponse{}, wrapNotFound(err)
 	}
+	if err := c.reapplyNetworkPolicy(ctx, info.Container.ID); err != nil {
+		return models.RestartResponse{}, err
+	}
 
 	var expiresAt *time.Time
 	if entry := c.getTimerEntry(id); entry != nil {
@@ -434,9 +795,13 @@ func (c *Client) Restart(ctx context.Context, id string) (models.RestartResponse
 
 	// Update persisted ports after restart (they may change).
 	if dbErr := c.repo.UpdatePorts(id, database.JSONMap(ports)); dbErr != nil {
-		log.Printf("database: failed to update ports for sandbox %s: %v", id, dbErr)
+		logging.FromContext(ctx).Error("database: failed to update ports", "sandbox_id", id, "err", dbErr)
 	}
+	c.setState(ctx, info.Container.ID, database.SandboxRunning)
+	c.runStoredHook(ctx, info.Container.ID, HookOnStart)
+	c.watchReady(ctx, info.Container.ID)
 	c.invalidateCache(id)
+	c.startProcesses(ctx, info.Container.ID)
 
 	return models.RestartResponse{
 		Status:    "restarted",
@@ -448,9 +813,19 @@ func (c *Client) Restart(ctx context.Context, id string) (models.RestartResponse
 // Remove removes a sandbox forcefully and cancels its expiration timer.
 // If the container no longer exists in Docker, it still cleans up the DB record.
 func (c *Client) Remove(ctx context.Context, id string) error {
+	id, unlock, err := c.lockResolved(ctx, id, "remove")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	c.cancelTimer(id)
+	c.cancelReady(id)
 	c.invalidateCache(id)
 
+	c.stopSupervisors(id)
+	c.closeTunnels(id)
+
 	// Kill all running commands for this sandbox.
 	c.commands.Range(func(key, value any) bool {
 		rc := value.(*runningCommand)
@@ -460,18 +835,30 @@ func (c *Client) Remove(ctx context.Context, id string) error {
 		return true
 	})
 
-	_, err := c.cli.ContainerRemove(ctx, id, moby.ContainerRemoveOptions{Force: true})
+	_, err = c.cli.ContainerRemove(ctx, id, moby.ContainerRemoveOptions{Force: true})
 	if err != nil && !errdefs.IsNotFound(err) {
 		return err
 	}
 
+	if dbErr := c.repo.DeleteProcessesBySandbox(id); dbErr != nil {
+		logging.FromContext(ctx).Error("database: failed to delete processes", "sandbox_id", id, "err", dbErr)
+	}
+
+	if dbErr := c.repo.DeleteDomainsBySandbox(id); dbErr != nil {
+		logging.FromContext(ctx).Error("database: failed to delete domains", "sandbox_id", id, "err", dbErr)
+	}
+
+	if dbErr := c.repo.DeleteSandboxEventsBySandbox(id); dbErr != nil {
+		logging.FromContext(ctx).Error("database: failed to delete events", "sandbox_id", id, "err", dbErr)
+	}
+
 	// Clean up command records from DB.
 	if dbErr := c.repo.DeleteCommandsBySandbox(id); dbErr != nil {
-		log.Printf("database: failed to delete commands for sandbox %s: %v", id, dbErr)
+		logging.FromContext(ctx).Error("database: failed to delete commands", "sandbox_id", id, "err", dbErr)
 	}
 
 	if dbErr := c.repo.Delete(id); dbErr != nil {
-		log.Printf("database: failed to delete sandbox %s: %v", id, dbErr)
+		logging.FromContext(ctx).Error("database: failed to delete sandbox", "sandbox_id", id, "err", dbErr)
 	}
 	return nil
 }
@@ -480,6 +867,12 @@ func (c *Client) Remove(ctx context.Context, id string) error {
 // Returns ErrNotRunning (409) if the sandbox is not running,
 // or ErrAlreadyPaused (409) if it is already paused.
 func (c *Client) Pause(ctx context.Context, id string) error {
+	id, unlock, err := c.lockResolved(ctx, id, "pause")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	info, err := c.cli.ContainerInspect(ctx, id, moby.ContainerInspectOptions{})
 	if err != nil {
 		return wrapNotFound(err)
@@ -498,6 +891,12 @@ func (c *Client) Pause(ctx context.Context, id string) error {
 // Resume unpauses a paused sandbox.
 // Returns ErrNotPaused (409) if the sandbox is not currently paused.
 func (c *Client) Resume(ctx context.Context, id string) error {
+	id, unlock, err := c.lockResolved(ctx, id, "resume")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	info, err := c.cli.ContainerInspect(ctx, id, moby.ContainerInspectOptions{})
 	if err != nil {
 		return wrapNotFound(err)
@@ -510,15 +909,21 @@ func (c *Client) Resume(ctx context.Context, id string) error {
 	return wrapNotFound(err)
 }
 
-// RenewExpiration resets the auto-stop timer for a sandbox.
+// RenewExpiration resets the auto-stop timer for a sandbox. The timer never
+// runs past the sandbox's max lifetime; once that is reached, renewing fails
+// with ErrLifetimeExceeded.
 func (c *Client) RenewExpiration(ctx context.Context, id string, timeout int) error {
 	// Verify the sandbox exists.
-	if _, err := c.cli.ContainerInspect(ctx, id, moby.ContainerInspectOptions{}); err != nil {
+	info, err := c.cli.ContainerInspect(ctx, id, moby.ContainerInspectOptions{})
+	if err != nil {
 		return wrapNotFound(err)
 	}
+	if err := c.checkLifetime(info.Container.ID); err != nil {
+		return err
+	}
 
 	c.cancelTimer(id)
-	c.scheduleStop(id, timeout)
+	c.rescheduleStop(id, timeout)
 	return nil
 }
 
@@ -558,10 +963,39 @@ func (c *Client) Stats(ctx context.Context, id string) (models.SandboxStats, err
 			Limit:   raw.MemoryStats.Limit,
 			Percent: math.Round(memPercent*100) / 100,
 		},
-		PIDs: raw.PidsStats.Current,
+		PIDs:    raw.PidsStats.Current,
+		Network: networkUsage(raw.Networks),
+		BlockIO: blockIOUsage(raw.BlkioStats),
 	}, nil
 }
 
+// networkUsage converts Docker's per-interface network counters.
+func networkUsage(networks map[string]container.NetworkStats) map[string]models.NetworkUsage {
+	if len(networks) == 0 {
+		return nil
+	}
+	usage := make(map[string]models.NetworkUsage, len(networks))
+	for name, n := range networks {
+		usage[name] = models.NetworkUsage{RxBytes: n.RxBytes, RxPackets: n.RxPackets, TxBytes: n.TxBytes, TxPackets: n.TxPackets}
+	}
+	return usage
+}
+
+// blockIOUsage sums the bytes read and written over all block devices. The
+// operation is "Read"/"Write" under cgroup v1 and "read"/"write" under v2.
+func blockIOUsage(blkio container.BlkioStats) models.BlockIOUsage {
+	var usage models.BlockIOUsage
+	for _, e := range blkio.IoServiceBytesRecursive {
+		switch strings.ToLower(e.Op) {
+		case "read":
+			usage.ReadBytes += e.Value
+		case "write":
+			usage.WriteBytes += e.Value
+		}
+	}
+	return usage
+}
+
 // generateCmdID creates a command ID: cmd_ + 40 hex chars.
 func generateCmdID() string {
 	b := make([]byte, 20)
@@ -580,27 +1014,47 @@ func (c *Client) ExecCommand(ctx context.Context, sandboxID string, req models.E
 		return models.CommandDetail{}, wrapNotFound(err)
 	}
 	if !info.Container.State.Running {
-		return models.CommandDetail{}, ErrNotRunning
+		return models.CommandDetail{}, notRunning(info.Container.State)
 	}
+	c.Touch(sandboxID)
+
+	return c.startCommand(ctx, sandboxID, req)
+}
 
+// startCommand creates and starts a command in a running sandbox without
+// counting as activity for idle timeouts.
+func (c *Client) startCommand(ctx context.Context, sandboxID string, req models.ExecCommandRequest) (models.CommandDetail, error) {
 	cmdID := generateCmdID()
 	now := time.Now().UnixMilli()
 
 	// Build full command.
 	fullCmd := append([]string{req.Command}, req.Args...)
 
-	// Build env slice.
-	var envSlice []string
-	for k, v := range req.Env {
-		envSlice = append(envSlice, k+"="+v)
+	// Variables set on the sandbox after creation apply to every command;
+	// the request's own variables take precedence.
+	env := maps.Clone(c.sandboxEnv(ctx, sandboxID))
+	if env == nil {
+		env = map[string]string{}
+	}
+	maps.Copy(env, req.Env)
+	execEnv := envSlice(env)
+
+	// Docker exec always starts from the container's environment; without
+	// inheritance, clear it with env -i and pass only the requested variables.
+	runCmd := fullCmd
+	if req.InheritEnv != nil && !*req.InheritEnv {
+		runCmd = cleanEnvCmd(fullCmd, req.Env)
+		execEnv = nil
 	}
 
 	// Create Docker exec instance.
 	execOpts := moby.ExecCreateOptions{
+		AttachStdin:  req.Stdin != "",
 		AttachStdout: true,
 		AttachStderr: true,
-		Cmd:          fullCmd,
-		Env:          envSlice,
+		Cmd:          runCmd,
+		Env:          execEnv,
+		User:         req.User,
 	}
 	if req.Cwd != "" {
 		execOpts.WorkingDir = req.Cwd
@@ -640,11 +1094,22 @@ func (c *Client) ExecCommand(ctx context.Context, sandboxID string, req models.E
 	}
 	c.commands.Store(cmdID, rc)
 
+	if req.Timeout > 0 {
+		timer := time.AfterFunc(time.Duration(req.Timeout)*time.Second, func() {
+			c.timeoutCommand(cmdID, rc)
+		})
+		go func() {
+			<-rc.done
+			timer.Stop()
+		}()
+	}
+
 	// Launch goroutine to attach and stream output.
 	go func() {
 		defer func() {
 			stdoutBuf.Close()
 			stderrBuf.Close()
+			c.saveCommandLogs(cmdID, rc, time.Now().UnixMilli())
 			close(rc.done)
 
 			// Schedule cleanup from map after 5 minutes.
@@ -655,7 +1120,7 @@ func (c *Client) ExecCommand(ctx context.Context, sandboxID string, req models.E
 
 		attached, err := c.cli.ExecAttach(execCtx, execCfg.ID, moby.ExecAttachOptions{})
 		if err != nil {
-			log.Printf("exec attach %s: %v", cmdID, err)
+			logging.FromContext(ctx).Error("exec attach failed", "sandbox_id", sandboxID, "cmd_id", cmdID, "err", err)
 			rc.mu.Lock()
 			rc.exitCode = -1
 			rc.finished = true
@@ -665,13 +1130,22 @@ func (c *Client) ExecCommand(ctx context.Context, sandboxID string, req models.E
 		}
 		defer attached.Close()
 
+		// Feed stdin concurrently so a command producing output before it
+		// reads all of its input cannot deadlock, then signal EOF.
+		if req.Stdin != "" {
+			go func() {
+				io.Copy(attached.Conn, strings.NewReader(req.Stdin))
+				attached.CloseWrite()
+			}()
+		}
+
 		// Demux stdout/stderr into ring buffers.
 		stdcopy.StdCopy(stdoutBuf, stderrBuf, attached.Reader)
 
 		// Get exit code.
 		exitCode := -1
 		inspect, err := c.cli.ExecInspect(context.Background(), execCfg.ID, moby.ExecInspectOptions{})
-		if err == nil {
+		if err == nil && !inspect.Running {
 			exitCode = inspect.ExitCode
 		}
 
@@ -710,23 +1184,29 @@ func (c *Client) GetCommand(ctx context.Context, sandboxID, cmdID string) (model
 	return c.dbCommandToDetail(*dbCmd), nil
 }
 
-// ListCommands returns all commands for a sandbox.
-func (c *Client) ListCommands(ctx context.Context, sandboxID string) ([]models.CommandDetail, error) {
+// ListCommands returns the page of a sandbox's commands selected by q and
+// the number of commands in the sandbox.
+func (c *Client) ListCommands(ctx context.Context, sandboxID string, q models.CommandListQuery) ([]models.CommandDetail, int, error) {
 	// Verify sandbox exists.
 	if _, err := c.cli.ContainerInspect(ctx, sandboxID, moby.ContainerInspectOptions{}); err != nil {
-		return nil, wrapNotFound(err)
+		return nil, 0, wrapNotFound(err)
 	}
 
-	dbCmds, err := c.repo.FindCommandsBySandbox(sandboxID)
+	dbCmds, total, err := c.repo.FindCommands(database.CommandFilter{
+		SandboxID: sandboxID,
+		Desc:      q.Order == "desc",
+		Limit:     q.Limit,
+		Offset:    q.Offset,
+	})
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	details := make([]models.CommandDetail, 0, len(dbCmds))
 	for _, cmd := range dbCmds {
 		details = append(details, c.dbCommandToDetail(cmd))
 	}
-	return details, nil
+	return details, int(total), nil
 }
 
 // KillCommand sends a signal to a running command.
@@ -755,14 +1235,9 @@ func (c *Client) KillCommand(ctx context.Context, sandboxID, cmdID string, signa
 		rc.mu.Unlock()
 		return models.CommandDetail{}, ErrCommandNotFound
 	}
-	cmd := rc.cmd
 	rc.mu.Unlock()
 
-	// Kill the process inside the container using pkill with the original command pattern.
-	pattern := strings.Join(cmd, " ")
-	killCmd := fmt.Sprintf("pkill -%d -f %q", signal, pattern)
-	// Ignore error: pkill returns 1 if process already exited (race condition).
-	c.execWithStdin(ctx, sandboxID, []string{"sh", "-c", killCmd}, nil)
+	c.signalCommand(ctx, rc, signal)
 
 	// Wait briefly for the command to finish, then return current state.
 	select {
@@ -773,11 +1248,107 @@ func (c *Client) KillCommand(ctx context.Context, sandboxID, cmdID string, signa
 	return c.GetCommand(ctx, sandboxID, cmdID)
 }
 
+// defaultExecPath is the PATH given to commands run without the container's environment.
+const defaultExecPath = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+
+// cleanEnvCmd wraps cmd in env -i so it sees only env, plus a default PATH
+// unless env sets one.
+func cleanEnvCmd(cmd []string, env map[string]string) []string {
+	wrapped := []string{"env", "-i"}
+	if _, ok := env["PATH"]; !ok {
+		wrapped = append(wrapped, "PATH="+defaultExecPath)
+	}
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		wrapped = append(wrapped, k+"="+env[k])
+	}
+	return append(wrapped, cmd...)
+}
+
+// signalCommand sends signal to a running command. The exec's root process is
+// located by PID and its process group is signalled when it leads one, else
+// the process alone. If the PID cannot be mapped into the container (e.g. the
+// server does not share the host's /proc), it falls back to pkill on the
+// original command line.
+func (c *Client) signalCommand(ctx context.Context, rc *runningCommand, signal int) {
+	var killCmd string
+	if pid, err := c.commandPID(ctx, rc); err == nil {
+		killCmd = fmt.Sprintf("kill -%d -- -%d 2>/dev/null || kill -%d %d", signal, pid, signal, pid)
+	} else {
+		slog.Warn("command pid unavailable, falling back to pkill", "sandbox_id", rc.sandboxID, "err", err)
+		killCmd = fmt.Sprintf("pkill -%d -f %q", signal, strings.Join(rc.cmd, " "))
+	}
+	// Ignore error: kill/pkill fail if the process already exited (race condition).
+	c.execWithStdin(ctx, rc.sandboxID, []string{"sh", "-c", killCmd}, nil)
+}
+
+// commandPID returns the PID of a command's root process as seen inside its
+// container. Docker reports the host PID, which is translated through the
+// NSpid line of /proc/<pid>/status.
+func (c *Client) commandPID(ctx context.Context, rc *runningCommand) (int, error) {
+	inspect, err := c.cli.ExecInspect(ctx, rc.execID, moby.ExecInspectOptions{})
+	if err != nil {
+		return 0, err
+	}
+	if !inspect.Running || inspect.PID <= 0 {
+		return 0, fmt.Errorf("exec %s is not running", rc.execID)
+	}
+	status, err := os.ReadFile(fmt.Sprintf("/proc/%d/status", inspect.PID))
+	if err != nil {
+		return 0, err
+	}
+	return nsPID(string(status))
+}
+
+// nsPID returns the innermost namespace PID from a /proc/<pid>/status file.
+func nsPID(status string) (int, error) {
+	for _, line := range strings.Split(status, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "NSpid:" {
+			continue
+		}
+		return strconv.Atoi(fields[len(fields)-1])
+	}
+	return 0, fmt.Errorf("no NSpid in process status")
+}
+
+// timeoutCommand kills a command that exceeded its timeout and flags it as
+// timed out. If the output stream is still open after a grace period (e.g. a
+// child process kept it), the exec is detached so the command is recorded as
+// finished anyway.
+func (c *Client) timeoutCommand(cmdID string, rc *runningCommand) {
+	rc.mu.Lock()
+	if rc.finished {
+		rc.mu.Unlock()
+		return
+	}
+	rc.timedOut = true
+	rc.mu.Unlock()
+
+	slog.Info("command timed out", "sandbox_id", rc.sandboxID, "cmd_id", cmdID)
+	c.repo.UpdateCommandTimedOut(cmdID)
+
+	ctx, cancel := context.WithTimeout(context.Background(), commandKillGrace)
+	defer cancel()
+	c.signalCommand(ctx, rc, 9)
+
+	select {
+	case <-rc.done:
+	case <-ctx.Done():
+		rc.cancel()
+	}
+}
+
 // StreamCommandLogs returns readers for stdout and stderr of a command.
+// Finished commands no longer held in memory are served from persisted logs.
 func (c *Client) StreamCommandLogs(ctx context.Context, sandboxID, cmdID string) (io.ReadCloser, io.ReadCloser, error) {
 	v, ok := c.commands.Load(cmdID)
 	if !ok {
-		return nil, nil, ErrCommandNotFound
+		return c.storedLogReaders(sandboxID, cmdID)
 	}
 
 	rc := v.(*runningCommand)
@@ -789,10 +1360,11 @@ func (c *Client) StreamCommandLogs(ctx context.Context, sandboxID, cmdID string)
 }
 
 // GetCommandLogs returns a snapshot of stdout and stderr for a command without streaming.
+// Finished commands no longer held in memory are served from persisted logs.
 func (c *Client) GetCommandLogs(ctx context.Context, sandboxID, cmdID string) (models.CommandLogsResponse, error) {
 	v, ok := c.commands.Load(cmdID)
 	if !ok {
-		return models.CommandLogsResponse{}, ErrCommandNotFound
+		return c.storedCommandLogs(sandboxID, cmdID)
 	}
 
 	rc := v.(*runningCommand)
@@ -847,6 +1419,7 @@ func (c *Client) dbCommandToDetail(cmd database.Command) models.CommandDetail {
 		Cwd:        cmd.Cwd,
 		SandboxID:  cmd.SandboxID,
 		ExitCode:   cmd.ExitCode,
+		TimedOut:   cmd.TimedOut,
 		StartedAt:  cmd.StartedAt,
 		FinishedAt: cmd.FinishedAt,
 	}
@@ -859,6 +1432,7 @@ func (c *Client) dbCommandToDetail(cmd database.Command) models.CommandDetail {
 			ec := rc.exitCode
 			detail.ExitCode = &ec
 		}
+		detail.TimedOut = detail.TimedOut || rc.timedOut
 		rc.mu.Unlock()
 	}
 
@@ -867,34 +1441,48 @@ func (c *Client) dbCommandToDetail(cmd database.Command) models.CommandDetail {
 
 // ReadFile reads the content of a file inside a sandbox.
 func (c *Client) ReadFile(ctx context.Context, id, path string) (string, error) {
-	result, err := c.execWithStdin(ctx, id, []string{"cat", path}, nil)
+	rc, _, err := c.ReadFileStream(ctx, id, path)
 	if err != nil {
 		return "", err
 	}
-	return result.stdout, nil
+	defer rc.Close()
+
+	b, err := io.ReadAll(rc)
+	return string(b), err
 }
 
 // WriteFile writes content to a file inside a sandbox (creates parent dirs as needed).
 func (c *Client) WriteFile(ctx context.Context, id, path, content string) error {
-	if _, err := c.execWithStdin(ctx, id, []string{"sh", "-c", "mkdir -p $(dirname '" + path + "')"}, nil); err != nil {
-		return err
-	}
-	_, err := c.execWithStdin(ctx, id, []string{"sh", "-c", "cat > '" + path + "'"}, strings.NewReader(content))
-	return err
+	return c.WriteFileStream(ctx, id, path, strings.NewReader(content), int64(len(content)))
 }
 
+// deleteScript removes $1 recursively. rm -f succeeds on missing paths, so
+// existence (including dangling symlinks) is checked first.
+const deleteScript = `[ -e "$1" ] || [ -L "$1" ] || { echo "rm: $1: No such file or directory" >&2; exit 1; }
+rm -rf -- "$1"`
+
 // DeleteFile deletes a file or directory inside a sandbox.
+// Returns ErrPathNotFound if nothing exists at path.
 func (c *Client) DeleteFile(ctx context.Context, id, path string) error {
-	_, err := c.execWithStdin(ctx, id, []string{"rm", "-rf", path}, nil)
-	return err
+	c.Touch(id)
+	result, err := c.execWithStdin(ctx, id, []string{"sh", "-c", deleteScript, "rm", path}, nil)
+	if err != nil {
+		return err
+	}
+	return result.check()
 }
 
 // ListDir lists the contents of a directory inside a sandbox.
+// Returns ErrPathNotFound if path does not exist.
 func (c *Client) ListDir(ctx context.Context, id, path string) (string, error) {
+	c.Touch(id)
 	result, err := c.execWithStdin(ctx, id, []string{"ls", "-la", path}, nil)
 	if err != nil {
 		return "", err
 	}
+	if err := result.check(); err != nil {
+		return "", err
+	}
 	return result.stdout, nil
 }
 
@@ -924,6 +1512,7 @@ func (c *Client) PullImage(ctx context.Context, image string) error {
 		return fmt.Errorf("pull %s: image not available after pull", image)
 	}
 
+	c.trackImage(ctx, image)
 	return nil
 }
 
@@ -1001,14 +1590,8 @@ func (c *Client) Shutdown(ctx context.Context) {
 		return true
 	})
 
-	log.Printf("docker shutdown: canceling %d commands, stopping %d sandboxes", commandCount, timerCount)
-
-	// Cancel all running commands.
-	c.commands.Range(func(key, value any) bool {
-		rc := value.(*runningCommand)
-		rc.cancel()
-		return true
-	})
+	slog.Info("docker shutdown: canceling commands and stopping sandboxes", "commands", commandCount, "sandboxes", timerCount)
+	c.cancelCommands()
 
 	c.timers.Range(func(key, value any) bool {
 		id := key.(string)
@@ -1018,15 +1601,24 @@ func (c *Client) Shutdown(ctx context.Context) {
 		c.timers.Delete(id)
 		if _, err := c.cli.ContainerStop(ctx, id, moby.ContainerStopOptions{}); err != nil {
 			if errors.Is(err, context.DeadlineExceeded) {
-				log.Printf("docker shutdown: stop sandbox %s timeout", id)
+				slog.Warn("docker shutdown: stop sandbox timeout", "sandbox_id", id)
 			} else {
-				log.Printf("docker shutdown: stop sandbox %s: %v", id, err)
+				slog.Error("docker shutdown: stop sandbox failed", "sandbox_id", id, "err", err)
 			}
 		}
 		return true
 	})
 }
 
+// cancelCommands cancels all running commands.
+func (c *Client) cancelCommands() {
+	c.commands.Range(func(key, value any) bool {
+		rc := value.(*runningCommand)
+		rc.cancel()
+		return true
+	})
+}
+
 // execResult holds the output from a synchronous exec (used internally for file operations).
 type execResult struct {
 	stdout   string
@@ -1034,6 +1626,26 @@ type execResult struct {
 	exitCode int
 }
 
+// check returns nil for a successful exec, otherwise an error carrying its
+// stderr. "No such file or directory" and "Not a directory" failures wrap
+// ErrPathNotFound and ErrNotADirectory.
+func (r execResult) check() error {
+	if r.exitCode == 0 {
+		return nil
+	}
+	msg := strings.TrimSpace(r.stderr)
+	if msg == "" {
+		msg = fmt.Sprintf("exit code %d", r.exitCode)
+	}
+	switch {
+	case strings.Contains(msg, "No such file or directory"):
+		return fmt.Errorf("%w: %s", ErrPathNotFound, msg)
+	case strings.Contains(msg, "Not a directory"):
+		return fmt.Errorf("%w: %s", ErrNotADirectory, msg)
+	}
+	return errors.New(msg)
+}
+
 // execWithStdin runs a command with optional stdin, returning separated stdout/stderr and exit code.
 func (c *Client) execWithStdin(ctx context.Context, id string, cmd []string, stdin io.Reader) (execResult, error) {
 	attachStdin := stdin != nil
@@ -1078,24 +1690,37 @@ func (c *Client) execWithStdin(ctx context.Context, id string, cmd []string, std
 	}, nil
 }
 
-// scheduleStop creates a timer that auto-stops the sandbox after the given seconds.
+// scheduleStop creates a timer that auto-stops the sandbox after the given seconds,
+// or at end (its max lifetime) if that comes first; a zero end means no limit.
+// With idle set, Touch restarts the timer on activity.
 // Uses a cancel channel so cancelTimer can cleanly terminate the goroutine.
-func (c *Client) scheduleStop(id string, seconds int) {
+func (c *Client) scheduleStop(id, name string, seconds int, idle bool, end time.Time) {
 	d := time.Duration(seconds) * time.Second
-	timer := time.NewTimer(d)
+	c.armStop(id, name, d, d, idle, end)
+}
+
+// armStop creates a timer that auto-stops the sandbox after remaining, but no
+// later than end. window is the full timeout Touch re-arms in idle mode.
+func (c *Client) armStop(id, name string, window, remaining time.Duration, idle bool, end time.Time) {
+	now := time.Now()
+	remaining = clampToLifetime(remaining, end, now)
+	timer := time.NewTimer(remaining)
 	cancel := make(chan struct{})
 
 	c.timers.Store(id, &timerEntry{
 		timer:     timer,
 		cancel:    cancel,
-		expiresAt: time.Now().Add(d),
+		expiresAt: now.Add(remaining),
+		window:    window,
+		idle:      idle,
+		name:      name,
+		end:       end,
 	})
 
 	go func() {
 		select {
 		case <-timer.C:
-			c.timers.Delete(id)
-			c.cli.ContainerStop(context.Background(), id, moby.ContainerStopOptions{})
+			c.expire(id, cancel)
 		case <-cancel:
 			// Timer was cancelled; stop it and drain the channel if needed.
 			if !timer.Stop() {
@@ -1108,6 +1733,40 @@ func (c *Client) scheduleStop(id string, seconds int) {
 	}()
 }
 
+// expire stops a sandbox whose timer fired. It waits for a lifecycle operation
+// in progress to finish, then does nothing if that operation cancelled or
+// re-armed the timer, identified by its cancel channel, or left the sandbox
+// stopped.
+func (c *Client) expire(id string, cancel chan struct{}) {
+	unlock := c.waitLockSandbox(id, "expire", cancel)
+	if unlock == nil {
+		return
+	}
+	defer unlock()
+
+	entry := c.getTimerEntry(id)
+	if entry == nil || entry.cancel != cancel || !c.timers.CompareAndDelete(id, entry) {
+		return
+	}
+	ctx := context.Background()
+	info, err := c.cli.ContainerInspect(ctx, id, moby.ContainerInspectOptions{})
+	if err != nil || !info.Container.State.Running {
+		return
+	}
+	c.runStoredHook(ctx, id, HookBeforeStop)
+	c.cancelReady(id)
+	c.cli.ContainerStop(ctx, id, moby.ContainerStopOptions{})
+	c.setState(ctx, id, database.SandboxExpired)
+	c.invalidateCache(id)
+}
+
+// setState records the state of a sandbox for the proxy's error pages.
+func (c *Client) setState(ctx context.Context, id, state string) {
+	if err := c.repo.UpdateState(id, state); err != nil {
+		logging.FromContext(ctx).Error("database: failed to update state", "sandbox_id", id, "err", err)
+	}
+}
+
 // cancelTimer stops and removes the expiration timer for a sandbox.
 func (c *Client) cancelTimer(id string) {
 	if v, ok := c.timers.LoadAndDelete(id); ok {
@@ -1135,6 +1794,34 @@ func wrapNotFound(err error) error {
 	return err
 }
 
+// EffectiveResources returns the memory (MB) and CPUs a sandbox gets for the
+// requested limits, filling in the defaults (1GB RAM, 1 vCPU).
+func EffectiveResources(r *models.ResourceLimits) (int64, float64) {
+	memory := int64(defaultMemoryMB)
+	cpus := defaultCPUs
+	if r != nil {
+		if r.Memory > 0 {
+			memory = r.Memory
+		}
+		if r.CPUs > 0 {
+			cpus = r.CPUs
+		}
+	}
+	return memory, cpus
+}
+
+// startupCmd returns the container command. Without an explicit cmd or entrypoint
+// the sandbox idles on "sleep infinity" so commands can be exec'd into it.
+func startupCmd(req models.CreateSandboxRequest) []string {
+	if len(req.Cmd) > 0 {
+		return req.Cmd
+	}
+	if len(req.Entrypoint) > 0 {
+		return nil
+	}
+	return []string{"sleep", "infinity"}
+}
+
 // normalizePort ensures a port spec has a protocol suffix.
 // "3000" → "3000/tcp", "3000/tcp" → "3000/tcp" (unchanged).
 func normalizePort(port string) string {
@@ -1177,9 +1864,10 @@ func buildExposedPorts(ports []string) network.PortSet {
 	return ps
 }
 
-// buildPortBindings creates port bindings that only listen on 127.0.0.1 (loopback).
-// This ensures container ports are only reachable through the reverse proxy, not directly.
-func buildPortBindings(ports []string) network.PortMap {
+// buildPortBindings publishes ports on hostIP, taking host ports from
+// hostPorts ("" = an ephemeral port each). With the default loopback address
+// container ports are only reachable through the reverse proxy, not directly.
+func buildPortBindings(ports []string, hostIP netip.Addr, hostPorts string) network.PortMap {
 	if len(ports) == 0 {
 		return nil
 	}
@@ -1189,7 +1877,7 @@ func buildPortBindings(ports []string) network.PortMap {
 		if err != nil {
 			continue
 		}
-		pm[parsed] = []network.PortBinding{{HostIP: netip.MustParseAddr("127.0.0.1")}}
+		pm[parsed] = []network.PortBinding{{HostIP: hostIP, HostPort: hostPorts}}
 	}
 	if len(pm) == 0 {
 		return nil
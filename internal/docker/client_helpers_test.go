@@ -1,15 +1,37 @@
 package docker
 
 import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"io"
+	"maps"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/containerd/errdefs"
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/api/types/events"
+	"github.com/moby/moby/api/types/image"
 	"github.com/moby/moby/api/types/network"
+	moby "github.com/moby/moby/client"
 	"opensbx/internal/database"
+	"opensbx/internal/firewall"
+	"opensbx/models"
 )
 
 func TestNormalizePort(t *testing.T) {
@@ -56,11 +78,11 @@ func TestBuildExposedPorts(t *testing.T) {
 }
 
 func TestBuildPortBindings(t *testing.T) {
-	if pm := buildPortBindings(nil); pm != nil {
+	if pm := buildPortBindings(nil, DefaultHostIP, ""); pm != nil {
 		t.Fatalf("buildPortBindings(nil) should be nil")
 	}
 
-	pm := buildPortBindings([]string{"3000/tcp", "bad"})
+	pm := buildPortBindings([]string{"3000/tcp", "bad"}, DefaultHostIP, "")
 	if pm == nil {
 		t.Fatalf("buildPortBindings() should not be nil")
 	}
@@ -77,8 +99,37 @@ func TestBuildPortBindings(t *testing.T) {
 	}
 }
 
+func TestBuildPortBindings_HostIPAndRange(t *testing.T) {
+	pm := buildPortBindings([]string{"3000/tcp"}, netip.MustParseAddr("10.0.0.5"), "30000-30999")
+	b := pm[network.MustParsePort("3000/tcp")]
+	if len(b) != 1 || b[0].HostIP.String() != "10.0.0.5" || b[0].HostPort != "30000-30999" {
+		t.Fatalf("bindings = %+v, want 10.0.0.5:30000-30999", b)
+	}
+}
+
+func TestParsePortRange(t *testing.T) {
+	tests := []struct {
+		in          string
+		first, last int
+		ok          bool
+	}{
+		{"30000-30999", 30000, 30999, true},
+		{"8080", 8080, 8080, true},
+		{"0-100", 0, 0, false},
+		{"200-100", 0, 0, false},
+		{"1-70000", 0, 0, false},
+		{"a-b", 0, 0, false},
+	}
+	for _, tt := range tests {
+		first, last, err := ParsePortRange(tt.in)
+		if (err == nil) != tt.ok || first != tt.first || last != tt.last {
+			t.Errorf("ParsePortRange(%q) = %d, %d, %v", tt.in, first, last, err)
+		}
+	}
+}
+
 func TestExtractPortsAndPortKeys(t *testing.T) {
-	pm := buildPortBindings([]string{"3000/tcp", "8080/tcp"})
+	pm := buildPortBindings([]string{"3000/tcp", "8080/tcp"}, DefaultHostIP, "")
 	ports := extractPorts(pm)
 
 	if _, ok := ports["3000/tcp"]; !ok {
@@ -104,6 +155,59 @@ func TestContainerName(t *testing.T) {
 	}
 }
 
+func TestStartupCmd(t *testing.T) {
+	tests := []struct {
+		req  models.CreateSandboxRequest
+		want []string
+	}{
+		{models.CreateSandboxRequest{}, []string{"sleep", "infinity"}},
+		{models.CreateSandboxRequest{Cmd: []string{"npm", "run", "dev"}}, []string{"npm", "run", "dev"}},
+		{models.CreateSandboxRequest{Entrypoint: []string{"/start.sh"}}, nil},
+		{models.CreateSandboxRequest{Entrypoint: []string{"node"}, Cmd: []string{"server.js"}}, []string{"server.js"}},
+	}
+	for _, tt := range tests {
+		if got := startupCmd(tt.req); !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("startupCmd(%+v) = %v, want %v", tt.req, got, tt.want)
+		}
+	}
+}
+
+func TestStoppedFor(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		finishedAt string
+		want       bool
+	}{
+		{"2026-01-01T11:00:00Z", true},
+		{"2026-01-01T11:55:00.123456789Z", false},
+		{"0001-01-01T00:00:00Z", true},
+		{"", true},
+	}
+	for _, tt := range tests {
+		if got := stoppedFor(tt.finishedAt, 10*time.Minute, now); got != tt.want {
+			t.Fatalf("stoppedFor(%q) = %v, want %v", tt.finishedAt, got, tt.want)
+		}
+	}
+}
+
+func TestOwnerContext(t *testing.T) {
+	ctx := context.Background()
+	if got := OwnerFrom(ctx); got != "" {
+		t.Fatalf("OwnerFrom(background) = %q, want empty", got)
+	}
+	if got := OwnerFrom(WithOwner(ctx, "team-a")); got != "team-a" {
+		t.Fatalf("OwnerFrom() = %q, want team-a", got)
+	}
+	if WithOwner(ctx, "") != ctx {
+		t.Fatalf("WithOwner(ctx, \"\") should return ctx unchanged")
+	}
+
+	// Unrestricted callers never hit Docker or the database.
+	if err := (&Client{}).CheckOwner(ctx, "any"); err != nil {
+		t.Fatalf("CheckOwner(unrestricted) = %v, want nil", err)
+	}
+}
+
 func TestPortHelpers(t *testing.T) {
 	if got := portValue(3000); got != "3000" {
 		t.Fatalf("portValue(3000) = %q, want 3000", got)
@@ -143,7 +247,7 @@ func TestGenerateCmdID(t *testing.T) {
 
 func TestTimerHelpers(t *testing.T) {
 	c := &Client{}
-	c.scheduleStop("sb-1", 10)
+	c.scheduleStop("sb-1", "demo", 10, false, time.Time{})
 
 	entry := c.getTimerEntry("sb-1")
 	if entry == nil {
@@ -158,6 +262,119 @@ func TestTimerHelpers(t *testing.T) {
 	}
 }
 
+func TestTouchIdleTimer(t *testing.T) {
+	c := &Client{}
+	c.scheduleStop("idle-1", "idle-app", 60, true, time.Time{})
+	c.scheduleStop("abs-1", "abs-app", 60, false, time.Time{})
+	defer c.cancelTimer("idle-1")
+	defer c.cancelTimer("abs-1")
+
+	// Pretend both timers were armed a while ago.
+	for _, id := range []string{"idle-1", "abs-1"} {
+		entry := *c.getTimerEntry(id)
+		entry.expiresAt = entry.expiresAt.Add(-30 * time.Second)
+		c.timers.Store(id, &entry)
+	}
+	idleBefore := c.getTimerEntry("idle-1").expiresAt
+	absBefore := c.getTimerEntry("abs-1").expiresAt
+
+	c.TouchByName("idle-app")
+	c.Touch("abs-1")
+
+	if got := c.getTimerEntry("idle-1").expiresAt; !got.After(idleBefore) {
+		t.Fatalf("idle expiresAt = %v, want after %v", got, idleBefore)
+	}
+	if got := c.getTimerEntry("abs-1").expiresAt; !got.Equal(absBefore) {
+		t.Fatalf("absolute expiresAt changed: %v -> %v", absBefore, got)
+	}
+}
+
+func TestMaxLifetime(t *testing.T) {
+	c := &Client{}
+	if got := c.effectiveLifetime(3600); got != 3600 {
+		t.Errorf("effectiveLifetime without cap = %d, want 3600", got)
+	}
+	c.SetMaxLifetime(2 * time.Hour)
+	for _, tt := range []struct{ req, want int }{{0, 7200}, {3600, 3600}, {86400, 7200}} {
+		if got := c.effectiveLifetime(tt.req); got != tt.want {
+			t.Errorf("effectiveLifetime(%d) = %d, want %d", tt.req, got, tt.want)
+		}
+	}
+
+	// The timer never outlasts the lifetime, not even when idle activity re-arms it.
+	end := time.Now().Add(5 * time.Second)
+	c.scheduleStop("idle-1", "idle-app", 60, true, end)
+	defer c.cancelTimer("idle-1")
+	if got := c.getTimerEntry("idle-1").expiresAt; got.After(end) {
+		t.Fatalf("expiresAt = %v, want at most %v", got, end)
+	}
+	entry := *c.getTimerEntry("idle-1")
+	entry.expiresAt = entry.expiresAt.Add(-time.Minute)
+	c.timers.Store("idle-1", &entry)
+	c.Touch("idle-1")
+	if got := c.getTimerEntry("idle-1").expiresAt; got.After(end) {
+		t.Fatalf("expiresAt after Touch = %v, want at most %v", got, end)
+	}
+
+	repo := database.NewRepository(database.New(":memory:"))
+	created := time.Now().Add(-2 * time.Hour).UnixMilli()
+	for _, sb := range []database.Sandbox{
+		{ID: "old", CreatedAt: created, MaxLifetime: 3600},
+		{ID: "young", CreatedAt: created, MaxLifetime: 86400},
+		{ID: "unlimited", CreatedAt: created},
+	} {
+		if err := repo.Save(sb); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.repo = repo
+	if err := c.checkLifetime("old"); !errors.Is(err, ErrLifetimeExceeded) {
+		t.Errorf("checkLifetime(old) = %v, want ErrLifetimeExceeded", err)
+	}
+	for _, id := range []string{"young", "unlimited"} {
+		if err := c.checkLifetime(id); err != nil {
+			t.Errorf("checkLifetime(%s) = %v", id, err)
+		}
+	}
+}
+
+func TestDetachRecordsTimers(t *testing.T) {
+	repo := database.NewRepository(database.New(":memory:"))
+	if err := repo.Save(database.Sandbox{ID: "sb-1", Name: "demo"}); err != nil {
+		t.Fatal(err)
+	}
+	c := &Client{repo: repo}
+	c.scheduleStop("sb-1", "demo", 60, false, time.Time{})
+	want := c.getTimerEntry("sb-1").expiresAt.UnixMilli()
+
+	c.Detach()
+
+	if c.getTimerEntry("sb-1") != nil {
+		t.Fatalf("expected timer to be cancelled")
+	}
+	sb, _ := repo.FindByID("sb-1")
+	if sb == nil || sb.ExpiresAt != want {
+		t.Fatalf("ExpiresAt = %+v, want %d", sb, want)
+	}
+}
+
+func TestResumedWindow(t *testing.T) {
+	now := time.UnixMilli(1_700_000_000_000)
+	sb := database.Sandbox{ExpiresAt: now.Add(90 * time.Second).UnixMilli()}
+
+	window, remaining := resumedWindow(sb, "600", now)
+	if window != 10*time.Minute || remaining != 90*time.Second {
+		t.Fatalf("resumedWindow() = %v, %v, want 10m, 1m30s", window, remaining)
+	}
+	window, remaining = resumedWindow(sb, "", now)
+	if window != 90*time.Second || remaining != 90*time.Second {
+		t.Fatalf("resumedWindow() without label = %v, %v, want 1m30s, 1m30s", window, remaining)
+	}
+	if _, remaining := resumedWindow(sb, "600", now.Add(time.Hour)); remaining != 0 {
+		t.Fatalf("remaining after the deadline = %v, want 0", remaining)
+	}
+}
+
 func TestDBCommandToDetail(t *testing.T) {
 	c := &Client{}
 	exitCode := 0
@@ -183,3 +400,1593 @@ func TestDBCommandToDetail(t *testing.T) {
 		t.Fatalf("exit code mismatch: %+v", detail.ExitCode)
 	}
 }
+
+func TestNetworkIsolation(t *testing.T) {
+	n := network.Inspect{
+		Network: network.Network{
+			Name:    "opensbx-isolated",
+			Driver:  "bridge",
+			Options: map[string]string{iccOption: "false"},
+		},
+		Containers: map[string]network.EndpointResource{"self": {}, "other": {}},
+	}
+
+	got := networkIsolation(n, "self")
+	if got.InterContainer {
+		t.Fatalf("expected inter-container traffic disabled: %+v", got)
+	}
+	if got.Peers != 1 {
+		t.Fatalf("Peers = %d, want 1", got.Peers)
+	}
+
+	n.Options = nil
+	if got := networkIsolation(n, "self"); !got.InterContainer {
+		t.Fatalf("bridge without icc option should allow inter-container traffic")
+	}
+}
+
+func TestPolicyValidate(t *testing.T) {
+	p := Policy{AllowedDevices: []string{"/dev/fuse"}}
+	device := func(path string) container.Resources {
+		return container.Resources{Devices: []container.DeviceMapping{{PathOnHost: path, PathInContainer: path}}}
+	}
+
+	tests := []struct {
+		name    string
+		cfg     *container.HostConfig
+		wantErr bool
+	}{
+		{"default", &container.HostConfig{NetworkMode: "opensbx-isolated"}, false},
+		{"privileged", &container.HostConfig{Privileged: true}, true},
+		{"host pid", &container.HostConfig{PidMode: "host"}, true},
+		{"host ipc", &container.HostConfig{IpcMode: "host"}, true},
+		{"host network", &container.HostConfig{NetworkMode: "host"}, true},
+		{"cap add", &container.HostConfig{CapAdd: []string{"SYS_ADMIN"}}, true},
+		{"bind mount", &container.HostConfig{Binds: []string{"/:/host"}}, true},
+		{"allowed device", &container.HostConfig{Resources: device("/dev/fuse")}, false},
+		{"denied device", &container.HostConfig{Resources: device("/dev/kvm")}, true},
+	}
+
+	for _, tt := range tests {
+		err := p.Validate(tt.cfg)
+		if tt.wantErr != (err != nil) {
+			t.Fatalf("%s: Validate() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+		if err != nil && !errors.Is(err, ErrPolicyViolation) {
+			t.Fatalf("%s: error %v does not wrap ErrPolicyViolation", tt.name, err)
+		}
+	}
+}
+
+func TestMainPortFirst(t *testing.T) {
+	got := mainPortFirst([]string{"3000/tcp", "8080/tcp", "9000/tcp"}, "8080")
+	want := []string{"8080/tcp", "3000/tcp", "9000/tcp"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("mainPortFirst() = %v, want %v", got, want)
+	}
+}
+
+func TestRetagImageArchive(t *testing.T) {
+	archive := func(files map[string]string) *bytes.Buffer {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		for name, body := range files {
+			tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(body))})
+			tw.Write([]byte(body))
+		}
+		tw.Close()
+		return &buf
+	}
+	const want = "opensbx-export/web:1"
+	const ref = "opensbx-import/abc:2"
+
+	var out bytes.Buffer
+	err := retagImageArchive(archive(map[string]string{
+		"blobs/sha256/aa": "layer",
+		"manifest.json":   `[{"Config":"blobs/sha256/cc","RepoTags":["opensbx-export/web:1"],"Layers":["blobs/sha256/aa"]}]`,
+		"repositories":    `{"ubuntu":{"latest":"cc"}}`,
+		"index.json":      `{"manifests":[]}`,
+		"oci-layout":      `{}`,
+	}), &out, want, ref)
+	if err != nil {
+		t.Fatalf("retagImageArchive() = %v", err)
+	}
+	got := map[string]string{}
+	tr := tar.NewReader(&out)
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			break
+		}
+		body, _ := io.ReadAll(tr)
+		got[hdr.Name] = string(body)
+	}
+	if len(got) != 2 || got["blobs/sha256/aa"] != "layer" {
+		t.Fatalf("retagged archive holds %v, want the layer and manifest.json only", got)
+	}
+	if want := `[{"Config":"blobs/sha256/cc","Layers":["blobs/sha256/aa"],"RepoTags":["opensbx-import/abc:2"]}]`; got["manifest.json"] != want {
+		t.Fatalf("manifest.json = %s, want %s", got["manifest.json"], want)
+	}
+
+	for _, tt := range []struct {
+		name     string
+		manifest string
+	}{
+		{"no manifest", ""},
+		{"two images", `[{"RepoTags":["opensbx-export/web:1"]},{"RepoTags":["ubuntu:latest"]}]`},
+		{"extra tag", `[{"RepoTags":["opensbx-export/web:1","ubuntu:latest"]}]`},
+		{"other tag", `[{"RepoTags":["ubuntu:latest"]}]`},
+		{"untagged", `[{"RepoTags":null}]`},
+	} {
+		files := map[string]string{"blobs/sha256/aa": "layer"}
+		if tt.manifest != "" {
+			files["./manifest.json"] = tt.manifest
+		}
+		if err := retagImageArchive(archive(files), io.Discard, want, ref); !errors.Is(err, ErrInvalidBundle) {
+			t.Errorf("%s: retagImageArchive() = %v, want ErrInvalidBundle", tt.name, err)
+		}
+	}
+
+	// Plain docker save archives may carry any tag, or none.
+	untagged := archive(map[string]string{"manifest.json": `[{"Config":"cc","RepoTags":null}]`})
+	if err := retagImageArchive(untagged, io.Discard, "", ref); err != nil {
+		t.Errorf("retagImageArchive(untagged, any tag) = %v", err)
+	}
+
+	if !sameImageRef("ubuntu", "docker.io/library/ubuntu:latest") || sameImageRef("ubuntu:24.04", "ubuntu") {
+		t.Errorf("sameImageRef does not normalize references")
+	}
+}
+
+func TestImportFormats(t *testing.T) {
+	archive := func(names ...string) []byte {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		for _, name := range names {
+			tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: 2})
+			tw.Write([]byte("{}"))
+		}
+		tw.Close()
+		return buf.Bytes()
+	}
+
+	for _, tt := range []struct {
+		name   string
+		body   []byte
+		bundle bool
+		saved  bool
+	}{
+		{"bundle", archive("metadata.json", "image.tar"), true, false},
+		{"docker save", archive("blobs/sha256/aa", "index.json", "manifest.json", "oci-layout"), false, true},
+		{"docker export", archive(".dockerenv", "bin/sh", "etc/passwd"), false, false},
+	} {
+		if got := isBundle(bufio.NewReader(bytes.NewReader(tt.body))); got != tt.bundle {
+			t.Errorf("%s: isBundle() = %v, want %v", tt.name, got, tt.bundle)
+		}
+		if tt.bundle {
+			continue
+		}
+		if got, err := isImageArchive(bytes.NewReader(tt.body)); err != nil || got != tt.saved {
+			t.Errorf("%s: isImageArchive() = %v, %v, want %v", tt.name, got, err, tt.saved)
+		}
+	}
+
+	for name, body := range map[string][]byte{"empty": archive(), "not a tar": []byte("hello")} {
+		if isBundle(bufio.NewReader(bytes.NewReader(body))) {
+			t.Errorf("%s: isBundle() = true", name)
+		}
+		if _, err := isImageArchive(bytes.NewReader(body)); !errors.Is(err, ErrInvalidBundle) {
+			t.Errorf("%s: isImageArchive() = %v, want ErrInvalidBundle", name, err)
+		}
+	}
+}
+
+func TestSpecHash(t *testing.T) {
+	a := models.ApplySandboxSpec{Name: "web", Image: "node:24", Ports: []string{"3000"}}
+	b := a
+	if specHash(a) != specHash(b) {
+		t.Fatalf("identical specs hash differently")
+	}
+	b.Files = []models.SeedFile{{Path: "/app/x", Content: "1"}}
+	if specHash(a) == specHash(b) {
+		t.Fatalf("different specs hash the same")
+	}
+}
+
+func TestArchiveConversionRoundTrip(t *testing.T) {
+	var zipBuf bytes.Buffer
+	zw := zip.NewWriter(&zipBuf)
+	zw.Create("src/")
+	f, _ := zw.Create("src/main.go")
+	f.Write([]byte("package main\x00\xff"))
+	zw.Close()
+
+	zr, err := zip.NewReader(bytes.NewReader(zipBuf.Bytes()), int64(zipBuf.Len()))
+	if err != nil {
+		t.Fatalf("zip.NewReader() error: %v", err)
+	}
+	var tarBuf bytes.Buffer
+	if err := zipToTar(zr, &tarBuf); err != nil {
+		t.Fatalf("zipToTar() error: %v", err)
+	}
+
+	var outBuf bytes.Buffer
+	if err := tarToZip(tar.NewReader(&tarBuf), &outBuf); err != nil {
+		t.Fatalf("tarToZip() error: %v", err)
+	}
+
+	out, err := zip.NewReader(bytes.NewReader(outBuf.Bytes()), int64(outBuf.Len()))
+	if err != nil {
+		t.Fatalf("zip.NewReader(out) error: %v", err)
+	}
+	if len(out.File) != 2 || out.File[0].Name != "src/" || out.File[1].Name != "src/main.go" {
+		t.Fatalf("unexpected entries: %+v", out.File)
+	}
+	rc, _ := out.File[1].Open()
+	content, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(content) != "package main\x00\xff" {
+		t.Fatalf("content = %q, binary data not preserved", content)
+	}
+}
+
+func TestEncodeRegistryAuth(t *testing.T) {
+	encoded, err := encodeRegistryAuth(&models.RegistryAuth{Username: "bot", Password: "p+/=", ServerAddress: "ghcr.io"})
+	if err != nil {
+		t.Fatalf("encodeRegistryAuth() error: %v", err)
+	}
+	raw, err := base64.URLEncoding.DecodeString(encoded)
+	if err != nil {
+		t.Fatalf("not base64url: %v", err)
+	}
+	var got map[string]string
+	json.Unmarshal(raw, &got)
+	if got["username"] != "bot" || got["password"] != "p+/=" || got["serveraddress"] != "ghcr.io" {
+		t.Fatalf("decoded auth = %v", got)
+	}
+}
+
+func TestNormalizeDomain(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "app.example.com", want: "app.example.com"},
+		{in: " App.Example.COM. ", want: "app.example.com"},
+		{in: "app.example.com:443", want: "app.example.com"},
+		{in: "localhost", wantErr: true},
+		{in: "bad_host.example.com", wantErr: true},
+		{in: "-app.example.com", wantErr: true},
+		{in: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := NormalizeDomain(tt.in)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidDomain) {
+				t.Fatalf("NormalizeDomain(%q) error = %v, want ErrInvalidDomain", tt.in, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Fatalf("NormalizeDomain(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestStoredCommandLogs(t *testing.T) {
+	repo := database.NewRepository(database.New(":memory:"))
+	c := &Client{repo: repo}
+	exitCode := 3
+	finishedAt := int64(20)
+	if err := repo.SaveCommand(database.Command{ID: "cmd_1", SandboxID: "sb1", Name: "make", ExitCode: &exitCode, StartedAt: 10, FinishedAt: &finishedAt}); err != nil {
+		t.Fatalf("SaveCommand() error: %v", err)
+	}
+
+	// Finished command whose logs were never persisted (e.g. pruned).
+	if _, err := c.GetCommandLogs(context.Background(), "sb1", "cmd_1"); !errors.Is(err, ErrCommandNotFound) {
+		t.Fatalf("GetCommandLogs() without stored logs error = %v, want ErrCommandNotFound", err)
+	}
+
+	if err := repo.SaveCommandLog(database.CommandLog{CommandID: "cmd_1", SandboxID: "sb1", Stdout: "out\n", Stderr: "err\n", FinishedAt: finishedAt}); err != nil {
+		t.Fatalf("SaveCommandLog() error: %v", err)
+	}
+
+	logs, err := c.GetCommandLogs(context.Background(), "sb1", "cmd_1")
+	if err != nil {
+		t.Fatalf("GetCommandLogs() error: %v", err)
+	}
+	if logs.Stdout != "out\n" || logs.Stderr != "err\n" || logs.ExitCode == nil || *logs.ExitCode != 3 {
+		t.Fatalf("GetCommandLogs() = %+v", logs)
+	}
+
+	stdout, stderr, err := c.StreamCommandLogs(context.Background(), "sb1", "cmd_1")
+	if err != nil {
+		t.Fatalf("StreamCommandLogs() error: %v", err)
+	}
+	out, _ := io.ReadAll(stdout)
+	errOut, _ := io.ReadAll(stderr)
+	if string(out) != "out\n" || string(errOut) != "err\n" {
+		t.Fatalf("StreamCommandLogs() = %q, %q", out, errOut)
+	}
+
+	if _, err := c.GetCommandLogs(context.Background(), "other", "cmd_1"); !errors.Is(err, ErrCommandNotFound) {
+		t.Fatalf("GetCommandLogs() for another sandbox error = %v, want ErrCommandNotFound", err)
+	}
+}
+
+func TestNsPID(t *testing.T) {
+	status := "Name:\tsleep\nTgid:\t4242\nPid:\t4242\nNSpid:\t4242\t17\n"
+	if pid, err := nsPID(status); err != nil || pid != 17 {
+		t.Fatalf("nsPID() = %d, %v, want 17", pid, err)
+	}
+	if pid, err := nsPID("Pid:\t1\nNSpid:\t1\n"); err != nil || pid != 1 {
+		t.Fatalf("nsPID(no namespace) = %d, %v, want 1", pid, err)
+	}
+	if _, err := nsPID("Pid:\t1\n"); err == nil {
+		t.Fatalf("nsPID() without NSpid should fail")
+	}
+}
+
+func TestCleanEnvCmd(t *testing.T) {
+	got := cleanEnvCmd([]string{"node", "app.js"}, map[string]string{"B": "2", "A": "1"})
+	want := []string{"env", "-i", "PATH=" + defaultExecPath, "A=1", "B=2", "node", "app.js"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("cleanEnvCmd() = %v, want %v", got, want)
+	}
+
+	got = cleanEnvCmd([]string{"ls"}, map[string]string{"PATH": "/bin"})
+	want = []string{"env", "-i", "PATH=/bin", "ls"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("cleanEnvCmd() with PATH = %v, want %v", got, want)
+	}
+}
+
+func TestProcessDetail(t *testing.T) {
+	c := &Client{}
+	p := database.Process{SandboxID: "sb1", Name: "web", Command: "npm", Args: `["run","dev"]`, Restart: RestartOnFailure, CreatedAt: 1000}
+
+	detail := c.processDetail(p)
+	if detail.Status != ProcessStopped || detail.CommandID != "" {
+		t.Fatalf("unsupervised processDetail() = %+v", detail)
+	}
+	if !reflect.DeepEqual(detail.Args, []string{"run", "dev"}) || !detail.CreatedAt.Equal(time.UnixMilli(1000)) {
+		t.Fatalf("processDetail() = %+v", detail)
+	}
+
+	c.processes.Store(processKey("sb1", "web"), &supervisedProcess{cancel: func() {}, status: ProcessBackoff, cmdID: "cmd_1", restarts: 3})
+	detail = c.processDetail(p)
+	if detail.Status != ProcessBackoff || detail.CommandID != "cmd_1" || detail.Restarts != 3 {
+		t.Fatalf("supervised processDetail() = %+v", detail)
+	}
+
+	req := processRequest(p)
+	if req.Command != "npm" || !reflect.DeepEqual(req.Args, []string{"run", "dev"}) {
+		t.Fatalf("processRequest() = %+v", req)
+	}
+
+	start := models.StartProcessRequest{Name: "web", Command: "npm", Args: []string{"run", "dev"}, Cwd: "/app", Env: map[string]string{"PORT": "3000"}, Restart: RestartOnFailure}
+	if got := startRequest(newProcess("sb1", start)); !reflect.DeepEqual(got, start) {
+		t.Fatalf("startRequest(newProcess()) = %+v, want %+v", got, start)
+	}
+	if got := newProcess("sb1", models.StartProcessRequest{Name: "w", Command: "x"}); got.Restart != RestartAlways {
+		t.Fatalf("newProcess() restart = %q, want %q", got.Restart, RestartAlways)
+	}
+
+	cancelled := false
+	c.processes.Store(processKey("sb1", "worker"), &supervisedProcess{cancel: func() { cancelled = true }})
+	c.processes.Store(processKey("sb2", "web"), &supervisedProcess{cancel: func() { t.Fatalf("stopped another sandbox's supervisor") }})
+	c.stopSupervisors("sb1")
+	if !cancelled {
+		t.Fatalf("stopSupervisors() did not cancel sb1/worker")
+	}
+	if _, ok := c.processes.Load(processKey("sb2", "web")); !ok {
+		t.Fatalf("stopSupervisors() removed sb2/web")
+	}
+}
+
+func TestParseTop(t *testing.T) {
+	titles := []string{"PID", "USER", "%CPU", "%MEM", "COMMAND"}
+	rows := [][]string{
+		{"4242", "root", "0.0", "0.1", "sleep infinity"},
+		{"4300", "1000", "12.5", "3.4", "node server.js --port 3000"},
+		{"bogus", "root", "0", "0", "ignored"},
+	}
+
+	got := parseTop(titles, rows)
+	want := []models.ProcessInfo{
+		{HostPID: 4242, User: "root", CPU: 0, Memory: 0.1, Command: "sleep infinity"},
+		{HostPID: 4300, User: "1000", CPU: 12.5, Memory: 3.4, Command: "node server.js --port 3000"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("parseTop() = %+v, want %+v", got, want)
+	}
+
+	// Docker's default "ps -ef" layout.
+	got = parseTop([]string{"UID", "PID", "PPID", "C", "STIME", "TTY", "TIME", "CMD"}, [][]string{{"root", "7", "1", "0", "10:00", "?", "00:00:00", "sh"}})
+	if len(got) != 1 || got[0].HostPID != 7 || got[0].User != "root" || got[0].Command != "sh" {
+		t.Fatalf("parseTop(ps -ef) = %+v", got)
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"**/*.ts", "a.ts", true},
+		{"**/*.ts", "src/lib/a.ts", true},
+		{"**/*.ts", "src/a.tsx", false},
+		{"src/**", "src/a/b", true},
+		{"src/**/test/*.go", "src/test/a.go", true},
+		{"src/**/test/*.go", "src/x/y/test/a.go", true},
+		{"*.go", "cmd/main.go", false},
+		{"cmd/*.go", "cmd/main.go", true},
+		{"[ab].txt", "b.txt", true},
+	}
+	for _, tt := range tests {
+		if got := MatchGlob(tt.pattern, tt.name); got != tt.want {
+			t.Fatalf("MatchGlob(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+
+	if ValidGlob("") || ValidGlob("src/[") || !ValidGlob("**/*.ts") {
+		t.Fatalf("ValidGlob() mismatch")
+	}
+}
+
+func TestBuildFileTree(t *testing.T) {
+	entries, truncated := parseWalk("d /app/src\nd /app/node_modules\nf /app/package.json\nf /app/src/main.ts\nl /app/src/link\nf /orphan/x\n")
+	if truncated || len(entries) != 6 {
+		t.Fatalf("parseWalk() = %+v, %v", entries, truncated)
+	}
+
+	tree := buildFileTree("/app", entries)
+	if len(tree) != 3 {
+		t.Fatalf("buildFileTree() top level = %+v", tree)
+	}
+	// Directories first, then files, each by name.
+	if tree[0].Name != "node_modules" || tree[1].Name != "src" || tree[2].Name != "package.json" {
+		t.Fatalf("buildFileTree() order = %+v", tree)
+	}
+	src := tree[1]
+	if len(src.Children) != 2 || src.Children[0].Name != "link" || src.Children[0].Type != FileTypeSymlink || src.Children[1].Path != "/app/src/main.ts" {
+		t.Fatalf("buildFileTree() src = %+v", src)
+	}
+
+	root := buildFileTree("/", []walkEntry{{path: "/bin", typ: FileTypeDir}, {path: "/bin/sh", typ: FileTypeFile}})
+	if len(root) != 1 || len(root[0].Children) != 1 {
+		t.Fatalf("buildFileTree(/) = %+v", root)
+	}
+}
+
+func TestFileStat(t *testing.T) {
+	mtime := time.Date(2025, 1, 1, 12, 0, 0, 0, time.FixedZone("x", 3600))
+	st := fileStat("/app/run.sh", 42, 0o755, mtime, "")
+	if st.Name != "run.sh" || st.Type != FileTypeFile || st.Mode != "0755" || st.Permissions != "-rwxr-xr-x" || !st.ModTime.Equal(mtime) {
+		t.Fatalf("fileStat(file) = %+v", st)
+	}
+
+	st = fileStat("/app/link", 8, os.ModeSymlink|0o777, mtime, "/app/run.sh")
+	if st.Type != FileTypeSymlink || st.LinkTarget != "/app/run.sh" {
+		t.Fatalf("fileStat(symlink) = %+v", st)
+	}
+
+	if fileType(os.ModeDir|0o755) != FileTypeDir || fileType(os.ModeNamedPipe) != FileTypeOther {
+		t.Fatalf("fileType() mismatch")
+	}
+}
+
+func TestExecResultCheck(t *testing.T) {
+	if err := (execResult{stderr: "warning"}).check(); err != nil {
+		t.Fatalf("check(exit 0) = %v", err)
+	}
+
+	err := execResult{exitCode: 2, stderr: "ls: cannot access '/nope': No such file or directory\n"}.check()
+	if !errors.Is(err, ErrPathNotFound) || !strings.Contains(err.Error(), "cannot access '/nope'") {
+		t.Fatalf("check(missing) = %v", err)
+	}
+
+	err = execResult{exitCode: 1, stderr: "mkdir: can't create directory '/a/b': Not a directory"}.check()
+	if !errors.Is(err, ErrNotADirectory) {
+		t.Fatalf("check(not a dir) = %v", err)
+	}
+
+	err = execResult{exitCode: 1, stderr: "rm: can't remove '/proc/1': Permission denied"}.check()
+	if err == nil || errors.Is(err, ErrPathNotFound) || !strings.Contains(err.Error(), "Permission denied") {
+		t.Fatalf("check(other) = %v", err)
+	}
+
+	if err := (execResult{exitCode: 7}).check(); err == nil || err.Error() != "exit code 7" {
+		t.Fatalf("check(no stderr) = %v", err)
+	}
+}
+
+func TestNetworkPolicyRoundTrip(t *testing.T) {
+	p, err := networkPolicy(models.CreateSandboxRequest{
+		Egress:    &models.EgressPolicy{Allow: []string{"140.82.112.0/20", "1.1.1.1:53", ":443"}},
+		Bandwidth: &models.BandwidthLimit{IngressKbps: 8000},
+	})
+	if err != nil || len(p.Allow) != 3 || p.IngressKbps != 8000 || p.Empty() {
+		t.Fatalf("networkPolicy() = %+v, %v", p, err)
+	}
+
+	stored, err := storedNetworkPolicy(&database.Sandbox{EgressAllow: joinRules(p.Allow), IngressKbps: p.IngressKbps})
+	if err != nil || !reflect.DeepEqual(stored, p) {
+		t.Fatalf("storedNetworkPolicy() = %+v, %v, want %+v", stored, err, p)
+	}
+
+	if p, _ := networkPolicy(models.CreateSandboxRequest{}); !p.Empty() {
+		t.Fatalf("networkPolicy(empty) = %+v", p)
+	}
+}
+
+func TestHardeningSecurity(t *testing.T) {
+	defaults := Hardening{NoNewPrivileges: true, CapDrop: []string{"NET_RAW"}, PidsLimit: 512, User: "1000:1000"}
+	yes, no := true, false
+
+	eff, err := defaults.Security(&models.SecurityOptions{ReadOnlyRootfs: &yes, CapDrop: []string{"MKNOD", "NET_RAW"}, PidsLimit: 64})
+	if err != nil || !eff.ReadOnlyRootfs || !eff.NoNewPrivileges || eff.PidsLimit != 64 || !reflect.DeepEqual(eff.CapDrop, []string{"NET_RAW", "MKNOD"}) {
+		t.Fatalf("Security(tighten) = %+v, %v", eff, err)
+	}
+	if !reflect.DeepEqual(defaults.CapDrop, []string{"NET_RAW"}) {
+		t.Fatalf("Security() modified the defaults: %v", defaults.CapDrop)
+	}
+
+	for _, opts := range []*models.SecurityOptions{
+		{NoNewPrivileges: &no},
+		{PidsLimit: 4096},
+		{User: "root"},
+		{User: "0:0"},
+	} {
+		if _, err := defaults.Security(opts); !errors.Is(err, ErrPolicyViolation) {
+			t.Fatalf("Security(%+v) error = %v, want ErrPolicyViolation", opts, err)
+		}
+	}
+
+	cfg, hostCfg := &container.Config{}, &container.HostConfig{}
+	eff.apply(cfg, hostCfg)
+	if !hostCfg.ReadonlyRootfs || hostCfg.Tmpfs["/tmp"] == "" || *hostCfg.Resources.PidsLimit != 64 || cfg.User != "1000:1000" ||
+		!reflect.DeepEqual(hostCfg.SecurityOpt, []string{"no-new-privileges:true"}) {
+		t.Fatalf("apply() = %+v, %+v", cfg, hostCfg)
+	}
+}
+
+func TestPolicyRejectsUnconfined(t *testing.T) {
+	for _, opt := range []string{"seccomp=unconfined", "seccomp:unconfined", "apparmor=unconfined", "label=disable"} {
+		err := Policy{}.Validate(&container.HostConfig{SecurityOpt: []string{opt}})
+		if !errors.Is(err, ErrPolicyViolation) {
+			t.Fatalf("Validate(%s) = %v, want ErrPolicyViolation", opt, err)
+		}
+	}
+	if err := (Policy{}).Validate(&container.HostConfig{SecurityOpt: []string{"no-new-privileges:true", `seccomp={"defaultAction":"SCMP_ACT_ERRNO"}`}}); err != nil {
+		t.Fatalf("Validate(hardened) = %v", err)
+	}
+}
+
+func TestPolicyAllowedRuntimes(t *testing.T) {
+	p := Policy{AllowedRuntimes: []string{"runsc"}}
+	if err := p.Validate(&container.HostConfig{Runtime: "runsc"}); err != nil {
+		t.Fatalf("Validate(runsc) = %v", err)
+	}
+	for _, runtime := range []string{"runc", ""} {
+		if err := p.Validate(&container.HostConfig{Runtime: runtime}); !errors.Is(err, ErrPolicyViolation) {
+			t.Fatalf("Validate(%q) = %v, want ErrPolicyViolation", runtime, err)
+		}
+	}
+	if err := (Policy{}).Validate(&container.HostConfig{Runtime: "kata"}); err != nil {
+		t.Fatalf("Validate(no allowlist) = %v", err)
+	}
+}
+
+func TestValidateLabels(t *testing.T) {
+	if msg := ValidateLabels(map[string]string{"team": "ml", "com.example/job-id": "42", "empty": ""}); msg != "" {
+		t.Fatalf("ValidateLabels(valid) = %q", msg)
+	}
+	for _, key := range []string{"", "-team", "team ", `a"b`, "opensbx.managed", strings.Repeat("k", MaxLabelKeyLen+1)} {
+		if msg := ValidateLabels(map[string]string{key: "x"}); msg == "" {
+			t.Fatalf("ValidateLabels(%q) accepted an invalid key", key)
+		}
+	}
+	if msg := ValidateLabels(map[string]string{"team": strings.Repeat("v", MaxLabelValueLen+1)}); msg == "" {
+		t.Fatal("ValidateLabels accepted a long value")
+	}
+
+	sel, msg := ParseLabelSelectors([]string{"team=ml", "job", "note="})
+	if msg != "" {
+		t.Fatalf("ParseLabelSelectors() = %q", msg)
+	}
+	if sel.Equals["team"] != "ml" || sel.Equals["note"] != "" || len(sel.Equals) != 2 || len(sel.Keys) != 1 || sel.Keys[0] != "job" {
+		t.Fatalf("ParseLabelSelectors() = %+v", sel)
+	}
+	if _, msg := ParseLabelSelectors([]string{"=ml"}); msg == "" {
+		t.Fatal("ParseLabelSelectors accepted an empty key")
+	}
+}
+
+func TestManagedLabels(t *testing.T) {
+	labels := managedLabels("web", "", 900, "")
+	want := map[string]string{LabelManaged: "true", LabelName: "web", LabelTimeout: "900", LabelExpirationAction: ExpirationStop}
+	if !maps.Equal(labels, want) {
+		t.Fatalf("managedLabels() = %v, want %v", labels, want)
+	}
+	if !isManaged(labels) || isManaged(map[string]string{"team": "ml"}) {
+		t.Fatal("isManaged() misreported the managed label")
+	}
+	if got := managedLabels("web", "key-1", 60, ExpirationDelete)[LabelOwner]; got != "key-1" {
+		t.Fatalf("owner label = %q", got)
+	}
+
+	labels["team"] = "ml"
+	if got := callerLabels(labels); !maps.Equal(got, map[string]string{"team": "ml"}) {
+		t.Fatalf("callerLabels() = %v", got)
+	}
+	if got := managedFilter(); !got["label"][LabelManaged+"=true"] {
+		t.Fatalf("managedFilter() = %v", got)
+	}
+}
+
+func TestGPUDeviceRequests(t *testing.T) {
+	hostCfg := &container.HostConfig{}
+	hostCfg.Resources.DeviceRequests = gpuDeviceRequests(&models.GPUs{Count: -1})
+	if err := (Policy{}).Validate(hostCfg); !errors.Is(err, ErrPolicyViolation) {
+		t.Fatalf("Validate(gpus denied) = %v, want ErrPolicyViolation", err)
+	}
+	if err := (Policy{AllowGPUs: true}).Validate(hostCfg); err != nil {
+		t.Fatalf("Validate(gpus allowed) = %v", err)
+	}
+
+	if got := containerGPUs(hostCfg.Resources.DeviceRequests); got == nil || got.Count != -1 {
+		t.Fatalf("containerGPUs(all) = %+v", got)
+	}
+	reqs := gpuDeviceRequests(&models.GPUs{DeviceIDs: []string{"0", "2"}})
+	if reqs[0].Count != 0 || len(reqs[0].DeviceIDs) != 2 {
+		t.Fatalf("gpuDeviceRequests(ids) = %+v", reqs)
+	}
+	if got := containerGPUs(reqs); got == nil || len(got.DeviceIDs) != 2 {
+		t.Fatalf("containerGPUs(ids) = %+v", got)
+	}
+	if gpuDeviceRequests(nil) != nil || containerGPUs(nil) != nil {
+		t.Fatal("no gpus should map to no device requests")
+	}
+}
+
+func TestPlanImageGC(t *testing.T) {
+	images := []image.Summary{
+		{ID: "used"},
+		{ID: "foreign"},
+		{ID: "foreign-used"},
+		{ID: "recent"},
+		{ID: "stale"},
+		{ID: "snap", Labels: map[string]string{LabelSnapshot: "web"}},
+	}
+	inUse := map[string]bool{"used": true, "foreign-used": true}
+	lastUsed := map[string]int64{"used": 10, "recent": 900, "stale": 100, "snap": 100}
+
+	remove, touch := planImageGC(images, inUse, lastUsed, 500)
+	if len(remove) != 1 || remove[0].ID != "stale" {
+		t.Fatalf("remove = %v, want only stale", remove)
+	}
+	if !reflect.DeepEqual(touch, []string{"used"}) {
+		t.Fatalf("touch = %v, want [used]", touch)
+	}
+}
+
+func TestSnapshotNamespace(t *testing.T) {
+	if got := snapshotRepo(""); got != "opensbx-snapshot/" {
+		t.Errorf("snapshotRepo(\"\") = %q", got)
+	}
+	if got := snapshotRepo("Team A/B"); got != "opensbx-snapshot/team-a-b/" {
+		t.Errorf("snapshotRepo(Team A/B) = %q", got)
+	}
+	for _, tc := range []struct {
+		ref, owner string
+		want       bool
+	}{
+		{"opensbx-snapshot/team-a/web:1", "team-a", true},
+		{"opensbx-snapshot/team-a/web", "team-a", true},
+		{"opensbx-snapshot/team-b/web:1", "team-a", false},
+		{"opensbx-snapshot/team-a-x/web:1", "team-a", false},
+		{"node:24", "team-a", false},
+		{"docker.io/library/node:24", "", false},
+		{"opensbx-snapshot/web:1", "", true},
+		{"Not A Ref", "", false},
+	} {
+		if got := InSnapshotNamespace(tc.ref, tc.owner); got != tc.want {
+			t.Errorf("InSnapshotNamespace(%q, %q) = %v, want %v", tc.ref, tc.owner, got, tc.want)
+		}
+	}
+
+	snap := map[string]string{LabelSnapshot: "web", LabelSnapshotOwner: "team-a"}
+	if !canReplaceImage(snap, "team-a") {
+		t.Error("an owner should replace its own snapshot")
+	}
+	if canReplaceImage(snap, "team-b") || canReplaceImage(snap, "") {
+		t.Error("another owner replaced a snapshot")
+	}
+	if canReplaceImage(map[string]string{"maintainer": "node"}, "") || canReplaceImage(nil, "") {
+		t.Error("a base image was replaced by a snapshot")
+	}
+}
+
+func TestCheckpointName(t *testing.T) {
+	if got, _ := checkpointName("", time.Unix(1700000000, 0)); got != "cp-1700000000" {
+		t.Fatalf("default name = %q", got)
+	}
+	if got, err := checkpointName("before-migration_1.2", time.Now()); err != nil || got != "before-migration_1.2" {
+		t.Fatalf("checkpointName() = %q, %v", got, err)
+	}
+	for _, bad := range []string{"-lead", "a/b", "../x", "has space"} {
+		if _, err := checkpointName(bad, time.Now()); !errors.Is(err, ErrInvalidCheckpoint) {
+			t.Fatalf("checkpointName(%q) err = %v, want ErrInvalidCheckpoint", bad, err)
+		}
+	}
+}
+
+func TestParseEnv(t *testing.T) {
+	got := parseEnv([]string{"PATH=/bin", "EMPTY=", "EQ=a=b"})
+	want := map[string]string{"PATH": "/bin", "EMPTY": "", "EQ": "a=b"}
+	if !maps.Equal(got, want) {
+		t.Fatalf("parseEnv() = %v, want %v", got, want)
+	}
+	for _, bad := range []string{"", "A=B", "A\x00"} {
+		if err := validateEnvName(bad); !errors.Is(err, ErrInvalidEnv) {
+			t.Fatalf("validateEnvName(%q) = %v", bad, err)
+		}
+	}
+}
+
+// fakeSecrets resolves secrets from a map keyed by owner and name.
+type fakeSecrets map[string]string
+
+func (f fakeSecrets) Resolve(owner string, names []string) (map[string]string, error) {
+	values := map[string]string{}
+	for _, n := range names {
+		v, ok := f[owner+"/"+n]
+		if !ok {
+			return nil, errors.New("secret not found: " + n)
+		}
+		values[n] = v
+	}
+	return values, nil
+}
+
+func TestCreateEnv(t *testing.T) {
+	c := &Client{}
+	if _, err := c.createEnv(context.Background(), nil, []string{"TOKEN"}); !errors.Is(err, ErrSecretsDisabled) {
+		t.Fatalf("createEnv() without store err = %v", err)
+	}
+	if got, _ := c.createEnv(context.Background(), []string{"A=1"}, nil); !reflect.DeepEqual(got, []string{"A=1"}) {
+		t.Fatalf("createEnv() without secrets = %v", got)
+	}
+
+	c.SetSecretResolver(fakeSecrets{"team-a/TOKEN": "s3cret", "team-a/KEY": "k"})
+	ctx := WithOwner(context.Background(), "team-a")
+	got, err := c.createEnv(ctx, []string{"TOKEN=override"}, []string{"TOKEN", "KEY", "TOKEN"})
+	if err != nil {
+		t.Fatalf("createEnv() error: %v", err)
+	}
+	if want := []string{"KEY=k", "TOKEN=s3cret", "TOKEN=override"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("createEnv() = %v, want %v", got, want)
+	}
+	if _, err := c.createEnv(context.Background(), nil, []string{"TOKEN"}); err == nil {
+		t.Fatal("createEnv() resolved another owner's secret")
+	}
+}
+
+func TestCloneRequest(t *testing.T) {
+	pids := int64(128)
+	cfg := &container.Config{
+		Env:        []string{"PATH=/usr/bin", "A=1"},
+		Cmd:        []string{"npm", "run", "dev"},
+		WorkingDir: "/app",
+		User:       "1000",
+		Labels:     map[string]string{"team": "ml", LabelManaged: "true", LabelTimeout: "600"},
+	}
+	hostCfg := &container.HostConfig{
+		Runtime:        "runsc",
+		ReadonlyRootfs: true,
+		SecurityOpt:    []string{"no-new-privileges:true"},
+		CapDrop:        []string{"NET_RAW"},
+		Resources:      container.Resources{Memory: 512 * 1024 * 1024, NanoCPUs: 5e8, PidsLimit: &pids},
+	}
+	sb := &database.Sandbox{
+		Image:       "node:24",
+		Network:     "internal",
+		EgressDeny:  "10.0.0.0/8,:25",
+		IngressKbps: 1000,
+		TimeoutMode: TimeoutIdle,
+		ReadyCheck:  `{"path":"/health"}`,
+	}
+
+	req, err := cloneRequest(cfg, hostCfg, sb)
+	if err != nil {
+		t.Fatalf("cloneRequest() error: %v", err)
+	}
+	if req.Image != "node:24" || req.Timeout != 600 || req.TimeoutMode != TimeoutIdle || req.Network != "internal" || req.Runtime != "runsc" {
+		t.Fatalf("cloneRequest() = %+v", req)
+	}
+	if !reflect.DeepEqual(req.Labels, map[string]string{"team": "ml"}) {
+		t.Fatalf("Labels = %v, want caller labels only", req.Labels)
+	}
+	if req.Resources.Memory != 512 || req.Resources.CPUs != 0.5 {
+		t.Fatalf("Resources = %+v", req.Resources)
+	}
+	if req.Egress == nil || !reflect.DeepEqual(req.Egress.Deny, []string{"10.0.0.0/8", ":25"}) || req.Egress.Allow != nil {
+		t.Fatalf("Egress = %+v", req.Egress)
+	}
+	if req.Bandwidth == nil || req.Bandwidth.IngressKbps != 1000 {
+		t.Fatalf("Bandwidth = %+v", req.Bandwidth)
+	}
+	if req.ReadyCheck == nil || req.ReadyCheck.Path != "/health" {
+		t.Fatalf("ReadyCheck = %+v", req.ReadyCheck)
+	}
+
+	sec := req.Security
+	if sec.ReadOnlyRootfs == nil || !*sec.ReadOnlyRootfs || sec.NoNewPrivileges == nil || !*sec.NoNewPrivileges ||
+		sec.PidsLimit != 128 || sec.User != "1000" || !reflect.DeepEqual(sec.CapDrop, []string{"NET_RAW"}) {
+		t.Fatalf("Security = %+v", sec)
+	}
+	// The source was created under the same defaults, so its hardening passes them.
+	h := Hardening{ReadOnlyRootfs: true, NoNewPrivileges: true, CapDrop: []string{"NET_RAW"}, PidsLimit: 256}
+	if _, err := h.Security(sec); err != nil {
+		t.Fatalf("Security() rejected cloned options: %v", err)
+	}
+}
+
+func TestStackNames(t *testing.T) {
+	req := models.CreateStackRequest{Name: "shop", Services: map[string]models.CreateSandboxRequest{
+		"app":   {Image: "node:24", Ports: []string{"3000"}},
+		"db":    {Image: "postgres:17"},
+		"cache": {Image: "redis:8"},
+	}}
+	if got := StackWeb(req); got != "app" {
+		t.Fatalf("StackWeb() = %q, want the only service with ports", got)
+	}
+	req.Services["admin"] = models.CreateSandboxRequest{Image: "adminer", Ports: []string{"8080"}}
+	if got := StackWeb(req); got != "" {
+		t.Fatalf("StackWeb() with two services with ports = %q, want none", got)
+	}
+	req.Web = "admin"
+	if got := StackWeb(req); got != "admin" {
+		t.Fatalf("StackWeb() = %q, want the declared web service", got)
+	}
+
+	if got := StackSandboxName("shop", "app", "app"); got != "shop" {
+		t.Fatalf("StackSandboxName(web) = %q", got)
+	}
+	if got := StackSandboxName("shop", "db", "app"); got != "shop-db" {
+		t.Fatalf("StackSandboxName(db) = %q", got)
+	}
+	st := database.Stack{Name: "shop", Web: "app"}
+	for _, sb := range []database.Sandbox{{Name: "shop"}, {Name: "shop-db"}} {
+		if svc := stackService(sb, st); StackSandboxName("shop", svc, "app") != sb.Name {
+			t.Fatalf("stackService(%s) = %q does not round-trip", sb.Name, svc)
+		}
+	}
+}
+
+func TestLockSandbox(t *testing.T) {
+	c := &Client{}
+	unlock, err := c.lockSandbox("abc123", "stop")
+	if err != nil {
+		t.Fatalf("lockSandbox() error = %v", err)
+	}
+	if _, err := c.lockSandbox("abc123", "remove"); !errors.Is(err, ErrOperationInProgress) || !strings.Contains(err.Error(), "stop") {
+		t.Fatalf("second lockSandbox() error = %v, want ErrOperationInProgress naming stop", err)
+	}
+	other, err := c.lockSandbox("def456", "start")
+	if err != nil {
+		t.Fatalf("lockSandbox(other sandbox) error = %v", err)
+	}
+	other()
+
+	unlock()
+	unlock, err = c.lockSandbox("abc123", "remove")
+	if err != nil {
+		t.Fatalf("lockSandbox() after unlock error = %v", err)
+	}
+	unlock()
+}
+
+func TestLockResolvedByNameAndID(t *testing.T) {
+	const id = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	repo := database.NewRepository(database.New(":memory:"))
+	if err := repo.Save(database.Sandbox{ID: id, Name: "web"}); err != nil {
+		t.Fatal(err)
+	}
+	c := &Client{repo: repo}
+	ctx := context.Background()
+
+	locked, unlock, err := c.lockResolved(ctx, id, "stop")
+	if err != nil || locked != id {
+		t.Fatalf("lockResolved(id) = %q, %v", locked, err)
+	}
+	// The engine is nil: Stop must fail on the lock before it reaches it.
+	if err := c.Stop(ctx, "web"); !errors.Is(err, ErrOperationInProgress) {
+		t.Fatalf("Stop(name) while locked by ID = %v, want ErrOperationInProgress", err)
+	}
+	unlock()
+
+	locked, unlock, err = c.lockResolved(ctx, "web", "remove")
+	if err != nil || locked != id {
+		t.Fatalf("lockResolved(name) = %q, %v", locked, err)
+	}
+	if _, err := c.lockSandbox(id, "start"); !errors.Is(err, ErrOperationInProgress) || !strings.Contains(err.Error(), "remove") {
+		t.Fatalf("lockSandbox(id) while locked by name = %v, want ErrOperationInProgress naming remove", err)
+	}
+	unlock()
+}
+
+func TestExpireWaitsForOperation(t *testing.T) {
+	c := &Client{}
+	unlock, _ := c.lockSandbox("abc123", "restart")
+
+	// A timer cancelled while its sandbox is busy gives up.
+	cancel := make(chan struct{})
+	close(cancel)
+	if got := c.waitLockSandbox("abc123", "expire", cancel); got != nil {
+		t.Fatalf("waitLockSandbox(cancelled) returned a lock")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		// The restart re-armed the timer, so the fired one must not stop the
+		// sandbox. The nil engine would panic if it tried.
+		c.expire("abc123", make(chan struct{}))
+	}()
+	c.scheduleStop("abc123", "demo", 60, false, time.Time{})
+	defer c.cancelTimer("abc123")
+	time.Sleep(2 * opLockRetry)
+	select {
+	case <-done:
+		t.Fatalf("expire() ran while the sandbox was locked")
+	default:
+	}
+	unlock()
+	<-done
+	if c.getTimerEntry("abc123") == nil {
+		t.Fatalf("expire() removed the re-armed timer")
+	}
+}
+
+func TestIdempotentCreate(t *testing.T) {
+	c := &Client{repo: database.NewRepository(database.New(":memory:"))}
+	ctx := WithOwner(context.Background(), "team-a")
+
+	replay, err := c.BeginIdempotent(ctx, "req-1", "hash-1")
+	if err != nil || replay != nil {
+		t.Fatalf("BeginIdempotent() = %+v, %v, want a reservation", replay, err)
+	}
+	if _, err := c.BeginIdempotent(ctx, "req-1", "hash-1"); !errors.Is(err, ErrIdempotencyInProgress) {
+		t.Fatalf("BeginIdempotent() while running error = %v, want ErrIdempotencyInProgress", err)
+	}
+
+	if err := c.FinishIdempotent(ctx, "req-1", &models.CreateSandboxResponse{ID: "sb1", Name: "app"}); err != nil {
+		t.Fatalf("FinishIdempotent() error = %v", err)
+	}
+	replay, err = c.BeginIdempotent(ctx, "req-1", "hash-1")
+	if err != nil || replay == nil || replay.ID != "sb1" || replay.Name != "app" {
+		t.Fatalf("BeginIdempotent() after finish = %+v, %v, want the recorded sandbox", replay, err)
+	}
+	if _, err := c.BeginIdempotent(ctx, "req-1", "hash-2"); !errors.Is(err, ErrIdempotencyKeyReused) {
+		t.Fatalf("BeginIdempotent(other request) error = %v, want ErrIdempotencyKeyReused", err)
+	}
+	if replay, err := c.BeginIdempotent(WithOwner(context.Background(), "team-b"), "req-1", "hash-2"); err != nil || replay != nil {
+		t.Fatalf("BeginIdempotent(other owner) = %+v, %v, want a reservation", replay, err)
+	}
+
+	// A failed create releases the key for the retry.
+	c.BeginIdempotent(ctx, "req-2", "hash-1")
+	if err := c.FinishIdempotent(ctx, "req-2", nil); err != nil {
+		t.Fatalf("FinishIdempotent(nil) error = %v", err)
+	}
+	if replay, err := c.BeginIdempotent(ctx, "req-2", "hash-1"); err != nil || replay != nil {
+		t.Fatalf("BeginIdempotent() after failure = %+v, %v, want a new reservation", replay, err)
+	}
+
+	// A reservation left by a create that never finished is taken over.
+	c.repo.SaveIdempotencyKey(database.IdempotencyKey{Owner: "team-a", Key: "req-3", RequestHash: "hash-1",
+		CreatedAt: time.Now().Add(-idempotencyAbandoned - time.Minute).UnixMilli()})
+	if replay, err := c.BeginIdempotent(ctx, "req-3", "hash-1"); err != nil || replay != nil {
+		t.Fatalf("BeginIdempotent(abandoned) = %+v, %v, want a reservation", replay, err)
+	}
+}
+
+func TestStatsUsageConversion(t *testing.T) {
+	blkio := container.BlkioStats{IoServiceBytesRecursive: []container.BlkioStatEntry{
+		{Major: 8, Op: "Read", Value: 100}, // cgroup v1
+		{Major: 8, Op: "Write", Value: 40},
+		{Major: 8, Op: "Total", Value: 140},
+		{Major: 253, Op: "read", Value: 5}, // cgroup v2
+		{Major: 253, Op: "write", Value: 2},
+	}}
+	if got := blockIOUsage(blkio); got != (models.BlockIOUsage{ReadBytes: 105, WriteBytes: 42}) {
+		t.Fatalf("blockIOUsage() = %+v", got)
+	}
+
+	if got := networkUsage(nil); got != nil {
+		t.Fatalf("networkUsage(nil) = %+v, want nil", got)
+	}
+	got := networkUsage(map[string]container.NetworkStats{"eth0": {RxBytes: 10, RxPackets: 1, TxBytes: 20, TxPackets: 2, RxErrors: 7}})
+	if got["eth0"] != (models.NetworkUsage{RxBytes: 10, RxPackets: 1, TxBytes: 20, TxPackets: 2}) {
+		t.Fatalf("networkUsage() = %+v", got)
+	}
+}
+
+func TestEventRecorder(t *testing.T) {
+	repo := database.NewRepository(database.New(":memory:"))
+	rec := newEventRecorder(repo)
+	msg := func(action events.Action, id string, attrs map[string]string) events.Message {
+		return events.Message{Type: events.ContainerEventType, Action: action, Actor: events.Actor{ID: id, Attributes: attrs}, TimeNano: time.Now().UnixNano()}
+	}
+
+	// A stop requested through Docker: kill, then die. Not recorded.
+	rec.handle(msg(events.ActionKill, "sb1", map[string]string{"signal": "15"}))
+	rec.handle(msg(events.ActionDie, "sb1", map[string]string{"exitCode": "143"}))
+	// An OOM kill: oom, then a die nobody asked for.
+	rec.handle(msg(events.ActionOOM, "sb1", nil))
+	rec.handle(msg(events.ActionDie, "sb1", map[string]string{"exitCode": "137"}))
+	rec.handle(msg(events.ActionRestart, "sb1", nil))
+	rec.handle(msg(events.ActionStart, "sb1", nil))
+
+	got, err := repo.FindSandboxEvents("sb1")
+	if err != nil || len(got) != 3 {
+		t.Fatalf("FindSandboxEvents() = %+v, %v, want oom, exit and restart", got, err)
+	}
+	if got[0].Type != EventOOM || got[1].Type != EventExit || got[2].Type != EventRestart {
+		t.Fatalf("event types = %s, %s, %s", got[0].Type, got[1].Type, got[2].Type)
+	}
+	if got[1].ExitCode == nil || *got[1].ExitCode != 137 {
+		t.Fatalf("exit event code = %v, want 137", got[1].ExitCode)
+	}
+}
+
+func TestNotRunning(t *testing.T) {
+	if err := notRunning(&container.State{Status: container.StateExited}); err != ErrNotRunning {
+		t.Fatalf("notRunning(exited) = %v, want ErrNotRunning", err)
+	}
+	err := notRunning(&container.State{Status: container.StateExited, OOMKilled: true})
+	if err != ErrOOMKilled || !errors.Is(err, ErrNotRunning) {
+		t.Fatalf("notRunning(oom killed) = %v, want ErrOOMKilled wrapping ErrNotRunning", err)
+	}
+}
+
+func TestWaitDial(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	if !waitDial(context.Background(), addr, time.Second) {
+		t.Fatalf("waitDial(%s) = false, want a connection", addr)
+	}
+
+	ln.Close()
+	if waitDial(context.Background(), addr, 200*time.Millisecond) {
+		t.Fatalf("waitDial(%s) after close = true, want a timeout", addr)
+	}
+}
+
+func TestNewWithOptions(t *testing.T) {
+	repoA := database.NewRepository(database.New(":memory:"))
+	repoB := database.NewRepository(database.New(":memory:"))
+
+	a, err := NewWithOptions(repoA, Options{Host: "tcp://10.0.0.5:2375"})
+	if err != nil {
+		t.Fatalf("NewWithOptions() error = %v", err)
+	}
+	defer a.Close()
+	b, err := NewWithOptions(repoB, Options{Host: "unix:///tmp/other.sock", APIVersion: "1.47"})
+	if err != nil {
+		t.Fatalf("NewWithOptions() error = %v", err)
+	}
+	defer b.Close()
+
+	if a.cli == b.cli || a.repo != repoA || b.repo != repoB {
+		t.Fatal("clients share their connection or repository")
+	}
+	if got := a.cli.DaemonHost(); got != "tcp://10.0.0.5:2375" {
+		t.Fatalf("DaemonHost() = %q", got)
+	}
+	if got := b.cli.(*moby.Client).ClientVersion(); got != "1.47" {
+		t.Fatalf("ClientVersion() = %q, want the pinned version", got)
+	}
+
+	if _, err := NewWithOptions(repoA, Options{APIVersion: "not-a-version"}); err == nil {
+		t.Fatal("NewWithOptions(invalid API version) error = nil")
+	}
+	if _, err := NewWithOptions(repoA, Options{Engine: "rkt"}); err == nil {
+		t.Fatal("NewWithOptions(unknown engine) error = nil")
+	}
+}
+
+func TestNewWithOptionsPodman(t *testing.T) {
+	t.Setenv("DOCKER_HOST", "")
+	t.Setenv("XDG_RUNTIME_DIR", "")
+	c, err := NewWithOptions(nil, Options{Engine: EnginePodman})
+	if err != nil {
+		t.Fatalf("NewWithOptions(podman) error = %v", err)
+	}
+	defer c.Close()
+	if got := c.DaemonHost(); got != "unix:///run/podman/podman.sock" {
+		t.Fatalf("DaemonHost() = %q, want the podman socket", got)
+	}
+	if _, err := c.ListCheckpoints(context.Background(), "sb"); !errors.Is(err, ErrUnsupportedByEngine) {
+		t.Fatalf("ListCheckpoints() error = %v, want ErrUnsupportedByEngine", err)
+	}
+}
+
+func TestReadContext(t *testing.T) {
+	dir := t.TempDir()
+	writeContext := func(name, meta string, withCerts bool) {
+		sum := sha256.Sum256([]byte(name))
+		id := hex.EncodeToString(sum[:])
+		metaDir := filepath.Join(dir, "contexts", "meta", id)
+		os.MkdirAll(metaDir, 0o755)
+		os.WriteFile(filepath.Join(metaDir, "meta.json"), []byte(meta), 0o644)
+		if withCerts {
+			tlsDir := filepath.Join(dir, "contexts", "tls", id, "docker")
+			os.MkdirAll(tlsDir, 0o755)
+			os.WriteFile(filepath.Join(tlsDir, "ca.pem"), []byte("ca"), 0o644)
+		}
+	}
+	writeContext("remote", `{"Name":"remote","Endpoints":{"docker":{"Host":"tcp://10.0.0.5:2376","SkipTLSVerify":false}}}`, true)
+	writeContext("plain", `{"Name":"plain","Endpoints":{"docker":{"Host":"tcp://10.0.0.6:2375"}}}`, false)
+	writeContext("insecure", `{"Name":"insecure","Endpoints":{"docker":{"Host":"tcp://10.0.0.7:2376","SkipTLSVerify":true}}}`, false)
+
+	sum := sha256.Sum256([]byte("remote"))
+	wantCerts := filepath.Join(dir, "contexts", "tls", hex.EncodeToString(sum[:]), "docker")
+	if opts, err := readContext(dir, "remote"); err != nil || opts != (Options{Host: "tcp://10.0.0.5:2376", CertPath: wantCerts}) {
+		t.Fatalf("readContext(remote) = %+v, %v", opts, err)
+	}
+	if opts, err := readContext(dir, "plain"); err != nil || opts != (Options{Host: "tcp://10.0.0.6:2375"}) {
+		t.Fatalf("readContext(plain) = %+v, %v", opts, err)
+	}
+	if _, err := readContext(dir, "insecure"); err == nil {
+		t.Fatal("readContext(insecure) error = nil, want unsupported")
+	}
+	if _, err := readContext(dir, "missing"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("readContext(missing) error = %v, want not found", err)
+	}
+	if opts, err := ContextOptions("default"); err != nil || opts != (Options{}) {
+		t.Fatalf("ContextOptions(default) = %+v, %v", opts, err)
+	}
+}
+
+func TestMicroVMIsolation(t *testing.T) {
+	m := MicroVM{Runtimes: []string{"kata-fc", "kata"}, MemoryOverhead: 128}
+	tests := []struct {
+		runtime   string
+		isolation string
+		overhead  int64
+	}{
+		{"", IsolationContainer, 0},
+		{"runc", IsolationContainer, 0},
+		{"runsc", IsolationContainer, 0},
+		{"kata-fc", IsolationMicroVM, 128},
+		{"kata", IsolationMicroVM, 128},
+	}
+	for _, tt := range tests {
+		if got := m.Isolation(tt.runtime); got != tt.isolation {
+			t.Errorf("Isolation(%q) = %q, want %q", tt.runtime, got, tt.isolation)
+		}
+		if got := m.overhead(tt.runtime); got != tt.overhead {
+			t.Errorf("overhead(%q) = %d, want %d", tt.runtime, got, tt.overhead)
+		}
+	}
+}
+
+func TestSchedules(t *testing.T) {
+	c := &Client{repo: database.NewRepository(database.New(":memory:"))}
+	teamA := WithOwner(context.Background(), "team-a")
+	req := models.CreateScheduleRequest{
+		Name:    "nightly",
+		Cron:    "0 3 * * *",
+		Sandbox: models.CreateSandboxRequest{Image: "node:22"},
+		Command: models.ExecCommandRequest{Command: "npm", Args: []string{"test"}},
+	}
+
+	s, err := c.CreateSchedule(teamA, req)
+	if err != nil {
+		t.Fatalf("CreateSchedule() error = %v", err)
+	}
+	if s.NextRun.Hour() != 3 || s.NextRun.Minute() != 0 || !s.NextRun.After(time.Now()) || s.Sandbox.Image != "node:22" || s.LastRun != nil {
+		t.Fatalf("CreateSchedule() = %+v", s)
+	}
+	if _, err := c.CreateSchedule(teamA, req); !errors.Is(err, ErrScheduleExists) {
+		t.Fatalf("CreateSchedule(duplicate) error = %v, want ErrScheduleExists", err)
+	}
+	if _, err := c.GetSchedule(WithOwner(context.Background(), "team-b"), "nightly"); !errors.Is(err, ErrScheduleNotFound) {
+		t.Fatalf("GetSchedule(other owner) error = %v, want ErrScheduleNotFound", err)
+	}
+
+	// A schedule whose previous run is still going skips the due run but
+	// still moves on to its next match.
+	now := time.Date(2026, 5, 1, 3, 0, 10, 0, time.UTC)
+	c.repo.UpdateScheduleNextRun("nightly", now.Add(-10*time.Second).UnixMilli())
+	c.scheduleRuns.Store("nightly", struct{}{})
+	if err := c.runDueSchedules(context.Background(), now); err != nil {
+		t.Fatalf("runDueSchedules() error = %v", err)
+	}
+	s, _ = c.GetSchedule(teamA, "nightly")
+	if want := time.Date(2026, 5, 2, 3, 0, 0, 0, time.UTC); !s.NextRun.Equal(want) || s.LastRun != nil {
+		t.Fatalf("after skipped run: next = %v, last = %+v, want next %v and no run", s.NextRun, s.LastRun, want)
+	}
+
+	if err := c.RemoveSchedule(teamA, "nightly"); err != nil {
+		t.Fatalf("RemoveSchedule() error = %v", err)
+	}
+	if list, _ := c.ListSchedules(teamA); len(list) != 0 {
+		t.Fatalf("ListSchedules() after remove = %+v", list)
+	}
+}
+
+func TestCodeRunners(t *testing.T) {
+	if got := CodeLanguages(); !reflect.DeepEqual(got, []string{"bash", "go", "node", "python", "ruby"}) {
+		t.Fatalf("CodeLanguages() = %v", got)
+	}
+	for name, lang := range codeLanguages {
+		if lang.image == "" || len(lang.cmd) == 0 || lang.cmd[len(lang.cmd)-1] != lang.file {
+			t.Fatalf("language %s: %+v must have an image and run its file", name, lang)
+		}
+	}
+
+	c := &Client{}
+	c.SetCodeRunners(CodeRunners{PoolSize: 2, Images: map[string]string{"python": "python:3.13-slim"}})
+	if got := c.codeImage("python"); got != "python:3.13-slim" {
+		t.Fatalf("codeImage(python) = %q, want the override", got)
+	}
+	if got := c.codeImage("node"); got != "node:22-slim" {
+		t.Fatalf("codeImage(node) = %q, want the default", got)
+	}
+
+	// Without StartCodePool nothing is kept idle.
+	c.fillCodePool("python")
+	if _, ok := c.popCodeRunner("python"); ok {
+		t.Fatal("popCodeRunner() found a runner in a stopped pool")
+	}
+	c.code.idle = map[string][]string{"python": {"a", "b"}}
+	if id, ok := c.popCodeRunner("python"); !ok || id != "a" {
+		t.Fatalf("popCodeRunner() = %q, %v, want the oldest runner", id, ok)
+	}
+	if _, ok := c.popCodeRunner("node"); ok {
+		t.Fatal("popCodeRunner(node) took a python runner")
+	}
+}
+
+func TestSessionProtocol(t *testing.T) {
+	// frame multiplexes output like a non-TTY exec attach: stream 1 is stdout, 2 stderr.
+	frame := func(stream byte, s string) []byte {
+		header := []byte{stream, 0, 0, 0, 0, 0, 0, 0}
+		binary.BigEndian.PutUint32(header[4:], uint32(len(s)))
+		return append(header, s...)
+	}
+	server, daemon := net.Pipe()
+	s := &codeSession{
+		id:    "ses_test",
+		conn:  moby.HijackedResponse{Conn: server, Reader: bufio.NewReader(server)},
+		ready: make(chan int, 1),
+		done:  make(chan struct{}),
+	}
+	c := &Client{}
+	c.sessions.Store(s.id, s)
+	go c.readSession(s)
+
+	daemon.Write(frame(2, "warming up\n"))
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+		s.mu.Lock()
+		startup := string(s.startup)
+		s.mu.Unlock()
+		if startup == "warming up\n" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("startup output = %q, want the output before ready", startup)
+		}
+	}
+	daemon.Write(frame(1, `{"type":"ready","pid":42}`+"\n"))
+	if pid := <-s.ready; pid != 42 {
+		t.Fatalf("ready pid = %d, want 42", pid)
+	}
+	s.mu.Lock()
+	s.pid = 42
+	cell := &sessionCell{events: make(chan models.SessionEvent, 8), stop: make(chan struct{})}
+	s.cell = cell
+	s.mu.Unlock()
+
+	// stdout and stderr are read concurrently, so each event is awaited before the next frame.
+	for _, tc := range []struct {
+		frame []byte
+		want  models.SessionEvent
+	}{
+		{frame(1, `{"type":"result","data":{"text/plain":"42"}}`+"\n"), models.SessionEvent{Type: SessionResult, Data: map[string]string{"text/plain": "42"}}},
+		{frame(2, "raw\n"), models.SessionEvent{Type: SessionStderr, Text: "raw\n"}},
+		{frame(1, "not json\n"), models.SessionEvent{Type: SessionStdout, Text: "not json\n"}},
+		{frame(1, `{"type":"end"}`+"\n"), models.SessionEvent{Type: SessionEnd}},
+	} {
+		daemon.Write(tc.frame)
+		if got := <-cell.events; !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("event = %+v, want %+v", got, tc.want)
+		}
+	}
+
+	daemon.Close()
+	<-s.done
+	if _, ok := c.sessions.Load(s.id); ok {
+		t.Fatal("session still registered after its interpreter exited")
+	}
+	if got := SessionLanguages(); !reflect.DeepEqual(got, []string{"node", "python"}) {
+		t.Fatalf("SessionLanguages() = %v", got)
+	}
+}
+
+func TestGitSource(t *testing.T) {
+	for _, src := range []models.GitSource{
+		{URL: "https://github.com/acme/web.git", Ref: "v1.2.0", Depth: 1},
+		{URL: "git@github.com:acme/web.git", SSHKey: "key"},
+		{URL: "ssh://git@example.com:2222/acme/web.git", Ref: "9fceb02d0ae5"},
+	} {
+		if msg := ValidateGitSource(&src); msg != "" {
+			t.Errorf("ValidateGitSource(%s) = %q, want valid", src.URL, msg)
+		}
+	}
+	for _, src := range []models.GitSource{
+		{URL: "/srv/repo.git"},
+		{URL: "ext::sh -c touch% /tmp/x"},
+		{URL: "https://github.com/acme/web.git", Token: "a\nb"},
+	} {
+		if ValidateGitSource(&src) == "" {
+			t.Errorf("ValidateGitSource(%s) accepted an invalid source", src.URL)
+		}
+	}
+
+	for _, tt := range []struct {
+		src        models.GitSource
+		workingDir string
+		want       string
+	}{
+		{models.GitSource{Dir: "/app/"}, "/srv", "/app"},
+		{models.GitSource{}, "/srv", "/srv"},
+		{models.GitSource{}, "", defaultGitDir},
+	} {
+		if got := gitCloneDir(tt.src, tt.workingDir); got != tt.want {
+			t.Errorf("gitCloneDir(%+v, %q) = %q, want %q", tt.src, tt.workingDir, got, tt.want)
+		}
+	}
+
+	// The token is sent as Basic auth; keys get the final newline OpenSSH needs.
+	if creds, _ := io.ReadAll(gitCredentials("tok", "")); string(creds) != "Basic eC1hY2Nlc3MtdG9rZW46dG9r\n" {
+		t.Errorf("gitCredentials(token) = %q", creds)
+	}
+	if creds, _ := io.ReadAll(gitCredentials("", "-----KEY-----")); string(creds) != "\n-----KEY-----\n" {
+		t.Errorf("gitCredentials(key) = %q", creds)
+	}
+}
+
+func TestHooksEncoding(t *testing.T) {
+	if got := encodeHooks(nil); got != "" {
+		t.Errorf("encodeHooks(nil) = %q, want empty", got)
+	}
+	if hooks, err := decodeHooks(""); err != nil || hooks != nil {
+		t.Errorf("decodeHooks(\"\") = %v, %v, want nil", hooks, err)
+	}
+
+	want := &models.Hooks{
+		OnCreate:   &models.Hook{Command: []string{"sh", "-c", "npm ci"}, Cwd: "/app", Timeout: 600},
+		BeforeStop: &models.Hook{Command: []string{"./flush.sh"}},
+	}
+	got, err := decodeHooks(encodeHooks(want))
+	if err != nil {
+		t.Fatalf("decodeHooks: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip = %+v, want %+v", got, want)
+	}
+
+	if msg := ValidateHooks(want); msg != "" {
+		t.Errorf("ValidateHooks = %q, want valid", msg)
+	}
+	if msg := ValidateHooks(&models.Hooks{OnStart: &models.Hook{Command: []string{"true"}, Timeout: -1}}); msg != "hooks.on_start.timeout must be between 0 and 3600" {
+		t.Errorf("ValidateHooks(negative timeout) = %q", msg)
+	}
+}
+
+// fakeNetworks is a ContainerRuntime holding only networks. Each network
+// created gets the next 172.30.x.0/24 subnet.
+type fakeNetworks struct {
+	ContainerRuntime
+	subnets map[string]netip.Prefix
+	next    byte
+}
+
+func (f *fakeNetworks) NetworkInspect(_ context.Context, name string, _ moby.NetworkInspectOptions) (moby.NetworkInspectResult, error) {
+	subnet, ok := f.subnets[name]
+	if !ok {
+		return moby.NetworkInspectResult{}, errdefs.ErrNotFound
+	}
+	var res moby.NetworkInspectResult
+	res.Network.IPAM.Config = []network.IPAMConfig{{Subnet: subnet}}
+	return res, nil
+}
+
+func (f *fakeNetworks) NetworkCreate(_ context.Context, name string, _ moby.NetworkCreateOptions) (moby.NetworkCreateResult, error) {
+	f.subnets[name] = netip.PrefixFrom(netip.AddrFrom4([4]byte{172, 30, f.next, 0}), 24)
+	f.next++
+	return moby.NetworkCreateResult{}, nil
+}
+
+func (f *fakeNetworks) NetworkRemove(_ context.Context, name string, _ moby.NetworkRemoveOptions) (moby.NetworkRemoveResult, error) {
+	delete(f.subnets, name)
+	return moby.NetworkRemoveResult{}, nil
+}
+
+func TestEgressFirewallFollowsNetworkGroups(t *testing.T) {
+	repo := database.NewRepository(database.New(":memory:"))
+	if err := repo.SaveNetworkGroup(database.NetworkGroup{Name: "old"}); err != nil {
+		t.Fatal(err)
+	}
+	engine := &fakeNetworks{subnets: map[string]netip.Prefix{"opensbx-net-old": netip.MustParsePrefix("172.29.0.0/24")}}
+	c := &Client{cli: engine, repo: repo}
+
+	var calls []string
+	fw := firewall.New(nil, func(_ context.Context, args ...string) error {
+		if args[0] == "-C" {
+			return errors.New("no such rule")
+		}
+		calls = append(calls, strings.Join(args, " "))
+		return nil
+	})
+
+	// Networks that exist at startup are attached.
+	if err := c.SetEgressFirewall(context.Background(), fw); err != nil {
+		t.Fatalf("SetEgressFirewall: %v", err)
+	}
+	want := []string{
+		"-I DOCKER-USER -s 172.29.0.0/24 -j OPENSBX-EGRESS",
+		"-I INPUT -s 172.29.0.0/24 -j OPENSBX-HOST",
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Fatalf("startup calls = %q, want %q", calls, want)
+	}
+
+	// New networks are attached on create and detached on removal.
+	calls = nil
+	if _, err := c.CreateNetworkGroup(context.Background(), "backend"); err != nil {
+		t.Fatalf("CreateNetworkGroup: %v", err)
+	}
+	if err := c.RemoveNetworkGroup(context.Background(), "backend"); err != nil {
+		t.Fatalf("RemoveNetworkGroup: %v", err)
+	}
+	want = []string{
+		"-I DOCKER-USER -s 172.30.0.0/24 -j OPENSBX-EGRESS",
+		"-I INPUT -s 172.30.0.0/24 -j OPENSBX-HOST",
+		"-D DOCKER-USER -s 172.30.0.0/24 -j OPENSBX-EGRESS",
+		"-D INPUT -s 172.30.0.0/24 -j OPENSBX-HOST",
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Fatalf("group calls = %q, want %q", calls, want)
+	}
+}
+
+// fakeHost is a ContainerRuntime that only reports its address.
+type fakeHost struct {
+	ContainerRuntime
+	host string
+}
+
+func (f fakeHost) DaemonHost() string { return f.host }
+
+func TestNetworkPolicyNeedsLocalDaemon(t *testing.T) {
+	local := &Client{cli: fakeHost{host: "unix:///var/run/docker.sock"}}
+	if !local.LocalDaemon() {
+		t.Errorf("LocalDaemon(unix socket) = false")
+	}
+
+	// A remote container's PID would name an unrelated process on this host.
+	remote := &Client{cli: fakeHost{host: "tcp://10.0.0.5:2376"}, firewall: firewall.NewSandbox(nil)}
+	if remote.LocalDaemon() {
+		t.Errorf("LocalDaemon(tcp) = true")
+	}
+	err := remote.enforceNetworkPolicy(context.Background(), "abc123", firewall.Policy{EgressKbps: 100})
+	if !errors.Is(err, ErrNetworkPolicyUnsupported) {
+		t.Fatalf("enforceNetworkPolicy(remote) = %v, want ErrNetworkPolicyUnsupported", err)
+	}
+}
+
+// fakeRunning is a ContainerRuntime whose running containers are ids.
+type fakeRunning struct {
+	ContainerRuntime
+	ids []string
+}
+
+func (f fakeRunning) ContainerList(context.Context, moby.ContainerListOptions) (moby.ContainerListResult, error) {
+	var res moby.ContainerListResult
+	for _, id := range f.ids {
+		res.Items = append(res.Items, container.Summary{ID: id})
+	}
+	return res, nil
+}
+
+func TestUsageSkipsIdleCodeRunners(t *testing.T) {
+	repo := database.NewRepository(database.New(":memory:"))
+	for _, sb := range []database.Sandbox{
+		{ID: "app", Memory: 1024, CPUs: 1},
+		{ID: "idle", Memory: 1024, CPUs: 1},
+		{ID: "taken", OwnerID: "team-a", Memory: 1024, CPUs: 1},
+	} {
+		if err := repo.Save(sb); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := &Client{repo: repo, cli: fakeRunning{ids: []string{"app", "idle", "taken"}}}
+	c.code.idle = map[string][]string{"python": {"idle"}}
+
+	usage, err := c.Usage(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.Sandboxes != 2 || usage.Memory != 2048 {
+		t.Fatalf("Usage(global) = %+v, want the idle runner left out", usage)
+	}
+	if usage, _ := c.Usage(context.Background(), "team-a"); usage.Sandboxes != 1 {
+		t.Fatalf("Usage(team-a) = %+v, want the taken runner counted", usage)
+	}
+}
+
+// fakeStartFails is a ContainerRuntime whose containers are created but fail
+// to start because ctx is done, recording the containers it removes.
+type fakeStartFails struct {
+	ContainerRuntime
+	removed []string
+}
+
+func (f *fakeStartFails) ImageInspect(context.Context, string, ...moby.ImageInspectOption) (moby.ImageInspectResult, error) {
+	return moby.ImageInspectResult{}, nil
+}
+
+func (f *fakeStartFails) ContainerCreate(context.Context, moby.ContainerCreateOptions) (moby.ContainerCreateResult, error) {
+	return moby.ContainerCreateResult{ID: "created"}, nil
+}
+
+func (f *fakeStartFails) ContainerStart(ctx context.Context, _ string, _ moby.ContainerStartOptions) (moby.ContainerStartResult, error) {
+	<-ctx.Done()
+	return moby.ContainerStartResult{}, ctx.Err()
+}
+
+func (f *fakeStartFails) ContainerInspect(context.Context, string, moby.ContainerInspectOptions) (moby.ContainerInspectResult, error) {
+	return moby.ContainerInspectResult{}, errdefs.ErrNotFound
+}
+
+func (f *fakeStartFails) ContainerRemove(ctx context.Context, id string, _ moby.ContainerRemoveOptions) (moby.ContainerRemoveResult, error) {
+	if err := ctx.Err(); err != nil {
+		return moby.ContainerRemoveResult{}, err
+	}
+	f.removed = append(f.removed, id)
+	return moby.ContainerRemoveResult{}, nil
+}
+
+func TestCreateRemovesContainerOnDeadline(t *testing.T) {
+	fake := &fakeStartFails{}
+	c := &Client{cli: fake, repo: database.NewRepository(database.New(":memory:"))}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := c.Create(ctx, models.CreateSandboxRequest{Image: "node:24"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Create() error = %v, want the deadline", err)
+	}
+	if !reflect.DeepEqual(fake.removed, []string{"created"}) {
+		t.Fatalf("removed = %v, want the created container", fake.removed)
+	}
+}
@@ -0,0 +1,158 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"opensbx/internal/database"
+	"opensbx/internal/logging"
+	"opensbx/models"
+
+	"github.com/moby/moby/api/types/container"
+	moby "github.com/moby/moby/client"
+)
+
+// Clone creates a new sandbox with the configuration of an existing one. By
+// default the source's filesystem is committed to an image the clone starts
+// from, so installed packages and files carry over; with Filesystem=false the
+// clone starts from the source's original image. The source keeps running.
+func (c *Client) Clone(ctx context.Context, id string, req models.CloneRequest) (models.CreateSandboxResponse, error) {
+	result, err := c.cli.ContainerInspect(ctx, id, moby.ContainerInspectOptions{})
+	if err != nil {
+		return models.CreateSandboxResponse{}, wrapNotFound(err)
+	}
+	info := result.Container
+	sb, err := c.repo.FindByID(info.ID)
+	if err != nil {
+		return models.CreateSandboxResponse{}, err
+	}
+	if sb == nil {
+		return models.CreateSandboxResponse{}, ErrNotFound
+	}
+
+	create, err := cloneRequest(info.Config, info.HostConfig, sb)
+	if err != nil {
+		return models.CreateSandboxResponse{}, err
+	}
+	create.Name = req.Name
+	create.Ports = mainPortFirst(portKeys(extractPorts(info.NetworkSettings.Ports)), sb.Port)
+	procs, err := c.repo.FindProcessesBySandbox(info.ID)
+	if err != nil {
+		return models.CreateSandboxResponse{}, err
+	}
+	for _, p := range procs {
+		create.Processes = append(create.Processes, startRequest(p))
+	}
+
+	if req.Filesystem == nil || *req.Filesystem {
+		name := strings.TrimPrefix(info.Name, "/")
+		ref := fmt.Sprintf("opensbx-clone/%s:%d", name, time.Now().Unix())
+		// Not a snapshot: image garbage collection removes it once no clone uses it.
+		if _, err := c.cli.ContainerCommit(ctx, info.ID, moby.ContainerCommitOptions{
+			Reference: ref,
+			Comment:   "opensbx clone of " + name,
+			Changes:   commitChanges(),
+		}); err != nil {
+			return models.CreateSandboxResponse{}, fmt.Errorf("commit sandbox: %w", err)
+		}
+		c.trackImage(ctx, ref)
+		create.Image = ref
+		// The copied filesystem already holds what on_create set up.
+		if create.Hooks != nil {
+			hooks := *create.Hooks
+			hooks.OnCreate = nil
+			create.Hooks = &hooks
+		}
+	}
+
+	resp, err := c.Create(ctx, create)
+	if err != nil {
+		return models.CreateSandboxResponse{}, err
+	}
+	if len(sb.Env) > 0 {
+		if err := c.repo.UpdateEnv(resp.ID, sb.Env); err != nil {
+			logging.FromContext(ctx).Error("database: failed to copy sandbox env", "sandbox_id", resp.ID, "err", err)
+		}
+	}
+	return resp, nil
+}
+
+// cloneRequest rebuilds the create request of a sandbox from its container and
+// database record. Ports, name and image are left to the caller.
+func cloneRequest(cfg *container.Config, hostCfg *container.HostConfig, sb *database.Sandbox) (models.CreateSandboxRequest, error) {
+	req := models.CreateSandboxRequest{
+		Image:            sb.Image,
+		Env:              cfg.Env,
+		Cmd:              cfg.Cmd,
+		Entrypoint:       cfg.Entrypoint,
+		WorkingDir:       cfg.WorkingDir,
+		ExpirationAction: sb.ExpirationAction,
+		TimeoutMode:      sb.TimeoutMode,
+		Network:          sb.Network,
+		NetworkGroup:     sb.NetworkGroup,
+		Security:         cloneSecurity(cfg, hostCfg),
+		Runtime:          hostCfg.Runtime,
+		Labels:           callerLabels(cfg.Labels),
+		WakeOnRequest:    sb.WakeOnRequest,
+		MaxLifetime:      sb.MaxLifetime,
+		Resources: &models.ResourceLimits{
+			Memory: hostCfg.Memory / (1024 * 1024),
+			CPUs:   float64(hostCfg.NanoCPUs) / 1e9,
+			GPUs:   containerGPUs(hostCfg.DeviceRequests),
+		},
+	}
+	req.Timeout, _ = strconv.Atoi(cfg.Labels[LabelTimeout])
+
+	if sb.EgressAllow != "" || sb.EgressDeny != "" {
+		req.Egress = &models.EgressPolicy{Allow: splitRules(sb.EgressAllow), Deny: splitRules(sb.EgressDeny)}
+	}
+	if sb.IngressKbps > 0 || sb.EgressKbps > 0 {
+		req.Bandwidth = &models.BandwidthLimit{IngressKbps: sb.IngressKbps, EgressKbps: sb.EgressKbps}
+	}
+	if sb.ReadyCheck != "" {
+		req.ReadyCheck = &models.ReadyCheck{}
+		if err := json.Unmarshal([]byte(sb.ReadyCheck), req.ReadyCheck); err != nil {
+			return models.CreateSandboxRequest{}, fmt.Errorf("stored ready check: %w", err)
+		}
+	}
+	hooks, err := decodeHooks(sb.Hooks)
+	if err != nil {
+		return models.CreateSandboxRequest{}, err
+	}
+	req.Hooks = hooks
+	return req, nil
+}
+
+// cloneSecurity reads the hardening a container was created with, so a clone
+// is at least as locked down as its source.
+func cloneSecurity(cfg *container.Config, hostCfg *container.HostConfig) *models.SecurityOptions {
+	opts := &models.SecurityOptions{CapDrop: hostCfg.CapDrop, User: cfg.User}
+	if hostCfg.ReadonlyRootfs {
+		opts.ReadOnlyRootfs = &hostCfg.ReadonlyRootfs
+	}
+	for _, opt := range hostCfg.SecurityOpt {
+		switch {
+		case strings.HasPrefix(opt, "no-new-privileges"):
+			nnp := true
+			opts.NoNewPrivileges = &nnp
+		case strings.HasPrefix(opt, "seccomp="):
+			opts.SeccompProfile = strings.TrimPrefix(opt, "seccomp=")
+		}
+	}
+	if hostCfg.PidsLimit != nil {
+		opts.PidsLimit = *hostCfg.PidsLimit
+	}
+	return opts
+}
+
+// splitRules is the inverse of joinRules.
+func splitRules(rules string) []string {
+	if rules == "" {
+		return nil
+	}
+	return strings.Split(rules, ",")
+}
@@ -0,0 +1,89 @@
+package docker
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	"opensbx/internal/database"
+	"opensbx/models"
+)
+
+// logPruneInterval is how often persisted command logs are checked against the retention.
+const logPruneInterval = 10 * time.Minute
+
+// saveCommandLogs persists the captured output of a finished command so it
+// outlives the in-memory buffers and server restarts.
+func (c *Client) saveCommandLogs(cmdID string, rc *runningCommand, finishedAt int64) {
+	if err := c.repo.SaveCommandLog(database.CommandLog{
+		CommandID:  cmdID,
+		SandboxID:  rc.sandboxID,
+		Stdout:     string(rc.stdout.Bytes()),
+		Stderr:     string(rc.stderr.Bytes()),
+		FinishedAt: finishedAt,
+	}); err != nil {
+		slog.Error("save command logs failed", "sandbox_id", rc.sandboxID, "cmd_id", cmdID, "err", err)
+	}
+}
+
+// storedCommandLogs returns the persisted output of a finished command.
+// Returns ErrCommandNotFound if the command does not belong to the sandbox
+// or its logs are no longer retained.
+func (c *Client) storedCommandLogs(sandboxID, cmdID string) (models.CommandLogsResponse, error) {
+	cmd, err := c.repo.FindCommandByID(cmdID)
+	if err != nil {
+		return models.CommandLogsResponse{}, err
+	}
+	if cmd == nil || cmd.SandboxID != sandboxID {
+		return models.CommandLogsResponse{}, ErrCommandNotFound
+	}
+	l, err := c.repo.FindCommandLog(cmdID)
+	if err != nil {
+		return models.CommandLogsResponse{}, err
+	}
+	if l == nil {
+		return models.CommandLogsResponse{}, ErrCommandNotFound
+	}
+	return models.CommandLogsResponse{
+		Stdout:   l.Stdout,
+		Stderr:   l.Stderr,
+		ExitCode: cmd.ExitCode,
+	}, nil
+}
+
+// storedLogReaders returns readers over the persisted output of a finished command.
+func (c *Client) storedLogReaders(sandboxID, cmdID string) (io.ReadCloser, io.ReadCloser, error) {
+	logs, err := c.storedCommandLogs(sandboxID, cmdID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return io.NopCloser(strings.NewReader(logs.Stdout)), io.NopCloser(strings.NewReader(logs.Stderr)), nil
+}
+
+// StartLogPruner periodically deletes persisted logs of commands that finished
+// more than retention ago. A zero retention keeps logs until their sandbox is
+// removed. Runs until ctx is cancelled.
+func (c *Client) StartLogPruner(ctx context.Context, retention time.Duration) {
+	if retention <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(logPruneInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				cutoff := time.Now().Add(-retention).UnixMilli()
+				if n, err := c.repo.DeleteCommandLogsBefore(cutoff); err != nil {
+					slog.Error("log pruner: sweep failed", "err", err)
+				} else if n > 0 {
+					slog.Info("log pruner: removed command logs", "count", n)
+				}
+			}
+		}
+	}()
+}
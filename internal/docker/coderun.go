@@ -0,0 +1,318 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"path"
+	"slices"
+	"sync"
+	"time"
+
+	"opensbx/internal/database"
+	"opensbx/models"
+
+	moby "github.com/moby/moby/client"
+)
+
+// codeLanguage describes how POST /v1/run runs programs of one language.
+type codeLanguage struct {
+	image string   // default image runners are created from
+	file  string   // file the code is written to, in codeDir
+	cmd   []string // runs file from codeDir
+}
+
+// codeLanguages are the languages POST /v1/run supports.
+var codeLanguages = map[string]codeLanguage{
+	"python": {image: "python:3.12-slim", file: "main.py", cmd: []string{"python3", "main.py"}},
+	"node":   {image: "node:22-slim", file: "main.js", cmd: []string{"node", "main.js"}},
+	"go":     {image: "golang:1.23-alpine", file: "main.go", cmd: []string{"go", "run", "main.go"}},
+	"ruby":   {image: "ruby:3.3-slim", file: "main.rb", cmd: []string{"ruby", "main.rb"}},
+	"bash":   {image: "bash:5", file: "main.sh", cmd: []string{"bash", "main.sh"}},
+}
+
+const (
+	codeDir            = "/code" // working directory programs are written to and run in
+	defaultCodeTimeout = 60      // seconds a program may run when the request sets no timeout
+	codeRunnerTimeout  = 86400   // seconds an idle runner waits in the pool before it expires
+)
+
+// LabelCodeRunner marks the sandboxes POST /v1/run executes code in; its
+// value is the runner's language.
+const LabelCodeRunner = reservedLabelRoot + "code-runner"
+
+// pullKey is the context key allowing RunCode to pull a missing runner image.
+type pullKey struct{}
+
+// WithImagePull lets RunCode pull a missing runner image for the caller of
+// ctx. Only callers allowed to manage images should get it; for others a
+// missing image is ErrImageNotFound.
+func WithImagePull(ctx context.Context) context.Context {
+	return context.WithValue(ctx, pullKey{}, true)
+}
+
+// imagePullAllowed reports whether ctx carries WithImagePull.
+func imagePullAllowed(ctx context.Context) bool {
+	ok, _ := ctx.Value(pullKey{}).(bool)
+	return ok
+}
+
+// CodeLanguages returns the languages POST /v1/run supports, sorted.
+func CodeLanguages() []string {
+	return slices.Sorted(maps.Keys(codeLanguages))
+}
+
+// CodeRunners configures the sandboxes POST /v1/run executes code in.
+type CodeRunners struct {
+	PoolSize int               // idle runners kept per language once it has been used, 0 = create one per run
+	Images   map[string]string // image per language replacing the default
+}
+
+// codePool holds idle runners, each used for one program and then removed.
+type codePool struct {
+	mu      sync.Mutex
+	ctx     context.Context // set while StartCodePool runs, nil = runs create their runner
+	cfg     CodeRunners
+	idle    map[string][]string // language -> idle runner sandbox IDs
+	filling map[string]int      // language -> runners being created for the pool
+}
+
+// SetCodeRunners configures the runners of POST /v1/run.
+func (c *Client) SetCodeRunners(r CodeRunners) {
+	c.code.mu.Lock()
+	defer c.code.mu.Unlock()
+	c.code.cfg = r
+}
+
+// codeImage returns the image runners of a language are created from.
+func (c *Client) codeImage(language string) string {
+	c.code.mu.Lock()
+	defer c.code.mu.Unlock()
+	if image := c.code.cfg.Images[language]; image != "" {
+		return image
+	}
+	return codeLanguages[language].image
+}
+
+// StartCodePool keeps idle runners for POST /v1/run until ctx is cancelled,
+// then removes them. Runners left over by a previous server process are
+// removed first.
+func (c *Client) StartCodePool(ctx context.Context) {
+	leftover, _, err := c.repo.FindSandboxes(database.SandboxFilter{Labels: database.LabelSelector{Keys: []string{LabelCodeRunner}}})
+	if err != nil {
+		slog.Error("code pool: failed to list leftover runners", "err", err)
+	}
+	for _, sb := range leftover {
+		if err := c.Remove(ctx, sb.ID); err != nil && !errors.Is(err, ErrNotFound) {
+			slog.Error("code pool: failed to remove leftover runner", "sandbox_id", sb.ID, "err", err)
+		}
+	}
+
+	c.code.mu.Lock()
+	c.code.ctx = ctx
+	c.code.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		c.code.mu.Lock()
+		var ids []string
+		for _, idle := range c.code.idle {
+			ids = append(ids, idle...)
+		}
+		c.code.ctx, c.code.idle = nil, nil
+		c.code.mu.Unlock()
+
+		removeCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		for _, id := range ids {
+			if err := c.Remove(removeCtx, id); err != nil {
+				slog.Error("code pool: failed to remove runner", "sandbox_id", id, "err", err)
+			}
+		}
+	}()
+}
+
+// RunCode runs req.Code as a program of req.Language in a runner sandbox of
+// its own, taken from the pool when one is idle, and removes the runner
+// afterwards.
+func (c *Client) RunCode(ctx context.Context, req models.RunCodeRequest) (models.RunCodeResponse, error) {
+	lang, ok := codeLanguages[req.Language]
+	if !ok {
+		return models.RunCodeResponse{}, fmt.Errorf("unsupported language %q", req.Language)
+	}
+	timeout := req.Timeout
+	if timeout == 0 {
+		timeout = defaultCodeTimeout
+	}
+
+	id, err := c.takeCodeRunner(ctx, req.Language)
+	if err != nil {
+		return models.RunCodeResponse{}, err
+	}
+	defer func() {
+		if err := c.Remove(context.WithoutCancel(ctx), id); err != nil {
+			slog.Error("code run: failed to remove runner", "sandbox_id", id, "err", err)
+		}
+	}()
+	if err := c.RenewExpiration(ctx, id, timeout+60); err != nil {
+		return models.RunCodeResponse{}, err
+	}
+	if err := c.WriteFile(ctx, id, path.Join(codeDir, lang.file), req.Code); err != nil {
+		return models.RunCodeResponse{}, fmt.Errorf("write code: %w", err)
+	}
+
+	cmd, err := c.ExecCommand(ctx, id, models.ExecCommandRequest{
+		Command: lang.cmd[0],
+		Args:    lang.cmd[1:],
+		Cwd:     codeDir,
+		Stdin:   req.Stdin,
+		Timeout: timeout,
+	})
+	if err != nil {
+		return models.RunCodeResponse{}, err
+	}
+	if cmd, err = c.WaitCommand(ctx, id, cmd.ID); err != nil {
+		return models.RunCodeResponse{}, err
+	}
+	logs, err := c.GetCommandLogs(ctx, id, cmd.ID)
+	if err != nil {
+		return models.RunCodeResponse{}, fmt.Errorf("read program output: %w", err)
+	}
+	if cmd.ExitCode == nil {
+		return models.RunCodeResponse{}, fmt.Errorf("command %s did not exit", cmd.ID)
+	}
+	return models.RunCodeResponse{
+		Language: req.Language,
+		ExitCode: *cmd.ExitCode,
+		TimedOut: cmd.TimedOut,
+		Stdout:   logs.Stdout,
+		Stderr:   logs.Stderr,
+	}, nil
+}
+
+// takeCodeRunner returns a running runner for language: an idle one from the
+// pool, or a new one when the pool is empty. Either way the runner counts
+// against the quotas of the caller in ctx and belongs to its owner, and the
+// pool is topped up in the background.
+func (c *Client) takeCodeRunner(ctx context.Context, language string) (string, error) {
+	// Idle runners are not counted, so this holds a pooled one to the quota
+	// as if it were created now.
+	if err := c.CheckQuota(ctx, c.quotaResources(nil, c.runtime)); err != nil {
+		return "", err
+	}
+	defer func() { go c.fillCodePool(language) }()
+	for {
+		id, ok := c.popCodeRunner(language)
+		if !ok {
+			return c.createCodeRunner(ctx, language)
+		}
+		info, err := c.cli.ContainerInspect(ctx, id, moby.ContainerInspectOptions{})
+		if err == nil && info.Container.State.Running {
+			if owner := OwnerFrom(ctx); owner != "" {
+				if err := c.repo.UpdateOwner(id, owner); err != nil {
+					c.Remove(context.WithoutCancel(ctx), id)
+					return "", err
+				}
+			}
+			return id, nil
+		}
+		// Expired or removed while idle.
+		if err := c.Remove(ctx, id); err != nil && !errors.Is(err, ErrNotFound) {
+			slog.Warn("code pool: failed to remove stale runner", "sandbox_id", id, "err", err)
+		}
+	}
+}
+
+// idleCodeRunner reports whether sandbox id is an idle runner in the pool.
+func (c *Client) idleCodeRunner(id string) bool {
+	c.code.mu.Lock()
+	defer c.code.mu.Unlock()
+	for _, idle := range c.code.idle {
+		if slices.Contains(idle, id) {
+			return true
+		}
+	}
+	return false
+}
+
+// popCodeRunner removes an idle runner of language from the pool.
+func (c *Client) popCodeRunner(language string) (string, bool) {
+	c.code.mu.Lock()
+	defer c.code.mu.Unlock()
+	idle := c.code.idle[language]
+	if len(idle) == 0 {
+		return "", false
+	}
+	c.code.idle[language] = idle[1:]
+	return idle[0], true
+}
+
+// fillCodePool creates runners until language has PoolSize idle ones. It does
+// nothing unless StartCodePool is running. Runners are created on the pool's
+// context, without an owner, and never pull their image: a run that created
+// one already found it present.
+func (c *Client) fillCodePool(language string) {
+	c.code.mu.Lock()
+	ctx := c.code.ctx
+	need := c.code.cfg.PoolSize - len(c.code.idle[language]) - c.code.filling[language]
+	if ctx == nil || need <= 0 {
+		c.code.mu.Unlock()
+		return
+	}
+	if c.code.filling == nil {
+		c.code.filling = map[string]int{}
+	}
+	c.code.filling[language] += need
+	c.code.mu.Unlock()
+
+	for n := need; n > 0; n-- {
+		id, err := c.createCodeRunner(ctx, language)
+		c.code.mu.Lock()
+		if err != nil {
+			c.code.filling[language] -= n
+			c.code.mu.Unlock()
+			slog.Error("code pool: failed to create runner", "language", language, "err", err)
+			return
+		}
+		c.code.filling[language]--
+		pooled := c.code.ctx != nil
+		if pooled {
+			if c.code.idle == nil {
+				c.code.idle = map[string][]string{}
+			}
+			c.code.idle[language] = append(c.code.idle[language], id)
+		}
+		c.code.mu.Unlock()
+		if !pooled {
+			// The pool was stopped while the runner was created.
+			c.Remove(context.Background(), id)
+			return
+		}
+	}
+}
+
+// createCodeRunner creates a runner sandbox for language. A missing image is
+// pulled if ctx carries WithImagePull, and is ErrImageNotFound otherwise.
+func (c *Client) createCodeRunner(ctx context.Context, language string) (string, error) {
+	image := c.codeImage(language)
+	spec := models.CreateSandboxRequest{
+		Image:            image,
+		Timeout:          codeRunnerTimeout,
+		ExpirationAction: ExpirationDelete,
+		WorkingDir:       codeDir,
+		Labels:           map[string]string{LabelCodeRunner: language},
+	}
+	created, err := c.Create(ctx, spec)
+	if errors.Is(err, ErrImageNotFound) && imagePullAllowed(ctx) {
+		if err := c.PullImage(ctx, image); err != nil {
+			return "", err
+		}
+		created, err = c.Create(ctx, spec)
+	}
+	if err != nil {
+		return "", err
+	}
+	return created.ID, nil
+}
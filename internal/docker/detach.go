@@ -0,0 +1,77 @@
+package docker
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"opensbx/internal/database"
+
+	moby "github.com/moby/moby/client"
+)
+
+// Detach is the alternative to Shutdown for deploys: it cancels running
+// commands and pending timers but leaves sandboxes running. Each auto-stop
+// deadline is recorded so ResumeTimers re-arms it when the server starts again.
+func (c *Client) Detach() {
+	c.cancelCommands()
+
+	detached := 0
+	c.timers.Range(func(key, value any) bool {
+		id := key.(string)
+		entry := value.(*timerEntry)
+		c.cancelTimer(id)
+		if err := c.repo.UpdateExpiresAt(id, entry.expiresAt.UnixMilli()); err != nil {
+			slog.Error("docker shutdown: failed to record sandbox timer", "sandbox_id", id, "err", err)
+			return true
+		}
+		detached++
+		return true
+	})
+	slog.Info("docker shutdown: leaving sandboxes running", "timers", detached)
+}
+
+// ResumeTimers re-arms the auto-stop timers Detach recorded and returns how
+// many it re-armed. Sandboxes whose deadline passed while the server was down
+// are stopped right away; stopped or removed ones just have the record cleared.
+func (c *Client) ResumeTimers(ctx context.Context) (int, error) {
+	pending, err := c.repo.FindPendingTimers()
+	if err != nil {
+		return 0, err
+	}
+
+	resumed := 0
+	for _, sb := range pending {
+		result, inspectErr := c.cli.ContainerInspect(ctx, sb.ID, moby.ContainerInspectOptions{})
+		if inspectErr != nil && wrapNotFound(inspectErr) != ErrNotFound {
+			slog.Error("resume timers: inspect sandbox failed", "sandbox_id", sb.ID, "err", inspectErr)
+			continue // kept for the next start
+		}
+		if err := c.repo.UpdateExpiresAt(sb.ID, 0); err != nil {
+			return resumed, err
+		}
+		if inspectErr != nil || !result.Container.State.Running {
+			continue
+		}
+		if c.getTimerEntry(sb.ID) != nil {
+			continue // re-armed by a start in the meantime
+		}
+		window, remaining := resumedWindow(sb, result.Container.Config.Labels[LabelTimeout], time.Now())
+		c.armStop(sb.ID, sb.Name, window, remaining, sb.TimeoutMode == TimeoutIdle, lifetimeEnd(&sb))
+		resumed++
+	}
+	return resumed, nil
+}
+
+// resumedWindow returns the timeout window and the time left of a recorded
+// timer. The window is the sandbox's timeout label, or the time left when the
+// label is missing; a deadline in the past leaves no time.
+func resumedWindow(sb database.Sandbox, timeoutLabel string, now time.Time) (time.Duration, time.Duration) {
+	remaining := max(time.UnixMilli(sb.ExpiresAt).Sub(now), 0)
+	window := remaining
+	if seconds, err := strconv.Atoi(timeoutLabel); err == nil && seconds > 0 {
+		window = time.Duration(seconds) * time.Second
+	}
+	return window, remaining
+}
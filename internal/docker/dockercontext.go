@@ -0,0 +1,70 @@
+package docker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// contextMeta is the part of a docker CLI context's meta.json we use.
+type contextMeta struct {
+	Endpoints map[string]struct {
+		Host          string
+		SkipTLSVerify bool
+	}
+}
+
+// ContextOptions returns the Options of the docker CLI context called name,
+// read from the CLI's config directory (DOCKER_CONFIG or ~/.docker). The
+// "default" context selects the daemon configured in the environment.
+func ContextOptions(name string) (Options, error) {
+	if name == "" || name == "default" {
+		return Options{}, nil
+	}
+	dir := os.Getenv("DOCKER_CONFIG")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return Options{}, fmt.Errorf("docker context %q: %w", name, err)
+		}
+		dir = filepath.Join(home, ".docker")
+	}
+	return readContext(dir, name)
+}
+
+// readContext reads the context called name from the docker config directory
+// dir. The CLI stores each context under the SHA-256 of its name.
+func readContext(dir, name string) (Options, error) {
+	sum := sha256.Sum256([]byte(name))
+	id := hex.EncodeToString(sum[:])
+
+	raw, err := os.ReadFile(filepath.Join(dir, "contexts", "meta", id, "meta.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return Options{}, fmt.Errorf("docker context %q not found in %s", name, dir)
+	}
+	if err != nil {
+		return Options{}, fmt.Errorf("docker context %q: %w", name, err)
+	}
+	var meta contextMeta
+	if err := json.Unmarshal(raw, &meta); err != nil {
+		return Options{}, fmt.Errorf("docker context %q: %w", name, err)
+	}
+	endpoint, ok := meta.Endpoints["docker"]
+	if !ok || endpoint.Host == "" {
+		return Options{}, fmt.Errorf("docker context %q has no docker endpoint", name)
+	}
+	if endpoint.SkipTLSVerify {
+		return Options{}, fmt.Errorf("docker context %q skips TLS verification, which is not supported", name)
+	}
+
+	opts := Options{Host: endpoint.Host}
+	certs := filepath.Join(dir, "contexts", "tls", id, "docker")
+	if _, err := os.Stat(filepath.Join(certs, "ca.pem")); err == nil {
+		opts.CertPath = certs
+	}
+	return opts, nil
+}
@@ -0,0 +1,112 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	moby "github.com/moby/moby/client"
+	"opensbx/internal/database"
+	"opensbx/models"
+)
+
+// domainPattern matches a lowercase hostname with at least two labels.
+var domainPattern = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// NormalizeDomain lowercases host and strips a port and trailing dot.
+// Returns ErrInvalidDomain if the result is not a valid hostname.
+func NormalizeDomain(host string) (string, error) {
+	h := strings.ToLower(strings.TrimSpace(host))
+	if idx := strings.LastIndex(h, ":"); idx != -1 {
+		h = h[:idx]
+	}
+	h = strings.TrimSuffix(h, ".")
+	if len(h) > 253 || !domainPattern.MatchString(h) {
+		return "", fmt.Errorf("%w %q", ErrInvalidDomain, host)
+	}
+	return h, nil
+}
+
+// AddDomain routes an external hostname to a sandbox. Adding a domain the
+// sandbox already has is a no-op; a domain mapped to another sandbox returns
+// ErrDomainTaken.
+func (c *Client) AddDomain(ctx context.Context, id, host string) (models.SandboxDomain, error) {
+	host, err := NormalizeDomain(host)
+	if err != nil {
+		return models.SandboxDomain{}, err
+	}
+	fullID, err := c.sandboxID(ctx, id)
+	if err != nil {
+		return models.SandboxDomain{}, err
+	}
+
+	existing, err := c.repo.FindDomain(host)
+	if err != nil {
+		return models.SandboxDomain{}, err
+	}
+	if existing != nil {
+		if existing.SandboxID != fullID {
+			return models.SandboxDomain{}, fmt.Errorf("%w: %s", ErrDomainTaken, host)
+		}
+		return toSandboxDomain(*existing), nil
+	}
+
+	d := database.Domain{Host: host, SandboxID: fullID, CreatedAt: time.Now().UnixMilli()}
+	if err := c.repo.SaveDomain(d); err != nil {
+		return models.SandboxDomain{}, err
+	}
+	return toSandboxDomain(d), nil
+}
+
+// ListDomains returns the custom domains routed to a sandbox.
+func (c *Client) ListDomains(ctx context.Context, id string) ([]models.SandboxDomain, error) {
+	fullID, err := c.sandboxID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	recs, err := c.repo.FindDomainsBySandbox(fullID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]models.SandboxDomain, 0, len(recs))
+	for _, rec := range recs {
+		out = append(out, toSandboxDomain(rec))
+	}
+	return out, nil
+}
+
+// RemoveDomain stops routing host to a sandbox. Returns ErrDomainNotFound if
+// the sandbox has no such domain.
+func (c *Client) RemoveDomain(ctx context.Context, id, host string) error {
+	host, err := NormalizeDomain(host)
+	if err != nil {
+		return err
+	}
+	fullID, err := c.sandboxID(ctx, id)
+	if err != nil {
+		return err
+	}
+	ok, err := c.repo.DeleteDomain(host, fullID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrDomainNotFound
+	}
+	return nil
+}
+
+// sandboxID resolves a short ID or name to the full container ID.
+func (c *Client) sandboxID(ctx context.Context, id string) (string, error) {
+	info, err := c.cli.ContainerInspect(ctx, id, moby.ContainerInspectOptions{})
+	if err != nil {
+		return "", wrapNotFound(err)
+	}
+	return info.Container.ID, nil
+}
+
+func toSandboxDomain(d database.Domain) models.SandboxDomain {
+	return models.SandboxDomain{Domain: d.Host, SandboxID: d.SandboxID, CreatedAt: time.UnixMilli(d.CreatedAt)}
+}
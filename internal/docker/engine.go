@@ -0,0 +1,120 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	moby "github.com/moby/moby/client"
+)
+
+// Container engines a Client can drive.
+const (
+	EngineDocker = "docker"
+	EnginePodman = "podman"
+)
+
+// ContainerRuntime is the container engine API a Client drives. The Docker
+// SDK client implements it; other engines plug in through a type that
+// implements it, see podmanRuntime.
+type ContainerRuntime interface {
+	DaemonHost() string
+	Close() error
+	Ping(ctx context.Context, options moby.PingOptions) (moby.PingResult, error)
+	Info(ctx context.Context, options moby.InfoOptions) (moby.SystemInfoResult, error)
+	ServerVersion(ctx context.Context, options moby.ServerVersionOptions) (moby.ServerVersionResult, error)
+	DiskUsage(ctx context.Context, options moby.DiskUsageOptions) (moby.DiskUsageResult, error)
+	Events(ctx context.Context, options moby.EventsListOptions) moby.EventsResult
+
+	ContainerCreate(ctx context.Context, options moby.ContainerCreateOptions) (moby.ContainerCreateResult, error)
+	ContainerInspect(ctx context.Context, containerID string, options moby.ContainerInspectOptions) (moby.ContainerInspectResult, error)
+	ContainerList(ctx context.Context, options moby.ContainerListOptions) (moby.ContainerListResult, error)
+	ContainerLogs(ctx context.Context, containerID string, options moby.ContainerLogsOptions) (moby.ContainerLogsResult, error)
+	ContainerStart(ctx context.Context, containerID string, options moby.ContainerStartOptions) (moby.ContainerStartResult, error)
+	ContainerStop(ctx context.Context, containerID string, options moby.ContainerStopOptions) (moby.ContainerStopResult, error)
+	ContainerRestart(ctx context.Context, containerID string, options moby.ContainerRestartOptions) (moby.ContainerRestartResult, error)
+	ContainerPause(ctx context.Context, containerID string, options moby.ContainerPauseOptions) (moby.ContainerPauseResult, error)
+	ContainerUnpause(ctx context.Context, containerID string, options moby.ContainerUnpauseOptions) (moby.ContainerUnpauseResult, error)
+	ContainerRemove(ctx context.Context, containerID string, options moby.ContainerRemoveOptions) (moby.ContainerRemoveResult, error)
+	ContainerCommit(ctx context.Context, containerID string, options moby.ContainerCommitOptions) (moby.ContainerCommitResult, error)
+	ContainerStats(ctx context.Context, containerID string, options moby.ContainerStatsOptions) (moby.ContainerStatsResult, error)
+	ContainerTop(ctx context.Context, containerID string, options moby.ContainerTopOptions) (moby.ContainerTopResult, error)
+	ContainerStatPath(ctx context.Context, containerID string, options moby.ContainerStatPathOptions) (moby.ContainerStatPathResult, error)
+	CopyFromContainer(ctx context.Context, containerID string, options moby.CopyFromContainerOptions) (moby.CopyFromContainerResult, error)
+	CopyToContainer(ctx context.Context, containerID string, options moby.CopyToContainerOptions) (moby.CopyToContainerResult, error)
+
+	ExecCreate(ctx context.Context, containerID string, options moby.ExecCreateOptions) (moby.ExecCreateResult, error)
+	ExecAttach(ctx context.Context, execID string, options moby.ExecAttachOptions) (moby.ExecAttachResult, error)
+	ExecInspect(ctx context.Context, execID string, options moby.ExecInspectOptions) (moby.ExecInspectResult, error)
+	ExecResize(ctx context.Context, execID string, options moby.ExecResizeOptions) (moby.ExecResizeResult, error)
+
+	CheckpointCreate(ctx context.Context, containerID string, options moby.CheckpointCreateOptions) (moby.CheckpointCreateResult, error)
+	CheckpointList(ctx context.Context, containerID string, options moby.CheckpointListOptions) (moby.CheckpointListResult, error)
+	CheckpointRemove(ctx context.Context, containerID string, options moby.CheckpointRemoveOptions) (moby.CheckpointRemoveResult, error)
+
+	ImageInspect(ctx context.Context, imageID string, options ...moby.ImageInspectOption) (moby.ImageInspectResult, error)
+	ImageList(ctx context.Context, options moby.ImageListOptions) (moby.ImageListResult, error)
+	ImagePull(ctx context.Context, ref string, options moby.ImagePullOptions) (moby.ImagePullResponse, error)
+	ImagePush(ctx context.Context, image string, options moby.ImagePushOptions) (moby.ImagePushResponse, error)
+	ImageRemove(ctx context.Context, imageID string, options moby.ImageRemoveOptions) (moby.ImageRemoveResult, error)
+	ImageLoad(ctx context.Context, input io.Reader, options ...moby.ImageLoadOption) (moby.ImageLoadResult, error)
+	ImageImport(ctx context.Context, source moby.ImageImportSource, ref string, options moby.ImageImportOptions) (moby.ImageImportResult, error)
+	ImageSave(ctx context.Context, imageIDs []string, options ...moby.ImageSaveOption) (moby.ImageSaveResult, error)
+
+	NetworkCreate(ctx context.Context, name string, options moby.NetworkCreateOptions) (moby.NetworkCreateResult, error)
+	NetworkInspect(ctx context.Context, networkID string, options moby.NetworkInspectOptions) (moby.NetworkInspectResult, error)
+	NetworkConnect(ctx context.Context, networkID string, options moby.NetworkConnectOptions) (moby.NetworkConnectResult, error)
+	NetworkRemove(ctx context.Context, networkID string, options moby.NetworkRemoveOptions) (moby.NetworkRemoveResult, error)
+}
+
+var _ ContainerRuntime = (*moby.Client)(nil)
+
+// newRuntime returns the ContainerRuntime for engine, connected with the
+// Docker SDK options mobyOpts.
+func newRuntime(engine string, mobyOpts []moby.Opt) (ContainerRuntime, error) {
+	switch engine {
+	case "", EngineDocker:
+		return moby.NewClientWithOpts(mobyOpts...)
+	case EnginePodman:
+		cli, err := moby.NewClientWithOpts(mobyOpts...)
+		if err != nil {
+			return nil, err
+		}
+		return podmanRuntime{cli}, nil
+	default:
+		return nil, fmt.Errorf("unknown container engine %q (use %s or %s)", engine, EngineDocker, EnginePodman)
+	}
+}
+
+// podmanRuntime drives Podman through the Docker-compatible API its service
+// (podman system service) serves. That API has everything sandboxes use but
+// checkpoints, which Podman only offers through its own API.
+type podmanRuntime struct {
+	*moby.Client
+}
+
+// podmanSocket returns the default socket of the Podman service: the
+// rootless one under XDG_RUNTIME_DIR when it exists, else the rootful one.
+func podmanSocket() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" && os.Geteuid() != 0 {
+		sock := filepath.Join(dir, "podman", "podman.sock")
+		if _, err := os.Stat(sock); err == nil {
+			return "unix://" + sock
+		}
+	}
+	return "unix:///run/podman/podman.sock"
+}
+
+func (podmanRuntime) CheckpointCreate(context.Context, string, moby.CheckpointCreateOptions) (moby.CheckpointCreateResult, error) {
+	return moby.CheckpointCreateResult{}, fmt.Errorf("%w: checkpoints", ErrUnsupportedByEngine)
+}
+
+func (podmanRuntime) CheckpointList(context.Context, string, moby.CheckpointListOptions) (moby.CheckpointListResult, error) {
+	return moby.CheckpointListResult{}, fmt.Errorf("%w: checkpoints", ErrUnsupportedByEngine)
+}
+
+func (podmanRuntime) CheckpointRemove(context.Context, string, moby.CheckpointRemoveOptions) (moby.CheckpointRemoveResult, error) {
+	return moby.CheckpointRemoveResult{}, fmt.Errorf("%w: checkpoints", ErrUnsupportedByEngine)
+}
@@ -0,0 +1,108 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+
+	"opensbx/internal/database"
+	"opensbx/internal/logging"
+	"opensbx/models"
+
+	moby "github.com/moby/moby/client"
+)
+
+// GetEnv returns a sandbox's environment: the variables its container was
+// created with, overridden by those set with UpdateEnv.
+func (c *Client) GetEnv(ctx context.Context, id string) (models.SandboxEnv, error) {
+	info, err := c.cli.ContainerInspect(ctx, id, moby.ContainerInspectOptions{})
+	if err != nil {
+		return models.SandboxEnv{}, wrapNotFound(err)
+	}
+	env := map[string]string{}
+	if info.Container.Config != nil {
+		env = parseEnv(info.Container.Config.Env)
+	}
+	sb, err := c.repo.FindByID(info.Container.ID)
+	if err != nil {
+		return models.SandboxEnv{}, err
+	}
+	if sb != nil {
+		maps.Copy(env, sb.Env)
+	}
+	return models.SandboxEnv{Env: env}, nil
+}
+
+// UpdateEnv sets and removes variables on a sandbox without recreating it. A
+// nil value removes a variable set earlier through UpdateEnv; variables the
+// container was created with can only be overridden. Commands and terminals
+// started afterwards see the new values, running processes keep theirs.
+func (c *Client) UpdateEnv(ctx context.Context, id string, vars map[string]*string) (models.SandboxEnv, error) {
+	for k := range vars {
+		if err := validateEnvName(k); err != nil {
+			return models.SandboxEnv{}, err
+		}
+	}
+	sb, err := c.repo.FindByID(id)
+	if err != nil {
+		return models.SandboxEnv{}, err
+	}
+	if sb == nil {
+		return models.SandboxEnv{}, ErrNotFound
+	}
+
+	env := database.JSONMap{}
+	maps.Copy(env, sb.Env)
+	for k, v := range vars {
+		if v == nil {
+			delete(env, k)
+		} else {
+			env[k] = *v
+		}
+	}
+	if err := c.repo.UpdateEnv(sb.ID, env); err != nil {
+		return models.SandboxEnv{}, err
+	}
+	return c.GetEnv(ctx, sb.ID)
+}
+
+// sandboxEnv returns the variables set on a sandbox after creation, for execs.
+func (c *Client) sandboxEnv(ctx context.Context, id string) map[string]string {
+	sb, err := c.repo.FindByID(id)
+	if err != nil {
+		logging.FromContext(ctx).Error("database: failed to load sandbox env", "sandbox_id", id, "err", err)
+		return nil
+	}
+	if sb == nil {
+		return nil
+	}
+	return sb.Env
+}
+
+// validateEnvName rejects names a process environment cannot hold.
+func validateEnvName(name string) error {
+	if name == "" || strings.ContainsAny(name, "=\x00") {
+		return fmt.Errorf("%w: %q", ErrInvalidEnv, name)
+	}
+	return nil
+}
+
+// parseEnv converts KEY=VALUE entries to a map.
+func parseEnv(entries []string) map[string]string {
+	env := make(map[string]string, len(entries))
+	for _, e := range entries {
+		k, v, _ := strings.Cut(e, "=")
+		env[k] = v
+	}
+	return env
+}
+
+// envSlice converts a map to KEY=VALUE entries.
+func envSlice(env map[string]string) []string {
+	out := make([]string, 0, len(env))
+	for k, v := range env {
+		out = append(out, k+"="+v)
+	}
+	return out
+}
@@ -1,6 +1,9 @@
 package docker
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 // ErrNotFound is returned when a container does not exist.
 var ErrNotFound = errors.New("sandbox not found")
@@ -8,6 +11,14 @@ var ErrNotFound = errors.New("sandbox not found")
 // ErrImageNotFound is returned when an image does not exist locally.
 var ErrImageNotFound = errors.New("image not found locally")
 
+// ErrImageExists is returned when a snapshot would replace an image it does
+// not own, such as a base image or another owner's snapshot.
+var ErrImageExists = errors.New("image already exists and was not snapshotted by this owner")
+
+// ErrQuotaExceeded is returned when starting a sandbox would exceed the global
+// quota or the quota of the caller's API key.
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
 // ErrAlreadyRunning is returned when trying to start a sandbox that is already running.
 var ErrAlreadyRunning = errors.New("sandbox is already running")
 
@@ -28,3 +39,129 @@ var ErrCommandNotFound = errors.New("command not found")
 
 // ErrCommandFinished is returned when trying to kill a command that has already exited.
 var ErrCommandFinished = errors.New("command has already finished")
+
+// ErrPathNotFound is returned when a file or directory does not exist inside a sandbox.
+var ErrPathNotFound = errors.New("path not found")
+
+// ErrNotADirectory is returned when a directory listing targets something else.
+var ErrNotADirectory = errors.New("not a directory")
+
+// ErrNotAFile is returned when a file operation targets a directory or special file.
+var ErrNotAFile = errors.New("not a regular file")
+
+// ErrInvalidArchive is returned when an uploaded archive cannot be read.
+var ErrInvalidArchive = errors.New("invalid archive")
+
+// ErrInvalidReference is returned when an image reference cannot be parsed.
+var ErrInvalidReference = errors.New("invalid image reference")
+
+// ErrInvalidBundle is returned when an import body is not a valid sandbox bundle or image archive.
+var ErrInvalidBundle = errors.New("invalid sandbox bundle")
+
+// ErrInvalidDomain is returned when a custom domain is not a valid hostname.
+var ErrInvalidDomain = errors.New("invalid domain")
+
+// ErrDomainTaken is returned when a custom domain is already mapped to another sandbox.
+var ErrDomainTaken = errors.New("domain is already mapped to another sandbox")
+
+// ErrDomainNotFound is returned when a sandbox has no mapping for the given domain.
+var ErrDomainNotFound = errors.New("domain not found")
+
+// ErrProcessExists is returned when a sandbox already has a process with the given name.
+var ErrProcessExists = errors.New("process already exists")
+
+// ErrProcessNotFound is returned when a sandbox has no process with the given name.
+var ErrProcessNotFound = errors.New("process not found")
+
+// ErrSessionNotFound is returned when a sandbox has no session with the given ID.
+var ErrSessionNotFound = errors.New("session not found")
+
+// ErrSessionBusy is returned when a cell is executed while the session is still running another.
+var ErrSessionBusy = errors.New("session is executing another cell")
+
+// ErrInterpreterUnavailable is returned when a session's interpreter cannot be
+// started, e.g. because the sandbox image does not provide it.
+var ErrInterpreterUnavailable = errors.New("interpreter could not be started")
+
+// ErrTunnelNotFound is returned when a sandbox has no tunnel with the given ID.
+var ErrTunnelNotFound = errors.New("tunnel not found")
+
+// ErrPortUnreachable is returned when a tunnel connection cannot reach its
+// port, e.g. because nothing listens on it inside the sandbox.
+var ErrPortUnreachable = errors.New("port is not reachable")
+
+// ErrGitFailed is returned when cloning or pulling a repository inside a
+// sandbox fails; it carries git's error output.
+var ErrGitFailed = errors.New("git failed")
+
+// ErrHookFailed is returned when a lifecycle hook exits non-zero or times out.
+var ErrHookFailed = errors.New("hook failed")
+
+// ErrPolicyViolation is returned when a container configuration grants host privileges the policy denies.
+var ErrPolicyViolation = errors.New("host policy violation")
+
+// ErrNetworkPolicyUnsupported is returned when a sandbox asks for egress rules or
+// bandwidth limits on a server without the egress firewall.
+var ErrNetworkPolicyUnsupported = errors.New("egress rules and bandwidth limits require the egress firewall (-egress-firewall)")
+
+// ErrUnsupportedByEngine is returned for features the configured container engine does not offer.
+var ErrUnsupportedByEngine = errors.New("not supported by the container engine")
+
+// ErrScheduleNotFound is returned when a schedule does not exist or belongs to another owner.
+var ErrScheduleNotFound = errors.New("schedule not found")
+
+// ErrScheduleExists is returned when a schedule is created with a name that is already in use.
+var ErrScheduleExists = errors.New("schedule already exists")
+
+// ErrRuntimeNotFound is returned when a sandbox asks for an OCI runtime the Docker daemon does not have.
+var ErrRuntimeNotFound = errors.New("runtime not configured on this worker")
+
+// ErrNameTaken is returned when a sandbox is created with a name that is already in use.
+var ErrNameTaken = errors.New("sandbox name is already taken")
+
+// ErrCheckpointNotFound is returned when a sandbox has no checkpoint with the given name.
+var ErrCheckpointNotFound = errors.New("checkpoint not found")
+
+// ErrInvalidCheckpoint is returned when a checkpoint name is not valid.
+var ErrInvalidCheckpoint = errors.New("invalid checkpoint name")
+
+// ErrInvalidEnv is returned when an environment variable name is not valid.
+var ErrInvalidEnv = errors.New("invalid environment variable")
+
+// ErrSecretsDisabled is returned when a sandbox references secrets on a server without a secrets store.
+var ErrSecretsDisabled = errors.New("secrets are disabled on this server (set SECRETS_KEY)")
+
+// ErrStackNotFound is returned when a stack does not exist or belongs to another owner.
+var ErrStackNotFound = errors.New("stack not found")
+
+// ErrStackExists is returned when a stack is created with a name that is already in use.
+var ErrStackExists = errors.New("stack already exists")
+
+// ErrNetworkGroupNotFound is returned when a network group does not exist or belongs to another owner.
+var ErrNetworkGroupNotFound = errors.New("network not found")
+
+// ErrNetworkGroupExists is returned when a network group is created with a name that is already in use.
+var ErrNetworkGroupExists = errors.New("network already exists")
+
+// ErrNetworkGroupInUse is returned when a network group is deleted while sandboxes are attached to it.
+var ErrNetworkGroupInUse = errors.New("network has sandboxes attached")
+
+// ErrLifetimeExceeded is returned when a sandbox that reached its max lifetime
+// is started, restarted or renewed.
+var ErrLifetimeExceeded = errors.New("sandbox reached its max lifetime")
+
+// ErrOperationInProgress is returned when a lifecycle operation is requested
+// while another one is still running on the same sandbox.
+var ErrOperationInProgress = errors.New("another operation is in progress on this sandbox")
+
+// ErrIdempotencyKeyReused is returned when an Idempotency-Key is sent again
+// with a different request than the one it was first used with.
+var ErrIdempotencyKeyReused = errors.New("idempotency key was already used with a different request")
+
+// ErrIdempotencyInProgress is returned when an Idempotency-Key is sent again
+// while the request it was first used with is still running.
+var ErrIdempotencyInProgress = errors.New("a request with this idempotency key is still in progress")
+
+// ErrOOMKilled is returned instead of a bare ErrNotRunning when the sandbox
+// stopped because the kernel killed it for exceeding its memory limit.
+var ErrOOMKilled = fmt.Errorf("%w: it was killed for exceeding its memory limit, restart it or raise resources.memory", ErrNotRunning)
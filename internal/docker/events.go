@@ -0,0 +1,138 @@
+package docker
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"opensbx/internal/database"
+	"opensbx/models"
+
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/api/types/events"
+	moby "github.com/moby/moby/client"
+)
+
+// Sandbox event types. All but EventHookFailed are recorded from the Docker
+// event stream.
+const (
+	EventOOM        = "oom"         // a process in the sandbox hit the memory limit
+	EventExit       = "exit"        // the sandbox stopped without being asked to
+	EventRestart    = "restart"     // the sandbox was restarted
+	EventHookFailed = "hook_failed" // an on_start or before_stop hook failed
+)
+
+const (
+	// maxSandboxEvents is how many events are kept per sandbox.
+	maxSandboxEvents = 50
+
+	// eventRetryDelay is how long the watcher waits before reconnecting to a
+	// failed event stream.
+	eventRetryDelay = 5 * time.Second
+)
+
+// StartEventWatcher subscribes to the Docker events of managed containers and
+// records OOM kills, unexpected exits and restarts per sandbox, reconnecting
+// when the stream fails. Runs until ctx is cancelled.
+func (c *Client) StartEventWatcher(ctx context.Context) {
+	go func() {
+		rec := newEventRecorder(c.repo)
+		for {
+			err := c.watchEvents(ctx, rec)
+			if ctx.Err() != nil {
+				return
+			}
+			slog.Warn("event watcher: stream failed, reconnecting", "err", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(eventRetryDelay):
+			}
+		}
+	}()
+}
+
+// watchEvents feeds container events to rec until the stream fails.
+func (c *Client) watchEvents(ctx context.Context, rec *eventRecorder) error {
+	filters := managedFilter().
+		Add("type", string(events.ContainerEventType)).
+		Add("event", string(events.ActionOOM), string(events.ActionKill), string(events.ActionDie), string(events.ActionRestart))
+	stream := c.cli.Events(ctx, moby.EventsListOptions{Filters: filters})
+	for {
+		select {
+		case msg := <-stream.Messages:
+			rec.handle(msg)
+		case err := <-stream.Err:
+			return err
+		}
+	}
+}
+
+// eventRecorder turns container events into sandbox events. Docker sends a
+// "kill" before the "die" of every stop it was asked for (API stop, restart,
+// timeout or remove), so only a "die" without one is an unexpected exit.
+type eventRecorder struct {
+	repo   *database.Repository
+	killed map[string]bool // containers signalled by Docker since their last exit
+}
+
+func newEventRecorder(repo *database.Repository) *eventRecorder {
+	return &eventRecorder{repo: repo, killed: make(map[string]bool)}
+}
+
+// handle records msg if it is a sandbox event.
+func (r *eventRecorder) handle(msg events.Message) {
+	id := msg.Actor.ID
+	e := database.SandboxEvent{SandboxID: id, CreatedAt: time.Now().UnixMilli()}
+	if msg.TimeNano > 0 {
+		e.CreatedAt = time.Unix(0, msg.TimeNano).UnixMilli()
+	}
+
+	switch msg.Action {
+	case events.ActionKill:
+		r.killed[id] = true
+		return
+	case events.ActionDie:
+		if r.killed[id] {
+			delete(r.killed, id)
+			return
+		}
+		e.Type = EventExit
+		if code, err := strconv.Atoi(msg.Actor.Attributes["exitCode"]); err == nil {
+			e.ExitCode = &code
+		}
+	case events.ActionOOM:
+		e.Type = EventOOM
+	case events.ActionRestart:
+		e.Type = EventRestart
+	default:
+		return
+	}
+
+	if err := r.repo.SaveSandboxEvent(e, maxSandboxEvents); err != nil {
+		slog.Error("event watcher: failed to record event", "sandbox_id", id, "type", e.Type, "err", err)
+	}
+}
+
+// sandboxEvents returns the recorded events of a sandbox for SandboxDetail.
+func (c *Client) sandboxEvents(id string) ([]models.SandboxEvent, error) {
+	records, err := c.repo.FindSandboxEvents(id)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]models.SandboxEvent, 0, len(records))
+	for _, e := range records {
+		out = append(out, models.SandboxEvent{Type: e.Type, Hook: e.Hook, ExitCode: e.ExitCode, Time: time.UnixMilli(e.CreatedAt).UTC()})
+	}
+	return out, nil
+}
+
+// notRunning returns the error for an operation that needs a running sandbox:
+// ErrOOMKilled when its last run ended in an OOM kill, else ErrNotRunning.
+func notRunning(state *container.State) error {
+	if state != nil && state.OOMKilled {
+		return ErrOOMKilled
+	}
+	return ErrNotRunning
+}
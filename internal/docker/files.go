@@ -0,0 +1,253 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"opensbx/models"
+
+	moby "github.com/moby/moby/client"
+)
+
+// Limits for recursive listings and searches.
+const (
+	DefaultTreeDepth   = 3     // depth of ?tree=true listings when none is given
+	MaxTreeDepth       = 10    // deepest ?tree=true listing allowed
+	DefaultSearchLimit = 1000  // matches returned by a search when no limit is given
+	maxSearchDepth     = 32    // directory levels a search descends
+	maxWalkEntries     = 20000 // entries read from the sandbox per listing or search
+)
+
+// File types reported in FileNode.Type.
+const (
+	FileTypeDir     = "dir"
+	FileTypeFile    = "file"
+	FileTypeSymlink = "symlink"
+	FileTypeOther   = "other" // devices, sockets and pipes
+)
+
+// walkScript prints "<type> <path>" for every entry below $1 up to depth $2,
+// stopping after $3 lines. Exit 3 = missing path, 4 = not a directory.
+// Only POSIX find options are used so it also runs on busybox.
+const walkScript = `[ -e "$1" ] || exit 3
+[ -d "$1" ] || exit 4
+{
+find "$1" -mindepth 1 -maxdepth "$2" -type d 2>/dev/null | sed 's/^/d /'
+find "$1" -mindepth 1 -maxdepth "$2" -type l 2>/dev/null | sed 's/^/l /'
+find "$1" -mindepth 1 -maxdepth "$2" ! -type d ! -type l 2>/dev/null | sed 's/^/f /'
+} | head -n "$3"`
+
+// walkEntry is one entry found below a listing's root.
+type walkEntry struct {
+	path string // absolute path
+	typ  string // FileTypeDir, FileTypeFile or FileTypeSymlink
+}
+
+// walk lists the entries below root up to depth levels. truncated is set when
+// more than maxWalkEntries entries exist.
+func (c *Client) walk(ctx context.Context, id, root string, depth int) ([]walkEntry, bool, error) {
+	c.Touch(id)
+	limit := strconv.Itoa(maxWalkEntries + 1)
+	result, err := c.execWithStdin(ctx, id, []string{"sh", "-c", walkScript, "walk", root, strconv.Itoa(depth), limit}, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	switch result.exitCode {
+	case 0:
+	case 3:
+		return nil, false, ErrPathNotFound
+	case 4:
+		return nil, false, ErrNotADirectory
+	default:
+		return nil, false, fmt.Errorf("list %s: exit code %d: %s", root, result.exitCode, strings.TrimSpace(result.stderr))
+	}
+	entries, truncated := parseWalk(result.stdout)
+	return entries, truncated, nil
+}
+
+// parseWalk parses walkScript output.
+func parseWalk(out string) ([]walkEntry, bool) {
+	types := map[byte]string{'d': FileTypeDir, 'f': FileTypeFile, 'l': FileTypeSymlink}
+	var entries []walkEntry
+	for _, line := range strings.Split(out, "\n") {
+		if len(line) < 3 || line[1] != ' ' {
+			continue
+		}
+		typ, ok := types[line[0]]
+		if !ok {
+			continue
+		}
+		entries = append(entries, walkEntry{path: line[2:], typ: typ})
+	}
+	if len(entries) > maxWalkEntries {
+		return entries[:maxWalkEntries], true
+	}
+	return entries, false
+}
+
+// FileTree returns the entries below root as a tree, depth levels deep.
+// Directories come first, then files, each sorted by name.
+func (c *Client) FileTree(ctx context.Context, id, root string, depth int) ([]models.FileNode, bool, error) {
+	root = path.Clean(root)
+	entries, truncated, err := c.walk(ctx, id, root, depth)
+	if err != nil {
+		return nil, false, err
+	}
+	return buildFileTree(root, entries), truncated, nil
+}
+
+// treeNode is a FileNode under construction.
+type treeNode struct {
+	node     models.FileNode
+	children []*treeNode
+}
+
+// buildFileTree nests entries under root by their parent directory. Entries
+// whose parent is missing (e.g. cut off by the entry limit) are dropped.
+func buildFileTree(root string, entries []walkEntry) []models.FileNode {
+	top := &treeNode{}
+	nodes := map[string]*treeNode{root: top}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].path < entries[j].path })
+	for _, e := range entries {
+		n := &treeNode{node: models.FileNode{Name: path.Base(e.path), Path: e.path, Type: e.typ}}
+		if e.typ == FileTypeDir {
+			nodes[e.path] = n
+		}
+		if parent, ok := nodes[path.Dir(e.path)]; ok {
+			parent.children = append(parent.children, n)
+		}
+	}
+	return flattenTree(top.children)
+}
+
+func flattenTree(children []*treeNode) []models.FileNode {
+	sort.Slice(children, func(i, j int) bool {
+		a, b := children[i].node, children[j].node
+		if (a.Type == FileTypeDir) != (b.Type == FileTypeDir) {
+			return a.Type == FileTypeDir
+		}
+		return a.Name < b.Name
+	})
+	out := make([]models.FileNode, 0, len(children))
+	for _, child := range children {
+		n := child.node
+		if len(child.children) > 0 {
+			n.Children = flattenTree(child.children)
+		}
+		out = append(out, n)
+	}
+	return out
+}
+
+// SearchFiles returns up to limit entries below root whose path relative to
+// root matches glob. truncated is set when matches or scanned entries were cut off.
+func (c *Client) SearchFiles(ctx context.Context, id, root, glob string, limit int) ([]models.FileNode, bool, error) {
+	root = path.Clean(root)
+	entries, truncated, err := c.walk(ctx, id, root, maxSearchDepth)
+	if err != nil {
+		return nil, false, err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].path < entries[j].path })
+
+	matches := []models.FileNode{}
+	for _, e := range entries {
+		rel := strings.TrimPrefix(strings.TrimPrefix(e.path, root), "/")
+		if !MatchGlob(glob, rel) {
+			continue
+		}
+		if len(matches) == limit {
+			return matches, true, nil
+		}
+		matches = append(matches, models.FileNode{Name: path.Base(e.path), Path: e.path, Type: e.typ})
+	}
+	return matches, truncated, nil
+}
+
+// ValidGlob reports whether pattern is a well-formed glob for MatchGlob.
+func ValidGlob(pattern string) bool {
+	if pattern == "" {
+		return false
+	}
+	for _, seg := range strings.Split(pattern, "/") {
+		if _, err := path.Match(seg, ""); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchGlob matches a slash-separated relative path against pattern. Segments
+// follow path.Match, and a "**" segment matches any number of directories,
+// including none: "**/*.ts" matches "a.ts" and "src/lib/a.ts".
+func MatchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// StatFile returns metadata for path inside a sandbox without reading it.
+// Symlinks are reported as such, with their target.
+func (c *Client) StatFile(ctx context.Context, id, filePath string) (models.FileStat, error) {
+	c.Touch(id)
+	result, err := c.cli.ContainerStatPath(ctx, id, moby.ContainerStatPathOptions{Path: filePath})
+	if err != nil {
+		return models.FileStat{}, c.pathError(ctx, id, err)
+	}
+	return fileStat(filePath, result.Stat.Size, result.Stat.Mode, result.Stat.Mtime, result.Stat.LinkTarget), nil
+}
+
+// fileStat builds the API view of a stat result.
+func fileStat(filePath string, size int64, mode os.FileMode, mtime time.Time, linkTarget string) models.FileStat {
+	st := models.FileStat{
+		Path:        filePath,
+		Name:        path.Base(filePath),
+		Type:        fileType(mode),
+		Size:        size,
+		Mode:        fmt.Sprintf("%04o", mode.Perm()),
+		Permissions: mode.String(),
+		ModTime:     mtime.UTC(),
+	}
+	if st.Type == FileTypeSymlink {
+		st.LinkTarget = linkTarget
+	}
+	return st
+}
+
+// fileType classifies mode as one of the FileType constants.
+func fileType(mode os.FileMode) string {
+	switch {
+	case mode.IsDir():
+		return FileTypeDir
+	case mode&os.ModeSymlink != 0:
+		return FileTypeSymlink
+	case mode.IsRegular():
+		return FileTypeFile
+	default:
+		return FileTypeOther
+	}
+}
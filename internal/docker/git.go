@@ -0,0 +1,213 @@
+package docker
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"opensbx/models"
+
+	moby "github.com/moby/moby/client"
+)
+
+// LabelGitDir records the directory a sandbox's repository was cloned into,
+// which POST /git/pull updates by default.
+const LabelGitDir = reservedLabelRoot + "git-dir"
+
+// defaultGitDir is where repositories are cloned when neither git.dir nor
+// working_dir is set.
+const defaultGitDir = "/workspace"
+
+// gitScript runs git with the credentials read from stdin: a first line with
+// an HTTP Authorization header value, possibly empty, then an optional SSH
+// private key. Neither ends up on a command line, in the sandbox's
+// environment or in the repository's config.
+const gitScript = `IFS= read -r auth
+if [ -n "$auth" ]; then
+	export GIT_CONFIG_COUNT=1 GIT_CONFIG_KEY_0=http.extraHeader GIT_CONFIG_VALUE_0="Authorization: $auth"
+fi
+key=$(mktemp) || exit 1
+trap 'rm -f "$key"' EXIT
+cat > "$key"
+if [ -s "$key" ]; then
+	chmod 600 "$key"
+	export GIT_SSH_COMMAND="ssh -i $key -o IdentitiesOnly=yes -o BatchMode=yes -o StrictHostKeyChecking=accept-new"
+fi
+export GIT_TERMINAL_PROMPT=0
+git "$@"`
+
+var (
+	// gitCommitPattern matches an abbreviated or full commit hash.
+	gitCommitPattern = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+	// scpGitURLPattern matches scp-like ssh URLs such as git@github.com:acme/web.git.
+	scpGitURLPattern = regexp.MustCompile(`^[A-Za-z0-9._-]+@[A-Za-z0-9.-]+:[^/]`)
+)
+
+// ValidateGitSource checks the git option of a create request. Returns an
+// empty string when valid or a client-facing message otherwise.
+func ValidateGitSource(src *models.GitSource) string {
+	if src == nil {
+		return ""
+	}
+	if msg := validateGitURL(src.URL); msg != "" {
+		return msg
+	}
+	if strings.HasPrefix(src.Ref, "-") || strings.ContainsAny(src.Ref, " \t\r\n") {
+		return fmt.Sprintf("git.ref %q is not a branch, tag or commit", src.Ref)
+	}
+	if src.Depth < 0 {
+		return "git.depth must be >= 0"
+	}
+	return validateGitAuth("git.", src.Dir, src.Token, src.SSHKey)
+}
+
+// ValidateGitPull checks the body of POST /git/pull.
+func ValidateGitPull(req models.GitPullRequest) string {
+	return validateGitAuth("", req.Dir, req.Token, req.SSHKey)
+}
+
+func validateGitURL(raw string) string {
+	if scpGitURLPattern.MatchString(raw) {
+		return ""
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return "git.url must be an https://, ssh:// or git@host:path URL"
+	}
+	switch u.Scheme {
+	case "https", "http", "ssh", "git":
+	default:
+		return "git.url must be an https://, ssh:// or git@host:path URL"
+	}
+	if _, ok := u.User.Password(); ok {
+		return "git.url must not contain a password, use git.token"
+	}
+	return ""
+}
+
+func validateGitAuth(prefix, dir, token, sshKey string) string {
+	if dir != "" && !path.IsAbs(dir) {
+		return prefix + "dir must be an absolute path"
+	}
+	if token != "" && sshKey != "" {
+		return prefix + "token and " + prefix + "ssh_key cannot be combined"
+	}
+	if strings.ContainsAny(token, "\r\n") {
+		return prefix + "token must be a single line"
+	}
+	return ""
+}
+
+// gitCloneDir returns the directory src is cloned into.
+func gitCloneDir(src models.GitSource, workingDir string) string {
+	switch {
+	case src.Dir != "":
+		return path.Clean(src.Dir)
+	case workingDir != "":
+		return path.Clean(workingDir)
+	}
+	return defaultGitDir
+}
+
+// gitCredentials is the stdin of gitScript.
+func gitCredentials(token, sshKey string) io.Reader {
+	auth := ""
+	if token != "" {
+		auth = "Basic " + base64.StdEncoding.EncodeToString([]byte("x-access-token:"+token))
+	}
+	if sshKey != "" && !strings.HasSuffix(sshKey, "\n") {
+		sshKey += "\n" // OpenSSH rejects keys without a final newline
+	}
+	return strings.NewReader(auth + "\n" + sshKey)
+}
+
+// runGit runs git with args in a sandbox. Returns ErrGitFailed with git's
+// error output when it fails.
+func (c *Client) runGit(ctx context.Context, id, token, sshKey string, args ...string) error {
+	cmd := append([]string{"sh", "-c", gitScript, "git"}, args...)
+	result, err := c.execWithStdin(ctx, id, cmd, gitCredentials(token, sshKey))
+	if err != nil {
+		return err
+	}
+	switch result.exitCode {
+	case 0:
+		return nil
+	case 127:
+		return fmt.Errorf("%w: git is not installed in the sandbox image", ErrGitFailed)
+	}
+	return fmt.Errorf("%w: %s", ErrGitFailed, strings.TrimSpace(result.stderr))
+}
+
+// cloneGit clones src into dir inside a sandbox and checks out its ref.
+func (c *Client) cloneGit(ctx context.Context, id, dir string, src models.GitSource) (models.GitStatus, error) {
+	commit := gitCommitPattern.MatchString(src.Ref)
+	args := []string{"clone", "--quiet"}
+	if src.Depth > 0 && !commit {
+		args = append(args, "--depth", strconv.Itoa(src.Depth))
+	}
+	if src.Ref != "" && !commit {
+		args = append(args, "--branch", src.Ref)
+	}
+	args = append(args, "--", src.URL, dir)
+	if err := c.runGit(ctx, id, src.Token, src.SSHKey, args...); err != nil {
+		return models.GitStatus{}, err
+	}
+	if commit {
+		if err := c.runGit(ctx, id, "", "", "-C", dir, "checkout", "--quiet", src.Ref); err != nil {
+			return models.GitStatus{}, err
+		}
+	}
+	return c.gitStatus(ctx, id, dir)
+}
+
+// PullGit fast-forwards the repository in a sandbox to its upstream branch,
+// by default the one cloned by the git option on create.
+func (c *Client) PullGit(ctx context.Context, id string, req models.GitPullRequest) (models.GitStatus, error) {
+	info, err := c.cli.ContainerInspect(ctx, id, moby.ContainerInspectOptions{})
+	if err != nil {
+		return models.GitStatus{}, wrapNotFound(err)
+	}
+	if !info.Container.State.Running {
+		return models.GitStatus{}, notRunning(info.Container.State)
+	}
+	dir := req.Dir
+	if dir == "" && info.Container.Config != nil {
+		dir = info.Container.Config.Labels[LabelGitDir]
+	}
+	if dir == "" {
+		return models.GitStatus{}, fmt.Errorf("%w: the sandbox was not created with git, set dir", ErrGitFailed)
+	}
+	c.Touch(info.Container.ID)
+
+	if err := c.runGit(ctx, info.Container.ID, req.Token, req.SSHKey, "-C", dir, "pull", "--quiet", "--ff-only"); err != nil {
+		return models.GitStatus{}, err
+	}
+	return c.gitStatus(ctx, info.Container.ID, dir)
+}
+
+// gitStatus reads the checked-out commit and branch of a repository.
+func (c *Client) gitStatus(ctx context.Context, id, dir string) (models.GitStatus, error) {
+	script := `cd "$1" && git rev-parse HEAD && { git symbolic-ref --quiet --short HEAD || true; }`
+	result, err := c.execWithStdin(ctx, id, []string{"sh", "-c", script, "sh", dir}, nil)
+	if err != nil {
+		return models.GitStatus{}, err
+	}
+	if result.exitCode != 0 {
+		return models.GitStatus{}, fmt.Errorf("%w: %s", ErrGitFailed, strings.TrimSpace(result.stderr))
+	}
+	lines := strings.Fields(result.stdout)
+	status := models.GitStatus{Dir: dir}
+	if len(lines) > 0 {
+		status.Commit = lines[0]
+	}
+	if len(lines) > 1 {
+		status.Branch = lines[1]
+	}
+	return status, nil
+}
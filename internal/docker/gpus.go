@@ -0,0 +1,50 @@
+package docker
+
+import (
+	"slices"
+
+	"opensbx/models"
+
+	"github.com/moby/moby/api/types/container"
+)
+
+// gpuCapability is the device request capability Docker's GPU drivers
+// (e.g. the NVIDIA container toolkit) register for.
+const gpuCapability = "gpu"
+
+// gpuDeviceRequests maps a sandbox's GPU selection to Docker device requests.
+func gpuDeviceRequests(g *models.GPUs) []container.DeviceRequest {
+	if g == nil {
+		return nil
+	}
+	req := container.DeviceRequest{Capabilities: [][]string{{gpuCapability}}}
+	if len(g.DeviceIDs) > 0 {
+		req.DeviceIDs = slices.Clone(g.DeviceIDs)
+	} else {
+		req.Count = g.Count
+	}
+	return []container.DeviceRequest{req}
+}
+
+// containerGPUs reads a GPU selection back from a container's device requests.
+func containerGPUs(reqs []container.DeviceRequest) *models.GPUs {
+	for _, r := range reqs {
+		if !isGPURequest(r) {
+			continue
+		}
+		if len(r.DeviceIDs) > 0 {
+			return &models.GPUs{DeviceIDs: r.DeviceIDs}
+		}
+		return &models.GPUs{Count: r.Count}
+	}
+	return nil
+}
+
+func isGPURequest(r container.DeviceRequest) bool {
+	for _, caps := range r.Capabilities {
+		if slices.Contains(caps, gpuCapability) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package docker
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"opensbx/models"
+
+	"github.com/moby/moby/api/types/container"
+)
+
+// Hardening holds the container hardening applied to every sandbox. Requests
+// may tighten it through models.SecurityOptions but never loosen it.
+type Hardening struct {
+	ReadOnlyRootfs  bool     // mount the root filesystem read-only
+	NoNewPrivileges bool     // set no-new-privileges
+	CapDrop         []string // capabilities dropped from Docker's default set
+	SeccompProfile  string   // seccomp profile JSON, "" = Docker's default profile
+	PidsLimit       int64    // maximum processes, 0 = unlimited
+	User            string   // user sandboxes run as, "" = the image's user
+}
+
+// readOnlyTmpfs keeps the usual scratch directories writable on a read-only rootfs.
+var readOnlyTmpfs = map[string]string{
+	"/tmp": "rw,nosuid,nodev",
+	"/run": "rw,nosuid,nodev",
+}
+
+// SetHardening replaces the hardening defaults applied on container creation.
+func (c *Client) SetHardening(h Hardening) {
+	c.hardening = h
+}
+
+// Security returns the effective security options for a sandbox that asked
+// for opts, or ErrPolicyViolation if opts would loosen the server defaults.
+func (h Hardening) Security(opts *models.SecurityOptions) (Hardening, error) {
+	eff := h
+	eff.CapDrop = slices.Clone(h.CapDrop)
+	if opts == nil {
+		return eff, nil
+	}
+
+	if opts.ReadOnlyRootfs != nil {
+		if h.ReadOnlyRootfs && !*opts.ReadOnlyRootfs {
+			return Hardening{}, fmt.Errorf("%w: a writable root filesystem is not allowed", ErrPolicyViolation)
+		}
+		eff.ReadOnlyRootfs = *opts.ReadOnlyRootfs
+	}
+	if opts.NoNewPrivileges != nil {
+		if h.NoNewPrivileges && !*opts.NoNewPrivileges {
+			return Hardening{}, fmt.Errorf("%w: disabling no-new-privileges is not allowed", ErrPolicyViolation)
+		}
+		eff.NoNewPrivileges = *opts.NoNewPrivileges
+	}
+	for _, capName := range opts.CapDrop {
+		if !slices.Contains(eff.CapDrop, capName) {
+			eff.CapDrop = append(eff.CapDrop, capName)
+		}
+	}
+	if opts.SeccompProfile != "" {
+		// Profiles cannot be compared, so a server profile cannot be replaced.
+		if h.SeccompProfile != "" && opts.SeccompProfile != h.SeccompProfile {
+			return Hardening{}, fmt.Errorf("%w: the server seccomp profile cannot be replaced", ErrPolicyViolation)
+		}
+		eff.SeccompProfile = opts.SeccompProfile
+	}
+	if opts.PidsLimit > 0 {
+		if h.PidsLimit > 0 && opts.PidsLimit > h.PidsLimit {
+			return Hardening{}, fmt.Errorf("%w: pids_limit above %d is not allowed", ErrPolicyViolation, h.PidsLimit)
+		}
+		eff.PidsLimit = opts.PidsLimit
+	}
+	if opts.User != "" {
+		if h.User != "" && isRootUser(opts.User) {
+			return Hardening{}, fmt.Errorf("%w: running as root is not allowed", ErrPolicyViolation)
+		}
+		eff.User = opts.User
+	}
+	return eff, nil
+}
+
+// apply writes the hardening into a container configuration. It must run after
+// the resource limits are set, since the pids limit is one of them.
+func (h Hardening) apply(cfg *container.Config, hostCfg *container.HostConfig) {
+	hostCfg.ReadonlyRootfs = h.ReadOnlyRootfs
+	if h.ReadOnlyRootfs {
+		hostCfg.Tmpfs = maps.Clone(readOnlyTmpfs)
+	}
+	if h.NoNewPrivileges {
+		hostCfg.SecurityOpt = append(hostCfg.SecurityOpt, "no-new-privileges:true")
+	}
+	if h.SeccompProfile != "" {
+		hostCfg.SecurityOpt = append(hostCfg.SecurityOpt, "seccomp="+h.SeccompProfile)
+	}
+	hostCfg.CapDrop = h.CapDrop
+	if h.PidsLimit > 0 {
+		limit := h.PidsLimit
+		hostCfg.Resources.PidsLimit = &limit
+	}
+	if h.User != "" {
+		cfg.User = h.User
+	}
+}
+
+// isRootUser reports whether a user spec ("name", "uid", "user:group") is root.
+func isRootUser(user string) bool {
+	name, _, _ := strings.Cut(user, ":")
+	return name == "root" || name == "0"
+}
@@ -0,0 +1,178 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"opensbx/internal/database"
+	"opensbx/internal/logging"
+	"opensbx/models"
+)
+
+// Lifecycle hook names, as used in CreateSandboxRequest.Hooks and reported by
+// hook_failed events.
+const (
+	HookOnCreate   = "on_create"
+	HookOnStart    = "on_start"
+	HookBeforeStop = "before_stop"
+)
+
+// Lifecycle hook limits.
+const (
+	DefaultHookTimeout = 300  // seconds a hook may run when it sets no timeout
+	MaxHookTimeout     = 3600 // longest hook timeout allowed
+)
+
+// maxHookOutput bounds the stderr quoted in the error of a failed hook; the
+// full output stays in the command's logs.
+const maxHookOutput = 1024
+
+// namedHooks returns the hooks that are set, in the order they run.
+func namedHooks(hooks *models.Hooks) []struct {
+	name string
+	hook *models.Hook
+} {
+	all := []struct {
+		name string
+		hook *models.Hook
+	}{
+		{HookOnCreate, hooks.OnCreate},
+		{HookOnStart, hooks.OnStart},
+		{HookBeforeStop, hooks.BeforeStop},
+	}
+	set := all[:0]
+	for _, h := range all {
+		if h.hook != nil {
+			set = append(set, h)
+		}
+	}
+	return set
+}
+
+// ValidateHooks checks the lifecycle hooks of a create request. Returns an
+// empty string when valid or a client-facing message otherwise.
+func ValidateHooks(hooks *models.Hooks) string {
+	if hooks == nil {
+		return ""
+	}
+	for _, h := range namedHooks(hooks) {
+		switch {
+		case len(h.hook.Command) == 0 || h.hook.Command[0] == "":
+			return fmt.Sprintf("hooks.%s.command is required", h.name)
+		case h.hook.Cwd != "" && !path.IsAbs(h.hook.Cwd):
+			return fmt.Sprintf("hooks.%s.cwd must be an absolute path", h.name)
+		case h.hook.Timeout < 0 || h.hook.Timeout > MaxHookTimeout:
+			return fmt.Sprintf("hooks.%s.timeout must be between 0 and %d", h.name, MaxHookTimeout)
+		}
+	}
+	return ""
+}
+
+// encodeHooks formats lifecycle hooks for storage; nil is stored as "".
+func encodeHooks(hooks *models.Hooks) string {
+	if hooks == nil {
+		return ""
+	}
+	b, _ := json.Marshal(hooks)
+	return string(b)
+}
+
+// decodeHooks parses stored lifecycle hooks; "" is nil.
+func decodeHooks(s string) (*models.Hooks, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var hooks models.Hooks
+	if err := json.Unmarshal([]byte(s), &hooks); err != nil {
+		return nil, fmt.Errorf("stored hooks: %w", err)
+	}
+	return &hooks, nil
+}
+
+// runHook runs a hook inside a running sandbox as a command and waits for it
+// to finish. A non-zero exit or a timeout is ErrHookFailed.
+func (c *Client) runHook(ctx context.Context, id, name string, hook models.Hook) (models.CommandDetail, error) {
+	timeout := hook.Timeout
+	if timeout == 0 {
+		timeout = DefaultHookTimeout
+	}
+	cmd, err := c.startCommand(ctx, id, models.ExecCommandRequest{
+		Command: hook.Command[0],
+		Args:    hook.Command[1:],
+		Cwd:     hook.Cwd,
+		Timeout: timeout,
+	})
+	if err != nil {
+		return models.CommandDetail{}, fmt.Errorf("%s hook: %w", name, err)
+	}
+	if cmd, err = c.WaitCommand(ctx, id, cmd.ID); err != nil {
+		return models.CommandDetail{}, fmt.Errorf("%s hook: %w", name, err)
+	}
+
+	switch {
+	case cmd.TimedOut:
+		return cmd, fmt.Errorf("%w: %s timed out after %ds (command %s)", ErrHookFailed, name, timeout, cmd.ID)
+	case cmd.ExitCode == nil:
+		return cmd, fmt.Errorf("%w: %s did not exit (command %s)", ErrHookFailed, name, cmd.ID)
+	case *cmd.ExitCode != 0:
+		msg := fmt.Sprintf("%s exited with code %d (command %s)", name, *cmd.ExitCode, cmd.ID)
+		if logs, err := c.GetCommandLogs(ctx, id, cmd.ID); err == nil {
+			if stderr := strings.TrimSpace(logs.Stderr); stderr != "" {
+				if len(stderr) > maxHookOutput {
+					stderr = "..." + stderr[len(stderr)-maxHookOutput:]
+				}
+				msg += ": " + stderr
+			}
+		}
+		return cmd, fmt.Errorf("%w: %s", ErrHookFailed, msg)
+	}
+	return cmd, nil
+}
+
+// runStoredHook runs the hook name a sandbox was created with, if it has one.
+// The lifecycle operation it belongs to goes ahead either way, so a failure is
+// logged and recorded as a hook_failed event rather than returned.
+func (c *Client) runStoredHook(ctx context.Context, id, name string) {
+	sb, err := c.repo.FindByID(id)
+	if err != nil || sb == nil {
+		return
+	}
+	hooks, err := decodeHooks(sb.Hooks)
+	if err != nil {
+		logging.FromContext(ctx).Error("invalid stored hooks", "sandbox_id", id, "err", err)
+		return
+	}
+	if hooks == nil {
+		return
+	}
+	hook := hooks.OnStart
+	if name == HookBeforeStop {
+		hook = hooks.BeforeStop
+	}
+	if hook == nil {
+		return
+	}
+
+	cmd, err := c.runHook(ctx, id, name, *hook)
+	if err == nil {
+		return
+	}
+	logging.FromContext(ctx).Warn("sandbox hook failed", "sandbox_id", id, "hook", name, "err", err)
+	if !errors.Is(err, ErrHookFailed) {
+		return // the hook could not be started, e.g. the sandbox had already exited
+	}
+	if err := c.repo.SaveSandboxEvent(database.SandboxEvent{
+		SandboxID: id,
+		Type:      EventHookFailed,
+		Hook:      name,
+		ExitCode:  cmd.ExitCode,
+		CreatedAt: time.Now().UnixMilli(),
+	}, maxSandboxEvents); err != nil {
+		logging.FromContext(ctx).Error("database: failed to record hook failure", "sandbox_id", id, "err", err)
+	}
+}
@@ -0,0 +1,94 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"opensbx/internal/database"
+	"opensbx/models"
+)
+
+const (
+	// idempotencyTTL is how long a create is remembered for retries with the
+	// same Idempotency-Key.
+	idempotencyTTL = 24 * time.Hour
+
+	// idempotencyAbandoned is how long a key can stay reserved by a create
+	// that never finished, e.g. because the server stopped, before a retry
+	// takes it over.
+	idempotencyAbandoned = 10 * time.Minute
+)
+
+// BeginIdempotent reserves key for a create whose request hashes to
+// requestHash. It returns the recorded response when a create with the key
+// already succeeded, or nil when the caller should create the sandbox and
+// then call FinishIdempotent. Keys are scoped to the owner of ctx.
+func (c *Client) BeginIdempotent(ctx context.Context, key, requestHash string) (*models.CreateSandboxResponse, error) {
+	now := time.Now()
+	if _, err := c.repo.DeleteIdempotencyKeysBefore(now.Add(-idempotencyTTL).UnixMilli()); err != nil {
+		return nil, err
+	}
+
+	rec := database.IdempotencyKey{Owner: OwnerFrom(ctx), Key: key, RequestHash: requestHash, CreatedAt: now.UnixMilli()}
+	reserved, err := c.repo.ReserveIdempotencyKey(rec)
+	if err != nil || reserved {
+		return nil, err
+	}
+
+	existing, err := c.repo.FindIdempotencyKey(rec.Owner, key)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, ErrIdempotencyInProgress // released between the insert and the read
+	}
+	if existing.RequestHash != requestHash {
+		return nil, ErrIdempotencyKeyReused
+	}
+	if existing.Response == "" {
+		if now.Sub(time.UnixMilli(existing.CreatedAt)) < idempotencyAbandoned {
+			return nil, ErrIdempotencyInProgress
+		}
+		// Concurrent retries may all see the key abandoned; only one takes it over.
+		taken, err := c.repo.TakeOverIdempotencyKey(rec, existing.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		if !taken {
+			return nil, ErrIdempotencyInProgress
+		}
+		slog.Warn("idempotency: took over abandoned key", "key", key)
+		return nil, nil
+	}
+
+	var resp models.CreateSandboxResponse
+	if err := json.Unmarshal([]byte(existing.Response), &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// FinishIdempotent records the response of a create started with
+// BeginIdempotent. A nil resp means the create failed: the key is released
+// so the request can be retried.
+func (c *Client) FinishIdempotent(ctx context.Context, key string, resp *models.CreateSandboxResponse) error {
+	owner := OwnerFrom(ctx)
+	if resp == nil {
+		return c.repo.DeleteIdempotencyKey(owner, key)
+	}
+	rec, err := c.repo.FindIdempotencyKey(owner, key)
+	if err != nil {
+		return err
+	}
+	if rec == nil {
+		return nil // expired while the create ran
+	}
+	b, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	rec.Response = string(b)
+	return c.repo.SaveIdempotencyKey(*rec)
+}
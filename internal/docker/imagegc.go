@@ -0,0 +1,180 @@
+package docker
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"opensbx/internal/database"
+	"opensbx/models"
+
+	"github.com/moby/moby/api/types/image"
+	moby "github.com/moby/moby/client"
+)
+
+// DefaultImageRetention is how long an image must have gone unused before
+// POST /v1/images/prune removes it, unless the request says otherwise.
+const DefaultImageRetention = 24 * time.Hour
+
+// imageGCInterval is how often the image garbage collector sweeps.
+const imageGCInterval = time.Hour
+
+// StartImageGC periodically removes images opensbx brought in (see trackImage)
+// that no container has used for longer than retention. A zero retention disables it. Runs until ctx is cancelled.
+func (c *Client) StartImageGC(ctx context.Context, retention time.Duration) {
+	if retention <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(imageGCInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if res, err := c.PruneImages(ctx, retention); err != nil {
+					slog.Error("image gc: sweep failed", "err", err)
+				} else if len(res.Deleted) > 0 {
+					slog.Info("image gc: removed unused images", "count", len(res.Deleted), "reclaimed_bytes", res.SpaceReclaimed)
+				}
+			}
+		}
+	}()
+}
+
+// PruneImages removes images opensbx pulled, imported or cloned that no
+// container, running or stopped, has used for at least unusedFor. Docker does
+// not record when an image was last used, so every sweep records the tracked
+// images in use. Other images on the daemon and snapshot images (LabelSnapshot)
+// are kept.
+func (c *Client) PruneImages(ctx context.Context, unusedFor time.Duration) (models.ImagePruneResponse, error) {
+	images, err := c.cli.ImageList(ctx, moby.ImageListOptions{})
+	if err != nil {
+		return models.ImagePruneResponse{}, err
+	}
+	containers, err := c.cli.ContainerList(ctx, moby.ContainerListOptions{All: true})
+	if err != nil {
+		return models.ImagePruneResponse{}, err
+	}
+	inUse := make(map[string]bool, len(containers.Items))
+	for _, ctr := range containers.Items {
+		inUse[ctr.ImageID] = true
+	}
+	uses, err := c.repo.FindImageUses()
+	if err != nil {
+		return models.ImagePruneResponse{}, err
+	}
+	lastUsed := make(map[string]int64, len(uses))
+	for _, u := range uses {
+		if u.Managed {
+			lastUsed[u.ImageID] = u.LastUsed
+		}
+	}
+
+	now := time.Now()
+	remove, touch := planImageGC(images.Items, inUse, lastUsed, now.Add(-unusedFor).UnixMilli())
+	for _, id := range touch {
+		if err := c.repo.SaveImageUse(database.ImageUse{ImageID: id, LastUsed: now.UnixMilli(), Managed: true}); err != nil {
+			return models.ImagePruneResponse{}, err
+		}
+	}
+	// Forget images removed outside the collector.
+	present := make(map[string]bool, len(images.Items))
+	for _, img := range images.Items {
+		present[img.ID] = true
+	}
+	for _, u := range uses {
+		if !present[u.ImageID] {
+			c.repo.DeleteImageUse(u.ImageID)
+		}
+	}
+
+	resp := models.ImagePruneResponse{Deleted: []models.ImageSummary{}}
+	if len(remove) == 0 {
+		return resp, nil
+	}
+	before, err := c.imagesSize(ctx)
+	if err != nil {
+		return resp, err
+	}
+	for _, img := range remove {
+		if err := c.removeUnusedImage(ctx, img); err != nil {
+			slog.Warn("image gc: remove image failed", "image", img.ID, "tags", img.RepoTags, "err", err)
+			continue
+		}
+		c.repo.DeleteImageUse(img.ID)
+		resp.Deleted = append(resp.Deleted, models.ImageSummary{ID: img.ID, Tags: img.RepoTags, Size: img.Size})
+	}
+	if after, err := c.imagesSize(ctx); err == nil && after < before {
+		resp.SpaceReclaimed = before - after
+	}
+	return resp, nil
+}
+
+// planImageGC returns the images a sweep removes and the IDs whose last use
+// must be recorded as now: tracked images in use. Only images in lastUsed are
+// considered; those last used after cutoff are kept.
+func planImageGC(images []image.Summary, inUse map[string]bool, lastUsed map[string]int64, cutoff int64) (remove []image.Summary, touch []string) {
+	for _, img := range images {
+		last, ok := lastUsed[img.ID]
+		if !ok {
+			continue
+		}
+		if inUse[img.ID] {
+			touch = append(touch, img.ID)
+			continue
+		}
+		if _, ok := img.Labels[LabelSnapshot]; ok {
+			continue
+		}
+		if last > cutoff {
+			continue
+		}
+		remove = append(remove, img)
+	}
+	return remove, touch
+}
+
+// trackImage marks the image ref as brought in by opensbx, used now, so the
+// image garbage collector may remove it once unused. Failures are logged: an
+// untracked image is merely kept.
+func (c *Client) trackImage(ctx context.Context, ref string) {
+	img, err := c.cli.ImageInspect(ctx, ref)
+	if err == nil {
+		err = c.repo.SaveImageUse(database.ImageUse{ImageID: img.ID, LastUsed: time.Now().UnixMilli(), Managed: true})
+	}
+	if err != nil {
+		slog.Warn("image gc: track image failed", "image", ref, "err", err)
+	}
+}
+
+// removeUnusedImage removes an image without forcing: tag by tag, so an image
+// with several tags goes away with its last one, or by ID when it has none.
+// Docker refuses if a container started using it in the meantime.
+func (c *Client) removeUnusedImage(ctx context.Context, img image.Summary) error {
+	var refs []string
+	for _, tag := range img.RepoTags {
+		if tag != "<none>:<none>" {
+			refs = append(refs, tag)
+		}
+	}
+	if len(refs) == 0 {
+		refs = []string{img.ID}
+	}
+	for _, ref := range refs {
+		if _, err := c.cli.ImageRemove(ctx, ref, moby.ImageRemoveOptions{PruneChildren: true}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// imagesSize returns the disk space used by all local images.
+func (c *Client) imagesSize(ctx context.Context) (int64, error) {
+	du, err := c.cli.DiskUsage(ctx, moby.DiskUsageOptions{Images: true})
+	if err != nil {
+		return 0, err
+	}
+	return du.Images.TotalSize, nil
+}
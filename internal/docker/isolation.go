@@ -0,0 +1,233 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"sort"
+
+	"opensbx/internal/firewall"
+	"opensbx/internal/logging"
+	"opensbx/models"
+
+	"github.com/containerd/errdefs"
+	"github.com/moby/moby/api/types/network"
+	moby "github.com/moby/moby/client"
+)
+
+// DefaultIsolatedNetwork is the bridge network sandboxes join when isolation is enabled.
+const DefaultIsolatedNetwork = "opensbx-isolated"
+
+// iccOption is the bridge driver option that controls inter-container communication.
+const iccOption = "com.docker.network.bridge.enable_icc"
+
+// SetIsolatedNetwork makes new sandboxes join the named bridge network, which is
+// created on demand with inter-container communication disabled. Empty disables isolation.
+func (c *Client) SetIsolatedNetwork(name string) {
+	c.isolatedNetwork = name
+}
+
+// ensureIsolatedNetwork creates the isolated bridge network if it does not exist yet.
+func (c *Client) ensureIsolatedNetwork(ctx context.Context) error {
+	return c.ensureNetwork(ctx, c.isolatedNetwork, moby.NetworkCreateOptions{
+		Driver:  "bridge",
+		Options: map[string]string{iccOption: "false"},
+	})
+}
+
+// ensureNetwork creates the named managed network with opts if it does not exist yet.
+func (c *Client) ensureNetwork(ctx context.Context, name string, opts moby.NetworkCreateOptions) error {
+	c.networkMu.Lock()
+	defer c.networkMu.Unlock()
+
+	_, err := c.cli.NetworkInspect(ctx, name, moby.NetworkInspectOptions{})
+	if err == nil {
+		return nil
+	}
+	if !errdefs.IsNotFound(err) {
+		return fmt.Errorf("inspect network %s: %w", name, err)
+	}
+
+	opts.Labels = map[string]string{"opensbx.managed": "true"}
+	_, err = c.cli.NetworkCreate(ctx, name, opts)
+	if err != nil && !errdefs.IsConflict(err) {
+		return fmt.Errorf("create network %s: %w", name, err)
+	}
+	return nil
+}
+
+// SandboxSubnets ensures the isolated and internal networks exist and returns
+// their IPv4 subnets, used as the source match for host egress rules.
+func (c *Client) SandboxSubnets(ctx context.Context) ([]netip.Prefix, error) {
+	if c.isolatedNetwork == "" {
+		return nil, fmt.Errorf("sandbox network isolation is disabled")
+	}
+	if err := c.ensureIsolatedNetwork(ctx); err != nil {
+		return nil, err
+	}
+	if _, err := c.networkMode(ctx, NetworkInternal); err != nil {
+		return nil, err
+	}
+
+	var subnets []netip.Prefix
+	for _, name := range []string{c.isolatedNetwork, DefaultInternalNetwork} {
+		found, err := c.networkSubnets(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		subnets = append(subnets, found...)
+	}
+	return subnets, nil
+}
+
+// networkSubnets returns the IPv4 subnets of a network.
+func (c *Client) networkSubnets(ctx context.Context, name string) ([]netip.Prefix, error) {
+	res, err := c.cli.NetworkInspect(ctx, name, moby.NetworkInspectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("inspect network %s: %w", name, err)
+	}
+	var subnets []netip.Prefix
+	for _, cfg := range res.Network.IPAM.Config {
+		if cfg.Subnet.IsValid() && cfg.Subnet.Addr().Is4() {
+			subnets = append(subnets, cfg.Subnet)
+		}
+	}
+	if len(subnets) == 0 {
+		return nil, fmt.Errorf("network %s has no ipv4 subnet", name)
+	}
+	return subnets, nil
+}
+
+// SetEgressFirewall sends traffic from the networks of existing stacks and
+// network groups through the host egress deny rules, and makes CreateStack
+// and CreateNetworkGroup do the same for the networks they create. The caller
+// attaches the isolated and internal networks, see SandboxSubnets.
+func (c *Client) SetEgressFirewall(ctx context.Context, fw *firewall.Manager) error {
+	c.egress = fw
+
+	var names []string
+	stacks, err := c.repo.FindStacks("")
+	if err != nil {
+		return err
+	}
+	for _, st := range stacks {
+		names = append(names, stackNetwork(st.Name))
+	}
+	groups, err := c.repo.FindNetworkGroups("")
+	if err != nil {
+		return err
+	}
+	for _, g := range groups {
+		names = append(names, groupNetwork(g.Name))
+	}
+
+	for _, name := range names {
+		// A network removed behind our back has no sandboxes to filter.
+		if err := c.attachEgress(ctx, name); err != nil && !errdefs.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// attachEgress sends traffic from a stack or network group network through
+// the host egress deny rules. A no-op without SetEgressFirewall.
+func (c *Client) attachEgress(ctx context.Context, name string) error {
+	if c.egress == nil {
+		return nil
+	}
+	subnets, err := c.networkSubnets(ctx, name)
+	if err != nil {
+		return err
+	}
+	for _, subnet := range subnets {
+		if err := c.egress.Attach(ctx, subnet); err != nil {
+			return fmt.Errorf("egress firewall: %w", err)
+		}
+	}
+	return nil
+}
+
+// removeNetwork removes a stack or network group network and detaches its
+// subnets from the host egress deny rules. A network that does not exist is
+// not an error.
+func (c *Client) removeNetwork(ctx context.Context, name string) error {
+	var subnets []netip.Prefix
+	if c.egress != nil {
+		// Read before removal; afterwards Docker may hand the subnet to another network.
+		subnets, _ = c.networkSubnets(ctx, name)
+	}
+	if _, err := c.cli.NetworkRemove(ctx, name, moby.NetworkRemoveOptions{}); err != nil && !errdefs.IsNotFound(err) {
+		return fmt.Errorf("remove network %s: %w", name, err)
+	}
+	for _, subnet := range subnets {
+		if err := c.egress.Detach(ctx, subnet); err != nil {
+			logging.FromContext(ctx).Warn("egress firewall: detach failed", "network", name, "subnet", subnet, "err", err)
+		}
+	}
+	return nil
+}
+
+// Isolation reports whether a sandbox can reach other containers over its networks.
+func (c *Client) Isolation(ctx context.Context, id string) (models.SandboxIsolation, error) {
+	info, err := c.cli.ContainerInspect(ctx, id, moby.ContainerInspectOptions{})
+	if err != nil {
+		return models.SandboxIsolation{}, wrapNotFound(err)
+	}
+
+	ctr := info.Container
+	result := models.SandboxIsolation{
+		NetworkMode: string(ctr.HostConfig.NetworkMode),
+		Networks:    []models.NetworkIsolation{},
+	}
+
+	switch {
+	case ctr.HostConfig.NetworkMode.IsHost():
+		result.Reasons = append(result.Reasons, "sandbox shares the host network namespace")
+	case ctr.HostConfig.NetworkMode.IsNone():
+		// No interfaces besides loopback: nothing to reach.
+	default:
+		names := make([]string, 0, len(ctr.NetworkSettings.Networks))
+		for name := range ctr.NetworkSettings.Networks {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			res, err := c.cli.NetworkInspect(ctx, name, moby.NetworkInspectOptions{})
+			if err != nil {
+				return models.SandboxIsolation{}, fmt.Errorf("inspect network %s: %w", name, err)
+			}
+			ni := networkIsolation(res.Network, ctr.ID)
+			if ni.InterContainer && ni.Peers > 0 {
+				result.Reasons = append(result.Reasons,
+					fmt.Sprintf("network %s allows traffic to %d other container(s)", name, ni.Peers))
+			}
+			result.Networks = append(result.Networks, ni)
+		}
+	}
+
+	result.Isolated = len(result.Reasons) == 0
+	return result, nil
+}
+
+// networkIsolation summarizes a network from the point of view of the given container.
+func networkIsolation(n network.Inspect, containerID string) models.NetworkIsolation {
+	peers := 0
+	for id := range n.Containers {
+		if id != containerID {
+			peers++
+		}
+	}
+
+	// Bridge networks allow inter-container traffic unless explicitly disabled.
+	icc := n.Options[iccOption] != "false"
+
+	return models.NetworkIsolation{
+		Name:           n.Name,
+		Driver:         n.Driver,
+		InterContainer: icc,
+		Internal:       n.Internal,
+		Peers:          peers,
+	}
+}
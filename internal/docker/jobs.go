@@ -0,0 +1,72 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"opensbx/models"
+)
+
+// defaultJobTimeout is how many seconds a job's command may run when it sets no timeout.
+const defaultJobTimeout = 3600
+
+// RunJob creates a sandbox from req.Sandbox, runs req.Command in it until it
+// exits, and removes the sandbox again, also when ctx is cancelled first. The
+// sandbox is set to be deleted on expiry as well, so it cannot outlive a
+// server restart by much.
+func (c *Client) RunJob(ctx context.Context, req models.JobRequest) (models.JobResult, error) {
+	return c.runJob(ctx, req, nil)
+}
+
+// runJob is RunJob with a callback invoked with the sandbox ID once the
+// sandbox is created.
+func (c *Client) runJob(ctx context.Context, req models.JobRequest, onCreate func(id string)) (models.JobResult, error) {
+	spec, command := req.Sandbox, req.Command
+	if command.Timeout == 0 {
+		command.Timeout = defaultJobTimeout
+	}
+	spec.ExpirationAction = ExpirationDelete
+	spec.TimeoutMode = TimeoutAbsolute
+	spec.Timeout = max(spec.Timeout, command.Timeout+60) // outlives the command
+
+	result := models.JobResult{StartedAt: time.Now().UTC()}
+	created, err := c.Create(ctx, spec)
+	if err != nil {
+		return models.JobResult{}, err
+	}
+	result.SandboxID = created.ID
+	if onCreate != nil {
+		onCreate(created.ID)
+	}
+	defer func() {
+		if err := c.Remove(context.WithoutCancel(ctx), created.ID); err != nil {
+			slog.Error("job: failed to remove sandbox", "sandbox_id", created.ID, "err", err)
+		}
+	}()
+
+	if spec.ReadyCheck != nil {
+		if _, err := c.WaitReady(ctx, created.ID); err != nil {
+			return result, fmt.Errorf("wait for ready check: %w", err)
+		}
+	}
+	cmd, err := c.ExecCommand(ctx, created.ID, command)
+	if err != nil {
+		return result, err
+	}
+	if cmd, err = c.WaitCommand(ctx, created.ID, cmd.ID); err != nil {
+		return result, err
+	}
+	logs, err := c.GetCommandLogs(ctx, created.ID, cmd.ID)
+	if err != nil {
+		return result, fmt.Errorf("read command output: %w", err)
+	}
+	result.Stdout, result.Stderr = logs.Stdout, logs.Stderr
+	if cmd.ExitCode == nil {
+		return result, fmt.Errorf("command %s did not exit", cmd.ID)
+	}
+	result.ExitCode, result.TimedOut = *cmd.ExitCode, cmd.TimedOut
+	result.FinishedAt = time.Now().UTC()
+	return result, nil
+}
@@ -0,0 +1,121 @@
+package docker
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"opensbx/internal/database"
+
+	moby "github.com/moby/moby/client"
+)
+
+// Limits on caller-defined sandbox labels.
+const (
+	MaxLabels         = 64
+	MaxLabelKeyLen    = 128
+	MaxLabelValueLen  = 256
+	reservedLabelRoot = "opensbx."
+)
+
+// labelKeyPattern matches label keys such as "team" or "com.example/job-id".
+var labelKeyPattern = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9._/-]*[A-Za-z0-9])?$`)
+
+// ValidateLabels returns an error message for the first invalid label, or ""
+// if all are valid. Keys under the "opensbx." prefix are reserved.
+func ValidateLabels(labels map[string]string) string {
+	if len(labels) > MaxLabels {
+		return fmt.Sprintf("at most %d labels are allowed", MaxLabels)
+	}
+	for k, v := range labels {
+		if msg := validateLabelKey(k); msg != "" {
+			return msg
+		}
+		if len(v) > MaxLabelValueLen {
+			return fmt.Sprintf("label %q: value must be at most %d characters", k, MaxLabelValueLen)
+		}
+	}
+	return ""
+}
+
+func validateLabelKey(k string) string {
+	switch {
+	case len(k) > MaxLabelKeyLen || !labelKeyPattern.MatchString(k):
+		return fmt.Sprintf("invalid label key %q", k)
+	case strings.HasPrefix(k, reservedLabelRoot):
+		return fmt.Sprintf("label key %q: the %s prefix is reserved", k, reservedLabelRoot)
+	}
+	return ""
+}
+
+// ParseLabelSelectors parses ?label= selectors: "key=value" matches that value
+// and "key" matches any value.
+func ParseLabelSelectors(selectors []string) (database.LabelSelector, string) {
+	var sel database.LabelSelector
+	for _, s := range selectors {
+		k, v, hasValue := strings.Cut(s, "=")
+		if msg := validateLabelKey(k); msg != "" {
+			return database.LabelSelector{}, msg
+		}
+		if !hasValue {
+			sel.Keys = append(sel.Keys, k)
+			continue
+		}
+		if sel.Equals == nil {
+			sel.Equals = map[string]string{}
+		}
+		sel.Equals[k] = v
+	}
+	return sel, ""
+}
+
+// Labels the server sets on every sandbox container, under the reserved
+// prefix. They keep `docker ps` legible and let the server recognise its
+// containers without the database. Docker labels cannot change after
+// creation, so expiry is recorded as the timeout the sandbox was created with.
+const (
+	LabelManaged          = reservedLabelRoot + "managed"           // always "true"
+	LabelName             = reservedLabelRoot + "name"              // sandbox name
+	LabelOwner            = reservedLabelRoot + "owner"             // owner ID, absent for unowned sandboxes
+	LabelTimeout          = reservedLabelRoot + "timeout"           // seconds until auto-stop, e.g. "900"
+	LabelExpirationAction = reservedLabelRoot + "expiration-action" // "stop" or "delete"
+)
+
+// managedLabels returns the server's labels for a new sandbox container.
+func managedLabels(name, owner string, timeout int, action string) map[string]string {
+	if action == "" {
+		action = ExpirationStop
+	}
+	labels := map[string]string{
+		LabelManaged:          "true",
+		LabelName:             name,
+		LabelTimeout:          strconv.Itoa(timeout),
+		LabelExpirationAction: action,
+	}
+	if owner != "" {
+		labels[LabelOwner] = owner
+	}
+	return labels
+}
+
+// managedFilter lists only the containers created by this server.
+func managedFilter() moby.Filters {
+	return make(moby.Filters).Add("label", LabelManaged+"=true")
+}
+
+// isManaged reports whether a container carries the server's managed label.
+func isManaged(labels map[string]string) bool {
+	return labels[LabelManaged] == "true"
+}
+
+// callerLabels returns labels without the server's reserved ones.
+func callerLabels(labels map[string]string) map[string]string {
+	out := make(map[string]string, len(labels))
+	for k, v := range labels {
+		if !strings.HasPrefix(k, reservedLabelRoot) {
+			out[k] = v
+		}
+	}
+	return out
+}
@@ -0,0 +1,54 @@
+package docker
+
+import (
+	"time"
+
+	"opensbx/internal/database"
+)
+
+// SetMaxLifetime sets the server cap on how long a sandbox may exist before it
+// is stopped for good, whatever its timeout and renewals. Sandboxes asking for
+// a longer max_lifetime, or for none, get the cap. 0 = no cap.
+func (c *Client) SetMaxLifetime(d time.Duration) {
+	c.maxLifetime = d
+}
+
+// effectiveLifetime returns the max lifetime in seconds a sandbox asking for
+// seconds gets under the server cap; 0 = unlimited.
+func (c *Client) effectiveLifetime(seconds int) int {
+	limit := int(c.maxLifetime / time.Second)
+	if limit > 0 && (seconds <= 0 || seconds > limit) {
+		return limit
+	}
+	return max(seconds, 0)
+}
+
+// lifetimeEnd returns when a sandbox reaches its max lifetime, or the zero
+// time when it has none.
+func lifetimeEnd(sb *database.Sandbox) time.Time {
+	if sb == nil || sb.MaxLifetime <= 0 || sb.CreatedAt == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(sb.CreatedAt).Add(time.Duration(sb.MaxLifetime) * time.Second)
+}
+
+// clampToLifetime shortens d so a timer armed now fires no later than end.
+func clampToLifetime(d time.Duration, end time.Time, now time.Time) time.Duration {
+	if end.IsZero() {
+		return d
+	}
+	return max(min(d, end.Sub(now)), 0)
+}
+
+// checkLifetime returns ErrLifetimeExceeded when a sandbox has reached its max
+// lifetime, so it cannot be started or renewed again.
+func (c *Client) checkLifetime(id string) error {
+	sb, err := c.repo.FindByID(id)
+	if err != nil || sb == nil {
+		return err
+	}
+	if end := lifetimeEnd(sb); !end.IsZero() && !time.Now().Before(end) {
+		return ErrLifetimeExceeded
+	}
+	return nil
+}
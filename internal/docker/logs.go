@@ -0,0 +1,44 @@
+package docker
+
+import (
+	"context"
+	"io"
+
+	"opensbx/models"
+
+	"github.com/moby/moby/api/pkg/stdcopy"
+	moby "github.com/moby/moby/client"
+)
+
+// Logs returns the output of a sandbox's main process (its entrypoint and
+// startup command), which the command logs do not cover, split into stdout
+// and stderr. Without Follow both readers end after the existing output;
+// with it they stay open until ctx ends or the readers are closed.
+func (c *Client) Logs(ctx context.Context, id string, q models.SandboxLogsQuery) (io.ReadCloser, io.ReadCloser, error) {
+	tail := q.Tail
+	if tail == "" {
+		tail = "all"
+	}
+	logs, err := c.cli.ContainerLogs(ctx, id, moby.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     q.Follow,
+		Tail:       tail,
+		Since:      q.Since,
+		Timestamps: q.Timestamps,
+	})
+	if err != nil {
+		return nil, nil, wrapNotFound(err)
+	}
+
+	stdoutR, stdoutW := io.Pipe()
+	stderrR, stderrW := io.Pipe()
+	go func() {
+		// Ends when the logs end or a reader is closed.
+		_, err := stdcopy.StdCopy(stdoutW, stderrW, logs)
+		logs.Close()
+		stdoutW.CloseWithError(err)
+		stderrW.CloseWithError(err)
+	}()
+	return stdoutR, stderrR, nil
+}
@@ -0,0 +1,39 @@
+package docker
+
+import "slices"
+
+// Sandbox isolation levels, reported in SandboxDetail.Isolation.
+const (
+	IsolationContainer = "container" // namespaces and cgroups on the host kernel
+	IsolationMicroVM   = "microvm"   // a lightweight VM with its own kernel per sandbox
+)
+
+// MicroVM describes the OCI runtimes that boot every sandbox in its own
+// micro-VM, such as Kata Containers with QEMU, Firecracker (kata-fc) or Cloud
+// Hypervisor (kata-clh), and what such a VM costs on top of the sandbox's limits.
+type MicroVM struct {
+	Runtimes       []string // OCI runtimes that boot a micro-VM per sandbox
+	MemoryOverhead int64    // MB of guest kernel and VMM memory per running VM, counted against memory quotas
+}
+
+// SetMicroVM sets which runtimes boot micro-VMs and how they are accounted.
+func (c *Client) SetMicroVM(m MicroVM) {
+	c.microVM = m
+}
+
+// Isolation returns the isolation level sandboxes on the given OCI runtime get.
+func (m MicroVM) Isolation(runtime string) string {
+	if runtime != "" && slices.Contains(m.Runtimes, runtime) {
+		return IsolationMicroVM
+	}
+	return IsolationContainer
+}
+
+// overhead returns the memory in MB a sandbox on the given runtime uses
+// beyond its limit: the VM's overhead for micro-VM runtimes, else nothing.
+func (m MicroVM) overhead(runtime string) int64 {
+	if m.Isolation(runtime) == IsolationMicroVM {
+		return m.MemoryOverhead
+	}
+	return 0
+}
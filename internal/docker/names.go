@@ -3,7 +3,21 @@ package docker
 // Random name generator for sandboxes (adjective-surname with hyphens for DNS compatibility).
 // Based on https://github.com/moby/moby/blob/master/internal/namesgenerator/names-generator.go
 
-import "math/rand/v2"
+import (
+	"math/rand/v2"
+	"regexp"
+	"strings"
+)
+
+// sandboxNamePattern matches a DNS label: lowercase letters, digits and hyphens,
+// at most 63 characters, not starting or ending with a hyphen.
+var sandboxNamePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// ValidSandboxName reports whether name can be chosen for a sandbox. Names are
+// subdomains of BASE_DOMAIN, and "--" is reserved for "<name>--<port>" routing.
+func ValidSandboxName(name string) bool {
+	return sandboxNamePattern.MatchString(name) && !strings.Contains(name, "--")
+}
 
 var adjectives = [...]string{
 	"admiring", "adoring", "affectionate", "agitated", "amazing",
@@ -66,3 +66,16 @@ func TestGenerateUniqueName_FallbackSuffix(t *testing.T) {
 		t.Fatalf("expected suffixed name, got %q", name)
 	}
 }
+
+func TestValidSandboxName(t *testing.T) {
+	for _, name := range []string{"web", "my-app-2", "a", strings.Repeat("a", 63), generateName()} {
+		if !ValidSandboxName(name) {
+			t.Fatalf("ValidSandboxName(%q) = false", name)
+		}
+	}
+	for _, name := range []string{"", "-web", "web-", "Web", "my_app", "my.app", "web--3000", strings.Repeat("a", 64)} {
+		if ValidSandboxName(name) {
+			t.Fatalf("ValidSandboxName(%q) = true", name)
+		}
+	}
+}
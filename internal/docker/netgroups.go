@@ -0,0 +1,151 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"opensbx/internal/database"
+	"opensbx/models"
+
+	"github.com/moby/moby/api/types/network"
+	moby "github.com/moby/moby/client"
+)
+
+// networkGroupPrefix names the Docker network of each network group.
+const networkGroupPrefix = "opensbx-net-"
+
+// groupNetwork returns the name of a network group's Docker network.
+func groupNetwork(group string) string {
+	return networkGroupPrefix + group
+}
+
+// CreateNetworkGroup creates a shared network that sandboxes join with
+// network_group. Like stack networks it is internal: it only carries traffic
+// between its sandboxes, which keep their usual network for everything else.
+func (c *Client) CreateNetworkGroup(ctx context.Context, name string) (models.NetworkGroup, error) {
+	if existing, err := c.repo.FindNetworkGroup(name); err != nil {
+		return models.NetworkGroup{}, err
+	} else if existing != nil {
+		return models.NetworkGroup{}, fmt.Errorf("%w: %s", ErrNetworkGroupExists, name)
+	}
+
+	if err := c.ensureNetwork(ctx, groupNetwork(name), moby.NetworkCreateOptions{Driver: "bridge", Internal: true}); err != nil {
+		return models.NetworkGroup{}, err
+	}
+	if err := c.attachEgress(ctx, groupNetwork(name)); err != nil {
+		c.removeNetwork(ctx, groupNetwork(name))
+		return models.NetworkGroup{}, err
+	}
+	g := database.NetworkGroup{Name: name, OwnerID: OwnerFrom(ctx), CreatedAt: time.Now().UnixMilli()}
+	if err := c.repo.SaveNetworkGroup(g); err != nil {
+		return models.NetworkGroup{}, err
+	}
+	return c.networkGroupDetail(g)
+}
+
+// ListNetworkGroups returns the caller's network groups ordered by name.
+func (c *Client) ListNetworkGroups(ctx context.Context) ([]models.NetworkGroup, error) {
+	groups, err := c.repo.FindNetworkGroups(OwnerFrom(ctx))
+	if err != nil {
+		return nil, err
+	}
+	out := make([]models.NetworkGroup, 0, len(groups))
+	for _, g := range groups {
+		detail, err := c.networkGroupDetail(g)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, detail)
+	}
+	return out, nil
+}
+
+// GetNetworkGroup returns a network group and the sandboxes attached to it.
+func (c *Client) GetNetworkGroup(ctx context.Context, name string) (models.NetworkGroup, error) {
+	g, err := c.findNetworkGroup(ctx, name)
+	if err != nil {
+		return models.NetworkGroup{}, err
+	}
+	return c.networkGroupDetail(*g)
+}
+
+// RemoveNetworkGroup deletes a network group. Returns ErrNetworkGroupInUse
+// while sandboxes are attached to it.
+func (c *Client) RemoveNetworkGroup(ctx context.Context, name string) error {
+	if _, err := c.findNetworkGroup(ctx, name); err != nil {
+		return err
+	}
+	attached, err := c.repo.FindByNetworkGroup(name)
+	if err != nil {
+		return err
+	}
+	if len(attached) > 0 {
+		return fmt.Errorf("%w: %d sandboxes, starting with %s", ErrNetworkGroupInUse, len(attached), attached[0].Name)
+	}
+
+	if err := c.removeNetwork(ctx, groupNetwork(name)); err != nil {
+		return err
+	}
+	return c.repo.DeleteNetworkGroup(name)
+}
+
+// findNetworkGroup returns a network group visible to the caller, or ErrNetworkGroupNotFound.
+func (c *Client) findNetworkGroup(ctx context.Context, name string) (*database.NetworkGroup, error) {
+	g, err := c.repo.FindNetworkGroup(name)
+	if err != nil {
+		return nil, err
+	}
+	if g == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNetworkGroupNotFound, name)
+	}
+	if owner := OwnerFrom(ctx); owner != "" && g.OwnerID != owner {
+		return nil, fmt.Errorf("%w: %s", ErrNetworkGroupNotFound, name)
+	}
+	return g, nil
+}
+
+// networkGroupDetail builds the API view of a network group.
+func (c *Client) networkGroupDetail(g database.NetworkGroup) (models.NetworkGroup, error) {
+	attached, err := c.repo.FindByNetworkGroup(g.Name)
+	if err != nil {
+		return models.NetworkGroup{}, err
+	}
+	detail := models.NetworkGroup{
+		Name:      g.Name,
+		Network:   groupNetwork(g.Name),
+		Sandboxes: make([]string, 0, len(attached)),
+		CreatedAt: time.UnixMilli(g.CreatedAt).UTC(),
+	}
+	for _, sb := range attached {
+		detail.Sandboxes = append(detail.Sandboxes, sb.Name)
+	}
+	return detail, nil
+}
+
+// joinNetworks connects a created container to its stack's network, where it
+// is reachable by service name, and to its network group, if any.
+func (c *Client) joinNetworks(ctx context.Context, containerID, group string, opts createOptions) error {
+	if opts.stack != "" {
+		if err := c.joinNetwork(ctx, containerID, stackNetwork(opts.stack), opts.service); err != nil {
+			return err
+		}
+	}
+	if group != "" {
+		return c.joinNetwork(ctx, containerID, groupNetwork(group))
+	}
+	return nil
+}
+
+// joinNetwork connects a container to a network. User-defined networks resolve
+// containers by name; aliases add more names.
+func (c *Client) joinNetwork(ctx context.Context, containerID, name string, aliases ...string) error {
+	_, err := c.cli.NetworkConnect(ctx, name, moby.NetworkConnectOptions{
+		Container:      containerID,
+		EndpointConfig: &network.EndpointSettings{Aliases: aliases},
+	})
+	if err != nil {
+		return fmt.Errorf("join network %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"opensbx/internal/database"
+	"opensbx/internal/firewall"
+	"opensbx/models"
+
+	"github.com/moby/moby/api/types/container"
+	moby "github.com/moby/moby/client"
+)
+
+// Network modes accepted in CreateSandboxRequest.Network.
+const (
+	NetworkBridge   = "bridge"   // outbound access through the isolated network (or Docker's default bridge)
+	NetworkInternal = "internal" // DefaultInternalNetwork: no outbound access
+	NetworkNone     = "none"     // loopback only
+)
+
+// DefaultInternalNetwork is the network "internal" sandboxes join. It has no
+// route out of the host, but its sandboxes can reach each other.
+const DefaultInternalNetwork = "opensbx-internal"
+
+// SetSandboxFirewall enables per-sandbox egress rules and bandwidth limits.
+// Without it, creating a sandbox that asks for either fails.
+func (c *Client) SetSandboxFirewall(fw *firewall.Sandbox) {
+	c.firewall = fw
+}
+
+// networkMode returns the Docker network mode for a sandbox network setting,
+// creating the backing network if needed. Empty means Docker's default bridge.
+func (c *Client) networkMode(ctx context.Context, network string) (container.NetworkMode, error) {
+	switch network {
+	case NetworkNone:
+		return container.NetworkMode(NetworkNone), nil
+	case NetworkInternal:
+		err := c.ensureNetwork(ctx, DefaultInternalNetwork, moby.NetworkCreateOptions{Driver: "bridge", Internal: true})
+		return container.NetworkMode(DefaultInternalNetwork), err
+	}
+	// Join the isolated network so sandboxes cannot reach each other.
+	if c.isolatedNetwork != "" {
+		if err := c.ensureIsolatedNetwork(ctx); err != nil {
+			return "", err
+		}
+		return container.NetworkMode(c.isolatedNetwork), nil
+	}
+	return "", nil
+}
+
+// networkPolicy converts the egress and bandwidth fields of a create request.
+func networkPolicy(req models.CreateSandboxRequest) (firewall.Policy, error) {
+	var p firewall.Policy
+	if req.Egress != nil {
+		var err error
+		if p.Allow, err = firewall.ParseRules(strings.Join(req.Egress.Allow, ",")); err != nil {
+			return firewall.Policy{}, err
+		}
+		if p.Deny, err = firewall.ParseRules(strings.Join(req.Egress.Deny, ",")); err != nil {
+			return firewall.Policy{}, err
+		}
+	}
+	if req.Bandwidth != nil {
+		p.IngressKbps = req.Bandwidth.IngressKbps
+		p.EgressKbps = req.Bandwidth.EgressKbps
+	}
+	return p, nil
+}
+
+// storedNetworkPolicy rebuilds the policy persisted for a sandbox.
+func storedNetworkPolicy(sb *database.Sandbox) (firewall.Policy, error) {
+	allow, err := firewall.ParseRules(sb.EgressAllow)
+	if err != nil {
+		return firewall.Policy{}, err
+	}
+	deny, err := firewall.ParseRules(sb.EgressDeny)
+	if err != nil {
+		return firewall.Policy{}, err
+	}
+	return firewall.Policy{Allow: allow, Deny: deny, IngressKbps: sb.IngressKbps, EgressKbps: sb.EgressKbps}, nil
+}
+
+// joinRules formats rules for storage, in the format ParseRules reads back.
+func joinRules(rules []firewall.Rule) string {
+	out := make([]string, len(rules))
+	for i, r := range rules {
+		out[i] = r.String()
+	}
+	return strings.Join(out, ",")
+}
+
+// enforceNetworkPolicy applies p to a running sandbox. Its rules live in the
+// sandbox's network namespace, so this must run after every start. With a
+// remote engine the container's PID names a process on another machine, so
+// policies are unsupported.
+func (c *Client) enforceNetworkPolicy(ctx context.Context, id string, p firewall.Policy) error {
+	if p.Empty() {
+		return nil
+	}
+	if c.firewall == nil || !c.LocalDaemon() {
+		return ErrNetworkPolicyUnsupported
+	}
+	info, err := c.cli.ContainerInspect(ctx, id, moby.ContainerInspectOptions{})
+	if err != nil {
+		return wrapNotFound(err)
+	}
+	if info.Container.State.Pid == 0 {
+		return ErrNotRunning
+	}
+	if err := c.firewall.Apply(ctx, info.Container.State.Pid, p); err != nil {
+		return fmt.Errorf("apply network policy: %w", err)
+	}
+	return nil
+}
+
+// reapplyNetworkPolicy applies a sandbox's stored policy after a start or
+// restart. The sandbox is stopped if that fails, so it never runs unrestricted.
+func (c *Client) reapplyNetworkPolicy(ctx context.Context, id string) error {
+	sb, err := c.repo.FindByID(id)
+	if err != nil || sb == nil {
+		return err
+	}
+	p, err := storedNetworkPolicy(sb)
+	if err == nil {
+		err = c.enforceNetworkPolicy(ctx, id, p)
+	}
+	if err != nil {
+		c.cancelTimer(id)
+		if _, stopErr := c.cli.ContainerStop(ctx, id, moby.ContainerStopOptions{}); stopErr != nil {
+			return fmt.Errorf("%w (stopping the sandbox also failed: %v)", err, stopErr)
+		}
+		c.setState(ctx, id, database.SandboxStopped)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+
+	"opensbx/internal/database"
+	"opensbx/models"
+
+	moby "github.com/moby/moby/client"
+)
+
+// nodeStatsWorkers bounds the concurrent per-sandbox stats calls made by NodeStats.
+const nodeStatsWorkers = 8
+
+// NodeStats aggregates the sandboxes on this node: counts by state, the
+// resources allocated to and used by running sandboxes, and the host capacity.
+// Scoped callers only see their own sandboxes.
+func (c *Client) NodeStats(ctx context.Context) (models.NodeStats, error) {
+	var records []database.Sandbox
+	var err error
+	if owner := OwnerFrom(ctx); owner != "" {
+		records, err = c.repo.FindByOwner(owner)
+	} else {
+		records, err = c.repo.FindAll()
+	}
+	if err != nil {
+		return models.NodeStats{}, err
+	}
+
+	result, err := c.cli.ContainerList(ctx, moby.ContainerListOptions{All: true, Filters: managedFilter()})
+	if err != nil {
+		return models.NodeStats{}, err
+	}
+	states := make(map[string]string, len(result.Items))
+	for _, item := range result.Items {
+		states[item.ID] = string(item.State)
+	}
+
+	info, err := c.cli.Info(ctx, moby.InfoOptions{})
+	if err != nil {
+		return models.NodeStats{}, fmt.Errorf("docker info: %w", err)
+	}
+
+	stats := models.NodeStats{
+		Sandboxes: models.SandboxCounts{Total: len(records), ByState: map[string]int{}},
+		Capacity:  models.ResourceTotals{Memory: info.Info.MemTotal / (1024 * 1024), CPUs: float64(info.Info.NCPU)},
+	}
+	var running []string
+	for _, sb := range records {
+		state, ok := states[sb.ID]
+		if !ok {
+			state = "removed"
+		}
+		stats.Sandboxes.ByState[state]++
+		if state == "running" {
+			running = append(running, sb.ID)
+			stats.Allocated.Memory += sb.Memory
+			stats.Allocated.CPUs += sb.CPUs
+		}
+	}
+	stats.Used = c.usedResources(ctx, running)
+	return stats, nil
+}
+
+// usedResources sums the live memory and CPU usage of the given sandboxes.
+// Sandboxes that stop while being sampled are skipped.
+func (c *Client) usedResources(ctx context.Context, ids []string) models.ResourceTotals {
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		memory uint64
+		cpu    float64
+	)
+	sem := make(chan struct{}, nodeStatsWorkers)
+	for _, id := range ids {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			s, err := c.Stats(ctx, id)
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			memory += s.Memory.Usage
+			cpu += s.CPU
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return models.ResourceTotals{
+		Memory: int64(memory / (1024 * 1024)), // bytes to MB
+		CPUs:   math.Round(cpu) / 100,         // percent of one CPU to CPUs
+	}
+}
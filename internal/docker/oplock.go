@@ -0,0 +1,58 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// opLockRetry is how often waitLockSandbox retries a busy sandbox.
+const opLockRetry = 100 * time.Millisecond
+
+// lockSandbox marks a lifecycle operation (start, stop, remove, ...) as running
+// on a sandbox, so concurrent ones cannot interleave their timer, database and
+// proxy cache updates. It returns ErrOperationInProgress, naming the running
+// operation, if the sandbox is busy, and otherwise a func that releases it.
+// id must be the full container ID; see lockResolved.
+func (c *Client) lockSandbox(id, op string) (func(), error) {
+	if running, busy := c.ops.LoadOrStore(id, op); busy {
+		return nil, fmt.Errorf("%w: %s", ErrOperationInProgress, running)
+	}
+	return func() { c.ops.Delete(id) }, nil
+}
+
+// lockResolved is lockSandbox for a sandbox reference: ref, a name or a full
+// or short ID, is resolved to the full container ID first, so callers naming
+// the same sandbox differently exclude each other. It returns the ID locked.
+// A ref that does not resolve is locked as given, and the operation fails or
+// cleans up as it would without the lock.
+func (c *Client) lockResolved(ctx context.Context, ref, op string) (string, func(), error) {
+	id, err := c.Resolve(ctx, ref)
+	if errors.Is(err, ErrNotFound) {
+		id = ref
+	} else if err != nil {
+		return "", nil, err
+	}
+	unlock, err := c.lockSandbox(id, op)
+	if err != nil {
+		return "", nil, err
+	}
+	return id, unlock, nil
+}
+
+// waitLockSandbox is lockSandbox for background work, which waits for the
+// running operation to finish instead of failing. It returns nil if cancel is
+// closed first.
+func (c *Client) waitLockSandbox(id, op string, cancel <-chan struct{}) func() {
+	for {
+		if unlock, err := c.lockSandbox(id, op); err == nil {
+			return unlock
+		}
+		select {
+		case <-cancel:
+			return nil
+		case <-time.After(opLockRetry):
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package docker
+
+import (
+	"context"
+
+	moby "github.com/moby/moby/client"
+)
+
+// ownerKey is the context key carrying the caller's owner ID.
+type ownerKey struct{}
+
+// WithOwner scopes ctx to an owner: sandboxes created with it are tagged with
+// the owner, and List and CheckOwner only expose that owner's sandboxes.
+// An empty owner leaves ctx unrestricted.
+func WithOwner(ctx context.Context, owner string) context.Context {
+	if owner == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, ownerKey{}, owner)
+}
+
+// OwnerFrom returns the owner ctx is scoped to, or "" if unrestricted.
+func OwnerFrom(ctx context.Context) string {
+	owner, _ := ctx.Value(ownerKey{}).(string)
+	return owner
+}
+
+// Resolve returns the full container ID of the sandbox that ref names. ref
+// may be a full ID, a name, or anything Docker resolves, such as a short ID.
+// Returns ErrNotFound if nothing matches, or only a container this server did
+// not create.
+func (c *Client) Resolve(ctx context.Context, ref string) (string, error) {
+	if sb, err := c.repo.FindByID(ref); err != nil {
+		return "", err
+	} else if sb != nil {
+		return sb.ID, nil
+	}
+	if sb, err := c.repo.FindByName(ref); err != nil {
+		return "", err
+	} else if sb != nil {
+		return sb.ID, nil
+	}
+	info, err := c.cli.ContainerInspect(ctx, ref, moby.ContainerInspectOptions{})
+	if err != nil {
+		return "", wrapNotFound(err)
+	}
+	// Never hand out containers this server did not create.
+	if info.Container.Config == nil || !isManaged(info.Container.Config.Labels) {
+		return "", ErrNotFound
+	}
+	return info.Container.ID, nil
+}
+
+// CheckOwner returns ErrNotFound unless the caller in ctx may access sandbox id.
+// Unrestricted callers may access every sandbox. id may be a full or short
+// container ID or a name, like everywhere else in the API.
+func (c *Client) CheckOwner(ctx context.Context, id string) error {
+	owner := OwnerFrom(ctx)
+	if owner == "" {
+		return nil
+	}
+
+	info, err := c.cli.ContainerInspect(ctx, id, moby.ContainerInspectOptions{})
+	if err != nil {
+		return wrapNotFound(err)
+	}
+	sb, err := c.repo.FindByID(info.Container.ID)
+	if err != nil {
+		return err
+	}
+	// Report foreign sandboxes as missing so their existence does not leak.
+	if sb == nil || sb.OwnerID != owner {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"opensbx/models"
+
+	"github.com/moby/moby/api/types/container"
+	moby "github.com/moby/moby/client"
+)
+
+// Policy restricts which host privileges a sandbox container may be granted.
+// Privileged mode, host namespaces, added capabilities and host mounts are always
+// denied; devices must be listed in AllowedDevices.
+type Policy struct {
+	AllowedDevices  []string // host device paths sandboxes may map, e.g. "/dev/fuse"
+	AllowedRuntimes []string // OCI runtimes sandboxes may use, empty = any configured runtime
+	AllowGPUs       bool     // sandboxes may request GPUs
+}
+
+// SetPolicy replaces the host policy enforced on container creation.
+func (c *Client) SetPolicy(p Policy) {
+	c.policy = p
+}
+
+// Policy returns the active host policy.
+func (c *Client) Policy() models.HostPolicy {
+	devices := c.policy.AllowedDevices
+	if devices == nil {
+		devices = []string{}
+	}
+	runtimes := c.policy.AllowedRuntimes
+	if runtimes == nil {
+		runtimes = []string{}
+	}
+	// Every host privilege is denied; only the device and runtime allowlists and GPUs are configurable.
+	return models.HostPolicy{AllowedDevices: devices, AllowedRuntimes: runtimes, GPUs: c.policy.AllowGPUs}
+}
+
+// Validate returns ErrPolicyViolation if hostCfg grants anything the policy denies.
+func (p Policy) Validate(hostCfg *container.HostConfig) error {
+	if hostCfg == nil {
+		return nil
+	}
+	switch {
+	case hostCfg.Privileged:
+		return fmt.Errorf("%w: privileged mode is not allowed", ErrPolicyViolation)
+	case hostCfg.PidMode.IsHost():
+		return fmt.Errorf("%w: host pid namespace is not allowed", ErrPolicyViolation)
+	case hostCfg.IpcMode.IsHost():
+		return fmt.Errorf("%w: host ipc namespace is not allowed", ErrPolicyViolation)
+	case hostCfg.NetworkMode.IsHost():
+		return fmt.Errorf("%w: host network is not allowed", ErrPolicyViolation)
+	case hostCfg.UTSMode.IsHost():
+		return fmt.Errorf("%w: host uts namespace is not allowed", ErrPolicyViolation)
+	case hostCfg.UsernsMode.IsHost():
+		return fmt.Errorf("%w: host user namespace is not allowed", ErrPolicyViolation)
+	case len(hostCfg.CapAdd) > 0:
+		return fmt.Errorf("%w: adding capabilities is not allowed", ErrPolicyViolation)
+	case len(hostCfg.Binds) > 0 || len(hostCfg.Mounts) > 0:
+		return fmt.Errorf("%w: host mounts are not allowed", ErrPolicyViolation)
+	}
+
+	for _, opt := range hostCfg.SecurityOpt {
+		if unconfinedOption(opt) {
+			return fmt.Errorf("%w: security option %s is not allowed", ErrPolicyViolation, opt)
+		}
+	}
+
+	if len(p.AllowedRuntimes) > 0 && !slices.Contains(p.AllowedRuntimes, hostCfg.Runtime) {
+		runtime := hostCfg.Runtime
+		if runtime == "" {
+			runtime = "the daemon default"
+		}
+		return fmt.Errorf("%w: runtime %s is not allowed", ErrPolicyViolation, runtime)
+	}
+
+	if len(hostCfg.Resources.DeviceRequests) > 0 && !p.AllowGPUs {
+		return fmt.Errorf("%w: gpus are not allowed on this worker", ErrPolicyViolation)
+	}
+
+	for _, d := range hostCfg.Resources.Devices {
+		if !slices.Contains(p.AllowedDevices, d.PathOnHost) {
+			return fmt.Errorf("%w: device %s is not allowed", ErrPolicyViolation, d.PathOnHost)
+		}
+	}
+	return nil
+}
+
+// unconfinedOption reports whether a --security-opt value disables a confinement
+// mechanism, e.g. "seccomp=unconfined" or "label:disable".
+func unconfinedOption(opt string) bool {
+	key, value, ok := strings.Cut(opt, "=")
+	if !ok {
+		key, value, _ = strings.Cut(opt, ":")
+	}
+	switch key {
+	case "seccomp", "apparmor", "systempaths":
+		return value == "unconfined"
+	case "label":
+		return value == "disable"
+	}
+	return false
+}
+
+// createContainer is the single path to ContainerCreate. Every HostConfig the
+// API produces passes through the policy check here.
+func (c *Client) createContainer(ctx context.Context, opts moby.ContainerCreateOptions) (moby.ContainerCreateResult, error) {
+	if err := c.policy.Validate(opts.HostConfig); err != nil {
+		return moby.ContainerCreateResult{}, err
+	}
+	return c.cli.ContainerCreate(ctx, opts)
+}
@@ -0,0 +1,49 @@
+package docker
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// DefaultHostIP is the address sandbox ports are published on unless
+// configured otherwise: loopback, so only the proxy can reach them.
+var DefaultHostIP = netip.MustParseAddr("127.0.0.1")
+
+// SetPortBinding sets the host address sandbox ports are published on and an
+// optional host port range ("30000-30999") Docker assigns them from. An empty
+// range uses ephemeral ports.
+func (c *Client) SetPortBinding(hostIP netip.Addr, portRange string) error {
+	if portRange != "" {
+		if _, _, err := ParsePortRange(portRange); err != nil {
+			return err
+		}
+	}
+	c.hostIP = hostIP
+	c.hostPorts = portRange
+	return nil
+}
+
+// publishIP returns the host address sandbox ports are published on.
+func (c *Client) publishIP() netip.Addr {
+	if !c.hostIP.IsValid() {
+		return DefaultHostIP
+	}
+	return c.hostIP
+}
+
+// ParsePortRange parses a host port range such as "30000-30999". A single
+// port is a range of one.
+func ParsePortRange(s string) (first, last int, err error) {
+	lo, hi, isRange := strings.Cut(strings.TrimSpace(s), "-")
+	if !isRange {
+		hi = lo
+	}
+	first, err1 := strconv.Atoi(lo)
+	last, err2 := strconv.Atoi(hi)
+	if err1 != nil || err2 != nil || first < 1 || last > 65535 || first > last {
+		return 0, 0, fmt.Errorf("invalid port range %q, want FIRST-LAST between 1 and 65535", s)
+	}
+	return first, last, nil
+}
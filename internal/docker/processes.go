@@ -0,0 +1,391 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	moby "github.com/moby/moby/client"
+	"opensbx/internal/database"
+	"opensbx/internal/logging"
+	"opensbx/models"
+)
+
+// Restart policies for supervised processes.
+const (
+	RestartAlways    = "always"     // restart whenever the process exits (default)
+	RestartOnFailure = "on-failure" // restart only after a non-zero exit
+	RestartNever     = "never"      // run once
+)
+
+// Status of a supervised process.
+const (
+	ProcessRunning = "running" // the process is running
+	ProcessBackoff = "backoff" // the process exited and will be restarted
+	ProcessExited  = "exited"  // the process exited and its policy does not restart it
+	ProcessStopped = "stopped" // not supervised until the sandbox is next started
+)
+
+// Restart backoff for supervised processes: doubles from processBackoffMin up
+// to processBackoffMax, and resets once a run lasted processStableAfter.
+const (
+	processBackoffMin  = time.Second
+	processBackoffMax  = 30 * time.Second
+	processStableAfter = time.Minute
+)
+
+// supervisedProcess is the in-memory state of a process supervisor.
+type supervisedProcess struct {
+	cancel   context.CancelFunc // stops the supervisor and kills the current run
+	mu       sync.Mutex
+	status   string
+	cmdID    string // current or last run
+	restarts int
+}
+
+func (sp *supervisedProcess) set(status, cmdID string) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	sp.status = status
+	if cmdID != "" {
+		sp.cmdID = cmdID
+	}
+}
+
+// StartProcess persists a process definition and starts supervising it. The
+// process is started again whenever the sandbox starts or restarts.
+// Returns ErrProcessExists if the sandbox already has a process with that name.
+func (c *Client) StartProcess(ctx context.Context, id string, req models.StartProcessRequest) (models.ProcessDetail, error) {
+	info, err := c.cli.ContainerInspect(ctx, id, moby.ContainerInspectOptions{})
+	if err != nil {
+		return models.ProcessDetail{}, wrapNotFound(err)
+	}
+	if !info.Container.State.Running {
+		return models.ProcessDetail{}, notRunning(info.Container.State)
+	}
+	fullID := info.Container.ID
+
+	existing, err := c.repo.FindProcess(fullID, req.Name)
+	if err != nil {
+		return models.ProcessDetail{}, err
+	}
+	if existing != nil {
+		return models.ProcessDetail{}, ErrProcessExists
+	}
+
+	p := newProcess(fullID, req)
+	if err := c.repo.SaveProcess(p); err != nil {
+		return models.ProcessDetail{}, err
+	}
+	c.Touch(fullID)
+	c.supervise(p)
+
+	return c.processDetail(p), nil
+}
+
+// ListProcesses returns the supervised processes of a sandbox.
+func (c *Client) ListProcesses(ctx context.Context, id string) ([]models.ProcessDetail, error) {
+	fullID, err := c.sandboxID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	recs, err := c.repo.FindProcessesBySandbox(fullID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]models.ProcessDetail, 0, len(recs))
+	for _, rec := range recs {
+		out = append(out, c.processDetail(rec))
+	}
+	return out, nil
+}
+
+// GetProcess returns a supervised process by name.
+func (c *Client) GetProcess(ctx context.Context, id, name string) (models.ProcessDetail, error) {
+	fullID, err := c.sandboxID(ctx, id)
+	if err != nil {
+		return models.ProcessDetail{}, err
+	}
+	p, err := c.repo.FindProcess(fullID, name)
+	if err != nil {
+		return models.ProcessDetail{}, err
+	}
+	if p == nil {
+		return models.ProcessDetail{}, ErrProcessNotFound
+	}
+	return c.processDetail(*p), nil
+}
+
+// RemoveProcess stops supervising a process, terminates its current run and
+// forgets its definition.
+func (c *Client) RemoveProcess(ctx context.Context, id, name string) error {
+	fullID, err := c.sandboxID(ctx, id)
+	if err != nil {
+		return err
+	}
+	ok, err := c.repo.DeleteProcess(fullID, name)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrProcessNotFound
+	}
+	if v, loaded := c.processes.LoadAndDelete(processKey(fullID, name)); loaded {
+		v.(*supervisedProcess).cancel()
+	}
+	return nil
+}
+
+// newProcess builds the persisted definition of a process.
+func newProcess(sandboxID string, req models.StartProcessRequest) database.Process {
+	restart := req.Restart
+	if restart == "" {
+		restart = RestartAlways
+	}
+	args, _ := json.Marshal(req.Args)
+	return database.Process{
+		SandboxID: sandboxID,
+		Name:      req.Name,
+		Command:   req.Command,
+		Args:      string(args),
+		Cwd:       req.Cwd,
+		Env:       database.JSONMap(req.Env),
+		User:      req.User,
+		Restart:   restart,
+		CreatedAt: time.Now().UnixMilli(),
+	}
+}
+
+// startRequest is the inverse of newProcess.
+func startRequest(p database.Process) models.StartProcessRequest {
+	req := processRequest(p)
+	return models.StartProcessRequest{
+		Name:    p.Name,
+		Command: req.Command,
+		Args:    req.Args,
+		Cwd:     req.Cwd,
+		Env:     req.Env,
+		User:    req.User,
+		Restart: p.Restart,
+	}
+}
+
+// launchProcesses persists and starts the startup processes of a new sandbox.
+func (c *Client) launchProcesses(ctx context.Context, sandboxID string, procs []models.StartProcessRequest) {
+	for _, req := range procs {
+		p := newProcess(sandboxID, req)
+		if err := c.repo.SaveProcess(p); err != nil {
+			logging.FromContext(ctx).Error("database: failed to persist process", "sandbox_id", sandboxID, "process", p.Name, "err", err)
+			continue
+		}
+		c.supervise(p)
+	}
+}
+
+// startProcesses starts supervising every persisted process of a sandbox that
+// was just started or restarted. Supervisors from before the restart are replaced.
+func (c *Client) startProcesses(ctx context.Context, sandboxID string) {
+	procs, err := c.repo.FindProcessesBySandbox(sandboxID)
+	if err != nil {
+		logging.FromContext(ctx).Error("database: failed to load processes", "sandbox_id", sandboxID, "err", err)
+		return
+	}
+	for _, p := range procs {
+		c.supervise(p)
+	}
+}
+
+// stopSupervisors stops the process supervisors of a sandbox being removed.
+func (c *Client) stopSupervisors(sandboxID string) {
+	prefix := processKey(sandboxID, "")
+	c.processes.Range(func(key, value any) bool {
+		if k := key.(string); strings.HasPrefix(k, prefix) {
+			c.processes.Delete(k)
+			value.(*supervisedProcess).cancel()
+		}
+		return true
+	})
+}
+
+// supervise starts a supervisor for p, replacing (and stopping) any previous one.
+func (c *Client) supervise(p database.Process) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sp := &supervisedProcess{cancel: cancel, status: ProcessRunning}
+	if old, loaded := c.processes.Swap(processKey(p.SandboxID, p.Name), sp); loaded {
+		old.(*supervisedProcess).cancel()
+	}
+	go c.runSupervisor(ctx, p, sp)
+}
+
+// runSupervisor runs p until its restart policy says stop, the supervisor is
+// cancelled, or the sandbox is no longer running.
+func (c *Client) runSupervisor(ctx context.Context, p database.Process, sp *supervisedProcess) {
+	log := slog.With("sandbox_id", p.SandboxID, "process", p.Name)
+	req := processRequest(p)
+	backoff := processBackoffMin
+
+	for {
+		started := time.Now()
+		cmd, err := c.startCommand(ctx, p.SandboxID, req)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Warn("process start failed", "err", err)
+				sp.set(ProcessStopped, "")
+			}
+			return
+		}
+		sp.set(ProcessRunning, cmd.ID)
+
+		done, err := c.WaitCommand(ctx, p.SandboxID, cmd.ID)
+		if ctx.Err() != nil {
+			// Removed or replaced: terminate this run.
+			c.KillCommand(context.Background(), p.SandboxID, cmd.ID, 15)
+			return
+		}
+		exitCode := -1
+		if err == nil && done.ExitCode != nil {
+			exitCode = *done.ExitCode
+		}
+		if p.Restart == RestartNever || (p.Restart == RestartOnFailure && exitCode == 0) {
+			sp.set(ProcessExited, "")
+			return
+		}
+
+		if time.Since(started) >= processStableAfter {
+			backoff = processBackoffMin
+		}
+		log.Info("process exited, restarting", "exit_code", exitCode, "backoff", backoff)
+		sp.set(ProcessBackoff, "")
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, processBackoffMax)
+
+		// A stopped sandbox is picked up again by startProcesses on its next start.
+		info, err := c.cli.ContainerInspect(ctx, p.SandboxID, moby.ContainerInspectOptions{})
+		if err != nil || !info.Container.State.Running {
+			sp.set(ProcessStopped, "")
+			return
+		}
+		sp.mu.Lock()
+		sp.restarts++
+		sp.mu.Unlock()
+	}
+}
+
+// processDetail converts a process definition and its supervisor state.
+func (c *Client) processDetail(p database.Process) models.ProcessDetail {
+	var args []string
+	if p.Args != "" {
+		json.Unmarshal([]byte(p.Args), &args)
+	}
+	detail := models.ProcessDetail{
+		Name:      p.Name,
+		SandboxID: p.SandboxID,
+		Command:   p.Command,
+		Args:      args,
+		Cwd:       p.Cwd,
+		Env:       p.Env,
+		User:      p.User,
+		Restart:   p.Restart,
+		Status:    ProcessStopped,
+		CreatedAt: time.UnixMilli(p.CreatedAt),
+	}
+	if v, ok := c.processes.Load(processKey(p.SandboxID, p.Name)); ok {
+		sp := v.(*supervisedProcess)
+		sp.mu.Lock()
+		detail.Status = sp.status
+		detail.CommandID = sp.cmdID
+		detail.Restarts = sp.restarts
+		sp.mu.Unlock()
+	}
+	return detail
+}
+
+// processRequest builds the exec request for one run of p.
+func processRequest(p database.Process) models.ExecCommandRequest {
+	var args []string
+	if p.Args != "" {
+		json.Unmarshal([]byte(p.Args), &args)
+	}
+	return models.ExecCommandRequest{
+		Command: p.Command,
+		Args:    args,
+		Cwd:     p.Cwd,
+		Env:     p.Env,
+		User:    p.User,
+	}
+}
+
+func processKey(sandboxID, name string) string {
+	return sandboxID + "/" + name
+}
+
+// topArgs are the ps arguments Docker runs on the host to list a container's processes.
+var topArgs = []string{"-eo", "pid,user,pcpu,pmem,args"}
+
+// RunningProcesses returns a snapshot of the processes running in a sandbox.
+// Returns ErrNotRunning if the sandbox is not running.
+func (c *Client) RunningProcesses(ctx context.Context, id string) ([]models.ProcessInfo, error) {
+	info, err := c.cli.ContainerInspect(ctx, id, moby.ContainerInspectOptions{})
+	if err != nil {
+		return nil, wrapNotFound(err)
+	}
+	if !info.Container.State.Running {
+		return nil, ErrNotRunning
+	}
+	top, err := c.cli.ContainerTop(ctx, info.Container.ID, moby.ContainerTopOptions{Arguments: topArgs})
+	if err != nil {
+		return nil, wrapNotFound(err)
+	}
+	procs := parseTop(top.Titles, top.Processes)
+	for i := range procs {
+		if status, err := os.ReadFile(fmt.Sprintf("/proc/%d/status", procs[i].HostPID)); err == nil {
+			procs[i].PID, _ = nsPID(string(status))
+		}
+	}
+	return procs, nil
+}
+
+// parseTop converts a ps table returned by Docker into process entries,
+// locating columns by their titles. Rows without a numeric PID are skipped.
+func parseTop(titles []string, rows [][]string) []models.ProcessInfo {
+	col := make(map[string]int, len(titles))
+	for i, t := range titles {
+		col[strings.ToUpper(t)] = i
+	}
+	field := func(row []string, names ...string) string {
+		for _, n := range names {
+			if i, ok := col[n]; ok && i < len(row) {
+				return row[i]
+			}
+		}
+		return ""
+	}
+
+	out := make([]models.ProcessInfo, 0, len(rows))
+	for _, row := range rows {
+		pid, err := strconv.Atoi(field(row, "PID"))
+		if err != nil {
+			continue
+		}
+		cpu, _ := strconv.ParseFloat(field(row, "%CPU"), 64)
+		mem, _ := strconv.ParseFloat(field(row, "%MEM"), 64)
+		out = append(out, models.ProcessInfo{
+			HostPID: pid,
+			User:    field(row, "USER", "UID"),
+			CPU:     cpu,
+			Memory:  mem,
+			Command: field(row, "COMMAND", "CMD", "ARGS"),
+		})
+	}
+	return out
+}
@@ -0,0 +1,175 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+
+	"opensbx/internal/database"
+	"opensbx/models"
+
+	moby "github.com/moby/moby/client"
+)
+
+// quotaKey is the context key carrying the quota of the caller's API key.
+type quotaKey struct{}
+
+// WithQuota holds the sandboxes ctx creates and starts to q, counted against
+// the usage of the owner ctx is scoped to. A nil q leaves ctx unlimited
+// except for the global quota.
+func WithQuota(ctx context.Context, q *models.Quota) context.Context {
+	if q == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, quotaKey{}, q)
+}
+
+// QuotaFrom returns the quota ctx carries, or nil if none.
+func QuotaFrom(ctx context.Context) *models.Quota {
+	q, _ := ctx.Value(quotaKey{}).(*models.Quota)
+	return q
+}
+
+// SetQuota sets deployment-wide limits on running sandboxes. Keys may carry
+// their own, stricter quota for their owner through WithQuota; both are
+// enforced. Safe to call while sandboxes are created.
+func (c *Client) SetQuota(q models.Quota) {
+	c.quotaMu.Lock()
+	defer c.quotaMu.Unlock()
+	c.quota = q
+}
+
+// globalQuota returns the deployment-wide quota.
+func (c *Client) globalQuota() models.Quota {
+	c.quotaMu.RLock()
+	defer c.quotaMu.RUnlock()
+	return c.quota
+}
+
+// CheckQuota returns ErrQuotaExceeded if starting sandboxes with the requested
+// resources, one per entry, would exceed the global quota or the quota ctx
+// carries. Create, Start, Restart, Restore and Import check it themselves;
+// callers only need it to reject a batch before starting any of it.
+func (c *Client) CheckQuota(ctx context.Context, res ...*models.ResourceLimits) error {
+	return checkQuota(ctx, c.globalQuota(), res, c.Usage)
+}
+
+// quotaResources returns res as counted against quotas for a sandbox on the
+// OCI runtime: a micro-VM also holds the VM's memory overhead, as Usage
+// counts it once the sandbox runs.
+func (c *Client) quotaResources(res *models.ResourceLimits, runtime string) *models.ResourceLimits {
+	overhead := c.microVM.overhead(runtime)
+	if overhead == 0 {
+		return res
+	}
+	memory, cpus := EffectiveResources(res)
+	return &models.ResourceLimits{Memory: memory + overhead, CPUs: cpus}
+}
+
+// checkStartQuota is CheckQuota for starting the stopped sandbox containerID
+// with the resources it was created with.
+func (c *Client) checkStartQuota(ctx context.Context, containerID string) error {
+	if c.globalQuota() == (models.Quota{}) && QuotaFrom(ctx) == nil {
+		return nil
+	}
+	sb, err := c.repo.FindByID(containerID)
+	if err != nil {
+		return err
+	}
+	var res *models.ResourceLimits
+	var runtime string
+	if sb != nil {
+		res = &models.ResourceLimits{Memory: sb.Memory, CPUs: sb.CPUs}
+		runtime = sb.Runtime
+	}
+	return c.CheckQuota(ctx, c.quotaResources(res, runtime))
+}
+
+// checkQuota is CheckQuota with the global quota and usage lookup passed in.
+func checkQuota(ctx context.Context, global models.Quota, res []*models.ResourceLimits, usageOf func(context.Context, string) (models.QuotaUsage, error)) error {
+	if global != (models.Quota{}) {
+		usage, err := usageOf(ctx, "")
+		if err != nil {
+			return err
+		}
+		if msg := quotaViolations("global", global, usage, res); msg != "" {
+			return fmt.Errorf("%w: %s", ErrQuotaExceeded, msg)
+		}
+	}
+
+	if q := QuotaFrom(ctx); q != nil {
+		usage, err := usageOf(ctx, OwnerFrom(ctx))
+		if err != nil {
+			return err
+		}
+		if msg := quotaViolations("api key", *q, usage, res); msg != "" {
+			return fmt.Errorf("%w: %s", ErrQuotaExceeded, msg)
+		}
+	}
+	return nil
+}
+
+// quotaViolations is quotaViolation for several sandboxes started together.
+func quotaViolations(scope string, q models.Quota, usage models.QuotaUsage, res []*models.ResourceLimits) string {
+	for _, r := range res {
+		memory, cpus := EffectiveResources(r)
+		if msg := quotaViolation(scope, q, usage, memory, cpus); msg != "" {
+			return msg
+		}
+		usage.Sandboxes++
+		usage.Memory += memory
+		usage.CPUs += cpus
+	}
+	return ""
+}
+
+// quotaViolation returns why adding a sandbox with memory (MB) and cpus to
+// usage would exceed q, or "" if it fits.
+func quotaViolation(scope string, q models.Quota, usage models.QuotaUsage, memory int64, cpus float64) string {
+	switch {
+	case q.MaxSandboxes > 0 && usage.Sandboxes+1 > q.MaxSandboxes:
+		return fmt.Sprintf("%s quota of %d running sandboxes reached", scope, q.MaxSandboxes)
+	case q.MaxMemory > 0 && usage.Memory+memory > q.MaxMemory:
+		return fmt.Sprintf("%s memory quota: %d MB in use + %d MB requested > %d MB", scope, usage.Memory, memory, q.MaxMemory)
+	case q.MaxCPUs > 0 && usage.CPUs+cpus > q.MaxCPUs:
+		return fmt.Sprintf("%s CPU quota: %g in use + %g requested > %g", scope, usage.CPUs, cpus, q.MaxCPUs)
+	}
+	return ""
+}
+
+// Usage returns the running sandboxes of owner and their summed resource
+// limits. Sandboxes in micro-VMs also count the VM's memory overhead. An
+// empty owner counts every sandbox on this node. Idle code runners are not
+// counted: they belong to no caller until a run takes one.
+func (c *Client) Usage(ctx context.Context, owner string) (models.QuotaUsage, error) {
+	var records []database.Sandbox
+	var err error
+	if owner != "" {
+		records, err = c.repo.FindByOwner(owner)
+	} else {
+		records, err = c.repo.FindAll()
+	}
+	if err != nil {
+		return models.QuotaUsage{}, err
+	}
+
+	// Without All, Docker lists running (and paused) containers only.
+	result, err := c.cli.ContainerList(ctx, moby.ContainerListOptions{Filters: managedFilter()})
+	if err != nil {
+		return models.QuotaUsage{}, err
+	}
+	running := make(map[string]bool, len(result.Items))
+	for _, item := range result.Items {
+		running[item.ID] = true
+	}
+
+	var usage models.QuotaUsage
+	for _, sb := range records {
+		if !running[sb.ID] || c.idleCodeRunner(sb.ID) {
+			continue
+		}
+		usage.Sandboxes++
+		usage.Memory += sb.Memory + c.microVM.overhead(sb.Runtime)
+		usage.CPUs += sb.CPUs
+	}
+	return usage, nil
+}
@@ -0,0 +1,98 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"opensbx/models"
+)
+
+func TestQuotaViolation(t *testing.T) {
+	tests := []struct {
+		name   string
+		quota  models.Quota
+		usage  models.QuotaUsage
+		memory int64
+		cpus   float64
+		want   string
+	}{
+		{name: "unlimited", quota: models.Quota{}, usage: models.QuotaUsage{Sandboxes: 100}, memory: 1024, cpus: 1},
+		{name: "fits", quota: models.Quota{MaxSandboxes: 2, MaxMemory: 2048, MaxCPUs: 2}, usage: models.QuotaUsage{Sandboxes: 1, Memory: 1024, CPUs: 1}, memory: 1024, cpus: 1},
+		{name: "sandboxes", quota: models.Quota{MaxSandboxes: 1}, usage: models.QuotaUsage{Sandboxes: 1}, want: "1 running sandboxes"},
+		{name: "memory", quota: models.Quota{MaxMemory: 1024}, usage: models.QuotaUsage{Memory: 512}, memory: 1024, want: "memory quota"},
+		{name: "cpus", quota: models.Quota{MaxCPUs: 1.5}, usage: models.QuotaUsage{CPUs: 1}, cpus: 1, want: "CPU quota"},
+	}
+
+	for _, tt := range tests {
+		got := quotaViolation("global", tt.quota, tt.usage, tt.memory, tt.cpus)
+		if tt.want == "" && got != "" || !strings.Contains(got, tt.want) {
+			t.Fatalf("%s: quotaViolation() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCheckQuota(t *testing.T) {
+	var usageFor []string
+	usageOf := func(_ context.Context, owner string) (models.QuotaUsage, error) {
+		usageFor = append(usageFor, owner)
+		if owner == "" {
+			return models.QuotaUsage{Sandboxes: 2, Memory: 2048, CPUs: 2}, nil
+		}
+		return models.QuotaUsage{Sandboxes: 1, Memory: 1024, CPUs: 1}, nil
+	}
+
+	// Without quotas, usage is never looked up.
+	if err := checkQuota(context.Background(), models.Quota{}, nil, usageOf); err != nil || len(usageFor) != 0 {
+		t.Fatalf("checkQuota(unlimited) = %v after %d lookups, want nil after 0", err, len(usageFor))
+	}
+
+	err := checkQuota(context.Background(), models.Quota{MaxSandboxes: 2}, []*models.ResourceLimits{nil}, usageOf)
+	if !errors.Is(err, ErrQuotaExceeded) || !strings.Contains(err.Error(), "global quota of 2") {
+		t.Fatalf("checkQuota(global) = %v, want ErrQuotaExceeded", err)
+	}
+
+	usageFor = nil
+	ctx := WithQuota(WithOwner(context.Background(), "team-a"), &models.Quota{MaxMemory: 1536})
+	err = checkQuota(ctx, models.Quota{}, []*models.ResourceLimits{nil}, usageOf)
+	if !errors.Is(err, ErrQuotaExceeded) || !strings.Contains(err.Error(), "api key memory quota") {
+		t.Fatalf("checkQuota(key) = %v, want ErrQuotaExceeded", err)
+	}
+	if len(usageFor) != 1 || usageFor[0] != "team-a" {
+		t.Fatalf("usage looked up for %q, want [team-a]", usageFor)
+	}
+	if err := checkQuota(ctx, models.Quota{}, []*models.ResourceLimits{{Memory: 256}}, usageOf); err != nil {
+		t.Fatalf("checkQuota(key, fits) = %v, want nil", err)
+	}
+
+	// A batch counts every sandbox in it.
+	err = checkQuota(context.Background(), models.Quota{MaxSandboxes: 4}, []*models.ResourceLimits{nil, nil, nil}, usageOf)
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("checkQuota(batch) = %v, want ErrQuotaExceeded", err)
+	}
+}
+
+func TestQuotaResourcesMicroVM(t *testing.T) {
+	c := &Client{microVM: MicroVM{Runtimes: []string{"kata"}, MemoryOverhead: 160}}
+
+	if got := c.quotaResources(nil, "kata"); got == nil || got.Memory != defaultMemoryMB+160 || got.CPUs != defaultCPUs {
+		t.Fatalf("quotaResources(default, kata) = %+v, want default memory plus overhead", got)
+	}
+	if got := c.quotaResources(&models.ResourceLimits{Memory: 512, CPUs: 2}, "kata"); got.Memory != 672 || got.CPUs != 2 {
+		t.Fatalf("quotaResources(512 MB, kata) = %+v, want 672 MB", got)
+	}
+	res := &models.ResourceLimits{Memory: 512}
+	if got := c.quotaResources(res, "runc"); got != res {
+		t.Fatalf("quotaResources(runc) = %+v, want res unchanged", got)
+	}
+
+	// 1024 MB in use plus a 1024 MB micro-VM and its overhead do not fit in 2048 MB.
+	usageOf := func(context.Context, string) (models.QuotaUsage, error) {
+		return models.QuotaUsage{Sandboxes: 1, Memory: 1024, CPUs: 1}, nil
+	}
+	err := checkQuota(context.Background(), models.Quota{MaxMemory: 2048}, []*models.ResourceLimits{c.quotaResources(nil, "kata")}, usageOf)
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("checkQuota(micro-VM) = %v, want ErrQuotaExceeded", err)
+	}
+}
@@ -0,0 +1,341 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"opensbx/internal/database"
+	"opensbx/internal/logging"
+	"opensbx/models"
+
+	"github.com/moby/moby/api/types/container"
+	moby "github.com/moby/moby/client"
+)
+
+// Ready check states reported in SandboxDetail.Ready.
+const (
+	ReadyStarting = "starting" // the check has not passed yet
+	ReadyReady    = "ready"    // the check passed
+	ReadyTimeout  = "timeout"  // the check did not pass in time; the sandbox keeps running
+)
+
+// Ready check limits.
+const (
+	DefaultReadyTimeout = 60  // seconds a check is retried when none is given
+	MaxReadyTimeout     = 600 // longest ready_check.timeout allowed
+)
+
+// readyInterval is the pause between two failed probes, and readyProbeTimeout
+// bounds a single probe.
+const (
+	readyInterval     = 500 * time.Millisecond
+	readyProbeTimeout = 5 * time.Second
+)
+
+// readyWatch tracks the ready check running for a sandbox.
+type readyWatch struct {
+	cancel context.CancelFunc
+	done   chan struct{} // closed with state set once the check passes or gives up
+	state  string
+}
+
+// ValidateReadyCheck checks a ready check against the ports a sandbox exposes.
+// Returns an empty string when valid or a client-facing message otherwise.
+func ValidateReadyCheck(check *models.ReadyCheck, ports []string) string {
+	if check == nil {
+		return ""
+	}
+	if check.Timeout < 0 || check.Timeout > MaxReadyTimeout {
+		return fmt.Sprintf("ready_check.timeout must be between 0 and %d", MaxReadyTimeout)
+	}
+	if len(check.Command) > 0 {
+		if check.Port != "" || check.Path != "" {
+			return "ready_check.command cannot be combined with port or path"
+		}
+		return ""
+	}
+	if check.Path != "" && !strings.HasPrefix(check.Path, "/") {
+		return "ready_check.path must start with /"
+	}
+	exposed := normalizePorts(ports)
+	if len(exposed) == 0 {
+		return "ready_check needs a command when the sandbox exposes no ports"
+	}
+	if check.Port != "" && !slices.Contains(exposed, normalizePort(check.Port)) {
+		return fmt.Sprintf("ready_check.port %q is not exposed", check.Port)
+	}
+	return ""
+}
+
+// encodeReadyCheck formats a ready check for storage; nil is stored as "".
+func encodeReadyCheck(check *models.ReadyCheck) string {
+	if check == nil {
+		return ""
+	}
+	b, _ := json.Marshal(check)
+	return string(b)
+}
+
+// initialReady is the ready state stored for a new sandbox.
+func initialReady(check *models.ReadyCheck) string {
+	if check == nil {
+		return ""
+	}
+	return ReadyStarting
+}
+
+// watchReady starts the stored ready check of a sandbox that was just started
+// or restarted, replacing any check still running. Sandboxes without a check
+// are left alone.
+func (c *Client) watchReady(ctx context.Context, id string) {
+	sb, err := c.repo.FindByID(id)
+	if err != nil || sb == nil || sb.ReadyCheck == "" {
+		return
+	}
+	var check models.ReadyCheck
+	if err := json.Unmarshal([]byte(sb.ReadyCheck), &check); err != nil {
+		logging.FromContext(ctx).Error("invalid stored ready check", "sandbox_id", id, "err", err)
+		return
+	}
+	if err := c.repo.UpdateReady(id, ReadyStarting); err != nil {
+		logging.FromContext(ctx).Error("database: failed to update ready state", "sandbox_id", id, "err", err)
+	}
+
+	watchCtx, cancel := context.WithCancel(context.Background())
+	w := &readyWatch{cancel: cancel, done: make(chan struct{})}
+	if old, loaded := c.readyWatches.Swap(id, w); loaded {
+		old.(*readyWatch).cancel()
+	}
+	go c.runReadyCheck(watchCtx, sb, check, w)
+}
+
+// runReadyCheck probes a sandbox until its check passes, the timeout passes or
+// the watch is cancelled, and records the result.
+func (c *Client) runReadyCheck(ctx context.Context, sb *database.Sandbox, check models.ReadyCheck, w *readyWatch) {
+	defer w.cancel()
+	timeout := check.Timeout
+	if timeout <= 0 {
+		timeout = DefaultReadyTimeout
+	}
+	deadline := time.Now().Add(time.Duration(timeout) * time.Second)
+
+	state := ReadyTimeout
+	for {
+		if c.probeReady(ctx, sb, check) {
+			state = ReadyReady
+			break
+		}
+		if time.Now().Add(readyInterval).After(deadline) {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			// Stopped, removed or restarted: the next start records a new state.
+			c.readyWatches.CompareAndDelete(sb.ID, w)
+			return
+		case <-time.After(readyInterval):
+		}
+	}
+
+	if err := c.repo.UpdateReady(sb.ID, state); err != nil {
+		slog.Error("database: failed to update ready state", "sandbox_id", sb.ID, "err", err)
+	}
+	if state == ReadyTimeout {
+		slog.Warn("sandbox ready check timed out", "sandbox_id", sb.ID, "timeout", timeout)
+	}
+	w.state = state
+	close(w.done)
+	c.readyWatches.CompareAndDelete(sb.ID, w)
+	c.invalidateCache(sb.ID)
+}
+
+// probeReady runs one attempt of check against a sandbox.
+func (c *Client) probeReady(ctx context.Context, sb *database.Sandbox, check models.ReadyCheck) bool {
+	ctx, cancel := context.WithTimeout(ctx, readyProbeTimeout)
+	defer cancel()
+
+	if len(check.Command) > 0 {
+		result, err := c.execWithStdin(ctx, sb.ID, check.Command, nil)
+		return err == nil && result.exitCode == 0
+	}
+
+	addr, err := c.readyAddr(ctx, sb, check.Port)
+	if err != nil {
+		return false
+	}
+	if check.Path == "" {
+		conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return false
+		}
+		conn.Close()
+		return true
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+addr+check.Path, nil)
+	if err != nil {
+		return false
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode < http.StatusBadRequest
+}
+
+// readyAddr returns the address a port check dials: the container's own IP,
+// since Docker's userland proxy accepts connections on published host ports
+// even while nothing listens inside the sandbox.
+func (c *Client) readyAddr(ctx context.Context, sb *database.Sandbox, port string) (string, error) {
+	port = normalizePort(port)
+	if port == "" {
+		port = sb.Port
+	}
+	if port == "" {
+		for p := range sb.Ports {
+			port = p
+			break
+		}
+	}
+	num, _, _ := strings.Cut(port, "/")
+	if num == "" {
+		return "", errors.New("no port to probe")
+	}
+
+	info, err := c.cli.ContainerInspect(ctx, sb.ID, moby.ContainerInspectOptions{})
+	if err != nil {
+		return "", wrapNotFound(err)
+	}
+	return containerAddr(info.Container, num)
+}
+
+// containerAddr returns the address of port on the container's own IP.
+func containerAddr(ctr container.InspectResponse, port string) (string, error) {
+	if ctr.NetworkSettings == nil {
+		return "", ErrNotRunning
+	}
+	for _, ep := range ctr.NetworkSettings.Networks {
+		if ep != nil && ep.IPAddress.IsValid() {
+			return net.JoinHostPort(ep.IPAddress.String(), port), nil
+		}
+	}
+	return "", errors.New("sandbox has no IP address")
+}
+
+// cancelReady stops the ready check of a sandbox being stopped or removed. A
+// check that had not finished is cleared, so the proxy stops reporting the
+// sandbox as starting.
+func (c *Client) cancelReady(id string) {
+	if v, loaded := c.readyWatches.LoadAndDelete(id); loaded {
+		v.(*readyWatch).cancel()
+		if err := c.repo.UpdateReady(id, ""); err != nil {
+			slog.Error("database: failed to update ready state", "sandbox_id", id, "err", err)
+		}
+	}
+}
+
+// WaitReady blocks until the ready check started by the last start of a
+// sandbox passes or gives up, and returns its state: ReadyReady or
+// ReadyTimeout. Sandboxes without a check return "".
+func (c *Client) WaitReady(ctx context.Context, id string) (string, error) {
+	if v, ok := c.readyWatches.Load(id); ok {
+		w := v.(*readyWatch)
+		select {
+		case <-w.done:
+			return w.state, nil
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+	sb, err := c.repo.FindByID(id)
+	if err != nil {
+		return "", err
+	}
+	if sb == nil {
+		return "", ErrNotFound
+	}
+	return sb.Ready, nil
+}
+
+// ResumeReadyChecks restarts the ready checks that were still running when the
+// server last stopped, so their sandboxes do not stay "starting" forever.
+func (c *Client) ResumeReadyChecks(ctx context.Context) {
+	sandboxes, err := c.repo.FindByReady(ReadyStarting)
+	if err != nil {
+		logging.FromContext(ctx).Error("database: failed to load ready checks", "err", err)
+		return
+	}
+	var wg sync.WaitGroup
+	for _, sb := range sandboxes {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			info, err := c.cli.ContainerInspect(ctx, id, moby.ContainerInspectOptions{})
+			if err != nil || !info.Container.State.Running {
+				// Checked again on the next start.
+				c.repo.UpdateReady(id, "")
+				return
+			}
+			c.watchReady(ctx, id)
+		}(sb.ID)
+	}
+	wg.Wait()
+}
+
+// DefaultPortTimeout is how many seconds WaitPort waits when no timeout is given.
+const DefaultPortTimeout = 30
+
+// WaitPort blocks until the main port of a running sandbox accepts TCP
+// connections or timeout passes, and returns ReadyReady or ReadyTimeout.
+// Sandboxes that expose no port return "".
+func (c *Client) WaitPort(ctx context.Context, id string, timeout time.Duration) (string, error) {
+	sb, err := c.repo.FindByID(id)
+	if err != nil {
+		return "", err
+	}
+	if sb == nil {
+		return "", ErrNotFound
+	}
+	if sb.Port == "" && len(sb.Ports) == 0 {
+		return "", nil
+	}
+	addr, err := c.readyAddr(ctx, sb, "")
+	if err != nil {
+		return "", err
+	}
+	if !waitDial(ctx, addr, timeout) {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return ReadyTimeout, nil
+	}
+	return ReadyReady, nil
+}
+
+// waitDial retries a TCP connection to addr until one succeeds or timeout
+// passes. Reports whether one succeeded.
+func waitDial(ctx context.Context, addr string, timeout time.Duration) bool {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	for {
+		conn, err := (&net.Dialer{Timeout: readyProbeTimeout}).DialContext(ctx, "tcp", addr)
+		if err == nil {
+			conn.Close()
+			return true
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(readyInterval):
+		}
+	}
+}
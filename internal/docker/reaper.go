@@ -0,0 +1,83 @@
+package docker
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	moby "github.com/moby/moby/client"
+)
+
+// Expiration actions applied when a sandbox's timeout fires.
+const (
+	ExpirationStop   = "stop"   // stop the container, keep it for a later start (default)
+	ExpirationDelete = "delete" // stop, then remove container and records after the reap grace period
+)
+
+// reapInterval is how often the reaper looks for stopped sandboxes to remove.
+const reapInterval = time.Minute
+
+// StartReaper periodically removes sandboxes created with expiration_action
+// "delete" once they have been stopped for longer than grace, whether they were
+// stopped by their timeout or by hand. Runs until ctx is cancelled.
+func (c *Client) StartReaper(ctx context.Context, grace time.Duration) {
+	go func() {
+		ticker := time.NewTicker(reapInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if n, err := c.Reap(ctx, grace); err != nil {
+					slog.Error("reaper: sweep failed", "err", err)
+				} else if n > 0 {
+					slog.Info("reaper: removed expired sandboxes", "count", n)
+				}
+			}
+		}
+	}()
+}
+
+// Reap removes delete-on-expiry sandboxes stopped for longer than grace and
+// returns how many were removed. Records whose container is already gone are
+// cleaned up too.
+func (c *Client) Reap(ctx context.Context, grace time.Duration) (int, error) {
+	candidates, err := c.repo.FindByExpirationAction(ExpirationDelete)
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	now := time.Now()
+	for _, sb := range candidates {
+		info, err := c.cli.ContainerInspect(ctx, sb.ID, moby.ContainerInspectOptions{})
+		if err != nil && wrapNotFound(err) != ErrNotFound {
+			slog.Error("reaper: inspect sandbox failed", "sandbox_id", sb.ID, "err", err)
+			continue
+		}
+		if err == nil {
+			state := info.Container.State
+			if state.Running || !stoppedFor(state.FinishedAt, grace, now) {
+				continue
+			}
+		}
+		if err := c.Remove(ctx, sb.ID); err != nil {
+			slog.Error("reaper: remove sandbox failed", "sandbox_id", sb.ID, "err", err)
+			continue
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// stoppedFor reports whether a container that finished at finishedAt (RFC 3339,
+// as reported by Docker) has been stopped for at least grace. Unparseable or zero
+// timestamps count as stopped long ago.
+func stoppedFor(finishedAt string, grace time.Duration, now time.Time) bool {
+	t, err := time.Parse(time.RFC3339Nano, finishedAt)
+	if err != nil || t.IsZero() || t.Year() <= 1 {
+		return true
+	}
+	return now.Sub(t) >= grace
+}
@@ -0,0 +1,43 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+
+	moby "github.com/moby/moby/client"
+)
+
+// SetRuntime sets the OCI runtime (e.g. "runsc" for gVisor) used by sandboxes
+// that do not ask for one. Empty uses the Docker daemon's default runtime.
+func (c *Client) SetRuntime(name string) {
+	c.runtime = name
+}
+
+// CheckRuntime returns ErrRuntimeNotFound unless the Docker daemon has a
+// runtime with the given name configured.
+func (c *Client) CheckRuntime(ctx context.Context, name string) error {
+	res, err := c.cli.Info(ctx, moby.InfoOptions{})
+	if err != nil {
+		return fmt.Errorf("docker info: %w", err)
+	}
+	if _, ok := res.Info.Runtimes[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrRuntimeNotFound, name)
+	}
+	return nil
+}
+
+// sandboxRuntime resolves the runtime for a create request and checks that the
+// daemon has it. Empty means the daemon's default runtime.
+func (c *Client) sandboxRuntime(ctx context.Context, requested string) (string, error) {
+	name := requested
+	if name == "" {
+		name = c.runtime
+	}
+	if name == "" {
+		return "", nil
+	}
+	if err := c.CheckRuntime(ctx, name); err != nil {
+		return "", err
+	}
+	return name, nil
+}
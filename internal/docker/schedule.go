@@ -0,0 +1,273 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"opensbx/internal/cron"
+	"opensbx/internal/database"
+	"opensbx/models"
+)
+
+// Schedule run statuses.
+const (
+	RunRunning   = "running"
+	RunSucceeded = "succeeded" // the command exited 0
+	RunFailed    = "failed"    // the command exited non-zero
+	RunError     = "error"     // the sandbox or command could not run
+)
+
+const (
+	scheduleInterval    = 15 * time.Second // how often due schedules are looked up
+	scheduleRunsKept    = 20               // runs recorded per schedule
+	scheduleOutputLimit = 64 << 10         // bytes of stdout and stderr kept per run
+)
+
+// CreateSchedule stores a recurring job. Its first run is at the next match
+// of req.Cron after now.
+func (c *Client) CreateSchedule(ctx context.Context, req models.CreateScheduleRequest) (models.Schedule, error) {
+	sched, err := cron.Parse(req.Cron)
+	if err != nil {
+		return models.Schedule{}, err
+	}
+	if existing, err := c.repo.FindSchedule(req.Name); err != nil {
+		return models.Schedule{}, err
+	} else if existing != nil {
+		return models.Schedule{}, fmt.Errorf("%w: %s", ErrScheduleExists, req.Name)
+	}
+
+	spec, err := json.Marshal(req.Sandbox)
+	if err != nil {
+		return models.Schedule{}, err
+	}
+	command, err := json.Marshal(req.Command)
+	if err != nil {
+		return models.Schedule{}, err
+	}
+	now := time.Now().UTC()
+	s := database.Schedule{
+		Name:      req.Name,
+		OwnerID:   OwnerFrom(ctx),
+		Cron:      req.Cron,
+		Spec:      string(spec),
+		Command:   string(command),
+		NextRunAt: sched.Next(now).UnixMilli(),
+		CreatedAt: now.UnixMilli(),
+	}
+	if err := c.repo.SaveSchedule(s); err != nil {
+		return models.Schedule{}, err
+	}
+	return c.scheduleDetail(s)
+}
+
+// ListSchedules returns the caller's schedules ordered by name.
+func (c *Client) ListSchedules(ctx context.Context) ([]models.Schedule, error) {
+	schedules, err := c.repo.FindSchedules(OwnerFrom(ctx))
+	if err != nil {
+		return nil, err
+	}
+	out := make([]models.Schedule, 0, len(schedules))
+	for _, s := range schedules {
+		detail, err := c.scheduleDetail(s)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, detail)
+	}
+	return out, nil
+}
+
+// GetSchedule returns a schedule and its most recent run.
+func (c *Client) GetSchedule(ctx context.Context, name string) (models.Schedule, error) {
+	s, err := c.findSchedule(ctx, name)
+	if err != nil {
+		return models.Schedule{}, err
+	}
+	return c.scheduleDetail(*s)
+}
+
+// ListScheduleRuns returns the recorded runs of a schedule, newest first.
+func (c *Client) ListScheduleRuns(ctx context.Context, name string) ([]models.ScheduleRun, error) {
+	if _, err := c.findSchedule(ctx, name); err != nil {
+		return nil, err
+	}
+	runs, err := c.repo.FindScheduleRuns(name)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]models.ScheduleRun, 0, len(runs))
+	for _, run := range runs {
+		out = append(out, scheduleRunDetail(run))
+	}
+	return out, nil
+}
+
+// RemoveSchedule deletes a schedule and its recorded runs. A run in progress
+// finishes and cleans up its sandbox.
+func (c *Client) RemoveSchedule(ctx context.Context, name string) error {
+	if _, err := c.findSchedule(ctx, name); err != nil {
+		return err
+	}
+	return c.repo.DeleteSchedule(name)
+}
+
+// findSchedule returns a schedule visible to the caller, or ErrScheduleNotFound.
+func (c *Client) findSchedule(ctx context.Context, name string) (*database.Schedule, error) {
+	s, err := c.repo.FindSchedule(name)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		return nil, fmt.Errorf("%w: %s", ErrScheduleNotFound, name)
+	}
+	if owner := OwnerFrom(ctx); owner != "" && s.OwnerID != owner {
+		return nil, fmt.Errorf("%w: %s", ErrScheduleNotFound, name)
+	}
+	return s, nil
+}
+
+// scheduleDetail builds the API view of a schedule.
+func (c *Client) scheduleDetail(s database.Schedule) (models.Schedule, error) {
+	detail := models.Schedule{
+		Name:      s.Name,
+		Cron:      s.Cron,
+		NextRun:   time.UnixMilli(s.NextRunAt).UTC(),
+		CreatedAt: time.UnixMilli(s.CreatedAt).UTC(),
+	}
+	json.Unmarshal([]byte(s.Spec), &detail.Sandbox)
+	json.Unmarshal([]byte(s.Command), &detail.Command)
+
+	runs, err := c.repo.FindScheduleRuns(s.Name)
+	if err != nil {
+		return models.Schedule{}, err
+	}
+	if len(runs) > 0 {
+		last := scheduleRunDetail(runs[0])
+		detail.LastRun = &last
+	}
+	return detail, nil
+}
+
+// scheduleRunDetail converts a database.ScheduleRun to its API view.
+func scheduleRunDetail(run database.ScheduleRun) models.ScheduleRun {
+	detail := models.ScheduleRun{
+		ID:        run.ID,
+		SandboxID: run.SandboxID,
+		Status:    run.Status,
+		ExitCode:  run.ExitCode,
+		Error:     run.Error,
+		Stdout:    run.Stdout,
+		Stderr:    run.Stderr,
+		StartedAt: time.UnixMilli(run.StartedAt).UTC(),
+	}
+	if run.FinishedAt > 0 {
+		finished := time.UnixMilli(run.FinishedAt).UTC()
+		detail.FinishedAt = &finished
+	}
+	return detail
+}
+
+// StartScheduler runs due schedules until ctx is cancelled. Runs recorded as
+// running by a previous server process are marked as errors first.
+func (c *Client) StartScheduler(ctx context.Context) {
+	if n, err := c.repo.FailRunningScheduleRuns("interrupted by a server restart", time.Now().UnixMilli()); err != nil {
+		slog.Error("scheduler: failed to close interrupted runs", "err", err)
+	} else if n > 0 {
+		slog.Warn("scheduler: closed runs interrupted by a restart", "count", n)
+	}
+
+	go func() {
+		ticker := time.NewTicker(scheduleInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := c.runDueSchedules(ctx, time.Now().UTC()); err != nil {
+					slog.Error("scheduler: sweep failed", "err", err)
+				}
+			}
+		}
+	}()
+}
+
+// runDueSchedules starts a run of every schedule due at now and moves each to
+// its next match. Runs missed while the server was down are not made up for,
+// and a schedule whose previous run is still going skips this one.
+func (c *Client) runDueSchedules(ctx context.Context, now time.Time) error {
+	due, err := c.repo.FindDueSchedules(now.UnixMilli())
+	if err != nil {
+		return err
+	}
+	for _, s := range due {
+		sched, err := cron.Parse(s.Cron)
+		if err != nil {
+			slog.Error("scheduler: invalid cron expression", "schedule", s.Name, "cron", s.Cron, "err", err)
+			continue
+		}
+		if err := c.repo.UpdateScheduleNextRun(s.Name, sched.Next(now).UnixMilli()); err != nil {
+			return err
+		}
+		if _, running := c.scheduleRuns.LoadOrStore(s.Name, struct{}{}); running {
+			slog.Warn("scheduler: previous run still in progress, skipping", "schedule", s.Name)
+			continue
+		}
+		go func() {
+			defer c.scheduleRuns.Delete(s.Name)
+			c.runSchedule(ctx, s)
+		}()
+	}
+	return nil
+}
+
+// runSchedule performs one run: it runs the schedule's command as a job and
+// records its exit code and output.
+func (c *Client) runSchedule(ctx context.Context, s database.Schedule) {
+	run := &database.ScheduleRun{Schedule: s.Name, Status: RunRunning, StartedAt: time.Now().UnixMilli()}
+	if err := c.repo.SaveScheduleRun(run, scheduleRunsKept); err != nil {
+		slog.Error("scheduler: failed to record run", "schedule", s.Name, "err", err)
+		return
+	}
+	log := slog.With("schedule", s.Name, "run", run.ID)
+	log.Info("scheduler: run started")
+
+	var job models.JobRequest
+	err := json.Unmarshal([]byte(s.Spec), &job.Sandbox)
+	if err == nil {
+		err = json.Unmarshal([]byte(s.Command), &job.Command)
+	}
+	var result models.JobResult
+	if err == nil {
+		result, err = c.runJob(WithOwner(ctx, s.OwnerID), job, func(id string) {
+			run.SandboxID = id
+			c.repo.UpdateScheduleRun(*run)
+		})
+	}
+
+	run.FinishedAt = time.Now().UnixMilli()
+	run.Stdout, run.Stderr = tail(result.Stdout, scheduleOutputLimit), tail(result.Stderr, scheduleOutputLimit)
+	switch {
+	case err != nil:
+		run.Status, run.Error = RunError, err.Error()
+	case result.ExitCode == 0:
+		run.Status, run.ExitCode = RunSucceeded, &result.ExitCode
+	default:
+		run.Status, run.ExitCode = RunFailed, &result.ExitCode
+	}
+	if err := c.repo.UpdateScheduleRun(*run); err != nil {
+		log.Error("scheduler: failed to record run result", "err", err)
+	}
+	log.Info("scheduler: run finished", "status", run.Status, "sandbox_id", run.SandboxID)
+}
+
+// tail returns the last limit bytes of s.
+func tail(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	return s[len(s)-limit:]
+}
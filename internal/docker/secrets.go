@@ -0,0 +1,38 @@
+package docker
+
+import (
+	"context"
+	"slices"
+)
+
+// SecretResolver returns the values of an owner's secrets by name.
+// Implemented by *secrets.Store.
+type SecretResolver interface {
+	Resolve(owner string, names []string) (map[string]string, error)
+}
+
+// SetSecretResolver enables env_from_secrets on create. Secrets are looked up
+// for the owner of the request's context.
+func (c *Client) SetSecretResolver(r SecretResolver) {
+	c.secrets = r
+}
+
+// createEnv returns a create request's container environment: the secrets it
+// references, then its env, so explicit variables win.
+func (c *Client) createEnv(ctx context.Context, env []string, fromSecrets []string) ([]string, error) {
+	if len(fromSecrets) == 0 {
+		return env, nil
+	}
+	if c.secrets == nil {
+		return nil, ErrSecretsDisabled
+	}
+	values, err := c.secrets.Resolve(OwnerFrom(ctx), fromSecrets)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, 0, len(values)+len(env))
+	for _, name := range slices.Compact(slices.Sorted(slices.Values(fromSecrets))) {
+		out = append(out, name+"="+values[name])
+	}
+	return append(out, env...), nil
+}
@@ -0,0 +1,177 @@
+package docker
+
+// Session drivers run inside the sandbox and speak a line protocol with the
+// server: each stdin line is a JSON object {"code": "..."} holding one cell,
+// and every stdout line a JSON event. The first event is {"type": "ready",
+// "pid": N}; every cell ends with an "end" event. The program's own output is
+// turned into stdout and stderr events, so the protocol stream stays clean;
+// output written to file descriptors directly (e.g. by subprocesses) ends up
+// on the exec's stderr. SIGINT interrupts the running cell.
+
+// pythonSessionDriver runs cells in one namespace, like a Jupyter kernel: a
+// trailing expression's value is reported as a result with the MIME
+// representations of IPython's _repr_*_ protocol, and display() emits them.
+const pythonSessionDriver = `
+import ast, base64, json, os, signal, sys, traceback
+
+_proto = os.fdopen(os.dup(1), "w")
+_cells = os.fdopen(os.dup(0), "r")
+os.dup2(2, 1)
+_null = os.open(os.devnull, os.O_RDONLY)
+os.dup2(_null, 0)
+sys.stdin = open(os.devnull)
+
+def _emit(**ev):
+    _proto.write(json.dumps(ev) + "\n")
+    _proto.flush()
+
+class _Stream:
+    def __init__(self, name):
+        self.name = name
+    def write(self, s):
+        if s:
+            _emit(type=self.name, text=s)
+        return len(s)
+    def flush(self):
+        pass
+    def isatty(self):
+        return False
+
+_MIME = (
+    ("text/html", "_repr_html_"),
+    ("text/markdown", "_repr_markdown_"),
+    ("text/latex", "_repr_latex_"),
+    ("image/svg+xml", "_repr_svg_"),
+    ("image/png", "_repr_png_"),
+    ("image/jpeg", "_repr_jpeg_"),
+    ("application/json", "_repr_json_"),
+)
+
+def _bundle(obj):
+    data = {"text/plain": repr(obj)}
+    for mime, name in _MIME:
+        fn = getattr(obj, name, None)
+        if not callable(fn):
+            continue
+        try:
+            v = fn()
+        except Exception:
+            continue
+        if isinstance(v, tuple):
+            v = v[0]
+        if v is None:
+            continue
+        if isinstance(v, bytes):
+            v = base64.b64encode(v).decode()
+        elif not isinstance(v, str):
+            v = json.dumps(v)
+        data[mime] = v
+    return data
+
+def display(*objs):
+    for obj in objs:
+        _emit(type="display", data=_bundle(obj))
+
+_running = False
+
+def _interrupt(signum, frame):
+    if _running:
+        raise KeyboardInterrupt
+
+signal.signal(signal.SIGINT, _interrupt)
+_ns = {"__name__": "__main__", "__builtins__": __builtins__, "display": display}
+sys.stdout, sys.stderr = _Stream("stdout"), _Stream("stderr")
+_emit(type="ready", pid=os.getpid())
+
+for _line in _cells:
+    try:
+        _code = json.loads(_line)["code"]
+    except Exception:
+        continue
+    _running = True
+    try:
+        _tree = ast.parse(_code, "<cell>", "exec")
+        _last = None
+        if _tree.body and isinstance(_tree.body[-1], ast.Expr):
+            _last = ast.Expression(_tree.body.pop().value)
+        exec(compile(_tree, "<cell>", "exec"), _ns)
+        if _last is not None:
+            _value = eval(compile(_last, "<cell>", "eval"), _ns)
+            if _value is not None:
+                _ns["_"] = _value
+                _emit(type="result", data=_bundle(_value))
+    except BaseException as e:
+        _running = False
+        frames = [f for f in traceback.extract_tb(e.__traceback__) if f.filename != "<string>"]
+        text = "".join(traceback.format_exception_only(type(e), e))
+        if frames:
+            text = "Traceback (most recent call last):\n" + "".join(traceback.format_list(frames)) + text
+        _emit(type="error", name=type(e).__name__, value=str(e), traceback=text)
+    _running = False
+    sys.stdout.flush()
+    _emit(type="end")
+`
+
+// nodeSessionDriver runs cells as scripts in the driver's global context, so
+// top-level declarations persist. A promise result is awaited.
+const nodeSessionDriver = `
+const fs = require("fs");
+const readline = require("readline");
+const util = require("util");
+const vm = require("vm");
+
+const emit = (ev) => fs.writeSync(1, JSON.stringify(ev) + "\n");
+const capture = (type) => (chunk, encoding, cb) => {
+  emit({ type, text: typeof chunk === "string" ? chunk : Buffer.from(chunk).toString() });
+  if (typeof encoding === "function") encoding();
+  else if (typeof cb === "function") cb();
+  return true;
+};
+process.stdout.write = capture("stdout");
+process.stderr.write = capture("stderr");
+
+const bundle = (v) => {
+  const data = { "text/plain": util.inspect(v) };
+  if (v && typeof v._repr_html_ === "function") data["text/html"] = String(v._repr_html_());
+  return data;
+};
+globalThis.require = require;
+globalThis.display = (...objs) => objs.forEach((o) => emit({ type: "display", data: bundle(o) }));
+
+process.on("SIGINT", () => {});
+process.on("uncaughtException", (e) => emit({ type: "stderr", text: String((e && e.stack) || e) + "\n" }));
+process.on("unhandledRejection", (e) => emit({ type: "stderr", text: String((e && e.stack) || e) + "\n" }));
+
+const queue = [];
+let busy = false;
+const run = async () => {
+  busy = true;
+  while (queue.length) {
+    let code;
+    try {
+      code = JSON.parse(queue.shift()).code;
+    } catch {
+      continue;
+    }
+    try {
+      let value = vm.runInThisContext(code, { filename: "<cell>", breakOnSigint: true });
+      if (value && typeof value.then === "function") value = await value;
+      if (value !== undefined) emit({ type: "result", data: bundle(value) });
+    } catch (e) {
+      emit({
+        type: "error",
+        name: (e && e.name) || "Error",
+        value: (e && e.message) || String(e),
+        traceback: (e && e.stack) || String(e),
+      });
+    }
+    emit({ type: "end" });
+  }
+  busy = false;
+};
+readline.createInterface({ input: process.stdin }).on("line", (line) => {
+  queue.push(line);
+  if (!busy) run();
+});
+emit({ type: "ready", pid: process.pid });
+`
@@ -0,0 +1,388 @@
+package docker
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"opensbx/models"
+
+	"github.com/moby/moby/api/pkg/stdcopy"
+	moby "github.com/moby/moby/client"
+)
+
+// Session event types.
+const (
+	SessionStdout  = "stdout"
+	SessionStderr  = "stderr"
+	SessionDisplay = "display"
+	SessionResult  = "result"
+	SessionError   = "error"
+	SessionEnd     = "end" // last event of every execution
+)
+
+// sessionInterpreters are the commands that start each session language's driver.
+var sessionInterpreters = map[string][]string{
+	"python": {"python3", "-u", "-c", pythonSessionDriver},
+	"node":   {"node", "-e", nodeSessionDriver},
+}
+
+const (
+	sessionStartTimeout   = 30 * time.Second // how long an interpreter may take to become ready
+	sessionInterruptGrace = 10 * time.Second // how long an interrupted cell may take to end before the session is killed
+	sessionStartOutput    = 4 << 10          // bytes of startup output kept for error messages
+	sessionMaxEvent       = 64 << 20         // longest protocol line, e.g. a display event with an image
+)
+
+// SessionLanguages returns the languages sessions can be started with, sorted.
+func SessionLanguages() []string {
+	return slices.Sorted(maps.Keys(sessionInterpreters))
+}
+
+// codeSession is a running interpreter driver. Sessions live in memory only:
+// they end with their sandbox, and with the server.
+type codeSession struct {
+	id        string
+	sandboxID string
+	language  string
+	conn      moby.HijackedResponse
+	createdAt time.Time
+	ready     chan int      // receives the interpreter's PID once it is ready
+	done      chan struct{} // closed when the interpreter exits
+
+	mu      sync.Mutex
+	pid     int
+	count   int          // executions so far
+	cell    *sessionCell // execution in progress, nil when idle
+	startup []byte       // output before the interpreter was ready
+}
+
+// sessionCell receives the events of one execution.
+type sessionCell struct {
+	events chan models.SessionEvent
+	stop   chan struct{} // closed when the execution stops listening
+}
+
+// driverEvent is a protocol line of a session driver.
+type driverEvent struct {
+	models.SessionEvent
+	PID int `json:"pid"` // "ready" only
+}
+
+// generateSessionID creates a session ID: ses_ + 24 hex chars.
+func generateSessionID() string {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return "ses_" + hex.EncodeToString(b)
+}
+
+// CreateSession starts a persistent interpreter in a running sandbox and waits
+// until it is ready to execute cells. Returns ErrInterpreterUnavailable if it
+// exits first, e.g. because the image has no python3 or node.
+func (c *Client) CreateSession(ctx context.Context, id string, req models.CreateSessionRequest) (models.Session, error) {
+	cmd, ok := sessionInterpreters[req.Language]
+	if !ok {
+		return models.Session{}, fmt.Errorf("unsupported session language %q", req.Language)
+	}
+	info, err := c.cli.ContainerInspect(ctx, id, moby.ContainerInspectOptions{})
+	if err != nil {
+		return models.Session{}, wrapNotFound(err)
+	}
+	if !info.Container.State.Running {
+		return models.Session{}, notRunning(info.Container.State)
+	}
+	fullID := info.Container.ID
+	c.Touch(fullID)
+
+	env := maps.Clone(c.sandboxEnv(ctx, fullID))
+	if env == nil {
+		env = map[string]string{}
+	}
+	maps.Copy(env, req.Env)
+	execCfg, err := c.cli.ExecCreate(ctx, fullID, moby.ExecCreateOptions{
+		AttachStdin:  true,
+		AttachStdout: true,
+		AttachStderr: true,
+		Cmd:          cmd,
+		Env:          envSlice(env),
+		WorkingDir:   req.Cwd,
+	})
+	if err != nil {
+		return models.Session{}, wrapNotFound(err)
+	}
+	// The session outlives the request that created it.
+	attached, err := c.cli.ExecAttach(context.Background(), execCfg.ID, moby.ExecAttachOptions{})
+	if err != nil {
+		return models.Session{}, err
+	}
+
+	s := &codeSession{
+		id:        generateSessionID(),
+		sandboxID: fullID,
+		language:  req.Language,
+		conn:      attached.HijackedResponse,
+		createdAt: time.Now().UTC(),
+		ready:     make(chan int, 1),
+		done:      make(chan struct{}),
+	}
+	go c.readSession(s)
+
+	timer := time.NewTimer(sessionStartTimeout)
+	defer timer.Stop()
+	select {
+	case pid := <-s.ready:
+		s.mu.Lock()
+		s.pid, s.startup = pid, nil
+		s.mu.Unlock()
+	case <-s.done:
+		s.mu.Lock()
+		output := strings.TrimSpace(string(s.startup))
+		s.mu.Unlock()
+		if output == "" {
+			output = "it exited without output"
+		}
+		return models.Session{}, fmt.Errorf("%w: %s: %s", ErrInterpreterUnavailable, cmd[0], output)
+	case <-timer.C:
+		attached.Close()
+		return models.Session{}, fmt.Errorf("%w: %s did not start within %s", ErrInterpreterUnavailable, cmd[0], sessionStartTimeout)
+	case <-ctx.Done():
+		attached.Close()
+		return models.Session{}, ctx.Err()
+	}
+
+	c.sessions.Store(s.id, s)
+	select {
+	case <-s.done: // exited right after becoming ready
+		c.sessions.Delete(s.id)
+	default:
+	}
+	slog.Info("session started", "sandbox_id", fullID, "session_id", s.id, "language", req.Language)
+	return s.detail(), nil
+}
+
+// readSession forwards a session's output until the interpreter exits: the
+// driver's protocol lines from stdout, and stray stderr output as stderr events.
+func (c *Client) readSession(s *codeSession) {
+	protoR, protoW := io.Pipe()
+	rawR, rawW := io.Pipe()
+	go func() {
+		_, err := stdcopy.StdCopy(protoW, rawW, s.conn.Reader)
+		protoW.CloseWithError(err)
+		rawW.CloseWithError(err)
+	}()
+	go func() {
+		buf := make([]byte, 32<<10)
+		for {
+			n, err := rawR.Read(buf)
+			if n > 0 {
+				s.deliver(models.SessionEvent{Type: SessionStderr, Text: string(buf[:n])})
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	scanner := bufio.NewScanner(protoR)
+	scanner.Buffer(make([]byte, 64<<10), sessionMaxEvent)
+	for scanner.Scan() {
+		var ev driverEvent
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			s.deliver(models.SessionEvent{Type: SessionStdout, Text: scanner.Text() + "\n"})
+			continue
+		}
+		if ev.Type == "ready" {
+			s.ready <- ev.PID
+			continue
+		}
+		s.deliver(ev.SessionEvent)
+	}
+	protoR.Close()
+	rawR.Close()
+	s.conn.Close()
+
+	c.sessions.Delete(s.id)
+	close(s.done)
+	slog.Info("session ended", "sandbox_id", s.sandboxID, "session_id", s.id)
+}
+
+// deliver hands an event to the execution in progress. Events while no cell
+// is executing are kept as startup output before the session is ready, and
+// dropped afterwards.
+func (s *codeSession) deliver(ev models.SessionEvent) {
+	s.mu.Lock()
+	cell := s.cell
+	if cell == nil {
+		if s.pid == 0 && len(s.startup) < sessionStartOutput {
+			s.startup = append(s.startup, ev.Text...)
+		}
+		s.mu.Unlock()
+		return
+	}
+	s.mu.Unlock()
+	select {
+	case cell.events <- ev:
+	case <-cell.stop:
+	}
+}
+
+// detail builds the API view of a session.
+func (s *codeSession) detail() models.Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return models.Session{
+		ID:             s.id,
+		SandboxID:      s.sandboxID,
+		Language:       s.language,
+		ExecutionCount: s.count,
+		Busy:           s.cell != nil,
+		CreatedAt:      s.createdAt,
+	}
+}
+
+// ListSessions returns the sessions running in a sandbox, oldest first.
+func (c *Client) ListSessions(ctx context.Context, id string) ([]models.Session, error) {
+	fullID, err := c.sandboxID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	out := []models.Session{}
+	c.sessions.Range(func(_, value any) bool {
+		if s := value.(*codeSession); s.sandboxID == fullID {
+			out = append(out, s.detail())
+		}
+		return true
+	})
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
+
+// findSession returns a session of the given sandbox, or ErrSessionNotFound.
+func (c *Client) findSession(ctx context.Context, id, sessionID string) (*codeSession, error) {
+	fullID, err := c.sandboxID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	v, ok := c.sessions.Load(sessionID)
+	if !ok || v.(*codeSession).sandboxID != fullID {
+		return nil, ErrSessionNotFound
+	}
+	return v.(*codeSession), nil
+}
+
+// RemoveSession stops a session's interpreter, discarding its state.
+func (c *Client) RemoveSession(ctx context.Context, id, sessionID string) error {
+	s, err := c.findSession(ctx, id, sessionID)
+	if err != nil {
+		return err
+	}
+	c.killSession(ctx, s)
+	return nil
+}
+
+// ExecuteSession runs a cell in a session and passes its events to emit as
+// they arrive, ending with an "end" event. With a timeout, or when ctx is
+// cancelled, the cell is interrupted; an interpreter that does not end the
+// cell within a grace period is killed, which ends the session. Returns
+// ErrSessionBusy if another cell is executing.
+func (c *Client) ExecuteSession(ctx context.Context, id, sessionID string, req models.ExecuteRequest, emit func(models.SessionEvent)) error {
+	s, err := c.findSession(ctx, id, sessionID)
+	if err != nil {
+		return err
+	}
+	cell := &sessionCell{events: make(chan models.SessionEvent, 64), stop: make(chan struct{})}
+	s.mu.Lock()
+	if s.cell != nil {
+		s.mu.Unlock()
+		return ErrSessionBusy
+	}
+	s.cell = cell
+	s.count++
+	count := s.count
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.cell = nil
+		s.mu.Unlock()
+		close(cell.stop)
+	}()
+
+	c.Touch(s.sandboxID)
+	line, err := json.Marshal(map[string]string{"code": req.Code})
+	if err != nil {
+		return err
+	}
+	if _, err := s.conn.Conn.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("send cell: %w", err)
+	}
+
+	var timeout, grace <-chan time.Time
+	if req.Timeout > 0 {
+		t := time.NewTimer(time.Duration(req.Timeout) * time.Second)
+		defer t.Stop()
+		timeout = t.C
+	}
+	cancelled := ctx.Done()
+	interrupt := func() {
+		cancelled, timeout = nil, nil
+		c.signalSession(context.WithoutCancel(ctx), s, "INT")
+		grace = time.After(sessionInterruptGrace)
+	}
+	for {
+		select {
+		case ev := <-cell.events:
+			if ev.Type == SessionEnd {
+				ev.ExecutionCount = count
+				emit(ev)
+				return nil
+			}
+			emit(ev)
+		case <-s.done:
+			emit(models.SessionEvent{Type: SessionError, Name: "SessionEnded", Value: "the interpreter exited, the session is gone"})
+			emit(models.SessionEvent{Type: SessionEnd, ExecutionCount: count})
+			return nil
+		case <-timeout:
+			emit(models.SessionEvent{Type: SessionStderr, Text: fmt.Sprintf("cell interrupted after %ds\n", req.Timeout)})
+			interrupt()
+		case <-cancelled:
+			interrupt()
+		case <-grace:
+			slog.Warn("session did not stop after an interrupt, killing it", "sandbox_id", s.sandboxID, "session_id", s.id)
+			c.killSession(context.WithoutCancel(ctx), s)
+			grace = nil
+		}
+	}
+}
+
+// signalSession sends a signal to a session's interpreter.
+func (c *Client) signalSession(ctx context.Context, s *codeSession, signal string) {
+	s.mu.Lock()
+	pid := s.pid
+	s.mu.Unlock()
+	// Ignore errors: the interpreter may have exited already.
+	c.execWithStdin(ctx, s.sandboxID, []string{"kill", "-" + signal, strconv.Itoa(pid)}, nil)
+}
+
+// killSession kills a session's interpreter and waits briefly for it to exit.
+func (c *Client) killSession(ctx context.Context, s *codeSession) {
+	c.signalSession(ctx, s, "KILL")
+	s.conn.Close()
+	select {
+	case <-s.done:
+	case <-time.After(5 * time.Second):
+	}
+}
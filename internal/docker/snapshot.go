@@ -0,0 +1,183 @@
+package docker
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"opensbx/models"
+
+	"github.com/containerd/errdefs"
+	"github.com/distribution/reference"
+	"github.com/moby/moby/api/types/registry"
+	moby "github.com/moby/moby/client"
+)
+
+// LabelSnapshot marks images committed by Snapshot with the name of the
+// sandbox they were taken from. Image garbage collection never removes them.
+const LabelSnapshot = reservedLabelRoot + "snapshot"
+
+// LabelSnapshotOwner records the owner whose sandbox a snapshot was taken
+// from, empty for unrestricted callers. Only that owner may replace the tag.
+const LabelSnapshotOwner = reservedLabelRoot + "snapshot-owner"
+
+// snapshotRoot is the repository namespace snapshots are committed under.
+const snapshotRoot = "opensbx-snapshot/"
+
+// snapshotRepo returns the repository prefix of owner's snapshots, e.g.
+// "opensbx-snapshot/team-a/". Owners are reduced to the characters an image
+// path component allows; LabelSnapshotOwner keeps the exact owner.
+func snapshotRepo(owner string) string {
+	if owner == "" {
+		return snapshotRoot
+	}
+	component := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + ('a' - 'A')
+		}
+		return '-'
+	}, owner)
+	component = strings.Trim(component, "-_.")
+	if component == "" {
+		component = "owner"
+	}
+	return snapshotRoot + component + "/"
+}
+
+// InSnapshotNamespace reports whether ref names an image in the snapshot
+// namespace of owner, which callers may commit to without the images scope.
+func InSnapshotNamespace(ref, owner string) bool {
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(reference.FamiliarName(named), snapshotRepo(owner))
+}
+
+// canReplaceImage reports whether a snapshot by owner may take over the tag of
+// an existing image with labels: only a snapshot the same owner took.
+func canReplaceImage(labels map[string]string, owner string) bool {
+	return labels[LabelSnapshot] != "" && labels[LabelSnapshotOwner] == owner
+}
+
+// commitChanges blanks the server's labels in committed images, which would
+// otherwise inherit them from the sandbox container.
+func commitChanges() []string {
+	return []string{fmt.Sprintf(`LABEL %s="" %s="" %s="" %s="" %s=""`,
+		LabelManaged, LabelName, LabelOwner, LabelTimeout, LabelExpirationAction)}
+}
+
+// snapshotChanges is commitChanges for snapshot images, which also get
+// LabelSnapshot and LabelSnapshotOwner.
+func snapshotChanges(name, owner string) []string {
+	return append(commitChanges(), fmt.Sprintf("LABEL %s=%q %s=%q", LabelSnapshot, name, LabelSnapshotOwner, owner))
+}
+
+// Snapshot commits a sandbox's filesystem to a new image, optionally pushing it.
+// The sandbox is paused during the commit so the snapshot is consistent.
+// New sandboxes can be created from the returned image like any other.
+// Without a ref the image goes to the caller's snapshot namespace. An existing
+// tag is only replaced when it is a snapshot by the same owner, so callers
+// cannot overwrite base images other sandboxes are created from.
+func (c *Client) Snapshot(ctx context.Context, id string, req models.SnapshotRequest) (models.SnapshotResponse, error) {
+	detail, err := c.Inspect(ctx, id)
+	if err != nil {
+		return models.SnapshotResponse{}, err
+	}
+
+	owner := OwnerFrom(ctx)
+	ref := req.Image
+	if ref == "" {
+		ref = fmt.Sprintf("%s%s:%d", snapshotRepo(owner), detail.Name, time.Now().Unix())
+	}
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return models.SnapshotResponse{}, fmt.Errorf("%w: %v", ErrInvalidReference, err)
+	}
+	ref = reference.FamiliarString(reference.TagNameOnly(named))
+
+	existing, err := c.cli.ImageInspect(ctx, ref)
+	switch {
+	case err == nil:
+		var labels map[string]string
+		if existing.Config != nil {
+			labels = existing.Config.Labels
+		}
+		if !canReplaceImage(labels, owner) {
+			return models.SnapshotResponse{}, fmt.Errorf("%w: %s", ErrImageExists, ref)
+		}
+	case !errdefs.IsNotFound(err):
+		return models.SnapshotResponse{}, err
+	}
+
+	comment := req.Comment
+	if comment == "" {
+		comment = "opensbx snapshot of " + detail.Name
+	}
+	result, err := c.cli.ContainerCommit(ctx, detail.ID, moby.ContainerCommitOptions{
+		Reference: ref,
+		Comment:   comment,
+		Changes:   snapshotChanges(detail.Name, owner),
+	})
+	if err != nil {
+		return models.SnapshotResponse{}, fmt.Errorf("commit sandbox: %w", err)
+	}
+
+	resp := models.SnapshotResponse{Image: ref, ID: result.ID}
+	if !req.Push {
+		return resp, nil
+	}
+
+	if err := c.pushImage(ctx, ref, req.RegistryAuth); err != nil {
+		return resp, err
+	}
+	resp.Pushed = true
+	return resp, nil
+}
+
+// pushImage pushes ref and waits for completion, surfacing inline stream errors.
+func (c *Client) pushImage(ctx context.Context, ref string, auth *models.RegistryAuth) error {
+	opts := moby.ImagePushOptions{}
+	if auth != nil {
+		encoded, err := encodeRegistryAuth(auth)
+		if err != nil {
+			return err
+		}
+		opts.RegistryAuth = encoded
+	}
+
+	resp, err := c.cli.ImagePush(ctx, ref, opts)
+	if err != nil {
+		return fmt.Errorf("push %s: %w", ref, err)
+	}
+	defer resp.Close()
+
+	for msg, err := range resp.JSONMessages(ctx) {
+		if err != nil {
+			return fmt.Errorf("push %s: %w", ref, err)
+		}
+		if msg.Error != nil {
+			return fmt.Errorf("push %s: %s", ref, msg.Error.Message)
+		}
+	}
+	return nil
+}
+
+// encodeRegistryAuth builds the base64url JSON header value the Docker API expects.
+func encodeRegistryAuth(auth *models.RegistryAuth) (string, error) {
+	b, err := json.Marshal(registry.AuthConfig{
+		Username:      auth.Username,
+		Password:      auth.Password,
+		ServerAddress: auth.ServerAddress,
+	})
+	if err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(b), nil
+}
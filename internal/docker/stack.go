@@ -0,0 +1,257 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"opensbx/internal/database"
+	"opensbx/internal/logging"
+	"opensbx/models"
+
+	moby "github.com/moby/moby/client"
+)
+
+// stackNetworkPrefix names the private network of each stack.
+const stackNetworkPrefix = "opensbx-stack-"
+
+// stackNetwork returns the name of a stack's network.
+func stackNetwork(stack string) string {
+	return stackNetworkPrefix + stack
+}
+
+// StackSandboxName returns the sandbox name of a stack service: the stack name
+// for the web service, so the proxy routes the stack name to it, and
+// "<stack>-<service>" for the others.
+func StackSandboxName(stack, service, web string) string {
+	if service == web {
+		return stack
+	}
+	return stack + "-" + service
+}
+
+// StackWeb returns the web service of a stack request: Web when set, else the
+// only service with ports, else "".
+func StackWeb(req models.CreateStackRequest) string {
+	if req.Web != "" {
+		return req.Web
+	}
+	web := ""
+	for name, svc := range req.Services {
+		if len(svc.Ports) == 0 {
+			continue
+		}
+		if web != "" {
+			return ""
+		}
+		web = name
+	}
+	return web
+}
+
+// CreateStack creates the services of a stack as sandboxes on a private network,
+// where each reaches the others by service name. The web service is created
+// last, so the services it depends on are already running. If any service
+// fails, the ones already created are removed.
+func (c *Client) CreateStack(ctx context.Context, req models.CreateStackRequest) (models.Stack, error) {
+	if existing, err := c.repo.FindStack(req.Name); err != nil {
+		return models.Stack{}, err
+	} else if existing != nil {
+		return models.Stack{}, fmt.Errorf("%w: %s", ErrStackExists, req.Name)
+	}
+
+	web := StackWeb(req)
+	st := database.Stack{Name: req.Name, OwnerID: OwnerFrom(ctx), Web: web, CreatedAt: time.Now().UnixMilli()}
+	if err := c.repo.SaveStack(st); err != nil {
+		return models.Stack{}, err
+	}
+	err := c.ensureNetwork(ctx, stackNetwork(req.Name), moby.NetworkCreateOptions{Driver: "bridge", Internal: true})
+	if err == nil {
+		err = c.attachEgress(ctx, stackNetwork(req.Name))
+	}
+	if err != nil {
+		c.removeStack(ctx, req.Name)
+		return models.Stack{}, err
+	}
+
+	services := make([]string, 0, len(req.Services))
+	for name := range req.Services {
+		services = append(services, name)
+	}
+	slices.SortFunc(services, func(a, b string) int {
+		if n := boolCompare(a == web, b == web); n != 0 {
+			return n
+		}
+		return strings.Compare(a, b)
+	})
+
+	for _, service := range services {
+		_, err := c.create(ctx, req.Services[service], createOptions{
+			name:    StackSandboxName(req.Name, service, web),
+			stack:   req.Name,
+			service: service,
+		})
+		if err != nil {
+			c.removeStack(ctx, req.Name)
+			return models.Stack{}, fmt.Errorf("service %s: %w", service, err)
+		}
+	}
+	return c.stackDetail(ctx, st)
+}
+
+// ListStacks returns the caller's stacks ordered by name.
+func (c *Client) ListStacks(ctx context.Context) ([]models.Stack, error) {
+	stacks, err := c.repo.FindStacks(OwnerFrom(ctx))
+	if err != nil {
+		return nil, err
+	}
+	out := make([]models.Stack, 0, len(stacks))
+	for _, st := range stacks {
+		detail, err := c.stackDetail(ctx, st)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, detail)
+	}
+	return out, nil
+}
+
+// GetStack returns a stack and the state of its services.
+func (c *Client) GetStack(ctx context.Context, name string) (models.Stack, error) {
+	st, err := c.findStack(ctx, name)
+	if err != nil {
+		return models.Stack{}, err
+	}
+	return c.stackDetail(ctx, *st)
+}
+
+// StartStack starts the stopped services of a stack, web service last.
+func (c *Client) StartStack(ctx context.Context, name string) (models.Stack, error) {
+	st, err := c.findStack(ctx, name)
+	if err != nil {
+		return models.Stack{}, err
+	}
+	sandboxes, err := c.repo.FindByStack(name)
+	if err != nil {
+		return models.Stack{}, err
+	}
+	slices.SortStableFunc(sandboxes, func(a, b database.Sandbox) int {
+		return boolCompare(a.Name == name, b.Name == name)
+	})
+	for _, sb := range sandboxes {
+		if _, err := c.Start(ctx, sb.ID); err != nil && !errors.Is(err, ErrAlreadyRunning) {
+			return models.Stack{}, fmt.Errorf("service %s: %w", stackService(sb, *st), err)
+		}
+	}
+	return c.stackDetail(ctx, *st)
+}
+
+// StopStack stops the running services of a stack, web service first.
+func (c *Client) StopStack(ctx context.Context, name string) (models.Stack, error) {
+	st, err := c.findStack(ctx, name)
+	if err != nil {
+		return models.Stack{}, err
+	}
+	sandboxes, err := c.repo.FindByStack(name)
+	if err != nil {
+		return models.Stack{}, err
+	}
+	slices.SortStableFunc(sandboxes, func(a, b database.Sandbox) int {
+		return boolCompare(b.Name == name, a.Name == name)
+	})
+	for _, sb := range sandboxes {
+		if err := c.Stop(ctx, sb.ID); err != nil && !errors.Is(err, ErrAlreadyStopped) {
+			return models.Stack{}, fmt.Errorf("service %s: %w", stackService(sb, *st), err)
+		}
+	}
+	return c.stackDetail(ctx, *st)
+}
+
+// RemoveStack removes a stack's services, its network and the stack itself.
+func (c *Client) RemoveStack(ctx context.Context, name string) error {
+	if _, err := c.findStack(ctx, name); err != nil {
+		return err
+	}
+	return c.removeStack(ctx, name)
+}
+
+// removeStack tears a stack down without checking its owner.
+func (c *Client) removeStack(ctx context.Context, name string) error {
+	sandboxes, err := c.repo.FindByStack(name)
+	if err != nil {
+		return err
+	}
+	for _, sb := range sandboxes {
+		if err := c.Remove(ctx, sb.ID); err != nil {
+			return fmt.Errorf("remove %s: %w", sb.Name, err)
+		}
+	}
+	if err := c.removeNetwork(ctx, stackNetwork(name)); err != nil {
+		logging.FromContext(ctx).Error("failed to remove stack network", "stack", name, "err", err)
+	}
+	return c.repo.DeleteStack(name)
+}
+
+// findStack returns a stack visible to the caller, or ErrStackNotFound.
+func (c *Client) findStack(ctx context.Context, name string) (*database.Stack, error) {
+	st, err := c.repo.FindStack(name)
+	if err != nil {
+		return nil, err
+	}
+	if st == nil {
+		return nil, ErrStackNotFound
+	}
+	if owner := OwnerFrom(ctx); owner != "" && st.OwnerID != owner {
+		return nil, ErrStackNotFound
+	}
+	return st, nil
+}
+
+// stackDetail builds the API view of a stack with the live state of its services.
+func (c *Client) stackDetail(ctx context.Context, st database.Stack) (models.Stack, error) {
+	sandboxes, err := c.repo.FindByStack(st.Name)
+	if err != nil {
+		return models.Stack{}, err
+	}
+	detail := models.Stack{
+		Name:      st.Name,
+		Web:       st.Web,
+		Network:   stackNetwork(st.Name),
+		Services:  make([]models.StackService, 0, len(sandboxes)),
+		CreatedAt: time.UnixMilli(st.CreatedAt).UTC(),
+	}
+	for _, sb := range sandboxes {
+		svc := models.StackService{Service: stackService(sb, st), ID: sb.ID, Name: sb.Name, Ports: []string{}}
+		if info, err := c.Inspect(ctx, sb.ID); err == nil {
+			svc.Status, svc.Ports = info.Status, info.Ports
+		} else if errors.Is(err, ErrNotFound) {
+			svc.Status = "missing"
+		} else {
+			return models.Stack{}, err
+		}
+		detail.Services = append(detail.Services, svc)
+	}
+	return detail, nil
+}
+
+// stackService returns the service name of a stack sandbox.
+func stackService(sb database.Sandbox, st database.Stack) string {
+	if sb.Name == st.Name {
+		return st.Web
+	}
+	return strings.TrimPrefix(sb.Name, st.Name+"-")
+}
+
+// boolCompare orders false before true.
+func boolCompare(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return 1
+	}
+	return -1
+}
@@ -0,0 +1,88 @@
+package docker
+
+import (
+	"context"
+	"io"
+
+	moby "github.com/moby/moby/client"
+)
+
+// defaultShell is used when an interactive session does not name a command.
+const defaultShell = "/bin/sh"
+
+// TerminalSession is an interactive exec attached to a TTY. Reads return the
+// terminal output, writes go to stdin.
+type TerminalSession interface {
+	io.ReadWriteCloser
+	// Resize changes the TTY size in rows and columns.
+	Resize(ctx context.Context, rows, cols uint) error
+	// ExitCode returns the exit code once the process has exited.
+	ExitCode(ctx context.Context) (int, error)
+}
+
+// ExecInteractive starts cmd (default /bin/sh) in a running sandbox with a TTY
+// and returns a bidirectional session over the hijacked connection.
+// Returns ErrNotRunning if the sandbox is not running.
+func (c *Client) ExecInteractive(ctx context.Context, id string, cmd []string, rows, cols uint) (TerminalSession, error) {
+	info, err := c.cli.ContainerInspect(ctx, id, moby.ContainerInspectOptions{})
+	if err != nil {
+		return nil, wrapNotFound(err)
+	}
+	if !info.Container.State.Running {
+		return nil, notRunning(info.Container.State)
+	}
+	c.Touch(id)
+
+	if len(cmd) == 0 {
+		cmd = []string{defaultShell}
+	}
+	size := moby.ConsoleSize{Height: rows, Width: cols}
+
+	execCfg, err := c.cli.ExecCreate(ctx, id, moby.ExecCreateOptions{
+		TTY:          true,
+		AttachStdin:  true,
+		AttachStdout: true,
+		AttachStderr: true,
+		ConsoleSize:  size,
+		Env:          append(envSlice(c.sandboxEnv(ctx, info.Container.ID)), "TERM=xterm-256color"),
+		Cmd:          cmd,
+	})
+	if err != nil {
+		return nil, wrapNotFound(err)
+	}
+
+	// With a TTY the output is a single raw stream, no stdcopy demultiplexing needed.
+	attached, err := c.cli.ExecAttach(ctx, execCfg.ID, moby.ExecAttachOptions{TTY: true, ConsoleSize: size})
+	if err != nil {
+		return nil, err
+	}
+
+	return &terminalSession{cli: c.cli, execID: execCfg.ID, attached: attached.HijackedResponse}, nil
+}
+
+type terminalSession struct {
+	cli      ContainerRuntime
+	execID   string
+	attached moby.HijackedResponse
+}
+
+func (t *terminalSession) Read(p []byte) (int, error)  { return t.attached.Reader.Read(p) }
+func (t *terminalSession) Write(p []byte) (int, error) { return t.attached.Conn.Write(p) }
+
+func (t *terminalSession) Close() error {
+	t.attached.Close()
+	return nil
+}
+
+func (t *terminalSession) Resize(ctx context.Context, rows, cols uint) error {
+	_, err := t.cli.ExecResize(ctx, t.execID, moby.ExecResizeOptions{Height: rows, Width: cols})
+	return err
+}
+
+func (t *terminalSession) ExitCode(ctx context.Context) (int, error) {
+	inspect, err := t.cli.ExecInspect(ctx, t.execID, moby.ExecInspectOptions{})
+	if err != nil {
+		return 0, err
+	}
+	return inspect.ExitCode, nil
+}
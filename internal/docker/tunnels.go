@@ -0,0 +1,197 @@
+package docker
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"opensbx/models"
+
+	moby "github.com/moby/moby/client"
+)
+
+// tunnelDialTimeout bounds how long a tunnel connection waits for its port.
+const tunnelDialTimeout = 10 * time.Second
+
+// tunnel forwards connections to a port of a sandbox. Tunnels live in memory
+// only: they end with their sandbox, and with the server.
+type tunnel struct {
+	id        string
+	sandboxID string
+	port      int
+	createdAt time.Time
+
+	mu     sync.Mutex
+	conns  map[net.Conn]struct{} // open connections
+	closed bool
+}
+
+// tunnelConn is a connection through a tunnel; closing it stops tracking it.
+type tunnelConn struct {
+	net.Conn
+	t *tunnel
+}
+
+func (tc *tunnelConn) Close() error {
+	tc.t.mu.Lock()
+	delete(tc.t.conns, tc.Conn)
+	tc.t.mu.Unlock()
+	return tc.Conn.Close()
+}
+
+// generateTunnelID creates a tunnel ID: tun_ + 24 hex chars.
+func generateTunnelID() string {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return "tun_" + hex.EncodeToString(b)
+}
+
+// CreateTunnel opens a tunnel to a port of a running sandbox. The port does
+// not need to be published, nor to be listening yet: each connection is
+// dialed when it is opened.
+func (c *Client) CreateTunnel(ctx context.Context, id string, req models.CreateTunnelRequest) (models.Tunnel, error) {
+	if req.Port < 1 || req.Port > 65535 {
+		return models.Tunnel{}, fmt.Errorf("invalid port %d", req.Port)
+	}
+	info, err := c.cli.ContainerInspect(ctx, id, moby.ContainerInspectOptions{})
+	if err != nil {
+		return models.Tunnel{}, wrapNotFound(err)
+	}
+	if !info.Container.State.Running {
+		return models.Tunnel{}, notRunning(info.Container.State)
+	}
+
+	t := &tunnel{
+		id:        generateTunnelID(),
+		sandboxID: info.Container.ID,
+		port:      req.Port,
+		createdAt: time.Now().UTC(),
+		conns:     map[net.Conn]struct{}{},
+	}
+	c.tunnels.Store(t.id, t)
+	return t.detail(), nil
+}
+
+// detail returns the API view of a tunnel.
+func (t *tunnel) detail() models.Tunnel {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return models.Tunnel{
+		ID:          t.id,
+		SandboxID:   t.sandboxID,
+		Port:        t.port,
+		URL:         "/v1/sandboxes/" + t.sandboxID + "/tunnels/" + t.id + "/connect",
+		Connections: len(t.conns),
+		CreatedAt:   t.createdAt,
+	}
+}
+
+// close closes the tunnel's open connections and refuses new ones.
+func (t *tunnel) close() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.closed = true
+	for conn := range t.conns {
+		conn.Close()
+	}
+	clear(t.conns)
+}
+
+// ListTunnels returns the tunnels of a sandbox, oldest first.
+func (c *Client) ListTunnels(ctx context.Context, id string) ([]models.Tunnel, error) {
+	fullID, err := c.sandboxID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	out := []models.Tunnel{}
+	c.tunnels.Range(func(_, value any) bool {
+		if t := value.(*tunnel); t.sandboxID == fullID {
+			out = append(out, t.detail())
+		}
+		return true
+	})
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
+
+// findTunnel returns a tunnel of the given sandbox, or ErrTunnelNotFound.
+func (c *Client) findTunnel(ctx context.Context, id, tunnelID string) (*tunnel, error) {
+	fullID, err := c.sandboxID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	v, ok := c.tunnels.Load(tunnelID)
+	if !ok || v.(*tunnel).sandboxID != fullID {
+		return nil, ErrTunnelNotFound
+	}
+	return v.(*tunnel), nil
+}
+
+// RemoveTunnel closes a tunnel and the connections open through it.
+func (c *Client) RemoveTunnel(ctx context.Context, id, tunnelID string) error {
+	t, err := c.findTunnel(ctx, id, tunnelID)
+	if err != nil {
+		return err
+	}
+	c.tunnels.Delete(t.id)
+	t.close()
+	return nil
+}
+
+// closeTunnels closes the tunnels of a sandbox being removed.
+func (c *Client) closeTunnels(sandboxID string) {
+	c.tunnels.Range(func(key, value any) bool {
+		if t := value.(*tunnel); t.sandboxID == sandboxID {
+			c.tunnels.Delete(key)
+			t.close()
+		}
+		return true
+	})
+}
+
+// DialTunnel opens a TCP connection to a tunnel's port on the sandbox's own
+// IP, so it reaches ports that are not published on the host. The connection
+// is closed when the tunnel is removed. Returns ErrPortUnreachable if nothing
+// accepts it.
+func (c *Client) DialTunnel(ctx context.Context, id, tunnelID string) (net.Conn, error) {
+	t, err := c.findTunnel(ctx, id, tunnelID)
+	if err != nil {
+		return nil, err
+	}
+	info, err := c.cli.ContainerInspect(ctx, t.sandboxID, moby.ContainerInspectOptions{})
+	if err != nil {
+		return nil, wrapNotFound(err)
+	}
+	if !info.Container.State.Running {
+		return nil, notRunning(info.Container.State)
+	}
+	addr, err := containerAddr(info.Container, strconv.Itoa(t.port))
+	if err != nil {
+		return nil, err
+	}
+	c.Touch(t.sandboxID)
+
+	dialCtx, cancel := context.WithTimeout(ctx, tunnelDialTimeout)
+	defer cancel()
+	conn, err := (&net.Dialer{}).DialContext(dialCtx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: port %d: %v", ErrPortUnreachable, t.port, err)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		conn.Close()
+		return nil, ErrTunnelNotFound
+	}
+	t.conns[conn] = struct{}{}
+	return &tunnelConn{Conn: conn, t: t}, nil
+}
@@ -0,0 +1,50 @@
+package docker
+
+import (
+	"context"
+	"errors"
+)
+
+// wakeCall is a wake-up in progress, shared by concurrent proxied requests.
+type wakeCall struct {
+	done chan struct{} // closed once the start finished, with err set
+	err  error
+}
+
+// WakeByName starts a stopped sandbox that opted into wake_on_request and
+// waits for its ready check, if it has one. woke is false when no sandbox has
+// that name or it did not opt in. Concurrent calls for a sandbox share one
+// start, which is not cancelled when a caller gives up.
+func (c *Client) WakeByName(ctx context.Context, name string) (bool, error) {
+	sb, err := c.repo.FindByName(name)
+	if err != nil || sb == nil || !sb.WakeOnRequest {
+		return false, err
+	}
+
+	call := &wakeCall{done: make(chan struct{})}
+	if v, loaded := c.wakes.LoadOrStore(sb.ID, call); loaded {
+		call = v.(*wakeCall)
+	} else {
+		go func() {
+			_, call.err = c.Start(context.Background(), sb.ID)
+			if errors.Is(call.err, ErrAlreadyRunning) {
+				call.err = nil
+			}
+			c.wakes.Delete(sb.ID)
+			close(call.done)
+		}()
+	}
+
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+	if call.err != nil {
+		return false, call.err
+	}
+	if _, err := c.WaitReady(ctx, sb.ID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,202 @@
+// Package firewall installs host iptables rules that deny sandbox traffic to
+// sensitive destinations (cloud metadata, the API port, the control plane).
+package firewall
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Chains managed by opensbx. Forwarded traffic (to other hosts) passes through
+// DOCKER-USER; traffic addressed to the host itself passes through INPUT.
+const (
+	chainForward = "OPENSBX-EGRESS"
+	chainHost    = "OPENSBX-HOST"
+)
+
+// DefaultDeny blocks the link-local cloud metadata service.
+const DefaultDeny = "169.254.169.254/32"
+
+// Rule denies traffic to a destination prefix and/or TCP port.
+// A rule without a prefix only applies to ports on the host itself.
+type Rule struct {
+	Dest netip.Prefix // invalid = host-local
+	Port uint16       // 0 = all ports
+}
+
+// String returns the rule in the same format accepted by ParseRules.
+func (r Rule) String() string {
+	dest := ""
+	if r.Dest.IsValid() {
+		dest = r.Dest.String()
+	}
+	if r.Port == 0 {
+		return dest
+	}
+	return dest + ":" + strconv.Itoa(int(r.Port))
+}
+
+// ParseRules parses a comma-separated deny list. Entries are an IP or CIDR,
+// optionally followed by :port, or :port alone for a port on the host itself.
+// Examples: "169.254.169.254", "10.0.0.0/8", "10.0.0.5:8080", ":8080".
+func ParseRules(raw string) ([]Rule, error) {
+	var rules []Rule
+	for _, part := range strings.Split(raw, ",") {
+		entry := strings.TrimSpace(part)
+		if entry == "" {
+			continue
+		}
+		r, err := parseRule(entry)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+func parseRule(entry string) (Rule, error) {
+	var r Rule
+	dest := entry
+	if i := strings.LastIndex(entry, ":"); i != -1 && !strings.Contains(entry[:i], ":") {
+		port, err := strconv.ParseUint(entry[i+1:], 10, 16)
+		if err != nil || port == 0 {
+			return Rule{}, fmt.Errorf("egress rule %q: invalid port", entry)
+		}
+		r.Port = uint16(port)
+		dest = entry[:i]
+	}
+
+	if dest == "" {
+		if r.Port == 0 {
+			return Rule{}, fmt.Errorf("egress rule %q: empty", entry)
+		}
+		return r, nil
+	}
+
+	if strings.Contains(dest, "/") {
+		p, err := netip.ParsePrefix(dest)
+		if err != nil {
+			return Rule{}, fmt.Errorf("egress rule %q: %w", entry, err)
+		}
+		r.Dest = p.Masked()
+		return r, nil
+	}
+	addr, err := netip.ParseAddr(dest)
+	if err != nil {
+		return Rule{}, fmt.Errorf("egress rule %q: %w", entry, err)
+	}
+	r.Dest = netip.PrefixFrom(addr, addr.BitLen())
+	return r, nil
+}
+
+// Runner executes a single iptables invocation.
+type Runner func(ctx context.Context, args ...string) error
+
+// Manager owns the opensbx iptables chains and the jumps into them.
+type Manager struct {
+	rules []Rule
+	run   Runner
+}
+
+// New creates a Manager. A nil runner uses the iptables binary on PATH.
+func New(rules []Rule, run Runner) *Manager {
+	if run == nil {
+		run = runIPTables
+	}
+	return &Manager{rules: rules, run: run}
+}
+
+// Rules returns the configured deny rules.
+func (m *Manager) Rules() []Rule {
+	return m.rules
+}
+
+// Install (re)creates the opensbx chains and fills them with DROP rules.
+func (m *Manager) Install(ctx context.Context) error {
+	for _, chain := range []string{chainForward, chainHost} {
+		// -N fails when the chain already exists; flushing below makes this idempotent.
+		_ = m.run(ctx, "-N", chain)
+		if err := m.run(ctx, "-F", chain); err != nil {
+			return fmt.Errorf("flush %s: %w", chain, err)
+		}
+	}
+
+	for _, args := range installArgs(m.rules) {
+		if err := m.run(ctx, args...); err != nil {
+			return fmt.Errorf("install rule: %w", err)
+		}
+	}
+	return nil
+}
+
+// Attach sends traffic originating from source (a sandbox subnet) through the deny chains.
+func (m *Manager) Attach(ctx context.Context, source netip.Prefix) error {
+	for _, jump := range jumpArgs(source) {
+		// Skip jumps that are already present (-C succeeds).
+		if m.run(ctx, append([]string{"-C"}, jump...)...) == nil {
+			continue
+		}
+		if err := m.run(ctx, append([]string{"-I"}, jump...)...); err != nil {
+			return fmt.Errorf("attach %s: %w", source, err)
+		}
+	}
+	return nil
+}
+
+// Detach removes the jumps installed by Attach.
+func (m *Manager) Detach(ctx context.Context, source netip.Prefix) error {
+	for _, jump := range jumpArgs(source) {
+		if err := m.run(ctx, append([]string{"-D"}, jump...)...); err != nil {
+			return fmt.Errorf("detach %s: %w", source, err)
+		}
+	}
+	return nil
+}
+
+// installArgs builds the -A invocations that populate the deny chains.
+// Remote destinations are denied on both paths; host-local ports only on INPUT.
+func installArgs(rules []Rule) [][]string {
+	var out [][]string
+	for _, r := range rules {
+		match := ruleMatch(r, "DROP")
+		if r.Dest.IsValid() {
+			out = append(out, append([]string{"-A", chainForward}, match...))
+		}
+		out = append(out, append([]string{"-A", chainHost}, match...))
+	}
+	return out
+}
+
+// ruleMatch returns the match arguments for r, jumping to target.
+func ruleMatch(r Rule, target string) []string {
+	var args []string
+	if r.Dest.IsValid() {
+		args = append(args, "-d", r.Dest.String())
+	}
+	if r.Port != 0 {
+		args = append(args, "-p", "tcp", "--dport", strconv.Itoa(int(r.Port)))
+	}
+	return append(args, "-j", target)
+}
+
+// jumpArgs returns the chain/match pairs (without the -I/-D/-C verb) for a source subnet.
+func jumpArgs(source netip.Prefix) [][]string {
+	src := source.Masked().String()
+	return [][]string{
+		{"DOCKER-USER", "-s", src, "-j", chainForward},
+		{"INPUT", "-s", src, "-j", chainHost},
+	}
+}
+
+func runIPTables(ctx context.Context, args ...string) error {
+	out, err := exec.CommandContext(ctx, "iptables", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("iptables %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
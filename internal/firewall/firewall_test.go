@@ -0,0 +1,74 @@
+package firewall
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules("169.254.169.254, 10.0.0.0/8 ,10.1.2.3:8080,:8080,")
+	if err != nil {
+		t.Fatalf("ParseRules() error: %v", err)
+	}
+
+	want := []string{"169.254.169.254/32", "10.0.0.0/8", "10.1.2.3/32:8080", ":8080"}
+	got := make([]string, 0, len(rules))
+	for _, r := range rules {
+		got = append(got, r.String())
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ParseRules() = %v, want %v", got, want)
+	}
+}
+
+func TestParseRulesInvalid(t *testing.T) {
+	for _, in := range []string{"nope", "10.0.0.1:0", "10.0.0.1:http", ":", "10.0.0.0/99"} {
+		if _, err := ParseRules(in); err == nil {
+			t.Fatalf("ParseRules(%q) expected error", in)
+		}
+	}
+}
+
+func TestInstallArgs(t *testing.T) {
+	rules, _ := ParseRules("169.254.169.254,:8080")
+	got := installArgs(rules)
+	want := [][]string{
+		{"-A", chainForward, "-d", "169.254.169.254/32", "-j", "DROP"},
+		{"-A", chainHost, "-d", "169.254.169.254/32", "-j", "DROP"},
+		{"-A", chainHost, "-p", "tcp", "--dport", "8080", "-j", "DROP"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("installArgs() = %v, want %v", got, want)
+	}
+}
+
+func TestAttachSkipsExistingJumps(t *testing.T) {
+	var calls []string
+	m := New(nil, func(_ context.Context, args ...string) error {
+		calls = append(calls, strings.Join(args, " "))
+		if args[0] == "-C" && args[1] == "INPUT" {
+			return nil // already present
+		}
+		if args[0] == "-C" {
+			return errors.New("missing")
+		}
+		return nil
+	})
+
+	if err := m.Attach(context.Background(), netip.MustParsePrefix("172.30.0.0/16")); err != nil {
+		t.Fatalf("Attach() error: %v", err)
+	}
+
+	want := []string{
+		"-C DOCKER-USER -s 172.30.0.0/16 -j OPENSBX-EGRESS",
+		"-I DOCKER-USER -s 172.30.0.0/16 -j OPENSBX-EGRESS",
+		"-C INPUT -s 172.30.0.0/16 -j OPENSBX-HOST",
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Fatalf("calls = %v, want %v", calls, want)
+	}
+}
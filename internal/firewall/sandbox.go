@@ -0,0 +1,128 @@
+package firewall
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// sandboxInterface is the interface Docker gives a container on its network.
+const sandboxInterface = "eth0"
+
+// Policy is the per-sandbox network policy applied by Sandbox.Apply.
+// In Allow and Deny, a rule without a prefix matches that TCP port on every host.
+type Policy struct {
+	Allow       []Rule // when set, only these destinations are reachable
+	Deny        []Rule // destinations that are never reachable
+	IngressKbps int    // download limit in kbit/s, 0 = unlimited
+	EgressKbps  int    // upload limit in kbit/s, 0 = unlimited
+}
+
+// Empty reports whether the policy has nothing to apply.
+func (p Policy) Empty() bool {
+	return len(p.Allow) == 0 && len(p.Deny) == 0 && p.IngressKbps == 0 && p.EgressKbps == 0
+}
+
+// NamespaceRunner runs a command (iptables or tc) inside the network namespace of pid.
+type NamespaceRunner func(ctx context.Context, pid int, name string, args ...string) error
+
+// Sandbox applies policies inside a container's network namespace. The rules
+// live and die with the container, so nothing has to be cleaned up on stop or
+// remove, but they must be applied again after every start.
+type Sandbox struct {
+	run NamespaceRunner
+}
+
+// NewSandbox creates a Sandbox. A nil runner uses nsenter on PATH.
+func NewSandbox(run NamespaceRunner) *Sandbox {
+	if run == nil {
+		run = runNsenter
+	}
+	return &Sandbox{run: run}
+}
+
+// Apply installs p in the network namespace of pid, replacing earlier rules.
+func (s *Sandbox) Apply(ctx context.Context, pid int, p Policy) error {
+	for _, args := range sandboxFilterArgs(p) {
+		if err := s.run(ctx, pid, "iptables", args...); err != nil {
+			return fmt.Errorf("egress rule: %w", err)
+		}
+	}
+
+	// Deleting a missing qdisc fails; the add calls below report real errors.
+	_ = s.run(ctx, pid, "tc", "qdisc", "del", "dev", sandboxInterface, "root")
+	_ = s.run(ctx, pid, "tc", "qdisc", "del", "dev", sandboxInterface, "ingress")
+	for _, args := range shapingArgs(p) {
+		if err := s.run(ctx, pid, "tc", args...); err != nil {
+			return fmt.Errorf("bandwidth limit: %w", err)
+		}
+	}
+	return nil
+}
+
+// sandboxFilterArgs builds the iptables invocations for the OUTPUT chain of a
+// sandbox namespace. Loopback (including Docker's embedded DNS) and replies on
+// established connections, such as proxied requests, are always allowed.
+func sandboxFilterArgs(p Policy) [][]string {
+	out := [][]string{{"-F", "OUTPUT"}}
+	if len(p.Allow) == 0 && len(p.Deny) == 0 {
+		return out
+	}
+	out = append(out,
+		[]string{"-A", "OUTPUT", "-o", "lo", "-j", "ACCEPT"},
+		[]string{"-A", "OUTPUT", "-m", "conntrack", "--ctstate", "ESTABLISHED,RELATED", "-j", "ACCEPT"},
+	)
+	for _, r := range p.Deny {
+		out = append(out, append([]string{"-A", "OUTPUT"}, ruleMatch(r, "DROP")...))
+	}
+	if len(p.Allow) > 0 {
+		for _, r := range p.Allow {
+			out = append(out, append([]string{"-A", "OUTPUT"}, ruleMatch(r, "ACCEPT")...))
+		}
+		out = append(out, []string{"-A", "OUTPUT", "-j", "DROP"})
+	}
+	return out
+}
+
+// shapingArgs builds the tc invocations for the bandwidth limits: a token
+// bucket on the egress side and a policer on the ingress side of eth0.
+func shapingArgs(p Policy) [][]string {
+	var out [][]string
+	if p.EgressKbps > 0 {
+		out = append(out, []string{"qdisc", "add", "dev", sandboxInterface, "root", "tbf",
+			"rate", kbit(p.EgressKbps), "burst", burst(p.EgressKbps), "latency", "400ms"})
+	}
+	if p.IngressKbps > 0 {
+		out = append(out,
+			[]string{"qdisc", "add", "dev", sandboxInterface, "handle", "ffff:", "ingress"},
+			[]string{"filter", "add", "dev", sandboxInterface, "parent", "ffff:", "protocol", "all", "prio", "1",
+				"u32", "match", "u32", "0", "0", "police", "rate", kbit(p.IngressKbps), "burst", burst(p.IngressKbps), "drop", "flowid", ":1"},
+		)
+	}
+	return out
+}
+
+func kbit(kbps int) string {
+	return strconv.Itoa(kbps) + "kbit"
+}
+
+// burst sizes a bucket to 100ms of traffic, and at least 16 KiB so full-size
+// packets always fit.
+func burst(kbps int) string {
+	b := kbps * 1000 / 8 / 10
+	if b < 16*1024 {
+		b = 16 * 1024
+	}
+	return strconv.Itoa(b)
+}
+
+func runNsenter(ctx context.Context, pid int, name string, args ...string) error {
+	argv := append([]string{"-t", strconv.Itoa(pid), "-n", name}, args...)
+	out, err := exec.CommandContext(ctx, "nsenter", argv...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s %s: %v: %s", name, strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
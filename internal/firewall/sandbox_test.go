@@ -0,0 +1,61 @@
+package firewall
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSandboxFilterArgs(t *testing.T) {
+	if got := sandboxFilterArgs(Policy{}); !reflect.DeepEqual(got, [][]string{{"-F", "OUTPUT"}}) {
+		t.Fatalf("sandboxFilterArgs(empty) = %v", got)
+	}
+
+	allow, _ := ParseRules("140.82.112.0/20,:443")
+	got := sandboxFilterArgs(Policy{Allow: allow})
+	want := [][]string{
+		{"-F", "OUTPUT"},
+		{"-A", "OUTPUT", "-o", "lo", "-j", "ACCEPT"},
+		{"-A", "OUTPUT", "-m", "conntrack", "--ctstate", "ESTABLISHED,RELATED", "-j", "ACCEPT"},
+		{"-A", "OUTPUT", "-d", "140.82.112.0/20", "-j", "ACCEPT"},
+		{"-A", "OUTPUT", "-p", "tcp", "--dport", "443", "-j", "ACCEPT"},
+		{"-A", "OUTPUT", "-j", "DROP"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("sandboxFilterArgs(allow) = %v, want %v", got, want)
+	}
+
+	deny, _ := ParseRules("10.0.0.0/8")
+	got = sandboxFilterArgs(Policy{Deny: deny})
+	if last := got[len(got)-1]; !reflect.DeepEqual(last, []string{"-A", "OUTPUT", "-d", "10.0.0.0/8", "-j", "DROP"}) {
+		t.Fatalf("sandboxFilterArgs(deny) last = %v", last)
+	}
+}
+
+func TestSandboxApply(t *testing.T) {
+	var calls []string
+	s := NewSandbox(func(_ context.Context, pid int, name string, args ...string) error {
+		if pid != 42 {
+			t.Fatalf("pid = %d, want 42", pid)
+		}
+		calls = append(calls, name+" "+strings.Join(args, " "))
+		return nil
+	})
+
+	if err := s.Apply(context.Background(), 42, Policy{IngressKbps: 8000, EgressKbps: 1000}); err != nil {
+		t.Fatalf("Apply() error: %v", err)
+	}
+
+	want := []string{
+		"iptables -F OUTPUT",
+		"tc qdisc del dev eth0 root",
+		"tc qdisc del dev eth0 ingress",
+		"tc qdisc add dev eth0 root tbf rate 1000kbit burst 16384 latency 400ms",
+		"tc qdisc add dev eth0 handle ffff: ingress",
+		"tc filter add dev eth0 parent ffff: protocol all prio 1 u32 match u32 0 0 police rate 8000kbit burst 100000 drop flowid :1",
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Fatalf("calls = %v, want %v", calls, want)
+	}
+}
@@ -0,0 +1,184 @@
+package keys
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"opensbx/internal/database"
+	"opensbx/models"
+)
+
+// Scopes that can be granted to an API key.
+const (
+	ScopeReadOnly = "read-only" // GET endpoints
+	ScopeExec     = "exec"      // create and manage sandboxes, run commands, write files
+	ScopeImages   = "images"    // pull and remove images
+	ScopeAdmin    = "admin"     // everything, including key management
+)
+
+// AllScopes lists every valid scope.
+var AllScopes = []string{ScopeReadOnly, ScopeExec, ScopeImages, ScopeAdmin}
+
+// ErrNotFound is returned when revoking a key that does not exist or is already revoked.
+var ErrNotFound = errors.New("api key not found")
+
+// ErrInvalidScope is returned when creating a key with an unknown scope.
+var ErrInvalidScope = errors.New("invalid scope")
+
+// secretPrefix marks opensbx keys so they are easy to spot in logs and secret scanners.
+const secretPrefix = "osbx_"
+
+// displayPrefixLen is how much of the secret is kept to recognise a key.
+const displayPrefixLen = len(secretPrefix) + 7
+
+// Store manages API keys persisted in the database.
+type Store struct {
+	repo *database.Repository
+}
+
+// New creates a Store backed by the given repository.
+func New(repo *database.Repository) *Store {
+	return &Store{repo: repo}
+}
+
+// Create generates a new key with the requested owner, scopes and quota. An
+// empty owner gives the key its own owner (its ID). The secret is only
+// available in the returned response; the database keeps its hash.
+func (s *Store) Create(req models.CreateAPIKeyRequest) (models.CreateAPIKeyResponse, error) {
+	scopes := normalizeScopes(req.Scopes)
+	for _, sc := range scopes {
+		if !slices.Contains(AllScopes, sc) {
+			return models.CreateAPIKeyResponse{}, fmt.Errorf("%w %q (valid: %s)", ErrInvalidScope, sc, strings.Join(AllScopes, ", "))
+		}
+	}
+
+	secret := secretPrefix + randomHex(24)
+	id := "key_" + randomHex(6)
+	owner := strings.TrimSpace(req.Owner)
+	if owner == "" {
+		owner = id
+	}
+	now := time.Now()
+	rec := database.APIKey{
+		ID:        id,
+		Name:      req.Name,
+		Owner:     owner,
+		Prefix:    secret[:displayPrefixLen],
+		Hash:      hashSecret(secret),
+		Scopes:    strings.Join(scopes, ","),
+		CreatedAt: now.UnixMilli(),
+	}
+	if q := req.Quota; q != nil {
+		rec.MaxSandboxes, rec.MaxMemory, rec.MaxCPUs = q.MaxSandboxes, q.MaxMemory, q.MaxCPUs
+	}
+	if err := s.repo.SaveAPIKey(rec); err != nil {
+		return models.CreateAPIKeyResponse{}, err
+	}
+	return models.CreateAPIKeyResponse{APIKey: toModel(rec), Key: secret}, nil
+}
+
+// Authenticate resolves a presented secret to an active key.
+// Returns ok=false for unknown or revoked keys.
+func (s *Store) Authenticate(secret string) (models.APIKey, bool, error) {
+	if !strings.HasPrefix(secret, secretPrefix) {
+		return models.APIKey{}, false, nil
+	}
+	rec, err := s.repo.FindAPIKeyByHash(hashSecret(secret))
+	if err != nil || rec == nil || rec.RevokedAt != nil {
+		return models.APIKey{}, false, err
+	}
+	return toModel(*rec), true, nil
+}
+
+// List returns all keys, including revoked ones. Secrets are never returned.
+func (s *Store) List() ([]models.APIKey, error) {
+	recs, err := s.repo.FindAllAPIKeys()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]models.APIKey, 0, len(recs))
+	for _, rec := range recs {
+		out = append(out, toModel(rec))
+	}
+	return out, nil
+}
+
+// Revoke disables a key immediately. Returns ErrNotFound if it does not exist or is already revoked.
+func (s *Store) Revoke(id string) error {
+	ok, err := s.repo.RevokeAPIKey(id, time.Now().UnixMilli())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// HasActive reports whether at least one key has not been revoked.
+func (s *Store) HasActive() (bool, error) {
+	n, err := s.repo.CountActiveAPIKeys()
+	return n > 0, err
+}
+
+// Allows reports whether granted scopes permit an operation requiring required.
+// admin permits everything, and any scope permits read-only access.
+func Allows(granted []string, required string) bool {
+	if slices.Contains(granted, ScopeAdmin) {
+		return true
+	}
+	if required == ScopeReadOnly {
+		return len(granted) > 0
+	}
+	return slices.Contains(granted, required)
+}
+
+func toModel(rec database.APIKey) models.APIKey {
+	k := models.APIKey{
+		ID:        rec.ID,
+		Name:      rec.Name,
+		Owner:     rec.Owner,
+		Prefix:    rec.Prefix,
+		Scopes:    normalizeScopes(strings.Split(rec.Scopes, ",")),
+		CreatedAt: time.UnixMilli(rec.CreatedAt),
+	}
+	if rec.MaxSandboxes > 0 || rec.MaxMemory > 0 || rec.MaxCPUs > 0 {
+		k.Quota = &models.Quota{MaxSandboxes: rec.MaxSandboxes, MaxMemory: rec.MaxMemory, MaxCPUs: rec.MaxCPUs}
+	}
+	if rec.RevokedAt != nil {
+		t := time.UnixMilli(*rec.RevokedAt)
+		k.RevokedAt = &t
+	}
+	return k
+}
+
+// normalizeScopes trims, lowercases and de-duplicates scopes, preserving order.
+func normalizeScopes(scopes []string) []string {
+	out := make([]string, 0, len(scopes))
+	for _, sc := range scopes {
+		sc = strings.ToLower(strings.TrimSpace(sc))
+		if sc != "" && !slices.Contains(out, sc) {
+			out = append(out, sc)
+		}
+	}
+	return out
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
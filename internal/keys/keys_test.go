@@ -0,0 +1,106 @@
+package keys
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"opensbx/internal/database"
+	"opensbx/models"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	return New(database.NewRepository(database.New(":memory:")))
+}
+
+func TestStoreLifecycle(t *testing.T) {
+	s := newTestStore(t)
+
+	created, err := s.Create(models.CreateAPIKeyRequest{Name: "ci", Scopes: []string{" Exec ", "read-only", "exec"}})
+	if err != nil {
+		t.Fatalf("Create() error: %v", err)
+	}
+	if !strings.HasPrefix(created.Key, secretPrefix) || !strings.HasPrefix(created.Key, created.Prefix) {
+		t.Fatalf("Create() key = %q, prefix = %q", created.Key, created.Prefix)
+	}
+	if created.Owner != created.ID {
+		t.Fatalf("Create() owner = %q, want key ID %q", created.Owner, created.ID)
+	}
+	if strings.Join(created.Scopes, ",") != "exec,read-only" {
+		t.Fatalf("Create() scopes = %v, want [exec read-only]", created.Scopes)
+	}
+	if created.Quota != nil {
+		t.Fatalf("Create() quota = %+v, want nil", created.Quota)
+	}
+
+	key, ok, err := s.Authenticate(created.Key)
+	if err != nil || !ok || key.ID != created.ID {
+		t.Fatalf("Authenticate() = %+v, %v, %v", key, ok, err)
+	}
+	if _, ok, _ := s.Authenticate(created.Key + "x"); ok {
+		t.Fatalf("Authenticate() accepted a wrong secret")
+	}
+
+	if active, _ := s.HasActive(); !active {
+		t.Fatalf("HasActive() = false after Create")
+	}
+	if err := s.Revoke(created.ID); err != nil {
+		t.Fatalf("Revoke() error: %v", err)
+	}
+	if _, ok, _ := s.Authenticate(created.Key); ok {
+		t.Fatalf("Authenticate() accepted a revoked key")
+	}
+	if err := s.Revoke(created.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Revoke() twice = %v, want ErrNotFound", err)
+	}
+	if active, _ := s.HasActive(); active {
+		t.Fatalf("HasActive() = true after revoking the only key")
+	}
+
+	list, err := s.List()
+	if err != nil || len(list) != 1 || list[0].RevokedAt == nil {
+		t.Fatalf("List() = %+v, %v", list, err)
+	}
+}
+
+func TestStoreCreateInvalidScope(t *testing.T) {
+	s := newTestStore(t)
+	if _, err := s.Create(models.CreateAPIKeyRequest{Name: "bad", Scopes: []string{"root"}}); !errors.Is(err, ErrInvalidScope) {
+		t.Fatalf("Create() error = %v, want ErrInvalidScope", err)
+	}
+}
+
+func TestStoreCreateSharedOwner(t *testing.T) {
+	s := newTestStore(t)
+	a, _ := s.Create(models.CreateAPIKeyRequest{Name: "ci", Owner: "team-a", Scopes: []string{ScopeExec}})
+	b, _ := s.Create(models.CreateAPIKeyRequest{Name: "ci-rotated", Owner: "team-a", Scopes: []string{ScopeExec}, Quota: &models.Quota{MaxSandboxes: 3}})
+	if a.Owner != "team-a" || b.Owner != "team-a" {
+		t.Fatalf("owners = %q, %q, want team-a", a.Owner, b.Owner)
+	}
+
+	key, _, _ := s.Authenticate(b.Key)
+	if key.Quota == nil || key.Quota.MaxSandboxes != 3 {
+		t.Fatalf("Authenticate() quota = %+v, want max 3 sandboxes", key.Quota)
+	}
+}
+
+func TestAllows(t *testing.T) {
+	tests := []struct {
+		granted  []string
+		required string
+		want     bool
+	}{
+		{[]string{ScopeAdmin}, ScopeImages, true},
+		{[]string{ScopeExec}, ScopeReadOnly, true},
+		{[]string{ScopeReadOnly}, ScopeExec, false},
+		{[]string{ScopeExec}, ScopeImages, false},
+		{[]string{ScopeImages}, ScopeImages, true},
+		{nil, ScopeReadOnly, false},
+	}
+	for _, tt := range tests {
+		if got := Allows(tt.granted, tt.required); got != tt.want {
+			t.Fatalf("Allows(%v, %q) = %v, want %v", tt.granted, tt.required, got, tt.want)
+		}
+	}
+}
@@ -1,16 +1,21 @@
 package logging
 
 import (
+	"context"
 	"io"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
-// Setup configures stdlib and Gin log writers to also append into logFilePath.
-func Setup(logFilePath string) (io.Closer, error) {
+// Setup installs a structured slog logger as the default, writing to stdout and
+// appending to logFilePath. format is "json" (default) or "text". Output from the
+// stdlib log package is routed through the same handler, and Gin's writers also
+// append to the file.
+func Setup(logFilePath, format string) (io.Closer, error) {
 	dir := filepath.Dir(logFilePath)
 	if dir != "." {
 		if err := os.MkdirAll(dir, 0o755); err != nil {
@@ -26,9 +31,47 @@ func Setup(logFilePath string) (io.Closer, error) {
 	logWriter := io.MultiWriter(os.Stdout, f)
 	errWriter := io.MultiWriter(os.Stderr, f)
 
-	log.SetOutput(logWriter)
+	slog.SetDefault(slog.New(newHandler(logWriter, format)))
 	gin.DefaultWriter = logWriter
 	gin.DefaultErrorWriter = errWriter
 
 	return f, nil
 }
+
+func newHandler(w io.Writer, format string) slog.Handler {
+	if strings.EqualFold(strings.TrimSpace(format), "text") {
+		return slog.NewTextHandler(w, nil)
+	}
+	return slog.NewJSONHandler(w, nil)
+}
+
+// Fatal logs msg at error level and exits, like log.Fatalf for structured logs.
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// requestIDKey is the context key carrying the request ID.
+type requestIDKey struct{}
+
+// WithRequestID returns ctx carrying the request ID, for FromContext and outgoing calls.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID carried by ctx, or "".
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// FromContext returns the default logger, tagged with the request ID in ctx if any.
+func FromContext(ctx context.Context) *slog.Logger {
+	if id := RequestID(ctx); id != "" {
+		return slog.Default().With("request_id", id)
+	}
+	return slog.Default()
+}
@@ -1,7 +1,10 @@
 package logging
 
 import (
+	"bytes"
+	"context"
 	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
@@ -12,9 +15,11 @@ import (
 
 func TestSetupCreatesFileAndWritesLogs(t *testing.T) {
 	oldOut := log.Writer()
+	oldSlog := slog.Default()
 	oldGinOut := gin.DefaultWriter
 	oldGinErr := gin.DefaultErrorWriter
 	defer func() {
+		slog.SetDefault(oldSlog)
 		log.SetOutput(oldOut)
 		gin.DefaultWriter = oldGinOut
 		gin.DefaultErrorWriter = oldGinErr
@@ -23,7 +28,7 @@ func TestSetupCreatesFileAndWritesLogs(t *testing.T) {
 	dir := t.TempDir()
 	logPath := filepath.Join(dir, "nested", "opensbx.log")
 
-	closer, err := Setup(logPath)
+	closer, err := Setup(logPath, "json")
 	if err != nil {
 		t.Fatalf("Setup() error: %v", err)
 	}
@@ -46,10 +51,40 @@ func TestSetupCreatesFileAndWritesLogs(t *testing.T) {
 	if !strings.Contains(content, "gin-log-line") {
 		t.Fatalf("log file missing gin log line: %q", content)
 	}
+	if !strings.Contains(content, `"msg":"logger-test-line"`) {
+		t.Fatalf("stdlib log line is not structured JSON: %q", content)
+	}
+}
+
+func TestNewHandlerFormat(t *testing.T) {
+	var buf bytes.Buffer
+	slog.New(newHandler(&buf, "json")).Info("hello", "sandbox_id", "abc")
+	if !strings.Contains(buf.String(), `"sandbox_id":"abc"`) {
+		t.Fatalf("json handler output = %q", buf.String())
+	}
+
+	buf.Reset()
+	slog.New(newHandler(&buf, " TEXT ")).Info("hello", "sandbox_id", "abc")
+	if !strings.Contains(buf.String(), "sandbox_id=abc") {
+		t.Fatalf("text handler output = %q", buf.String())
+	}
+}
+
+func TestRequestIDContext(t *testing.T) {
+	ctx := context.Background()
+	if got := RequestID(ctx); got != "" {
+		t.Fatalf("RequestID(empty) = %q", got)
+	}
+	if WithRequestID(ctx, "") != ctx {
+		t.Fatalf("WithRequestID with empty id should return ctx unchanged")
+	}
+	if got := RequestID(WithRequestID(ctx, "req-1")); got != "req-1" {
+		t.Fatalf("RequestID() = %q, want req-1", got)
+	}
 }
 
 func TestSetupInvalidPath(t *testing.T) {
-	if _, err := Setup(""); err == nil {
+	if _, err := Setup("", "json"); err == nil {
 		t.Fatalf("Setup(\"\") should return error")
 	}
 }
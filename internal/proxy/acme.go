@@ -0,0 +1,47 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// ACMEManager returns an autocert manager that obtains certificates for
+// <sandbox>.<baseDomain> on first use, via TLS-ALPN-01 on the HTTPS listener or
+// HTTP-01 when its HTTPHandler wraps a plain listener on port 80. Certificates
+// are cached in cacheDir. directoryURL selects the ACME server; empty = Let's Encrypt.
+func (s *Server) ACMEManager(email, cacheDir, directoryURL string) *autocert.Manager {
+	m := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		Cache:      autocert.DirCache(cacheDir),
+		Email:      email,
+		HostPolicy: s.hostPolicy,
+	}
+	if directoryURL != "" {
+		m.Client = &acme.Client{DirectoryURL: directoryURL}
+	}
+	return m
+}
+
+// hostPolicy only allows certificates for sandboxes and custom domains that
+// exist, so arbitrary SNI names cannot make the proxy request certificates and
+// exhaust rate limits.
+func (s *Server) hostPolicy(_ context.Context, host string) error {
+	name, _ := splitPort(s.extractSubdomain(host))
+	if name == "" {
+		if mapped, err := s.resolveDomain(host); err != nil || mapped != "" {
+			return err
+		}
+		return fmt.Errorf("acme: host %q is neither a sandbox subdomain of %s nor a custom domain", host, s.domain())
+	}
+	sb, err := s.repo.FindByName(name)
+	if err != nil {
+		return err
+	}
+	if sb == nil {
+		return fmt.Errorf("acme: sandbox %q not found", name)
+	}
+	return nil
+}
@@ -11,7 +11,8 @@ type cacheEntry struct {
 	expiresAt time.Time
 }
 
-// routeCache is a thread-safe in-memory cache mapping sandbox names to target URLs.
+// routeCache is a thread-safe in-memory cache mapping sandbox names to target
+// URLs. A zero ttl disables it.
 type routeCache struct {
 	mu  sync.RWMutex
 	m   map[string]cacheEntry
@@ -37,6 +38,9 @@ func (c *routeCache) get(name string) (*url.URL, bool) {
 }
 
 func (c *routeCache) set(name string, target *url.URL) {
+	if c.ttl <= 0 {
+		return // caching disabled
+	}
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -46,9 +50,14 @@ func (c *routeCache) set(name string, target *url.URL) {
 	}
 }
 
-// Invalidate removes a sandbox from the cache.
+// Invalidate removes a sandbox from the cache, including its per-port routes.
 func (c *routeCache) Invalidate(name string) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	delete(c.m, name)
+	for key := range c.m {
+		if n, _ := splitPort(key); n == name {
+			delete(c.m, key)
+		}
+	}
 }
@@ -0,0 +1,128 @@
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// Pages the proxy serves instead of forwarding a request. Each can be replaced
+// by an HTML template named after it (e.g. "stopped.html"), see SetPagesDir.
+const (
+	PageLoading     = "loading"     // the sandbox's ready check has not passed yet
+	PageStopped     = "stopped"     // the sandbox was stopped through the API
+	PageExpired     = "expired"     // the sandbox was stopped by its timeout
+	PageNotFound    = "not_found"   // no sandbox has that name
+	PageUnavailable = "unavailable" // the sandbox did not answer
+)
+
+// Errors returned by resolve for sandboxes that cannot be proxied to. They are
+// never cached, so traffic flows as soon as the sandbox is up again.
+var (
+	errStarting = errors.New("starting")
+	errStopped  = errors.New("stopped")
+	errExpired  = errors.New("expired")
+	errNotFound = errors.New("not found")
+)
+
+// PageData is passed to page templates.
+type PageData struct {
+	Name  string // sandbox name
+	Host  string // requested host
+	Error string // why the sandbox did not answer, only for PageUnavailable
+}
+
+// pageStatus is the status code each page is served with.
+var pageStatus = map[string]int{
+	PageLoading:     http.StatusServiceUnavailable,
+	PageStopped:     http.StatusServiceUnavailable,
+	PageExpired:     http.StatusServiceUnavailable,
+	PageNotFound:    http.StatusNotFound,
+	PageUnavailable: http.StatusBadGateway,
+}
+
+// defaultPageLayout renders the built-in pages from a title and a message.
+const defaultPageLayout = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+%s<title>%s</title>
+</head>
+<body style="font-family: sans-serif; text-align: center; margin-top: 20vh">
+<h1>%[2]s</h1>
+<p>%s</p>
+</body>
+</html>
+`
+
+// defaultPages holds the title and message of each built-in page.
+var defaultPages = map[string][2]string{
+	PageLoading:     {"Starting {{.Name}}&hellip;", "This page reloads when the sandbox is ready."},
+	PageStopped:     {"{{.Name}} is stopped", "Start the sandbox to see it here again."},
+	PageExpired:     {"{{.Name}} has expired", "The sandbox was stopped when its timeout ran out. Start it to see it here again."},
+	PageNotFound:    {"Sandbox not found", "No sandbox is called {{.Name}}."},
+	PageUnavailable: {"{{.Name}} is not responding", "The sandbox is running, but nothing answered on its port: {{.Error}}"},
+}
+
+// pageErrors maps resolve errors to the page served for them.
+var pageErrors = map[error]string{
+	errStarting: PageLoading,
+	errStopped:  PageStopped,
+	errExpired:  PageExpired,
+	errNotFound: PageNotFound,
+}
+
+// builtinPages parses the built-in pages. Only the loading page refreshes itself.
+func builtinPages() map[string]*template.Template {
+	pages := make(map[string]*template.Template, len(defaultPages))
+	for name, text := range defaultPages {
+		refresh := ""
+		if name == PageLoading {
+			refresh = `<meta http-equiv="refresh" content="2">` + "\n"
+		}
+		src := fmt.Sprintf(defaultPageLayout, refresh, text[0], text[1])
+		pages[name] = template.Must(template.New(name).Parse(src))
+	}
+	return pages
+}
+
+// SetPagesDir replaces built-in pages with the templates found in dir, one
+// html/template file per page named "<page>.html" (e.g. "not_found.html").
+// Templates receive a PageData. Pages without a file keep the built-in one.
+func (s *Server) SetPagesDir(dir string) error {
+	pages := builtinPages()
+	for name := range pages {
+		file := filepath.Join(dir, name+".html")
+		src, err := os.ReadFile(file)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		tmpl, err := template.New(name).Parse(string(src))
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		pages[name] = tmpl
+	}
+	s.pages = pages
+	return nil
+}
+
+// servePage answers a request with one of the proxy's pages.
+func (s *Server) servePage(w http.ResponseWriter, name string, data PageData) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	if name == PageLoading {
+		w.Header().Set("Retry-After", "2")
+	}
+	w.WriteHeader(pageStatus[name])
+	if err := s.pages[name].Execute(w, data); err != nil {
+		slog.Warn("proxy page failed", "page", name, "err", err)
+	}
+}
@@ -1,11 +1,15 @@
 package proxy
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"html/template"
+	"log/slog"
 	"net/http"
 	"net/http/httputil"
+	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"opensbx/internal/database"
@@ -13,40 +17,126 @@ import (
 
 // Server is a reverse proxy that routes HTTP requests based on subdomain.
 type Server struct {
+	mu         sync.RWMutex // guards baseDomain
 	baseDomain string
 	repo       *database.Repository
 	cache      *routeCache
+	onActivity func(name string) // called for every proxied request (idle timeouts)
+	wake       WakeFunc          // starts stopped sandboxes that opted in, nil = never
+	pages      map[string]*template.Template
+	upstream   string // host sandbox ports are published on
+
+	wsIdleTimeout time.Duration // close WebSockets without traffic for this long, 0 = never
+	wsMaxDuration time.Duration // close WebSockets open for this long, 0 = never
 }
 
 // New creates a proxy Server.
 func New(baseDomain string, repo *database.Repository) *Server {
 	return &Server{
-		baseDomain: baseDomain,
-		repo:       repo,
-		cache:      newRouteCache(30 * time.Second),
+		baseDomain:    baseDomain,
+		repo:          repo,
+		cache:         newRouteCache(defaultCacheTTL),
+		pages:         builtinPages(),
+		upstream:      "127.0.0.1",
+		wsIdleTimeout: defaultWSIdleTimeout,
+		wsMaxDuration: defaultWSMaxDuration,
 	}
 }
 
+// SetBaseDomain changes the base domain sandbox subdomains are routed under.
+// Safe to call while requests are served; open connections are kept.
+func (s *Server) SetBaseDomain(domain string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.baseDomain = domain
+}
+
+// domain returns the current base domain.
+func (s *Server) domain() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.baseDomain
+}
+
 // Handler returns the http.Handler for the proxy server.
 func (s *Server) Handler() http.Handler {
 	return http.HandlerFunc(s.handleRequest)
 }
 
+// defaultCacheTTL is how long a resolved route is cached, overridable with SetCacheTTL.
+const defaultCacheTTL = 30 * time.Second
+
+// SetCacheTTL sets how long resolved routes are cached. Zero disables the
+// cache, so every request looks its sandbox up. Must be called before serving.
+func (s *Server) SetCacheTTL(ttl time.Duration) {
+	s.cache = newRouteCache(ttl)
+}
+
 // InvalidateCache removes a sandbox entry from the route cache.
 func (s *Server) InvalidateCache(name string) {
 	s.cache.Invalidate(name)
 }
 
+// SetUpstreamHost sets the address the proxy dials sandbox host ports on. It
+// must match where Docker publishes them; the default is 127.0.0.1.
+func (s *Server) SetUpstreamHost(host string) {
+	s.upstream = host
+}
+
+// WakeFunc starts the stopped sandbox called name if it opted into wake on
+// request, and returns once it is ready. woke is false when it did not opt in.
+type WakeFunc func(ctx context.Context, name string) (woke bool, err error)
+
+// wakeTimeout bounds how long a request waits for its sandbox to wake up. A
+// sandbox still starting after that gets the loading page, which retries.
+const wakeTimeout = 30 * time.Second
+
+// SetWakeHook registers the callback that wakes stopped sandboxes on request.
+func (s *Server) SetWakeHook(fn WakeFunc) {
+	s.wake = fn
+}
+
+// SetActivityHook registers a callback invoked with the sandbox name for every
+// proxied request, so idle-mode sandboxes stay alive while serving traffic.
+func (s *Server) SetActivityHook(fn func(name string)) {
+	s.onActivity = fn
+}
+
 func (s *Server) handleRequest(w http.ResponseWriter, r *http.Request) {
-	name := s.extractSubdomain(r.Host)
-	if name == "" {
+	sub := s.extractSubdomain(r.Host)
+	if sub == "" {
+		// Not under the base domain: try a custom domain mapped to a sandbox.
+		var err error
+		sub, err = s.resolveDomain(r.Host)
+		if err != nil {
+			s.servePage(w, PageUnavailable, PageData{Host: r.Host, Error: fmt.Sprintf("domain %q: %v", r.Host, err)})
+			return
+		}
+	}
+	if sub == "" {
 		http.Error(w, "no subdomain in request", http.StatusBadGateway)
 		return
 	}
+	name, _ := splitPort(sub)
 
-	target, err := s.resolve(name)
+	target, err := s.resolve(sub)
+	if (err == errStopped || err == errExpired) && s.wake != nil {
+		target, err = s.wakeAndResolve(r, sub, name, err)
+	}
+	if page, ok := pageErrors[err]; ok {
+		s.servePage(w, page, PageData{Name: name, Host: r.Host})
+		return
+	}
 	if err != nil {
-		http.Error(w, fmt.Sprintf("sandbox %q: %v", name, err), http.StatusBadGateway)
+		s.servePage(w, PageUnavailable, PageData{Name: name, Host: r.Host, Error: err.Error()})
+		return
+	}
+	if s.onActivity != nil {
+		s.onActivity(name)
+	}
+
+	if isWebSocketUpgrade(r) {
+		s.proxyWebSocket(w, r, target, name)
 		return
 	}
 
@@ -57,14 +147,30 @@ func (s *Server) handleRequest(w http.ResponseWriter, r *http.Request) {
 		},
 		FlushInterval: -1, // stream immediately (SSE, WebSocket, HMR)
 		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
-			log.Printf("proxy error for %s: %v", name, err)
-			http.Error(w, "sandbox unavailable", http.StatusBadGateway)
+			slog.Warn("proxy error", "sandbox", name, "request_id", r.Header.Get("X-Request-ID"), "err", err)
+			s.servePage(w, PageUnavailable, PageData{Name: name, Host: r.Host, Error: "connection refused or reset"})
 		},
 	}
 
 	proxy.ServeHTTP(w, r)
 }
 
+// wakeAndResolve wakes a stopped sandbox and resolves it again. cause is
+// returned when the sandbox did not opt into waking up.
+func (s *Server) wakeAndResolve(r *http.Request, sub, name string, cause error) (*url.URL, error) {
+	ctx, cancel := context.WithTimeout(r.Context(), wakeTimeout)
+	defer cancel()
+	woke, err := s.wake(ctx, name)
+	if err != nil && ctx.Err() == nil {
+		slog.Warn("proxy wake failed", "sandbox", name, "err", err)
+		return nil, fmt.Errorf("wake: %w", err)
+	}
+	if err == nil && !woke {
+		return nil, cause
+	}
+	return s.resolve(sub)
+}
+
 // extractSubdomain extracts the sandbox name from the Host header.
 // "mi-app.localhost:3000" with baseDomain "localhost" → "mi-app"
 func (s *Server) extractSubdomain(host string) string {
@@ -74,7 +180,7 @@ func (s *Server) extractSubdomain(host string) string {
 		h = h[:idx]
 	}
 
-	suffix := "." + s.baseDomain
+	suffix := "." + s.domain()
 	if !strings.HasSuffix(h, suffix) {
 		return ""
 	}
@@ -1,11 +1,14 @@
 package proxy
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -65,7 +68,7 @@ func TestResolveHostPort(t *testing.T) {
 		Port:  "3000/tcp",
 		Ports: database.JSONMap{"3000/tcp": "32768"},
 	}
-	hp, err := resolveHostPort(sb)
+	hp, err := resolveHostPort(sb, "")
 	require.NoError(t, err)
 	assert.Equal(t, "32768", hp)
 
@@ -74,14 +77,14 @@ func TestResolveHostPort(t *testing.T) {
 		Port:  "9999/tcp",
 		Ports: database.JSONMap{"3000/tcp": "32768"},
 	}
-	_, err = resolveHostPort(sb2)
+	_, err = resolveHostPort(sb2, "")
 	assert.Error(t, err)
 
 	// no port configured, single port in map → auto-resolve
 	sb3 := &database.Sandbox{
 		Ports: database.JSONMap{"3000/tcp": "32768"},
 	}
-	hp3, err := resolveHostPort(sb3)
+	hp3, err := resolveHostPort(sb3, "")
 	require.NoError(t, err)
 	assert.Equal(t, "32768", hp3)
 
@@ -89,15 +92,43 @@ func TestResolveHostPort(t *testing.T) {
 	sb4 := &database.Sandbox{
 		Ports: database.JSONMap{"80/tcp": "32000", "443/tcp": "32001"},
 	}
-	_, err = resolveHostPort(sb4)
+	_, err = resolveHostPort(sb4, "")
 	assert.Error(t, err)
 
 	// no port configured, no ports at all → error
 	sb5 := &database.Sandbox{
 		Ports: database.JSONMap{},
 	}
-	_, err = resolveHostPort(sb5)
+	_, err = resolveHostPort(sb5, "")
 	assert.Error(t, err)
+
+	// explicit port → that port, even when it is not the main one
+	hp6, err := resolveHostPort(sb4, "443/tcp")
+	require.NoError(t, err)
+	assert.Equal(t, "32001", hp6)
+
+	// explicit port not exposed → error
+	_, err = resolveHostPort(sb4, "8080/tcp")
+	assert.Error(t, err)
+}
+
+func TestSplitPort(t *testing.T) {
+	tests := []struct {
+		sub, name, port string
+	}{
+		{"my-app", "my-app", ""},
+		{"my-app--8080", "my-app", "8080/tcp"},
+		{"my--app--3000", "my--app", "3000/tcp"},
+		{"my-app--web", "my-app--web", ""},
+		{"my-app--0", "my-app--0", ""},
+		{"my-app--70000", "my-app--70000", ""},
+		{"--8080", "--8080", ""},
+	}
+	for _, tt := range tests {
+		name, port := splitPort(tt.sub)
+		assert.Equal(t, tt.name, name, tt.sub)
+		assert.Equal(t, tt.port, port, tt.sub)
+	}
 }
 
 func TestRouteCache(t *testing.T) {
@@ -115,11 +146,17 @@ func TestRouteCache(t *testing.T) {
 	_, ok = c.get("other")
 	assert.False(t, ok)
 
-	// Invalidate
+	// Invalidate, including per-port routes
 	c.set("mi-app", target)
+	c.set("mi-app--8080", target)
+	c.set("mi-app-2", target)
 	c.Invalidate("mi-app")
 	_, ok = c.get("mi-app")
 	assert.False(t, ok)
+	_, ok = c.get("mi-app--8080")
+	assert.False(t, ok)
+	_, ok = c.get("mi-app-2")
+	assert.True(t, ok)
 
 	// Expire
 	c.set("mi-app", target)
@@ -128,6 +165,15 @@ func TestRouteCache(t *testing.T) {
 	assert.False(t, ok)
 }
 
+func TestRouteCache_Disabled(t *testing.T) {
+	c := newRouteCache(0)
+
+	target, _ := url.Parse("http://127.0.0.1:32768")
+	c.set("mi-app", target)
+	_, ok := c.get("mi-app")
+	assert.False(t, ok)
+}
+
 func TestProxy_NoSubdomain(t *testing.T) {
 	s := New("localhost", nil)
 	srv := httptest.NewServer(s.Handler())
@@ -158,7 +204,48 @@ func TestProxy_SandboxNotFound(t *testing.T) {
 	require.NoError(t, err)
 	defer resp.Body.Close()
 
-	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, "text/html; charset=utf-8", resp.Header.Get("Content-Type"))
+	body, _ := io.ReadAll(resp.Body)
+	assert.Contains(t, string(body), "No sandbox is called unknown.")
+}
+
+func TestProxy_Pages(t *testing.T) {
+	db := database.New(":memory:")
+	repo := database.NewRepository(db)
+	repo.Save(database.Sandbox{ID: "a", Name: "stopped-app", Ports: database.JSONMap{"3000/tcp": "1"}, State: database.SandboxStopped})
+	repo.Save(database.Sandbox{ID: "b", Name: "expired-app", Ports: database.JSONMap{"3000/tcp": "1"}, State: database.SandboxExpired})
+	repo.Save(database.Sandbox{ID: "c", Name: "dead-app", Ports: database.JSONMap{"3000/tcp": "1"}, State: database.SandboxRunning})
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "expired.html"), []byte("<p>{{.Name}} on {{.Host}} timed out</p>"), 0o644))
+
+	s := New("localhost", repo)
+	require.NoError(t, s.SetPagesDir(dir))
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	for _, tc := range []struct {
+		host   string
+		status int
+		body   string
+	}{
+		{"stopped-app.localhost", http.StatusServiceUnavailable, "stopped-app is stopped"},
+		{"expired-app.localhost", http.StatusServiceUnavailable, "<p>expired-app on expired-app.localhost timed out</p>"},
+		{"dead-app.localhost", http.StatusBadGateway, "dead-app is not responding"},
+	} {
+		req, _ := http.NewRequest("GET", srv.URL+"/", nil)
+		req.Host = tc.host
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, tc.status, resp.StatusCode, tc.host)
+		assert.Contains(t, string(body), tc.body, tc.host)
+	}
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "stopped.html"), []byte("{{.Nope"), 0o644))
+	assert.Error(t, s.SetPagesDir(dir))
 }
 
 func TestProxy_EndToEnd(t *testing.T) {
@@ -184,6 +271,8 @@ func TestProxy_EndToEnd(t *testing.T) {
 
 	// Create proxy server.
 	s := New("localhost", repo)
+	var active []string
+	s.SetActivityHook(func(name string) { active = append(active, name) })
 	proxySrv := httptest.NewServer(s.Handler())
 	defer proxySrv.Close()
 
@@ -198,6 +287,142 @@ func TestProxy_EndToEnd(t *testing.T) {
 	assert.Equal(t, http.StatusOK, resp.StatusCode)
 	body, _ := io.ReadAll(resp.Body)
 	assert.Equal(t, "hello from sandbox", string(body))
+	assert.Equal(t, []string{"mi-app"}, active)
+}
+
+func TestProxy_Starting(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ready"))
+	}))
+	defer backend.Close()
+	u, _ := url.Parse(backend.URL)
+
+	db := database.New(":memory:")
+	repo := database.NewRepository(db)
+	repo.Save(database.Sandbox{
+		ID:    "test123",
+		Name:  "mi-app",
+		Ports: database.JSONMap{"3000/tcp": u.Port()},
+		Port:  "3000/tcp",
+		Ready: "starting",
+	})
+
+	s := New("localhost", repo)
+	proxySrv := httptest.NewServer(s.Handler())
+	defer proxySrv.Close()
+
+	get := func() *http.Response {
+		req, _ := http.NewRequest("GET", proxySrv.URL+"/", nil)
+		req.Host = "mi-app.localhost"
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	resp := get()
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, "2", resp.Header.Get("Retry-After"))
+	assert.Contains(t, string(body), "Starting mi-app")
+
+	// The starting state is not cached: traffic flows once the check passes.
+	require.NoError(t, repo.UpdateReady("test123", "ready"))
+	resp = get()
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "ready", string(body))
+}
+
+func TestProxy_Wake(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("awake"))
+	}))
+	defer backend.Close()
+	u, _ := url.Parse(backend.URL)
+
+	db := database.New(":memory:")
+	repo := database.NewRepository(db)
+	repo.Save(database.Sandbox{ID: "a", Name: "sleepy", Ports: database.JSONMap{"3000/tcp": u.Port()}, State: database.SandboxExpired})
+	repo.Save(database.Sandbox{ID: "b", Name: "off", Ports: database.JSONMap{"3000/tcp": u.Port()}, State: database.SandboxStopped})
+
+	s := New("localhost", repo)
+	var woken []string
+	s.SetWakeHook(func(_ context.Context, name string) (bool, error) {
+		if name != "sleepy" {
+			return false, nil
+		}
+		woken = append(woken, name)
+		return true, repo.UpdateState("a", database.SandboxRunning)
+	})
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	get := func(host string) (int, string) {
+		req, _ := http.NewRequest("GET", srv.URL+"/", nil)
+		req.Host = host
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	status, body := get("sleepy.localhost")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "awake", body)
+	assert.Equal(t, []string{"sleepy"}, woken)
+
+	status, body = get("off.localhost")
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Contains(t, body, "off is stopped")
+}
+
+func TestProxy_PortSubdomain(t *testing.T) {
+	mainSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("main"))
+	}))
+	defer mainSrv.Close()
+	apiSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("api"))
+	}))
+	defer apiSrv.Close()
+	um, _ := url.Parse(mainSrv.URL)
+	ua, _ := url.Parse(apiSrv.URL)
+
+	repo := database.NewRepository(database.New(":memory:"))
+	repo.Save(database.Sandbox{
+		ID:    "test123",
+		Name:  "mi-app",
+		Ports: database.JSONMap{"3000/tcp": um.Port(), "8080/tcp": ua.Port()},
+		Port:  "3000/tcp",
+	})
+
+	s := New("localhost", repo)
+	var active []string
+	s.SetActivityHook(func(name string) { active = append(active, name) })
+	proxySrv := httptest.NewServer(s.Handler())
+	defer proxySrv.Close()
+
+	get := func(host string) (int, string) {
+		req, _ := http.NewRequest("GET", proxySrv.URL+"/", nil)
+		req.Host = host
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	_, body := get("mi-app.localhost:3000")
+	assert.Equal(t, "main", body)
+	_, body = get("mi-app--8080.localhost:3000")
+	assert.Equal(t, "api", body)
+	code, body := get("mi-app--9000.localhost:3000")
+	assert.Equal(t, http.StatusBadGateway, code)
+	assert.Contains(t, body, "not exposed")
+	assert.Equal(t, []string{"mi-app", "mi-app"}, active)
 }
 
 func TestProxy_CacheInvalidation(t *testing.T) {
@@ -296,3 +521,70 @@ func TestProxy_UpgradeHeaderForwarding(t *testing.T) {
 	assert.Equal(t, "websocket", receivedUpgrade)
 	assert.Contains(t, receivedConnection, "Upgrade")
 }
+
+func TestACMEHostPolicy(t *testing.T) {
+	repo := database.NewRepository(database.New(":memory:"))
+	require.NoError(t, repo.Save(database.Sandbox{ID: "c1", Name: "my-app"}))
+
+	s := New("sandbox.example.com", repo)
+	m := s.ACMEManager("ops@example.com", t.TempDir(), "")
+	ctx := t.Context()
+
+	assert.NoError(t, m.HostPolicy(ctx, "my-app.sandbox.example.com"))
+	assert.Error(t, m.HostPolicy(ctx, "other.sandbox.example.com"))
+	assert.Error(t, m.HostPolicy(ctx, "sandbox.example.com"))
+	assert.Error(t, m.HostPolicy(ctx, "my-app.evil.com"))
+
+	require.NoError(t, repo.SaveDomain(database.Domain{Host: "app.customer.com", SandboxID: "c1"}))
+	assert.NoError(t, m.HostPolicy(ctx, "app.customer.com"))
+}
+
+func TestProxy_CustomDomain(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("custom:" + r.Host))
+	}))
+	defer backend.Close()
+	u, _ := url.Parse(backend.URL)
+
+	repo := database.NewRepository(database.New(":memory:"))
+	require.NoError(t, repo.Save(database.Sandbox{
+		ID:    "full-id",
+		Name:  "mi-app",
+		Ports: database.JSONMap{"3000/tcp": u.Port()},
+		Port:  "3000/tcp",
+	}))
+	require.NoError(t, repo.SaveDomain(database.Domain{Host: "app.customer.com", SandboxID: "full-id"}))
+
+	s := New("localhost", repo)
+	var active []string
+	s.SetActivityHook(func(name string) { active = append(active, name) })
+	proxySrv := httptest.NewServer(s.Handler())
+	defer proxySrv.Close()
+
+	get := func(host string) (int, string) {
+		req, _ := http.NewRequest("GET", proxySrv.URL+"/", nil)
+		req.Host = host
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	code, body := get("App.Customer.com:3000")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "custom:App.Customer.com:3000", body)
+	assert.Equal(t, []string{"mi-app"}, active)
+
+	code, _ = get("unknown.customer.com")
+	assert.Equal(t, http.StatusBadGateway, code)
+}
+
+func TestSetBaseDomain(t *testing.T) {
+	s := New("localhost", nil)
+	assert.Equal(t, "mi-app", s.extractSubdomain("mi-app.localhost:3000"))
+
+	s.SetBaseDomain("sandbox.example.com")
+	assert.Equal(t, "", s.extractSubdomain("mi-app.localhost:3000"))
+	assert.Equal(t, "mi-app", s.extractSubdomain("mi-app.sandbox.example.com"))
+}
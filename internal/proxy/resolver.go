@@ -2,17 +2,42 @@ package proxy
 
 import (
 	"fmt"
+	"net"
 	"net/url"
+	"strconv"
+	"strings"
 
 	"opensbx/internal/database"
 )
 
-// resolve looks up the sandbox by name and returns the target URL (http://127.0.0.1:{hostPort}).
-func (s *Server) resolve(name string) (*url.URL, error) {
+// portSeparator splits a sandbox name from a container port in a subdomain,
+// e.g. "my-app--8080" routes to port 8080 of sandbox "my-app".
+const portSeparator = "--"
+
+// splitPort splits "name--port" into the sandbox name and container port
+// ("8080/tcp"). Subdomains without a numeric port suffix return port "".
+func splitPort(sub string) (name, port string) {
+	idx := strings.LastIndex(sub, portSeparator)
+	if idx <= 0 {
+		return sub, ""
+	}
+	n, err := strconv.Atoi(sub[idx+len(portSeparator):])
+	if err != nil || n < 1 || n > 65535 {
+		return sub, ""
+	}
+	return sub[:idx], strconv.Itoa(n) + "/tcp"
+}
+
+// resolve looks up the sandbox for a subdomain and returns the target URL
+// (http://{upstream}:{hostPort}). "name" routes to the sandbox's main port and
+// "name--port" to another exposed port. Sandboxes that are stopped, expired or
+// still starting return the error of the page to show instead.
+func (s *Server) resolve(sub string) (*url.URL, error) {
 	// Check cache first.
-	if target, ok := s.cache.get(name); ok {
+	if target, ok := s.cache.get(sub); ok {
 		return target, nil
 	}
+	name, port := splitPort(sub)
 
 	// DB lookup.
 	sb, err := s.repo.FindByName(name)
@@ -20,27 +45,72 @@ func (s *Server) resolve(name string) (*url.URL, error) {
 		return nil, fmt.Errorf("lookup failed: %w", err)
 	}
 	if sb == nil {
-		return nil, fmt.Errorf("not found")
+		return nil, errNotFound
+	}
+	switch {
+	case sb.State == database.SandboxStopped:
+		return nil, errStopped
+	case sb.State == database.SandboxExpired:
+		return nil, errExpired
+	case sb.Ready == "starting":
+		return nil, errStarting
 	}
 
-	// Resolve the host port for the main port.
-	hostPort, err := resolveHostPort(sb)
+	// Resolve the host port for the requested or main port.
+	hostPort, err := resolveHostPort(sb, port)
 	if err != nil {
 		return nil, err
 	}
 
 	target := &url.URL{
 		Scheme: "http",
-		Host:   "127.0.0.1:" + hostPort,
+		Host:   net.JoinHostPort(s.upstream, hostPort),
 	}
 
-	s.cache.set(name, target)
+	s.cache.set(sub, target)
 	return target, nil
 }
 
-// resolveHostPort returns the Docker-assigned host port for the sandbox's port.
-// If Port is not set but there is exactly one port in the map, it uses that.
-func resolveHostPort(sb *database.Sandbox) (string, error) {
+// resolveDomain returns the name of the sandbox a custom domain is mapped to,
+// or "" if host is not mapped. Custom domains always route to the main port.
+func (s *Server) resolveDomain(host string) (string, error) {
+	if s.repo == nil {
+		return "", nil
+	}
+	h := strings.ToLower(host)
+	if idx := strings.LastIndex(h, ":"); idx != -1 {
+		h = h[:idx]
+	}
+	h = strings.TrimSuffix(h, ".")
+
+	d, err := s.repo.FindDomain(h)
+	if err != nil {
+		return "", fmt.Errorf("lookup failed: %w", err)
+	}
+	if d == nil {
+		return "", nil
+	}
+	sb, err := s.repo.FindByID(d.SandboxID)
+	if err != nil {
+		return "", fmt.Errorf("lookup failed: %w", err)
+	}
+	if sb == nil || sb.Name == "" {
+		return "", fmt.Errorf("sandbox not found")
+	}
+	return sb.Name, nil
+}
+
+// resolveHostPort returns the Docker-assigned host port for port, or for the
+// sandbox's main port when port is empty. If Port is not set but there is
+// exactly one port in the map, it uses that.
+func resolveHostPort(sb *database.Sandbox, port string) (string, error) {
+	if port != "" {
+		hp, ok := sb.Ports[port]
+		if !ok {
+			return "", fmt.Errorf("port %q is not exposed", port)
+		}
+		return hp, nil
+	}
 	if sb.Port != "" {
 		hp, ok := sb.Ports[sb.Port]
 		if !ok {
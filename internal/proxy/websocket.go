@@ -0,0 +1,187 @@
+package proxy
+
+import (
+	"bufio"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Default WebSocket limits, overridable with SetWebSocketLimits.
+const (
+	defaultWSIdleTimeout = 30 * time.Minute
+	defaultWSMaxDuration = 24 * time.Hour
+	wsDialTimeout        = 10 * time.Second
+)
+
+// SetWebSocketLimits sets how long a proxied WebSocket may stay without traffic
+// in either direction (idle) and how long it may stay open at all (maxDuration).
+// Zero disables the corresponding limit.
+func (s *Server) SetWebSocketLimits(idle, maxDuration time.Duration) {
+	s.wsIdleTimeout = idle
+	s.wsMaxDuration = maxDuration
+}
+
+// isWebSocketUpgrade reports whether r asks to switch to the WebSocket protocol.
+func isWebSocketUpgrade(r *http.Request) bool {
+	return headerHasToken(r.Header, "Connection", "upgrade") &&
+		strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
+
+func headerHasToken(h http.Header, key, token string) bool {
+	for _, v := range h.Values(key) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// proxyWebSocket forwards the handshake to target and, once the backend
+// answers 101 Switching Protocols, hijacks the client connection and copies
+// bytes both ways until either side closes or a limit is reached. Any other
+// backend answer is relayed as a normal HTTP response.
+func (s *Server) proxyWebSocket(w http.ResponseWriter, r *http.Request, target *url.URL, name string) {
+	backend, err := net.DialTimeout("tcp", target.Host, wsDialTimeout)
+	if err != nil {
+		slog.Warn("proxy websocket dial failed", "sandbox", name, "err", err)
+		http.Error(w, "sandbox unavailable", http.StatusBadGateway)
+		return
+	}
+
+	out := r.Clone(r.Context())
+	out.URL = &url.URL{Path: r.URL.Path, RawPath: r.URL.RawPath, RawQuery: r.URL.RawQuery}
+	out.RequestURI = ""
+	if err := out.Write(backend); err != nil {
+		backend.Close()
+		http.Error(w, "sandbox unavailable", http.StatusBadGateway)
+		return
+	}
+
+	backendBuf := bufio.NewReader(backend)
+	resp, err := http.ReadResponse(backendBuf, out)
+	if err != nil {
+		backend.Close()
+		http.Error(w, "sandbox unavailable", http.StatusBadGateway)
+		return
+	}
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		defer backend.Close()
+		defer resp.Body.Close()
+		for k, vv := range resp.Header {
+			for _, v := range vv {
+				w.Header().Add(k, v)
+			}
+		}
+		w.WriteHeader(resp.StatusCode)
+		io.Copy(w, resp.Body)
+		return
+	}
+
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		backend.Close()
+		http.Error(w, "websocket not supported", http.StatusInternalServerError)
+		return
+	}
+	client, clientBuf, err := hj.Hijack()
+	if err != nil {
+		backend.Close()
+		return
+	}
+
+	if err := resp.Write(client); err != nil {
+		client.Close()
+		backend.Close()
+		return
+	}
+
+	s.pipeWebSocket(client, clientBuf.Reader, backend, backendBuf, name)
+}
+
+// pipeWebSocket copies bytes between client and backend, including anything
+// already buffered on either side, and closes both when one side finishes, no
+// traffic flows for the idle timeout, or the maximum duration is reached.
+// Traffic counts as sandbox activity for idle-mode timeouts.
+func (s *Server) pipeWebSocket(client net.Conn, clientR io.Reader, backend net.Conn, backendR io.Reader, name string) {
+	var lastActive atomic.Int64
+	lastActive.Store(time.Now().UnixNano())
+
+	var once sync.Once
+	done := make(chan struct{})
+	closeBoth := func() {
+		once.Do(func() {
+			close(done)
+			client.Close()
+			backend.Close()
+		})
+	}
+
+	copyConn := func(dst net.Conn, src io.Reader) {
+		defer closeBoth()
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := src.Read(buf)
+			if n > 0 {
+				lastActive.Store(time.Now().UnixNano())
+				if _, werr := dst.Write(buf[:n]); werr != nil {
+					return
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}
+	go copyConn(backend, clientR)
+	go copyConn(client, backendR)
+
+	s.watchWebSocket(done, &lastActive, closeBoth, name)
+}
+
+// watchWebSocket enforces the idle and max-duration limits and reports
+// activity until done is closed.
+func (s *Server) watchWebSocket(done <-chan struct{}, lastActive *atomic.Int64, closeBoth func(), name string) {
+	started := time.Now()
+	tick := time.Second
+	if s.wsIdleTimeout > 0 && s.wsIdleTimeout < 4*tick {
+		tick = s.wsIdleTimeout / 4
+	}
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	reported := lastActive.Load()
+	for {
+		select {
+		case <-done:
+			return
+		case now := <-ticker.C:
+			last := lastActive.Load()
+			if last != reported {
+				reported = last
+				if s.onActivity != nil {
+					s.onActivity(name)
+				}
+			}
+			if s.wsIdleTimeout > 0 && now.Sub(time.Unix(0, last)) >= s.wsIdleTimeout {
+				slog.Info("proxy websocket idle timeout", "sandbox", name)
+				closeBoth()
+				return
+			}
+			if s.wsMaxDuration > 0 && now.Sub(started) >= s.wsMaxDuration {
+				slog.Info("proxy websocket max duration reached", "sandbox", name)
+				closeBoth()
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package proxy
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"opensbx/internal/database"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newWSProxy starts a WebSocket echo backend registered as sandbox "ws-app"
+// and a proxy in front of it. It returns the proxy's ws:// URL.
+func newWSProxy(t *testing.T, configure func(*Server)) string {
+	t.Helper()
+	upgrader := websocket.Upgrader{}
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			mt, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteMessage(mt, append([]byte(r.URL.Path+":"), msg...)); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(backend.Close)
+	u, _ := url.Parse(backend.URL)
+
+	repo := database.NewRepository(database.New(":memory:"))
+	require.NoError(t, repo.Save(database.Sandbox{
+		ID:    "ws-test",
+		Name:  "ws-app",
+		Ports: database.JSONMap{"3000/tcp": u.Port()},
+		Port:  "3000/tcp",
+	}))
+
+	s := New("localhost", repo)
+	if configure != nil {
+		configure(s)
+	}
+	proxySrv := httptest.NewServer(s.Handler())
+	t.Cleanup(proxySrv.Close)
+	return "ws" + strings.TrimPrefix(proxySrv.URL, "http")
+}
+
+func dialWS(t *testing.T, wsURL string) *websocket.Conn {
+	t.Helper()
+	conn, resp, err := websocket.DefaultDialer.Dial(wsURL+"/hmr", http.Header{"Host": {"ws-app.localhost:3000"}})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestProxy_WebSocketEcho(t *testing.T) {
+	var activity atomic.Int32
+	wsURL := newWSProxy(t, func(s *Server) {
+		s.SetActivityHook(func(string) { activity.Add(1) })
+	})
+	conn := dialWS(t, wsURL)
+
+	for _, msg := range []string{"hello", "world"} {
+		require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(msg)))
+		_, got, err := conn.ReadMessage()
+		require.NoError(t, err)
+		assert.Equal(t, "/hmr:"+msg, string(got))
+	}
+	assert.GreaterOrEqual(t, activity.Load(), int32(1))
+}
+
+func TestProxy_WebSocketIdleTimeout(t *testing.T) {
+	wsURL := newWSProxy(t, func(s *Server) {
+		s.SetWebSocketLimits(200*time.Millisecond, 0)
+	})
+	conn := dialWS(t, wsURL)
+
+	conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	_, _, err := conn.ReadMessage()
+	require.Error(t, err)
+	var netErr net.Error
+	assert.False(t, errors.As(err, &netErr) && netErr.Timeout(), "connection should be closed by the proxy, got %v", err)
+}
+
+func TestProxy_WebSocketMaxDuration(t *testing.T) {
+	wsURL := newWSProxy(t, func(s *Server) {
+		s.SetWebSocketLimits(0, 300*time.Millisecond)
+	})
+	conn := dialWS(t, wsURL)
+
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte("ping")); err != nil {
+			return
+		}
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		if _, _, err := conn.ReadMessage(); err != nil {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatalf("websocket still open after max duration")
+}
+
+func TestIsWebSocketUpgrade(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	assert.False(t, isWebSocketUpgrade(r))
+
+	r.Header.Set("Connection", "keep-alive, Upgrade")
+	r.Header.Set("Upgrade", "WebSocket")
+	assert.True(t, isWebSocketUpgrade(r))
+
+	r.Header.Set("Upgrade", "h2c")
+	assert.False(t, isWebSocketUpgrade(r))
+}
@@ -0,0 +1,154 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"opensbx/internal/database"
+	"opensbx/models"
+)
+
+// ErrNotFound is returned when an owner has no secret with the given name.
+var ErrNotFound = errors.New("secret not found")
+
+// ErrInvalidName is returned when a secret name is not a valid environment variable name.
+var ErrInvalidName = errors.New("invalid secret name")
+
+// namePattern matches environment variable names, since secrets are injected
+// as variables of the same name.
+var namePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,127}$`)
+
+// Store manages secrets persisted in the database. Values are encrypted with
+// AES-256-GCM under a key derived from the server's SECRETS_KEY and are bound
+// to their owner and name, so a row copied to another name does not decrypt.
+type Store struct {
+	repo *database.Repository
+	aead cipher.AEAD
+}
+
+// New creates a Store backed by the given repository, encrypting with a key
+// derived from passphrase.
+func New(repo *database.Repository, passphrase string) (*Store, error) {
+	if passphrase == "" {
+		return nil, errors.New("secrets: empty encryption key")
+	}
+	key := sha256.Sum256([]byte(passphrase))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Store{repo: repo, aead: aead}, nil
+}
+
+// Put creates or replaces an owner's secret. The value is never returned again.
+func (s *Store) Put(owner, name, value string) (models.Secret, error) {
+	if !namePattern.MatchString(name) {
+		return models.Secret{}, fmt.Errorf("%w %q: use letters, digits and underscores, not starting with a digit", ErrInvalidName, name)
+	}
+	existing, err := s.repo.FindSecret(owner, name)
+	if err != nil {
+		return models.Secret{}, err
+	}
+	now := time.Now().UnixMilli()
+	rec := database.Secret{Owner: owner, Name: name, CreatedAt: now, UpdatedAt: now}
+	if existing != nil {
+		rec.CreatedAt = existing.CreatedAt
+	}
+	if rec.Value, err = s.seal(owner, name, value); err != nil {
+		return models.Secret{}, err
+	}
+	if err := s.repo.SaveSecret(rec); err != nil {
+		return models.Secret{}, err
+	}
+	return toModel(rec), nil
+}
+
+// List returns an owner's secrets, without their values.
+func (s *Store) List(owner string) ([]models.Secret, error) {
+	recs, err := s.repo.FindSecretsByOwner(owner)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]models.Secret, 0, len(recs))
+	for _, rec := range recs {
+		out = append(out, toModel(rec))
+	}
+	return out, nil
+}
+
+// Delete removes an owner's secret. Returns ErrNotFound if it does not exist.
+func (s *Store) Delete(owner, name string) error {
+	ok, err := s.repo.DeleteSecret(owner, name)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Resolve returns the values of an owner's secrets by name. Returns
+// ErrNotFound naming the first secret that does not exist.
+func (s *Store) Resolve(owner string, names []string) (map[string]string, error) {
+	values := make(map[string]string, len(names))
+	for _, name := range names {
+		rec, err := s.repo.FindSecret(owner, name)
+		if err != nil {
+			return nil, err
+		}
+		if rec == nil {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+		}
+		v, err := s.open(owner, name, rec.Value)
+		if err != nil {
+			return nil, fmt.Errorf("secret %s: %w", name, err)
+		}
+		values[name] = v
+	}
+	return values, nil
+}
+
+// seal encrypts value as nonce followed by ciphertext.
+func (s *Store) seal(owner, name, value string) ([]byte, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return s.aead.Seal(nonce, nonce, []byte(value), additionalData(owner, name)), nil
+}
+
+// open decrypts a value sealed by seal. It fails if SECRETS_KEY changed.
+func (s *Store) open(owner, name string, sealed []byte) (string, error) {
+	n := s.aead.NonceSize()
+	if len(sealed) < n {
+		return "", errors.New("malformed ciphertext")
+	}
+	plain, err := s.aead.Open(nil, sealed[:n], sealed[n:], additionalData(owner, name))
+	if err != nil {
+		return "", errors.New("cannot decrypt, was SECRETS_KEY changed?")
+	}
+	return string(plain), nil
+}
+
+func additionalData(owner, name string) []byte {
+	return []byte(owner + "\x00" + name)
+}
+
+func toModel(rec database.Secret) models.Secret {
+	return models.Secret{
+		Name:      rec.Name,
+		CreatedAt: time.UnixMilli(rec.CreatedAt),
+		UpdatedAt: time.UnixMilli(rec.UpdatedAt),
+	}
+}
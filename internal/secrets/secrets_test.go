@@ -0,0 +1,73 @@
+package secrets
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"opensbx/internal/database"
+)
+
+func newTestStore(t *testing.T) (*Store, *database.Repository) {
+	t.Helper()
+	repo := database.NewRepository(database.New(":memory:"))
+	s, err := New(repo, "test-key")
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	return s, repo
+}
+
+func TestStoreLifecycle(t *testing.T) {
+	s, repo := newTestStore(t)
+
+	if _, err := s.Put("team-a", "OPENAI_API_KEY", "sk-one"); err != nil {
+		t.Fatalf("Put() error: %v", err)
+	}
+	rec, _ := repo.FindSecret("team-a", "OPENAI_API_KEY")
+	if rec == nil || bytes.Contains(rec.Value, []byte("sk-one")) {
+		t.Fatalf("stored value is not encrypted: %+v", rec)
+	}
+
+	if _, err := s.Put("team-a", "OPENAI_API_KEY", "sk-two"); err != nil {
+		t.Fatalf("Put() replace error: %v", err)
+	}
+	values, err := s.Resolve("team-a", []string{"OPENAI_API_KEY"})
+	if err != nil || values["OPENAI_API_KEY"] != "sk-two" {
+		t.Fatalf("Resolve() = %v, %v", values, err)
+	}
+
+	if _, err := s.Resolve("team-b", []string{"OPENAI_API_KEY"}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Resolve() for another owner err = %v, want ErrNotFound", err)
+	}
+	if list, _ := s.List("team-a"); len(list) != 1 || list[0].Name != "OPENAI_API_KEY" {
+		t.Fatalf("List() = %+v", list)
+	}
+
+	if err := s.Delete("team-a", "OPENAI_API_KEY"); err != nil {
+		t.Fatalf("Delete() error: %v", err)
+	}
+	if err := s.Delete("team-a", "OPENAI_API_KEY"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("second Delete() err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestStoreRejectsInvalidNames(t *testing.T) {
+	s, _ := newTestStore(t)
+	for _, name := range []string{"", "1KEY", "A-B", "A=B"} {
+		if _, err := s.Put("", name, "v"); !errors.Is(err, ErrInvalidName) {
+			t.Fatalf("Put(%q) err = %v, want ErrInvalidName", name, err)
+		}
+	}
+}
+
+func TestStoreWrongKey(t *testing.T) {
+	s, repo := newTestStore(t)
+	if _, err := s.Put("", "TOKEN", "v"); err != nil {
+		t.Fatalf("Put() error: %v", err)
+	}
+	other, _ := New(repo, "other-key")
+	if _, err := other.Resolve("", []string{"TOKEN"}); err == nil {
+		t.Fatal("Resolve() with another key succeeded")
+	}
+}
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// APIKey describes a stored API key. The secret itself is only returned on creation.
+type APIKey struct {
+	ID        string     `json:"id" example:"key_1a2b3c4d5e6f"`
+	Name      string     `json:"name" example:"ci"`
+	Owner     string     `json:"owner" example:"team-a"`        // tenant whose sandboxes the key can access (admin keys see all)
+	Prefix    string     `json:"prefix" example:"osbx_1a2b3c4"` // first characters of the key, to recognise it
+	Scopes    []string   `json:"scopes" example:"read-only,exec"`
+	Quota     *Quota     `json:"quota,omitempty"` // limits for the key's owner, nil = only global limits
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// CreateAPIKeyRequest is the body for POST /v1/admin/keys
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name" binding:"required" example:"ci"`
+	Owner  string   `json:"owner" example:"team-a"`                                   // keys with the same owner share sandboxes; empty = a new owner for this key
+	Scopes []string `json:"scopes" binding:"required,min=1" example:"read-only,exec"` // read-only, exec, images, admin
+	Quota  *Quota   `json:"quota"`                                                    // limits for the key's owner, nil = only global limits
+}
+
+// CreateAPIKeyResponse is the response for POST /v1/admin/keys
+type CreateAPIKeyResponse struct {
+	APIKey
+	Key string `json:"key" example:"osbx_1a2b3c4d5e6f..."` // the secret, shown only once
+}
+
+// Quota caps what an owner (or the whole deployment) may run at once.
+// Zero values mean unlimited.
+type Quota struct {
+	MaxSandboxes int     `json:"max_sandboxes,omitempty" example:"5"` // running sandboxes
+	MaxMemory    int64   `json:"max_memory,omitempty" example:"4096"` // total memory of running sandboxes, in MB
+	MaxCPUs      float64 `json:"max_cpus,omitempty" example:"4"`      // total CPUs of running sandboxes
+}
+
+// QuotaUsage is what counts against a Quota: running sandboxes and their limits.
+type QuotaUsage struct {
+	Sandboxes int     `json:"sandboxes"`
+	Memory    int64   `json:"memory"` // MB
+	CPUs      float64 `json:"cpus"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// AuditEvent records a mutating operation: who did it, what, when and on which sandbox.
+type AuditEvent struct {
+	ID        uint      `json:"id" example:"42"`
+	Time      time.Time `json:"time"`
+	Action    string    `json:"action" example:"sandbox.create"`             // e.g. sandbox.create, command.exec, file.write
+	SandboxID string    `json:"sandbox_id,omitempty" example:"a1b2c3d4e5f6"` // full container ID of the sandbox
+	Auth      string    `json:"auth" example:"api_key"`                      // "api_key", "signature" or "none"
+	KeyID     string    `json:"key_id,omitempty" example:"key_1a2b3c4d5e6f"` // stored API key used, empty for API_KEY and signatures
+	Owner     string    `json:"owner,omitempty" example:"team-a"`            // owner the caller was restricted to
+	Actor     string    `json:"actor,omitempty" example:"alice"`             // X-Opensbx-Actor header, if sent
+	Method    string    `json:"method" example:"POST"`
+	Path      string    `json:"path" example:"/v1/sandboxes/a1b2c3d4e5f6/stop"`
+	Status    int       `json:"status" example:"200"`                                // HTTP status of the response
+	RequestID string    `json:"request_id,omitempty" example:"req_1a2b3c4d5e6f7a8b"` // X-Request-ID, to correlate with logs
+}
+
+// AuditQuery filters GET /v1/audit. Zero values match everything.
+type AuditQuery struct {
+	SandboxID string    `form:"sandbox_id"`
+	Action    string    `form:"action"`
+	Since     time.Time `form:"since" time_format:"2006-01-02T15:04:05Z07:00"` // RFC 3339, inclusive
+	Until     time.Time `form:"until" time_format:"2006-01-02T15:04:05Z07:00"` // RFC 3339, exclusive
+	Limit     int       `form:"limit"`                                         // default 100, max 1000
+}
@@ -0,0 +1,18 @@
+package models
+
+// RunCodeRequest is the body for POST /v1/run.
+type RunCodeRequest struct {
+	Language string `json:"language" binding:"required" enums:"python,node,go,ruby,bash" example:"python"`
+	Code     string `json:"code" binding:"required" example:"print(input().upper())"` // program source, written to a file and run as the language's main program
+	Stdin    string `json:"stdin,omitempty" example:"hello\n"`                        // written to the program's stdin, which is then closed
+	Timeout  int    `json:"timeout,omitempty" example:"30"`                           // seconds before the program is killed, 0 = default (60s)
+}
+
+// RunCodeResponse is the response for POST /v1/run.
+type RunCodeResponse struct {
+	Language string `json:"language" example:"python"`
+	ExitCode int    `json:"exit_code" example:"0"`
+	TimedOut bool   `json:"timed_out,omitempty"` // the program was killed after exceeding its timeout
+	Stdout   string `json:"stdout" example:"HELLO\n"`
+	Stderr   string `json:"stderr"`
+}
@@ -0,0 +1,27 @@
+package models
+
+// GitSource is a repository cloned into a sandbox while it is created, after
+// the startup command started and before processes and the ready check run.
+// The image must provide git (2.31 or later for token auth).
+type GitSource struct {
+	URL    string `json:"url" binding:"required" example:"https://github.com/acme/web.git"` // https://, ssh:// or git@host:path; put credentials in token or ssh_key, not in the URL
+	Ref    string `json:"ref,omitempty" example:"main"`                                     // branch, tag or commit to check out, empty = the default branch
+	Dir    string `json:"dir,omitempty" example:"/app"`                                     // absolute directory to clone into, default working_dir or /workspace; must be empty or missing
+	Depth  int    `json:"depth,omitempty" example:"1"`                                      // commits of history to fetch, 0 = all. Ignored when ref is a commit
+	Token  string `json:"token,omitempty"`                                                  // HTTPS access token, e.g. a GitHub personal access token; sent as a header, never stored in the sandbox
+	SSHKey string `json:"ssh_key,omitempty"`                                                // private deploy key for ssh URLs; written to a temporary file for the clone only
+}
+
+// GitPullRequest is the body for POST /v1/sandboxes/:id/git/pull.
+type GitPullRequest struct {
+	Dir    string `json:"dir,omitempty" example:"/app"` // repository directory, default the directory git cloned into on create
+	Token  string `json:"token,omitempty"`              // HTTPS access token, as on create
+	SSHKey string `json:"ssh_key,omitempty"`            // private deploy key, as on create
+}
+
+// GitStatus is the checkout of a repository inside a sandbox.
+type GitStatus struct {
+	Dir    string `json:"dir" example:"/app"`
+	Commit string `json:"commit" example:"9fceb02d0ae598e95dc970b74767f19372d61af8"` // checked-out commit
+	Branch string `json:"branch,omitempty" example:"main"`                           // checked-out branch, empty when HEAD is detached (e.g. a tag or commit ref)
+}
@@ -0,0 +1,15 @@
+package models
+
+// Health is the response of GET /v1/health and GET /readyz.
+type Health struct {
+	Status     string                     `json:"status" example:"healthy"` // healthy (or ready) when every component is, otherwise unhealthy (or not_ready)
+	Components map[string]ComponentHealth `json:"components"`               // by name: docker, database, proxy
+	Versions   map[string]string          `json:"versions,omitempty"`       // api, go and docker; only on /v1/health
+}
+
+// ComponentHealth is the result of checking one component the API depends on.
+type ComponentHealth struct {
+	Status    string `json:"status" example:"healthy"` // healthy or unhealthy
+	Error     string `json:"error,omitempty"`
+	LatencyMS int64  `json:"latency_ms" example:"3"`
+}
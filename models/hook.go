@@ -0,0 +1,18 @@
+package models
+
+// Hooks are commands run inside a sandbox at points of its lifecycle, so it
+// can set itself up (install dependencies, warm caches) and flush state before
+// it stops. Each hook runs like POST /cmd: its output is kept in the
+// sandbox's command list.
+type Hooks struct {
+	OnCreate   *Hook `json:"on_create,omitempty"`   // once, after the git clone and before processes start; create fails if it does
+	OnStart    *Hook `json:"on_start,omitempty"`    // after every start and restart, the first one included, before processes start
+	BeforeStop *Hook `json:"before_stop,omitempty"` // before every stop and restart through the API or the timeout; the stop goes ahead if it fails
+}
+
+// Hook is a command run by a lifecycle hook.
+type Hook struct {
+	Command []string `json:"command" binding:"required,min=1" example:"sh,-c,npm ci"` // executable and arguments
+	Cwd     string   `json:"cwd,omitempty" example:"/app"`                            // working directory, default the sandbox's
+	Timeout int      `json:"timeout,omitempty" example:"300"`                         // seconds before the hook is killed and counted as failed, 0 = default (300s), max 3600
+}
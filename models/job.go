@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// JobRequest is the body for POST /v1/jobs: a command run to completion in a
+// sandbox that only exists for it.
+type JobRequest struct {
+	Sandbox CreateSandboxRequest `json:"sandbox"` // sandbox to run the command in; expiration_action is always "delete"
+	Command ExecCommandRequest   `json:"command"` // command to run; its timeout defaults to 1 hour
+}
+
+// JobResult is the response for POST /v1/jobs.
+type JobResult struct {
+	SandboxID  string    `json:"sandbox_id"` // sandbox the job ran in, already deleted
+	ExitCode   int       `json:"exit_code" example:"0"`
+	TimedOut   bool      `json:"timed_out,omitempty"` // the command was killed after exceeding its timeout
+	Stdout     string    `json:"stdout"`              // captured stdout, the last 1 MB at most
+	Stderr     string    `json:"stderr"`              // captured stderr, the last 1 MB at most
+	StartedAt  time.Time `json:"started_at"`          // when the sandbox was created
+	FinishedAt time.Time `json:"finished_at"`         // when the command exited
+}
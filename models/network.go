@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// CreateNetworkRequest is the body for POST /v1/networks.
+type CreateNetworkRequest struct {
+	Name string `json:"name" binding:"required" example:"backend"` // lowercase letters, digits and single hyphens, max 63
+}
+
+// NetworkGroup is a shared network sandboxes join with network_group. Sandboxes
+// on it reach each other by sandbox name, without publishing host ports.
+type NetworkGroup struct {
+	Name      string    `json:"name" example:"backend"`
+	Network   string    `json:"network" example:"opensbx-net-backend"` // backing Docker network
+	Sandboxes []string  `json:"sandboxes" example:"app,db"`            // names of the sandboxes attached to it
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -1,55 +1,213 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
 
 // ResourceLimits defines CPU and memory constraints for a sandbox.
 type ResourceLimits struct {
-	Memory int64   `json:"memory" example:"1024"` // memory limit in MB (e.g. 512 = 512MB). Default: 1024 (1GB), Max: 8192 (8GB)
-	CPUs   float64 `json:"cpus" example:"1.0"`    // fractional CPU limit (e.g. 1.5). Default: 1.0, Max: 4.0
+	Memory int64   `json:"memory" example:"1024"`                             // memory limit in MB (e.g. 512 = 512MB). Default: 1024 (1GB), Max: 8192 (8GB)
+	CPUs   float64 `json:"cpus" example:"1.0"`                                // fractional CPU limit (e.g. 1.5). Default: 1.0, Max: 4.0
+	GPUs   *GPUs   `json:"gpus,omitempty" swaggertype:"string" example:"all"` // GPUs to pass through: a count (1), "all", or device IDs (["0", "GPU-3a23c669"]). Default: none
+}
+
+// GPUs selects the GPUs passed through to a sandbox, either by count or by ID.
+type GPUs struct {
+	Count     int      // number of GPUs, -1 = all; ignored when DeviceIDs is set
+	DeviceIDs []string // device indexes or UUIDs
+}
+
+// UnmarshalJSON accepts a count (2), "all", or a list of device IDs (["0", "1"]).
+func (g *GPUs) UnmarshalJSON(b []byte) error {
+	var n int
+	if err := json.Unmarshal(b, &n); err == nil {
+		if n < 1 {
+			return fmt.Errorf("gpus count must be >= 1")
+		}
+		*g = GPUs{Count: n}
+		return nil
+	}
+	var all string
+	if err := json.Unmarshal(b, &all); err == nil {
+		if all != "all" {
+			return fmt.Errorf("gpus must be a count, \"all\" or a list of device IDs")
+		}
+		*g = GPUs{Count: -1}
+		return nil
+	}
+	var ids []string
+	if err := json.Unmarshal(b, &ids); err != nil || len(ids) == 0 {
+		return fmt.Errorf("gpus must be a count, \"all\" or a list of device IDs")
+	}
+	for _, id := range ids {
+		if strings.TrimSpace(id) == "" || strings.Contains(id, ",") {
+			return fmt.Errorf("invalid gpu device id %q", id)
+		}
+	}
+	*g = GPUs{DeviceIDs: ids}
+	return nil
+}
+
+// MarshalJSON writes the same forms UnmarshalJSON accepts.
+func (g GPUs) MarshalJSON() ([]byte, error) {
+	switch {
+	case len(g.DeviceIDs) > 0:
+		return json.Marshal(g.DeviceIDs)
+	case g.Count < 0:
+		return json.Marshal("all")
+	}
+	return json.Marshal(g.Count)
 }
 
 // CreateSandboxRequest is the body for POST /v1/sandboxes
 type CreateSandboxRequest struct {
-	Image     string          `json:"image" binding:"required" example:"node:24"`
-	Ports     []string        `json:"ports" example:"3000,8080"` // container ports to expose, e.g. ["3000", "8080/tcp"]. First port is the default for proxy routing.
-	Timeout   int             `json:"timeout" example:"900"`     // seconds until auto-stop, 0 = default (900s)
-	Resources *ResourceLimits `json:"resources"`                 // CPU/memory limits, nil = defaults (1GB RAM, 1 vCPU)
-	Env       []string        `json:"env"`                       // extra environment variables (e.g. ["KEY=VALUE"])
+	Name             string                `json:"name,omitempty" example:"my-app"` // sandbox name and subdomain: lowercase letters, digits and single hyphens, max 63. Empty = generated
+	Image            string                `json:"image" binding:"required" example:"node:24"`
+	Ports            []string              `json:"ports" example:"3000,8080"`                                       // container ports to expose, e.g. ["3000", "8080/tcp"]. First port is the default for proxy routing.
+	Timeout          int                   `json:"timeout" example:"900"`                                           // seconds until auto-stop, 0 = default (900s)
+	MaxLifetime      int                   `json:"max_lifetime,omitempty" example:"86400"`                          // seconds after creation when the sandbox is stopped (or deleted with expiration_action "delete") whatever its renewals and activity, and cannot be started again. 0 = the server cap, if any; longer values are lowered to it
+	Resources        *ResourceLimits       `json:"resources"`                                                       // CPU/memory limits, nil = defaults (1GB RAM, 1 vCPU)
+	Env              []string              `json:"env"`                                                             // extra environment variables (e.g. ["KEY=VALUE"])
+	EnvFromSecrets   []string              `json:"env_from_secrets,omitempty" example:"OPENAI_API_KEY"`             // stored secrets set as environment variables of the same name; env wins on conflicts
+	Cmd              []string              `json:"cmd,omitempty" example:"npm,run,dev"`                             // startup command, empty = keep alive with "sleep infinity" (or the entrypoint alone)
+	Entrypoint       []string              `json:"entrypoint,omitempty"`                                            // override the image entrypoint
+	WorkingDir       string                `json:"working_dir,omitempty" example:"/app"`                            // working directory for the startup command
+	ExpirationAction string                `json:"expiration_action,omitempty" enums:"stop,delete" example:"stop"`  // on timeout: "stop" (default) or "delete" (removed once stopped past the reap grace period)
+	TimeoutMode      string                `json:"timeout_mode,omitempty" enums:"absolute,idle" example:"idle"`     // "absolute" (default) or "idle": timeout restarts on exec, file and proxy activity
+	Network          string                `json:"network,omitempty" enums:"bridge,internal,none" example:"bridge"` // "bridge" (default): outbound access; "internal": no outbound access, reaches other "internal" sandboxes only; "none": loopback only
+	NetworkGroup     string                `json:"network_group,omitempty" example:"backend"`                       // shared network created with POST /v1/networks; sandboxes on it reach each other by sandbox name. Not with network "none"
+	Egress           *EgressPolicy         `json:"egress,omitempty"`                                                // outbound allow/deny rules, bridge network only
+	Bandwidth        *BandwidthLimit       `json:"bandwidth,omitempty"`                                             // network throughput limits
+	Security         *SecurityOptions      `json:"security,omitempty"`                                              // container hardening on top of the server defaults
+	Runtime          string                `json:"runtime,omitempty" example:"runsc"`                               // OCI runtime, e.g. "runsc" (gVisor) or "kata"; must be configured on the worker. Empty = server default
+	Labels           map[string]string     `json:"labels,omitempty"`                                                // caller-defined tags, e.g. {"team": "ml"}; also set as Docker labels. Keys under "opensbx." are reserved
+	ReadyCheck       *ReadyCheck           `json:"ready_check,omitempty"`                                           // how to tell the app inside is serving; the proxy shows a "starting" page until it passes
+	WakeOnRequest    bool                  `json:"wake_on_request,omitempty"`                                       // start the sandbox when the proxy gets a request while it is stopped or expired
+	Processes        []StartProcessRequest `json:"processes,omitempty" binding:"omitempty,dive"`                    // supervised processes started with the sandbox and again after every start and restart, as with POST /processes
+	Git              *GitSource            `json:"git,omitempty"`                                                   // repository cloned into the sandbox before processes start; create fails if the clone does
+	Hooks            *Hooks                `json:"hooks,omitempty"`                                                 // commands run inside the sandbox on create, on every start and before every stop
+}
+
+// ReadyCheck probes a sandbox after every start until its app is serving.
+// With Command, the sandbox is ready once it exits 0. Otherwise Port is
+// probed: with an HTTP GET of Path when set (ready on a status below 400),
+// or by opening a TCP connection.
+type ReadyCheck struct {
+	Port    string   `json:"port,omitempty" example:"3000"`                  // container port to probe, default the main port
+	Path    string   `json:"path,omitempty" example:"/health"`               // HTTP path; empty = TCP check
+	Command []string `json:"command,omitempty" example:"test,-f,/tmp/ready"` // command run inside the sandbox instead of probing a port
+	Timeout int      `json:"timeout,omitempty" example:"60"`                 // seconds to keep probing, 0 = default (60s), max 600
+}
+
+// SecurityOptions hardens a sandbox container. Unset fields use the server
+// defaults, which requests can tighten but not loosen.
+type SecurityOptions struct {
+	ReadOnlyRootfs  *bool    `json:"read_only_rootfs,omitempty" example:"true"`  // read-only root filesystem; /tmp and /run stay writable. The files API cannot write to such a sandbox
+	NoNewPrivileges *bool    `json:"no_new_privileges,omitempty" example:"true"` // stop setuid binaries from gaining privileges
+	CapDrop         []string `json:"cap_drop,omitempty" example:"NET_RAW,MKNOD"` // capabilities dropped on top of the server defaults; "ALL" drops every capability
+	SeccompProfile  string   `json:"seccomp_profile,omitempty"`                  // seccomp profile JSON, empty = server default
+	PidsLimit       int64    `json:"pids_limit,omitempty" example:"256"`         // maximum processes, 0 = server default
+	User            string   `json:"user,omitempty" example:"1000:1000"`         // run as this user: name, uid, or user:group / uid:gid
+}
+
+// EgressPolicy restricts the destinations a sandbox can connect to.
+// Entries are an IP or CIDR, optionally with :port, or :port alone for that TCP port on any host.
+type EgressPolicy struct {
+	Allow []string `json:"allow,omitempty" example:"140.82.112.0/20,:443"` // only these destinations are reachable
+	Deny  []string `json:"deny,omitempty" example:"10.0.0.0/8"`            // these destinations are blocked; cannot be combined with allow
+}
+
+// BandwidthLimit caps a sandbox's network throughput in kbit/s. 0 = unlimited.
+type BandwidthLimit struct {
+	IngressKbps int `json:"ingress_kbps,omitempty" example:"10000"` // download
+	EgressKbps  int `json:"egress_kbps,omitempty" example:"2000"`   // upload
 }
 
 // CreateSandboxResponse is the response for POST /v1/sandboxes
 type CreateSandboxResponse struct {
-	ID    string   `json:"id"`
-	Name  string   `json:"name"`          // auto-generated name (e.g. "eager-turing")
-	Ports []string `json:"ports"`         // exposed container ports, e.g. ["3000/tcp", "8080/tcp"]
-	URL   string   `json:"url,omitempty"` // proxy URL, e.g. "http://eager-turing.localhost"
+	ID    string     `json:"id"`
+	Name  string     `json:"name"`                                  // auto-generated name (e.g. "eager-turing")
+	Ports []string   `json:"ports"`                                 // exposed container ports, e.g. ["3000/tcp", "8080/tcp"]
+	URL   string     `json:"url,omitempty"`                         // proxy URL, e.g. "http://eager-turing.localhost"
+	Ready string     `json:"ready,omitempty" enums:"ready,timeout"` // ready check result, only with ?wait_ready=true
+	Git   *GitStatus `json:"git,omitempty"`                         // the clone of the git option
 }
 
 // SandboxSummary is a concise view of a sandbox for list endpoints.
 type SandboxSummary struct {
-	ID        string     `json:"id"`
-	Name      string     `json:"name"`
-	Image     string     `json:"image"`
-	Status    string     `json:"status"`
-	State     string     `json:"state"`
-	Ports     []string   `json:"ports"`
-	ExpiresAt *time.Time `json:"expires_at,omitempty"`
-	URL       string     `json:"url,omitempty"`
+	ID        string            `json:"id"`
+	Name      string            `json:"name"`
+	Image     string            `json:"image"`
+	Status    string            `json:"status"`
+	State     string            `json:"state"`
+	Ports     []string          `json:"ports"`
+	ExpiresAt *time.Time        `json:"expires_at,omitempty"`
+	URL       string            `json:"url,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+}
+
+// SandboxListQuery filters, sorts and pages GET /v1/sandboxes. Zero values match everything.
+type SandboxListQuery struct {
+	State      string   `form:"state"`       // container state, e.g. running, exited, paused, or removed
+	Image      string   `form:"image"`       // exact image reference
+	NamePrefix string   `form:"name_prefix"` // names starting with this
+	Label      []string `form:"label"`       // "key=value" or "key"; repeat to require several
+	Sort       string   `form:"sort"`        // created (default), name or image
+	Order      string   `form:"order"`       // asc (default) or desc
+	Limit      int      `form:"limit"`       // 0 = every match, max 1000
+	Offset     int      `form:"offset"`      // matches to skip
+}
+
+// CommandListQuery pages GET /v1/sandboxes/:id/cmd.
+type CommandListQuery struct {
+	Order  string `form:"order"`  // asc (oldest first, default) or desc
+	Limit  int    `form:"limit"`  // 0 = every command, max 1000
+	Offset int    `form:"offset"` // commands to skip
+}
+
+// SandboxLogsQuery selects the output returned by GET /v1/sandboxes/:id/logs.
+type SandboxLogsQuery struct {
+	Follow     bool   `form:"follow"`     // keep streaming new output until the client disconnects
+	Tail       string `form:"tail"`       // number of lines from the end, or "all" (default)
+	Since      string `form:"since"`      // only output after this: RFC 3339, unix seconds, or a duration ago such as "10m"
+	Timestamps bool   `form:"timestamps"` // prefix every line with its RFC 3339 timestamp
 }
 
 // SandboxDetail is the full inspect response with only relevant fields.
 type SandboxDetail struct {
-	ID         string         `json:"id"`
-	Name       string         `json:"name"`
-	Image      string         `json:"image"`
-	Status     string         `json:"status"`
-	Running    bool           `json:"running"`
-	Ports      []string       `json:"ports"`
-	Resources  ResourceLimits `json:"resources"`
-	StartedAt  string         `json:"started_at"`
-	FinishedAt string         `json:"finished_at"`
-	ExpiresAt  *time.Time     `json:"expires_at,omitempty"`
-	URL        string         `json:"url,omitempty"`
+	ID             string            `json:"id"`
+	Name           string            `json:"name"`
+	Image          string            `json:"image"`
+	Status         string            `json:"status"`
+	Running        bool              `json:"running"`
+	Ports          []string          `json:"ports"`
+	Resources      ResourceLimits    `json:"resources"`
+	Runtime        string            `json:"runtime,omitempty" example:"runsc"`   // OCI runtime, empty = the worker's default
+	Isolation      string            `json:"isolation" enums:"container,microvm"` // "microvm" when the runtime boots a VM per sandbox (Kata, Firecracker)
+	StartedAt      string            `json:"started_at"`
+	FinishedAt     string            `json:"finished_at"`
+	ExpiresAt      *time.Time        `json:"expires_at,omitempty"`
+	MaxLifetime    int               `json:"max_lifetime,omitempty" example:"86400"` // seconds the sandbox may exist in total, whatever its renewals; omitted when unlimited
+	LifetimeEndsAt *time.Time        `json:"lifetime_ends_at,omitempty"`             // when max_lifetime is reached; expires_at never goes past it
+	URL            string            `json:"url,omitempty"`
+	Labels         map[string]string `json:"labels,omitempty"`
+	Ready          string            `json:"ready,omitempty" enums:"starting,ready,timeout"` // ready check state since the last start; empty without a ready check
+	WakeOnRequest  bool              `json:"wake_on_request,omitempty"`
+	OOMKilled      bool              `json:"oom_killed"`          // the last run was ended by the kernel OOM killer
+	ExitCode       *int              `json:"exit_code,omitempty"` // exit code of the last run, only while not running
+	Events         []SandboxEvent    `json:"events,omitempty"`    // recent OOM kills, unexpected exits, restarts and hook failures, oldest first
+}
+
+// SandboxEvent is something that happened to a sandbox outside the API.
+type SandboxEvent struct {
+	Type     string    `json:"type" enums:"oom,exit,restart,hook_failed"`   // "oom": a process hit the memory limit, "exit": the sandbox stopped without being asked to, "restart": it was restarted, "hook_failed": a lifecycle hook failed
+	Hook     string    `json:"hook,omitempty" enums:"on_start,before_stop"` // hook of "hook_failed" events
+	ExitCode *int      `json:"exit_code,omitempty"`                         // exit code of "exit" and "hook_failed" events; none when the hook timed out
+	Time     time.Time `json:"time"`
 }
 
 // RestartResponse is the response for POST /v1/sandboxes/:id/restart
@@ -57,20 +215,318 @@ type RestartResponse struct {
 	Status    string     `json:"status"`
 	Ports     []string   `json:"ports"`
 	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	PortReady string     `json:"port_ready,omitempty" enums:"ready,timeout"` // whether the main port accepted connections in time, only with ?wait_port=true
+}
+
+// WaitPortQuery makes POST /v1/sandboxes/:id/start and /restart respond once
+// the sandbox's main port accepts connections.
+type WaitPortQuery struct {
+	WaitPort    bool `form:"wait_port"`    // wait for the main port before responding
+	PortTimeout int  `form:"port_timeout"` // seconds to wait, default 30
+}
+
+// SandboxDomain is an external hostname routed to a sandbox by the proxy.
+type SandboxDomain struct {
+	Domain    string    `json:"domain" example:"app.example.com"`
+	SandboxID string    `json:"sandbox_id" example:"a1b2c3d4e5f6"`
+	URL       string    `json:"url,omitempty" example:"https://app.example.com"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AddDomainRequest is the body for POST /v1/sandboxes/:id/domains
+type AddDomainRequest struct {
+	Domain string `json:"domain" binding:"required" example:"app.example.com"` // hostname whose DNS points at the proxy
 }
 
 // SandboxNetwork is the network/routing view for a sandbox.
 type SandboxNetwork struct {
-	MainPort string            `json:"main_port"` // selected container port for proxy routing (e.g. "3000/tcp")
-	PortsMap map[string]string `json:"ports_map"` // map of container port -> docker host port
+	Name     string            `json:"name"`           // sandbox name, used in proxy URLs
+	MainPort string            `json:"main_port"`      // selected container port for proxy routing (e.g. "3000/tcp")
+	PortsMap map[string]string `json:"ports_map"`      // map of container port -> docker host port
+	URLs     map[string]string `json:"urls,omitempty"` // proxy URL per container port, e.g. "8080/tcp" -> "http://my-app--8080.localhost:3000"
+}
+
+// SandboxIsolation is the response for GET /v1/sandboxes/:id/isolation.
+type SandboxIsolation struct {
+	Isolated    bool               `json:"isolated"`          // true when no other container is reachable over the sandbox's networks
+	NetworkMode string             `json:"network_mode"`      // docker network mode (e.g. "opensbx-isolated", "bridge")
+	Networks    []NetworkIsolation `json:"networks"`          // attached networks and their isolation settings
+	Reasons     []string           `json:"reasons,omitempty"` // why the sandbox is not isolated
+}
+
+// SnapshotRequest is the optional body for POST /v1/sandboxes/:id/snapshot.
+type SnapshotRequest struct {
+	Image        string        `json:"image" example:"myorg/node-deps:v1"` // image reference to create, empty = opensbx-snapshot/<owner>/<name>:<unix time>; other names need the images scope
+	Comment      string        `json:"comment"`                            // commit message stored in the image
+	Push         bool          `json:"push"`                               // push the image to its registry after committing
+	RegistryAuth *RegistryAuth `json:"registry_auth,omitempty"`            // credentials for push, nil = anonymous
+}
+
+// RegistryAuth holds registry credentials used when pushing an image.
+type RegistryAuth struct {
+	Username      string `json:"username"`
+	Password      string `json:"password"`
+	ServerAddress string `json:"server_address,omitempty"` // e.g. "ghcr.io", empty = derived from the image
+}
+
+// SnapshotResponse is the response for POST /v1/sandboxes/:id/snapshot.
+type SnapshotResponse struct {
+	Image  string `json:"image"`  // reference of the created image
+	ID     string `json:"id"`     // image ID (sha256:...)
+	Pushed bool   `json:"pushed"` // true if the image was pushed to a registry
+}
+
+// CloneRequest is the optional body for POST /v1/sandboxes/:id/clone.
+type CloneRequest struct {
+	Name       string `json:"name,omitempty" example:"my-app-fork"` // name of the clone, empty = generated
+	Filesystem *bool  `json:"filesystem,omitempty" example:"true"`  // copy the source's filesystem (default); false = start from its original image
+}
+
+// SandboxEnv is the response for GET and PUT /v1/sandboxes/:id/env.
+type SandboxEnv struct {
+	Env map[string]string `json:"env"` // the container's environment with the variables set after creation applied
+}
+
+// UpdateEnvRequest is the body for PUT /v1/sandboxes/:id/env.
+type UpdateEnvRequest struct {
+	Env map[string]*string `json:"env" binding:"required"` // variables to set; null removes a variable set earlier through this endpoint
+}
+
+// Secret describes a stored secret. Its value is never returned.
+type Secret struct {
+	Name      string    `json:"name" example:"OPENAI_API_KEY"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PutSecretRequest is the body for PUT /v1/secrets/:name.
+type PutSecretRequest struct {
+	Value string `json:"value" binding:"required"`
+}
+
+// CheckpointRequest is the optional body for POST /v1/sandboxes/:id/checkpoint.
+type CheckpointRequest struct {
+	Name         string `json:"name" example:"before-migration"` // checkpoint name, empty = cp-<unix time>
+	LeaveRunning bool   `json:"leave_running"`                   // keep the sandbox running, default stops it once its state is saved
+}
+
+// Checkpoint is a saved memory and process state of a sandbox (CRIU).
+type Checkpoint struct {
+	Name string `json:"name"`
+}
+
+// RestoreRequest is the body for POST /v1/sandboxes/:id/restore.
+type RestoreRequest struct {
+	Checkpoint string `json:"checkpoint" binding:"required" example:"before-migration"` // name of the checkpoint to restore
+}
+
+// TerminalMessage is a JSON control frame on the GET /v1/sandboxes/:id/terminal WebSocket.
+// Clients send "input" and "resize"; the server sends "exit" (and "error") before closing.
+// Binary frames carry raw terminal bytes in both directions.
+type TerminalMessage struct {
+	Type     string `json:"type"`                // "input", "resize", "exit" or "error"
+	Data     string `json:"data,omitempty"`      // keystrokes for "input", message for "error"
+	Rows     uint   `json:"rows,omitempty"`      // terminal height for "resize"
+	Cols     uint   `json:"cols,omitempty"`      // terminal width for "resize"
+	ExitCode *int   `json:"exit_code,omitempty"` // process exit code for "exit"
+}
+
+// ApplyRequest is the request body for POST /v1/apply: the desired set of sandboxes.
+type ApplyRequest struct {
+	Sandboxes []ApplySandboxSpec `json:"sandboxes" binding:"dive"`
+	Prune     bool               `json:"prune"` // delete apply-managed sandboxes missing from the spec
+}
+
+// ApplySandboxSpec declares one sandbox, identified by name.
+type ApplySandboxSpec struct {
+	Name             string                `json:"name" binding:"required" example:"web"` // sandbox name and subdomain: lowercase letters, digits and single hyphens, max 63
+	Image            string                `json:"image" binding:"required" example:"node:24"`
+	Ports            []string              `json:"ports" example:"3000"`
+	Timeout          int                   `json:"timeout" example:"900"` // seconds until auto-stop, 0 = default (900s)
+	MaxLifetime      int                   `json:"max_lifetime,omitempty"`
+	Resources        *ResourceLimits       `json:"resources"`
+	Env              []string              `json:"env"`
+	EnvFromSecrets   []string              `json:"env_from_secrets,omitempty"`
+	Cmd              []string              `json:"cmd,omitempty"`
+	Entrypoint       []string              `json:"entrypoint,omitempty"`
+	WorkingDir       string                `json:"working_dir,omitempty"`
+	ExpirationAction string                `json:"expiration_action,omitempty" enums:"stop,delete"`
+	TimeoutMode      string                `json:"timeout_mode,omitempty" enums:"absolute,idle"`
+	Network          string                `json:"network,omitempty" enums:"bridge,internal,none"`
+	NetworkGroup     string                `json:"network_group,omitempty"`
+	Egress           *EgressPolicy         `json:"egress,omitempty"`
+	Bandwidth        *BandwidthLimit       `json:"bandwidth,omitempty"`
+	Security         *SecurityOptions      `json:"security,omitempty"`
+	Runtime          string                `json:"runtime,omitempty"`
+	Labels           map[string]string     `json:"labels,omitempty"`
+	ReadyCheck       *ReadyCheck           `json:"ready_check,omitempty"`
+	WakeOnRequest    bool                  `json:"wake_on_request,omitempty"`
+	Processes        []StartProcessRequest `json:"processes,omitempty" binding:"omitempty,dive"`
+	Hooks            *Hooks                `json:"hooks,omitempty"`
+	Files            []SeedFile            `json:"files"` // files written after the sandbox starts
+}
+
+// CreateRequest converts the spec into a regular create request.
+func (s ApplySandboxSpec) CreateRequest() CreateSandboxRequest {
+	return CreateSandboxRequest{
+		Name:             s.Name,
+		Image:            s.Image,
+		Ports:            s.Ports,
+		Timeout:          s.Timeout,
+		MaxLifetime:      s.MaxLifetime,
+		Resources:        s.Resources,
+		Env:              s.Env,
+		EnvFromSecrets:   s.EnvFromSecrets,
+		Cmd:              s.Cmd,
+		Entrypoint:       s.Entrypoint,
+		WorkingDir:       s.WorkingDir,
+		ExpirationAction: s.ExpirationAction,
+		TimeoutMode:      s.TimeoutMode,
+		Network:          s.Network,
+		NetworkGroup:     s.NetworkGroup,
+		Egress:           s.Egress,
+		Bandwidth:        s.Bandwidth,
+		Security:         s.Security,
+		Runtime:          s.Runtime,
+		Labels:           s.Labels,
+		ReadyCheck:       s.ReadyCheck,
+		WakeOnRequest:    s.WakeOnRequest,
+		Processes:        s.Processes,
+		Hooks:            s.Hooks,
+	}
+}
+
+// SeedFile is a file written into a sandbox by POST /v1/apply.
+type SeedFile struct {
+	Path    string `json:"path" binding:"required" example:"/app/index.js"`
+	Content string `json:"content"`
+}
+
+// ApplyResponse is the response for POST /v1/apply.
+type ApplyResponse struct {
+	Results []ApplyResult `json:"results"`
+}
+
+// ApplyResult reports what apply did for one sandbox.
+type ApplyResult struct {
+	Name   string `json:"name"`
+	ID     string `json:"id,omitempty"`
+	Action string `json:"action"`          // "created", "updated", "unchanged", "deleted" or "failed"
+	Error  string `json:"error,omitempty"` // set when action is "failed"
+	URL    string `json:"url,omitempty"`
+}
+
+// SandboxBundleMetadata is the metadata.json entry of a sandbox export bundle.
+type SandboxBundleMetadata struct {
+	Version     int               `json:"version"`          // bundle format version (currently 1)
+	Name        string            `json:"name"`             // name of the exported sandbox
+	SourceImage string            `json:"source_image"`     // image the sandbox was originally created from
+	Image       string            `json:"image"`            // reference of the committed image inside image.tar
+	Ports       []string          `json:"ports"`            // exposed container ports, main routing port first
+	Resources   ResourceLimits    `json:"resources"`        // CPU/memory limits of the exported sandbox
+	Labels      map[string]string `json:"labels,omitempty"` // labels of the exported sandbox
+	ExportedAt  time.Time         `json:"exported_at"`
+}
+
+// HostPolicy is the response for GET /v1/admin/policy.
+// A false flag means the option is denied for every sandbox.
+type HostPolicy struct {
+	Privileged      bool     `json:"privileged"`       // privileged containers
+	HostPID         bool     `json:"host_pid"`         // sharing the host pid namespace
+	HostIPC         bool     `json:"host_ipc"`         // sharing the host ipc namespace
+	HostNetwork     bool     `json:"host_network"`     // sharing the host network namespace
+	HostUTS         bool     `json:"host_uts"`         // sharing the host uts namespace
+	HostMounts      bool     `json:"host_mounts"`      // bind mounts from the host filesystem
+	CapAdd          bool     `json:"cap_add"`          // adding linux capabilities
+	AllowedDevices  []string `json:"allowed_devices"`  // host devices sandboxes may map
+	AllowedRuntimes []string `json:"allowed_runtimes"` // OCI runtimes sandboxes may use, empty = any runtime the worker has
+	GPUs            bool     `json:"gpus"`             // GPU passthrough
+}
+
+// NetworkIsolation describes one network a sandbox is attached to.
+type NetworkIsolation struct {
+	Name           string `json:"name"`
+	Driver         string `json:"driver"`
+	InterContainer bool   `json:"inter_container"` // inter-container communication enabled on this network
+	Internal       bool   `json:"internal"`        // network has no outbound connectivity
+	Peers          int    `json:"peers"`           // other containers attached to this network
 }
 
 // ExecCommandRequest is the body for POST /v1/sandboxes/:id/cmd
 type ExecCommandRequest struct {
-	Command string            `json:"command" binding:"required" example:"npm"` // executable name (e.g. "npm")
-	Args    []string          `json:"args" example:"install"`                   // arguments (e.g. ["install"])
-	Cwd     string            `json:"cwd" example:"/app"`                       // working directory
-	Env     map[string]string `json:"env"`                                      // extra environment variables
+	Command    string            `json:"command" binding:"required" example:"npm"` // executable name (e.g. "npm")
+	Args       []string          `json:"args" example:"install"`                   // arguments (e.g. ["install"])
+	Cwd        string            `json:"cwd" example:"/app"`                       // working directory
+	Env        map[string]string `json:"env"`                                      // extra environment variables
+	Stdin      string            `json:"stdin,omitempty" example:"SELECT 1;\n"`    // written to the command's stdin, which is then closed
+	Timeout    int               `json:"timeout,omitempty" example:"300"`          // seconds before the command is killed, 0 = no limit
+	User       string            `json:"user,omitempty" example:"1000:1000"`       // run as this user: name, uid, or user:group / uid:gid. Default: the container's user
+	InheritEnv *bool             `json:"inherit_env,omitempty" example:"true"`     // see the container's environment (default true); false runs with only env and a default PATH
+}
+
+// BatchCommandRequest is the body for POST /v1/sandboxes/:id/cmd/batch
+type BatchCommandRequest struct {
+	Commands    []ExecCommandRequest `json:"commands" binding:"required,min=1,max=100,dive"` // run in order, each after the previous one finished
+	StopOnError bool                 `json:"stop_on_error" example:"true"`                   // skip the remaining commands after a non-zero exit
+}
+
+// BatchStep is the result of one command of a batch.
+type BatchStep struct {
+	Index   int           `json:"index"`           // position in the request's commands
+	Command CommandDetail `json:"command"`         // finished command
+	Stdout  string        `json:"stdout"`          // captured stdout text
+	Stderr  string        `json:"stderr"`          // captured stderr text
+	Error   string        `json:"error,omitempty"` // set when the command could not be run; the batch stops
+}
+
+// BatchCommandResponse is the response for POST /v1/sandboxes/:id/cmd/batch.
+// When streaming, steps are sent one per line and the final line carries only success.
+type BatchCommandResponse struct {
+	Steps   []BatchStep `json:"steps,omitempty"` // results of the commands that ran
+	Success bool        `json:"success"`         // every command ran and exited 0
+}
+
+// StartProcessRequest is the body for POST /v1/sandboxes/:id/processes
+type StartProcessRequest struct {
+	Name    string            `json:"name" binding:"required" example:"web"`                                  // unique per sandbox: lowercase letters, digits and dashes
+	Command string            `json:"command" binding:"required" example:"npm"`                               // executable name
+	Args    []string          `json:"args" example:"run,dev"`                                                 // arguments
+	Cwd     string            `json:"cwd" example:"/app"`                                                     // working directory
+	Env     map[string]string `json:"env"`                                                                    // extra environment variables
+	User    string            `json:"user,omitempty" example:"node"`                                          // run as this user (see ExecCommandRequest)
+	Restart string            `json:"restart,omitempty" enums:"always,on-failure,never" example:"on-failure"` // restart policy when the process exits. Default: always
+}
+
+// ProcessDetail is a supervised long-running process.
+type ProcessDetail struct {
+	Name      string            `json:"name" example:"web"`
+	SandboxID string            `json:"sandbox_id"`
+	Command   string            `json:"command" example:"npm"`
+	Args      []string          `json:"args"`
+	Cwd       string            `json:"cwd,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+	User      string            `json:"user,omitempty"`
+	Restart   string            `json:"restart" example:"on-failure"`
+	Status    string            `json:"status" enums:"running,backoff,exited,stopped" example:"running"` // stopped = not supervised until the sandbox is next started
+	CommandID string            `json:"command_id,omitempty"`                                            // current or last run; read its output via /cmd/:cmdId/logs
+	Restarts  int               `json:"restarts"`                                                        // restarts since the process was last (re)started
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// ProcessInfo is one entry of a sandbox's process table.
+type ProcessInfo struct {
+	PID     int     `json:"pid,omitempty" example:"17"` // PID inside the sandbox, omitted when it cannot be resolved
+	HostPID int     `json:"host_pid" example:"48213"`   // PID on the Docker host
+	User    string  `json:"user" example:"root"`        // owner, as resolved on the Docker host
+	CPU     float64 `json:"cpu" example:"1.5"`          // CPU usage in percent
+	Memory  float64 `json:"memory" example:"0.7"`       // resident memory in percent of host memory
+	Command string  `json:"command" example:"node server.js"`
+}
+
+// ProcessListResponse is the response for GET /v1/sandboxes/:id/processes.
+type ProcessListResponse struct {
+	Processes []ProcessDetail `json:"processes"` // supervised processes
+	Running   []ProcessInfo   `json:"running"`   // process table snapshot, empty when the sandbox is not running
 }
 
 // CommandDetail represents a command executed in a sandbox.
@@ -81,6 +537,7 @@ type CommandDetail struct {
 	Cwd        string   `json:"cwd"`                   // working directory
 	SandboxID  string   `json:"sandbox_id"`            // parent sandbox container ID
 	ExitCode   *int     `json:"exit_code,omitempty"`   // nil while running
+	TimedOut   bool     `json:"timed_out,omitempty"`   // killed after exceeding its timeout
 	StartedAt  int64    `json:"started_at"`            // unix milliseconds
 	FinishedAt *int64   `json:"finished_at,omitempty"` // unix milliseconds, nil while running
 }
@@ -93,6 +550,7 @@ type CommandResponse struct {
 // CommandListResponse wraps a list of commands.
 type CommandListResponse struct {
 	Commands []CommandDetail `json:"commands"`
+	Total    int             `json:"total"` // commands in the sandbox, before limit and offset
 }
 
 // CommandLogsResponse is the response for GET /v1/sandboxes/:id/cmd/:cmdId/logs (non-stream).
@@ -102,20 +560,76 @@ type CommandLogsResponse struct {
 	ExitCode *int   `json:"exit_code,omitempty"` // nil while command is still running
 }
 
+// CommandEvent is one ND-JSON line of a command's output stream. Log streams
+// carry only stdout and stderr lines; POST /v1/sandboxes/:id/run adds a start
+// line before them and an exit line after them. Idle streams carry ping lines,
+// which clients skip.
+type CommandEvent struct {
+	Type     string         `json:"type" enums:"start,stdout,stderr,exit,ping"`
+	Data     string         `json:"data,omitempty"`      // output line, for stdout and stderr
+	Command  *CommandDetail `json:"command,omitempty"`   // for start and exit
+	ExitCode *int           `json:"exit_code,omitempty"` // for exit
+}
+
 // KillCommandRequest is the body for POST /v1/sandboxes/:id/cmd/:cmdId/kill
 type KillCommandRequest struct {
-	Signal int `json:"signal" binding:"required" example:"15"` // POSIX signal number (15=SIGTERM, 9=SIGKILL)
+	Signal Signal `json:"signal" binding:"required" swaggertype:"string" example:"SIGTERM"` // signal name ("SIGTERM", "KILL") or POSIX number (15, 9)
+}
+
+// Signal is a POSIX signal number that unmarshals from either a number or a
+// name such as "SIGTERM" or "term".
+type Signal int
+
+// signalNumbers maps the signal names accepted by Signal to their Linux numbers.
+var signalNumbers = map[string]Signal{
+	"HUP": 1, "INT": 2, "QUIT": 3, "KILL": 9, "USR1": 10, "USR2": 12,
+	"PIPE": 13, "ALRM": 14, "TERM": 15, "CONT": 18, "STOP": 19, "TSTP": 20,
+	"WINCH": 28,
+}
+
+// UnmarshalJSON accepts a signal number (15 or "15") or name ("SIGTERM", "TERM").
+func (s *Signal) UnmarshalJSON(b []byte) error {
+	var n int
+	if err := json.Unmarshal(b, &n); err == nil {
+		*s = Signal(n)
+		return s.validate()
+	}
+	var name string
+	if err := json.Unmarshal(b, &name); err != nil {
+		return fmt.Errorf("signal must be a number or a name")
+	}
+	if n, err := strconv.Atoi(name); err == nil {
+		*s = Signal(n)
+		return s.validate()
+	}
+	sig, ok := signalNumbers[strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(name)), "SIG")]
+	if !ok {
+		return fmt.Errorf("unknown signal %q", name)
+	}
+	*s = sig
+	return nil
+}
+
+// validate rejects numbers outside the Linux signal range.
+func (s Signal) validate() error {
+	if s < 1 || s > 64 {
+		return fmt.Errorf("signal %d out of range", int(s))
+	}
+	return nil
 }
 
 // FileReadResponse is the response for GET /v1/sandboxes/:id/files
 type FileReadResponse struct {
-	Path    string `json:"path"`
-	Content string `json:"content"`
+	Path     string `json:"path"`
+	Content  string `json:"content"`
+	Encoding string `json:"encoding,omitempty"` // "base64" when requested with ?encoding=base64
 }
 
-// FileWriteRequest is the body for PUT /v1/sandboxes/:id/files
+// FileWriteRequest is the JSON body for PUT /v1/sandboxes/:id/files.
+// Binary files can also be sent as application/octet-stream or multipart/form-data.
 type FileWriteRequest struct {
-	Content string `json:"content" binding:"required" example:"console.log('hello')"`
+	Content  string `json:"content" binding:"required" example:"console.log('hello')"`
+	Encoding string `json:"encoding,omitempty" example:""` // "base64" if content is base64-encoded
 }
 
 // FileListResponse is the response for GET /v1/sandboxes/:id/files/list
@@ -124,6 +638,41 @@ type FileListResponse struct {
 	Output string `json:"output"`
 }
 
+// FileNode is an entry of a recursive directory listing.
+type FileNode struct {
+	Name     string     `json:"name" example:"src"`
+	Path     string     `json:"path" example:"/app/src"`
+	Type     string     `json:"type" enums:"dir,file,symlink" example:"dir"`
+	Children []FileNode `json:"children,omitempty"` // directory contents, up to the requested depth
+}
+
+// FileTreeResponse is the response for GET /v1/sandboxes/:id/files/list?tree=true
+type FileTreeResponse struct {
+	Path      string     `json:"path"`
+	Entries   []FileNode `json:"entries"`
+	Truncated bool       `json:"truncated"` // the entry limit was reached; some entries are missing
+}
+
+// FileSearchResponse is the response for GET /v1/sandboxes/:id/files/search
+type FileSearchResponse struct {
+	Path      string     `json:"path"`
+	Glob      string     `json:"glob" example:"**/*.ts"`
+	Matches   []FileNode `json:"matches"`   // matching entries, without children
+	Truncated bool       `json:"truncated"` // the result or scan limit was reached; some matches may be missing
+}
+
+// FileStat is the response for GET /v1/sandboxes/:id/files/stat
+type FileStat struct {
+	Path        string    `json:"path" example:"/app/package.json"`
+	Name        string    `json:"name" example:"package.json"`
+	Type        string    `json:"type" enums:"dir,file,symlink,other" example:"file"`
+	Size        int64     `json:"size" example:"512"`                   // bytes
+	Mode        string    `json:"mode" example:"0644"`                  // permission bits in octal
+	Permissions string    `json:"permissions" example:"-rw-r--r--"`     // ls-style mode string
+	ModTime     time.Time `json:"mtime" example:"2025-01-01T00:00:00Z"` // last modification
+	LinkTarget  string    `json:"link_target,omitempty"`                // symlink target, for symlinks only
+}
+
 // RenewExpirationRequest is the body for POST /v1/sandboxes/:id/renew-expiration
 type RenewExpirationRequest struct {
 	Timeout int `json:"timeout" binding:"required" example:"900"` // new TTL in seconds
@@ -151,6 +700,43 @@ type SandboxStats struct {
 	CPU    float64     `json:"cpu_percent"` // CPU usage percentage
 	Memory MemoryUsage `json:"memory"`      // memory usage and limit
 	PIDs   uint64      `json:"pids"`        // number of running processes
+
+	Network map[string]NetworkUsage `json:"network,omitempty"` // traffic per interface (e.g. "eth0") since start; empty without networking
+	BlockIO BlockIOUsage            `json:"block_io"`          // bytes read from and written to block devices since start
+}
+
+// NetworkUsage counts the traffic of one network interface.
+type NetworkUsage struct {
+	RxBytes   uint64 `json:"rx_bytes"`
+	RxPackets uint64 `json:"rx_packets"`
+	TxBytes   uint64 `json:"tx_bytes"`
+	TxPackets uint64 `json:"tx_packets"`
+}
+
+// BlockIOUsage sums the block device I/O of a sandbox over all devices.
+type BlockIOUsage struct {
+	ReadBytes  uint64 `json:"read_bytes"`
+	WriteBytes uint64 `json:"write_bytes"`
+}
+
+// NodeStats is the response for GET /v1/stats.
+type NodeStats struct {
+	Sandboxes SandboxCounts  `json:"sandboxes"` // sandboxes by container state
+	Allocated ResourceTotals `json:"allocated"` // summed limits of running sandboxes
+	Used      ResourceTotals `json:"used"`      // live usage of running sandboxes
+	Capacity  ResourceTotals `json:"capacity"`  // host memory and CPUs
+}
+
+// SandboxCounts counts sandboxes by state ("running", "exited", "paused", "removed", ...).
+type SandboxCounts struct {
+	Total   int            `json:"total"`
+	ByState map[string]int `json:"by_state"`
+}
+
+// ResourceTotals is an amount of memory and CPU.
+type ResourceTotals struct {
+	Memory int64   `json:"memory"` // MB
+	CPUs   float64 `json:"cpus"`
 }
 
 // MemoryUsage holds memory consumption details.
@@ -176,3 +762,9 @@ type ImageSummary struct {
 	Tags []string `json:"tags"`
 	Size int64    `json:"size"` // bytes
 }
+
+// ImagePruneResponse is the response for POST /v1/images/prune.
+type ImagePruneResponse struct {
+	Deleted        []ImageSummary `json:"deleted"`         // removed images
+	SpaceReclaimed int64          `json:"space_reclaimed"` // bytes freed on disk
+}
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// CreateScheduleRequest is the body for POST /v1/schedules.
+type CreateScheduleRequest struct {
+	Name    string               `json:"name" binding:"required" example:"nightly-tests"` // lowercase letters, digits and single hyphens, max 63
+	Cron    string               `json:"cron" binding:"required" example:"0 3 * * *"`     // five-field cron expression or @hourly, @daily, @weekly, @monthly, @yearly; evaluated in UTC
+	Sandbox CreateSandboxRequest `json:"sandbox"`                                         // sandbox created for every run; its name must be empty
+	Command ExecCommandRequest   `json:"command"`                                         // command each run executes; its timeout defaults to 1 hour
+}
+
+// Schedule is a recurring job: on every match of its cron expression a
+// sandbox is created, runs the command and is deleted again.
+type Schedule struct {
+	Name      string               `json:"name" example:"nightly-tests"`
+	Cron      string               `json:"cron" example:"0 3 * * *"`
+	Sandbox   CreateSandboxRequest `json:"sandbox"`
+	Command   ExecCommandRequest   `json:"command"`
+	NextRun   time.Time            `json:"next_run"`
+	LastRun   *ScheduleRun         `json:"last_run,omitempty"` // most recent run, nil before the first
+	CreatedAt time.Time            `json:"created_at"`
+}
+
+// ScheduleRun is one run of a schedule.
+type ScheduleRun struct {
+	ID         uint       `json:"id"`
+	SandboxID  string     `json:"sandbox_id,omitempty"`                                              // sandbox the run created, deleted once it finished
+	Status     string     `json:"status" enums:"running,succeeded,failed,error" example:"succeeded"` // "failed": non-zero exit code, "error": the sandbox or command could not run
+	ExitCode   *int       `json:"exit_code,omitempty"`
+	Error      string     `json:"error,omitempty"`  // why the run could not complete, for status "error"
+	Stdout     string     `json:"stdout,omitempty"` // last 64 KiB of the command's stdout
+	Stderr     string     `json:"stderr,omitempty"` // last 64 KiB of the command's stderr
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"` // nil while running
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// CreateSessionRequest is the body for POST /v1/sandboxes/:id/sessions.
+type CreateSessionRequest struct {
+	Language string            `json:"language" binding:"required" enums:"python,node" example:"python"` // interpreter to start; the sandbox image must provide python3 or node
+	Cwd      string            `json:"cwd,omitempty" example:"/app"`                                     // working directory of the interpreter
+	Env      map[string]string `json:"env,omitempty"`                                                    // extra environment variables
+}
+
+// Session is a persistent interpreter running inside a sandbox. Variables,
+// imports and definitions survive from one execution to the next.
+type Session struct {
+	ID             string    `json:"id" example:"ses_4f9c2a1b7d3e8f60a5b1c2d3"`
+	SandboxID      string    `json:"sandbox_id"`
+	Language       string    `json:"language" example:"python"`
+	ExecutionCount int       `json:"execution_count"` // cells executed so far
+	Busy           bool      `json:"busy"`            // a cell is executing
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// ExecuteRequest is the body for POST /v1/sandboxes/:id/sessions/:sid/execute.
+type ExecuteRequest struct {
+	Code    string `json:"code" binding:"required" example:"x = 21\nx * 2"` // cell source; in Python the value of a trailing expression is returned as a result event
+	Timeout int    `json:"timeout,omitempty" example:"60"`                  // seconds before the cell is interrupted, 0 = no limit
+}
+
+// SessionEvent is one line of the ND-JSON stream of an execution.
+type SessionEvent struct {
+	Type           string            `json:"type" enums:"stdout,stderr,display,result,error,end,ping" example:"result"` // "end" is always the last line; "ping" is sent while the cell runs without output
+	Text           string            `json:"text,omitempty"`                                                            // stdout and stderr: the text written
+	Data           map[string]string `json:"data,omitempty"`                                                            // display and result: representations keyed by MIME type, e.g. "text/plain", "text/html", "image/png" (base64)
+	Name           string            `json:"name,omitempty" example:"ZeroDivisionError"`                                // error: exception type
+	Value          string            `json:"value,omitempty" example:"division by zero"`                                // error: exception message
+	Traceback      string            `json:"traceback,omitempty"`                                                       // error: formatted traceback
+	ExecutionCount int               `json:"execution_count,omitempty"`                                                 // end: number of this execution in the session
+}
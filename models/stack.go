@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// CreateStackRequest is the body for POST /v1/stacks: a minimal compose-like
+// group of sandboxes sharing a private network.
+type CreateStackRequest struct {
+	Name     string                          `json:"name" binding:"required" example:"shop"` // stack name; also the name of the web service's sandbox. The others are named "<stack>-<service>"
+	Web      string                          `json:"web,omitempty" example:"app"`            // service the proxy routes the stack name to; empty = the only service with ports, if there is one
+	Services map[string]CreateSandboxRequest `json:"services" binding:"required,min=1"`      // services by name, e.g. "app", "db"; each reaches the others by service name. Their name and network fields must be empty
+}
+
+// Stack describes a stack and the state of its services.
+type Stack struct {
+	Name      string         `json:"name" example:"shop"`
+	Web       string         `json:"web,omitempty" example:"app"`
+	URL       string         `json:"url,omitempty"`                        // proxy URL of the web service
+	Network   string         `json:"network" example:"opensbx-stack-shop"` // Docker network the services share
+	Services  []StackService `json:"services"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// StackService is one service of a stack and the sandbox running it.
+type StackService struct {
+	Service string   `json:"service" example:"db"`
+	ID      string   `json:"id"`                     // sandbox ID
+	Name    string   `json:"name" example:"shop-db"` // sandbox name
+	Status  string   `json:"status" example:"running"`
+	Ports   []string `json:"ports"`
+}
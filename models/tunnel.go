@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// CreateTunnelRequest is the body for POST /v1/sandboxes/:id/tunnels.
+type CreateTunnelRequest struct {
+	Port int `json:"port" binding:"required,min=1,max=65535" example:"5432"` // TCP port inside the sandbox; it does not need to be published
+}
+
+// Tunnel forwards TCP connections from clients to a port inside a sandbox.
+// Each WebSocket connection to URL is one TCP connection to the port.
+type Tunnel struct {
+	ID          string    `json:"id" example:"tun_8a3f0c5d2b7e4f1a6c9d0e2b"`
+	SandboxID   string    `json:"sandbox_id"`
+	Port        int       `json:"port" example:"5432"`
+	URL         string    `json:"url" example:"/v1/sandboxes/3f2a1b/tunnels/tun_8a3f0c5d2b7e4f1a6c9d0e2b/connect"` // WebSocket path to connect to, authenticated like the rest of the API
+	Connections int       `json:"connections"`                                                                     // connections currently open
+	CreatedAt   time.Time `json:"created_at"`
+}